@@ -0,0 +1,163 @@
+// +build !js
+
+package webrtc
+
+import (
+	"encoding/binary"
+	"sync"
+	"time"
+
+	"github.com/pion/rtp"
+)
+
+// AbsCaptureTimeURI is the URI of the absolute capture time header extension.
+// Once it, or sdp.ABSSendTimeURI for the absolute send time used by remote
+// bandwidth estimation, is added with SettingEngine.AddSDPExtensions and
+// negotiated, RTPSenders stamp it on packets, and RTPReceivers pass it to
+// OnAbsCaptureTime or OnAbsSendTime.
+const AbsCaptureTimeURI = "http://www.webrtc.org/experiments/rtp-hdrext/abs-capture-time"
+
+// absCaptureTimeLength is the length of the absolute capture time, without
+// the optional estimated capture clock offset
+const absCaptureTimeLength = 8
+
+// ntpEpochOffset is the offset in seconds between the NTP and Unix epochs
+const ntpEpochOffset = 0x83AA7E80
+
+// absTimeSender stamps the absolute send and capture time on the packets of
+// an RTPSender
+type absTimeSender struct {
+	mu sync.Mutex
+
+	// ids of the negotiated extensions, 0 if they aren't
+	sendTimeID    uint8
+	captureTimeID uint8
+	clockRate     uint32
+
+	// captureTime is the capture time of captureTimestamp, the RTP timestamp
+	// of the first packet, from which the capture time of others is derived
+	hasCaptureTime   bool
+	captureTime      time.Time
+	captureTimestamp uint32
+	lastTimestamp    uint32
+}
+
+func (a *absTimeSender) setExtensionIDs(sendTimeID, captureTimeID uint8, clockRate uint32) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.sendTimeID, a.captureTimeID, a.clockRate = sendTimeID, captureTimeID, clockRate
+}
+
+// stamp returns the header with the absolute send time, and the capture time
+// on the first packet of each frame, or header if none is negotiated. The
+// header of the caller isn't modified.
+func (a *absTimeSender) stamp(header *rtp.Header, now time.Time) *rtp.Header {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.sendTimeID == 0 && a.captureTimeID == 0 {
+		return header
+	}
+
+	stamped := *header
+	stamped.Extensions = append([]rtp.Extension{}, header.Extensions...)
+
+	if a.sendTimeID != 0 {
+		payload, err := rtp.NewAbsSendTimeExtension(now).Marshal()
+		if err != nil {
+			return header
+		}
+		if err = stamped.SetExtension(a.sendTimeID, payload); err != nil {
+			return header
+		}
+	}
+
+	if a.captureTimeID != 0 && a.clockRate != 0 && (!a.hasCaptureTime || header.Timestamp != a.lastTimestamp) {
+		if !a.hasCaptureTime {
+			a.hasCaptureTime = true
+			a.captureTime, a.captureTimestamp = now, header.Timestamp
+		}
+		a.lastTimestamp = header.Timestamp
+
+		elapsed := time.Duration(int32(header.Timestamp-a.captureTimestamp)) * time.Second / time.Duration(a.clockRate)
+		payload := make([]byte, absCaptureTimeLength)
+		binary.BigEndian.PutUint64(payload, toNTPTime(a.captureTime.Add(elapsed)))
+		if err := stamped.SetExtension(a.captureTimeID, payload); err != nil {
+			return header
+		}
+	}
+	return &stamped
+}
+
+// absTimeReceiver calls the OnAbsSendTime and OnAbsCaptureTime handlers of an
+// RTPReceiver with the times of read packets
+type absTimeReceiver struct {
+	mu                 sync.Mutex
+	sendTimeID         uint8
+	captureTimeID      uint8
+	sendTimeHandler    func(sendTime time.Time)
+	captureTimeHandler func(captureTime time.Time, timestamp uint32)
+}
+
+// OnAbsSendTime sets an event handler which is called with the absolute send
+// time of each packet read from the Track that has it. It is called while the
+// Track is read.
+func (r *RTPReceiver) OnAbsSendTime(f func(sendTime time.Time)) {
+	r.absTime.mu.Lock()
+	defer r.absTime.mu.Unlock()
+	r.absTime.sendTimeHandler = f
+}
+
+// OnAbsCaptureTime sets an event handler which is called with the absolute
+// capture time, in the clock of the sender, and the RTP timestamp of each
+// packet read from the Track that has it. It is called while the Track is
+// read.
+func (r *RTPReceiver) OnAbsCaptureTime(f func(captureTime time.Time, timestamp uint32)) {
+	r.absTime.mu.Lock()
+	defer r.absTime.mu.Unlock()
+	r.absTime.captureTimeHandler = f
+}
+
+func (a *absTimeReceiver) setExtensionIDs(sendTimeID, captureTimeID uint8) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.sendTimeID, a.captureTimeID = sendTimeID, captureTimeID
+}
+
+// handleAbsTime calls the OnAbsSendTime and OnAbsCaptureTime handlers if
+// packet, which was read, has the extensions
+func (r *RTPReceiver) handleAbsTime(packet *rtp.Packet) {
+	r.absTime.mu.Lock()
+	sendTimeID, captureTimeID := r.absTime.sendTimeID, r.absTime.captureTimeID
+	sendTimeHandler, captureTimeHandler := r.absTime.sendTimeHandler, r.absTime.captureTimeHandler
+	r.absTime.mu.Unlock()
+	if (sendTimeID == 0 || sendTimeHandler == nil) && (captureTimeID == 0 || captureTimeHandler == nil) {
+		return
+	}
+
+	if sendTimeID != 0 && sendTimeHandler != nil {
+		extension := &rtp.AbsSendTimeExtension{}
+		if payload := packet.GetExtension(sendTimeID); payload != nil && extension.Unmarshal(payload) == nil {
+			sendTimeHandler(extension.Estimate(time.Now()))
+		}
+	}
+	if captureTimeID != 0 && captureTimeHandler != nil {
+		if payload := packet.GetExtension(captureTimeID); len(payload) >= absCaptureTimeLength {
+			captureTimeHandler(fromNTPTime(binary.BigEndian.Uint64(payload)), packet.Timestamp)
+		}
+	}
+}
+
+// toNTPTime converts t to a 64 bit NTP timestamp
+func toNTPTime(t time.Time) uint64 {
+	nanos := uint64(t.UnixNano())
+	seconds := nanos/uint64(time.Second) + ntpEpochOffset
+	fraction := ((nanos % uint64(time.Second)) << 32) / uint64(time.Second)
+	return seconds<<32 | fraction
+}
+
+// fromNTPTime converts a 64 bit NTP timestamp to a time
+func fromNTPTime(ntp uint64) time.Time {
+	seconds := ntp>>32 - ntpEpochOffset
+	nanos := ((ntp & 0xFFFFFFFF) * uint64(time.Second)) >> 32
+	return time.Unix(int64(seconds), int64(nanos))
+}
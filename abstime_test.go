@@ -0,0 +1,71 @@
+// +build !js
+
+package webrtc
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAbsTimeSender(t *testing.T) {
+	a := &absTimeSender{}
+	now := time.Unix(1600000000, 0)
+	header := &rtp.Header{Version: 2, Timestamp: 1000}
+
+	// Nothing is stamped until the extensions are negotiated
+	assert.Equal(t, header, a.stamp(header, now))
+	a.setExtensionIDs(2, 3, 90000)
+
+	stamped := a.stamp(header, now)
+	assert.False(t, header.Extension)
+	assert.Equal(t, 3, len(stamped.GetExtension(2)))
+	assert.Equal(t, toNTPTime(now), binary.BigEndian.Uint64(stamped.GetExtension(3)))
+
+	// The capture time is only sent with the first packet of a frame, and
+	// follows the RTP timestamps
+	assert.Nil(t, a.stamp(header, now.Add(time.Millisecond)).GetExtension(3))
+	next := &rtp.Header{Version: 2, Timestamp: 1000 + 9000}
+	stamped = a.stamp(next, now.Add(150*time.Millisecond))
+	assert.Equal(t, toNTPTime(now.Add(100*time.Millisecond)), binary.BigEndian.Uint64(stamped.GetExtension(3)))
+
+	send := &rtp.AbsSendTimeExtension{}
+	assert.NoError(t, send.Unmarshal(stamped.GetExtension(2)))
+	assert.Equal(t, rtp.NewAbsSendTimeExtension(now.Add(150*time.Millisecond)).Timestamp&0xFFFFFF, send.Timestamp)
+}
+
+func TestAbsTimeReceiver(t *testing.T) {
+	r := &RTPReceiver{}
+	sendTimes := []time.Time{}
+	r.OnAbsSendTime(func(sendTime time.Time) {
+		sendTimes = append(sendTimes, sendTime)
+	})
+	captureTimes := []time.Time{}
+	r.OnAbsCaptureTime(func(captureTime time.Time, timestamp uint32) {
+		assert.Equal(t, uint32(1234), timestamp)
+		captureTimes = append(captureTimes, captureTime)
+	})
+
+	sent := time.Now().Add(-10 * time.Millisecond)
+	captured := time.Unix(1600000000, 500000000)
+	header := &rtp.Header{Version: 2, Timestamp: 1234}
+	sendTime, err := rtp.NewAbsSendTimeExtension(sent).Marshal()
+	assert.NoError(t, err)
+	assert.NoError(t, header.SetExtension(2, sendTime))
+	captureTime := make([]byte, 8)
+	binary.BigEndian.PutUint64(captureTime, toNTPTime(captured))
+	assert.NoError(t, header.SetExtension(3, captureTime))
+	packet := &rtp.Packet{Header: *header}
+
+	r.handleAbsTime(packet)
+	r.absTime.setExtensionIDs(2, 3)
+	r.handleAbsTime(packet)
+
+	assert.Equal(t, 1, len(sendTimes))
+	assert.WithinDuration(t, sent, sendTimes[0], time.Millisecond)
+	assert.Equal(t, 1, len(captureTimes))
+	assert.WithinDuration(t, captured, captureTimes[0], time.Microsecond)
+}
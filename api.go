@@ -3,21 +3,43 @@
 package webrtc
 
 import (
+	"sync"
+
 	"github.com/pion/logging"
+	"github.com/pion/webrtc/v3/internal/util"
+	"github.com/pion/webrtc/v3/pkg/interceptor"
 )
 
 // API bundles the global functions of the WebRTC and ORTC API.
 // Some of these functions are also exported globally using the
 // defaultAPI object. Note that the global version of the API
 // may be phased out in the future.
+//
+// An API is built by NewAPI from a MediaEngine, a SettingEngine and an
+// interceptor Registry, and the PeerConnections and Tracks it creates use
+// them. APIs are independent, so an application can use several, like an SFU
+// with other codecs for each room.
 type API struct {
 	settingEngine *SettingEngine
 	mediaEngine   *MediaEngine
+	interceptor   interceptor.Interceptor
+
+	mu              sync.Mutex
+	isClosed        bool
+	peerConnections map[*PeerConnection]struct{}
+
+	// Buffers for reading RTP packets, shared by all Tracks
+	rtpPacketPool sync.Pool
 }
 
 // NewAPI Creates a new API object for keeping semi-global settings to WebRTC objects
 func NewAPI(options ...func(*API)) *API {
-	a := &API{}
+	a := &API{
+		peerConnections: map[*PeerConnection]struct{}{},
+	}
+	a.rtpPacketPool.New = func() interface{} {
+		return &pooledPacket{buffer: make([]byte, receiveMTU)}
+	}
 
 	for _, o := range options {
 		o(a)
@@ -35,6 +57,10 @@ func NewAPI(options ...func(*API)) *API {
 		a.mediaEngine = &MediaEngine{}
 	}
 
+	if a.interceptor == nil {
+		a.interceptor = &interceptor.NoOp{}
+	}
+
 	return a
 }
 
@@ -46,6 +72,32 @@ func WithMediaEngine(m MediaEngine) func(a *API) {
 	}
 }
 
+// SetMediaEngine replaces the MediaEngine of the API. Only PeerConnections
+// created afterwards use the new MediaEngine, existing PeerConnections keep
+// the MediaEngine they were created with. This allows enabling new codecs
+// without recreating the API.
+func (api *API) SetMediaEngine(m MediaEngine) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	api.mediaEngine = &m
+}
+
+func (api *API) getMediaEngine() *MediaEngine {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	return api.mediaEngine
+}
+
+// NewTrack creates a Track with the codec of the MediaEngine of the API
+// with payloadType, like PeerConnection.NewTrack. The Track can be added to
+// any PeerConnection of the API, which send it with the payload type they
+// negotiated for the codec.
+func (api *API) NewTrack(payloadType uint8, ssrc uint32, id, label string) (*Track, error) {
+	return api.getMediaEngine().newTrack(payloadType, ssrc, id, label)
+}
+
 // WithSettingEngine allows providing a SettingEngine to the API.
 // Settings should not be changed after passing the engine to an API.
 func WithSettingEngine(s SettingEngine) func(a *API) {
@@ -53,3 +105,59 @@ func WithSettingEngine(s SettingEngine) func(a *API) {
 		a.settingEngine = &s
 	}
 }
+
+// WithInterceptorRegistry allows providing Interceptors to the API.
+// Settings should not be changed after passing the registry to an API.
+func WithInterceptorRegistry(interceptorRegistry interceptor.Registry) func(a *API) {
+	return func(a *API) {
+		a.interceptor = interceptorRegistry.Build()
+	}
+}
+
+// Close closes all PeerConnections created by this API that are still
+// open and then stops the components shared between them, like the
+// Interceptors. Objects created with the ORTC API are not tracked and
+// must be stopped by the user. The API can't be used after it has been closed.
+func (api *API) Close() error {
+	api.mu.Lock()
+	if api.isClosed {
+		api.mu.Unlock()
+		return nil
+	}
+	api.isClosed = true
+
+	peerConnections := make([]*PeerConnection, 0, len(api.peerConnections))
+	for pc := range api.peerConnections {
+		peerConnections = append(peerConnections, pc)
+	}
+	api.mu.Unlock()
+
+	closeErrs := []error{}
+	for _, pc := range peerConnections {
+		closeErrs = append(closeErrs, pc.Close())
+	}
+	closeErrs = append(closeErrs, api.interceptor.Close())
+
+	return util.FlattenErrs(closeErrs)
+}
+
+func (api *API) addPeerConnection(pc *PeerConnection) error {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	if api.isClosed {
+		return ErrAPIClosed
+	}
+	api.peerConnections[pc] = struct{}{}
+	addLivePeerConnection(pc)
+
+	return nil
+}
+
+func (api *API) removePeerConnection(pc *PeerConnection) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	delete(api.peerConnections, pc)
+	removeLivePeerConnection(pc)
+}
@@ -4,6 +4,12 @@ package webrtc
 
 import (
 	"testing"
+
+	"github.com/pion/rtp"
+	"github.com/pion/rtp/codecs"
+	"github.com/pion/transport/test"
+	"github.com/pion/webrtc/v3/pkg/interceptor"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestNewAPI(t *testing.T) {
@@ -37,3 +43,96 @@ func TestNewAPI_Options(t *testing.T) {
 		t.Error("Failed to set media engine")
 	}
 }
+
+type closeInterceptor struct {
+	interceptor.NoOp
+	closed int
+}
+
+func (c *closeInterceptor) Close() error {
+	c.closed++
+	return nil
+}
+
+func TestAPI_Close(t *testing.T) {
+	report := test.CheckRoutines(t)
+	defer report()
+
+	closer := &closeInterceptor{}
+	ir := interceptor.Registry{}
+	ir.Add(closer)
+
+	api := NewAPI(WithInterceptorRegistry(ir))
+
+	pcClosedByUser, err := api.NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	pcClosedByAPI, err := api.NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	assert.NoError(t, pcClosedByUser.Close())
+	assert.Len(t, api.peerConnections, 1)
+
+	assert.NoError(t, api.Close())
+	assert.Equal(t, SignalingStateClosed, pcClosedByAPI.SignalingState())
+	assert.Empty(t, api.peerConnections)
+	assert.Equal(t, 1, closer.closed)
+
+	_, err = api.NewPeerConnection(Configuration{})
+	assert.Equal(t, ErrAPIClosed, err)
+
+	assert.NoError(t, api.Close())
+	assert.Equal(t, 1, closer.closed)
+}
+
+func TestAPI_SetMediaEngine(t *testing.T) {
+	videoOnly := MediaEngine{}
+	videoOnly.RegisterCodec(NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000))
+
+	api := NewAPI(WithMediaEngine(videoOnly))
+	pcBefore, err := api.NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	withAudio := MediaEngine{}
+	withAudio.RegisterCodec(NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000))
+	withAudio.RegisterCodec(NewRTPOpusCodec(DefaultPayloadTypeOpus, 48000))
+	api.SetMediaEngine(withAudio)
+
+	pcAfter, err := api.NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	assert.Empty(t, pcBefore.GetRegisteredRTPCodecs(RTPCodecTypeAudio))
+	assert.Len(t, pcAfter.GetRegisteredRTPCodecs(RTPCodecTypeAudio), 1)
+	assert.Len(t, pcAfter.GetRegisteredRTPCodecs(RTPCodecTypeVideo), 1)
+
+	assert.NoError(t, api.Close())
+}
+
+func TestAPI_NewTrack(t *testing.T) {
+	vp8 := MediaEngine{}
+	vp8.RegisterCodec(NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000))
+	h264 := MediaEngine{}
+	h264.RegisterCodec(NewRTPH264Codec(DefaultPayloadTypeH264, 90000))
+	h264.RegisterCodec(NewRTPCodec(RTPCodecTypeVideo, "H265", 90000, 0, "", 100, nil))
+	h264.RegisterPayloader("video/H265", func() rtp.Payloader { return &codecs.H264Payloader{} })
+
+	// The APIs each create Tracks with their own codecs
+	vp8API, h264API := NewAPI(WithMediaEngine(vp8)), NewAPI(WithMediaEngine(h264))
+	track, err := vp8API.NewTrack(DefaultPayloadTypeVP8, 1, "video", "pion")
+	assert.NoError(t, err)
+	assert.Equal(t, VP8, track.Codec().Name)
+	_, err = vp8API.NewTrack(DefaultPayloadTypeH264, 1, "video", "pion")
+	assert.Equal(t, ErrCodecNotFound, err)
+
+	track, err = h264API.NewTrack(DefaultPayloadTypeH264, 1, "video", "pion")
+	assert.NoError(t, err)
+	assert.Equal(t, H264, track.Codec().Name)
+	track, err = h264API.NewTrack(100, 1, "video", "pion")
+	assert.NoError(t, err)
+	assert.Equal(t, "H265", track.Codec().Name)
+	_, err = h264API.NewTrack(DefaultPayloadTypeVP8, 1, "video", "pion")
+	assert.Equal(t, ErrCodecNotFound, err)
+
+	assert.NoError(t, vp8API.Close())
+	assert.NoError(t, h264API.Close())
+}
@@ -0,0 +1,131 @@
+// +build !js
+
+package webrtc
+
+import (
+	"sync"
+
+	"github.com/pion/rtp"
+)
+
+// AudioLevelURI is the URI of the audio level header extension of RFC 6464.
+// Once it is added with SettingEngine.AddSDPExtensions for SDPSectionAudio
+// and negotiated, RTPSenders of audio stamp the level set with SetAudioLevel
+// or SetAudioLevelFunc on packets, and RTPReceivers pass the levels of read
+// packets to OnAudioLevel.
+const AudioLevelURI = "urn:ietf:params:rtp-hdrext:ssrc-audio-level"
+
+// audioLevelMax is the level of silence, in -dBov
+const audioLevelMax = 127
+
+// audioLevelSender stamps the audio level on the packets of an RTPSender
+type audioLevelSender struct {
+	mu sync.Mutex
+
+	// id of the negotiated extension, 0 if it isn't
+	id       uint8
+	hasLevel bool
+	level    uint8
+	voice    bool
+	levelFn  func() (level uint8, voice bool)
+}
+
+// SetAudioLevel sets the level, from 0 for 0 dBov to 127 for -127 dBov, and
+// the voice activity stamped on the next packets of the RTPSender. Levels
+// above 127 are 127.
+func (r *RTPSender) SetAudioLevel(level uint8, voice bool) {
+	if level > audioLevelMax {
+		level = audioLevelMax
+	}
+
+	r.audioLevel.mu.Lock()
+	defer r.audioLevel.mu.Unlock()
+	r.audioLevel.hasLevel = true
+	r.audioLevel.level = level
+	r.audioLevel.voice = voice
+}
+
+// SetAudioLevelFunc sets a function which is called for each packet of the
+// RTPSender, it returns the level and voice activity stamped on the packet
+// like SetAudioLevel. It takes precedence over SetAudioLevel.
+func (r *RTPSender) SetAudioLevelFunc(f func() (level uint8, voice bool)) {
+	r.audioLevel.mu.Lock()
+	defer r.audioLevel.mu.Unlock()
+	r.audioLevel.levelFn = f
+}
+
+func (a *audioLevelSender) setExtensionID(id uint8) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.id = id
+}
+
+// stamp returns the header with the audio level extension, or header if
+// there is no level to stamp. The header of the caller isn't modified.
+func (a *audioLevelSender) stamp(header *rtp.Header) *rtp.Header {
+	a.mu.Lock()
+	id, hasLevel, level, voice, levelFn := a.id, a.hasLevel, a.level, a.voice, a.levelFn
+	a.mu.Unlock()
+
+	switch {
+	case id == 0:
+		return header
+	case levelFn != nil:
+		level, voice = levelFn()
+	case !hasLevel:
+		return header
+	}
+
+	payload, err := (&rtp.AudioLevelExtension{Level: level, Voice: voice}).Marshal()
+	if err != nil {
+		return header
+	}
+
+	stamped := *header
+	stamped.Extensions = append([]rtp.Extension{}, header.Extensions...)
+	if err = stamped.SetExtension(id, payload); err != nil {
+		return header
+	}
+	return &stamped
+}
+
+// audioLevelReceiver calls the OnAudioLevel handler of an RTPReceiver with the
+// levels of read packets
+type audioLevelReceiver struct {
+	mu      sync.Mutex
+	id      uint8
+	handler func(level uint8, voice bool)
+}
+
+// OnAudioLevel sets an event handler which is called with the audio level
+// and voice activity of each packet read from the Track that has them. Levels
+// are from 0 for 0 dBov to 127 for -127 dBov, it is called while the Track is
+// read.
+func (r *RTPReceiver) OnAudioLevel(f func(level uint8, voice bool)) {
+	r.audioLevel.mu.Lock()
+	defer r.audioLevel.mu.Unlock()
+	r.audioLevel.handler = f
+}
+
+func (a *audioLevelReceiver) setExtensionID(id uint8) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.id = id
+}
+
+// handleAudioLevel calls the OnAudioLevel handler if packet, which was read,
+// has the audio level extension
+func (r *RTPReceiver) handleAudioLevel(packet *rtp.Packet) {
+	r.audioLevel.mu.Lock()
+	id, handler := r.audioLevel.id, r.audioLevel.handler
+	r.audioLevel.mu.Unlock()
+	if id == 0 || handler == nil {
+		return
+	}
+
+	extension := &rtp.AudioLevelExtension{}
+	if payload := packet.GetExtension(id); payload == nil || extension.Unmarshal(payload) != nil {
+		return
+	}
+	handler(extension.Level, extension.Voice)
+}
@@ -0,0 +1,96 @@
+// +build !js
+
+package webrtc
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/pion/rtp"
+	"github.com/pion/sdp/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAudioLevelSender(t *testing.T) {
+	r := &RTPSender{}
+	header := &rtp.Header{Version: 2, SequenceNumber: 1}
+
+	// Nothing is stamped until the extension is negotiated and a level is set
+	assert.Equal(t, header, r.audioLevel.stamp(header))
+	r.audioLevel.setExtensionID(3)
+	assert.Equal(t, header, r.audioLevel.stamp(header))
+
+	r.SetAudioLevel(200, true)
+	stamped := r.audioLevel.stamp(header)
+	assert.Equal(t, []byte{0x80 | audioLevelMax}, stamped.GetExtension(3))
+	assert.False(t, header.Extension)
+
+	r.SetAudioLevelFunc(func() (uint8, bool) {
+		return 30, false
+	})
+	assert.Equal(t, []byte{30}, r.audioLevel.stamp(header).GetExtension(3))
+}
+
+func TestAudioLevelReceiver(t *testing.T) {
+	r := &RTPReceiver{}
+	levels := []uint8{}
+	r.OnAudioLevel(func(level uint8, voice bool) {
+		assert.True(t, voice)
+		levels = append(levels, level)
+	})
+
+	header := &rtp.Header{Version: 2}
+	assert.NoError(t, header.SetExtension(5, []byte{0x80 | 42}))
+	packet := &rtp.Packet{Header: *header}
+
+	r.handleAudioLevel(packet)
+	r.audioLevel.setExtensionID(4)
+	r.handleAudioLevel(packet)
+	r.audioLevel.setExtensionID(5)
+	r.handleAudioLevel(packet)
+	assert.Equal(t, []uint8{42}, levels)
+}
+
+func TestPeerConnection_AudioLevelExtensionID(t *testing.T) {
+	newPC := func(audioLevel bool) *PeerConnection {
+		m := MediaEngine{}
+		m.RegisterDefaultCodecs()
+		s := SettingEngine{}
+		if audioLevel {
+			audioLevelURL, err := url.Parse(AudioLevelURI)
+			assert.NoError(t, err)
+			s.AddSDPExtensions(SDPSectionAudio, []sdp.ExtMap{{URI: audioLevelURL}})
+		}
+		pc, err := NewAPI(WithMediaEngine(m), WithSettingEngine(s)).NewPeerConnection(Configuration{})
+		assert.NoError(t, err)
+		return pc
+	}
+
+	// Descriptions are passed as SDP like with signaling
+	negotiate := func(offerer, answerer *PeerConnection) {
+		_, err := offerer.AddTransceiverFromKind(RTPCodecTypeAudio)
+		assert.NoError(t, err)
+		offer, err := offerer.CreateOffer(nil)
+		assert.NoError(t, err)
+		assert.NoError(t, offerer.SetLocalDescription(offer))
+		assert.NoError(t, answerer.SetRemoteDescription(SessionDescription{Type: offer.Type, SDP: offer.SDP}))
+		answer, err := answerer.CreateAnswer(nil)
+		assert.NoError(t, err)
+		assert.NoError(t, answerer.SetLocalDescription(answer))
+		assert.NoError(t, offerer.SetRemoteDescription(SessionDescription{Type: answer.Type, SDP: answer.SDP}))
+	}
+
+	offerer, answerer := newPC(true), newPC(true)
+	assert.Equal(t, uint8(0), offerer.negotiatedExtensionID(AudioLevelURI))
+	negotiate(offerer, answerer)
+	assert.NotEqual(t, uint8(0), offerer.negotiatedExtensionID(AudioLevelURI))
+	assert.Equal(t, offerer.negotiatedExtensionID(AudioLevelURI), answerer.negotiatedExtensionID(AudioLevelURI))
+	closePairNow(t, offerer, answerer)
+
+	// The extension is only used if both sides support it
+	offerer, answerer = newPC(true), newPC(false)
+	negotiate(offerer, answerer)
+	assert.Equal(t, uint8(0), offerer.negotiatedExtensionID(AudioLevelURI))
+	assert.Equal(t, uint8(0), answerer.negotiatedExtensionID(AudioLevelURI))
+	closePairNow(t, offerer, answerer)
+}
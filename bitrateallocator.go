@@ -0,0 +1,159 @@
+// +build !js
+
+package webrtc
+
+import (
+	"sort"
+	"sync"
+)
+
+// targetBitrateNotifier holds the bitrate allocated to an RTPSender, and
+// calls its OnTargetBitrateChanged handler
+type targetBitrateNotifier struct {
+	mu      sync.Mutex
+	bitrate uint64
+	handler func(bitrate uint64)
+}
+
+// OnTargetBitrateChanged sets an event handler which is called when the
+// bitrate allocated to the RTPSender by PeerConnection.SetTargetBitrate
+// changes, so the encoder writing to the Track can be adjusted.
+func (r *RTPSender) OnTargetBitrateChanged(f func(bitrate uint64)) {
+	r.targetBitrate.mu.Lock()
+	defer r.targetBitrate.mu.Unlock()
+	r.targetBitrate.handler = f
+}
+
+// TargetBitrate returns the bitrate in bits per second allocated to the
+// RTPSender by PeerConnection.SetTargetBitrate, 0 if none was
+func (r *RTPSender) TargetBitrate() uint64 {
+	r.targetBitrate.mu.Lock()
+	defer r.targetBitrate.mu.Unlock()
+	return r.targetBitrate.bitrate
+}
+
+// setTargetBitrate returns the OnTargetBitrateChanged handler to call if
+// bitrate changed, nil otherwise
+func (r *RTPSender) setTargetBitrate(bitrate uint64) func() {
+	r.targetBitrate.mu.Lock()
+	defer r.targetBitrate.mu.Unlock()
+
+	if r.targetBitrate.bitrate == bitrate {
+		return nil
+	}
+	r.targetBitrate.bitrate = bitrate
+	if handler := r.targetBitrate.handler; handler != nil {
+		return func() { handler(bitrate) }
+	}
+	return nil
+}
+
+// isSending tells if the RTPSender has a Track whose RTP is sent, and so
+// needs bitrate
+func (r *RTPSender) isSending() bool {
+	select {
+	case <-r.stopCalled:
+		return false
+	default:
+	}
+	return r.Track() != nil && !r.paused.get() && !r.inactive.get() && !r.mute.muted.get()
+}
+
+// bitrateAllocation is the bitrate allocated to an RTPSender
+type bitrateAllocation struct {
+	sender   *RTPSender
+	min, max uint64
+	weight   uint64
+	bitrate  uint64
+}
+
+// SetTargetBitrate divides bitrate, in bits per second, among the RTPSenders
+// of the PeerConnection, and calls the OnTargetBitrateChanged handler of the
+// ones whose share changed. Pion WebRTC doesn't estimate the bandwidth
+// itself: bitrate is the estimate of the application, like the one of a
+// REMB or a transport-cc based estimator.
+//
+// Each RTPSender first gets the MinBitrate of its parameters, by Priority,
+// and gets nothing if what remains is less than it. The rest is shared by
+// Priority, up to the MaxBitrate of each RTPSender. RTPSenders that are
+// stopped, paused, muted or whose encoding isn't active get nothing.
+func (pc *PeerConnection) SetTargetBitrate(bitrate uint64) {
+	pc.bitrateMu.Lock()
+	defer pc.bitrateMu.Unlock()
+
+	allocations := []*bitrateAllocation{}
+	for _, sender := range pc.GetSenders() {
+		allocation := &bitrateAllocation{sender: sender}
+		allocations = append(allocations, allocation)
+		if !sender.isSending() {
+			continue
+		}
+
+		encodings := sender.GetParameters().Encodings
+		allocation.weight = encodings.Priority.weight()
+		allocation.min, allocation.max = encodings.MinBitrate, sender.MaxBitrate()
+		if allocation.max != 0 && allocation.min > allocation.max {
+			allocation.min = allocation.max
+		}
+	}
+	allocateBitrate(bitrate, allocations)
+
+	for _, allocation := range allocations {
+		if handler := allocation.sender.setTargetBitrate(allocation.bitrate); handler != nil {
+			handler()
+		}
+	}
+}
+
+// allocateBitrate sets the bitrate of the allocations with a weight, see
+// PeerConnection.SetTargetBitrate
+func allocateBitrate(bitrate uint64, allocations []*bitrateAllocation) {
+	sorted := append([]*bitrateAllocation{}, allocations...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].weight > sorted[j].weight
+	})
+
+	remaining := bitrate
+	unsaturated := []*bitrateAllocation{}
+	for _, allocation := range sorted {
+		if allocation.weight == 0 || allocation.min > remaining {
+			continue
+		}
+		allocation.bitrate = allocation.min
+		remaining -= allocation.min
+		if allocation.max == 0 || allocation.bitrate < allocation.max {
+			unsaturated = append(unsaturated, allocation)
+		}
+	}
+
+	// The allocations reaching their max with their share are saturated
+	// first, as what they leave is shared by the others
+	for remaining > 0 && len(unsaturated) > 0 {
+		var totalWeight uint64
+		for _, allocation := range unsaturated {
+			totalWeight += allocation.weight
+		}
+
+		saturated := false
+		others := []*bitrateAllocation{}
+		available := remaining
+		for _, allocation := range unsaturated {
+			share := available * allocation.weight / totalWeight
+			if allocation.max != 0 && allocation.max-allocation.bitrate <= share {
+				remaining -= allocation.max - allocation.bitrate
+				allocation.bitrate = allocation.max
+				saturated = true
+				continue
+			}
+			others = append(others, allocation)
+		}
+		unsaturated = others
+
+		if !saturated {
+			for _, allocation := range unsaturated {
+				allocation.bitrate += available * allocation.weight / totalWeight
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,102 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/pion/randutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllocateBitrate(t *testing.T) {
+	for _, test := range []struct {
+		name        string
+		bitrate     uint64
+		allocations []bitrateAllocation
+		expected    []uint64
+	}{
+		{
+			name:        "shared by weight",
+			bitrate:     900,
+			allocations: []bitrateAllocation{{weight: 2}, {weight: 4}, {}},
+			expected:    []uint64{300, 600, 0},
+		},
+		{
+			name:        "max is redistributed",
+			bitrate:     900,
+			allocations: []bitrateAllocation{{weight: 2}, {weight: 8, max: 100}},
+			expected:    []uint64{800, 100},
+		},
+		{
+			name:        "min first",
+			bitrate:     1000,
+			allocations: []bitrateAllocation{{weight: 2, min: 700}, {weight: 2}},
+			expected:    []uint64{850, 150},
+		},
+		{
+			name:        "min by priority",
+			bitrate:     500,
+			allocations: []bitrateAllocation{{weight: 2, min: 400}, {weight: 8, min: 400}, {weight: 1}},
+			expected:    []uint64{0, 488, 11},
+		},
+		{
+			name:        "all saturated",
+			bitrate:     1000,
+			allocations: []bitrateAllocation{{weight: 2, max: 100}, {weight: 2, min: 200, max: 200}},
+			expected:    []uint64{100, 200},
+		},
+	} {
+		allocations := []*bitrateAllocation{}
+		for i := range test.allocations {
+			allocations = append(allocations, &test.allocations[i])
+		}
+		allocateBitrate(test.bitrate, allocations)
+
+		actual := []uint64{}
+		for _, allocation := range allocations {
+			actual = append(actual, allocation.bitrate)
+		}
+		assert.Equal(t, test.expected, actual, test.name)
+	}
+}
+
+func TestPeerConnection_SetTargetBitrate(t *testing.T) {
+	pc, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	senders := []*RTPSender{}
+	for _, priority := range []PriorityType{PriorityTypeHigh, PriorityTypeLow, PriorityTypeLow} {
+		track, trackErr := pc.NewTrack(DefaultPayloadTypeVP8, randutil.NewMathRandomGenerator().Uint32(), "video", "pion")
+		assert.NoError(t, trackErr)
+		sender, addErr := pc.AddTrack(track)
+		assert.NoError(t, addErr)
+
+		parameters := sender.GetParameters()
+		parameters.Encodings.Priority = priority
+		assert.NoError(t, sender.SetParameters(parameters))
+		senders = append(senders, sender)
+	}
+
+	changes := make(chan uint64, 3)
+	senders[0].OnTargetBitrateChanged(func(bitrate uint64) {
+		changes <- bitrate
+	})
+
+	// A muted RTPSender gets nothing
+	senders[2].SetMuted(true)
+	pc.SetTargetBitrate(1000000)
+	assert.Equal(t, uint64(800000), <-changes)
+	assert.Equal(t, uint64(200000), senders[1].TargetBitrate())
+	assert.Equal(t, uint64(0), senders[2].TargetBitrate())
+
+	// The handler is only called on changes
+	pc.SetTargetBitrate(1000000)
+	senders[2].SetMuted(false)
+	pc.SetTargetBitrate(1000000)
+	assert.Equal(t, uint64(2000000/3), <-changes)
+	assert.Equal(t, 0, len(changes))
+
+	assert.Equal(t, "very-low", PriorityTypeVeryLow.String())
+	assert.NoError(t, pc.Close())
+}
@@ -0,0 +1,210 @@
+// +build !js
+
+package webrtc
+
+import (
+	"strings"
+
+	"github.com/pion/sdp/v3"
+)
+
+// RemoteBrowser is the browser of the remote peer, whose quirks are worked
+// around in the offers and answers of a PeerConnection, see
+// SettingEngine.SetRemoteBrowser.
+type RemoteBrowser int
+
+const (
+	// RemoteBrowserUnknown doesn't work around any quirk. This is the
+	// default.
+	RemoteBrowserUnknown RemoteBrowser = iota
+
+	// RemoteBrowserFirefox only offers header extensions with IDs from 1
+	// to 14, as older Firefox versions reject the others.
+	RemoteBrowserFirefox
+
+	// RemoteBrowserSafari only offers H264 in the baseline profiles with
+	// packetization-mode=1, which is the only H264 Safari decodes.
+	RemoteBrowserSafari
+
+	// RemoteBrowserEdgeLegacy writes fmtp lines without whitespace or empty
+	// parameters, which the EdgeHTML based Edge fails to parse.
+	RemoteBrowserEdgeLegacy
+)
+
+// This is done this way because of a linter.
+const (
+	remoteBrowserUnknownStr    = "unknown"
+	remoteBrowserFirefoxStr    = "firefox"
+	remoteBrowserSafariStr     = "safari"
+	remoteBrowserEdgeLegacyStr = "edge-legacy"
+)
+
+// RemoteBrowserFromUserAgent returns the RemoteBrowser of the User-Agent
+// header ua of a browser, like the one of the request that signals an offer
+func RemoteBrowserFromUserAgent(ua string) RemoteBrowser {
+	switch {
+	case strings.Contains(ua, "Edge/"):
+		return RemoteBrowserEdgeLegacy
+	case strings.Contains(ua, "Firefox/"):
+		return RemoteBrowserFirefox
+	case strings.Contains(ua, "Safari/") && !strings.Contains(ua, "Chrome/") && !strings.Contains(ua, "Chromium/"):
+		return RemoteBrowserSafari
+	default:
+		return RemoteBrowserUnknown
+	}
+}
+
+func (b RemoteBrowser) String() string {
+	switch b {
+	case RemoteBrowserUnknown:
+		return remoteBrowserUnknownStr
+	case RemoteBrowserFirefox:
+		return remoteBrowserFirefoxStr
+	case RemoteBrowserSafari:
+		return remoteBrowserSafariStr
+	case RemoteBrowserEdgeLegacy:
+		return remoteBrowserEdgeLegacyStr
+	default:
+		return ErrUnknownType.Error()
+	}
+}
+
+// maxFirefoxExtensionID is the highest header extension ID older Firefox
+// versions accept, the one-byte header range of RFC 8285
+const maxFirefoxExtensionID = 14
+
+// applyBrowserQuirks changes the offer or answer d to work around the
+// quirks of browser
+func applyBrowserQuirks(browser RemoteBrowser, d *sdp.SessionDescription) {
+	for _, media := range d.MediaDescriptions {
+		switch browser {
+		case RemoteBrowserFirefox:
+			removeAttributes(media, func(attr sdp.Attribute) bool {
+				// Extmaps are added as property attributes
+				raw := *attr.String()
+				if !strings.HasPrefix(raw, sdp.AttrKeyExtMap+":") {
+					return false
+				}
+				extMap := sdp.ExtMap{}
+				return extMap.Unmarshal(raw) == nil && extMap.Value > maxFirefoxExtensionID
+			})
+		case RemoteBrowserSafari:
+			for _, payloadType := range unsupportedSafariH264(media) {
+				removePayloadType(media, payloadType)
+			}
+		case RemoteBrowserEdgeLegacy:
+			for i, attr := range media.Attributes {
+				if attr.Key == "fmtp" {
+					media.Attributes[i].Value = normalizeFmtp(attr.Value)
+				}
+			}
+		}
+	}
+}
+
+// unsupportedSafariH264 returns the payload types of the H264 codecs of
+// media Safari doesn't decode, unless they are all of the codecs
+func unsupportedSafariH264(media *sdp.MediaDescription) []string {
+	fmtps := map[string]string{}
+	var h264 []string
+	for _, attr := range media.Attributes {
+		parts := strings.SplitN(attr.Value, " ", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		switch attr.Key {
+		case "rtpmap":
+			if strings.HasPrefix(strings.ToUpper(parts[1]), H264+"/") {
+				h264 = append(h264, parts[0])
+			}
+		case "fmtp":
+			fmtps[parts[0]] = parts[1]
+		}
+	}
+
+	unsupported := []string{}
+	for _, payloadType := range h264 {
+		if !isSafariH264(fmtps[payloadType]) {
+			unsupported = append(unsupported, payloadType)
+		}
+	}
+	if len(unsupported) == len(media.MediaName.Formats) {
+		return nil
+	}
+	return unsupported
+}
+
+// isSafariH264 returns whether the H264 codec with fmtp is in one of the
+// baseline profiles with packetization-mode=1
+func isSafariH264(fmtp string) bool {
+	// The profile-level-id defaults to baseline, packetization-mode to 0
+	profile, packetizationMode := "42", ""
+	for _, parameter := range strings.Split(fmtp, ";") {
+		keyValue := strings.SplitN(strings.TrimSpace(parameter), "=", 2)
+		if len(keyValue) != 2 {
+			continue
+		}
+		switch strings.ToLower(keyValue[0]) {
+		case "profile-level-id":
+			if len(keyValue[1]) == 6 {
+				profile = strings.ToLower(keyValue[1][:2])
+			}
+		case "packetization-mode":
+			packetizationMode = keyValue[1]
+		}
+	}
+	return profile == "42" && packetizationMode == "1"
+}
+
+// normalizeFmtp removes the whitespace and empty parameters of the value
+// of an fmtp attribute
+func normalizeFmtp(value string) string {
+	parts := strings.SplitN(value, " ", 2)
+	if len(parts) != 2 {
+		return value
+	}
+
+	parameters := []string{}
+	for _, parameter := range strings.Split(parts[1], ";") {
+		keyValue := strings.SplitN(parameter, "=", 2)
+		for i := range keyValue {
+			keyValue[i] = strings.TrimSpace(keyValue[i])
+		}
+		if keyValue[0] != "" {
+			parameters = append(parameters, strings.Join(keyValue, "="))
+		}
+	}
+	return parts[0] + " " + strings.Join(parameters, ";")
+}
+
+// removePayloadType removes the payload type and its rtpmap, fmtp and
+// rtcp-fb attributes from media
+func removePayloadType(media *sdp.MediaDescription, payloadType string) {
+	formats := media.MediaName.Formats[:0]
+	for _, format := range media.MediaName.Formats {
+		if format != payloadType {
+			formats = append(formats, format)
+		}
+	}
+	media.MediaName.Formats = formats
+
+	removeAttributes(media, func(attr sdp.Attribute) bool {
+		switch attr.Key {
+		case "rtpmap", "fmtp", "rtcp-fb":
+			return strings.HasPrefix(attr.Value, payloadType+" ")
+		default:
+			return false
+		}
+	})
+}
+
+// removeAttributes removes the attributes of media remove returns true for
+func removeAttributes(media *sdp.MediaDescription, remove func(sdp.Attribute) bool) {
+	attributes := media.Attributes[:0]
+	for _, attr := range media.Attributes {
+		if !remove(attr) {
+			attributes = append(attributes, attr)
+		}
+	}
+	media.Attributes = attributes
+}
@@ -0,0 +1,161 @@
+// +build !js
+
+package webrtc
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/pion/sdp/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+// safariOffer is an offer of Safari with H264 it can't decode
+const safariOffer = `v=0
+o=- 6920920643910646739 2 IN IP4 127.0.0.1
+s=-
+t=0 0
+a=group:BUNDLE 0
+a=msid-semantic: WMS
+m=video 9 UDP/TLS/RTP/SAVPF 96 97 98 99
+c=IN IP4 0.0.0.0
+a=rtcp:9 IN IP4 0.0.0.0
+a=ice-ufrag:Qg4n
+a=ice-pwd:m3s1IwLkVtdV0EFXzxQ7fDgl
+a=ice-options:trickle
+a=fingerprint:sha-256 6B:8B:F0:65:5F:78:E2:51:3B:AC:6F:F3:3F:46:1B:35:DC:B8:5F:64:1A:24:C2:43:F0:A1:58:D0:A1:2C:19:08
+a=setup:actpass
+a=mid:0
+a=recvonly
+a=rtcp-mux
+a=rtcp-rsize
+a=rtpmap:96 H264/90000
+a=rtcp-fb:96 nack
+a=fmtp:96 level-asymmetry-allowed=1;packetization-mode=1;profile-level-id=640c1f
+a=rtpmap:97 H264/90000
+a=rtcp-fb:97 nack
+a=fmtp:97 level-asymmetry-allowed=1;packetization-mode=1;profile-level-id=42e01f
+a=rtpmap:98 H264/90000
+a=fmtp:98 level-asymmetry-allowed=1;packetization-mode=0;profile-level-id=42e01f
+a=rtpmap:99 VP8/90000
+`
+
+func TestRemoteBrowserFromUserAgent(t *testing.T) {
+	for ua, browser := range map[string]RemoteBrowser{
+		"Mozilla/5.0 (X11; Linux x86_64; rv:84.0) Gecko/20100101 Firefox/84.0":                                                               RemoteBrowserFirefox,
+		"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_6) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/14.0.1 Safari/605.1.15":            RemoteBrowserSafari,
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/70.0.3538.102 Safari/537.36 Edge/18.18363":  RemoteBrowserEdgeLegacy,
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/87.0.4280.88 Safari/537.36 Edg/87.0.664.66": RemoteBrowserUnknown,
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/87.0.4280.88 Safari/537.36":                 RemoteBrowserUnknown,
+		"": RemoteBrowserUnknown,
+	} {
+		assert.Equal(t, browser, RemoteBrowserFromUserAgent(ua), ua)
+	}
+	assert.Equal(t, "safari", RemoteBrowserSafari.String())
+}
+
+func TestBrowserQuirks(t *testing.T) {
+	extension := func(uri string, id int) sdp.ExtMap {
+		parsed, err := url.Parse(uri)
+		assert.NoError(t, err)
+		return sdp.ExtMap{URI: parsed, Value: id}
+	}
+
+	for _, test := range []struct {
+		name    string
+		browser RemoteBrowser
+		// offer is answered, an offer of kind with codec or the default
+		// codecs is created if it is empty
+		offer       string
+		kind        RTPCodecType
+		codec       *RTPCodec
+		contains    []string
+		notContains []string
+	}{
+		{
+			name:        "Firefox",
+			browser:     RemoteBrowserFirefox,
+			contains:    []string{"a=extmap:3 " + sdp.SDESMidURI},
+			notContains: []string{"a=extmap:15 "},
+		},
+		{
+			name:     "Firefox unknown",
+			browser:  RemoteBrowserUnknown,
+			contains: []string{"a=extmap:3 " + sdp.SDESMidURI, "a=extmap:15 " + sdp.TransportCCURI},
+		},
+		{
+			name:        "Safari",
+			browser:     RemoteBrowserSafari,
+			offer:       safariOffer,
+			contains:    []string{"m=video 9 UDP/TLS/RTP/SAVPF 97 99", "a=fmtp:97 level-asymmetry-allowed=1;packetization-mode=1;profile-level-id=42e01f"},
+			notContains: []string{"a=rtpmap:96 ", "a=rtcp-fb:96 ", "a=fmtp:96 ", "a=rtpmap:98 "},
+		},
+		{
+			name:     "Safari unknown",
+			browser:  RemoteBrowserUnknown,
+			offer:    safariOffer,
+			contains: []string{"m=video 9 UDP/TLS/RTP/SAVPF 96 97 98 99"},
+		},
+		{
+			name:        "Edge legacy",
+			browser:     RemoteBrowserEdgeLegacy,
+			kind:        RTPCodecTypeAudio,
+			codec:       NewRTPCodec(RTPCodecTypeAudio, Opus, 48000, 2, "minptime=10; useinbandfec=1;", DefaultPayloadTypeOpus, nil),
+			contains:    []string{"a=fmtp:111 minptime=10;useinbandfec=1\r\n"},
+			notContains: []string{"a=fmtp:111 minptime=10; useinbandfec=1;"},
+		},
+		{
+			name:     "Edge legacy unknown",
+			browser:  RemoteBrowserUnknown,
+			kind:     RTPCodecTypeAudio,
+			codec:    NewRTPCodec(RTPCodecTypeAudio, Opus, 48000, 2, "minptime=10; useinbandfec=1;", DefaultPayloadTypeOpus, nil),
+			contains: []string{"a=fmtp:111 minptime=10; useinbandfec=1;"},
+		},
+	} {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			s := SettingEngine{}
+			s.SetRemoteBrowser(test.browser)
+			s.AddSDPExtensions(SDPSectionVideo, []sdp.ExtMap{
+				extension(sdp.SDESMidURI, 3),
+				extension(sdp.TransportCCURI, 15),
+			})
+
+			m := MediaEngine{}
+			switch {
+			case test.codec != nil:
+				m.RegisterCodec(test.codec)
+			case test.offer == "":
+				m.RegisterDefaultCodecs()
+			default:
+				assert.NoError(t, m.PopulateFromSDP(SessionDescription{Type: SDPTypeOffer, SDP: test.offer}))
+			}
+
+			pc, err := NewAPI(WithMediaEngine(m), WithSettingEngine(s)).NewPeerConnection(Configuration{})
+			assert.NoError(t, err)
+
+			var description SessionDescription
+			if test.offer == "" {
+				kind := test.kind
+				if kind == 0 {
+					kind = RTPCodecTypeVideo
+				}
+				_, err = pc.AddTransceiverFromKind(kind, RtpTransceiverInit{Direction: RTPTransceiverDirectionRecvonly})
+				assert.NoError(t, err)
+				description, err = pc.CreateOffer(nil)
+			} else {
+				assert.NoError(t, pc.SetRemoteDescription(SessionDescription{Type: SDPTypeOffer, SDP: test.offer}))
+				description, err = pc.CreateAnswer(nil)
+			}
+			assert.NoError(t, err)
+
+			for _, s := range test.contains {
+				assert.Contains(t, description.SDP, s)
+			}
+			for _, s := range test.notContains {
+				assert.NotContains(t, description.SDP, s)
+			}
+			assert.NoError(t, pc.Close())
+		})
+	}
+}
@@ -4,6 +4,11 @@ package webrtc
 // endpoint is not bundle-aware, and what ICE candidates are gathered. If the
 // remote endpoint is bundle-aware, all media tracks and data channels are
 // bundled onto the same transport.
+//
+// A PeerConnection only has one transport, so when answering, the media
+// sections the offer doesn't bundle are rejected. If the offer doesn't use
+// BUNDLE at all, BundlePolicyMaxBundle answers only the first media section,
+// and the other policies answer all of them on the one transport.
 type BundlePolicy int
 
 const (
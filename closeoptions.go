@@ -0,0 +1,121 @@
+// +build !js
+
+package webrtc
+
+import (
+	"time"
+
+	"github.com/pion/rtcp"
+)
+
+// closeLingerInterval is how often the data queued in the DataChannels is
+// checked while lingering
+const closeLingerInterval = 10 * time.Millisecond
+
+// CloseOptions configures how PeerConnection.CloseWithOptions tears down the
+// PeerConnection. The zero value closes it like Close, right away.
+type CloseOptions struct {
+	// Linger is how long to wait at most for the data queued in the
+	// DataChannels to be sent before closing them. RTP is written to the
+	// transport synchronously, so no RTP is queued.
+	Linger time.Duration
+
+	// SendBye sends an RTCP BYE for the SSRCs of the RTPSenders that are
+	// sending, so the remote ends their tracks right away instead of timing
+	// them out.
+	SendBye bool
+
+	// OnTransportClosed is called once each transport of the PeerConnection
+	// is closed, in the order they are closed.
+	OnTransportClosed func(TransportClosedEvent)
+}
+
+// ClosedTransport is a transport of a PeerConnection closed by
+// CloseWithOptions.
+type ClosedTransport int
+
+const (
+	// ClosedTransportRTP is the RTP of the RTPTransceivers.
+	ClosedTransportRTP ClosedTransport = iota + 1
+
+	// ClosedTransportSCTP is the SCTPTransport of the DataChannels.
+	ClosedTransportSCTP
+
+	// ClosedTransportDTLS is the DTLSTransport. Closing it sends a DTLS
+	// close_notify alert.
+	ClosedTransportDTLS
+
+	// ClosedTransportICE is the ICETransport.
+	ClosedTransportICE
+)
+
+// This is done this way because of a linter.
+const (
+	closedTransportRTPStr  = "rtp"
+	closedTransportSCTPStr = "sctp"
+	closedTransportDTLSStr = "dtls"
+	closedTransportICEStr  = "ice"
+)
+
+func (t ClosedTransport) String() string {
+	switch t {
+	case ClosedTransportRTP:
+		return closedTransportRTPStr
+	case ClosedTransportSCTP:
+		return closedTransportSCTPStr
+	case ClosedTransportDTLS:
+		return closedTransportDTLSStr
+	case ClosedTransportICE:
+		return closedTransportICEStr
+	default:
+		return ErrUnknownType.Error()
+	}
+}
+
+// TransportClosedEvent tells that a transport of a PeerConnection was
+// closed, see CloseOptions.OnTransportClosed.
+type TransportClosedEvent struct {
+	Transport ClosedTransport
+
+	// Started is when closing the transport started, and Duration how long
+	// it took, including the time lingering for queued data
+	Started  time.Time
+	Duration time.Duration
+
+	// Err is the error closing the transport, if any
+	Err error
+}
+
+// sendBye sends an RTCP BYE for the SSRCs of the RTPSenders that are
+// sending
+func (pc *PeerConnection) sendBye() error {
+	sources := []uint32{}
+	for _, sender := range pc.GetSenders() {
+		if sender.Track() != nil && sender.hasSent() {
+			sources = append(sources, sender.SSRC())
+		}
+	}
+	if len(sources) == 0 {
+		return nil
+	}
+	return pc.dtlsTransport.writeRTCP([]rtcp.Packet{&rtcp.Goodbye{Sources: sources}})
+}
+
+// lingerDataChannels waits until the data queued in the DataChannels is
+// sent, or for linger at most
+func (pc *PeerConnection) lingerDataChannels(linger time.Duration) {
+	deadline := time.Now().Add(linger)
+	for time.Now().Before(deadline) {
+		var bufferedAmount uint64
+		pc.sctpTransport.lock.Lock()
+		for _, d := range pc.sctpTransport.dataChannels {
+			bufferedAmount += d.BufferedAmount()
+		}
+		pc.sctpTransport.lock.Unlock()
+
+		if bufferedAmount == 0 {
+			return
+		}
+		time.Sleep(closeLingerInterval)
+	}
+}
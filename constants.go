@@ -10,6 +10,10 @@ const (
 	// Equal to UDP MTU
 	receiveMTU = 1460
 
+	// rtpFixedHeaderLength is the length of the RTP header without CSRCs
+	// and extensions
+	rtpFixedHeaderLength = 12
+
 	// simulcastProbeCount is the amount of RTP Packets
 	// that handleUndeclaredSSRC will read and try to dispatch from
 	// mid and rid values
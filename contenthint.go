@@ -0,0 +1,139 @@
+// +build !js
+
+package webrtc
+
+import (
+	"sync"
+
+	"github.com/pion/rtp"
+)
+
+// VideoContentTypeURI is the URI of the video-content-type header extension.
+// Once it is added with SettingEngine.AddSDPExtensions for SDPSectionVideo
+// and negotiated, RTPSenders with the detail or text ContentHint mark the
+// last packet of each frame as screen content.
+const VideoContentTypeURI = "http://www.webrtc.org/experiments/rtp-hdrext/video-content-type"
+
+// videoContentTypeScreenshare is the video-content-type of screen content
+const videoContentTypeScreenshare = 0x01
+
+// ContentHint tells what the video sent by an RTPSender shows, like the
+// contentHint of a MediaStreamTrack. It chooses the default
+// DegradationPreference of the RTPSender.
+type ContentHint int
+
+const (
+	// ContentHintNone gives no hint. This is the default.
+	ContentHintNone ContentHint = iota
+
+	// ContentHintMotion indicates video with motion, like from a camera,
+	// whose framerate is kept over its resolution.
+	ContentHintMotion
+
+	// ContentHintDetail indicates video with details, like a screen share,
+	// whose resolution is kept over its framerate so text stays crisp.
+	ContentHintDetail
+
+	// ContentHintText indicates video with text, like a screen share of a
+	// document, treated as ContentHintDetail.
+	ContentHintText
+)
+
+// This is done this way because of a linter.
+const (
+	contentHintNoneStr   = ""
+	contentHintMotionStr = "motion"
+	contentHintDetailStr = "detail"
+	contentHintTextStr   = "text"
+)
+
+func (h ContentHint) String() string {
+	switch h {
+	case ContentHintNone:
+		return contentHintNoneStr
+	case ContentHintMotion:
+		return contentHintMotionStr
+	case ContentHintDetail:
+		return contentHintDetailStr
+	case ContentHintText:
+		return contentHintTextStr
+	default:
+		return ErrUnknownType.Error()
+	}
+}
+
+// contentHintSender holds the ContentHint of an RTPSender, and stamps the
+// video-content-type it implies on its packets
+type contentHintSender struct {
+	mu sync.Mutex
+
+	// id of the negotiated extension, 0 if it isn't
+	id   uint8
+	hint ContentHint
+}
+
+// SetContentHint sets what the video sent by the RTPSender shows. Pion
+// WebRTC doesn't encode, pace or protect media itself: the hint chooses the
+// DegradationPreference the encoder writing to the Track reads, and detail
+// and text are signaled to the receiver as screen content.
+func (r *RTPSender) SetContentHint(hint ContentHint) {
+	r.contentHint.mu.Lock()
+	defer r.contentHint.mu.Unlock()
+	r.contentHint.hint = hint
+}
+
+// ContentHint returns the hint set with SetContentHint
+func (r *RTPSender) ContentHint() ContentHint {
+	r.contentHint.mu.Lock()
+	defer r.contentHint.mu.Unlock()
+	return r.contentHint.hint
+}
+
+// DegradationPreference returns what the encoder writing to the Track should
+// give up first when the bitrate is constrained. It is the
+// DegradationPreference of the parameters if it is set, otherwise the
+// resolution is kept for the detail and text ContentHints, and the framerate
+// for the motion one.
+func (r *RTPSender) DegradationPreference() DegradationPreference {
+	r.mu.RLock()
+	preference := r.parameters.DegradationPreference
+	r.mu.RUnlock()
+	if preference != DegradationPreference(Unknown) {
+		return preference
+	}
+
+	switch r.ContentHint() {
+	case ContentHintMotion:
+		return DegradationPreferenceMaintainFramerate
+	case ContentHintDetail, ContentHintText:
+		return DegradationPreferenceMaintainResolution
+	default:
+		return DegradationPreferenceBalanced
+	}
+}
+
+func (c *contentHintSender) setExtensionID(id uint8) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.id = id
+}
+
+// stamp returns the header with the video-content-type of screen content on
+// the last packet of a frame, or header if the hint isn't detail or text.
+// The header of the caller isn't modified.
+func (c *contentHintSender) stamp(header *rtp.Header) *rtp.Header {
+	c.mu.Lock()
+	id, hint := c.id, c.hint
+	c.mu.Unlock()
+
+	if id == 0 || !header.Marker || (hint != ContentHintDetail && hint != ContentHintText) {
+		return header
+	}
+
+	stamped := *header
+	stamped.Extensions = append([]rtp.Extension{}, header.Extensions...)
+	if err := stamped.SetExtension(id, []byte{videoContentTypeScreenshare}); err != nil {
+		return header
+	}
+	return &stamped
+}
@@ -0,0 +1,42 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRTPSender_ContentHint(t *testing.T) {
+	r := &RTPSender{}
+	assert.Equal(t, ContentHintNone, r.ContentHint())
+	assert.Equal(t, DegradationPreferenceBalanced, r.DegradationPreference())
+
+	r.SetContentHint(ContentHintMotion)
+	assert.Equal(t, DegradationPreferenceMaintainFramerate, r.DegradationPreference())
+	r.SetContentHint(ContentHintText)
+	assert.Equal(t, DegradationPreferenceMaintainResolution, r.DegradationPreference())
+	assert.Equal(t, "text", r.ContentHint().String())
+
+	// The preference of the parameters takes precedence
+	r.parameters.DegradationPreference = DegradationPreferenceBalanced
+	assert.Equal(t, DegradationPreferenceBalanced, r.DegradationPreference())
+	assert.Equal(t, "maintain-resolution", DegradationPreferenceMaintainResolution.String())
+}
+
+func TestContentHintSender_stamp(t *testing.T) {
+	c := &contentHintSender{hint: ContentHintDetail}
+	header := &rtp.Header{Version: 2, Marker: true}
+	assert.Equal(t, header, c.stamp(header))
+
+	// Only the last packet of a frame is stamped
+	c.setExtensionID(7)
+	assert.Equal(t, []byte{0x01}, c.stamp(header).GetExtension(7))
+	assert.False(t, header.Extension)
+	assert.Nil(t, c.stamp(&rtp.Header{Version: 2}).GetExtension(7))
+
+	c.hint = ContentHintMotion
+	assert.Equal(t, header, c.stamp(header))
+}
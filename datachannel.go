@@ -169,7 +169,7 @@ func (d *DataChannel) open(sctpTransport *SCTPTransport) error {
 	dc.OnBufferedAmountLow(d.onBufferedAmountLow)
 	d.mu.Unlock()
 
-	d.handleOpen(dc)
+	d.handleOpen(dc, sctpTransport)
 	return nil
 }
 
@@ -277,7 +277,7 @@ func (d *DataChannel) onMessage(msg DataChannelMessage) {
 	handler(msg)
 }
 
-func (d *DataChannel) handleOpen(dc *datachannel.DataChannel) {
+func (d *DataChannel) handleOpen(dc *datachannel.DataChannel, sctpTransport *SCTPTransport) {
 	d.mu.Lock()
 	d.dataChannel = dc
 	d.mu.Unlock()
@@ -289,7 +289,10 @@ func (d *DataChannel) handleOpen(dc *datachannel.DataChannel) {
 	defer d.mu.Unlock()
 
 	if !d.api.settingEngine.detach.DataChannels {
-		go d.readLoop()
+		associationClosed := sctpTransport.getAssociationClosed()
+		sctpTransport.goAssociation(func() {
+			d.readLoop(associationClosed)
+		})
 	}
 }
 
@@ -311,20 +314,38 @@ func (d *DataChannel) onError(err error) {
 	}
 }
 
-func (d *DataChannel) readLoop() {
+// readLoop reads the messages until the DataChannel is closed. They are
+// delivered to the OnMessage handler on another goroutine, which isn't
+// waited for by Close as the handler may be closing the PeerConnection.
+// Messages the handler doesn't take before associationClosed is closed are
+// dropped.
+func (d *DataChannel) readLoop(associationClosed <-chan struct{}) {
+	messages := make(chan DataChannelMessage)
+	var err error
+	go func() {
+		for msg := range messages {
+			d.onMessage(msg)
+		}
+		if err != io.EOF {
+			d.onError(err)
+		}
+		d.onClose()
+	}()
+	defer close(messages)
+
 	for {
 		buffer := make([]byte, dataChannelBufferSize)
-		n, isString, err := d.dataChannel.ReadDataChannel(buffer)
-		if err != nil {
+		var n int
+		var isString bool
+		if n, isString, err = d.dataChannel.ReadDataChannel(buffer); err != nil {
 			d.setReadyState(DataChannelStateClosed)
-			if err != io.EOF {
-				d.onError(err)
-			}
-			d.onClose()
 			return
 		}
 
-		d.onMessage(DataChannelMessage{Data: buffer[:n], IsString: isString})
+		select {
+		case messages <- DataChannelMessage{Data: buffer[:n], IsString: isString}:
+		case <-associationClosed:
+		}
 	}
 }
 
@@ -0,0 +1,106 @@
+// +build !js
+
+package webrtc
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"time"
+)
+
+const (
+	defaultDataChannelAuthTimeout = 10 * time.Second
+
+	dataChannelTokenTimestampLength = 8
+)
+
+// DataChannelAuthenticator authenticates incoming DataChannels by their
+// first message, before any message reaches the application. DataChannels
+// that fail to authenticate, or don't send a message in time, are closed.
+type DataChannelAuthenticator struct {
+	// Verify checks the first message of a DataChannel, which is closed if
+	// it returns an error
+	Verify func(d *DataChannel, msg DataChannelMessage) error
+
+	// Timeout is how long a DataChannel has to send its first message,
+	// 10 seconds if zero
+	Timeout time.Duration
+}
+
+// OnDataChannel returns a handler for PeerConnection.OnDataChannel, which
+// calls f with the DataChannels that authenticate. As f is called once the
+// DataChannel is open, OnOpen handlers set by f are never called. The first
+// message isn't passed to OnMessage, all messages after it are.
+func (a DataChannelAuthenticator) OnDataChannel(f func(*DataChannel)) func(*DataChannel) {
+	timeout := a.Timeout
+	if timeout == 0 {
+		timeout = defaultDataChannelAuthTimeout
+	}
+
+	return func(d *DataChannel) {
+		timer := time.AfterFunc(timeout, func() {
+			d.log.Warnf("DataChannel %s did not authenticate in time", d.Label())
+			if err := d.Close(); err != nil {
+				d.log.Warnf("Failed to close DataChannel: %v", err)
+			}
+		})
+
+		d.OnMessage(func(msg DataChannelMessage) {
+			d.OnMessage(nil)
+			if !timer.Stop() {
+				return
+			}
+
+			if err := a.Verify(d, msg); err != nil {
+				d.log.Warnf("DataChannel %s failed to authenticate: %v", d.Label(), err)
+				if closeErr := d.Close(); closeErr != nil {
+					d.log.Warnf("Failed to close DataChannel: %v", closeErr)
+				}
+				return
+			}
+
+			// Messages are read after f returns, so none are missed by its handlers
+			f(d)
+		})
+	}
+}
+
+// NewDataChannelToken creates the first message of a DataChannel, for a
+// remote peer that verifies it with DataChannelTokenVerifier. The token is
+// an HMAC-SHA256 with key of the label of the DataChannel and the time.
+func NewDataChannelToken(key []byte, label string) []byte {
+	token := make([]byte, dataChannelTokenTimestampLength, dataChannelTokenTimestampLength+sha256.Size)
+	binary.BigEndian.PutUint64(token, uint64(time.Now().Unix()))
+	return append(token, dataChannelTokenMAC(key, label, token)...)
+}
+
+// DataChannelTokenVerifier returns a Verify function for a
+// DataChannelAuthenticator, which accepts tokens of NewDataChannelToken
+// created with key in the last maxAge. A token can be replayed in maxAge,
+// so it should only be a few seconds.
+func DataChannelTokenVerifier(key []byte, maxAge time.Duration) func(*DataChannel, DataChannelMessage) error {
+	return func(d *DataChannel, msg DataChannelMessage) error {
+		if msg.IsString || len(msg.Data) != dataChannelTokenTimestampLength+sha256.Size {
+			return errDataChannelTokenInvalid
+		}
+
+		timestamp := msg.Data[:dataChannelTokenTimestampLength]
+		if !hmac.Equal(msg.Data[dataChannelTokenTimestampLength:], dataChannelTokenMAC(key, d.Label(), timestamp)) {
+			return errDataChannelTokenInvalid
+		}
+
+		age := time.Since(time.Unix(int64(binary.BigEndian.Uint64(timestamp)), 0))
+		if age > maxAge || age < -maxAge {
+			return errDataChannelTokenExpired
+		}
+		return nil
+	}
+}
+
+func dataChannelTokenMAC(key []byte, label string, timestamp []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(timestamp)     // nolint:errcheck
+	mac.Write([]byte(label)) // nolint:errcheck
+	return mac.Sum(nil)
+}
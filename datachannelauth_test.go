@@ -0,0 +1,86 @@
+// +build !js
+
+package webrtc
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/pion/transport/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDataChannelTokenVerifier(t *testing.T) {
+	key := []byte("key")
+	verify := DataChannelTokenVerifier(key, time.Minute)
+	d := &DataChannel{label: "chat"}
+
+	token := NewDataChannelToken(key, "chat")
+	assert.NoError(t, verify(d, DataChannelMessage{Data: token}))
+	assert.Equal(t, errDataChannelTokenInvalid, verify(d, DataChannelMessage{Data: token, IsString: true}))
+	assert.Equal(t, errDataChannelTokenInvalid, verify(d, DataChannelMessage{Data: token[1:]}))
+	assert.Equal(t, errDataChannelTokenInvalid, verify(d, DataChannelMessage{Data: NewDataChannelToken([]byte("other"), "chat")}))
+	assert.Equal(t, errDataChannelTokenInvalid, verify(d, DataChannelMessage{Data: NewDataChannelToken(key, "other")}))
+
+	expired := make([]byte, dataChannelTokenTimestampLength)
+	binary.BigEndian.PutUint64(expired, uint64(time.Now().Add(-time.Hour).Unix()))
+	expired = append(expired, dataChannelTokenMAC(key, "chat", expired)...)
+	assert.Equal(t, errDataChannelTokenExpired, verify(d, DataChannelMessage{Data: expired}))
+}
+
+func TestDataChannelAuthenticator(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	key := []byte("key")
+	pcOffer, pcAnswer, err := newPair()
+	assert.NoError(t, err)
+
+	authenticated := make(chan string)
+	received := make(chan string, 1)
+	pcAnswer.OnDataChannel(DataChannelAuthenticator{
+		Verify:  DataChannelTokenVerifier(key, time.Minute),
+		Timeout: time.Second,
+	}.OnDataChannel(func(d *DataChannel) {
+		d.OnMessage(func(msg DataChannelMessage) {
+			received <- string(msg.Data)
+		})
+		authenticated <- d.Label()
+	}))
+
+	// Valid token, then an application message
+	valid, err := pcOffer.CreateDataChannel("valid", nil)
+	assert.NoError(t, err)
+	valid.OnOpen(func() {
+		assert.NoError(t, valid.Send(NewDataChannelToken(key, "valid")))
+		assert.NoError(t, valid.SendText("hello"))
+	})
+
+	// Invalid token and no message are both closed
+	invalidClosed := make(chan struct{})
+	invalid, err := pcOffer.CreateDataChannel("invalid", nil)
+	assert.NoError(t, err)
+	invalid.OnOpen(func() {
+		assert.NoError(t, invalid.Send(NewDataChannelToken([]byte("other"), "invalid")))
+	})
+	invalid.OnClose(func() { close(invalidClosed) })
+
+	silentClosed := make(chan struct{})
+	silent, err := pcOffer.CreateDataChannel("silent", nil)
+	assert.NoError(t, err)
+	silent.OnClose(func() { close(silentClosed) })
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	assert.Equal(t, "valid", <-authenticated)
+	assert.Equal(t, "hello", <-received)
+	<-invalidClosed
+	<-silentClosed
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
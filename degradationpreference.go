@@ -0,0 +1,38 @@
+package webrtc
+
+// DegradationPreference tells what the encoder writing to the Track of an
+// RTPSender gives up first when the bitrate is constrained, see
+// RTPSender.DegradationPreference.
+type DegradationPreference int
+
+const (
+	// DegradationPreferenceBalanced lowers both the framerate and the
+	// resolution.
+	DegradationPreferenceBalanced DegradationPreference = iota + 1
+
+	// DegradationPreferenceMaintainFramerate lowers the resolution.
+	DegradationPreferenceMaintainFramerate
+
+	// DegradationPreferenceMaintainResolution lowers the framerate.
+	DegradationPreferenceMaintainResolution
+)
+
+// This is done this way because of a linter.
+const (
+	degradationPreferenceBalancedStr           = "balanced"
+	degradationPreferenceMaintainFramerateStr  = "maintain-framerate"
+	degradationPreferenceMaintainResolutionStr = "maintain-resolution"
+)
+
+func (p DegradationPreference) String() string {
+	switch p {
+	case DegradationPreferenceBalanced:
+		return degradationPreferenceBalancedStr
+	case DegradationPreferenceMaintainFramerate:
+		return degradationPreferenceMaintainFramerateStr
+	case DegradationPreferenceMaintainResolution:
+		return degradationPreferenceMaintainResolutionStr
+	default:
+		return ErrUnknownType.Error()
+	}
+}
@@ -0,0 +1,525 @@
+// +build !js
+
+package webrtc
+
+import (
+	"encoding/binary"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/pion/dtls/v2"
+	"github.com/pion/logging"
+	"github.com/pion/sdp/v3"
+)
+
+// sdpAttributeDTLSDataChannel is the custom attribute that is added to the
+// application media section to signal that DTLS application data should be used
+// instead of SCTP
+const sdpAttributeDTLSDataChannel = "x-dtls-datachannel"
+
+const (
+	dtlsDataChannelHeaderSize = 6
+
+	// dtlsDataChannelFragmentSize keeps a record within the default DTLS MTU
+	// with the overhead of any cipher suite
+	dtlsDataChannelFragmentSize = 1100
+
+	// dtlsDataChannelWindow is the number of fragments sent and not acknowledged
+	dtlsDataChannelWindow = 128
+
+	// dtlsDataChannelMaxBufferedAmount is the number of bytes of messages
+	// sent and not acknowledged above which Send fails
+	dtlsDataChannelMaxBufferedAmount = 4 * 1024 * 1024
+
+	dtlsDataChannelInitialRTO = 200 * time.Millisecond
+	dtlsDataChannelMaxRTO     = 3200 * time.Millisecond
+
+	// dtlsDataChannelMaxRetransmits is the number of retransmissions without
+	// an acknowledgement after which the DTLSDataChannel is closed
+	dtlsDataChannelMaxRetransmits = 10
+
+	dtlsDataChannelTypeData byte = 0
+	dtlsDataChannelTypeAck  byte = 1
+
+	dtlsDataChannelFlagEnd    byte = 1
+	dtlsDataChannelFlagString byte = 2
+)
+
+// DTLSDataChannel is a DataChannel-like stream that sends messages as DTLS
+// application data records, without SCTP. It allows talking to embedded and
+// IoT endpoints that are unable to run SCTP.
+//
+// Messages are delivered reliably and in order. Each message is split into
+// fragments of at most dtlsDataChannelFragmentSize bytes, which are sent as
+// one DTLS record each, with a 6 byte header:
+//
+//	type (1 byte): 0 for data, 1 for an acknowledgement
+//	flags (1 byte): 1 on the last fragment of a message, 2 for text messages
+//	sequence number (4 bytes, big endian)
+//
+// The sequence number of a data record counts fragments. An acknowledgement
+// carries the sequence number of the next fragment expected, and is sent for
+// every data record received. Fragments not acknowledged are retransmitted
+// with an exponential backoff. After dtlsDataChannelMaxRetransmits
+// retransmissions without an acknowledgement the DTLSDataChannel is closed,
+// and OnError is fired with ErrDTLSDataChannelRetransmitLimit.
+//
+// DTLSDataChannel is enabled by SettingEngine.SetDTLSDataChannel
+type DTLSDataChannel struct {
+	mu sync.RWMutex
+
+	conn       *dtls.Conn
+	readyState DataChannelState
+
+	// Fragments sent and not acknowledged, in order, and those waiting for
+	// the window to open
+	nextSequenceNumber uint32
+	inFlight           []*dtlsFragment
+	queued             []*dtlsFragment
+	bufferedAmount     uint64
+	rto                time.Duration
+	retransmits        int
+	retransmitTimer    *time.Timer
+
+	// Fragments received out of order, and the message being reassembled
+	expectedSequenceNumber uint32
+	received               map[uint32][]byte
+	message                []byte
+
+	onMessageHandler func(DataChannelMessage)
+	onCloseHandler   func()
+	onErrorHandler   func(error)
+
+	// closed is closed with the DTLSDataChannel, closeErr is why it was
+	// closed if it failed. readLoopDone is closed when the read loop exits,
+	// if it was started.
+	closed          chan struct{}
+	closeErr        error
+	readLoopStarted bool
+	readLoopDone    chan struct{}
+
+	log logging.LeveledLogger
+}
+
+// dtlsFragment is a data record sent by a DTLSDataChannel
+type dtlsFragment struct {
+	sequenceNumber uint32
+	record         []byte
+}
+
+func newDTLSDataChannel(conn *dtls.Conn, log logging.LeveledLogger) *DTLSDataChannel {
+	return &DTLSDataChannel{
+		conn:         conn,
+		readyState:   DataChannelStateOpen,
+		rto:          dtlsDataChannelInitialRTO,
+		received:     map[uint32][]byte{},
+		closed:       make(chan struct{}),
+		readLoopDone: make(chan struct{}),
+		log:          log,
+	}
+}
+
+// OnMessage sets an event handler which is invoked on a message arrival
+// from the remote peer. Every DTLS record is delivered as a binary message.
+func (d *DTLSDataChannel) OnMessage(f func(msg DataChannelMessage)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.onMessageHandler = f
+}
+
+func (d *DTLSDataChannel) onMessage(msg DataChannelMessage) {
+	d.mu.RLock()
+	handler := d.onMessageHandler
+	d.mu.RUnlock()
+
+	if handler != nil {
+		handler(msg)
+	}
+}
+
+// OnClose sets an event handler which is invoked when
+// the underlying DTLS transport has been closed.
+func (d *DTLSDataChannel) OnClose(f func()) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.onCloseHandler = f
+}
+
+func (d *DTLSDataChannel) onClose() {
+	d.mu.RLock()
+	handler := d.onCloseHandler
+	d.mu.RUnlock()
+
+	if handler != nil {
+		go handler()
+	}
+}
+
+// OnError sets an event handler which is invoked when
+// the underlying DTLS transport cannot be read.
+func (d *DTLSDataChannel) OnError(f func(err error)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.onErrorHandler = f
+}
+
+func (d *DTLSDataChannel) onError(err error) {
+	d.mu.RLock()
+	handler := d.onErrorHandler
+	d.mu.RUnlock()
+
+	if handler != nil {
+		go handler(err)
+	}
+}
+
+// readLoop reads the records until the DTLSDataChannel or the DTLS
+// connection is closed. The messages are delivered on another goroutine,
+// which isn't waited for by Close as the handlers may be closing the
+// DTLSDataChannel or the PeerConnection. It calls onOpen first, so the
+// handlers it sets receive every message. Messages the handlers don't take
+// before the DTLSDataChannel is closed are dropped.
+func (d *DTLSDataChannel) readLoop(onOpen func(*DTLSDataChannel)) {
+	defer close(d.readLoopDone)
+
+	d.mu.Lock()
+	d.readLoopStarted = true
+	d.mu.Unlock()
+
+	messages := make(chan DataChannelMessage)
+	go func() {
+		if onOpen != nil {
+			onOpen(d)
+		}
+		for msg := range messages {
+			d.onMessage(msg)
+		}
+
+		d.mu.RLock()
+		err := d.closeErr
+		d.mu.RUnlock()
+		if err != nil {
+			d.onError(err)
+		}
+		d.onClose()
+	}()
+	defer close(messages)
+
+	buffer := make([]byte, dataChannelBufferSize)
+	for {
+		n, err := d.conn.Read(buffer)
+		if err != nil {
+			// The read fails once Close set the deadline, or the DTLS
+			// connection is closed
+			if err == io.EOF {
+				err = nil
+			}
+			d.mu.Lock()
+			d.closeLocked(err)
+			d.mu.Unlock()
+			return
+		}
+
+		if n < dtlsDataChannelHeaderSize {
+			d.log.Warnf("Dropping DTLSDataChannel record of %d bytes", n)
+			continue
+		}
+
+		sequenceNumber := binary.BigEndian.Uint32(buffer[2:])
+		switch buffer[0] {
+		case dtlsDataChannelTypeData:
+			for _, msg := range d.handleData(sequenceNumber, buffer[:n]) {
+				select {
+				case messages <- msg:
+				case <-d.closed:
+				}
+			}
+		case dtlsDataChannelTypeAck:
+			d.handleAck(sequenceNumber)
+		default:
+			d.log.Warnf("Dropping DTLSDataChannel record of unknown type %d", buffer[0])
+		}
+	}
+}
+
+// handleData stores a data record, acknowledges it and returns the messages
+// it completes
+func (d *DTLSDataChannel) handleData(sequenceNumber uint32, record []byte) []DataChannelMessage {
+	d.mu.Lock()
+	// Duplicates and fragments beyond the window of the sender are dropped
+	if ahead := sequenceNumber - d.expectedSequenceNumber; ahead < dtlsDataChannelWindow {
+		if _, ok := d.received[sequenceNumber]; !ok {
+			d.received[sequenceNumber] = append([]byte(nil), record...)
+		}
+	}
+
+	messages := []DataChannelMessage{}
+	for {
+		fragment, ok := d.received[d.expectedSequenceNumber]
+		if !ok {
+			break
+		}
+		delete(d.received, d.expectedSequenceNumber)
+		d.expectedSequenceNumber++
+
+		if d.message == nil {
+			d.message = []byte{}
+		}
+		d.message = append(d.message, fragment[dtlsDataChannelHeaderSize:]...)
+		if flags := fragment[1]; flags&dtlsDataChannelFlagEnd != 0 {
+			messages = append(messages, DataChannelMessage{
+				IsString: flags&dtlsDataChannelFlagString != 0,
+				Data:     d.message,
+			})
+			d.message = nil
+		}
+	}
+
+	ack := make([]byte, dtlsDataChannelHeaderSize)
+	ack[0] = dtlsDataChannelTypeAck
+	binary.BigEndian.PutUint32(ack[2:], d.expectedSequenceNumber)
+	isOpen := d.readyState == DataChannelStateOpen
+	d.mu.Unlock()
+
+	d.write([][]byte{ack})
+
+	if !isOpen {
+		return nil
+	}
+	return messages
+}
+
+// handleAck drops the fragments acknowledged, and sends those waiting for
+// the window to open
+func (d *DTLSDataChannel) handleAck(nextSequenceNumber uint32) {
+	d.mu.Lock()
+	acknowledged := false
+	for len(d.inFlight) > 0 {
+		if unacknowledged := nextSequenceNumber - d.inFlight[0].sequenceNumber; unacknowledged == 0 || unacknowledged > dtlsDataChannelWindow {
+			break
+		}
+		d.bufferedAmount -= uint64(len(d.inFlight[0].record) - dtlsDataChannelHeaderSize)
+		d.inFlight = d.inFlight[1:]
+		acknowledged = true
+	}
+	if !acknowledged {
+		d.mu.Unlock()
+		return
+	}
+
+	d.rto = dtlsDataChannelInitialRTO
+	d.retransmits = 0
+	records := d.sendQueued()
+	d.startRetransmitTimer()
+	d.mu.Unlock()
+
+	d.write(records)
+}
+
+// sendQueued moves the queued fragments that fit in the window in flight,
+// and returns their records to be written
+func (d *DTLSDataChannel) sendQueued() [][]byte {
+	records := [][]byte{}
+	for len(d.queued) > 0 && len(d.inFlight) < dtlsDataChannelWindow {
+		fragment := d.queued[0]
+		d.queued = d.queued[1:]
+		d.inFlight = append(d.inFlight, fragment)
+		records = append(records, fragment.record)
+	}
+	return records
+}
+
+// write writes records without holding the lock, as writing can block.
+// Lost records are retransmitted.
+func (d *DTLSDataChannel) write(records [][]byte) {
+	for _, record := range records {
+		if _, err := d.conn.Write(record); err != nil {
+			d.log.Debugf("Failed to send DTLSDataChannel record: %v", err)
+		}
+	}
+}
+
+// startRetransmitTimer retransmits the fragments in flight once the
+// retransmission timeout passes, or stops retransmitting if there are none
+func (d *DTLSDataChannel) startRetransmitTimer() {
+	switch {
+	case len(d.inFlight) == 0:
+		d.stopRetransmitTimer()
+	case d.retransmitTimer == nil:
+		d.retransmitTimer = time.AfterFunc(d.rto, d.retransmit)
+	default:
+		d.retransmitTimer.Reset(d.rto)
+	}
+}
+
+func (d *DTLSDataChannel) stopRetransmitTimer() {
+	if d.retransmitTimer != nil {
+		d.retransmitTimer.Stop()
+	}
+}
+
+func (d *DTLSDataChannel) retransmit() {
+	d.mu.Lock()
+	if d.readyState != DataChannelStateOpen || len(d.inFlight) == 0 {
+		d.mu.Unlock()
+		return
+	}
+	if d.retransmits++; d.retransmits > dtlsDataChannelMaxRetransmits {
+		d.log.Warnf("DTLSDataChannel fragments weren't acknowledged after %d retransmissions, closing", dtlsDataChannelMaxRetransmits)
+		d.closeLocked(ErrDTLSDataChannelRetransmitLimit)
+		d.mu.Unlock()
+		return
+	}
+
+	records := make([][]byte, 0, len(d.inFlight))
+	for _, fragment := range d.inFlight {
+		records = append(records, fragment.record)
+	}
+
+	if d.rto *= 2; d.rto > dtlsDataChannelMaxRTO {
+		d.rto = dtlsDataChannelMaxRTO
+	}
+	d.retransmitTimer.Reset(d.rto)
+	d.mu.Unlock()
+
+	d.write(records)
+}
+
+// Send sends the binary message to the remote peer. It fails with
+// ErrDTLSDataChannelBufferFull if the message doesn't fit in the send buffer.
+func (d *DTLSDataChannel) Send(data []byte) error {
+	return d.send(data, false)
+}
+
+// SendText sends the text message to the remote peer
+func (d *DTLSDataChannel) SendText(s string) error {
+	return d.send([]byte(s), true)
+}
+
+func (d *DTLSDataChannel) send(data []byte, isString bool) error {
+	d.mu.Lock()
+	if d.readyState != DataChannelStateOpen {
+		d.mu.Unlock()
+		return io.ErrClosedPipe
+	}
+	if d.bufferedAmount+uint64(len(data)) > dtlsDataChannelMaxBufferedAmount {
+		d.mu.Unlock()
+		return ErrDTLSDataChannelBufferFull
+	}
+	d.bufferedAmount += uint64(len(data))
+
+	flags := byte(0)
+	if isString {
+		flags |= dtlsDataChannelFlagString
+	}
+
+	// An empty message is sent as one empty fragment
+	for {
+		size := len(data)
+		if size > dtlsDataChannelFragmentSize {
+			size = dtlsDataChannelFragmentSize
+		}
+
+		record := make([]byte, dtlsDataChannelHeaderSize+size)
+		record[0] = dtlsDataChannelTypeData
+		record[1] = flags
+		if size == len(data) {
+			record[1] |= dtlsDataChannelFlagEnd
+		}
+		binary.BigEndian.PutUint32(record[2:], d.nextSequenceNumber)
+		copy(record[dtlsDataChannelHeaderSize:], data[:size])
+
+		d.queued = append(d.queued, &dtlsFragment{sequenceNumber: d.nextSequenceNumber, record: record})
+		d.nextSequenceNumber++
+
+		if data = data[size:]; len(data) == 0 {
+			break
+		}
+	}
+
+	wasIdle := len(d.inFlight) == 0
+	records := d.sendQueued()
+	if wasIdle {
+		d.startRetransmitTimer()
+	}
+	d.mu.Unlock()
+
+	d.write(records)
+	return nil
+}
+
+// BufferedAmount returns the number of bytes of messages sent and not yet
+// acknowledged by the remote peer
+func (d *DTLSDataChannel) BufferedAmount() uint64 {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return d.bufferedAmount
+}
+
+// Close stops sending and delivering messages, and waits for the read loop
+// to exit. Messages not yet acknowledged aren't retransmitted anymore. The
+// DTLS transport is shared with media, so it is only torn down when the
+// PeerConnection is closed.
+func (d *DTLSDataChannel) Close() error {
+	d.mu.Lock()
+	d.closeLocked(nil)
+	started := d.readLoopStarted
+	d.mu.Unlock()
+
+	if started {
+		<-d.readLoopDone
+	}
+	return nil
+}
+
+// closeLocked closes the DTLSDataChannel if it is open, err is why it failed.
+// The read loop is stopped by the deadline, the DTLS connection is only read
+// by it. d.mu must be held.
+func (d *DTLSDataChannel) closeLocked(err error) {
+	if d.readyState == DataChannelStateClosed {
+		return
+	}
+
+	d.readyState = DataChannelStateClosed
+	d.closeErr = err
+	d.stopRetransmitTimer()
+	close(d.closed)
+	if deadlineErr := d.conn.SetReadDeadline(time.Now()); deadlineErr != nil {
+		d.log.Warnf("Failed to stop reading DTLSDataChannel: %v", deadlineErr)
+	}
+}
+
+// ReadyState represents the state of the DTLSDataChannel object.
+func (d *DTLSDataChannel) ReadyState() DataChannelState {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return d.readyState
+}
+
+// haveDTLSDataChannel returns true if the application media section of desc
+// requests DTLSDataChannel instead of SCTP
+func haveDTLSDataChannel(desc *sdp.SessionDescription) bool {
+	for _, m := range desc.MediaDescriptions {
+		if m.MediaName.Media != mediaSectionApplication {
+			continue
+		}
+
+		if _, ok := m.Attribute(sdpAttributeDTLSDataChannel); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// addDTLSDataChannelAttribute marks the application media section of desc as using DTLSDataChannel
+func addDTLSDataChannelAttribute(desc *sdp.SessionDescription) {
+	for _, m := range desc.MediaDescriptions {
+		if m.MediaName.Media == mediaSectionApplication {
+			m.WithPropertyAttribute(sdpAttributeDTLSDataChannel)
+		}
+	}
+}
@@ -0,0 +1,262 @@
+// +build !js
+
+package webrtc
+
+import (
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pion/dtls/v2"
+	"github.com/pion/logging"
+	"github.com/pion/transport/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDTLSDataChannel(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	s := SettingEngine{}
+	s.SetDTLSDataChannel(true)
+
+	pcOffer, pcAnswer, err := NewAPI(WithSettingEngine(s)).newPair(Configuration{})
+	assert.NoError(t, err)
+
+	offerChannel := make(chan *DTLSDataChannel, 1)
+	pcOffer.OnDTLSDataChannel(func(d *DTLSDataChannel) {
+		offerChannel <- d
+	})
+
+	// Close can be called from the handlers
+	messageReceived := make(chan DataChannelMessage, 2)
+	answerClosed := make(chan struct{})
+	pcAnswer.OnDTLSDataChannel(func(d *DTLSDataChannel) {
+		d.OnMessage(func(msg DataChannelMessage) {
+			messageReceived <- msg
+			if !msg.IsString {
+				assert.NoError(t, d.Close())
+			}
+		})
+		d.OnClose(func() {
+			close(answerClosed)
+		})
+	})
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	assert.True(t, haveDTLSDataChannel(pcOffer.CurrentRemoteDescription().parsed))
+	assert.True(t, haveDTLSDataChannel(pcAnswer.CurrentRemoteDescription().parsed))
+
+	d := <-offerChannel
+
+	// Messages larger than a fragment are reassembled
+	large := make([]byte, 3*dtlsDataChannelFragmentSize+1)
+	for i := range large {
+		large[i] = byte(i)
+	}
+	assert.NoError(t, d.SendText("hello"))
+	assert.NoError(t, d.Send(large))
+	assert.Equal(t, DataChannelMessage{IsString: true, Data: []byte("hello")}, <-messageReceived)
+	assert.Equal(t, DataChannelMessage{Data: large}, <-messageReceived)
+	<-answerClosed
+
+	// Messages which don't fit in the send buffer are rejected
+	assert.Equal(t, ErrDTLSDataChannelBufferFull, d.Send(make([]byte, dtlsDataChannelMaxBufferedAmount+1)))
+
+	assert.NoError(t, d.Close())
+	assert.Equal(t, DataChannelStateClosed, d.ReadyState())
+	assert.Error(t, d.SendText("hello"))
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestDTLSDataChannel_RemoteUnsupported(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	s := SettingEngine{}
+	s.SetDTLSDataChannel(true)
+
+	pcOffer, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	pcAnswer, err := NewAPI(WithSettingEngine(s)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	_, err = pcOffer.CreateDataChannel("data", nil)
+	assert.NoError(t, err)
+
+	offer, err := pcOffer.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, pcOffer.SetLocalDescription(offer))
+	assert.NoError(t, pcAnswer.SetRemoteDescription(offer))
+
+	answer, err := pcAnswer.CreateAnswer(nil)
+	assert.NoError(t, err)
+	assert.False(t, haveDTLSDataChannel(answer.parsed))
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+// lossyConn drops every third datagram written and every fourth read once
+// lossy is set
+type lossyConn struct {
+	net.Conn
+	lossy         int32
+	writes, reads int32
+}
+
+func (c *lossyConn) Write(b []byte) (int, error) {
+	if atomic.LoadInt32(&c.lossy) == 1 && atomic.AddInt32(&c.writes, 1)%3 == 0 {
+		return len(b), nil
+	}
+	return c.Conn.Write(b)
+}
+
+func (c *lossyConn) Read(b []byte) (int, error) {
+	for {
+		n, err := c.Conn.Read(b)
+		if err != nil || atomic.LoadInt32(&c.lossy) == 0 || atomic.AddInt32(&c.reads, 1)%4 != 0 {
+			return n, err
+		}
+	}
+}
+
+// udpPair returns two UDP sockets on the loopback interface connected to each other
+func udpPair(t *testing.T) (*net.UDPConn, *net.UDPConn) {
+	addrs := []*net.UDPAddr{}
+	for i := 0; i < 2; i++ {
+		conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		assert.NoError(t, err)
+		addrs = append(addrs, conn.LocalAddr().(*net.UDPAddr))
+		assert.NoError(t, conn.Close())
+	}
+
+	a, err := net.DialUDP("udp4", addrs[0], addrs[1])
+	assert.NoError(t, err)
+	b, err := net.DialUDP("udp4", addrs[1], addrs[0])
+	assert.NoError(t, err)
+	return a, b
+}
+
+// dtlsPair returns the DTLS connections established over clientConn and serverConn
+func dtlsPair(t *testing.T, clientConn, serverConn net.Conn) (*dtls.Conn, *dtls.Conn) {
+	config := &dtls.Config{
+		PSK: func([]byte) ([]byte, error) {
+			return []byte{0x01, 0x02, 0x03}, nil
+		},
+		PSKIdentityHint: []byte("pion"),
+		CipherSuites:    []dtls.CipherSuiteID{dtls.TLS_PSK_WITH_AES_128_CCM_8},
+	}
+
+	servers := make(chan *dtls.Conn)
+	go func() {
+		server, err := dtls.Server(serverConn, config)
+		assert.NoError(t, err)
+		servers <- server
+	}()
+	client, err := dtls.Client(clientConn, config)
+	assert.NoError(t, err)
+	server := <-servers
+	return client, server
+}
+
+func TestDTLSDataChannel_Loss(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	clientConn, serverConn := udpPair(t)
+	lossy := &lossyConn{Conn: clientConn}
+	client, server := dtlsPair(t, lossy, serverConn)
+	atomic.StoreInt32(&lossy.lossy, 1)
+
+	log := logging.NewDefaultLoggerFactory().NewLogger("test")
+	sender := newDTLSDataChannel(client, log)
+	receiver := newDTLSDataChannel(server, log)
+
+	messageReceived := make(chan DataChannelMessage, 100)
+	receiver.OnMessage(func(msg DataChannelMessage) {
+		messageReceived <- msg
+	})
+
+	readLoopsDone := make(chan struct{}, 2)
+	for _, d := range []*DTLSDataChannel{sender, receiver} {
+		go func(d *DTLSDataChannel) {
+			d.readLoop(nil)
+			readLoopsDone <- struct{}{}
+		}(d)
+	}
+
+	// Every message arrives in order, including one larger than the window
+	messages := [][]byte{{}, []byte("small"), make([]byte, dtlsDataChannelFragmentSize*(dtlsDataChannelWindow+2))}
+	for i := 0; i < 20; i++ {
+		messages = append(messages, []byte{byte(i)})
+	}
+	for _, msg := range messages {
+		assert.NoError(t, sender.Send(msg))
+	}
+	for _, msg := range messages {
+		assert.Equal(t, DataChannelMessage{Data: msg}, <-messageReceived)
+	}
+
+	// The acknowledgements are retransmitted along with the fragments
+	for sender.BufferedAmount() != 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	assert.NoError(t, client.Close())
+	assert.NoError(t, server.Close())
+	<-readLoopsDone
+	<-readLoopsDone
+}
+
+func TestDTLSDataChannel_RetransmitLimit(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	clientConn, serverConn := udpPair(t)
+	client, server := dtlsPair(t, clientConn, serverConn)
+
+	// The server isn't read, so nothing is acknowledged
+	sender := newDTLSDataChannel(client, logging.NewDefaultLoggerFactory().NewLogger("test"))
+	sender.rto = time.Millisecond
+
+	errored := make(chan error, 1)
+	sender.OnError(func(err error) {
+		errored <- err
+	})
+	closed := make(chan struct{})
+	sender.OnClose(func() {
+		close(closed)
+	})
+
+	readLoopDone := make(chan struct{})
+	go func() {
+		sender.readLoop(nil)
+		close(readLoopDone)
+	}()
+
+	assert.NoError(t, sender.SendText("hello"))
+	assert.Equal(t, ErrDTLSDataChannelRetransmitLimit, <-errored)
+	<-closed
+	<-readLoopDone
+	assert.Equal(t, DataChannelStateClosed, sender.ReadyState())
+
+	assert.NoError(t, client.Close())
+	assert.NoError(t, server.Close())
+}
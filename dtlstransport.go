@@ -17,6 +17,7 @@ import (
 
 	"github.com/pion/dtls/v2"
 	"github.com/pion/dtls/v2/pkg/crypto/fingerprint"
+	"github.com/pion/rtcp"
 	"github.com/pion/srtp"
 	"github.com/pion/webrtc/v3/internal/mux"
 	"github.com/pion/webrtc/v3/internal/util"
@@ -48,6 +49,14 @@ type DTLSTransport struct {
 
 	dtlsMatcher mux.MatchFunc
 
+	// goInternal runs the goroutines reading the streams of the transport,
+	// the PeerConnection sets it so Close waits for them
+	goInternal func(func()) bool
+
+	// rtpArrival are the handlers called when RTP of their SSRC arrives
+	rtpArrivalMu sync.RWMutex
+	rtpArrival   map[uint32]func()
+
 	api *API
 }
 
@@ -192,7 +201,7 @@ func (t *DTLSTransport) startSRTP() error {
 		return fmt.Errorf("%w: %v", errDtlsKeyExtractionFailed, err)
 	}
 
-	srtpSession, err := srtp.NewSessionSRTP(t.srtpEndpoint, srtpConfig)
+	srtpSession, err := srtp.NewSessionSRTP(&rtpArrivalConn{Conn: t.srtpEndpoint, transport: t}, srtpConfig)
 	if err != nil {
 		return fmt.Errorf("%w: %v", errFailedToStartSRTP, err)
 	}
@@ -230,6 +239,30 @@ func (t *DTLSTransport) getSRTCPSession() (*srtp.SessionSRTCP, error) {
 	return t.srtcpSession.Load().(*srtp.SessionSRTCP), nil
 }
 
+// writeRTCP sends RTCP packets to the connected peer,
+// if no peer is connected the packets are discarded
+func (t *DTLSTransport) writeRTCP(pkts []rtcp.Packet) error {
+	raw, err := rtcp.Marshal(pkts)
+	if err != nil {
+		return err
+	}
+
+	srtcpSession, err := t.getSRTCPSession()
+	if err != nil {
+		return nil
+	}
+
+	writeStream, err := srtcpSession.OpenWriteStream()
+	if err != nil {
+		return fmt.Errorf("%w: %v", errPeerConnWriteRTCPOpenWriteStream, err)
+	}
+
+	if _, err := writeStream.Write(raw); err != nil {
+		return err
+	}
+	return nil
+}
+
 func (t *DTLSTransport) role() DTLSRole {
 	// If remote has an explicit role use the inverse
 	switch t.remoteParameters.Role {
@@ -279,6 +312,11 @@ func (t *DTLSTransport) Start(remoteParameters DTLSParameters) error {
 		cert := t.certificates[0]
 		t.onStateChange(DTLSTransportStateConnecting)
 
+		profiles := t.api.settingEngine.srtpProtectionProfiles
+		if len(profiles) == 0 {
+			profiles = []dtls.SRTPProtectionProfile{dtls.SRTP_AEAD_AES_128_GCM, dtls.SRTP_AES128_CM_HMAC_SHA1_80}
+		}
+
 		return t.role(), &dtls.Config{
 			Certificates: []tls.Certificate{
 				{
@@ -286,7 +324,7 @@ func (t *DTLSTransport) Start(remoteParameters DTLSParameters) error {
 					PrivateKey:  cert.privateKey,
 				},
 			},
-			SRTPProtectionProfiles: []dtls.SRTPProtectionProfile{dtls.SRTP_AEAD_AES_128_GCM, dtls.SRTP_AES128_CM_HMAC_SHA1_80},
+			SRTPProtectionProfiles: profiles,
 			ClientAuth:             dtls.RequireAnyClientCert,
 			LoggerFactory:          t.api.settingEngine.LoggerFactory,
 			InsecureSkipVerify:     true,
@@ -365,6 +403,16 @@ func (t *DTLSTransport) Start(remoteParameters DTLSParameters) error {
 	return err
 }
 
+// goStreamReader runs f, which reads a stream of the transport until the
+// stream is closed, on a goroutine
+func (t *DTLSTransport) goStreamReader(f func()) {
+	if t.goInternal == nil || !t.goInternal(f) {
+		// Without a PeerConnection, or once it is closed, which closes the
+		// streams so f returns
+		go f()
+	}
+}
+
 // Stop stops and closes the DTLSTransport object.
 func (t *DTLSTransport) Stop() error {
 	t.lock.Lock()
@@ -4,6 +4,7 @@ package webrtc
 
 import (
 	"context"
+	"errors"
 	"regexp"
 	"testing"
 	"time"
@@ -36,9 +37,11 @@ func TestInvalidFingerprintCausesFailed(t *testing.T) {
 		}
 	})
 
+	var connectionError error
 	connectionHasFailed, closeFunc := context.WithCancel(context.Background())
 	pcAnswer.OnConnectionStateChange(func(connectionState PeerConnectionState) {
 		if connectionState == PeerConnectionStateFailed {
+			connectionError = pcAnswer.GetConnectionError()
 			closeFunc()
 		}
 	})
@@ -86,6 +89,9 @@ func TestInvalidFingerprintCausesFailed(t *testing.T) {
 	case <-time.After(30 * time.Second):
 		t.Fatal("timed out waiting for connection to fail")
 	}
+
+	// The failure is explained to the handler
+	assert.True(t, errors.Is(connectionError, ErrDTLSTransportFailed))
 }
 
 func TestPeerConnection_DTLSRoleSettingEngine(t *testing.T) {
@@ -0,0 +1,328 @@
+// +build !js
+
+package webrtc
+
+import (
+	"encoding/binary"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pion/rtp"
+)
+
+const (
+	// dtmfTones are the tones of the DTMF events 0-15 of RFC 4733
+	dtmfTones = "0123456789*#ABCD"
+
+	// dtmfPause is a tone of InsertDTMF that sends nothing for 2 seconds
+	dtmfPause         = ','
+	dtmfPauseDuration = 2 * time.Second
+
+	dtmfMinDuration = 40 * time.Millisecond
+	dtmfMaxDuration = 6 * time.Second
+	dtmfMinGap      = 30 * time.Millisecond
+
+	// dtmfPacketInterval is the interval of the packets of an event, which
+	// update its duration
+	dtmfPacketInterval = 50 * time.Millisecond
+
+	// dtmfEndRetransmits is how many times the end of an event is sent, so
+	// it survives packet loss
+	dtmfEndRetransmits = 3
+
+	dtmfVolume           = 10
+	dtmfPayloadSize      = 4
+	dtmfEndBit           = 0x80
+	dtmfMaxEventDuration = 0xFFFF
+)
+
+// telephoneEventPayloader sends the payloads of telephone-event samples as is,
+// each sample is an event of RFC 4733
+type telephoneEventPayloader struct{}
+
+func (telephoneEventPayloader) Payload(mtu int, payload []byte) [][]byte {
+	if len(payload) == 0 {
+		return nil
+	}
+	return [][]byte{append([]byte{}, payload...)}
+}
+
+// dtmfSequencer interleaves the DTMF events of an RTPSender with its media. The
+// sequence numbers of the media are shifted by the number of event packets.
+type dtmfSequencer struct {
+	mu sync.Mutex
+
+	// inserting serializes calls of InsertDTMF
+	inserting sync.Mutex
+
+	hasSent              bool
+	sequenceNumberOffset uint16
+	lastSequenceNumber   uint16
+	lastTimestamp        uint32
+	lastSent             time.Time
+}
+
+// rewrite returns the header of a media packet with the sequence number
+// after the event packets sent so far
+func (d *dtmfSequencer) rewrite(header *rtp.Header) *rtp.Header {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.sequenceNumberOffset != 0 {
+		rewritten := *header
+		rewritten.SequenceNumber += d.sequenceNumberOffset
+		header = &rewritten
+	}
+
+	d.hasSent = true
+	d.lastSequenceNumber = header.SequenceNumber
+	d.lastTimestamp = header.Timestamp
+	d.lastSent = time.Now()
+	return header
+}
+
+// next returns the sequence number of an event packet, and the media
+// timestamp of now
+func (d *dtmfSequencer) next(clockRate uint32) (sequenceNumber uint16, timestamp uint32) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.sequenceNumberOffset++
+	d.lastSequenceNumber++
+	elapsed := time.Since(d.lastSent)
+	return d.lastSequenceNumber, d.lastTimestamp + uint32(elapsed.Seconds()*float64(clockRate))
+}
+
+// InsertDTMF sends the DTMF tones, which are 0-9, *, #, A-D and the pause ',',
+// as telephone-events of RFC 4733 interleaved with the media of the Track.
+// Each tone lasts duration, between 40ms and 6s, and is followed by gap, of at
+// least 30ms. A telephone-event codec with the clockrate of the Track must be
+// registered in the MediaEngine, and media must have been sent already.
+// InsertDTMF returns once all the tones are sent, DTMF queues them instead.
+func (r *RTPSender) InsertDTMF(tones string, duration, gap time.Duration) error {
+	tones, err := parseDTMFTones(tones)
+	if err != nil {
+		return err
+	}
+	duration, gap = clampDTMF(duration, gap)
+
+	r.dtmf.inserting.Lock()
+	defer r.dtmf.inserting.Unlock()
+
+	if _, _, err = r.dtmfCodec(); err != nil {
+		return err
+	}
+	for _, tone := range tones {
+		if err = r.playDTMFTone(tone, duration, gap); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseDTMFTones returns the tones in upper case, or an error if one of them
+// isn't a DTMF tone
+func parseDTMFTones(tones string) (string, error) {
+	tones = strings.ToUpper(tones)
+	for _, tone := range tones {
+		if !strings.ContainsRune(dtmfTones, tone) && tone != dtmfPause {
+			return "", errRTPSenderDTMFInvalidTone
+		}
+	}
+	return tones, nil
+}
+
+// clampDTMF returns the duration and gap of tones within their limits
+func clampDTMF(duration, gap time.Duration) (time.Duration, time.Duration) {
+	switch {
+	case duration < dtmfMinDuration:
+		duration = dtmfMinDuration
+	case duration > dtmfMaxDuration:
+		duration = dtmfMaxDuration
+	}
+	if gap < dtmfMinGap {
+		gap = dtmfMinGap
+	}
+	return duration, gap
+}
+
+// dtmfCodec returns the payload type of the telephone-event codec with the
+// clockrate of the Track, or an error if DTMF can't be sent
+func (r *RTPSender) dtmfCodec() (payloadType uint8, clockRate uint32, err error) {
+	clockRate = r.Track().Codec().ClockRate
+	payloadType, ok := telephoneEventPayloadTypes(r.api.getMediaEngine())[clockRate]
+	if !ok {
+		return 0, 0, errRTPSenderDTMFNoCodec
+	}
+
+	r.dtmf.mu.Lock()
+	hasSent := r.dtmf.hasSent
+	r.dtmf.mu.Unlock()
+	if !hasSent {
+		return 0, 0, errRTPSenderDTMFNotSending
+	}
+	return payloadType, clockRate, nil
+}
+
+// playDTMFTone sends a tone followed by gap, or waits 2 seconds for a pause.
+// The caller holds the inserting lock.
+func (r *RTPSender) playDTMFTone(tone rune, duration, gap time.Duration) error {
+	if tone == dtmfPause {
+		return r.waitDTMF(dtmfPauseDuration)
+	}
+
+	payloadType, clockRate, err := r.dtmfCodec()
+	if err != nil {
+		return err
+	}
+	event := uint8(strings.IndexRune(dtmfTones, tone))
+	if err = r.sendDTMFEvent(event, payloadType, r.SSRC(), clockRate, duration); err != nil {
+		return err
+	}
+	return r.waitDTMF(gap)
+}
+
+// sendDTMFEvent sends the packets of an event every 50ms until its end
+func (r *RTPSender) sendDTMFEvent(event, payloadType uint8, ssrc, clockRate uint32, duration time.Duration) error {
+	var timestamp uint32
+	for elapsed, packets := dtmfPacketInterval, 0; ; elapsed += dtmfPacketInterval {
+		end := elapsed >= duration
+		if end {
+			elapsed = duration
+		}
+
+		eventDuration := elapsed.Seconds() * float64(clockRate)
+		if eventDuration > dtmfMaxEventDuration {
+			eventDuration = dtmfMaxEventDuration
+		}
+		payload := make([]byte, dtmfPayloadSize)
+		payload[0] = event
+		payload[1] = dtmfVolume
+		if end {
+			payload[1] |= dtmfEndBit
+		}
+		binary.BigEndian.PutUint16(payload[2:], uint16(eventDuration))
+
+		for i := 0; i == 0 || (end && i < dtmfEndRetransmits); i++ {
+			sequenceNumber, now := r.dtmf.next(clockRate)
+			if packets == 0 {
+				timestamp = now
+			}
+			header := &rtp.Header{
+				Version:        2,
+				Marker:         packets == 0,
+				PayloadType:    payloadType,
+				SequenceNumber: sequenceNumber,
+				Timestamp:      timestamp,
+				SSRC:           ssrc,
+			}
+			if err := r.sendDTMFPacket(header, payload); err != nil {
+				return err
+			}
+			packets++
+		}
+
+		if end {
+			return nil
+		}
+		if err := r.waitDTMF(dtmfPacketInterval); err != nil {
+			return err
+		}
+	}
+}
+
+func (r *RTPSender) sendDTMFPacket(header *rtp.Header, payload []byte) error {
+	if r.paused.get() || r.inactive.get() || r.mute.muted.get() {
+		return nil
+	}
+	_, err := r.write(header, payload)
+	return err
+}
+
+// waitDTMF waits for d, or returns an error if the RTPSender is stopped
+func (r *RTPSender) waitDTMF(d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-r.stopCalled:
+		return errRTPSenderStopped
+	}
+}
+
+// telephoneEventPayloadTypes returns the payload types of the telephone-event
+// codecs of m by clockrate
+func telephoneEventPayloadTypes(m *MediaEngine) map[uint32]uint8 {
+	payloadTypes := map[uint32]uint8{}
+	for _, codec := range m.GetCodecsByName(TelephoneEvent) {
+		if _, ok := payloadTypes[codec.ClockRate]; !ok {
+			payloadTypes[codec.ClockRate] = codec.PayloadType
+		}
+	}
+	return payloadTypes
+}
+
+// dtmfReceiver calls the OnDTMF handler of an RTPReceiver with the events
+// read from its Track
+type dtmfReceiver struct {
+	mu      sync.Mutex
+	handler func(tone rune, duration time.Duration)
+
+	// clockRates of the telephone-event codecs by payload type
+	clockRates map[uint8]uint32
+
+	hasEnded         bool
+	lastEndTimestamp uint32
+}
+
+// OnDTMF sets an event handler which is called with the DTMF tones of RFC 4733
+// telephone-events when they end. It is called while the Track is read, the
+// telephone-event packets are read too. A telephone-event codec must be
+// registered in the MediaEngine.
+func (r *RTPReceiver) OnDTMF(f func(tone rune, duration time.Duration)) {
+	clockRates := map[uint8]uint32{}
+	for _, codec := range r.api.getMediaEngine().GetCodecsByName(TelephoneEvent) {
+		clockRates[codec.PayloadType] = codec.ClockRate
+	}
+
+	r.dtmf.mu.Lock()
+	defer r.dtmf.mu.Unlock()
+	r.dtmf.handler = f
+	r.dtmf.clockRates = clockRates
+}
+
+// handleDTMF calls the OnDTMF handler if packet, which was read, ends an
+// event
+func (r *RTPReceiver) handleDTMF(packet *rtp.Packet) {
+	r.dtmf.mu.Lock()
+	handler, clockRates := r.dtmf.handler, r.dtmf.clockRates
+	r.dtmf.mu.Unlock()
+	if handler == nil {
+		return
+	}
+
+	clockRate, ok := clockRates[packet.PayloadType]
+	if !ok || len(packet.Payload) < dtmfPayloadSize {
+		return
+	}
+	event, flags := packet.Payload[0], packet.Payload[1]
+	if flags&dtmfEndBit == 0 || int(event) >= len(dtmfTones) {
+		return
+	}
+
+	// The end of an event is sent several times
+	r.dtmf.mu.Lock()
+	repeated := r.dtmf.hasEnded && r.dtmf.lastEndTimestamp == packet.Timestamp
+	r.dtmf.hasEnded = true
+	r.dtmf.lastEndTimestamp = packet.Timestamp
+	r.dtmf.mu.Unlock()
+	if repeated {
+		return
+	}
+
+	eventDuration := binary.BigEndian.Uint16(packet.Payload[2:])
+	handler(rune(dtmfTones[event]), time.Duration(eventDuration)*time.Second/time.Duration(clockRate))
+}
@@ -0,0 +1,120 @@
+// +build !js
+
+package webrtc
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3/pkg/interceptor"
+	"github.com/stretchr/testify/assert"
+)
+
+func dtmfAPI() *API {
+	m := MediaEngine{}
+	m.RegisterCodec(NewRTPOpusCodec(DefaultPayloadTypeOpus, 48000))
+	m.RegisterCodec(NewRTPTelephoneEventCodec(110, 48000))
+	return NewAPI(WithMediaEngine(m))
+}
+
+// newDTMFSender returns an RTPSender of an Opus Track, and the packets written
+// by it
+func newDTMFSender(t *testing.T) (*RTPSender, *Track, func() []*rtp.Packet) {
+	api := dtmfAPI()
+	track, err := NewTrack(DefaultPayloadTypeOpus, 1234, "audio", "pion", NewRTPOpusCodec(DefaultPayloadTypeOpus, 48000))
+	assert.NoError(t, err)
+
+	var mu sync.Mutex
+	written := []*rtp.Packet{}
+	sender := &RTPSender{
+		track:      track,
+		api:        api,
+		sendCalled: make(chan interface{}),
+		stopCalled: make(chan interface{}),
+	}
+	sender.streamInfo = createStreamInfo(track.ID(), track.SSRC(), track.PayloadType(), track.Codec())
+	sender.rtpWriter = api.interceptor.BindLocalStream(&sender.streamInfo, interceptor.RTPWriterFunc(func(header *rtp.Header, payload []byte) (int, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		written = append(written, &rtp.Packet{Header: *header, Payload: payload})
+		return header.MarshalSize() + len(payload), nil
+	}))
+	close(sender.sendCalled)
+	track.activeSenders = append(track.activeSenders, sender)
+	track.totalSenderCount++
+
+	return sender, track, func() []*rtp.Packet {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]*rtp.Packet{}, written...)
+	}
+}
+
+func TestRTPSender_InsertDTMF(t *testing.T) {
+	sender, track, written := newDTMFSender(t)
+
+	assert.Equal(t, errRTPSenderDTMFInvalidTone, sender.InsertDTMF("1x", time.Second, time.Second))
+	assert.Equal(t, errRTPSenderDTMFNotSending, sender.InsertDTMF("1", time.Second, time.Second))
+
+	media := &rtp.Packet{Header: rtp.Header{Version: 2, PayloadType: DefaultPayloadTypeOpus, SequenceNumber: 10, Timestamp: 1000, SSRC: 1234}, Payload: []byte{0x01}}
+	assert.NoError(t, track.WriteRTP(media))
+	assert.NoError(t, sender.InsertDTMF("#", 100*time.Millisecond, 0))
+	media.SequenceNumber++
+	assert.NoError(t, track.WriteRTP(media))
+
+	// An update after 50ms, and the end sent 3 times, with the timestamp of the start
+	assert.Equal(t, 6, len(written()))
+	events := written()[1:5]
+	for i, p := range events {
+		assert.Equal(t, uint8(110), p.PayloadType)
+		assert.Equal(t, uint16(11+i), p.SequenceNumber)
+		assert.Equal(t, events[0].Timestamp, p.Timestamp)
+		assert.Equal(t, i == 0, p.Marker)
+	}
+	assert.True(t, events[0].Timestamp >= 1000)
+	assert.Equal(t, []byte{11, dtmfVolume, 0x09, 0x60}, events[0].Payload)
+	assert.Equal(t, []byte{11, dtmfEndBit | dtmfVolume, 0x12, 0xC0}, events[3].Payload)
+
+	// The media after the events is shifted, the packet of the caller isn't
+	assert.Equal(t, uint16(15), written()[5].SequenceNumber)
+	assert.Equal(t, uint16(11), media.SequenceNumber)
+
+	// Without a telephone-event codec of the clockrate of the Track
+	var err error
+	sender.track, err = NewTrack(DefaultPayloadTypePCMU, 1234, "audio", "pion", NewRTPPCMUCodec(DefaultPayloadTypePCMU, 8000))
+	assert.NoError(t, err)
+	assert.Equal(t, errRTPSenderDTMFNoCodec, sender.InsertDTMF("1", time.Second, time.Second))
+}
+
+func TestRTPReceiver_OnDTMF(t *testing.T) {
+	receiver := &RTPReceiver{api: dtmfAPI()}
+
+	type tone struct {
+		tone     rune
+		duration time.Duration
+	}
+	tones := []tone{}
+	receiver.OnDTMF(func(t rune, duration time.Duration) {
+		tones = append(tones, tone{t, duration})
+	})
+
+	read := func(payloadType uint8, timestamp uint32, payload ...byte) {
+		receiver.handleDTMF(&rtp.Packet{Header: rtp.Header{Version: 2, PayloadType: payloadType, Timestamp: timestamp}, Payload: payload})
+	}
+
+	read(DefaultPayloadTypeOpus, 0, 0x01, 0x80, 0x00, 0x00)
+	read(110, 100, 1, dtmfVolume, 0x09, 0x60)
+	for i := 0; i < 3; i++ {
+		read(110, 100, 1, dtmfEndBit|dtmfVolume, 0x12, 0xC0)
+	}
+	read(110, 9000, 12, dtmfEndBit|dtmfVolume, 0x12, 0xC0)
+	read(110, 18000, 15, dtmfEndBit|dtmfVolume, 0x25, 0x80)
+
+	assert.Equal(t, []tone{
+		{'1', 100 * time.Millisecond},
+		{'A', 100 * time.Millisecond},
+		{'D', 200 * time.Millisecond},
+	}, tones)
+}
@@ -0,0 +1,129 @@
+// +build !js
+
+package webrtc
+
+import (
+	"sync"
+	"time"
+)
+
+// DTMFSender sends DTMF tones with an RTPSender like the RTCDTMFSender of
+// browsers. Inserted tones are queued in a tone buffer, and played one after
+// the other on a goroutine.
+type DTMFSender struct {
+	sender *RTPSender
+
+	mu           sync.Mutex
+	toneBuffer   string
+	duration     time.Duration
+	interToneGap time.Duration
+	playing      bool
+	onToneChange func(tone string)
+}
+
+// DTMF returns the DTMFSender of the RTPSender
+func (r *RTPSender) DTMF() *DTMFSender {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.dtmfSender == nil {
+		r.dtmfSender = &DTMFSender{sender: r}
+	}
+	return r.dtmfSender
+}
+
+// CanInsertDTMF tells if tones can be inserted, which requires a
+// telephone-event codec with the clockrate of the Track and media to have
+// been sent
+func (d *DTMFSender) CanInsertDTMF() bool {
+	select {
+	case <-d.sender.stopCalled:
+		return false
+	default:
+	}
+
+	_, _, err := d.sender.dtmfCodec()
+	return err == nil
+}
+
+// InsertDTMF replaces the tone buffer with tones, which are 0-9, *, #, A-D and
+// the pause ','. A tone being played isn't interrupted. Each tone lasts
+// duration, between 40ms and 6s, and is followed by interToneGap, of at least
+// 30ms. Browsers use 100ms and 70ms by default.
+func (d *DTMFSender) InsertDTMF(tones string, duration, interToneGap time.Duration) error {
+	select {
+	case <-d.sender.stopCalled:
+		return errRTPSenderStopped
+	default:
+	}
+
+	tones, err := parseDTMFTones(tones)
+	if err != nil {
+		return err
+	}
+	if _, _, err = d.sender.dtmfCodec(); err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.toneBuffer = tones
+	d.duration, d.interToneGap = clampDTMF(duration, interToneGap)
+	if !d.playing && tones != "" {
+		d.playing = true
+		go d.play()
+	}
+	return nil
+}
+
+// ToneBuffer returns the tones that remain to be played
+func (d *DTMFSender) ToneBuffer() string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.toneBuffer
+}
+
+// OnToneChange sets an event handler which is called with each tone when it
+// starts to be played, and with "" once the tone buffer is empty
+func (d *DTMFSender) OnToneChange(f func(tone string)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.onToneChange = f
+}
+
+// play plays the tones of the tone buffer until it is empty, or the
+// RTPSender stops
+func (d *DTMFSender) play() {
+	for {
+		d.mu.Lock()
+		handler := d.onToneChange
+		if d.toneBuffer == "" {
+			d.playing = false
+			d.mu.Unlock()
+			if handler != nil {
+				handler("")
+			}
+			return
+		}
+
+		tone := rune(d.toneBuffer[0])
+		d.toneBuffer = d.toneBuffer[1:]
+		duration, interToneGap := d.duration, d.interToneGap
+		d.mu.Unlock()
+
+		if handler != nil {
+			handler(string(tone))
+		}
+
+		d.sender.dtmf.inserting.Lock()
+		err := d.sender.playDTMFTone(tone, duration, interToneGap)
+		d.sender.dtmf.inserting.Unlock()
+		if err != nil {
+			d.mu.Lock()
+			d.toneBuffer = ""
+			d.playing = false
+			d.mu.Unlock()
+			return
+		}
+	}
+}
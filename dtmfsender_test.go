@@ -0,0 +1,62 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDTMFSender(t *testing.T) {
+	sender, track, written := newDTMFSender(t)
+	dtmf := sender.DTMF()
+	assert.Equal(t, dtmf, sender.DTMF())
+
+	assert.False(t, dtmf.CanInsertDTMF())
+	assert.Equal(t, errRTPSenderDTMFNotSending, dtmf.InsertDTMF("1", 0, 0))
+	assert.NoError(t, track.WriteRTP(&rtp.Packet{Header: rtp.Header{Version: 2, SequenceNumber: 10}}))
+	assert.True(t, dtmf.CanInsertDTMF())
+	assert.Equal(t, errRTPSenderDTMFInvalidTone, dtmf.InsertDTMF("1x", 0, 0))
+
+	tones := make(chan string, 8)
+	dtmf.OnToneChange(func(tone string) {
+		tones <- tone
+	})
+
+	// Inserting replaces the tone buffer, the tone being played finishes
+	assert.NoError(t, dtmf.InsertDTMF("12", 200*time.Millisecond, 0))
+	assert.Equal(t, "1", <-tones)
+	assert.Equal(t, "2", dtmf.ToneBuffer())
+	assert.NoError(t, dtmf.InsertDTMF("a", 0, 0))
+	assert.Equal(t, "A", dtmf.ToneBuffer())
+	assert.Equal(t, "A", <-tones)
+	assert.Equal(t, "", <-tones)
+	assert.Equal(t, "", dtmf.ToneBuffer())
+
+	// A tone of 200ms is sent as 3 updates and 3 ends, the shortest tones of
+	// 40ms only as 3 ends
+	events := []uint8{}
+	for _, p := range written()[1:] {
+		if p.Payload[1]&dtmfEndBit != 0 {
+			events = append(events, p.Payload[0])
+		}
+	}
+	assert.Equal(t, []uint8{1, 1, 1, 12, 12, 12}, events)
+	assert.Equal(t, 1+6+3, len(written()))
+
+	// Stopping the RTPSender empties the tone buffer
+	assert.NoError(t, dtmf.InsertDTMF("1,2", 0, 0))
+	assert.Equal(t, "1", <-tones)
+	close(sender.stopCalled)
+	select {
+	case tone := <-tones:
+		t.Fatalf("tone %q played after the RTPSender stopped", tone)
+	case <-time.After(200 * time.Millisecond):
+	}
+	assert.Equal(t, "", dtmf.ToneBuffer())
+	assert.False(t, dtmf.CanInsertDTMF())
+	assert.Equal(t, errRTPSenderStopped, dtmf.InsertDTMF("1", 0, 0))
+}
@@ -12,6 +12,9 @@ var (
 	// has already been closed.
 	ErrConnectionClosed = errors.New("connection closed")
 
+	// ErrAPIClosed indicates an API object was used after API.Close was called
+	ErrAPIClosed = errors.New("api closed")
+
 	// ErrDataChannelNotOpen indicates an operation executed when the data
 	// channel is not (yet) open.
 	ErrDataChannelNotOpen = errors.New("data channel not open")
@@ -76,6 +79,15 @@ var (
 	// ErrCodecNotFound is returned when a codec search to the Media Engine fails
 	ErrCodecNotFound = errors.New("codec not found")
 
+	// ErrPayloadTypeConflict is returned by MediaEngine.RegisterCodecStrict
+	// when the payload type of a codec is used by another codec
+	ErrPayloadTypeConflict = errors.New("payload type used by another codec")
+
+	// ErrNoFreePayloadType is returned by MediaEngine.RegisterCodec when the
+	// payload type of a codec is used by another codec, and so are all the
+	// dynamic payload types it could be moved to
+	ErrNoFreePayloadType = errors.New("no free dynamic payload type")
+
 	// ErrNoRemoteDescription indicates that an operation was rejected because
 	// the remote description is not set
 	ErrNoRemoteDescription = errors.New("remote description is not set")
@@ -129,6 +141,29 @@ var (
 	// ErrFailedToGenerateCertificateFingerprint indicates that we failed to generate the fingerprint used for comparing certificates
 	ErrFailedToGenerateCertificateFingerprint = errors.New("failed to generate certificate fingerprint")
 
+	// ErrICEConnectionFailed indicates a PeerConnection failed as ICE found
+	// no candidate pair that works, like when a firewall blocks the traffic
+	ErrICEConnectionFailed = errors.New("ice connectivity checks failed")
+
+	// ErrDTLSTransportFailed indicates a PeerConnection failed as the DTLS
+	// handshake failed or its certificate didn't match the fingerprint
+	ErrDTLSTransportFailed = errors.New("dtls transport failed")
+
+	// ErrSCTPAssociationFailed indicates the SCTP association of the
+	// DataChannels couldn't be established or was aborted
+	ErrSCTPAssociationFailed = errors.New("sctp association failed")
+
+	// ErrDTLSDataChannelBufferFull indicates that a message doesn't fit in
+	// the send buffer of a DTLSDataChannel, see BufferedAmount
+	ErrDTLSDataChannelBufferFull = errors.New("DTLSDataChannel send buffer is full")
+
+	// ErrDTLSDataChannelRetransmitLimit indicates that a DTLSDataChannel was
+	// closed as the remote didn't acknowledge the fragments retransmitted
+	ErrDTLSDataChannelRetransmitLimit = errors.New("DTLSDataChannel fragments weren't acknowledged")
+
+	errDataChannelTokenInvalid = errors.New("DataChannel token is invalid")
+	errDataChannelTokenExpired = errors.New("DataChannel token has expired")
+
 	errDetachNotEnabled                 = errors.New("enable detaching by calling webrtc.DetachDataChannels()")
 	errDetachBeforeOpened               = errors.New("datachannel not opened yet, try calling Detach from OnOpen")
 	errDtlsTransportNotStarted          = errors.New("the DTLS transport has not started yet")
@@ -148,10 +183,30 @@ var (
 	errICERoleUnknown                 = errors.New("unknown ICE Role")
 	errICEProtocolUnknown             = errors.New("unknown protocol")
 	errICEGathererNotStarted          = errors.New("gatherer not started")
+	errICETransportStopped            = errors.New("ICETransport has been stopped")
+	errICEGathererHostOnlyICEServers  = errors.New("ICE servers must not be configured in host only mode")
+	errICEGathererHostOnlyNoRoute     = errors.New("no network interface to gather host candidates from in host only mode")
+	errICEProxySchemeUnsupported      = errors.New("ICE proxy URL must be http, https or socks5")
+	errICEProxyConnectFailed          = errors.New("ICE proxy refused to CONNECT")
+	errICEResolverNoAddress           = errors.New("ICE resolver returned no address")
+
+	errListenerClosed         = errors.New("the Listener is closed")
+	errListenerConnectTimeout = errors.New("PeerConnection of the Listener did not connect in time")
+	errListenerConnectFailed  = errors.New("PeerConnection of the Listener failed to connect")
+
+	errSignalerUnexpectedDescription = errors.New("received a session description of unexpected type")
 
 	errMediaEngineParseError    = errors.New("format parse error")
 	errMediaEngineCodecNotFound = errors.New("could not find codec")
-	errNetworkTypeUnknown       = errors.New("unknown network type")
+
+	errMulticastSourceRemoteTrack  = errors.New("a MulticastSource must write to a local track")
+	errMulticastSourceGroupCount   = errors.New("a MulticastSource must have one or two groups")
+	errMulticastSourceNotMulticast = errors.New("the address of a MulticastGroup must be a multicast address")
+
+	errNetworkTypeUnknown = errors.New("unknown network type")
+
+	errOpusChannelsInvalid       = errors.New("Opus must have 2 channels, multiopus between 3 and 255")
+	errOpusChannelMappingInvalid = errors.New("the streams and channel_mapping of multiopus don't match its channels")
 
 	errSDPDoesNotMatchOffer                           = errors.New("new sdp does not match previous offer")
 	errSDPDoesNotMatchAnswer                          = errors.New("new sdp does not match previous answer")
@@ -168,27 +223,41 @@ var (
 	errPeerConnAddTransceiverFromKindOnlyAcceptsOne   = errors.New("AddTransceiverFromKind only accepts one RtpTransceiverInit")
 	errPeerConnAddTransceiverFromTrackOnlyAcceptsOne  = errors.New("AddTransceiverFromTrack only accepts one RtpTransceiverInit")
 	errPeerConnCodecsNotFound                         = errors.New("no codecs found")
-	errPeerConnAddTransceiverFromKindSupport          = errors.New("AddTransceiverFromKind currently only supports recvonly and sendrecv")
-	errPeerConnAddTransceiverFromTrackOneTransceiver  = errors.New("AddTransceiverFromTrack only accepts one RtpTransceiverInit")
+	errPeerConnICECandidateUfragMismatch              = errors.New("usernameFragment of the ICE candidate isn't the one of the remote description")
+	errPeerConnConnectFailed                          = errors.New("peer connection failed before it was connected")
+	errPeerConnAddTransceiverFromKindSupport          = errors.New("AddTransceiverFromKind only supports the sendrecv, sendonly, recvonly and inactive directions")
+	errPeerConnAddTransceiverFromTrackSupport         = errors.New("AddTransceiverFromTrack only supports the sendrecv, sendonly, recvonly and inactive directions")
 	errPeerConnSetIdentityProviderNotImplemented      = errors.New("TODO SetIdentityProvider")
 	errPeerConnWriteRTCPOpenWriteStream               = errors.New("WriteRTCP failed to open WriteStream")
 	errPeerConnCodecPayloaderNotSet                   = errors.New("codec payloader not set")
 	errPeerConnTranscieverMidNil                      = errors.New("cannot find transceiver with mid")
+	errPeerConnRTCPMuxRequired                        = errors.New("remote description doesn't multiplex RTCP, which an explicit RTCPMuxPolicyRequire requires")
 
 	errRTPReceiverDTLSTransportNil            = errors.New("DTLSTransport must not be nil")
 	errRTPReceiverReceiveAlreadyCalled        = errors.New("Receive has already been called")
 	errRTPReceiverWithSSRCTrackStreamNotFound = errors.New("unable to find stream for Track with SSRC")
 	errRTPReceiverForSSRCTrackStreamNotFound  = errors.New("no trackStreams found for SSRC")
 	errRTPReceiverForRIDTrackStreamNotFound   = errors.New("no trackStreams found for RID")
+	errRTPReceiverRTCPSSRCMismatch            = errors.New("RTCP packet isn't for a Track of the RTPReceiver")
+	errRTPReceiverStopped                     = errors.New("RTPReceiver has been stopped")
 
 	errRTPSenderTrackNil                   = errors.New("Track must not be nil")
 	errRTPSenderDTLSTransportNil           = errors.New("DTLSTransport must not be nil")
 	errRTPSenderCannotConstructRemoteTrack = errors.New("RTPSender can not be constructed with remote track")
 	errRTPSenderSendAlreadyCalled          = errors.New("Send has already been called")
 	errRTPSenderStopped                    = errors.New("RTPSender has been stopped")
+	errRTPSenderModifyingCoding            = errors.New("RID, SSRC and PayloadType of an RTPSender cannot be modified")
+	errRTPSenderEncodingInvalid            = errors.New("MaxFramerate must not be negative, ScaleResolutionDownBy must be 0 or at least 1 and MinBitrate must not exceed MaxBitrate")
+	errRTPSenderSendEncodingsSimulcast     = errors.New("an RTPSender sends a single encoding, simulcast isn't supported")
+	errRTPSenderDTMFInvalidTone            = errors.New("DTMF tones must be 0-9, *, #, A-D or ,")
+	errRTPSenderDTMFNoCodec                = errors.New("no telephone-event codec with the clockrate of the Track is registered")
+	errRTPSenderDTMFNotSending             = errors.New("DTMF can't be sent before media")
+	errRTPSenderRTCPSSRCMismatch           = errors.New("RTCP packet isn't for the SSRC of the RTPSender")
 
 	errRTPTransceiverCannotChangeMid        = errors.New("errRTPSenderTrackNil")
 	errRTPTransceiverSetSendingInvalidState = errors.New("invalid state change in RTPTransceiver.setSending")
+	errRTPTransceiverSetDirectionNoSender   = errors.New("RTPTransceiver can't send without an RTPSender")
+	errRTPTransceiverSetDirectionUnknown    = errors.New("unknown RTPTransceiverDirection")
 
 	errSCTPTransportDTLS = errors.New("DTLS not established")
 
@@ -197,6 +266,7 @@ var (
 	errSDPMediaSectionMultipleTrackInvalid = errors.New("invalid Media Section. Can not have multiple tracks in one MediaSection in UnifiedPlan")
 	errSDPParseExtMap                      = errors.New("failed to parse ExtMap")
 	errSDPRemoteDescriptionChangedExtMap   = errors.New("RemoteDescription changed some extmaps values")
+	errSDPMungerChangedCredentials         = errors.New("SDP munger changed the ICE credentials or DTLS fingerprints")
 
 	errSettingEngineSetAnsweringDTLSRole = errors.New("SetAnsweringDTLSRole must DTLSRoleClient or DTLSRoleServer")
 
@@ -208,4 +278,16 @@ var (
 	errTrackLocalTrackRead   = errors.New("this is a local track and must not be read from")
 	errTrackLocalTrackWrite  = errors.New("this is a remote track and must not be written to")
 	errTrackSSRCNewTrackZero = errors.New("SSRC supplied to NewTrack() must be non-zero")
+
+	errTrackPlayoutDelayInvalid  = errors.New("playout delay must be between 0 and 40.95s with min <= max")
+	errTrackExtensionRemoteTrack = errors.New("header extensions of a remote track can't be set")
+
+	errTrackHandlerPanic = errors.New("OnTrack handler panicked")
+
+	errTrackRelayRemoteTrack = errors.New("a TrackRelay must forward to a local track")
+	errTrackRelayLocalSource = errors.New("the source of a TrackRelay must be a remote track")
+	errTrackRelayClosed      = errors.New("the TrackRelay is closed")
+
+	errTrackSwitcherRemoteTrack   = errors.New("a TrackSwitcher must write to a local track")
+	errTrackSwitcherSourceUnknown = errors.New("the source was not created by this TrackSwitcher")
 )
@@ -3,16 +3,12 @@ package main
 import (
 	"context"
 	"fmt"
-	"io"
 	"os"
-	"time"
 
 	"github.com/pion/randutil"
 	"github.com/pion/webrtc/v3"
 	"github.com/pion/webrtc/v3/examples/internal/signal"
-	"github.com/pion/webrtc/v3/pkg/media"
-	"github.com/pion/webrtc/v3/pkg/media/ivfreader"
-	"github.com/pion/webrtc/v3/pkg/media/oggreader"
+	"github.com/pion/webrtc/v3/pkg/media/playback"
 )
 
 const (
@@ -68,13 +64,13 @@ func main() {
 		}
 
 		go func() {
-			// Open a IVF file and start reading using our IVFReader
+			// Open a IVF file and start reading using our IVFSource
 			file, ivfErr := os.Open(videoFileName)
 			if ivfErr != nil {
 				panic(ivfErr)
 			}
 
-			ivf, header, ivfErr := ivfreader.NewWith(file)
+			source, ivfErr := playback.NewIVFSource(file)
 			if ivfErr != nil {
 				panic(ivfErr)
 			}
@@ -84,23 +80,12 @@ func main() {
 
 			// Send our video file frame at a time. Pace our sending so we send it at the same speed it should be played back as.
 			// This isn't required since the video is timestamped, but we will such much higher loss if we send all at once.
-			sleepTime := time.Millisecond * time.Duration((float32(header.TimebaseNumerator)/float32(header.TimebaseDenominator))*1000)
-			for {
-				frame, _, ivfErr := ivf.ParseNextFrame()
-				if ivfErr == io.EOF {
-					fmt.Printf("All video frames parsed and sent")
-					os.Exit(0)
-				}
-
-				if ivfErr != nil {
-					panic(ivfErr)
-				}
-
-				time.Sleep(sleepTime)
-				if ivfErr = videoTrack.WriteSample(media.Sample{Data: frame, Samples: 90000}); ivfErr != nil {
-					panic(ivfErr)
-				}
+			if ivfErr = playback.NewPlayer(source, videoTrack).Play(); ivfErr != nil {
+				panic(ivfErr)
 			}
+
+			fmt.Printf("All video frames parsed and sent")
+			os.Exit(0)
 		}()
 	}
 
@@ -115,14 +100,13 @@ func main() {
 		}
 
 		go func() {
-			// Open a IVF file and start reading using our IVFReader
+			// Open a Ogg file and start reading using our OggSource
 			file, oggErr := os.Open(audioFileName)
 			if oggErr != nil {
 				panic(oggErr)
 			}
 
-			// Open on oggfile in non-checksum mode.
-			ogg, _, oggErr := oggreader.NewWith(file)
+			source, oggErr := playback.NewOggSource(file)
 			if oggErr != nil {
 				panic(oggErr)
 			}
@@ -130,30 +114,13 @@ func main() {
 			// Wait for connection established
 			<-iceConnectedCtx.Done()
 
-			// Keep track of last granule, the difference is the amount of samples in the buffer
-			var lastGranule uint64
-			for {
-				pageData, pageHeader, oggErr := ogg.ParseNextPage()
-				if oggErr == io.EOF {
-					fmt.Printf("All audio pages parsed and sent")
-					os.Exit(0)
-				}
-
-				if oggErr != nil {
-					panic(oggErr)
-				}
-
-				// The amount of samples is the difference between the last and current timestamp
-				sampleCount := float64(pageHeader.GranulePosition - lastGranule)
-				lastGranule = pageHeader.GranulePosition
-
-				if oggErr = audioTrack.WriteSample(media.Sample{Data: pageData, Samples: uint32(sampleCount)}); oggErr != nil {
-					panic(oggErr)
-				}
-
-				// Convert seconds to Milliseconds, Sleep doesn't accept floats
-				time.Sleep(time.Duration((sampleCount/48000)*1000) * time.Millisecond)
+			// Send our audio file page at a time, paced at the speed it should be played back as
+			if oggErr = playback.NewPlayer(source, audioTrack).Play(); oggErr != nil {
+				panic(oggErr)
 			}
+
+			fmt.Printf("All audio pages parsed and sent")
+			os.Exit(0)
 		}()
 	}
 
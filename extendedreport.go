@@ -0,0 +1,340 @@
+// +build !js
+
+package webrtc
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/webrtc/v3/internal/util"
+	"github.com/pion/webrtc/v3/pkg/rtcpxr"
+)
+
+// voipMetricsGmin is the Gmin of the VoIP metrics sent, the threshold of
+// the burst metrics recommended by RFC 3611
+const voipMetricsGmin = 16
+
+// WithExtendedReports sends an RTCP receiver report and an extended report
+// for the Tracks of the RTPReceiver every interval, as in RFC 3611. They
+// carry a receiver reference time, which gives the RTPReceiver a round trip
+// time once the remote answers it with a DLRR, and a statistics summary of
+// each Track, and VoIP metrics for audio. DLRRs are learned from the RTCP
+// of the RTPReceiver as it arrives, it doesn't have to be read. By default
+// no reports are sent.
+func WithExtendedReports(interval time.Duration) RTPReceiverOption {
+	return func(r *RTPReceiver) {
+		r.extendedReport.interval = interval
+	}
+}
+
+// extendedReportReceiver sends the extended reports of an RTPReceiver, and
+// holds the round trip time learned from the DLRRs answering them
+type extendedReportReceiver struct {
+	interval time.Duration
+
+	// ssrc is the SSRC the reports are sent with
+	ssrc uint32
+
+	mu               sync.Mutex
+	roundTripTime    time.Duration
+	hasRoundTripTime bool
+}
+
+// startExtendedReports starts sending the extended reports of the
+// RTPReceiver until it is stopped, if WithExtendedReports was set
+func (r *RTPReceiver) startExtendedReports() {
+	if r.extendedReport.interval <= 0 {
+		return
+	}
+	r.extendedReport.ssrc = util.RandUint32()
+
+	go func() {
+		ticker := time.NewTicker(r.extendedReport.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+			case <-r.closed:
+				return
+			}
+
+			// RTCP is best effort, the reports are sent again
+			if pkts := r.extendedReports(time.Now()); len(pkts) != 0 {
+				r.transport.writeRTCP(pkts) // nolint:errcheck
+			}
+		}
+	}()
+}
+
+// extendedReports returns the receiver report and the extended report for
+// the Tracks RTP was read from, sent at now. The receiver report comes first
+// as RTCP is routed by the SSRCs of its packets.
+func (r *RTPReceiver) extendedReports(now time.Time) []rtcp.Packet {
+	r.extendedReport.mu.Lock()
+	roundTripTime, hasRoundTripTime := r.extendedReport.roundTripTime, r.extendedReport.hasRoundTripTime
+	r.extendedReport.mu.Unlock()
+
+	ssrc := r.extendedReport.ssrc
+	receiverReport := &rtcp.ReceiverReport{SSRC: ssrc}
+	extendedReport := &rtcpxr.ExtendedReport{
+		SenderSSRC: ssrc,
+		Reports: []rtcpxr.ReportBlock{
+			&rtcpxr.ReceiverReferenceTimeReportBlock{NTPTimestamp: toNTPTime(now)},
+		},
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	r.statsMu.Lock()
+	defer r.statsMu.Unlock()
+
+	for i := range r.tracks {
+		t := &r.tracks[i]
+		trackSSRC := t.track.SSRC()
+		if trackSSRC == 0 || t.stats.received == 0 {
+			continue
+		}
+
+		lost := t.stats.packetsLost()
+		if lost < 0 {
+			lost = 0
+		}
+		highest := t.stats.sequence.Highest()
+
+		receiverReport.Reports = append(receiverReport.Reports, rtcp.ReceptionReport{
+			SSRC:               trackSSRC,
+			FractionLost:       t.stats.fractionLost(),
+			TotalLost:          uint32(min64(lost, 0x7FFFFF)),
+			LastSequenceNumber: uint32(highest),
+			Jitter:             uint32(t.stats.jitter),
+		})
+		extendedReport.Reports = append(extendedReport.Reports, &rtcpxr.StatisticsSummaryReportBlock{
+			LossReports: true,
+			SSRC:        trackSSRC,
+			BeginSeq:    uint16(t.stats.base),
+			EndSeq:      uint16(highest + 1),
+			LostPackets: uint32(min64(lost, math.MaxUint32)),
+		})
+
+		if r.kind != RTPCodecTypeAudio {
+			continue
+		}
+		metrics := &rtcpxr.VoIPMetricsReportBlock{
+			SSRC:        trackSSRC,
+			LossRate:    uint8(min64(lost*256/(int64(t.stats.received)+lost), math.MaxUint8)),
+			SignalLevel: rtcpxr.Unavailable,
+			NoiseLevel:  rtcpxr.Unavailable,
+			RERL:        rtcpxr.Unavailable,
+			Gmin:        voipMetricsGmin,
+			RFactor:     rtcpxr.Unavailable,
+			ExtRFactor:  rtcpxr.Unavailable,
+			MOSLQ:       rtcpxr.Unavailable,
+			MOSCQ:       rtcpxr.Unavailable,
+		}
+		if hasRoundTripTime {
+			metrics.RoundTripDelay = uint16(min64(int64(roundTripTime/time.Millisecond), math.MaxUint16))
+		}
+		extendedReport.Reports = append(extendedReport.Reports, metrics)
+	}
+
+	if len(receiverReport.Reports) == 0 {
+		return nil
+	}
+	return []rtcp.Packet{receiverReport, extendedReport}
+}
+
+// handleExtendedReport learns the round trip time from the DLRRs answering
+// the reports of the RTPReceiver in the RTCP it received
+func (r *RTPReceiver) handleExtendedReport(b []byte) {
+	if r.extendedReport.interval <= 0 {
+		return
+	}
+
+	reports, err := rtcpxr.Unmarshal(b)
+	if err != nil {
+		return
+	}
+
+	now := ntpMiddle(toNTPTime(time.Now()))
+	for _, report := range reports {
+		for _, block := range report.Reports {
+			dlrr, ok := block.(*rtcpxr.DLRRReportBlock)
+			if !ok {
+				continue
+			}
+
+			for _, sub := range dlrr.Reports {
+				if sub.SSRC != r.extendedReport.ssrc || sub.LastRR == 0 || now-sub.LastRR < sub.DLRR {
+					continue
+				}
+
+				r.extendedReport.mu.Lock()
+				r.extendedReport.roundTripTime = time.Duration(now-sub.LastRR-sub.DLRR) * time.Second / 65536
+				r.extendedReport.hasRoundTripTime = true
+				r.extendedReport.mu.Unlock()
+			}
+		}
+	}
+}
+
+// RoundTripTime returns the round trip time learned from the DLRRs
+// answering the extended reports sent with WithExtendedReports, false if
+// none was received
+func (r *RTPReceiver) RoundTripTime() (time.Duration, bool) {
+	r.extendedReport.mu.Lock()
+	defer r.extendedReport.mu.Unlock()
+	return r.extendedReport.roundTripTime, r.extendedReport.hasRoundTripTime
+}
+
+// remoteOutboundRTPStreamStatsID returns the ID of the stats of the sender
+// of the remote stream with ssrc
+func remoteOutboundRTPStreamStatsID(ssrc uint32) string {
+	return fmt.Sprintf("RemoteOutboundRTPStream-%d", ssrc)
+}
+
+// extendedReportSender answers the receiver reference times with DLRRs, and
+// holds the statistics of the remote received from extended reports
+type extendedReportSender struct {
+	mu sync.Mutex
+
+	hasRemoteInbound bool
+	remoteInbound    RemoteInboundRTPStreamStats
+}
+
+// handleExtendedReport answers the receiver reference times in the RTCP
+// received by the RTPSender, and keeps the statistics of its SSRC
+func (r *RTPSender) handleExtendedReport(b []byte) {
+	reports, err := rtcpxr.Unmarshal(b)
+	if err != nil || len(reports) == 0 {
+		return
+	}
+	received := time.Now()
+
+	ssrc := r.SSRC()
+	clockRate := uint32(0)
+	if track := r.Track(); track != nil && track.Codec() != nil {
+		clockRate = track.Codec().ClockRate
+	}
+
+	dlrr := &rtcpxr.DLRRReportBlock{}
+	r.extendedReport.mu.Lock()
+	for _, report := range reports {
+		for _, block := range report.Reports {
+			switch block := block.(type) {
+			case *rtcpxr.ReceiverReferenceTimeReportBlock:
+				dlrr.Reports = append(dlrr.Reports, rtcpxr.DLRRReport{
+					SSRC:   report.SenderSSRC,
+					LastRR: ntpMiddle(block.NTPTimestamp),
+				})
+			case *rtcpxr.StatisticsSummaryReportBlock:
+				if block.SSRC == ssrc {
+					r.extendedReport.updateStatisticsSummary(block, clockRate)
+				}
+			case *rtcpxr.VoIPMetricsReportBlock:
+				if block.SSRC == ssrc {
+					r.extendedReport.updateVoIPMetrics(block)
+				}
+			}
+		}
+	}
+	r.extendedReport.mu.Unlock()
+
+	if len(dlrr.Reports) == 0 {
+		return
+	}
+	delay := uint32(time.Since(received) * 65536 / time.Second)
+	for i := range dlrr.Reports {
+		dlrr.Reports[i].DLRR = delay
+	}
+
+	// RTCP is best effort, the receiver sends its reference time again
+	r.writeDLRR(ssrc, dlrr) // nolint:errcheck
+}
+
+// writeDLRR sends dlrr in an extended report, after an SDES as RTCP is
+// routed by the SSRCs of its packets
+func (r *RTPSender) writeDLRR(ssrc uint32, dlrr *rtcpxr.DLRRReportBlock) error {
+	r.mu.RLock()
+	transport := r.transport
+	cname := ""
+	if r.track != nil {
+		cname = r.track.Label()
+	}
+	r.mu.RUnlock()
+
+	return transport.writeRTCP([]rtcp.Packet{
+		&rtcp.SourceDescription{Chunks: []rtcp.SourceDescriptionChunk{{
+			Source: ssrc,
+			Items:  []rtcp.SourceDescriptionItem{{Type: rtcp.SDESCNAME, Text: cname}},
+		}}},
+		&rtcpxr.ExtendedReport{SenderSSRC: ssrc, Reports: []rtcpxr.ReportBlock{dlrr}},
+	})
+}
+
+func (x *extendedReportSender) updateStatisticsSummary(block *rtcpxr.StatisticsSummaryReportBlock, clockRate uint32) {
+	x.hasRemoteInbound = true
+	if block.LossReports {
+		x.remoteInbound.PacketsLost = int32(min64(int64(block.LostPackets), math.MaxInt32))
+	}
+	if block.JitterReports && clockRate != 0 {
+		x.remoteInbound.Jitter = float64(block.MeanJitter) / float64(clockRate)
+	}
+}
+
+func (x *extendedReportSender) updateVoIPMetrics(block *rtcpxr.VoIPMetricsReportBlock) {
+	x.hasRemoteInbound = true
+	x.remoteInbound.FractionLost = float64(block.LossRate) / 256
+	x.remoteInbound.BurstLossRate = float64(block.BurstDensity) / 256
+	x.remoteInbound.GapLossRate = float64(block.GapDensity) / 256
+	if block.RoundTripDelay != 0 {
+		x.remoteInbound.RoundTripTime = float64(block.RoundTripDelay) / 1000
+	}
+}
+
+// remoteInboundRTPStreamStatsID returns the ID of the stats of the receiver
+// of the local stream with ssrc
+func remoteInboundRTPStreamStatsID(ssrc uint32) string {
+	return fmt.Sprintf("RemoteInboundRTPStream-%d", ssrc)
+}
+
+func (r *RTPSender) collectStats(collector *statsReportCollector) {
+	r.extendedReport.mu.Lock()
+	stats, ok := r.extendedReport.remoteInbound, r.extendedReport.hasRemoteInbound
+	r.extendedReport.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	collector.Collecting()
+
+	ssrc := r.SSRC()
+	stats.Timestamp = statsTimestampFrom(time.Now())
+	stats.Type = StatsTypeRemoteInboundRTP
+	stats.ID = remoteInboundRTPStreamStatsID(ssrc)
+	stats.SSRC = ssrc
+	if track := r.Track(); track != nil {
+		stats.Kind = track.Kind().String()
+		if codec := track.Codec(); codec != nil {
+			stats.CodecID = codec.statsID
+		}
+	}
+
+	collector.Collect(stats.ID, stats)
+}
+
+// ntpMiddle returns the middle 32 bits of an NTP timestamp, the format of
+// the times of DLRRs
+func ntpMiddle(ntp uint64) uint32 {
+	return uint32(ntp >> 16)
+}
+
+func min64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,172 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/pion/transport/test"
+	"github.com/pion/webrtc/v3/pkg/rtcpxr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRTPReceiver_extendedReports(t *testing.T) {
+	r := &RTPReceiver{kind: RTPCodecTypeAudio}
+	WithExtendedReports(time.Second)(r)
+	r.extendedReport.ssrc = 1234
+	r.tracks = []trackStreams{{track: &Track{ssrc: 5000}}, {track: &Track{ssrc: 6000}}}
+
+	now := time.Now()
+	assert.Empty(t, r.extendedReports(now))
+
+	// Packets 11 and 12 are lost
+	for _, sequenceNumber := range []uint16{10, 13, 14, 15} {
+		r.tracks[0].stats.update(&rtp.Header{SequenceNumber: sequenceNumber}, 100, now, 0)
+	}
+
+	pkts := r.extendedReports(now)
+	assert.Equal(t, &rtcp.ReceiverReport{SSRC: 1234, Reports: []rtcp.ReceptionReport{{
+		SSRC:               5000,
+		FractionLost:       256 * 2 / 6,
+		TotalLost:          2,
+		LastSequenceNumber: 15,
+	}}}, pkts[0])
+
+	raw, err := rtcp.Marshal(pkts)
+	assert.NoError(t, err)
+	reports, err := rtcpxr.Unmarshal(raw)
+	assert.NoError(t, err)
+	assert.Equal(t, []*rtcpxr.ExtendedReport{{
+		SenderSSRC: 1234,
+		Reports: []rtcpxr.ReportBlock{
+			&rtcpxr.ReceiverReferenceTimeReportBlock{NTPTimestamp: toNTPTime(now)},
+			&rtcpxr.StatisticsSummaryReportBlock{LossReports: true, SSRC: 5000, BeginSeq: 10, EndSeq: 16, LostPackets: 2},
+			&rtcpxr.VoIPMetricsReportBlock{
+				SSRC:        5000,
+				LossRate:    256 * 2 / 6,
+				SignalLevel: rtcpxr.Unavailable,
+				NoiseLevel:  rtcpxr.Unavailable,
+				RERL:        rtcpxr.Unavailable,
+				Gmin:        voipMetricsGmin,
+				RFactor:     rtcpxr.Unavailable,
+				ExtRFactor:  rtcpxr.Unavailable,
+				MOSLQ:       rtcpxr.Unavailable,
+				MOSCQ:       rtcpxr.Unavailable,
+			},
+		},
+	}}, reports)
+
+	// The fraction lost is since the previous report
+	assert.Equal(t, uint8(0), r.extendedReports(now)[0].(*rtcp.ReceiverReport).Reports[0].FractionLost)
+
+	// The round trip time is learned from DLRRs for the SSRC of the reports
+	_, ok := r.RoundTripTime()
+	assert.False(t, ok)
+	dlrr := func(ssrc uint32) []byte {
+		b, dlrrErr := rtcp.Marshal([]rtcp.Packet{
+			&rtcp.SourceDescription{},
+			&rtcpxr.ExtendedReport{Reports: []rtcpxr.ReportBlock{&rtcpxr.DLRRReportBlock{Reports: []rtcpxr.DLRRReport{{
+				SSRC:   ssrc,
+				LastRR: ntpMiddle(toNTPTime(time.Now().Add(-300 * time.Millisecond))),
+				DLRR:   65536 / 10,
+			}}}}},
+		})
+		assert.NoError(t, dlrrErr)
+		return b
+	}
+	r.handleExtendedReport(dlrr(4321))
+	_, ok = r.RoundTripTime()
+	assert.False(t, ok)
+	r.handleExtendedReport(dlrr(1234))
+	roundTripTime, ok := r.RoundTripTime()
+	assert.True(t, ok)
+	assert.InDelta(t, 200*time.Millisecond, roundTripTime, float64(10*time.Millisecond))
+}
+
+func TestRTPSender_handleExtendedReport(t *testing.T) {
+	r := &RTPSender{track: &Track{ssrc: 5000, kind: RTPCodecTypeAudio, codec: &RTPCodec{RTPCodecCapability: RTPCodecCapability{ClockRate: 48000}}}}
+
+	collect := func() (RemoteInboundRTPStreamStats, bool) {
+		collector := newStatsReportCollector()
+		r.collectStats(collector)
+		return collector.Ready().GetRemoteInboundRTPStreamStats(r)
+	}
+	_, ok := collect()
+	assert.False(t, ok)
+
+	b, err := rtcp.Marshal([]rtcp.Packet{
+		&rtcp.ReceiverReport{SSRC: 1234},
+		&rtcpxr.ExtendedReport{SenderSSRC: 1234, Reports: []rtcpxr.ReportBlock{
+			&rtcpxr.StatisticsSummaryReportBlock{LossReports: true, JitterReports: true, SSRC: 5000, LostPackets: 7, MeanJitter: 480},
+			&rtcpxr.StatisticsSummaryReportBlock{LossReports: true, SSRC: 6000, LostPackets: 9},
+			&rtcpxr.VoIPMetricsReportBlock{SSRC: 5000, LossRate: 64, BurstDensity: 128, RoundTripDelay: 150},
+		}},
+	})
+	assert.NoError(t, err)
+	r.handleExtendedReport(b)
+
+	stats, ok := collect()
+	assert.True(t, ok)
+	assert.Equal(t, StatsTypeRemoteInboundRTP, stats.Type)
+	assert.Equal(t, "audio", stats.Kind)
+	assert.Equal(t, int32(7), stats.PacketsLost)
+	assert.Equal(t, 0.01, stats.Jitter)
+	assert.Equal(t, 0.25, stats.FractionLost)
+	assert.Equal(t, 0.5, stats.BurstLossRate)
+	assert.Equal(t, 0.15, stats.RoundTripTime)
+}
+
+func TestPeerConnection_ExtendedReports(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	s := SettingEngine{}
+	s.SetRTPReceiverOptions(WithExtendedReports(50 * time.Millisecond))
+	m := MediaEngine{}
+	m.RegisterDefaultCodecs()
+	pcAnswer, err := NewAPI(WithMediaEngine(m), WithSettingEngine(s)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	pcOffer, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, 5000, "video", "pion")
+	assert.NoError(t, err)
+	sender, err := pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+
+	// DLRRs are sent and learned without the RTCP being read
+	done := make(chan struct{})
+	pcAnswer.OnTrack(func(track *Track, receiver *RTPReceiver) {
+		go func() {
+			for {
+				roundTripTime, ok := receiver.RoundTripTime()
+				_, hasStats := pcOffer.GetStats().GetRemoteInboundRTPStreamStats(sender)
+				if ok && hasStats {
+					assert.True(t, roundTripTime < time.Second)
+					remoteStats, hasRemoteStats := pcAnswer.GetStats().GetRemoteOutboundRTPStreamStats(track)
+					assert.True(t, hasRemoteStats)
+					assert.Equal(t, roundTripTime.Seconds(), remoteStats.RoundTripTime)
+					close(done)
+					return
+				}
+				time.Sleep(20 * time.Millisecond)
+			}
+		}()
+		for {
+			if _, readErr := track.ReadRTP(); readErr != nil {
+				return
+			}
+		}
+	})
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	sendVideoUntilDone(done, t, []*Track{track})
+
+	closePairNow(t, pcOffer, pcAnswer)
+}
@@ -9,6 +9,10 @@ import (
 //
 // It is better to not use this function, and instead trickle candidates. If you use this function you will see longer connection startup times.
 // When the call is connected you will see no impact however.
+//
+// Each call returns its own channel, so it can be waited on by several
+// goroutines and signaling flows. OnICEGatheringStateChange reports the
+// other states of gathering.
 func GatheringCompletePromise(pc *PeerConnection) (gatherComplete <-chan struct{}) {
 	gatheringComplete, done := context.WithCancel(context.Background())
 
@@ -0,0 +1,56 @@
+// +build !js
+
+package webrtc
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pion/transport/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGatheringCompletePromise(t *testing.T) {
+	lim := test.TimeOut(time.Second * 10)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	pc, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	var statesMu sync.Mutex
+	states := []ICEGathererState{}
+	pc.OnICEGatheringStateChange(func(state ICEGathererState) {
+		statesMu.Lock()
+		defer statesMu.Unlock()
+		states = append(states, state)
+	})
+
+	_, err = pc.CreateDataChannel("data", nil)
+	assert.NoError(t, err)
+	offer, err := pc.CreateOffer(nil)
+	assert.NoError(t, err)
+
+	// Every promise is resolved, not only the last one
+	first, second := GatheringCompletePromise(pc), GatheringCompletePromise(pc)
+	assert.NoError(t, pc.SetLocalDescription(offer))
+	<-first
+	<-second
+	assert.Equal(t, ICEGatheringStateComplete, pc.ICEGatheringState())
+
+	statesMu.Lock()
+	assert.Equal(t, []ICEGathererState{ICEGathererStateGathering, ICEGathererStateComplete}, states)
+	statesMu.Unlock()
+
+	// A promise after gathering completed is resolved right away
+	select {
+	case <-GatheringCompletePromise(pc):
+	default:
+		assert.Fail(t, "GatheringCompletePromise after completion isn't resolved")
+	}
+
+	assert.NoError(t, pc.Close())
+}
@@ -10,7 +10,9 @@ const (
 	ICECredentialTypePassword ICECredentialType = iota
 
 	// ICECredentialTypeOauth describes token based credential as described
-	// in https://tools.ietf.org/html/rfc7635.
+	// in https://tools.ietf.org/html/rfc7635. The credential is validated,
+	// but the TURN client of the ICE agent doesn't send access tokens yet,
+	// so ephemeral credentials like NewTURNRESTICEServer are used instead.
 	ICECredentialTypeOauth
 )
 
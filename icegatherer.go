@@ -3,6 +3,7 @@
 package webrtc
 
 import (
+	"net"
 	"sync"
 	"sync/atomic"
 
@@ -27,8 +28,13 @@ type ICEGatherer struct {
 	onLocalCandidateHandler atomic.Value // func(candidate *ICECandidate)
 	onStateChangeHandler    atomic.Value // func(state ICEGathererState)
 
-	// Used for GatheringCompletePromise
-	onGatheringCompleteHandler atomic.Value // func()
+	// Used for GatheringCompletePromise, each handler is called once
+	gatheringCompleteMu       sync.Mutex
+	gatheringCompleteHandlers []func()
+
+	// goInternal runs the goroutines collecting the stats of the agent, the
+	// PeerConnection sets it so Close waits for them
+	goInternal func(func()) bool
 
 	api *API
 }
@@ -37,6 +43,10 @@ type ICEGatherer struct {
 // This constructor is part of the ORTC API. It is not
 // meant to be used together with the basic WebRTC API.
 func (api *API) NewICEGatherer(opts ICEGatherOptions) (*ICEGatherer, error) {
+	if api.settingEngine.candidates.HostOnly && len(opts.ICEServers) > 0 {
+		return nil, errICEGathererHostOnlyICEServers
+	}
+
 	var validatedServers []*ice.URL
 	if len(opts.ICEServers) > 0 {
 		for _, server := range opts.ICEServers {
@@ -65,13 +75,6 @@ func (g *ICEGatherer) createAgent() error {
 		return nil
 	}
 
-	candidateTypes := []ice.CandidateType{}
-	if g.api.settingEngine.candidates.ICELite {
-		candidateTypes = append(candidateTypes, ice.CandidateTypeHost)
-	} else if g.gatherPolicy == ICETransportPolicyRelay {
-		candidateTypes = append(candidateTypes, ice.CandidateTypeRelay)
-	}
-
 	var nat1To1CandiTyp ice.CandidateType
 	switch g.api.settingEngine.candidates.NAT1To1IPCandidateType {
 	case ICECandidateTypeHost:
@@ -90,14 +93,14 @@ func (g *ICEGatherer) createAgent() error {
 
 	config := &ice.AgentConfig{
 		Lite:                   g.api.settingEngine.candidates.ICELite,
-		Urls:                   g.validatedServers,
+		Urls:                   g.resolvedServers(),
 		PortMin:                g.api.settingEngine.ephemeralUDP.PortMin,
 		PortMax:                g.api.settingEngine.ephemeralUDP.PortMax,
 		DisconnectedTimeout:    g.api.settingEngine.timeout.ICEDisconnectedTimeout,
 		FailedTimeout:          g.api.settingEngine.timeout.ICEFailedTimeout,
 		KeepaliveInterval:      g.api.settingEngine.timeout.ICEKeepaliveInterval,
 		LoggerFactory:          g.api.settingEngine.LoggerFactory,
-		CandidateTypes:         candidateTypes,
+		CandidateTypes:         g.candidateTypes(),
 		HostAcceptanceMinWait:  g.api.settingEngine.timeout.ICEHostAcceptanceMinWait,
 		SrflxAcceptanceMinWait: g.api.settingEngine.timeout.ICESrflxAcceptanceMinWait,
 		PrflxAcceptanceMinWait: g.api.settingEngine.timeout.ICEPrflxAcceptanceMinWait,
@@ -132,8 +135,78 @@ func (g *ICEGatherer) createAgent() error {
 	return nil
 }
 
+// candidateTypes returns the types of candidates to gather, all types if empty
+func (g *ICEGatherer) candidateTypes() []ice.CandidateType {
+	candidateTypes := []ice.CandidateType{}
+	switch {
+	case g.api.settingEngine.candidates.ICELite:
+		candidateTypes = append(candidateTypes, ice.CandidateTypeHost)
+	case g.api.settingEngine.candidates.HostOnly:
+		candidateTypes = append(candidateTypes, ice.CandidateTypeHost)
+		if len(g.api.settingEngine.candidates.NAT1To1IPs) != 0 &&
+			g.api.settingEngine.candidates.NAT1To1IPCandidateType == ICECandidateTypeSrflx {
+			candidateTypes = append(candidateTypes, ice.CandidateTypeServerReflexive)
+		}
+	case g.gatherPolicy == ICETransportPolicyRelay:
+		candidateTypes = append(candidateTypes, ice.CandidateTypeRelay)
+	}
+
+	return candidateTypes
+}
+
+// hasHostRoute checks if a network interface has an address to gather host
+// candidates from. Virtual networks are assumed to have one.
+func (g *ICEGatherer) hasHostRoute() bool {
+	if g.api.settingEngine.vnet != nil {
+		return true
+	}
+
+	networkTypes := g.api.settingEngine.candidates.ICENetworkTypes
+	if len(networkTypes) == 0 {
+		networkTypes = supportedNetworkTypes()
+	}
+	hasIPv4, hasIPv6 := false, false
+	for _, typ := range networkTypes {
+		hasIPv4 = hasIPv4 || ice.NetworkType(typ).IsIPv4()
+		hasIPv6 = hasIPv6 || ice.NetworkType(typ).IsIPv6()
+	}
+
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return false
+	}
+
+	filter := g.api.settingEngine.candidates.InterfaceFilter
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 ||
+			(filter != nil && !filter(iface.Name)) {
+			continue
+		}
+
+		addrs, addrsErr := iface.Addrs()
+		if addrsErr != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			ipNet, ok := addr.(*net.IPNet)
+			if !ok || ipNet.IP.IsLoopback() {
+				continue
+			}
+			if (ipNet.IP.To4() != nil && hasIPv4) || (ipNet.IP.To4() == nil && hasIPv6) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
 // Gather ICE candidates.
 func (g *ICEGatherer) Gather() error {
+	if g.api.settingEngine.candidates.HostOnly && !g.hasHostRoute() {
+		return errICEGathererHostOnlyNoRoute
+	}
+
 	if err := g.createAgent(); err != nil {
 		return err
 	}
@@ -149,11 +222,6 @@ func (g *ICEGatherer) Gather() error {
 			onLocalCandidateHandler = handler
 		}
 
-		onGatheringCompleteHandler := func() {}
-		if handler, ok := g.onGatheringCompleteHandler.Load().(func()); ok && handler != nil {
-			onGatheringCompleteHandler = handler
-		}
-
 		if candidate != nil {
 			c, err := newICECandidateFromICE(candidate)
 			if err != nil {
@@ -162,9 +230,12 @@ func (g *ICEGatherer) Gather() error {
 			}
 			onLocalCandidateHandler(&c)
 		} else {
+			handlers := g.completeGathering()
 			g.setState(ICEGathererStateComplete)
 
-			onGatheringCompleteHandler()
+			for _, handler := range handlers {
+				handler()
+			}
 			onLocalCandidateHandler(nil)
 		}
 	}); err != nil {
@@ -204,7 +275,7 @@ func (g *ICEGatherer) GetLocalParameters() (ICEParameters, error) {
 	return ICEParameters{
 		UsernameFragment: frag,
 		Password:         pwd,
-		ICELite:          false,
+		ICELite:          g.api.settingEngine.candidates.ICELite,
 	}, nil
 }
 
@@ -221,6 +292,22 @@ func (g *ICEGatherer) GetLocalCandidates() ([]ICECandidate, error) {
 	return newICECandidatesFromICE(iceCandidates)
 }
 
+// localCandidateCount returns the number of gathered local candidates, 0
+// without an agent
+func (g *ICEGatherer) localCandidateCount() int {
+	g.lock.RLock()
+	defer g.lock.RUnlock()
+
+	if g.agent == nil {
+		return 0
+	}
+	candidates, err := g.agent.GetLocalCandidates()
+	if err != nil {
+		return 0
+	}
+	return len(candidates)
+}
+
 // OnLocalCandidate sets an event handler which fires when a new local ICE candidate is available
 // Take note that the handler is gonna be called with a nil pointer when gathering is finished.
 func (g *ICEGatherer) OnLocalCandidate(f func(*ICECandidate)) {
@@ -245,6 +332,33 @@ func (g *ICEGatherer) setState(s ICEGathererState) {
 	}
 }
 
+// onGatheringComplete adds a handler which is called once gathering is
+// complete, right away if it already is
+func (g *ICEGatherer) onGatheringComplete(handler func()) {
+	g.gatheringCompleteMu.Lock()
+	if g.State() != ICEGathererStateComplete {
+		g.gatheringCompleteHandlers = append(g.gatheringCompleteHandlers, handler)
+		g.gatheringCompleteMu.Unlock()
+		return
+	}
+	g.gatheringCompleteMu.Unlock()
+
+	handler()
+}
+
+// completeGathering sets the state to complete, and returns the handlers
+// added by onGatheringComplete to call. The state is set with the lock held,
+// so no handler added afterwards is missed.
+func (g *ICEGatherer) completeGathering() []func() {
+	g.gatheringCompleteMu.Lock()
+	defer g.gatheringCompleteMu.Unlock()
+
+	atomicStoreICEGathererState(&g.state, ICEGathererStateComplete)
+	handlers := g.gatheringCompleteHandlers
+	g.gatheringCompleteHandlers = nil
+	return handlers
+}
+
 func (g *ICEGatherer) getAgent() *ice.Agent {
 	g.lock.RLock()
 	defer g.lock.RUnlock()
@@ -258,7 +372,7 @@ func (g *ICEGatherer) collectStats(collector *statsReportCollector) {
 	}
 
 	collector.Collecting()
-	go func(collector *statsReportCollector, agent *ice.Agent) {
+	collect := func() {
 		for _, candidatePairStats := range agent.GetCandidatePairsStats() {
 			collector.Collecting()
 
@@ -363,5 +477,11 @@ func (g *ICEGatherer) collectStats(collector *statsReportCollector) {
 			collector.Collect(stats.ID, stats)
 		}
 		collector.Done()
-	}(collector, agent)
+	}
+
+	if g.goInternal == nil || !g.goInternal(collect) {
+		// Without a PeerConnection, or once it is closed, as the stats are
+		// still collected
+		go collect()
+	}
 }
@@ -97,3 +97,62 @@ func TestICEGather_mDNSCandidateGathering(t *testing.T) {
 	<-gotMulticastDNSCandidate.Done()
 	assert.NoError(t, gatherer.Close())
 }
+
+func TestICEGatherer_HostOnly(t *testing.T) {
+	s := SettingEngine{}
+	s.SetHostOnly(true)
+
+	_, err := NewAPI(WithSettingEngine(s)).NewICEGatherer(ICEGatherOptions{
+		ICEServers: []ICEServer{{URLs: []string{"stun:stun.l.google.com:19302"}}},
+	})
+	assert.Equal(t, errICEGathererHostOnlyICEServers, err)
+
+	gatherer, err := NewAPI(WithSettingEngine(s)).NewICEGatherer(ICEGatherOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, []ice.CandidateType{ice.CandidateTypeHost}, gatherer.candidateTypes())
+
+	// Static mappings are the only server reflexive candidates
+	s.SetNAT1To1IPs([]string{"1.2.3.4"}, ICECandidateTypeSrflx)
+	gatherer, err = NewAPI(WithSettingEngine(s)).NewICEGatherer(ICEGatherOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, []ice.CandidateType{ice.CandidateTypeHost, ice.CandidateTypeServerReflexive}, gatherer.candidateTypes())
+
+	// Gathering fails without a network interface
+	s.SetInterfaceFilter(func(string) bool { return false })
+	gatherer, err = NewAPI(WithSettingEngine(s)).NewICEGatherer(ICEGatherOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, errICEGathererHostOnlyNoRoute, gatherer.Gather())
+	assert.NoError(t, gatherer.Close())
+}
+
+func TestICEGatherer_NetworkTypes(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	s := SettingEngine{}
+	s.SetNetworkTypes([]NetworkType{NetworkTypeUDP4})
+
+	gatherer, err := NewAPI(WithSettingEngine(s)).NewICEGatherer(ICEGatherOptions{})
+	assert.NoError(t, err)
+
+	gatherFinished := make(chan struct{})
+	gatherer.OnLocalCandidate(func(c *ICECandidate) {
+		if c == nil {
+			close(gatherFinished)
+		}
+	})
+	assert.NoError(t, gatherer.Gather())
+	<-gatherFinished
+
+	// Only UDP over IPv4 is gathered
+	candidates, err := gatherer.GetLocalCandidates()
+	assert.NoError(t, err)
+	for _, c := range candidates {
+		assert.Equal(t, ICEProtocolUDP, c.Protocol)
+		assert.NotContains(t, c.Address, ":")
+	}
+	assert.NoError(t, gatherer.Close())
+}
@@ -0,0 +1,124 @@
+// +build !js
+
+package webrtc
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+
+	"golang.org/x/net/http/httpproxy"
+	"golang.org/x/net/proxy"
+)
+
+// newICEProxyDialer returns the dialer of the proxy at proxyURL, HTTP CONNECT
+// for an http or https URL and SOCKS5 for a socks5 one
+func newICEProxyDialer(proxyURL *url.URL) (proxy.Dialer, error) {
+	switch proxyURL.Scheme {
+	case "http", "https":
+		return &httpConnectDialer{proxyURL: proxyURL, forward: proxy.Direct}, nil
+	case "socks5", "socks5h":
+		return proxy.FromURL(proxyURL, proxy.Direct)
+	default:
+		return nil, fmt.Errorf("%w: %s", errICEProxySchemeUnsupported, proxyURL.Scheme)
+	}
+}
+
+// httpConnectDialer dials through an HTTP proxy with the CONNECT method
+type httpConnectDialer struct {
+	proxyURL *url.URL
+	forward  proxy.Dialer
+}
+
+// Dial connects to addr through the proxy
+func (d *httpConnectDialer) Dial(network, addr string) (net.Conn, error) {
+	proxyAddr := d.proxyURL.Host
+	if d.proxyURL.Port() == "" {
+		port := "80"
+		if d.proxyURL.Scheme == "https" {
+			port = "443"
+		}
+		proxyAddr = net.JoinHostPort(d.proxyURL.Hostname(), port)
+	}
+
+	conn, err := d.forward.Dial(network, proxyAddr)
+	if err != nil {
+		return nil, err
+	}
+	if d.proxyURL.Scheme == "https" {
+		conn = tls.Client(conn, &tls.Config{ServerName: d.proxyURL.Hostname()}) // nolint:gosec
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: http.Header{},
+	}
+	if user := d.proxyURL.User; user != nil {
+		password, _ := user.Password()
+		req.Header.Set("Proxy-Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(user.Username()+":"+password)))
+	}
+	if err = req.Write(conn); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		_ = conn.Close()
+		return nil, fmt.Errorf("%w: %s", errICEProxyConnectFailed, resp.Status)
+	}
+
+	// The proxy may have sent data of addr with its response
+	if reader.Buffered() != 0 {
+		return &bufferedConn{Conn: conn, reader: reader}, nil
+	}
+	return conn, nil
+}
+
+// bufferedConn reads what was buffered while reading the response of the
+// proxy before the rest of the Conn
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
+
+// environmentProxyDialer dials through the proxy the environment variables
+// set for each address, or directly if there is none
+type environmentProxyDialer struct {
+	proxyFunc func(*url.URL) (*url.URL, error)
+}
+
+// Dial connects to addr through the proxy for it
+func (d *environmentProxyDialer) Dial(network, addr string) (net.Conn, error) {
+	proxyURL, err := d.proxyFunc(&url.URL{Scheme: "https", Host: addr})
+	if err != nil {
+		return nil, err
+	} else if proxyURL == nil {
+		return proxy.Direct.Dial(network, addr)
+	}
+
+	dialer, err := newICEProxyDialer(proxyURL)
+	if err != nil {
+		return nil, err
+	}
+	return dialer.Dial(network, addr)
+}
+
+func newEnvironmentProxyDialer() *environmentProxyDialer {
+	return &environmentProxyDialer{proxyFunc: httpproxy.FromEnvironment().ProxyFunc()}
+}
@@ -0,0 +1,113 @@
+// +build !js
+
+package webrtc
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// serveHTTPProxy answers a CONNECT request on listener with status, and
+// sends data after it
+func serveHTTPProxy(t *testing.T, listener net.Listener, status int, data string) <-chan *http.Request {
+	requests := make(chan *http.Request, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close() // nolint:errcheck
+
+		req, err := http.ReadRequest(bufio.NewReader(conn))
+		assert.NoError(t, err)
+		requests <- req
+
+		_, err = fmt.Fprintf(conn, "HTTP/1.1 %d %s\r\n\r\n%s", status, http.StatusText(status), data)
+		assert.NoError(t, err)
+	}()
+	return requests
+}
+
+func TestICEProxy_HTTPConnect(t *testing.T) {
+	listener, err := net.Listen("tcp4", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close() // nolint:errcheck
+
+	s := SettingEngine{}
+	assert.NoError(t, s.SetICEProxy(&url.URL{Scheme: "http", User: url.UserPassword("user", "pass"), Host: listener.Addr().String()}))
+
+	requests := serveHTTPProxy(t, listener, http.StatusOK, "turn")
+	conn, err := s.iceProxyDialer.Dial("tcp4", "turn.example.com:3478")
+	assert.NoError(t, err)
+
+	req := <-requests
+	assert.Equal(t, http.MethodConnect, req.Method)
+	assert.Equal(t, "turn.example.com:3478", req.Host)
+	assert.Equal(t, "Basic dXNlcjpwYXNz", req.Header.Get("Proxy-Authorization"))
+
+	// The data sent with the response isn't lost, and ICE gets the TCP
+	// address of the connection
+	buf := make([]byte, 4)
+	_, err = conn.Read(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "turn", string(buf))
+	_, ok := conn.LocalAddr().(*net.TCPAddr)
+	assert.True(t, ok)
+	assert.NoError(t, conn.Close())
+
+	// A refused CONNECT fails the dial
+	serveHTTPProxy(t, listener, http.StatusProxyAuthRequired, "")
+	_, err = s.iceProxyDialer.Dial("tcp4", "turn.example.com:3478")
+	assert.True(t, errors.Is(err, errICEProxyConnectFailed))
+}
+
+func TestICEProxy_Schemes(t *testing.T) {
+	s := SettingEngine{}
+	assert.NoError(t, s.SetICEProxy(&url.URL{Scheme: "socks5", Host: "127.0.0.1:1080"}))
+	assert.NotNil(t, s.iceProxyDialer)
+
+	err := s.SetICEProxy(&url.URL{Scheme: "ftp", Host: "127.0.0.1:21"})
+	assert.True(t, errors.Is(err, errICEProxySchemeUnsupported))
+}
+
+func TestICEProxy_Environment(t *testing.T) {
+	listener, err := net.Listen("tcp4", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close() // nolint:errcheck
+
+	// An address without a proxy is dialed directly
+	dialer := &environmentProxyDialer{proxyFunc: func(*url.URL) (*url.URL, error) {
+		return nil, nil
+	}}
+	accepted := make(chan struct{})
+	go func() {
+		if conn, acceptErr := listener.Accept(); acceptErr == nil {
+			_ = conn.Close()
+		}
+		close(accepted)
+	}()
+	conn, err := dialer.Dial("tcp4", listener.Addr().String())
+	assert.NoError(t, err)
+	<-accepted
+	assert.NoError(t, conn.Close())
+
+	// Otherwise the proxy for it is used
+	var proxied *url.URL
+	dialer.proxyFunc = func(u *url.URL) (*url.URL, error) {
+		proxied = u
+		return &url.URL{Scheme: "http", Host: listener.Addr().String()}, nil
+	}
+	requests := serveHTTPProxy(t, listener, http.StatusOK, "")
+	conn, err = dialer.Dial("tcp4", "turn.example.com:3478")
+	assert.NoError(t, err)
+	assert.Equal(t, "turn.example.com:3478", (<-requests).Host)
+	assert.Equal(t, "turn.example.com:3478", proxied.Host)
+	assert.NoError(t, conn.Close())
+}
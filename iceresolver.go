@@ -0,0 +1,96 @@
+// +build !js
+
+package webrtc
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/pion/ice/v2"
+)
+
+// iceResolveTimeout is how long resolving a host name for ICE may take
+const iceResolveTimeout = 5 * time.Second
+
+// ICEResolver resolves the host names of STUN and TURN servers and of mDNS
+// candidates, see SettingEngine.SetICEResolver. *net.Resolver implements it.
+type ICEResolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// resolveICEHost returns the address of host from resolver, an IPv4 one if
+// there is one
+func resolveICEHost(resolver ICEResolver, host string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), iceResolveTimeout)
+	defer cancel()
+
+	addrs, err := resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return "", err
+	} else if len(addrs) == 0 {
+		return "", fmt.Errorf("%w: %s", errICEResolverNoAddress, host)
+	}
+
+	for _, addr := range addrs {
+		if addr.IP.To4() != nil {
+			return addr.IP.String(), nil
+		}
+	}
+	return addrs[0].IP.String(), nil
+}
+
+// resolvedServers returns the ICE servers of the ICEGatherer with their host
+// names resolved by the ICEResolver of the SettingEngine. TURN over TLS keeps
+// its host name, which its certificate is verified with, and so does TURN
+// over TCP through a proxy, which resolves it. A server that can't be
+// resolved is left to the resolution of the ICE agent.
+func (g *ICEGatherer) resolvedServers() []*ice.URL {
+	resolver := g.api.settingEngine.iceResolver
+	if resolver == nil {
+		return g.validatedServers
+	}
+
+	servers := make([]*ice.URL, 0, len(g.validatedServers))
+	for _, server := range g.validatedServers {
+		keepHost := server.Proto == ice.ProtoTypeTCP &&
+			(server.Scheme == ice.SchemeTypeTURNS || g.api.settingEngine.iceProxyDialer != nil)
+		if keepHost || net.ParseIP(server.Host) != nil {
+			servers = append(servers, server)
+			continue
+		}
+
+		address, err := resolveICEHost(resolver, server.Host)
+		if err != nil {
+			g.log.Warnf("Failed to resolve ICE server %s: %v", server.Host, err)
+			servers = append(servers, server)
+			continue
+		}
+
+		resolved := *server
+		resolved.Host = address
+		servers = append(servers, &resolved)
+	}
+	return servers
+}
+
+// resolveMDNSCandidate resolves the .local address of a remote candidate
+// with the ICEResolver of the SettingEngine, if one is set. The ICE agent
+// resolves it with mDNS otherwise.
+func (t *ICETransport) resolveMDNSCandidate(candidate ICECandidate) ICECandidate {
+	resolver := t.gatherer.api.settingEngine.iceResolver
+	if resolver == nil || !strings.HasSuffix(candidate.Address, ".local") {
+		return candidate
+	}
+
+	address, err := resolveICEHost(resolver, candidate.Address)
+	if err != nil {
+		t.log.Warnf("Failed to resolve mDNS candidate %s: %v", candidate.Address, err)
+		return candidate
+	}
+
+	candidate.Address = address
+	return candidate
+}
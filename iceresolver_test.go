@@ -0,0 +1,65 @@
+// +build !js
+
+package webrtc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/pion/ice/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+var errTestResolverUnknownHost = errors.New("unknown host")
+
+// testResolver resolves the host names in its map
+type testResolver map[string][]net.IPAddr
+
+func (r testResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	if addrs, ok := r[host]; ok {
+		return addrs, nil
+	}
+	return nil, errTestResolverUnknownHost
+}
+
+func TestICEResolver(t *testing.T) {
+	resolver := testResolver{
+		"stun.example.com":     {{IP: net.ParseIP("2001:db8::1")}, {IP: net.ParseIP("192.0.2.1")}},
+		"turn.example.com":     {{IP: net.ParseIP("192.0.2.2")}},
+		"host-candidate.local": {{IP: net.ParseIP("192.168.1.2")}},
+	}
+	s := SettingEngine{}
+	s.SetICEResolver(resolver)
+	api := NewAPI(WithSettingEngine(s))
+
+	gatherer, err := api.NewICEGatherer(ICEGatherOptions{
+		ICEServers: []ICEServer{
+			{URLs: []string{"stun:stun.example.com:3478", "stun:unknown.example.com:3478", "stun:192.0.2.3:3478"}},
+			{
+				URLs:       []string{"turn:turn.example.com:3478", "turns:turn.example.com:5349?transport=tcp"},
+				Username:   "user",
+				Credential: "pass",
+			},
+		},
+	})
+	assert.NoError(t, err)
+
+	// IPv4 addresses are preferred, TURN over TLS keeps its host name for
+	// its certificate and the servers of the ICE gatherer aren't modified
+	hosts := []string{}
+	for _, server := range gatherer.resolvedServers() {
+		hosts = append(hosts, server.Host)
+	}
+	assert.Equal(t, []string{"192.0.2.1", "unknown.example.com", "192.0.2.3", "192.0.2.2", "turn.example.com"}, hosts)
+	assert.Equal(t, "stun.example.com", gatherer.validatedServers[0].Host)
+	assert.Equal(t, ice.SchemeTypeSTUN, gatherer.resolvedServers()[0].Scheme)
+
+	transport := api.NewICETransport(gatherer)
+	candidate := ICECandidate{Address: "host-candidate.local", Port: 5000}
+	assert.Equal(t, "192.168.1.2", transport.resolveMDNSCandidate(candidate).Address)
+	candidate.Address = "unknown.local"
+	assert.Equal(t, "unknown.local", transport.resolveMDNSCandidate(candidate).Address)
+	assert.NoError(t, gatherer.Close())
+}
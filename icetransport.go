@@ -33,6 +33,10 @@ type ICETransport struct {
 	conn     *ice.Conn
 	mux      *mux.Mux
 
+	// stopped is set by Stop, so a Start racing with it doesn't create an
+	// agent that is never closed
+	stopped bool
+
 	loggerFactory logging.LoggerFactory
 
 	log logging.LeveledLogger
@@ -73,6 +77,9 @@ func (t *ICETransport) Start(gatherer *ICEGatherer, params ICEParameters, role *
 	t.lock.Lock()
 	defer t.lock.Unlock()
 
+	if t.stopped {
+		return errICETransportStopped
+	}
 	if gatherer != nil {
 		t.gatherer = gatherer
 	}
@@ -174,6 +181,7 @@ func (t *ICETransport) Stop() error {
 	t.lock.Lock()
 	defer t.lock.Unlock()
 
+	t.stopped = true
 	if t.mux != nil {
 		return t.mux.Close()
 	} else if t.gatherer != nil {
@@ -253,7 +261,7 @@ func (t *ICETransport) AddRemoteCandidate(remoteCandidate ICECandidate) error {
 		return err
 	}
 
-	c, err := remoteCandidate.toICE()
+	c, err := t.resolveMDNSCandidate(remoteCandidate).toICE()
 	if err != nil {
 		return err
 	}
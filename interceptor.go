@@ -0,0 +1,22 @@
+// +build !js
+
+package webrtc
+
+import (
+	"github.com/pion/webrtc/v3/pkg/interceptor"
+)
+
+func createStreamInfo(id string, ssrc uint32, payloadType uint8, codec *RTPCodec) interceptor.StreamInfo {
+	info := interceptor.StreamInfo{
+		ID:          id,
+		SSRC:        ssrc,
+		PayloadType: payloadType,
+	}
+
+	if codec != nil {
+		info.MimeType = codec.MimeType
+		info.ClockRate = codec.ClockRate
+	}
+
+	return info
+}
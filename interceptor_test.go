@@ -0,0 +1,85 @@
+// +build !js
+
+package webrtc
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/transport/test"
+	"github.com/pion/webrtc/v3/pkg/interceptor"
+	"github.com/pion/webrtc/v3/pkg/media"
+	"github.com/stretchr/testify/assert"
+)
+
+type countingInterceptor struct {
+	interceptor.NoOp
+	written, read uint32
+}
+
+func (c *countingInterceptor) BindLocalStream(_ *interceptor.StreamInfo, writer interceptor.RTPWriter) interceptor.RTPWriter {
+	return interceptor.RTPWriterFunc(func(header *rtp.Header, payload []byte) (int, error) {
+		atomic.AddUint32(&c.written, 1)
+		return writer.Write(header, payload)
+	})
+}
+
+func (c *countingInterceptor) BindRemoteStream(_ *interceptor.StreamInfo, reader interceptor.RTPReader) interceptor.RTPReader {
+	return interceptor.RTPReaderFunc(func(b []byte) (int, error) {
+		atomic.AddUint32(&c.read, 1)
+		return reader.Read(b)
+	})
+}
+
+func TestPeerConnection_Interceptor(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	counter := &countingInterceptor{}
+	ir := interceptor.Registry{}
+	ir.Add(counter)
+
+	m := MediaEngine{}
+	m.RegisterDefaultCodecs()
+	api := NewAPI(WithMediaEngine(m), WithInterceptorRegistry(ir))
+
+	pcOffer, pcAnswer, err := api.newPair(Configuration{})
+	assert.NoError(t, err)
+
+	track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, 5000, "video", "pion")
+	assert.NoError(t, err)
+
+	_, err = pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+
+	trackRead := make(chan struct{})
+	pcAnswer.OnTrack(func(track *Track, r *RTPReceiver) {
+		_, readErr := track.ReadRTP()
+		assert.NoError(t, readErr)
+		close(trackRead)
+	})
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	func() {
+		for {
+			select {
+			case <-time.After(20 * time.Millisecond):
+				assert.NoError(t, track.WriteSample(media.Sample{Data: []byte{0x00}, Samples: 1}))
+			case <-trackRead:
+				return
+			}
+		}
+	}()
+
+	assert.NotZero(t, atomic.LoadUint32(&counter.written))
+	assert.NotZero(t, atomic.LoadUint32(&counter.read))
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
@@ -0,0 +1,59 @@
+package util
+
+// SequenceUnwrapper extends 16-bit RTP sequence numbers to 64 bits, as in
+// RFC 3550 appendix A.1, so they keep increasing when they wrap around.
+// Reordered packets are placed before the highest sequence number instead
+// of being taken for a new cycle.
+type SequenceUnwrapper struct {
+	started bool
+	last    uint16
+	highest int64
+}
+
+// Unwrap returns the extended sequence number of seq
+func (u *SequenceUnwrapper) Unwrap(seq uint16) int64 {
+	if !u.started {
+		u.started = true
+		u.last, u.highest = seq, int64(seq)
+		return u.highest
+	}
+
+	extended := u.highest + int64(int16(seq-u.last))
+	if extended > u.highest {
+		u.last, u.highest = seq, extended
+	}
+	return extended
+}
+
+// Highest returns the highest extended sequence number unwrapped
+func (u *SequenceUnwrapper) Highest() int64 {
+	return u.highest
+}
+
+// TimestampUnwrapper extends 32-bit RTP timestamps to 64 bits, so they keep
+// increasing when they wrap around, which takes about 13 hours at 90kHz
+type TimestampUnwrapper struct {
+	started bool
+	last    uint32
+	highest int64
+}
+
+// Unwrap returns the extended timestamp of timestamp
+func (u *TimestampUnwrapper) Unwrap(timestamp uint32) int64 {
+	if !u.started {
+		u.started = true
+		u.last, u.highest = timestamp, int64(timestamp)
+		return u.highest
+	}
+
+	extended := u.highest + int64(int32(timestamp-u.last))
+	if extended > u.highest {
+		u.last, u.highest = timestamp, extended
+	}
+	return extended
+}
+
+// Highest returns the highest extended timestamp unwrapped
+func (u *TimestampUnwrapper) Highest() int64 {
+	return u.highest
+}
@@ -0,0 +1,47 @@
+package util
+
+import (
+	"testing"
+)
+
+func TestSequenceUnwrapper(t *testing.T) {
+	u := &SequenceUnwrapper{}
+	for _, c := range []struct {
+		seq      uint16
+		extended int64
+	}{
+		{65534, 65534},
+		{65535, 65535},
+		{1, 65537},
+		{0, 65536},
+		{65533, 65533},
+		{2, 65538},
+	} {
+		if extended := u.Unwrap(c.seq); extended != c.extended {
+			t.Errorf("Unwrap(%d) = %d, expected %d", c.seq, extended, c.extended)
+		}
+	}
+	if u.Highest() != 65538 {
+		t.Errorf("Highest() = %d, expected 65538", u.Highest())
+	}
+}
+
+func TestTimestampUnwrapper(t *testing.T) {
+	u := &TimestampUnwrapper{}
+	for _, c := range []struct {
+		timestamp uint32
+		extended  int64
+	}{
+		{0xFFFFF000, 0xFFFFF000},
+		{0x00000800, 0x100000800},
+		{0xFFFFF800, 0xFFFFF800},
+		{0x00001000, 0x100001000},
+	} {
+		if extended := u.Unwrap(c.timestamp); extended != c.extended {
+			t.Errorf("Unwrap(%d) = %d, expected %d", c.timestamp, extended, c.extended)
+		}
+	}
+	if u.Highest() != 0x100001000 {
+		t.Errorf("Highest() = %d, expected %d", u.Highest(), int64(0x100001000))
+	}
+}
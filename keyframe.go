@@ -0,0 +1,113 @@
+// +build !js
+
+package webrtc
+
+import (
+	"strings"
+)
+
+const (
+	mimeTypeAV1 = "video/AV1"
+
+	h264NALUTypeIDR   = 5
+	h264NALUTypeSPS   = 7
+	h264NALUTypeSTAPA = 24
+	h264NALUTypeFUA   = 28
+)
+
+// IsKeyframe checks if an RTP payload of codec mimeType is the start of
+// a keyframe, which a receiver can start decoding from. VP8, VP9, H264
+// and AV1 are supported, payloads of other codecs are never keyframes.
+func IsKeyframe(payload []byte, mimeType string) bool {
+	switch {
+	case strings.EqualFold(mimeType, mediaNameVideo+"/"+VP8):
+		return isVP8Keyframe(payload)
+	case strings.EqualFold(mimeType, mediaNameVideo+"/"+VP9):
+		return isVP9Keyframe(payload)
+	case strings.EqualFold(mimeType, mediaNameVideo+"/"+H264):
+		return isH264Keyframe(payload)
+	case strings.EqualFold(mimeType, mimeTypeAV1):
+		return isAV1Keyframe(payload)
+	default:
+		return false
+	}
+}
+
+// isVP8Keyframe checks the payload descriptor of RFC 7741 for the start of
+// the first partition, and the VP8 payload header for the key frame flag
+func isVP8Keyframe(payload []byte) bool {
+	if len(payload) < 1 {
+		return false
+	}
+
+	// S bit and a partition index of 0
+	if payload[0]&0x10 == 0 || payload[0]&0x07 != 0 {
+		return false
+	}
+
+	offset := 1
+	if payload[0]&0x80 != 0 {
+		if len(payload) < 2 {
+			return false
+		}
+		extensions := payload[1]
+		offset++
+
+		if extensions&0x80 != 0 { // PictureID, 7 or 15 bits
+			if len(payload) <= offset {
+				return false
+			}
+			if payload[offset]&0x80 != 0 {
+				offset++
+			}
+			offset++
+		}
+		if extensions&0x40 != 0 { // TL0PICIDX
+			offset++
+		}
+		if extensions&0x30 != 0 { // TID and KEYIDX
+			offset++
+		}
+	}
+
+	// P bit of the VP8 payload header is 0 for keyframes
+	return len(payload) > offset && payload[offset]&0x01 == 0
+}
+
+// isVP9Keyframe checks the payload descriptor of the VP9 RTP payload format
+// for the start of a frame that isn't inter-picture predicted
+func isVP9Keyframe(payload []byte) bool {
+	return len(payload) >= 1 && payload[0]&0x40 == 0 && payload[0]&0x08 != 0
+}
+
+// isH264Keyframe checks for an IDR slice or SPS, either as a single NAL unit,
+// aggregated in a STAP-A or as the start of an FU-A
+func isH264Keyframe(payload []byte) bool {
+	if len(payload) < 1 {
+		return false
+	}
+
+	switch naluType := payload[0] & 0x1F; naluType {
+	case h264NALUTypeIDR, h264NALUTypeSPS:
+		return true
+	case h264NALUTypeSTAPA:
+		for offset := 1; offset+2 < len(payload); {
+			size := int(payload[offset])<<8 | int(payload[offset+1])
+			switch payload[offset+2] & 0x1F {
+			case h264NALUTypeIDR, h264NALUTypeSPS:
+				return true
+			}
+			offset += 2 + size
+		}
+	case h264NALUTypeFUA:
+		return len(payload) >= 2 && payload[1]&0x80 != 0 && payload[1]&0x1F == h264NALUTypeIDR
+	}
+
+	return false
+}
+
+// isAV1Keyframe checks the N bit of the AV1 aggregation header, which is
+// set for the first packet of a coded video sequence
+func isAV1Keyframe(payload []byte) bool {
+	return len(payload) >= 1 && payload[0]&0x08 != 0
+}
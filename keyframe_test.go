@@ -0,0 +1,40 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsKeyframe(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		mimeType string
+		payload  []byte
+		keyframe bool
+	}{
+		{"VP8 keyframe", "video/VP8", []byte{0x10, 0x00}, true},
+		{"VP8 keyframe with PictureID", "video/vp8", []byte{0x90, 0x80, 0x81, 0x02, 0x00}, true},
+		{"VP8 interframe", "video/VP8", []byte{0x10, 0x01}, false},
+		{"VP8 continuation", "video/VP8", []byte{0x00, 0x00}, false},
+		{"VP8 truncated", "video/VP8", []byte{0x90, 0x80}, false},
+		{"VP9 keyframe", "video/VP9", []byte{0x08}, true},
+		{"VP9 interframe", "video/VP9", []byte{0x48}, false},
+		{"VP9 end of keyframe", "video/VP9", []byte{0x04}, false},
+		{"H264 IDR", "video/H264", []byte{0x65, 0x88}, true},
+		{"H264 SPS", "video/H264", []byte{0x67, 0x42}, true},
+		{"H264 non-IDR", "video/H264", []byte{0x41, 0x9A}, false},
+		{"H264 STAP-A", "video/H264", []byte{0x78, 0x00, 0x01, 0x09, 0x00, 0x02, 0x67, 0x42}, true},
+		{"H264 STAP-A non-IDR", "video/H264", []byte{0x78, 0x00, 0x02, 0x41, 0x9A}, false},
+		{"H264 FU-A start", "video/H264", []byte{0x7C, 0x85}, true},
+		{"H264 FU-A middle", "video/H264", []byte{0x7C, 0x05}, false},
+		{"AV1 new sequence", "video/AV1", []byte{0x18}, true},
+		{"AV1 interframe", "video/AV1", []byte{0x10}, false},
+		{"Opus", "audio/opus", []byte{0xFF}, false},
+		{"Empty", "video/VP8", []byte{}, false},
+	} {
+		assert.Equal(t, test.keyframe, IsKeyframe(test.payload, test.mimeType), test.name)
+	}
+}
@@ -0,0 +1,130 @@
+// +build !js
+
+package webrtc
+
+import (
+	"encoding/binary"
+	"sync"
+
+	"github.com/pion/rtcp"
+)
+
+// usesFIR tells if keyframes of codec are requested with a Full Intra
+// Request, as ccm fir is its only keyframe feedback. PLI is used otherwise,
+// also when no feedback was negotiated.
+func usesFIR(codec *RTPCodec) bool {
+	if codec == nil {
+		return false
+	}
+
+	fir := false
+	for _, feedback := range codec.RTCPFeedback {
+		switch {
+		case feedback.Type == TypeRTCPFBNACK && feedback.Parameter == "pli":
+			return false
+		case feedback.Type == TypeRTCPFBCCM && feedback.Parameter == "fir":
+			fir = true
+		}
+	}
+	return fir
+}
+
+// keyframeRequests returns the PLI or FIR packets requesting a keyframe for
+// every Track of the RTPReceiver. Each FIR has a new sequence number, as
+// RFC 5104 section 4.3.1.2 asks.
+func (r *RTPReceiver) keyframeRequests() []rtcp.Packet {
+	pkts := []rtcp.Packet{}
+	for _, t := range r.Tracks() {
+		ssrc := t.SSRC()
+		if ssrc == 0 {
+			continue
+		}
+
+		if !usesFIR(t.Codec()) {
+			pkts = append(pkts, &rtcp.PictureLossIndication{MediaSSRC: ssrc})
+			continue
+		}
+
+		r.pliMu.Lock()
+		r.firSequenceNumber++
+		sequenceNumber := r.firSequenceNumber
+		r.pliMu.Unlock()
+		pkts = append(pkts, &rtcp.FullIntraRequest{
+			MediaSSRC: ssrc,
+			FIR:       []rtcp.FIREntry{{SSRC: ssrc, SequenceNumber: sequenceNumber}},
+		})
+	}
+	return pkts
+}
+
+// keyframeRequestHandler calls the OnKeyframeRequest handler of an
+// RTPSender
+type keyframeRequestHandler struct {
+	mu      sync.Mutex
+	handler func()
+
+	// lastFIR is the sequence number of the last FIR, retransmissions of it
+	// aren't new requests
+	hasFIR  bool
+	lastFIR uint8
+}
+
+// OnKeyframeRequest sets an event handler which is called when the receiver
+// asks for a keyframe with a PLI or a FIR, so the encoder of the Track can
+// be driven directly. Requests are learned from the RTCP of the RTPSender
+// as it arrives, whether the application reads it or not.
+func (r *RTPSender) OnKeyframeRequest(f func()) {
+	r.keyframeRequest.mu.Lock()
+	defer r.keyframeRequest.mu.Unlock()
+	r.keyframeRequest.handler = f
+}
+
+// handleKeyframeRequest calls the OnKeyframeRequest handler for the PLI and
+// FIR packets for the SSRC of the RTPSender in the RTCP it received
+func (r *RTPSender) handleKeyframeRequest(b []byte) {
+	ssrc := r.SSRC()
+	requested := false
+	for len(b) >= 4 {
+		length := (int(binary.BigEndian.Uint16(b[2:])) + 1) * 4
+		if length > len(b) {
+			return
+		}
+		if rtcp.PacketType(b[1]) == rtcp.TypePayloadSpecificFeedback {
+			switch b[0] & 0x1F {
+			case rtcp.FormatPLI:
+				requested = requested || (length >= 12 && binary.BigEndian.Uint32(b[8:]) == ssrc)
+			case rtcp.FormatFIR:
+				for offset := 12; offset+8 <= length; offset += 8 {
+					if binary.BigEndian.Uint32(b[offset:]) == ssrc && r.newFIR(b[offset+4]) {
+						requested = true
+					}
+				}
+			}
+		}
+		b = b[length:]
+	}
+
+	if !requested {
+		return
+	}
+	r.keyframeRequest.mu.Lock()
+	handler := r.keyframeRequest.handler
+	r.keyframeRequest.mu.Unlock()
+	if handler != nil {
+		handler()
+	}
+}
+
+// newFIR tells if a FIR with sequenceNumber isn't a retransmission of the
+// previous one
+func (r *RTPSender) newFIR(sequenceNumber uint8) bool {
+	r.keyframeRequest.mu.Lock()
+	defer r.keyframeRequest.mu.Unlock()
+
+	if r.keyframeRequest.hasFIR && r.keyframeRequest.lastFIR == sequenceNumber {
+		return false
+	}
+	r.keyframeRequest.hasFIR = true
+	r.keyframeRequest.lastFIR = sequenceNumber
+	return true
+}
@@ -0,0 +1,108 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/transport/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRTPReceiver_keyframeRequests(t *testing.T) {
+	pli := []RTCPFeedback{{Type: TypeRTCPFBNACK, Parameter: "pli"}, {Type: TypeRTCPFBCCM, Parameter: "fir"}}
+	fir := []RTCPFeedback{{Type: TypeRTCPFBNACK}, {Type: TypeRTCPFBCCM, Parameter: "fir"}}
+	assert.False(t, usesFIR(nil))
+	assert.False(t, usesFIR(&RTPCodec{}))
+	assert.False(t, usesFIR(&RTPCodec{RTPCodecCapability: RTPCodecCapability{RTCPFeedback: pli}}))
+	assert.True(t, usesFIR(&RTPCodec{RTPCodecCapability: RTPCodecCapability{RTCPFeedback: fir}}))
+
+	r := &RTPReceiver{tracks: []trackStreams{
+		{track: &Track{ssrc: 1, codec: &RTPCodec{RTPCodecCapability: RTPCodecCapability{RTCPFeedback: pli}}}},
+		{track: &Track{ssrc: 2, codec: &RTPCodec{RTPCodecCapability: RTPCodecCapability{RTCPFeedback: fir}}}},
+		{track: &Track{rid: "f"}},
+	}}
+	assert.Equal(t, []rtcp.Packet{
+		&rtcp.PictureLossIndication{MediaSSRC: 1},
+		&rtcp.FullIntraRequest{MediaSSRC: 2, FIR: []rtcp.FIREntry{{SSRC: 2, SequenceNumber: 1}}},
+	}, r.keyframeRequests())
+	assert.Equal(t, &rtcp.FullIntraRequest{MediaSSRC: 2, FIR: []rtcp.FIREntry{{SSRC: 2, SequenceNumber: 2}}}, r.keyframeRequests()[1])
+}
+
+func TestRTPSender_OnKeyframeRequest(t *testing.T) {
+	r := &RTPSender{track: &Track{ssrc: 5000}}
+	requests := 0
+	r.OnKeyframeRequest(func() {
+		requests++
+	})
+
+	marshal := func(pkts ...rtcp.Packet) []byte {
+		b, err := rtcp.Marshal(pkts)
+		assert.NoError(t, err)
+		return b
+	}
+
+	r.handleKeyframeRequest(marshal(&rtcp.ReceiverReport{}, &rtcp.PictureLossIndication{MediaSSRC: 4000}))
+	assert.Equal(t, 0, requests)
+	r.handleKeyframeRequest(marshal(&rtcp.ReceiverReport{}, &rtcp.PictureLossIndication{MediaSSRC: 5000}))
+	assert.Equal(t, 1, requests)
+
+	// Retransmissions of a FIR aren't new requests
+	fir := marshal(&rtcp.FullIntraRequest{MediaSSRC: 5000, FIR: []rtcp.FIREntry{{SSRC: 4000}, {SSRC: 5000, SequenceNumber: 7}}})
+	r.handleKeyframeRequest(fir)
+	r.handleKeyframeRequest(fir)
+	assert.Equal(t, 2, requests)
+	r.handleKeyframeRequest(marshal(&rtcp.FullIntraRequest{MediaSSRC: 5000, FIR: []rtcp.FIREntry{{SSRC: 5000, SequenceNumber: 8}}}))
+	assert.Equal(t, 3, requests)
+}
+
+func TestRTPSender_OnKeyframeRequestUnread(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	pcOffer, pcAnswer, err := newPair()
+	assert.NoError(t, err)
+
+	track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, 5000, "video", "pion")
+	assert.NoError(t, err)
+	sender, err := pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+
+	// The RTCP of the RTPSender isn't read by the application
+	requested := make(chan struct{}, 1)
+	sender.OnKeyframeRequest(func() {
+		select {
+		case requested <- struct{}{}:
+		default:
+		}
+	})
+
+	onTrack := make(chan struct{})
+	var receiver *RTPReceiver
+	pcAnswer.OnTrack(func(_ *Track, r *RTPReceiver) {
+		receiver = r
+		close(onTrack)
+	})
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	sendVideoUntilDone(onTrack, t, []*Track{track})
+
+	// The PLI is resent in case it's lost
+	ticker := time.NewTicker(20 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		assert.NoError(t, receiver.WriteRTCP([]rtcp.Packet{&rtcp.PictureLossIndication{MediaSSRC: track.SSRC()}}))
+
+		select {
+		case <-requested:
+			closePairNow(t, pcOffer, pcAnswer)
+			return
+		case <-ticker.C:
+		}
+	}
+}
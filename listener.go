@@ -0,0 +1,243 @@
+// +build !js
+
+package webrtc
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pion/logging"
+)
+
+const defaultListenerConnectTimeout = 30 * time.Second
+
+// SignalerListener accepts a Signaler for each remote peer that connects
+// to the signaling of an application, like a WebSocket server does
+type SignalerListener interface {
+	// Accept blocks until the next remote peer connects. The Listener stops
+	// accepting PeerConnections once it returns an error.
+	Accept() (Signaler, error)
+
+	// Close unblocks Accept, it is called when the Listener is closed
+	Close() error
+}
+
+// ListenerOptions configures a Listener
+type ListenerOptions struct {
+	// Configuration of the PeerConnections, unless overridden by Configure
+	Configuration Configuration
+
+	// Configure returns the Configuration of the PeerConnection negotiated
+	// over signaler, which overrides Configuration unless it is nil
+	Configure func(signaler Signaler) *Configuration
+
+	// OnPeerConnection is called for each Signaler before the offer is
+	// received, to set event handlers or add Tracks. The remote peer is
+	// rejected if it returns an error.
+	OnPeerConnection func(*PeerConnection, Signaler) error
+
+	// ConnectTimeout is how long a PeerConnection may take to connect after
+	// its Signaler was accepted, 30 seconds if zero
+	ConnectTimeout time.Duration
+}
+
+// Listener answers the offers of remote peers, received over the Signalers
+// of a SignalerListener, and returns their PeerConnections once connected,
+// like a net.Listener does for connections. PeerConnections that fail to
+// connect are closed and never returned. The Signaler of a PeerConnection
+// is closed once it connected or failed to.
+type Listener struct {
+	api       *API
+	signalers SignalerListener
+	options   ListenerOptions
+
+	accepted chan *PeerConnection
+	done     chan struct{}
+
+	log logging.LeveledLogger
+
+	mu  sync.Mutex
+	err error
+}
+
+// NewListener creates a Listener with the default codecs.
+// See API.NewListener for details.
+func NewListener(signalers SignalerListener, options ListenerOptions) *Listener {
+	m := MediaEngine{}
+	m.RegisterDefaultCodecs()
+	api := NewAPI(WithMediaEngine(m))
+	return api.NewListener(signalers, options)
+}
+
+// NewListener creates a Listener that answers the offers received over the
+// Signalers accepted by signalers
+func (api *API) NewListener(signalers SignalerListener, options ListenerOptions) *Listener {
+	if options.ConnectTimeout == 0 {
+		options.ConnectTimeout = defaultListenerConnectTimeout
+	}
+
+	l := &Listener{
+		api:       api,
+		signalers: signalers,
+		options:   options,
+		accepted:  make(chan *PeerConnection),
+		done:      make(chan struct{}),
+		log:       api.settingEngine.LoggerFactory.NewLogger("listener"),
+	}
+	go l.acceptSignalers()
+
+	return l
+}
+
+// Accept waits for the next connected PeerConnection. It returns the error
+// of the SignalerListener once it stops accepting Signalers, or an error
+// once the Listener is closed.
+func (l *Listener) Accept() (*PeerConnection, error) {
+	select {
+	case pc := <-l.accepted:
+		return pc, nil
+	case <-l.done:
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		return nil, l.err
+	}
+}
+
+// Close stops accepting offers and closes the SignalerListener.
+// PeerConnections which haven't been returned by Accept yet are closed.
+func (l *Listener) Close() error {
+	if !l.stop(errListenerClosed) {
+		return nil
+	}
+	return l.signalers.Close()
+}
+
+// stop makes Accept return err, and returns false if already stopped
+func (l *Listener) stop(err error) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.err != nil {
+		return false
+	}
+	l.err = err
+	close(l.done)
+	return true
+}
+
+func (l *Listener) acceptSignalers() {
+	for {
+		signaler, err := l.signalers.Accept()
+		if err != nil {
+			l.stop(err)
+			return
+		}
+
+		go l.negotiate(signaler)
+	}
+}
+
+// negotiate answers the offer received over signaler and passes its
+// PeerConnection to Accept once connected
+func (l *Listener) negotiate(signaler Signaler) {
+	defer func() {
+		if err := signaler.Close(); err != nil {
+			l.log.Warnf("Failed to close Signaler: %v", err)
+		}
+	}()
+
+	configuration := l.options.Configuration
+	if l.options.Configure != nil {
+		if override := l.options.Configure(signaler); override != nil {
+			configuration = *override
+		}
+	}
+
+	pc, err := l.api.NewPeerConnection(configuration)
+	if err != nil {
+		l.log.Warnf("Failed to create PeerConnection: %v", err)
+		return
+	}
+
+	if err = l.connect(pc, signaler); err != nil {
+		l.log.Warnf("Failed to connect PeerConnection: %v", err)
+		if closeErr := pc.Close(); closeErr != nil {
+			l.log.Warnf("Failed to close PeerConnection: %v", closeErr)
+		}
+		return
+	}
+
+	select {
+	case l.accepted <- pc:
+	case <-l.done:
+		if closeErr := pc.Close(); closeErr != nil {
+			l.log.Warnf("Failed to close PeerConnection: %v", closeErr)
+		}
+	}
+}
+
+// connect answers the offer received over signaler with pc and waits until
+// pc is connected
+func (l *Listener) connect(pc *PeerConnection, signaler Signaler) error {
+	ctx, cancel := context.WithTimeout(context.Background(), l.options.ConnectTimeout)
+	defer cancel()
+	go func() {
+		select {
+		case <-l.done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	if l.options.OnPeerConnection != nil {
+		if err := l.options.OnPeerConnection(pc, signaler); err != nil {
+			return err
+		}
+	}
+
+	// Watch the connection state without replacing the handler of OnPeerConnection
+	connectionState := make(chan PeerConnectionState, 1)
+	pc.mu.Lock()
+	handler := pc.onConnectionStateChangeHandler
+	pc.onConnectionStateChangeHandler = func(state PeerConnectionState) {
+		if handler != nil {
+			handler(state)
+		}
+		if state == PeerConnectionStateConnected || state == PeerConnectionStateFailed {
+			select {
+			case connectionState <- state:
+			default:
+			}
+		}
+	}
+	pc.mu.Unlock()
+
+	if err := Negotiate(ctx, pc, signaler, false); err != nil {
+		return l.contextError(ctx, err)
+	}
+
+	select {
+	case state := <-connectionState:
+		if state != PeerConnectionStateConnected {
+			return errListenerConnectFailed
+		}
+		return nil
+	case <-ctx.Done():
+		return l.contextError(ctx, ctx.Err())
+	}
+}
+
+// contextError returns why ctx of connect is done if err is its error
+func (l *Listener) contextError(ctx context.Context, err error) error {
+	if err != ctx.Err() {
+		return err
+	}
+
+	select {
+	case <-l.done:
+		return errListenerClosed
+	default:
+		return errListenerConnectTimeout
+	}
+}
@@ -0,0 +1,115 @@
+// +build !js
+
+package webrtc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/pion/transport/test"
+	"github.com/stretchr/testify/assert"
+)
+
+// chanSignalerListener is a SignalerListener that accepts the chanSignalers
+// of dial
+type chanSignalerListener struct {
+	signalers chan Signaler
+	closed    chan struct{}
+}
+
+func newChanSignalerListener() *chanSignalerListener {
+	return &chanSignalerListener{signalers: make(chan Signaler), closed: make(chan struct{})}
+}
+
+// dial returns the Signaler of a remote peer of the Listener
+func (l *chanSignalerListener) dial() Signaler {
+	local, remote := newChanSignalerPair()
+	l.signalers <- remote
+	return local
+}
+
+func (l *chanSignalerListener) Accept() (Signaler, error) {
+	select {
+	case signaler := <-l.signalers:
+		return signaler, nil
+	case <-l.closed:
+		return nil, io.EOF
+	}
+}
+
+func (l *chanSignalerListener) Close() error {
+	close(l.closed)
+	return nil
+}
+
+func TestListener(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	signalers := newChanSignalerListener()
+	errRejected := errors.New("rejected")
+	configured := make(chan Signaler, 1)
+	listener := NewListener(signalers, ListenerOptions{
+		Configure: func(signaler Signaler) *Configuration {
+			select {
+			case rejected := <-configured:
+				if rejected == signaler {
+					return &Configuration{PeerIdentity: "rejected"}
+				}
+			default:
+			}
+			return nil
+		},
+		OnPeerConnection: func(pc *PeerConnection, signaler Signaler) error {
+			if pc.GetConfiguration().PeerIdentity == "rejected" {
+				return errRejected
+			}
+			return nil
+		},
+	})
+
+	// A rejected remote peer isn't answered, its Signaler is closed
+	pcRejected, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	_, err = pcRejected.CreateDataChannel("data", nil)
+	assert.NoError(t, err)
+	local, remote := newChanSignalerPair()
+	configured <- remote
+	signalers.signalers <- remote
+	assert.Error(t, Negotiate(context.Background(), pcRejected, local, true))
+
+	pcOffer, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	_, err = pcOffer.CreateDataChannel("data", nil)
+	assert.NoError(t, err)
+	signaler := signalers.dial()
+	assert.NoError(t, Negotiate(context.Background(), pcOffer, signaler, true))
+
+	pcAnswer, err := listener.Accept()
+	assert.NoError(t, err)
+	assert.Equal(t, PeerConnectionStateConnected, pcAnswer.ConnectionState())
+
+	assert.NoError(t, listener.Close())
+	_, err = listener.Accept()
+	assert.Equal(t, errListenerClosed, err)
+	assert.NoError(t, listener.Close())
+
+	assert.NoError(t, pcRejected.Close())
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestListener_SignalerListenerError(t *testing.T) {
+	signalers := newChanSignalerListener()
+	listener := NewListener(signalers, ListenerOptions{})
+
+	assert.NoError(t, signalers.Close())
+	_, err := listener.Accept()
+	assert.Equal(t, io.EOF, err)
+}
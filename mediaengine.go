@@ -11,6 +11,13 @@ import (
 	"github.com/pion/rtp"
 	"github.com/pion/rtp/codecs"
 	"github.com/pion/sdp/v3"
+	"github.com/pion/webrtc/v3/pkg/media/aacpacketizer"
+	"github.com/pion/webrtc/v3/pkg/media/framepacketizer"
+	"github.com/pion/webrtc/v3/pkg/media/h264packetizer"
+	"github.com/pion/webrtc/v3/pkg/media/jpegpacketizer"
+	"github.com/pion/webrtc/v3/pkg/media/pcmpacketizer"
+	"github.com/pion/webrtc/v3/pkg/media/rawvideopacketizer"
+	"github.com/pion/webrtc/v3/pkg/media/red"
 )
 
 // PayloadTypes for the default codecs
@@ -23,6 +30,24 @@ const (
 	DefaultPayloadTypeVP9  = 98
 	DefaultPayloadTypeH264 = 102
 
+	// DefaultPayloadTypeJPEG is the static payload type of RFC 3551,
+	// JPEG isn't registered by RegisterDefaultCodecs
+	DefaultPayloadTypeJPEG = 26
+
+	// DefaultPayloadTypeL16Stereo and DefaultPayloadTypeL16Mono are the
+	// static payload types of RFC 3551 for L16 at 44100 Hz, L16 isn't
+	// registered by RegisterDefaultCodecs
+	DefaultPayloadTypeL16Stereo = 10
+	DefaultPayloadTypeL16Mono   = 11
+
+	// DefaultPayloadTypeG729 is the static payload type of RFC 3551,
+	// G729 isn't registered by RegisterDefaultCodecs
+	DefaultPayloadTypeG729 = 18
+
+	// DefaultPayloadTypeTelephoneEvent is the payload type browsers use for
+	// telephone-event at 8000 Hz, it isn't registered by RegisterDefaultCodecs
+	DefaultPayloadTypeTelephoneEvent = 101
+
 	mediaNameAudio = "audio"
 	mediaNameVideo = "video"
 )
@@ -32,12 +57,80 @@ const (
 // may be set up once and reused, including concurrently,
 // as long as no other codecs are added subsequently.
 // MediaEngines populated using PopulateFromSDP should be used
-// only for that session.
+// only for that session, a Copy of a template MediaEngine can be populated
+// for each session instead.
 type MediaEngine struct {
 	codecs []*RTPCodec
+
+	// payloaders are the factories registered with RegisterPayloader, by
+	// lowercase mime type
+	payloaders map[string]func() rtp.Payloader
+}
+
+// newTrack creates a Track with the codec of m with payloadType
+func (m *MediaEngine) newTrack(payloadType uint8, ssrc uint32, id, label string) (*Track, error) {
+	codec, err := m.getCodec(payloadType)
+	if err != nil {
+		return nil, err
+	}
+
+	// A registered payloader is created for each Track, as it may keep state
+	if payloader := m.newPayloader(codec); payloader != nil {
+		return newTrack(payloadType, ssrc, id, label, codec, payloader)
+	}
+	if codec.Payloader == nil {
+		return nil, errPeerConnCodecPayloaderNotSet
+	}
+
+	return NewTrack(payloadType, ssrc, id, label, codec)
 }
 
-// RegisterCodec adds codec to m.
+// Copy returns a deep copy of m, whose codecs can be registered and
+// populated from a remote description without changing m, so a MediaEngine
+// set up once can be customized for each PeerConnection.
+// Copy is not safe for concurrent use with the methods registering codecs.
+func (m *MediaEngine) Copy() MediaEngine {
+	c := MediaEngine{}
+	for _, codec := range m.codecs {
+		copied := *codec
+		copied.RTCPFeedback = append([]RTCPFeedback(nil), codec.RTCPFeedback...)
+		c.codecs = append(c.codecs, &copied)
+	}
+
+	for mimeType, newPayloader := range m.payloaders {
+		c.RegisterPayloader(mimeType, newPayloader)
+	}
+	return c
+}
+
+// RegisterPayloader sets the factory of the payloaders of the codecs with
+// mimeType, like "video/H265", so codecs Pion WebRTC doesn't know can be
+// written with WriteSample. Codecs with mimeType found by PopulateFromSDP
+// are added with a payloader from it, and PeerConnection.NewTrack gives
+// each Track of such a codec its own payloader. It overrides the built-in
+// payloader of a known codec.
+// RegisterPayloader is not safe for concurrent use.
+func (m *MediaEngine) RegisterPayloader(mimeType string, newPayloader func() rtp.Payloader) {
+	if m.payloaders == nil {
+		m.payloaders = map[string]func() rtp.Payloader{}
+	}
+	m.payloaders[strings.ToLower(mimeType)] = newPayloader
+}
+
+// newPayloader returns a payloader from the factory registered for the mime
+// type of codec, nil if there is none
+func (m *MediaEngine) newPayloader(codec *RTPCodec) rtp.Payloader {
+	newPayloader, ok := m.payloaders[strings.ToLower(codec.MimeType)]
+	if !ok {
+		return nil
+	}
+	return newPayloader()
+}
+
+// RegisterCodec adds codec to m and returns its payload type, without
+// checking whether the payload type is used by another codec. Use
+// RegisterCodecDynamic to move it to a free payload type, or
+// RegisterCodecStrict to get an error on a conflict.
 // RegisterCodec is not safe for concurrent use.
 func (m *MediaEngine) RegisterCodec(codec *RTPCodec) uint8 {
 	// nolint:godox
@@ -47,7 +140,83 @@ func (m *MediaEngine) RegisterCodec(codec *RTPCodec) uint8 {
 	return codec.PayloadType
 }
 
+// RegisterCodecDynamic adds codec to m and returns its payload type.
+// A codec already registered with the same payload type isn't added again.
+// If the payload type is used by another codec, a copy of codec is added
+// with a free dynamic payload type in the range 96-127, codec isn't
+// modified. ErrNoFreePayloadType is returned if they are all used.
+// RegisterCodecDynamic is not safe for concurrent use.
+func (m *MediaEngine) RegisterCodecDynamic(codec *RTPCodec) (uint8, error) {
+	if registered, err := m.getCodec(codec.PayloadType); err == nil {
+		if registered.matches(codec) {
+			return codec.PayloadType, nil
+		}
+
+		payloadType, ok := m.freeDynamicPayloadType()
+		if !ok {
+			return 0, fmt.Errorf("%w: %d is used by %s", ErrNoFreePayloadType, codec.PayloadType, registered.MimeType)
+		}
+		moved := *codec
+		moved.PayloadType = payloadType
+		codec = &moved
+	}
+	m.codecs = append(m.codecs, codec)
+	return codec.PayloadType, nil
+}
+
+// RegisterCodecStrict adds codec to m like RegisterCodecDynamic, but returns
+// ErrPayloadTypeConflict instead of changing its payload type if it is used
+// by another codec. The channels of Opus and multiopus codecs are validated
+// against their OpusParameters.
+// RegisterCodecStrict is not safe for concurrent use.
+func (m *MediaEngine) RegisterCodecStrict(codec *RTPCodec) error {
+	switch {
+	case strings.EqualFold(codec.Name, Opus) && codec.Channels != 2:
+		return errOpusChannelsInvalid
+	case strings.EqualFold(codec.Name, MultiOpus):
+		if err := codec.OpusParameters().ValidateChannels(codec.Channels); err != nil {
+			return err
+		}
+	}
+	if registered, err := m.getCodec(codec.PayloadType); err == nil {
+		if registered.matches(codec) {
+			return nil
+		}
+		return fmt.Errorf("%w: %d is used by %s", ErrPayloadTypeConflict, codec.PayloadType, registered.MimeType)
+	}
+	m.codecs = append(m.codecs, codec)
+	return nil
+}
+
+// registerRemoteCodec adds codec from a remote description to m. The
+// payload types of the offerer are kept, so a codec registered with the
+// same payload type is replaced. The codecs are filtered into a new slice, as
+// copies of m may share its array.
+func (m *MediaEngine) registerRemoteCodec(codec *RTPCodec) {
+	codecs := make([]*RTPCodec, 0, len(m.codecs)+1)
+	for _, registered := range m.codecs {
+		if registered.PayloadType != codec.PayloadType {
+			codecs = append(codecs, registered)
+		} else if registered.matches(codec) {
+			return
+		}
+	}
+	m.codecs = append(codecs, codec)
+}
+
+// freeDynamicPayloadType returns the lowest dynamic payload type no codec
+// of m uses
+func (m *MediaEngine) freeDynamicPayloadType() (uint8, bool) {
+	for payloadType := uint8(96); payloadType <= 127; payloadType++ {
+		if _, err := m.getCodec(payloadType); err != nil {
+			return payloadType, true
+		}
+	}
+	return 0, false
+}
+
 // RegisterDefaultCodecs registers the default codecs supported by Pion WebRTC.
+// Codecs that don't fit in the dynamic payload types left are skipped.
 // RegisterDefaultCodecs is not safe for concurrent use.
 func (m *MediaEngine) RegisterDefaultCodecs() {
 	// Audio Codecs in descending order of preference
@@ -62,12 +231,73 @@ func (m *MediaEngine) RegisterDefaultCodecs() {
 	m.RegisterCodec(NewRTPH264Codec(DefaultPayloadTypeH264, 90000))
 }
 
+// withRemotePayloadTypes returns a copy of m whose codecs use the payload
+// types of the same codecs in the remote offer sd, or m if they already do.
+// Codecs of m using a payload type taken this way move to a free dynamic one.
+// m isn't modified, so it can be shared by PeerConnections.
+func (m *MediaEngine) withRemotePayloadTypes(sd *sdp.SessionDescription) *MediaEngine {
+	remote := map[*RTPCodec]uint8{}
+	taken := map[uint8]bool{}
+	changed := false
+	for _, md := range sd.MediaDescriptions {
+		if md.MediaName.Media != mediaNameAudio && md.MediaName.Media != mediaNameVideo {
+			continue
+		}
+
+		// The codecs are looked up in their section only, as offers can have
+		// many sections
+		section := &sdp.SessionDescription{MediaDescriptions: []*sdp.MediaDescription{md}}
+		for _, format := range md.MediaName.Formats {
+			pt, err := strconv.ParseUint(format, 10, 7)
+			if err != nil {
+				continue
+			}
+			payloadCodec, err := section.GetCodecForPayloadType(uint8(pt))
+			if err != nil {
+				continue
+			}
+			codec, err := m.getCodecSDP(payloadCodec)
+			if err != nil {
+				continue
+			}
+			if _, ok := remote[codec]; ok || taken[uint8(pt)] {
+				continue
+			}
+
+			remote[codec] = uint8(pt)
+			taken[uint8(pt)] = true
+			changed = changed || codec.PayloadType != uint8(pt)
+		}
+	}
+	if !changed {
+		return m
+	}
+
+	c := m.Copy()
+	for i, codec := range m.codecs {
+		if payloadType, ok := remote[codec]; ok {
+			c.codecs[i].PayloadType = payloadType
+		}
+	}
+	for i, codec := range m.codecs {
+		if _, ok := remote[codec]; ok || !taken[codec.PayloadType] {
+			continue
+		}
+		if payloadType, ok := c.freeDynamicPayloadType(); ok {
+			c.codecs[i].PayloadType = payloadType
+		}
+	}
+	return &c
+}
+
 // PopulateFromSDP finds all codecs in sd and adds them to m, using the dynamic
 // payload types and parameters from sd.
 // PopulateFromSDP is intended for use when answering a request.
 // The offerer sets the PayloadTypes for the connection.
 // PopulateFromSDP allows an answerer to properly match the PayloadTypes from the offerer.
 // A MediaEngine populated by PopulateFromSDP should be used only for a single session.
+// It isn't needed to match the payload types of the offer: a PeerConnection
+// answers with them for the codecs its MediaEngine shares with the offer.
 func (m *MediaEngine) PopulateFromSDP(sd SessionDescription) error {
 	sdp := sdp.SessionDescription{}
 	if err := sdp.Unmarshal([]byte(sd.SDP)); err != nil {
@@ -100,20 +330,75 @@ func (m *MediaEngine) PopulateFromSDP(sd SessionDescription) error {
 			case strings.EqualFold(payloadCodec.Name, G722):
 				codec = NewRTPG722Codec(payloadType, payloadCodec.ClockRate)
 			case strings.EqualFold(payloadCodec.Name, Opus):
+				if payloadCodec.EncodingParameters != "" && payloadCodec.EncodingParameters != "2" {
+					continue
+				}
 				codec = NewRTPOpusCodec(payloadType, payloadCodec.ClockRate)
+			case strings.EqualFold(payloadCodec.Name, MultiOpus):
+				channels := pcmChannels(payloadCodec.EncodingParameters)
+				if ParseOpusParameters(payloadCodec.Fmtp).ValidateChannels(channels) != nil {
+					continue
+				}
+				codec = NewRTPMultiOpusCodec(payloadType, payloadCodec.ClockRate, channels)
 			case strings.EqualFold(payloadCodec.Name, VP8):
 				codec = NewRTPVP8Codec(payloadType, payloadCodec.ClockRate)
 			case strings.EqualFold(payloadCodec.Name, VP9):
 				codec = NewRTPVP9Codec(payloadType, payloadCodec.ClockRate)
 			case strings.EqualFold(payloadCodec.Name, H264):
 				codec = NewRTPH264Codec(payloadType, payloadCodec.ClockRate)
+			case strings.EqualFold(payloadCodec.Name, JPEG):
+				codec = NewRTPJPEGCodec(payloadType, payloadCodec.ClockRate)
+			case strings.EqualFold(payloadCodec.Name, RED) && payloadCodec.ClockRate == 48000:
+				opusPayloadType, distance, ok := parseRedFmtp(payloadCodec.Fmtp)
+				if !ok {
+					continue
+				}
+				codec = NewRTPRedCodec(payloadType, payloadCodec.ClockRate, opusPayloadType, distance)
+			case strings.EqualFold(payloadCodec.Name, G729):
+				codec = NewRTPG729Codec(payloadType, payloadCodec.ClockRate)
+			case strings.EqualFold(payloadCodec.Name, ILBC):
+				codec = NewRTPILBCCodec(payloadType, payloadCodec.ClockRate, ilbcMode(payloadCodec.Fmtp))
+			case strings.EqualFold(payloadCodec.Name, TelephoneEvent):
+				codec = NewRTPTelephoneEventCodec(payloadType, payloadCodec.ClockRate)
+			case strings.EqualFold(payloadCodec.Name, L16):
+				codec = NewRTPL16Codec(payloadType, payloadCodec.ClockRate, pcmChannels(payloadCodec.EncodingParameters))
+			case strings.EqualFold(payloadCodec.Name, L24):
+				codec = NewRTPL24Codec(payloadType, payloadCodec.ClockRate, pcmChannels(payloadCodec.EncodingParameters))
+			case strings.EqualFold(payloadCodec.Name, MP4ALATM):
+				// The configuration can only be sent out of band
+				if fmtpParameter(payloadCodec.Fmtp, "cpresent") != "0" {
+					continue
+				}
+				codec = NewRTPAACCodec(payloadType, payloadCodec.ClockRate, pcmChannels(payloadCodec.EncodingParameters), nil)
+			case strings.EqualFold(payloadCodec.Name, RawVideo):
+				payloadCodec.Fmtp = fmtpForPayloadType(md, payloadType)
+				videoFormat, parseErr := rawvideopacketizer.ParseFormat(payloadCodec.Fmtp)
+				if parseErr != nil {
+					continue
+				}
+				codec = NewRTPRawVideoCodec(payloadType, payloadCodec.ClockRate, videoFormat)
 			default:
-				// ignoring other codecs
-				continue
+				// ignoring other codecs, unless a payloader is registered
+				codecType := NewRTPCodecType(md.MediaName.Media)
+				if _, ok := m.payloaders[strings.ToLower(codecType.String()+"/"+payloadCodec.Name)]; !ok {
+					continue
+				}
+				channels := uint16(0)
+				if codecType == RTPCodecTypeAudio {
+					channels = pcmChannels(payloadCodec.EncodingParameters)
+				}
+				payloadCodec.Fmtp = fmtpForPayloadType(md, payloadType)
+				codec = NewRTPCodec(codecType, payloadCodec.Name, payloadCodec.ClockRate, channels, "", payloadType, nil)
 			}
 
 			codec.SDPFmtpLine = payloadCodec.Fmtp
-			m.RegisterCodec(codec)
+			if codec.Name == H264 {
+				codec.Payloader = newH264Payloader(payloadCodec.Fmtp)
+			}
+			if payloader := m.newPayloader(codec); payloader != nil {
+				codec.Payloader = payloader
+			}
+			m.registerRemoteCodec(codec)
 		}
 	}
 	return nil
@@ -146,7 +431,8 @@ func (m *MediaEngine) getCodecSDP(sdpCodec sdp.Codec) (*RTPCodec, error) {
 			codec.ClockRate == sdpCodec.ClockRate &&
 			(sdpCodec.EncodingParameters == "" ||
 				strconv.Itoa(int(codec.Channels)) == sdpCodec.EncodingParameters) &&
-			codec.SDPFmtpLine == sdpCodec.Fmtp { // pion/webrtc#43
+			(codec.SDPFmtpLine == sdpCodec.Fmtp || strings.EqualFold(codec.Name, Opus) || // pion/webrtc#43
+				(strings.EqualFold(codec.Name, MultiOpus) && codec.OpusParameters().sameStreams(ParseOpusParameters(sdpCodec.Fmtp)))) {
 			return codec, nil
 		}
 	}
@@ -165,15 +451,26 @@ func (m *MediaEngine) GetCodecsByKind(kind RTPCodecType) []*RTPCodec {
 	return codecs
 }
 
-// Names for the default codecs supported by Pion WebRTC
+// Names for the codecs supported by Pion WebRTC
 const (
-	PCMU = "PCMU"
-	PCMA = "PCMA"
-	G722 = "G722"
-	Opus = "opus"
-	VP8  = "VP8"
-	VP9  = "VP9"
-	H264 = "H264"
+	PCMU      = "PCMU"
+	PCMA      = "PCMA"
+	G722      = "G722"
+	Opus      = "opus"
+	MultiOpus = "multiopus"
+	VP8       = "VP8"
+	VP9       = "VP9"
+	H264      = "H264"
+	JPEG      = "JPEG"
+	RED       = "red"
+	RawVideo  = "raw"
+	L16       = "L16"
+	L24       = "L24"
+	G729      = "G729"
+	ILBC      = "iLBC"
+	MP4ALATM  = "MP4A-LATM"
+
+	TelephoneEvent = "telephone-event"
 )
 
 // NewRTPPCMUCodec is a helper to create a PCMU codec
@@ -224,6 +521,193 @@ func NewRTPOpusCodec(payloadType uint8, clockrate uint32) *RTPCodec {
 	return c
 }
 
+// NewRTPMultiOpusCodec is a helper to create a multiopus codec, the
+// multichannel Opus of Chromium, with channels between 3 and 255. The packets
+// are multistream Opus packets of RFC 7845, coding 3 to 8 channels in the
+// Vorbis channel order, like 5.1 for 6 channels. Other layouts, like
+// ambisonics, code each channel in its own stream; other streams are set
+// with the Fmtp of OpusParameters as the SDPFmtpLine of the codec.
+func NewRTPMultiOpusCodec(payloadType uint8, clockrate uint32, channels uint16) *RTPCodec {
+	params, ok := opusVorbisMapping(channels)
+	if !ok {
+		params = OpusParameters{NumStreams: uint32(channels)}
+		for i := uint16(0); i < channels; i++ {
+			params.ChannelMapping = append(params.ChannelMapping, uint8(i))
+		}
+	}
+	params.MinPTime = 10
+	params.UseInbandFEC = true
+
+	c := NewRTPCodec(RTPCodecTypeAudio,
+		MultiOpus,
+		clockrate,
+		channels,
+		params.Fmtp(),
+		payloadType,
+		&codecs.OpusPayloader{})
+	return c
+}
+
+// NewRTPRedCodec is a helper to create a RED codec of RFC 2198, which sends
+// up to distance previous Opus packets with each packet. RED isn't registered
+// by RegisterDefaultCodecs. When both peers have it, Opus Tracks are sent as
+// RED, and remote Opus Tracks decode it, so they read the Opus packets.
+func NewRTPRedCodec(payloadType uint8, clockrate uint32, opusPayloadType uint8, distance int) *RTPCodec {
+	fmtp := strconv.Itoa(int(opusPayloadType))
+	for i := 0; i < distance; i++ {
+		fmtp += "/" + strconv.Itoa(int(opusPayloadType))
+	}
+
+	c := NewRTPCodec(RTPCodecTypeAudio,
+		RED,
+		clockrate,
+		2,
+		fmtp,
+		payloadType,
+		red.NewPayloader(opusPayloadType, &codecs.OpusPayloader{}, distance))
+	return c
+}
+
+// parseRedFmtp parses the payload types of the encodings of a RED fmtp line,
+// only redundant encodings of the same codec are supported
+func parseRedFmtp(fmtp string) (payloadType uint8, distance int, ok bool) {
+	payloadTypes := strings.Split(fmtp, "/")
+	for i, p := range payloadTypes {
+		parsed, err := strconv.ParseUint(strings.TrimSpace(p), 10, 7)
+		if err != nil || (i != 0 && uint8(parsed) != payloadType) {
+			return 0, 0, false
+		}
+		payloadType = uint8(parsed)
+	}
+
+	return payloadType, len(payloadTypes) - 1, true
+}
+
+// NewRTPG729Codec is a helper to create a G729 codec, the frames are relayed
+// without transcoding
+func NewRTPG729Codec(payloadType uint8, clockrate uint32) *RTPCodec {
+	c := NewRTPCodec(RTPCodecTypeAudio,
+		G729,
+		clockrate,
+		0,
+		"",
+		payloadType,
+		&framepacketizer.Payloader{FrameSize: framepacketizer.FrameSizeG729})
+	return c
+}
+
+// NewRTPILBCCodec is a helper to create an iLBC codec with frames of mode
+// milliseconds, which is 20 or 30. The frames are relayed without transcoding.
+func NewRTPILBCCodec(payloadType uint8, clockrate uint32, mode int) *RTPCodec {
+	frameSize := framepacketizer.FrameSizeILBC30ms
+	if mode == 20 {
+		frameSize = framepacketizer.FrameSizeILBC20ms
+	} else {
+		mode = 30
+	}
+
+	c := NewRTPCodec(RTPCodecTypeAudio,
+		ILBC,
+		clockrate,
+		0,
+		fmt.Sprintf("mode=%d", mode),
+		payloadType,
+		&framepacketizer.Payloader{FrameSize: frameSize})
+	return c
+}
+
+// ilbcMode parses the mode of an iLBC fmtp line, which is 30 if omitted
+func ilbcMode(fmtp string) int {
+	if fmtpParameter(fmtp, "mode") == "20" {
+		return 20
+	}
+	return 30
+}
+
+// fmtpParameter returns the value of the parameter key of fmtp, "" if it
+// isn't set
+func fmtpParameter(fmtp, key string) string {
+	for _, param := range strings.Split(fmtp, ";") {
+		keyValue := strings.SplitN(strings.TrimSpace(param), "=", 2)
+		if len(keyValue) == 2 && strings.EqualFold(keyValue[0], key) {
+			return strings.TrimSpace(keyValue[1])
+		}
+	}
+	return ""
+}
+
+// NewRTPTelephoneEventCodec is a helper to create a telephone-event codec of
+// RFC 4733 for the DTMF events 0-15. Its clockrate must be the one of the audio
+// codec, RTPSender.InsertDTMF sends the events and RTPReceiver.OnDTMF receives
+// them.
+func NewRTPTelephoneEventCodec(payloadType uint8, clockrate uint32) *RTPCodec {
+	c := NewRTPCodec(RTPCodecTypeAudio,
+		TelephoneEvent,
+		clockrate,
+		0,
+		"0-15",
+		payloadType,
+		telephoneEventPayloader{})
+	return c
+}
+
+// NewRTPL16Codec is a helper to create a codec for 16 bit linear PCM audio.
+// Samples are sent big endian and interleaved, without transcoding.
+func NewRTPL16Codec(payloadType uint8, clockrate uint32, channels uint16) *RTPCodec {
+	c := NewRTPCodec(RTPCodecTypeAudio,
+		L16,
+		clockrate,
+		channels,
+		"",
+		payloadType,
+		&pcmpacketizer.Payloader{SampleSize: pcmpacketizer.SampleSizeL16, Channels: int(channels)})
+	return c
+}
+
+// NewRTPL24Codec is a helper to create a codec for 24 bit linear PCM audio.
+// Samples are sent big endian and interleaved, without transcoding.
+func NewRTPL24Codec(payloadType uint8, clockrate uint32, channels uint16) *RTPCodec {
+	c := NewRTPCodec(RTPCodecTypeAudio,
+		L24,
+		clockrate,
+		channels,
+		"",
+		payloadType,
+		&pcmpacketizer.Payloader{SampleSize: pcmpacketizer.SampleSizeL24, Channels: int(channels)})
+	return c
+}
+
+// NewRTPAACCodec is a helper to create an MP4A-LATM codec of RFC 6416 for
+// AAC frames described by audioSpecificConfig, which are relayed without
+// transcoding. The clockrate is usually the sample rate, like 44100, and
+// each Sample holds one frame of aacpacketizer.SamplesPerFrame samples.
+func NewRTPAACCodec(payloadType uint8, clockrate uint32, channels uint16, audioSpecificConfig []byte) *RTPCodec {
+	fmtp := "cpresent=0"
+	if len(audioSpecificConfig) != 0 {
+		fmtp = fmt.Sprintf("profile-level-id=1;cpresent=0;object=%d;config=%x",
+			aacpacketizer.ObjectType(audioSpecificConfig),
+			aacpacketizer.StreamMuxConfig(audioSpecificConfig))
+	}
+
+	c := NewRTPCodec(RTPCodecTypeAudio,
+		MP4ALATM,
+		clockrate,
+		channels,
+		fmtp,
+		payloadType,
+		&aacpacketizer.Payloader{})
+	return c
+}
+
+// pcmChannels parses the number of channels of an rtpmap, which is 1 if omitted
+func pcmChannels(encodingParameters string) uint16 {
+	channels, err := strconv.ParseUint(encodingParameters, 10, 16)
+	if err != nil || channels == 0 {
+		return 1
+	}
+	return uint16(channels)
+}
+
 // NewRTPVP8Codec is a helper to create an VP8 codec
 func NewRTPVP8Codec(payloadType uint8, clockrate uint32) *RTPCodec {
 	c := NewRTPCodec(RTPCodecTypeVideo,
@@ -295,10 +779,58 @@ func NewRTPH264CodecExt(payloadType uint8, clockrate uint32, rtcpfb []RTCPFeedba
 		fmtp,
 		payloadType,
 		rtcpfb,
-		&codecs.H264Payloader{})
+		newH264Payloader(fmtp))
 	return c
 }
 
+// NewRTPJPEGCodec is a helper to create a JPEG codec, as sent by MJPEG cameras
+func NewRTPJPEGCodec(payloadType uint8, clockrate uint32) *RTPCodec {
+	c := NewRTPCodec(RTPCodecTypeVideo,
+		JPEG,
+		clockrate,
+		0,
+		"",
+		payloadType,
+		&jpegpacketizer.Payloader{})
+	return c
+}
+
+// fmtpForPayloadType returns the fmtp line of payloadType in md. Unlike
+// GetCodecForPayloadType it allows spaces between the parameters, which
+// RFC 4175 uses.
+func fmtpForPayloadType(md *sdp.MediaDescription, payloadType uint8) string {
+	prefix := strconv.Itoa(int(payloadType)) + " "
+	for _, a := range md.Attributes {
+		if a.Key == "fmtp" && strings.HasPrefix(a.Value, prefix) {
+			return strings.TrimSpace(strings.TrimPrefix(a.Value, prefix))
+		}
+	}
+	return ""
+}
+
+// NewRTPRawVideoCodec is a helper to create an uncompressed video codec of
+// RFC 4175 for frames of format, which is only suited for LANs
+func NewRTPRawVideoCodec(payloadType uint8, clockrate uint32, format rawvideopacketizer.Format) *RTPCodec {
+	c := NewRTPCodec(RTPCodecTypeVideo,
+		RawVideo,
+		clockrate,
+		0,
+		format.Fmtp(),
+		payloadType,
+		&rawvideopacketizer.Payloader{Format: format})
+	return c
+}
+
+// newH264Payloader returns a payloader for the packetization-mode of fmtp
+func newH264Payloader(fmtp string) rtp.Payloader {
+	mode := h264packetizer.PacketizationModeFromFmtp(fmtp)
+	if mode == h264packetizer.PacketizationModeNonInterleaved {
+		return &codecs.H264Payloader{}
+	}
+
+	return h264packetizer.NewPayloader(h264packetizer.WithPacketizationMode(mode))
+}
+
 // RTPCodecType determines the type of a codec
 type RTPCodecType int
 
@@ -395,6 +927,16 @@ func NewRTPCodecExt(
 	}
 }
 
+// matches returns whether c and codec are the same codec with the same
+// parameters, which can share a payload type
+func (c *RTPCodec) matches(codec *RTPCodec) bool {
+	return c.Type == codec.Type &&
+		strings.EqualFold(c.Name, codec.Name) &&
+		c.ClockRate == codec.ClockRate &&
+		c.Channels == codec.Channels &&
+		c.SDPFmtpLine == codec.SDPFmtpLine
+}
+
 // RTPCodecCapability provides information about codec capabilities.
 type RTPCodecCapability struct {
 	MimeType     string
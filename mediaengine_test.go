@@ -3,11 +3,20 @@
 package webrtc
 
 import (
+	"errors"
 	"regexp"
 	"strings"
 	"testing"
 
+	"github.com/pion/rtp"
+	"github.com/pion/rtp/codecs"
 	"github.com/pion/sdp/v3"
+	"github.com/pion/webrtc/v3/pkg/media/aacpacketizer"
+	"github.com/pion/webrtc/v3/pkg/media/framepacketizer"
+	"github.com/pion/webrtc/v3/pkg/media/h264packetizer"
+	"github.com/pion/webrtc/v3/pkg/media/jpegpacketizer"
+	"github.com/pion/webrtc/v3/pkg/media/pcmpacketizer"
+	"github.com/pion/webrtc/v3/pkg/media/rawvideopacketizer"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -169,3 +178,440 @@ func TestGetCodecsByName(t *testing.T) {
 	assertGetCodecsByName(VP9)
 	assertGetCodecsByName(Opus)
 }
+
+func TestH264PacketizationMode(t *testing.T) {
+	codec := NewRTPH264CodecExt(DefaultPayloadTypeH264, 90000, nil, "packetization-mode=1;profile-level-id=42001f")
+	assert.IsType(t, &codecs.H264Payloader{}, codec.Payloader)
+
+	codec = NewRTPH264CodecExt(DefaultPayloadTypeH264, 90000, nil, "packetization-mode=2;profile-level-id=42001f")
+	assert.IsType(t, &h264packetizer.Payloader{}, codec.Payloader)
+
+	const sdpH264 = `v=0
+o=- 0 2 IN IP4 127.0.0.1
+s=-
+t=0 0
+m=video 9 UDP/TLS/RTP/SAVPF 97 98
+a=rtpmap:97 H264/90000
+a=fmtp:97 profile-level-id=42001f
+a=rtpmap:98 H264/90000
+a=fmtp:98 packetization-mode=2;profile-level-id=42001f
+`
+
+	m := MediaEngine{}
+	assert.NoError(t, m.PopulateFromSDP(SessionDescription{SDP: sdpH264}))
+
+	h264Codecs := m.GetCodecsByName(H264)
+	assert.Equal(t, 2, len(h264Codecs))
+	for _, c := range h264Codecs {
+		assert.IsType(t, &h264packetizer.Payloader{}, c.Payloader)
+	}
+	assert.Equal(t, [][]byte{{0x67, 0x42}, {0x68, 0xCE}}, h264Codecs[0].Payloader.Payload(1200, []byte{0x00, 0x00, 0x01, 0x67, 0x42, 0x00, 0x00, 0x01, 0x68, 0xCE}))
+}
+
+func TestJPEGCodec(t *testing.T) {
+	const sdpJPEG = `v=0
+o=- 0 2 IN IP4 127.0.0.1
+s=-
+t=0 0
+m=video 9 UDP/TLS/RTP/SAVPF 26
+a=rtpmap:26 JPEG/90000
+`
+
+	m := MediaEngine{}
+	assert.NoError(t, m.PopulateFromSDP(SessionDescription{SDP: sdpJPEG}))
+
+	jpegCodecs := m.GetCodecsByName(JPEG)
+	assert.Equal(t, 1, len(jpegCodecs))
+	assert.Equal(t, uint8(DefaultPayloadTypeJPEG), jpegCodecs[0].PayloadType)
+	assert.Equal(t, RTPCodecTypeVideo, jpegCodecs[0].Type)
+	assert.IsType(t, &jpegpacketizer.Payloader{}, jpegCodecs[0].Payloader)
+}
+
+func TestRedCodec(t *testing.T) {
+	const sdpRed = `v=0
+o=- 0 2 IN IP4 127.0.0.1
+s=-
+t=0 0
+m=audio 9 UDP/TLS/RTP/SAVPF 63 111 62
+a=rtpmap:63 red/48000/2
+a=fmtp:63 111/111
+a=rtpmap:111 opus/48000/2
+a=fmtp:111 minptime=10;useinbandfec=1;usedtx=1
+a=rtpmap:62 red/48000/2
+a=fmtp:62 111/0
+`
+
+	m := MediaEngine{}
+	assert.NoError(t, m.PopulateFromSDP(SessionDescription{SDP: sdpRed}))
+
+	// Redundant encodings of other codecs aren't supported
+	redCodecs := m.GetCodecsByName(RED)
+	assert.Equal(t, 1, len(redCodecs))
+	assert.Equal(t, uint8(63), redCodecs[0].PayloadType)
+	assert.Equal(t, "111/111", redCodecs[0].SDPFmtpLine)
+	assert.Equal(t, NewRTPRedCodec(63, 48000, 111, 1).RTPCodecCapability, redCodecs[0].RTPCodecCapability)
+
+	// Opus matches regardless of the parameters of the receiver
+	codec, err := m.getCodecSDP(sdp.Codec{Name: Opus, ClockRate: 48000, Fmtp: "minptime=10;useinbandfec=1;cbr=1"})
+	assert.NoError(t, err)
+	assert.True(t, codec.OpusParameters().UseDTX)
+}
+
+func TestRawVideoCodec(t *testing.T) {
+	const sdpRaw = `v=0
+o=- 0 2 IN IP4 127.0.0.1
+s=-
+t=0 0
+m=video 9 UDP/TLS/RTP/SAVPF 112 113
+a=rtpmap:112 raw/90000
+a=fmtp:112 sampling=YCbCr-4:2:2; width=1280; height=720; depth=10; colorimetry=BT709-2
+a=rtpmap:113 raw/90000
+a=fmtp:113 sampling=YCbCr-4:2:0; width=1280; height=720; depth=8; colorimetry=BT709-2
+`
+
+	m := MediaEngine{}
+	assert.NoError(t, m.PopulateFromSDP(SessionDescription{SDP: sdpRaw}))
+
+	// Unsupported formats are ignored
+	rawCodecs := m.GetCodecsByName(RawVideo)
+	assert.Equal(t, 1, len(rawCodecs))
+	assert.Equal(t, uint8(112), rawCodecs[0].PayloadType)
+	assert.Equal(t, &rawvideopacketizer.Payloader{Format: rawvideopacketizer.Format{
+		Sampling:    rawvideopacketizer.SamplingYCbCr422,
+		Width:       1280,
+		Height:      720,
+		Depth:       10,
+		Colorimetry: "BT709-2",
+	}}, rawCodecs[0].Payloader)
+	assert.Equal(t, "sampling=YCbCr-4:2:2; width=1280; height=720; depth=10; colorimetry=BT709-2", rawCodecs[0].SDPFmtpLine)
+}
+
+func TestPCMCodecs(t *testing.T) {
+	const sdpPCM = `v=0
+o=- 0 2 IN IP4 127.0.0.1
+s=-
+t=0 0
+m=audio 9 UDP/TLS/RTP/SAVPF 10 11 97
+a=rtpmap:10 L16/44100/2
+a=rtpmap:11 L16/44100
+a=rtpmap:97 L24/48000/2
+`
+
+	m := MediaEngine{}
+	assert.NoError(t, m.PopulateFromSDP(SessionDescription{SDP: sdpPCM}))
+
+	l16Codecs := m.GetCodecsByName(L16)
+	assert.Equal(t, 2, len(l16Codecs))
+	assert.Equal(t, uint16(2), l16Codecs[0].Channels)
+	assert.Equal(t, &pcmpacketizer.Payloader{SampleSize: 2, Channels: 2}, l16Codecs[0].Payloader)
+	assert.Equal(t, uint16(1), l16Codecs[1].Channels)
+	assert.Equal(t, &pcmpacketizer.Payloader{SampleSize: 2, Channels: 1}, l16Codecs[1].Payloader)
+
+	l24Codecs := m.GetCodecsByName(L24)
+	assert.Equal(t, 1, len(l24Codecs))
+	assert.Equal(t, uint32(48000), l24Codecs[0].ClockRate)
+	assert.Equal(t, &pcmpacketizer.Payloader{SampleSize: 3, Channels: 2}, l24Codecs[0].Payloader)
+
+	// The codec with the same number of channels matches
+	codec, err := m.getCodecSDP(sdp.Codec{Name: L16, ClockRate: 44100, EncodingParameters: "1"})
+	assert.NoError(t, err)
+	assert.Equal(t, uint8(DefaultPayloadTypeL16Mono), codec.PayloadType)
+}
+
+func TestG729AndILBCCodecs(t *testing.T) {
+	const sdpSIP = `v=0
+o=- 0 2 IN IP4 127.0.0.1
+s=-
+t=0 0
+m=audio 9 UDP/TLS/RTP/SAVPF 18 97 98
+a=rtpmap:18 G729/8000
+a=fmtp:18 annexb=no
+a=rtpmap:97 iLBC/8000
+a=fmtp:97 mode=20
+a=rtpmap:98 iLBC/8000
+`
+
+	m := MediaEngine{}
+	assert.NoError(t, m.PopulateFromSDP(SessionDescription{SDP: sdpSIP}))
+
+	g729Codecs := m.GetCodecsByName(G729)
+	assert.Equal(t, 1, len(g729Codecs))
+	assert.Equal(t, uint8(DefaultPayloadTypeG729), g729Codecs[0].PayloadType)
+	assert.Equal(t, "audio/G729", g729Codecs[0].MimeType)
+	assert.Equal(t, "annexb=no", g729Codecs[0].SDPFmtpLine)
+	assert.Equal(t, &framepacketizer.Payloader{FrameSize: framepacketizer.FrameSizeG729}, g729Codecs[0].Payloader)
+
+	ilbcCodecs := m.GetCodecsByName(ILBC)
+	assert.Equal(t, 2, len(ilbcCodecs))
+	assert.Equal(t, &framepacketizer.Payloader{FrameSize: framepacketizer.FrameSizeILBC20ms}, ilbcCodecs[0].Payloader)
+	assert.Equal(t, &framepacketizer.Payloader{FrameSize: framepacketizer.FrameSizeILBC30ms}, ilbcCodecs[1].Payloader)
+
+	assert.Equal(t, "mode=30", NewRTPILBCCodec(97, 8000, 0).SDPFmtpLine)
+	assert.Equal(t, "mode=20", NewRTPILBCCodec(97, 8000, 20).SDPFmtpLine)
+}
+
+func TestAACCodec(t *testing.T) {
+	const sdpAAC = `v=0
+o=- 0 2 IN IP4 127.0.0.1
+s=-
+t=0 0
+m=audio 9 UDP/TLS/RTP/SAVPF 96 97
+a=rtpmap:96 MP4A-LATM/44100/2
+a=fmtp:96 profile-level-id=1;cpresent=0;object=2;config=400024203fc0
+a=rtpmap:97 MP4A-LATM/44100/2
+a=fmtp:97 profile-level-id=1;cpresent=1
+`
+
+	m := MediaEngine{}
+	assert.NoError(t, m.PopulateFromSDP(SessionDescription{SDP: sdpAAC}))
+
+	// The configuration in band isn't supported
+	aacCodecs := m.GetCodecsByName(MP4ALATM)
+	assert.Equal(t, 1, len(aacCodecs))
+	assert.Equal(t, uint8(96), aacCodecs[0].PayloadType)
+	assert.Equal(t, uint32(44100), aacCodecs[0].ClockRate)
+	assert.Equal(t, uint16(2), aacCodecs[0].Channels)
+	assert.Equal(t, &aacpacketizer.Payloader{}, aacCodecs[0].Payloader)
+	assert.Equal(t, "profile-level-id=1;cpresent=0;object=2;config=400024203fc0", aacCodecs[0].SDPFmtpLine)
+
+	// The local codec matches the remote one with the same configuration
+	local := NewRTPAACCodec(96, 44100, 2, []byte{0x12, 0x10})
+	assert.Equal(t, "audio/MP4A-LATM", local.MimeType)
+	assert.True(t, local.matches(aacCodecs[0]))
+	assert.Equal(t, "cpresent=0", NewRTPAACCodec(96, 48000, 1, nil).SDPFmtpLine)
+}
+
+func TestMultiOpusCodec(t *testing.T) {
+	const sdpMultiOpus = `v=0
+o=- 0 2 IN IP4 127.0.0.1
+s=-
+t=0 0
+m=audio 9 UDP/TLS/RTP/SAVPF 111 112 113 114
+a=rtpmap:111 opus/48000/2
+a=rtpmap:112 multiopus/48000/6
+a=fmtp:112 channel_mapping=0,4,1,2,3,5;coupled_streams=2;minptime=10;num_streams=4;useinbandfec=1
+a=rtpmap:113 multiopus/48000/6
+a=fmtp:113 channel_mapping=0,4,1,2,3;coupled_streams=2;num_streams=4
+a=rtpmap:114 opus/48000/6
+`
+
+	m := MediaEngine{}
+	assert.NoError(t, m.PopulateFromSDP(SessionDescription{SDP: sdpMultiOpus}))
+
+	// Opus with other than 2 channels and invalid mappings are ignored
+	assert.Equal(t, 1, len(m.GetCodecsByName(Opus)))
+	multiOpusCodecs := m.GetCodecsByName(MultiOpus)
+	assert.Equal(t, 1, len(multiOpusCodecs))
+	assert.Equal(t, uint8(112), multiOpusCodecs[0].PayloadType)
+	assert.Equal(t, uint16(6), multiOpusCodecs[0].Channels)
+	assert.Equal(t, &codecs.OpusPayloader{}, multiOpusCodecs[0].Payloader)
+
+	// The local 5.1 codec matches the one of Chromium
+	local := MediaEngine{}
+	assert.NoError(t, local.RegisterCodecStrict(NewRTPMultiOpusCodec(112, 48000, 6)))
+	codec, err := local.getCodecSDP(sdp.Codec{
+		Name: MultiOpus, ClockRate: 48000, EncodingParameters: "6",
+		Fmtp: "channel_mapping=0,4,1,2,3,5;coupled_streams=2;minptime=10;num_streams=4;useinbandfec=1",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, uint8(112), codec.PayloadType)
+	_, err = local.getCodecSDP(sdp.Codec{
+		Name: MultiOpus, ClockRate: 48000, EncodingParameters: "6",
+		Fmtp: "channel_mapping=0,1,2,3,4,5;coupled_streams=0;num_streams=6",
+	})
+	assert.Equal(t, ErrCodecNotFound, err)
+
+	invalid := NewRTPMultiOpusCodec(113, 48000, 6)
+	invalid.Channels = 8
+	assert.Equal(t, errOpusChannelMappingInvalid, local.RegisterCodecStrict(invalid))
+	surround := NewRTPOpusCodec(114, 48000)
+	surround.Channels = 6
+	assert.Equal(t, errOpusChannelsInvalid, local.RegisterCodecStrict(surround))
+}
+
+func TestRegisterCodecPayloadTypeConflict(t *testing.T) {
+	m := MediaEngine{}
+	m.RegisterDefaultCodecs()
+
+	// A codec already registered isn't added again
+	payloadType, err := m.RegisterCodecDynamic(NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000))
+	assert.NoError(t, err)
+	assert.Equal(t, uint8(DefaultPayloadTypeVP8), payloadType)
+	assert.Equal(t, 7, len(m.codecs))
+
+	// A copy of H264 on the payload type of VP8 is moved to a free dynamic one
+	h264 := NewRTPH264Codec(DefaultPayloadTypeVP8, 90000)
+	payloadType, err = m.RegisterCodecDynamic(h264)
+	assert.NoError(t, err)
+	assert.Equal(t, uint8(97), payloadType)
+	assert.Equal(t, uint8(DefaultPayloadTypeVP8), h264.PayloadType)
+	codec, err := m.getCodec(DefaultPayloadTypeVP8)
+	assert.NoError(t, err)
+	assert.Equal(t, VP8, codec.Name)
+	codec, err = m.getCodec(97)
+	assert.NoError(t, err)
+	assert.Equal(t, H264, codec.Name)
+
+	err = m.RegisterCodecStrict(NewRTPVP9Codec(DefaultPayloadTypeVP8, 90000))
+	assert.True(t, errors.Is(err, ErrPayloadTypeConflict))
+	assert.NoError(t, m.RegisterCodecStrict(NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000)))
+	assert.NoError(t, m.RegisterCodecStrict(NewRTPJPEGCodec(DefaultPayloadTypeJPEG, 90000)))
+	assert.Equal(t, 9, len(m.codecs))
+
+	// The payload types of a remote description replace the registered ones
+	assert.NoError(t, m.PopulateFromSDP(SessionDescription{SDP: `v=0
+o=- 0 2 IN IP4 127.0.0.1
+s=-
+t=0 0
+m=video 9 UDP/TLS/RTP/SAVPF 96
+c=IN IP4 0.0.0.0
+a=rtpmap:96 VP9/90000
+`}))
+	codec, err = m.getCodec(DefaultPayloadTypeVP8)
+	assert.NoError(t, err)
+	assert.Equal(t, VP9, codec.Name)
+	assert.Equal(t, 0, len(m.GetCodecsByName(VP8)))
+}
+
+func TestRegisterCodecNoFreePayloadType(t *testing.T) {
+	m := MediaEngine{}
+	for payloadType := uint8(96); payloadType <= 127; payloadType++ {
+		assert.NoError(t, m.RegisterCodecStrict(NewRTPVP8Codec(payloadType, 90000)))
+	}
+
+	_, err := m.RegisterCodecDynamic(NewRTPH264Codec(96, 90000))
+	assert.True(t, errors.Is(err, ErrNoFreePayloadType))
+	assert.Equal(t, 32, len(m.codecs))
+}
+
+func TestRegisterPayloader(t *testing.T) {
+	const sdpCustom = `v=0
+o=- 0 2 IN IP4 127.0.0.1
+s=-
+t=0 0
+m=audio 9 UDP/TLS/RTP/SAVPF 97 10
+a=rtpmap:97 X-CUSTOM/16000/2
+a=rtpmap:10 L16/44100/2
+m=video 9 UDP/TLS/RTP/SAVPF 98 99
+a=rtpmap:98 H265/90000
+a=fmtp:98 profile-id=1
+a=rtpmap:99 AV2/90000
+`
+
+	created := 0
+	m := MediaEngine{}
+	m.RegisterPayloader("video/h265", func() rtp.Payloader {
+		created++
+		return &codecs.VP8Payloader{}
+	})
+	m.RegisterPayloader("audio/X-CUSTOM", func() rtp.Payloader { return &codecs.G711Payloader{} })
+	m.RegisterPayloader("audio/L16", func() rtp.Payloader { return &codecs.G722Payloader{} })
+	assert.NoError(t, m.PopulateFromSDP(SessionDescription{SDP: sdpCustom}))
+
+	// Codecs without a payloader are still ignored
+	assert.Equal(t, 3, len(m.codecs))
+
+	h265, err := m.getCodec(98)
+	assert.NoError(t, err)
+	assert.Equal(t, RTPCodecTypeVideo, h265.Type)
+	assert.Equal(t, "video/H265", h265.MimeType)
+	assert.Equal(t, "profile-id=1", h265.SDPFmtpLine)
+	assert.Equal(t, &codecs.VP8Payloader{}, h265.Payloader)
+	assert.Equal(t, 1, created)
+
+	custom, err := m.getCodec(97)
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(16000), custom.ClockRate)
+	assert.Equal(t, uint16(2), custom.Channels)
+	assert.Equal(t, &codecs.G711Payloader{}, custom.Payloader)
+
+	// A registered payloader overrides the built-in one
+	l16, err := m.getCodec(10)
+	assert.NoError(t, err)
+	assert.Equal(t, &codecs.G722Payloader{}, l16.Payloader)
+
+	// Each Track gets its own payloader, also for registered codecs
+	api := NewAPI(WithMediaEngine(m))
+	pc, err := api.NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	track, err := pc.NewTrack(98, 1, "video", "pion")
+	assert.NoError(t, err)
+	assert.Equal(t, h265, track.Codec())
+	_, err = pc.NewTrack(98, 2, "video", "pion")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, created)
+
+	assert.NoError(t, pc.Close())
+}
+
+func TestMediaEngineCopy(t *testing.T) {
+	template := MediaEngine{}
+	template.RegisterDefaultCodecs()
+	template.RegisterCodec(NewRTPCodecExt(RTPCodecTypeVideo, "H265", 90000, 0, "", 100,
+		[]RTCPFeedback{{Type: TypeRTCPFBNACK}}, &codecs.VP8Payloader{}))
+	template.RegisterPayloader("video/H265", func() rtp.Payloader { return &codecs.VP8Payloader{} })
+
+	copied := template.Copy()
+	assert.Equal(t, len(template.codecs), len(copied.codecs))
+	assert.Equal(t, 1, len(copied.payloaders))
+
+	// Populating the copy leaves the template as is
+	assert.NoError(t, copied.PopulateFromSDP(SessionDescription{SDP: `v=0
+o=- 0 2 IN IP4 127.0.0.1
+s=-
+t=0 0
+m=video 9 UDP/TLS/RTP/SAVPF 96
+c=IN IP4 0.0.0.0
+a=rtpmap:96 VP9/90000
+`}))
+	codec, err := copied.getCodec(DefaultPayloadTypeVP8)
+	assert.NoError(t, err)
+	assert.Equal(t, VP9, codec.Name)
+	codec, err = template.getCodec(DefaultPayloadTypeVP8)
+	assert.NoError(t, err)
+	assert.Equal(t, VP8, codec.Name)
+
+	// The codecs are copied too
+	codec, err = copied.getCodec(100)
+	assert.NoError(t, err)
+	codec.RTCPFeedback[0].Type = "changed"
+	codec.SDPFmtpLine = "changed"
+	codec, err = template.getCodec(100)
+	assert.NoError(t, err)
+	assert.Equal(t, TypeRTCPFBNACK, codec.RTCPFeedback[0].Type)
+	assert.Equal(t, "", codec.SDPFmtpLine)
+
+	copied.RegisterPayloader("audio/L16", func() rtp.Payloader { return &codecs.G711Payloader{} })
+	assert.Equal(t, 1, len(template.payloaders))
+}
+
+func TestMediaEngine_withRemotePayloadTypes(t *testing.T) {
+	m := &MediaEngine{}
+	m.RegisterDefaultCodecs()
+
+	parse := func(formats, rtpmap string) *sdp.SessionDescription {
+		parsed := &sdp.SessionDescription{}
+		assert.NoError(t, parsed.Unmarshal([]byte("v=0\r\no=- 0 2 IN IP4 127.0.0.1\r\ns=-\r\nt=0 0\r\n"+
+			"m=video 9 UDP/TLS/RTP/SAVPF "+formats+"\r\n"+rtpmap)))
+		return parsed
+	}
+
+	// The MediaEngine is kept if it has the payload types of the offer
+	assert.Equal(t, m, m.withRemotePayloadTypes(parse("96", "a=rtpmap:96 VP8/90000\r\n")))
+
+	// The codecs take the payload types of the offer, VP8 moves off the one
+	// of VP9 to a free one
+	negotiated := m.withRemotePayloadTypes(parse("96 100", "a=rtpmap:96 VP9/90000\r\na=rtpmap:100 H264/90000\r\n"))
+	assert.True(t, m != negotiated)
+	for name, payloadType := range map[string]uint8{VP9: 96, VP8: 97, Opus: DefaultPayloadTypeOpus} {
+		codecs := negotiated.GetCodecsByName(name)
+		assert.Equal(t, 1, len(codecs))
+		assert.Equal(t, payloadType, codecs[0].PayloadType, name)
+	}
+
+	// m isn't modified
+	codec, err := m.getCodec(DefaultPayloadTypeVP8)
+	assert.NoError(t, err)
+	assert.Equal(t, VP8, codec.Name)
+}
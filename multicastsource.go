@@ -0,0 +1,198 @@
+// +build !js
+
+package webrtc
+
+import (
+	"net"
+	"sync"
+
+	"github.com/pion/rtp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// multicastMergeWindow is how many sequence numbers a MulticastSource
+// remembers, to write each packet of redundant groups once
+const multicastMergeWindow = 1024
+
+// MulticastGroup is a multicast group that carries an RTP stream
+type MulticastGroup struct {
+	// Address is the address and port of the group, like 239.1.1.1:5004
+	Address *net.UDPAddr
+
+	// Source is the sender of source-specific multicast (SSM). Any sender is
+	// received with any-source multicast (ASM) if it is nil.
+	Source net.IP
+
+	// Interface receives the group, it is chosen by the system if nil
+	Interface *net.Interface
+}
+
+// MulticastSource receives an RTP stream from multicast groups, as used in
+// broadcast facilities, and writes it to a local Track. The SSRC and payload
+// type are those of the local Track, and header extensions are removed.
+//
+// With two groups the stream is received twice, usually over redundant
+// networks, and merged as in SMPTE ST 2022-7. Each packet is written once,
+// from the group it arrives on first, so a loss on one network is covered
+// by the other.
+type MulticastSource struct {
+	local *Track
+	conns []net.PacketConn
+	wg    sync.WaitGroup
+
+	mu     sync.Mutex
+	merger multicastMerger
+}
+
+// multicastMerger tells which sequence numbers were already written
+type multicastMerger struct {
+	started bool
+	highest uint16
+	seen    [multicastMergeWindow]struct {
+		valid          bool
+		sequenceNumber uint16
+	}
+}
+
+// NewMulticastSource joins one group, or two redundant groups, and writes the
+// RTP stream they carry to local
+func NewMulticastSource(local *Track, groups ...MulticastGroup) (*MulticastSource, error) {
+	local.mu.RLock()
+	isRemote := local.receiver != nil
+	local.mu.RUnlock()
+	switch {
+	case isRemote:
+		return nil, errMulticastSourceRemoteTrack
+	case len(groups) == 0 || len(groups) > 2:
+		return nil, errMulticastSourceGroupCount
+	}
+
+	s := &MulticastSource{local: local}
+	for _, group := range groups {
+		conn, err := joinMulticastGroup(group)
+		if err != nil {
+			for _, c := range s.conns {
+				_ = c.Close()
+			}
+			return nil, err
+		}
+		s.conns = append(s.conns, conn)
+	}
+
+	for _, conn := range s.conns {
+		s.wg.Add(1)
+		go s.readLoop(conn)
+	}
+	return s, nil
+}
+
+// Close leaves the groups
+func (s *MulticastSource) Close() error {
+	var closeErr error
+	for _, conn := range s.conns {
+		if err := conn.Close(); err != nil {
+			closeErr = err
+		}
+	}
+	s.wg.Wait()
+	return closeErr
+}
+
+// joinMulticastGroup listens on the port of group, and joins it
+func joinMulticastGroup(group MulticastGroup) (net.PacketConn, error) {
+	if group.Address == nil || !group.Address.IP.IsMulticast() {
+		return nil, errMulticastSourceNotMulticast
+	}
+
+	network := "udp6"
+	if group.Address.IP.To4() != nil {
+		network = "udp4"
+	}
+
+	// ListenMulticastUDP reuses the port, so redundant groups can share it,
+	// and joins the group for any source
+	conn, err := net.ListenMulticastUDP(network, group.Interface, group.Address)
+	if err != nil || group.Source == nil {
+		return conn, err
+	}
+
+	// The membership for any source is replaced by one for the source
+	groupAddr := &net.UDPAddr{IP: group.Address.IP}
+	sourceAddr := &net.UDPAddr{IP: group.Source}
+	if network == "udp4" {
+		p := ipv4.NewPacketConn(conn)
+		if err = p.LeaveGroup(group.Interface, groupAddr); err == nil {
+			err = p.JoinSourceSpecificGroup(group.Interface, groupAddr, sourceAddr)
+		}
+	} else {
+		p := ipv6.NewPacketConn(conn)
+		if err = p.LeaveGroup(group.Interface, groupAddr); err == nil {
+			err = p.JoinSourceSpecificGroup(group.Interface, groupAddr, sourceAddr)
+		}
+	}
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+func (s *MulticastSource) readLoop(conn net.PacketConn) {
+	defer s.wg.Done()
+
+	b := make([]byte, receiveMTU)
+	for {
+		n, _, err := conn.ReadFrom(b)
+		if err != nil {
+			return
+		}
+
+		packet := &rtp.Packet{}
+		if packet.Unmarshal(b[:n]) != nil {
+			continue
+		}
+		s.write(packet)
+	}
+}
+
+func (s *MulticastSource) write(packet *rtp.Packet) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.merger.accept(packet.SequenceNumber) {
+		return
+	}
+
+	packet.SSRC = s.local.SSRC()
+	packet.PayloadType = s.local.PayloadType()
+	packet.Extension = false
+	packet.ExtensionProfile = 0
+	packet.Extensions = nil
+
+	_ = s.local.WriteRTP(packet)
+}
+
+// accept tells if the packet with sequenceNumber is to be written, it is
+// dropped if it was written or is older than the window
+func (m *multicastMerger) accept(sequenceNumber uint16) bool {
+	switch diff := sequenceNumber - m.highest; {
+	case !m.started:
+		m.started = true
+		m.highest = sequenceNumber
+	case diff == 0:
+		return false
+	case diff < 1<<15:
+		m.highest = sequenceNumber
+	case m.highest-sequenceNumber >= multicastMergeWindow:
+		return false
+	}
+
+	seen := &m.seen[sequenceNumber%multicastMergeWindow]
+	if seen.valid && seen.sequenceNumber == sequenceNumber {
+		return false
+	}
+	seen.valid = true
+	seen.sequenceNumber = sequenceNumber
+	return true
+}
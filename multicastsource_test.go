@@ -0,0 +1,52 @@
+// +build !js
+
+package webrtc
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewMulticastSource(t *testing.T) {
+	local, err := NewTrack(DefaultPayloadTypeVP8, 1234, "video", "pion", NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000))
+	assert.NoError(t, err)
+	group := MulticastGroup{Address: &net.UDPAddr{IP: net.IPv4(239, 1, 1, 1), Port: 5004}}
+
+	_, err = NewMulticastSource(&Track{receiver: &RTPReceiver{}}, group)
+	assert.Equal(t, errMulticastSourceRemoteTrack, err)
+
+	_, err = NewMulticastSource(local)
+	assert.Equal(t, errMulticastSourceGroupCount, err)
+
+	_, err = NewMulticastSource(local, group, group, group)
+	assert.Equal(t, errMulticastSourceGroupCount, err)
+
+	_, err = NewMulticastSource(local, MulticastGroup{Address: &net.UDPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 5004}})
+	assert.Equal(t, errMulticastSourceNotMulticast, err)
+}
+
+func TestMulticastMerger(t *testing.T) {
+	m := multicastMerger{}
+
+	// Each sequence number is accepted once, from either feed
+	assert.True(t, m.accept(65534))
+	assert.False(t, m.accept(65534))
+	assert.True(t, m.accept(65535))
+	assert.True(t, m.accept(1))
+	assert.False(t, m.accept(65535))
+	assert.False(t, m.accept(1))
+
+	// Losses of one feed are covered by the other
+	assert.True(t, m.accept(0))
+	assert.False(t, m.accept(0))
+	assert.True(t, m.accept(2))
+
+	// Packets older than the window are dropped
+	assert.True(t, m.accept(2+multicastMergeWindow))
+	assert.False(t, m.accept(65000))
+	assert.True(t, m.accept(3))
+	assert.True(t, m.accept(4+multicastMergeWindow/2))
+	assert.False(t, m.accept(4+multicastMergeWindow/2))
+}
@@ -0,0 +1,183 @@
+// +build !js
+
+package webrtc
+
+import (
+	"encoding/binary"
+	"sync"
+	"time"
+
+	"github.com/pion/rtcp"
+)
+
+const (
+	// muteAppName is the name of the RTCP APP packets signaling the mute state
+	// of an RTPSender, their subtype is 1 while muted and 0 once unmuted
+	muteAppName = "MUTE"
+
+	// muteAppLength is the length of a mute APP packet, which has no data
+	muteAppLength = 12
+
+	// muteSignalInterval is how often the mute state is sent while muted, so
+	// receivers that missed it or joined later learn it
+	muteSignalInterval = time.Second
+)
+
+// muteSender sends the mute state of an RTPSender to the receiver
+type muteSender struct {
+	muted atomicBool
+
+	mu        sync.Mutex
+	signaling bool
+	changed   chan struct{}
+}
+
+// SetMuted mutes or unmutes the RTPSender. While muted, RTP isn't sent, and
+// the receiver is told with RTCP so it can tell muted media from network
+// failure, see RTPReceiver.OnMute.
+func (r *RTPSender) SetMuted(muted bool) {
+	r.mute.mu.Lock()
+	defer r.mute.mu.Unlock()
+
+	if r.mute.muted.get() == muted {
+		return
+	}
+	r.mute.muted.set(muted)
+
+	if r.mute.signaling {
+		select {
+		case r.mute.changed <- struct{}{}:
+		default:
+		}
+		return
+	}
+	if muted {
+		if r.mute.changed == nil {
+			r.mute.changed = make(chan struct{}, 1)
+		}
+		r.mute.signaling = true
+		go r.signalMute()
+	}
+}
+
+// Muted tells if the RTPSender is muted
+func (r *RTPSender) Muted() bool {
+	return r.mute.muted.get()
+}
+
+// signalMute sends the mute state once sending starts, then every
+// muteSignalInterval while muted, and once when unmuted
+func (r *RTPSender) signalMute() {
+	select {
+	case <-r.sendCalled:
+	case <-r.stopCalled:
+		return
+	}
+
+	ticker := time.NewTicker(muteSignalInterval)
+	defer ticker.Stop()
+
+	for {
+		r.mute.mu.Lock()
+		muted := r.mute.muted.get()
+		if !muted {
+			r.mute.signaling = false
+		}
+		r.mute.mu.Unlock()
+
+		// RTCP is best effort, the state is sent again while muted
+		r.writeMute(muted) // nolint:errcheck
+		if !muted {
+			return
+		}
+
+		select {
+		case <-ticker.C:
+		case <-r.mute.changed:
+		case <-r.stopCalled:
+			return
+		}
+	}
+}
+
+// writeMute sends the mute state in an RTCP APP packet, after an SDES as
+// RTCP is routed by the SSRCs of its packets
+func (r *RTPSender) writeMute(muted bool) error {
+	r.mu.RLock()
+	ssrc, transport := r.parameters.Encodings.SSRC, r.transport
+	cname := ""
+	if r.track != nil {
+		cname = r.track.Label()
+	}
+	r.mu.RUnlock()
+
+	app := make(rtcp.RawPacket, muteAppLength)
+	app[0] = 0x80
+	if muted {
+		app[0] |= 1
+	}
+	app[1] = uint8(rtcp.TypeApplicationDefined)
+	binary.BigEndian.PutUint16(app[2:], muteAppLength/4-1)
+	binary.BigEndian.PutUint32(app[4:], ssrc)
+	copy(app[8:], muteAppName)
+
+	return transport.writeRTCP([]rtcp.Packet{
+		&rtcp.SourceDescription{Chunks: []rtcp.SourceDescriptionChunk{{
+			Source: ssrc,
+			Items:  []rtcp.SourceDescriptionItem{{Type: rtcp.SDESCNAME, Text: cname}},
+		}}},
+		&app,
+	})
+}
+
+// muteReceiver holds the mute state of the remote RTPSender of an RTPReceiver
+type muteReceiver struct {
+	muted atomicBool
+
+	mu      sync.Mutex
+	handler func(muted bool)
+}
+
+// OnMute sets an event handler which is called when the remote RTPSender is
+// muted or unmuted with SetMuted. The mute state is learned from RTCP as it
+// arrives, and RTP arriving after muting unmutes.
+func (r *RTPReceiver) OnMute(f func(muted bool)) {
+	r.mute.mu.Lock()
+	defer r.mute.mu.Unlock()
+	r.mute.handler = f
+}
+
+// Muted tells if the remote RTPSender is muted, see OnMute
+func (r *RTPReceiver) Muted() bool {
+	return r.mute.muted.get()
+}
+
+func (r *RTPReceiver) setMuted(muted bool) {
+	r.mute.mu.Lock()
+	if r.mute.muted.get() == muted {
+		r.mute.mu.Unlock()
+		return
+	}
+	r.mute.muted.set(muted)
+	handler := r.mute.handler
+	r.mute.mu.Unlock()
+
+	if handler != nil {
+		handler(muted)
+	}
+}
+
+// handleMute updates the mute state with the mute APP packets of the RTCP
+// of the RTPReceiver
+func (r *RTPReceiver) handleMute(b []byte) {
+	for len(b) >= 4 {
+		length := (int(binary.BigEndian.Uint16(b[2:])) + 1) * 4
+		if length > len(b) {
+			return
+		}
+		if rtcp.PacketType(b[1]) == rtcp.TypeApplicationDefined && length == muteAppLength && string(b[8:12]) == muteAppName {
+			r.setMuted(b[0]&0x1F == 1)
+		}
+		b = b[length:]
+	}
+}
@@ -0,0 +1,86 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/webrtc/v3/pkg/media"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRTPSender_SetMuted(t *testing.T) {
+	pcOffer, pcAnswer, err := newPair()
+	assert.NoError(t, err)
+
+	track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, 5000, "video", "pion")
+	assert.NoError(t, err)
+	sender, err := pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+
+	muted := make(chan bool, 4)
+	onTrack := make(chan *RTPReceiver)
+	pcAnswer.OnTrack(func(track *Track, r *RTPReceiver) {
+		// RTCP isn't read, the RTPReceiver reads it itself
+		r.OnMute(func(m bool) {
+			muted <- m
+		})
+		go func() {
+			for {
+				if _, readErr := track.ReadRTP(); readErr != nil {
+					return
+				}
+			}
+		}()
+		onTrack <- r
+	})
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-time.After(20 * time.Millisecond):
+				assert.NoError(t, track.WriteSample(media.Sample{Data: []byte{0x00}, Samples: 1}))
+			case <-done:
+				return
+			}
+		}
+	}()
+	receiver := <-onTrack
+
+	sender.SetMuted(true)
+	assert.True(t, sender.Muted())
+	assert.True(t, <-muted)
+	assert.True(t, receiver.Muted())
+	assert.True(t, pcAnswer.GetTransceiverInfo()[0].Receiver.Muted)
+
+	sender.SetMuted(false)
+	assert.False(t, <-muted)
+	assert.False(t, receiver.Muted())
+
+	close(done)
+	closePairNow(t, pcOffer, pcAnswer)
+}
+
+func TestRTPReceiver_handleMute(t *testing.T) {
+	r := &RTPReceiver{}
+	muted := []bool{}
+	r.OnMute(func(m bool) {
+		muted = append(muted, m)
+	})
+
+	sdes := []byte{0x81, 202, 0x00, 0x02, 0x00, 0x00, 0x13, 0x88, 0x01, 0x00, 0x00, 0x00}
+	app := []byte{0x81, 204, 0x00, 0x02, 0x00, 0x00, 0x13, 0x88, 'M', 'U', 'T', 'E'}
+	other := []byte{0x81, 204, 0x00, 0x02, 0x00, 0x00, 0x13, 0x88, 'P', 'I', 'O', 'N'}
+
+	r.handleMute(append(append([]byte{}, sdes...), other...))
+	r.handleMute(append(append([]byte{}, sdes...), app...))
+	r.handleMute(app)
+	r.handleMute(app[:8])
+	app[0] = 0x80
+	r.handleMute(app)
+	assert.Equal(t, []bool{true, false}, muted)
+}
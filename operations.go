@@ -1,6 +1,8 @@
 package webrtc
 
 import (
+	"context"
+	"runtime/pprof"
 	"sync"
 )
 
@@ -12,12 +14,20 @@ type operations struct {
 	mu   sync.Mutex
 	busy bool
 	ops  []operation
+
+	// labels are the pprof labels of the goroutine running the operations,
+	// and so of the goroutines they start
+	labels *pprof.LabelSet
 }
 
 func newOperations() *operations {
 	return &operations{}
 }
 
+func newLabeledOperations(labels pprof.LabelSet) *operations {
+	return &operations{labels: &labels}
+}
+
 // Enqueue adds a new action to be executed. If there are no actions scheduled,
 // the execution will start immediately in a new goroutine.
 func (o *operations) Enqueue(op operation) {
@@ -32,7 +42,7 @@ func (o *operations) Enqueue(op operation) {
 	o.mu.Unlock()
 
 	if !running {
-		go o.start()
+		go o.run()
 	}
 }
 
@@ -66,6 +76,16 @@ func (o *operations) pop() func() {
 	return fn
 }
 
+func (o *operations) run() {
+	if o.labels == nil {
+		o.start()
+		return
+	}
+	pprof.Do(context.Background(), *o.labels, func(context.Context) {
+		o.start()
+	})
+}
+
 func (o *operations) start() {
 	defer func() {
 		o.mu.Lock()
@@ -76,7 +96,7 @@ func (o *operations) start() {
 		}
 		// either a new operation was enqueued while we
 		// were busy, or an operation panicked
-		go o.start()
+		go o.run()
 	}()
 
 	fn := o.pop()
@@ -0,0 +1,198 @@
+// +build !js
+
+package webrtc
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// OpusParameters are the Opus fmtp parameters of RFC 7587 Section 7.
+// They describe what the receiver of a stream prefers, so an Opus encoder
+// is configured from the parameters of the remote description.
+type OpusParameters struct {
+	// MinPTime is the minimum duration of media in a packet in milliseconds
+	MinPTime uint32
+
+	// UseInbandFEC requests forward error correction in the Opus packets
+	UseInbandFEC bool
+
+	// UseDTX requests discontinuous transmission, no packets are sent during silence
+	UseDTX bool
+
+	// CBR requests a constant bitrate
+	CBR bool
+
+	// Stereo signals that stereo is preferred, SpropStereo that stereo is sent
+	Stereo      bool
+	SpropStereo bool
+
+	// MaxAverageBitrate is the maximum average bitrate in bits per second
+	MaxAverageBitrate uint32
+
+	// MaxPlaybackRate is the maximum sampling rate the receiver can render
+	MaxPlaybackRate uint32
+
+	// NumStreams, CoupledStreams and ChannelMapping describe how the channels
+	// of multiopus, the multichannel Opus of Chromium, are coded in Opus
+	// streams, like the channel mapping table of RFC 7845 Section 5.1.1
+	NumStreams     uint32
+	CoupledStreams uint32
+	ChannelMapping []uint8
+}
+
+// opusVorbisMapping returns the streams of the Vorbis channel order of
+// RFC 7845 for 3 to 8 channels, the surround layouts multiopus is used for
+func opusVorbisMapping(channels uint16) (OpusParameters, bool) {
+	switch channels {
+	case 3:
+		return OpusParameters{NumStreams: 2, CoupledStreams: 1, ChannelMapping: []uint8{0, 2, 1}}, true
+	case 4:
+		return OpusParameters{NumStreams: 2, CoupledStreams: 2, ChannelMapping: []uint8{0, 1, 2, 3}}, true
+	case 5:
+		return OpusParameters{NumStreams: 3, CoupledStreams: 2, ChannelMapping: []uint8{0, 4, 1, 2, 3}}, true
+	case 6:
+		return OpusParameters{NumStreams: 4, CoupledStreams: 2, ChannelMapping: []uint8{0, 4, 1, 2, 3, 5}}, true
+	case 7:
+		return OpusParameters{NumStreams: 4, CoupledStreams: 3, ChannelMapping: []uint8{0, 4, 1, 2, 3, 5, 6}}, true
+	case 8:
+		return OpusParameters{NumStreams: 5, CoupledStreams: 3, ChannelMapping: []uint8{0, 6, 1, 2, 3, 4, 5, 7}}, true
+	default:
+		return OpusParameters{}, false
+	}
+}
+
+// ParseOpusParameters parses an Opus fmtp line, unknown and invalid parameters are ignored
+func ParseOpusParameters(fmtp string) OpusParameters {
+	p := OpusParameters{}
+	for _, param := range strings.Split(fmtp, ";") {
+		keyValue := strings.SplitN(strings.TrimSpace(param), "=", 2)
+		if len(keyValue) != 2 {
+			continue
+		}
+
+		if strings.EqualFold(keyValue[0], "channel_mapping") {
+			p.ChannelMapping = parseOpusChannelMapping(keyValue[1])
+			continue
+		}
+
+		value, err := strconv.ParseUint(strings.TrimSpace(keyValue[1]), 10, 32)
+		if err != nil {
+			continue
+		}
+
+		switch strings.ToLower(keyValue[0]) {
+		case "minptime":
+			p.MinPTime = uint32(value)
+		case "useinbandfec":
+			p.UseInbandFEC = value == 1
+		case "usedtx":
+			p.UseDTX = value == 1
+		case "cbr":
+			p.CBR = value == 1
+		case "stereo":
+			p.Stereo = value == 1
+		case "sprop-stereo":
+			p.SpropStereo = value == 1
+		case "maxaveragebitrate":
+			p.MaxAverageBitrate = uint32(value)
+		case "maxplaybackrate":
+			p.MaxPlaybackRate = uint32(value)
+		case "num_streams":
+			p.NumStreams = uint32(value)
+		case "coupled_streams":
+			p.CoupledStreams = uint32(value)
+		}
+	}
+
+	return p
+}
+
+// parseOpusChannelMapping parses a comma separated channel_mapping, nil if
+// it is invalid
+func parseOpusChannelMapping(value string) []uint8 {
+	mapping := []uint8{}
+	for _, index := range strings.Split(value, ",") {
+		parsed, err := strconv.ParseUint(strings.TrimSpace(index), 10, 8)
+		if err != nil {
+			return nil
+		}
+		mapping = append(mapping, uint8(parsed))
+	}
+	return mapping
+}
+
+// Fmtp returns the fmtp line for p, parameters with their default value are left out
+func (p OpusParameters) Fmtp() string {
+	params := []string{}
+	addUint := func(name string, value uint32) {
+		if value != 0 {
+			params = append(params, fmt.Sprintf("%s=%d", name, value))
+		}
+	}
+	addBool := func(name string, value bool) {
+		if value {
+			params = append(params, name+"=1")
+		}
+	}
+
+	addUint("minptime", p.MinPTime)
+	addBool("useinbandfec", p.UseInbandFEC)
+	addBool("usedtx", p.UseDTX)
+	addBool("cbr", p.CBR)
+	addBool("stereo", p.Stereo)
+	addBool("sprop-stereo", p.SpropStereo)
+	addUint("maxaveragebitrate", p.MaxAverageBitrate)
+	addUint("maxplaybackrate", p.MaxPlaybackRate)
+	addUint("num_streams", p.NumStreams)
+	addUint("coupled_streams", p.CoupledStreams)
+	if len(p.ChannelMapping) != 0 {
+		mapping := make([]string, len(p.ChannelMapping))
+		for i, index := range p.ChannelMapping {
+			mapping[i] = strconv.Itoa(int(index))
+		}
+		params = append(params, "channel_mapping="+strings.Join(mapping, ","))
+	}
+
+	return strings.Join(params, ";")
+}
+
+// OpusParameters parses the fmtp line of an Opus codec
+func (c *RTPCodec) OpusParameters() OpusParameters {
+	return ParseOpusParameters(c.SDPFmtpLine)
+}
+
+// ValidateChannels checks that the streams and channel mapping of p code
+// the channels of a multiopus stream, as RFC 7845 Section 5.1.1 requires
+func (p OpusParameters) ValidateChannels(channels uint16) error {
+	switch {
+	case channels < 3 || channels > 255:
+		return errOpusChannelsInvalid
+	case p.NumStreams == 0 || p.CoupledStreams > p.NumStreams ||
+		p.NumStreams+p.CoupledStreams > 255 || len(p.ChannelMapping) != int(channels):
+		return errOpusChannelMappingInvalid
+	}
+
+	// 255 is a silent channel
+	for _, index := range p.ChannelMapping {
+		if index != 255 && uint32(index) >= p.NumStreams+p.CoupledStreams {
+			return errOpusChannelMappingInvalid
+		}
+	}
+	return nil
+}
+
+// sameStreams returns whether p and other code channels in the same streams
+func (p OpusParameters) sameStreams(other OpusParameters) bool {
+	if p.NumStreams != other.NumStreams || p.CoupledStreams != other.CoupledStreams ||
+		len(p.ChannelMapping) != len(other.ChannelMapping) {
+		return false
+	}
+	for i := range p.ChannelMapping {
+		if p.ChannelMapping[i] != other.ChannelMapping[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,64 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpusParameters(t *testing.T) {
+	p := ParseOpusParameters("minptime=10; useinbandfec=1;usedtx=1;cbr=0;maxaveragebitrate=20000;stereo=invalid;unknown=1")
+	assert.Equal(t, OpusParameters{
+		MinPTime:          10,
+		UseInbandFEC:      true,
+		UseDTX:            true,
+		MaxAverageBitrate: 20000,
+	}, p)
+	assert.Equal(t, "minptime=10;useinbandfec=1;usedtx=1;maxaveragebitrate=20000", p.Fmtp())
+
+	// The default Opus codec is described by its parameters
+	codec := NewRTPOpusCodec(DefaultPayloadTypeOpus, 48000)
+	assert.Equal(t, codec.SDPFmtpLine, codec.OpusParameters().Fmtp())
+}
+
+func TestOpusParameters_Multichannel(t *testing.T) {
+	p := ParseOpusParameters("channel_mapping=0,4,1,2,3,5;coupled_streams=2;minptime=10;num_streams=4;useinbandfec=1")
+	assert.Equal(t, OpusParameters{
+		MinPTime:       10,
+		UseInbandFEC:   true,
+		NumStreams:     4,
+		CoupledStreams: 2,
+		ChannelMapping: []uint8{0, 4, 1, 2, 3, 5},
+	}, p)
+	assert.Equal(t, "minptime=10;useinbandfec=1;num_streams=4;coupled_streams=2;channel_mapping=0,4,1,2,3,5", p.Fmtp())
+	assert.NoError(t, p.ValidateChannels(6))
+	assert.Nil(t, ParseOpusParameters("channel_mapping=0,256").ChannelMapping)
+
+	for _, test := range []struct {
+		channels uint16
+		params   OpusParameters
+		err      error
+	}{
+		{2, OpusParameters{}, errOpusChannelsInvalid},
+		{6, OpusParameters{}, errOpusChannelMappingInvalid},
+		{6, OpusParameters{NumStreams: 4, CoupledStreams: 2, ChannelMapping: []uint8{0, 4, 1, 2, 3}}, errOpusChannelMappingInvalid},
+		{6, OpusParameters{NumStreams: 4, CoupledStreams: 2, ChannelMapping: []uint8{0, 4, 1, 2, 3, 6}}, errOpusChannelMappingInvalid},
+		{6, OpusParameters{NumStreams: 2, CoupledStreams: 3, ChannelMapping: []uint8{0, 4, 1, 2, 3, 5}}, errOpusChannelMappingInvalid},
+		// 255 is a silent channel
+		{3, OpusParameters{NumStreams: 1, CoupledStreams: 1, ChannelMapping: []uint8{0, 1, 255}}, nil},
+	} {
+		assert.Equal(t, test.err, test.params.ValidateChannels(test.channels))
+	}
+
+	// The surround layouts are in the Vorbis channel order, others have a
+	// stream per channel
+	codec := NewRTPMultiOpusCodec(100, 48000, 8)
+	assert.Equal(t, "minptime=10;useinbandfec=1;num_streams=5;coupled_streams=3;channel_mapping=0,6,1,2,3,4,5,7", codec.SDPFmtpLine)
+	for channels := uint16(3); channels <= 16; channels++ {
+		codec = NewRTPMultiOpusCodec(100, 48000, channels)
+		assert.NoError(t, codec.OpusParameters().ValidateChannels(channels))
+	}
+	assert.Equal(t, uint32(16), codec.OpusParameters().NumStreams)
+}
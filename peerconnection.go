@@ -4,11 +4,14 @@
 package webrtc
 
 import (
+	"context"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
+	"errors"
 	"fmt"
 	"io"
+	"runtime/pprof"
 	"strconv"
 	"strings"
 	"sync"
@@ -20,7 +23,10 @@ import (
 	"github.com/pion/rtcp"
 	"github.com/pion/sdp/v3"
 	"github.com/pion/webrtc/v3/internal/util"
+	"github.com/pion/webrtc/v3/pkg/colorspace"
 	"github.com/pion/webrtc/v3/pkg/rtcerr"
+	"github.com/pion/webrtc/v3/pkg/videoorientation"
+	"github.com/pion/webrtc/v3/pkg/videotiming"
 )
 
 // PeerConnection represents a WebRTC connection that establishes a
@@ -35,8 +41,19 @@ type PeerConnection struct {
 	// remote and local descriptions
 	ops *operations
 
+	// trackHandlerOps calls the OnTrack handler serially when the
+	// TrackHandlerModeDispatcher is set
+	trackHandlerOps *operations
+
+	// chainOps is the operations chain the signaling calls are run on
+	chainOps *operations
+
 	configuration Configuration
 
+	// rtcpMuxRequired is set if RTCPMuxPolicyRequire was given explicitly,
+	// only then remote descriptions that don't multiplex RTCP are rejected
+	rtcpMuxRequired bool
+
 	currentLocalDescription  *SessionDescription
 	pendingLocalDescription  *SessionDescription
 	currentRemoteDescription *SessionDescription
@@ -45,12 +62,42 @@ type PeerConnection struct {
 	iceConnectionState       ICEConnectionState
 	connectionState          PeerConnectionState
 
+	// connectionError is why the connection failed, see GetConnectionError
+	connectionError error
+
+	// remoteCandidates are the candidates given to AddICECandidate since the
+	// remote description was set, they are folded into it by its accessors
+	remoteCandidates []ICECandidateInit
+
+	// bufferedRemoteCandidates are the candidates given to AddICECandidate
+	// before there was a remote description
+	bufferedRemoteCandidates []ICECandidateInit
+
+	// stateChanged is closed and replaced when the connection state changes
+	// or SCTP is started, ConnectContext waits on it
+	stateChanged chan struct{}
+
 	idpLoginURL *string
 
 	isClosed               *atomicBool
 	isNegotiationNeeded    *atomicBool
 	negotiationNeededState negotiationNeededState
 
+	// done is closed when Close returns
+	done chan struct{}
+
+	// goroutines are the internal goroutines of the PeerConnection, Close
+	// waits for them. None are started once goroutinesStopped is set.
+	goroutines        sync.WaitGroup
+	goroutinesMu      sync.Mutex
+	goroutinesStopped bool
+	goroutineCount    int32
+
+	// rtpStarts are the startRTP calls starting senders and receivers.
+	// Close waits for them before stopping the transceivers, and none are
+	// started once goroutinesStopped is set.
+	rtpStarts sync.WaitGroup
+
 	lastOffer  string
 	lastAnswer string
 
@@ -67,13 +114,26 @@ type PeerConnection struct {
 	onConnectionStateChangeHandler    func(PeerConnectionState)
 	onTrackHandler                    func(*Track, *RTPReceiver)
 	onDataChannelHandler              func(*DataChannel)
+	onDTLSDataChannelHandler          func(*DTLSDataChannel)
+	onSSRCCollisionHandler            func(*RTPSender, uint32, uint32)
 	onNegotiationNeededHandler        atomic.Value // func()
 
+	// bitrateMu serializes the allocations of SetTargetBitrate
+	bitrateMu sync.Mutex
+
 	iceGatherer   *ICEGatherer
 	iceTransport  *ICETransport
 	dtlsTransport *DTLSTransport
 	sctpTransport *SCTPTransport
 
+	// mediaEngine is the MediaEngine of api at creation, it isn't
+	// affected by API.SetMediaEngine. It is replaced by a copy when a remote
+	// offer negotiates other payload types, the shared one isn't modified.
+	mediaEngineMu sync.RWMutex
+	mediaEngine   *MediaEngine
+
+	dtlsDataChannel *DTLSDataChannel
+
 	// A reference to the associated API state used by this connection
 	api *API
 	log logging.LeveledLogger
@@ -103,8 +163,11 @@ func (api *API) NewPeerConnection(configuration Configuration) (*PeerConnection,
 			Certificates:         []Certificate{},
 			ICECandidatePoolSize: 0,
 		},
-		ops:                    newOperations(),
+		trackHandlerOps:        newOperations(),
+		chainOps:               newOperations(),
 		isClosed:               &atomicBool{},
+		done:                   make(chan struct{}),
+		stateChanged:           make(chan struct{}),
 		isNegotiationNeeded:    &atomicBool{},
 		negotiationNeededState: negotiationNeededStateEmpty,
 		lastOffer:              "",
@@ -114,17 +177,24 @@ func (api *API) NewPeerConnection(configuration Configuration) (*PeerConnection,
 		iceConnectionState:     ICEConnectionStateNew,
 		connectionState:        PeerConnectionStateNew,
 
-		api: api,
-		log: api.settingEngine.LoggerFactory.NewLogger("pc"),
+		api:         api,
+		mediaEngine: api.getMediaEngine(),
+		log:         api.settingEngine.LoggerFactory.NewLogger("pc"),
 	}
+	pc.ops = newLabeledOperations(pc.pprofLabels())
 
 	var err error
 	if err = pc.initConfiguration(configuration); err != nil {
 		return nil, err
 	}
 
+	if err = api.addPeerConnection(pc); err != nil {
+		return nil, err
+	}
+
 	pc.iceGatherer, err = pc.createICEGatherer()
 	if err != nil {
+		api.removePeerConnection(pc)
 		return nil, err
 	}
 
@@ -135,12 +205,15 @@ func (api *API) NewPeerConnection(configuration Configuration) (*PeerConnection,
 	// Create the DTLS transport
 	dtlsTransport, err := pc.api.NewDTLSTransport(pc.iceTransport, pc.configuration.Certificates)
 	if err != nil {
+		api.removePeerConnection(pc)
 		return nil, err
 	}
 	pc.dtlsTransport = dtlsTransport
+	dtlsTransport.goInternal = pc.goInternal
 
 	// Create the SCTP transport
 	pc.sctpTransport = pc.api.NewSCTPTransport(pc.dtlsTransport)
+	pc.sctpTransport.goInternal = pc.goInternal
 
 	// Wire up the on datachannel handler
 	pc.sctpTransport.OnDataChannel(func(d *DataChannel) {
@@ -192,6 +265,7 @@ func (pc *PeerConnection) initConfiguration(configuration Configuration) error {
 
 	if configuration.RTCPMuxPolicy != RTCPMuxPolicy(Unknown) {
 		pc.configuration.RTCPMuxPolicy = configuration.RTCPMuxPolicy
+		pc.rtcpMuxRequired = configuration.RTCPMuxPolicy == RTCPMuxPolicyRequire
 	}
 
 	if configuration.ICECandidatePoolSize != 0 {
@@ -246,6 +320,16 @@ func (pc *PeerConnection) OnDataChannel(f func(*DataChannel)) {
 	pc.onDataChannelHandler = f
 }
 
+// OnDTLSDataChannel sets an event handler which is invoked when a
+// DTLSDataChannel has been negotiated and is ready to send and receive.
+// Messages are delivered once f returns, so the handlers set by f receive
+// every message.
+func (pc *PeerConnection) OnDTLSDataChannel(f func(*DTLSDataChannel)) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	pc.onDTLSDataChannelHandler = f
+}
+
 // OnNegotiationNeeded sets an event handler which is invoked when
 // a change has occurred which requires session negotiation
 func (pc *PeerConnection) OnNegotiationNeeded(f func()) {
@@ -269,6 +353,23 @@ func (pc *PeerConnection) onNegotiationNeeded() {
 	pc.ops.Enqueue(pc.negotiationNeededOp)
 }
 
+// chain runs op after the calls made before it to CreateOffer, CreateAnswer,
+// SetLocalDescription, SetRemoteDescription and AddICECandidate are done,
+// like the operations chain of a browser. Signaling from several goroutines
+// is applied in the order of the calls.
+// https://www.w3.org/TR/webrtc/#dfn-chain-an-operation
+func (pc *PeerConnection) chain(op func() error) error {
+	done := make(chan error, 1)
+	pc.chainOps.Enqueue(func() {
+		if pc.isClosed.get() {
+			done <- &rtcerr.InvalidStateError{Err: ErrConnectionClosed}
+			return
+		}
+		done <- op()
+	})
+	return <-done
+}
+
 func (pc *PeerConnection) negotiationNeededOp() {
 	// https://www.w3.org/TR/webrtc/#updating-the-negotiation-needed-flag
 	// Step 2.1
@@ -370,7 +471,13 @@ func (pc *PeerConnection) checkNegotiationNeeded() bool { //nolint:gocognit
 				}
 			case SDPTypeAnswer:
 				// Step 5.3.3
-				if _, ok := m.Attribute(t.Direction().String()); !ok {
+				direction := t.Direction()
+				if remoteDesc != nil {
+					if rm := getByMid(t.Mid(), remoteDesc); rm != nil {
+						direction = answerDirection(direction, getPeerDirection(rm))
+					}
+				}
+				if _, ok := m.Attribute(direction.String()); !ok {
 					return true
 				}
 			default:
@@ -402,7 +509,9 @@ func (pc *PeerConnection) OnICEGatheringStateChange(f func(ICEGathererState)) {
 }
 
 // OnTrack sets an event handler which is called when remote track
-// arrives from a remote peer.
+// arrives from a remote peer. Track.StreamIDs tells which tracks of the
+// remote peer belong together, like the audio and video of a participant.
+// SettingEngine.SetTrackHandlerMode sets on which goroutine it is called.
 func (pc *PeerConnection) OnTrack(f func(*Track, *RTPReceiver)) {
 	pc.mu.Lock()
 	defer pc.mu.Unlock()
@@ -416,10 +525,16 @@ func (pc *PeerConnection) onTrack(t *Track, r *RTPReceiver) {
 
 	pc.log.Debugf("got new track: %+v", t)
 	if t != nil {
-		if handler != nil {
-			go handler(t, r)
-		} else {
+		settingEngine := pc.api.settingEngine
+		switch {
+		case handler == nil:
 			pc.log.Warnf("OnTrack unset, unable to handle incoming media streams")
+		case settingEngine.trackHandlerMode == TrackHandlerModeDispatcher:
+			pc.trackHandlerOps.Enqueue(func() {
+				callTrackHandler(handler, t, r, settingEngine.trackHandlerPanicHandler)
+			})
+		default:
+			go callTrackHandler(handler, t, r, settingEngine.trackHandlerPanicHandler)
 		}
 	}
 }
@@ -556,7 +671,16 @@ func (pc *PeerConnection) hasLocalDescriptionChanged(desc *SessionDescription) b
 
 // CreateOffer starts the PeerConnection and generates the localDescription
 // https://w3c.github.io/webrtc-pc/#dom-rtcpeerconnection-createoffer
-func (pc *PeerConnection) CreateOffer(options *OfferOptions) (SessionDescription, error) { //nolint:gocognit
+func (pc *PeerConnection) CreateOffer(options *OfferOptions) (SessionDescription, error) {
+	var offer SessionDescription
+	err := pc.chain(func() (err error) {
+		offer, err = pc.createOffer(options)
+		return err
+	})
+	return offer, err
+}
+
+func (pc *PeerConnection) createOffer(options *OfferOptions) (SessionDescription, error) { //nolint:gocognit
 	useIdentity := pc.idpLoginURL != nil
 	switch {
 	case useIdentity:
@@ -591,7 +715,7 @@ func (pc *PeerConnection) CreateOffer(options *OfferOptions) (SessionDescription
 		// https://w3c.github.io/webrtc-pc/#dfn-in-parallel-steps-to-create-an-offer
 		isPlanB := pc.configuration.SDPSemantics == SDPSemanticsPlanB
 		if pc.currentRemoteDescription != nil {
-			isPlanB = descriptionIsPlanB(pc.RemoteDescription())
+			isPlanB = descriptionIsPlanB(pc.remoteDescription())
 		}
 
 		// include unmatched local transceivers
@@ -635,6 +759,11 @@ func (pc *PeerConnection) CreateOffer(options *OfferOptions) (SessionDescription
 			return SessionDescription{}, err
 		}
 
+		applyBrowserQuirks(pc.api.settingEngine.remoteBrowser, d)
+		if err = mungeSDP(pc.api.settingEngine.sdpMunger, SDPTypeOffer, d); err != nil {
+			return SessionDescription{}, err
+		}
+
 		sdpBytes, err := d.Marshal()
 		if err != nil {
 			return SessionDescription{}, err
@@ -665,6 +794,7 @@ func (pc *PeerConnection) createICEGatherer() (*ICEGatherer, error) {
 	if err != nil {
 		return nil, err
 	}
+	g.goInternal = pc.goInternal
 
 	return g, nil
 }
@@ -707,6 +837,10 @@ func (pc *PeerConnection) updateConnectionState(iceConnectionState ICEConnection
 
 	pc.log.Infof("peer connection state changed: %s", connectionState)
 	pc.connectionState = connectionState
+	if connectionState == PeerConnectionStateConnected {
+		pc.connectionError = nil
+	}
+	pc.signalStateChanged()
 	handler := pc.onConnectionStateChangeHandler
 	if handler != nil {
 		go handler(connectionState)
@@ -728,6 +862,7 @@ func (pc *PeerConnection) createICETransport() *ICETransport {
 			cs = ICEConnectionStateCompleted
 		case ICETransportStateFailed:
 			cs = ICEConnectionStateFailed
+			pc.setConnectionError(ErrICEConnectionFailed)
 		case ICETransportStateDisconnected:
 			cs = ICEConnectionStateDisconnected
 		case ICETransportStateClosed:
@@ -745,9 +880,18 @@ func (pc *PeerConnection) createICETransport() *ICETransport {
 
 // CreateAnswer starts the PeerConnection and generates the localDescription
 func (pc *PeerConnection) CreateAnswer(options *AnswerOptions) (SessionDescription, error) {
+	var answer SessionDescription
+	err := pc.chain(func() (err error) {
+		answer, err = pc.createAnswer(options)
+		return err
+	})
+	return answer, err
+}
+
+func (pc *PeerConnection) createAnswer(options *AnswerOptions) (SessionDescription, error) {
 	useIdentity := pc.idpLoginURL != nil
 	switch {
-	case pc.RemoteDescription() == nil:
+	case pc.remoteDescription() == nil:
 		return SessionDescription{}, &rtcerr.InvalidStateError{Err: ErrNoRemoteDescription}
 	case useIdentity:
 		return SessionDescription{}, errIdentityProviderNotImplemented
@@ -768,6 +912,11 @@ func (pc *PeerConnection) CreateAnswer(options *AnswerOptions) (SessionDescripti
 		return SessionDescription{}, err
 	}
 
+	applyBrowserQuirks(pc.api.settingEngine.remoteBrowser, d)
+	if err = mungeSDP(pc.api.settingEngine.sdpMunger, SDPTypeAnswer, d); err != nil {
+		return SessionDescription{}, err
+	}
+
 	sdpBytes, err := d.Marshal()
 	if err != nil {
 		return SessionDescription{}, err
@@ -852,6 +1001,7 @@ func (pc *PeerConnection) setDescription(sd *SessionDescription, op stateChangeO
 				nextState, err = checkNextSignalingState(cur, SignalingStateHaveRemoteOffer, setRemote, sd.Type)
 				if err == nil {
 					pc.pendingRemoteDescription = sd
+					pc.remoteCandidates = nil
 				}
 			// have-local-offer->SetRemote(answer)->stable
 			// have-remote-pranswer->SetRemote(answer)->stable
@@ -862,6 +1012,7 @@ func (pc *PeerConnection) setDescription(sd *SessionDescription, op stateChangeO
 					pc.currentLocalDescription = pc.pendingLocalDescription
 					pc.pendingRemoteDescription = nil
 					pc.pendingLocalDescription = nil
+					pc.remoteCandidates = nil
 				}
 			case SDPTypeRollback:
 				nextState, err = checkNextSignalingState(cur, SignalingStateStable, setRemote, sd.Type)
@@ -873,6 +1024,7 @@ func (pc *PeerConnection) setDescription(sd *SessionDescription, op stateChangeO
 				nextState, err = checkNextSignalingState(cur, SignalingStateHaveRemotePranswer, setRemote, sd.Type)
 				if err == nil {
 					pc.pendingRemoteDescription = sd
+					pc.remoteCandidates = nil
 				}
 			default:
 				return nextState, &rtcerr.OperationError{Err: fmt.Errorf("%w: %s(%s)", errPeerConnStateChangeInvalid, op, sd.Type)}
@@ -897,6 +1049,12 @@ func (pc *PeerConnection) setDescription(sd *SessionDescription, op stateChangeO
 
 // SetLocalDescription sets the SessionDescription of the local peer
 func (pc *PeerConnection) SetLocalDescription(desc SessionDescription) error {
+	return pc.chain(func() error {
+		return pc.setLocalDescription(desc)
+	})
+}
+
+func (pc *PeerConnection) setLocalDescription(desc SessionDescription) error {
 	if pc.isClosed.get() {
 		return &rtcerr.InvalidStateError{Err: ErrConnectionClosed}
 	}
@@ -928,7 +1086,10 @@ func (pc *PeerConnection) SetLocalDescription(desc SessionDescription) error {
 	currentTransceivers := append([]*RTPTransceiver{}, pc.GetTransceivers()...)
 
 	weAnswer := desc.Type == SDPTypeAnswer
-	remoteDesc := pc.RemoteDescription()
+	remoteDesc := pc.remoteDescription()
+	if weAnswer {
+		pc.setCurrentDirections(&desc, false)
+	}
 	if weAnswer && remoteDesc != nil {
 		pc.ops.Enqueue(func() {
 			pc.startRTP(haveLocalDescription, remoteDesc, currentTransceivers)
@@ -952,9 +1113,36 @@ func (pc *PeerConnection) LocalDescription() *SessionDescription {
 	return pc.CurrentLocalDescription()
 }
 
-// SetRemoteDescription sets the SessionDescription of the remote peer
+// SetRemoteDescription sets the SessionDescription of the remote peer. The
+// candidates given to AddICECandidate before are added once it is set.
+func (pc *PeerConnection) SetRemoteDescription(desc SessionDescription) error {
+	return pc.chain(func() error {
+		if err := pc.setRemoteDescription(desc); err != nil {
+			return err
+		}
+
+		pc.addBufferedRemoteCandidates()
+		return nil
+	})
+}
+
+// addBufferedRemoteCandidates adds the candidates given to AddICECandidate
+// before there was a remote description
+func (pc *PeerConnection) addBufferedRemoteCandidates() {
+	pc.mu.Lock()
+	candidates := pc.bufferedRemoteCandidates
+	pc.bufferedRemoteCandidates = nil
+	pc.mu.Unlock()
+
+	for _, candidate := range candidates {
+		if err := pc.addICECandidate(candidate); err != nil {
+			pc.log.Warnf("Failed to add buffered ICE candidate: %v", err)
+		}
+	}
+}
+
 // nolint: gocyclo
-func (pc *PeerConnection) SetRemoteDescription(desc SessionDescription) error { //nolint:gocognit
+func (pc *PeerConnection) setRemoteDescription(desc SessionDescription) error { //nolint:gocognit
 	if pc.isClosed.get() {
 		return &rtcerr.InvalidStateError{Err: ErrConnectionClosed}
 	}
@@ -964,17 +1152,32 @@ func (pc *PeerConnection) SetRemoteDescription(desc SessionDescription) error {
 	if _, err := desc.Unmarshal(); err != nil {
 		return err
 	}
+	if rtcpMuxMissing(desc.parsed) {
+		// Only RTP candidates are gathered, RTCP is always multiplexed
+		if pc.rtcpMuxRequired {
+			return &rtcerr.InvalidAccessError{Err: errPeerConnRTCPMuxRequired}
+		}
+		pc.log.Warn("Remote description doesn't multiplex RTCP, RTCP is sent on the RTP candidates")
+	}
+
 	if err := pc.setDescription(&desc, stateChangeOpSetRemote); err != nil {
 		return err
 	}
 
+	// The answer uses the payload types of the offer
+	if desc.Type == SDPTypeOffer {
+		pc.mediaEngineMu.Lock()
+		pc.mediaEngine = pc.mediaEngine.withRemotePayloadTypes(desc.parsed)
+		pc.mediaEngineMu.Unlock()
+	}
+
 	var t *RTPTransceiver
 	localTransceivers := append([]*RTPTransceiver{}, pc.GetTransceivers()...)
-	detectedPlanB := descriptionIsPlanB(pc.RemoteDescription())
+	detectedPlanB := descriptionIsPlanB(pc.remoteDescription())
 	weOffer := desc.Type == SDPTypeAnswer
 
 	if !weOffer && !detectedPlanB {
-		for _, media := range pc.RemoteDescription().parsed.MediaDescriptions {
+		for _, media := range pc.remoteDescription().parsed.MediaDescriptions {
 			midValue := getMidValue(media)
 			if midValue == "" {
 				return errPeerConnRemoteDescriptionWithoutMidValue
@@ -993,10 +1196,6 @@ func (pc *PeerConnection) SetRemoteDescription(desc SessionDescription) error {
 			t, localTransceivers = findByMid(midValue, localTransceivers)
 			if t == nil {
 				t, localTransceivers = satisfyTypeAndDirection(kind, direction, localTransceivers)
-			} else if direction == RTPTransceiverDirectionInactive {
-				if err := t.Stop(); err != nil {
-					return err
-				}
 			}
 
 			if t == nil {
@@ -1041,6 +1240,10 @@ func (pc *PeerConnection) SetRemoteDescription(desc SessionDescription) error {
 	}
 
 	currentTransceivers := append([]*RTPTransceiver{}, pc.GetTransceivers()...)
+	setRemoteBandwidths(desc.parsed, currentTransceivers)
+	if weOffer {
+		pc.setCurrentDirections(&desc, true)
+	}
 
 	if isRenegotation {
 		if weOffer {
@@ -1051,10 +1254,7 @@ func (pc *PeerConnection) SetRemoteDescription(desc SessionDescription) error {
 		return nil
 	}
 
-	remoteIsLite := false
-	if liteValue, haveRemoteIs := desc.parsed.Attribute(sdp.AttrKeyICELite); haveRemoteIs && liteValue == sdp.AttrKeyICELite {
-		remoteIsLite = true
-	}
+	remoteIsLite := isICELite(desc.parsed)
 
 	fingerprint, fingerprintHash, err := extractFingerprint(desc.parsed)
 	if err != nil {
@@ -1062,7 +1262,7 @@ func (pc *PeerConnection) SetRemoteDescription(desc SessionDescription) error {
 	}
 
 	iceRole := ICERoleControlled
-	// If one of the agents is lite and the other one is not, the lite agent must be the controlling agent.
+	// If one of the agents is lite and the other one is not, the full agent must be the controlling agent.
 	// If both or neither agents are lite the offering agent is controlling.
 	// RFC 8445 S6.1.1
 	if (weOffer && remoteIsLite == pc.api.settingEngine.candidates.ICELite) || (remoteIsLite && !pc.api.settingEngine.candidates.ICELite) {
@@ -1080,7 +1280,43 @@ func (pc *PeerConnection) SetRemoteDescription(desc SessionDescription) error {
 	return nil
 }
 
+// setCurrentDirections sets the directions negotiated by answer, which was
+// created by the remote peer if isRemote
+func (pc *PeerConnection) setCurrentDirections(answer *SessionDescription, isRemote bool) {
+	if answer.parsed == nil || descriptionIsPlanB(answer) {
+		return
+	}
+
+	for _, t := range pc.GetTransceivers() {
+		m := getByMid(t.Mid(), answer)
+		if m == nil {
+			continue
+		}
+
+		direction := getPeerDirection(m)
+		if direction == RTPTransceiverDirection(Unknown) {
+			continue
+		}
+		if isRemote {
+			direction = direction.Revers()
+		}
+		t.setCurrentDirection(direction)
+	}
+}
+
 func (pc *PeerConnection) startReceiver(incoming trackDetails, receiver *RTPReceiver) {
+	if receiver.kind == RTPCodecTypeAudio {
+		receiver.audioLevel.setExtensionID(pc.negotiatedExtensionID(AudioLevelURI))
+	}
+	receiver.absTime.setExtensionIDs(pc.negotiatedExtensionID(sdp.ABSSendTimeURI), pc.negotiatedExtensionID(AbsCaptureTimeURI))
+	if receiver.kind == RTPCodecTypeVideo {
+		receiver.trackExtensions.setExtensionIDs(
+			pc.negotiatedExtensionID(PlayoutDelayURI), pc.negotiatedExtensionID(videotiming.URI),
+			pc.negotiatedExtensionID(videoorientation.URI), pc.negotiatedExtensionID(colorspace.URI),
+		)
+	}
+	receiver.setHeaderExtensions(pc.negotiatedExtensions())
+
 	encodings := []RTPDecodingParameters{}
 	if incoming.ssrc != 0 {
 		encodings = append(encodings, RTPDecodingParameters{RTPCodingParameters{SSRC: incoming.ssrc}})
@@ -1090,7 +1326,9 @@ func (pc *PeerConnection) startReceiver(incoming trackDetails, receiver *RTPRece
 	}
 
 	if err := receiver.Receive(RTPReceiveParameters{Encodings: encodings}); err != nil {
-		pc.log.Warnf("RTPReceiver Receive failed %s", err)
+		if !errors.Is(err, errRTPReceiverStopped) {
+			pc.log.Warnf("RTPReceiver Receive failed %s", err)
+		}
 		return
 	}
 
@@ -1100,6 +1338,7 @@ func (pc *PeerConnection) startReceiver(incoming trackDetails, receiver *RTPRece
 		receiver.tracks[i].track.mu.Lock()
 		receiver.tracks[i].track.id = incoming.id
 		receiver.tracks[i].track.label = incoming.label
+		receiver.tracks[i].track.streamIDs = incoming.streamIDs
 		receiver.tracks[i].track.mu.Unlock()
 	}
 
@@ -1108,13 +1347,13 @@ func (pc *PeerConnection) startReceiver(incoming trackDetails, receiver *RTPRece
 		return
 	}
 
-	go func() {
+	pc.goInternal(func() {
 		if err := receiver.Track().determinePayloadType(); err != nil {
 			pc.log.Warnf("Could not determine PayloadType for SSRC %d", receiver.Track().SSRC())
 			return
 		}
 
-		codec, err := pc.api.mediaEngine.getCodec(receiver.Track().PayloadType())
+		codec, redPayloadType, err := pc.getMediaEngine().getRemoteCodec(receiver.Track().PayloadType())
 		if err != nil {
 			pc.log.Warnf("no codec could be found for payloadType %d", receiver.Track().PayloadType())
 			return
@@ -1123,10 +1362,12 @@ func (pc *PeerConnection) startReceiver(incoming trackDetails, receiver *RTPRece
 		receiver.Track().mu.Lock()
 		receiver.Track().kind = codec.Type
 		receiver.Track().codec = codec
+		receiver.Track().payloadType = codec.PayloadType
+		receiver.Track().redPayloadType = redPayloadType
 		receiver.Track().mu.Unlock()
 
 		pc.onTrack(receiver.Track(), receiver)
-	}()
+	})
 }
 
 // startRTPReceivers opens knows inbound SRTP streams from the RemoteDescription
@@ -1138,7 +1379,7 @@ func (pc *PeerConnection) startRTPReceivers(incomingTracks []trackDetails, curre
 	case SDPSemanticsPlanB:
 		remoteIsPlanB = true
 	case SDPSemanticsUnifiedPlanWithFallback:
-		remoteIsPlanB = descriptionIsPlanB(pc.RemoteDescription())
+		remoteIsPlanB = descriptionIsPlanB(pc.remoteDescription())
 	default:
 		// none
 	}
@@ -1203,23 +1444,108 @@ func (pc *PeerConnection) startRTPReceivers(incomingTracks []trackDetails, curre
 
 // startRTPSenders starts all outbound RTP streams
 func (pc *PeerConnection) startRTPSenders(currentTransceivers []*RTPTransceiver) {
+	audioLevelExtensionID := pc.negotiatedExtensionID(AudioLevelURI)
+	absSendTimeExtensionID := pc.negotiatedExtensionID(sdp.ABSSendTimeURI)
+	absCaptureTimeExtensionID := pc.negotiatedExtensionID(AbsCaptureTimeURI)
+	playoutDelayExtensionID := pc.negotiatedExtensionID(PlayoutDelayURI)
+	orientationExtensionID := pc.negotiatedExtensionID(videoorientation.URI)
+	colorSpaceExtensionID := pc.negotiatedExtensionID(colorspace.URI)
+	contentTypeExtensionID := pc.negotiatedExtensionID(VideoContentTypeURI)
 	for _, transceiver := range currentTransceivers {
 		if transceiver.Sender() != nil && transceiver.Sender().isNegotiated() && !transceiver.Sender().hasSent() {
+			payloadType := pc.negotiatedPayloadType(transceiver.Sender().Track())
+			if transceiver.Sender().Track().Kind() == RTPCodecTypeAudio {
+				transceiver.Sender().audioLevel.setExtensionID(audioLevelExtensionID)
+				if strings.EqualFold(transceiver.Sender().Track().Codec().Name, Opus) {
+					if redPayloadType, distance, ok := pc.negotiatedRed(payloadType); ok {
+						transceiver.Sender().red.setPayloadTypes(payloadType, redPayloadType, distance)
+					}
+				}
+			}
+			transceiver.Sender().absTime.setExtensionIDs(absSendTimeExtensionID, absCaptureTimeExtensionID, transceiver.Sender().Track().Codec().ClockRate)
+			if transceiver.Sender().Track().Kind() == RTPCodecTypeVideo {
+				transceiver.Sender().trackExtensions.setExtensionIDs(playoutDelayExtensionID, orientationExtensionID, colorSpaceExtensionID)
+				transceiver.Sender().contentHint.setExtensionID(contentTypeExtensionID)
+			}
 			err := transceiver.Sender().Send(RTPSendParameters{
 				Encodings: RTPEncodingParameters{
-					RTPCodingParameters{
-						SSRC:        transceiver.Sender().Track().SSRC(),
-						PayloadType: transceiver.Sender().Track().PayloadType(),
+					RTPCodingParameters: RTPCodingParameters{
+						SSRC:        transceiver.Sender().SSRC(),
+						PayloadType: payloadType,
 					},
 				},
 			})
-			if err != nil {
+			if err != nil && !errors.Is(err, errRTPSenderStopped) {
 				pc.log.Warnf("Failed to start Sender: %s", err)
 			}
 		}
 	}
 }
 
+// negotiatedExtensionID returns the ID of the header extension uri if it is
+// negotiated, or 0
+func (pc *PeerConnection) negotiatedExtensionID(uri string) uint8 {
+	remoteDescription := pc.remoteDescription()
+	if remoteDescription == nil || remoteDescription.parsed == nil {
+		return 0
+	}
+
+	matched, err := matchedAnswerExt(remoteDescription.parsed, pc.api.settingEngine.getSDPExtensions())
+	if err != nil {
+		return 0
+	}
+	if extMap := getExtMapByURI(matched, uri); extMap != nil {
+		return uint8(extMap.Value)
+	}
+	return 0
+}
+
+// negotiatedExtensions returns the IDs of the negotiated header extensions by
+// their URI
+func (pc *PeerConnection) negotiatedExtensions() map[string]uint8 {
+	extensions := map[string]uint8{}
+	remoteDescription := pc.remoteDescription()
+	if remoteDescription == nil || remoteDescription.parsed == nil {
+		return extensions
+	}
+
+	matched, err := matchedAnswerExt(remoteDescription.parsed, pc.api.settingEngine.getSDPExtensions())
+	if err != nil {
+		return extensions
+	}
+	for _, extList := range matched {
+		for _, extMap := range extList {
+			if _, ok := extensions[extMap.URI.String()]; !ok {
+				extensions[extMap.URI.String()] = uint8(extMap.Value)
+			}
+		}
+	}
+	return extensions
+}
+
+// Start the DTLSDataChannel, the DTLS transport must already be connected
+func (pc *PeerConnection) startDTLSDataChannel() {
+	pc.mu.Lock()
+	if pc.dtlsDataChannel != nil || pc.dtlsTransport.conn == nil {
+		pc.mu.Unlock()
+		return
+	}
+
+	d := newDTLSDataChannel(pc.dtlsTransport.conn, pc.log)
+	pc.dtlsDataChannel = d
+	handler := pc.onDTLSDataChannelHandler
+	pc.mu.Unlock()
+
+	// The read loop exits once the DTLS connection is closed, Close waits
+	// for it. The handlers run on another goroutine.
+	readLoop := func() {
+		d.readLoop(handler)
+	}
+	if !pc.goInternal(readLoop) {
+		go readLoop()
+	}
+}
+
 // Start SCTP subsystem
 func (pc *PeerConnection) startSCTP() {
 	// Start sctp
@@ -1234,6 +1560,10 @@ func (pc *PeerConnection) startSCTP() {
 		return
 	}
 
+	pc.mu.Lock()
+	pc.signalStateChanged()
+	pc.mu.Unlock()
+
 	// DataChannels that need to be opened now that SCTP is available
 	// make a copy we may have incoming DataChannels mutating this while we open
 	pc.sctpTransport.lock.RLock()
@@ -1258,7 +1588,7 @@ func (pc *PeerConnection) startSCTP() {
 }
 
 func (pc *PeerConnection) handleUndeclaredSSRC(rtpStream io.Reader, ssrc uint32) error { //nolint:gocognit
-	remoteDescription := pc.RemoteDescription()
+	remoteDescription := pc.remoteDescription()
 	if remoteDescription == nil {
 		return errPeerConnRemoteDescriptionNil
 	}
@@ -1291,7 +1621,7 @@ func (pc *PeerConnection) handleUndeclaredSSRC(rtpStream io.Reader, ssrc uint32)
 	}
 
 	//  Simulcast no longer uses SSRCes, but RID instead. We then use that value to populate rest of Track Data
-	matchedSDPMap, err := matchedAnswerExt(pc.RemoteDescription().parsed, pc.api.settingEngine.getSDPExtensions())
+	matchedSDPMap, err := matchedAnswerExt(pc.remoteDescription().parsed, pc.api.settingEngine.getSDPExtensions())
 	if err != nil {
 		return err
 	}
@@ -1326,7 +1656,7 @@ func (pc *PeerConnection) handleUndeclaredSSRC(rtpStream io.Reader, ssrc uint32)
 			continue
 		}
 
-		codec, err := pc.api.mediaEngine.getCodec(payloadType)
+		codec, err := pc.getMediaEngine().getCodec(payloadType)
 		if err != nil {
 			return err
 		}
@@ -1350,7 +1680,7 @@ func (pc *PeerConnection) handleUndeclaredSSRC(rtpStream io.Reader, ssrc uint32)
 
 // undeclaredMediaProcessor handles RTP/RTCP packets that don't match any a:ssrc lines
 func (pc *PeerConnection) undeclaredMediaProcessor() {
-	go func() {
+	pc.goInternal(func() {
 		for {
 			srtpSession, err := pc.dtlsTransport.getSRTPSession()
 			if err != nil {
@@ -1364,13 +1694,14 @@ func (pc *PeerConnection) undeclaredMediaProcessor() {
 				return
 			}
 
+			pc.resolveSSRCCollisions([]uint32{ssrc}, pc.GetTransceivers())
 			if err := pc.handleUndeclaredSSRC(stream, ssrc); err != nil {
 				pc.log.Errorf("Incoming unhandled RTP ssrc(%d), OnTrack will not be fired. %v", ssrc, err)
 			}
 		}
-	}()
+	})
 
-	go func() {
+	pc.goInternal(func() {
 		for {
 			srtcpSession, err := pc.dtlsTransport.getSRTCPSession()
 			if err != nil {
@@ -1385,7 +1716,7 @@ func (pc *PeerConnection) undeclaredMediaProcessor() {
 			}
 			pc.log.Warnf("Incoming unhandled RTCP ssrc(%d), OnTrack will not be fired", ssrc)
 		}
-	}()
+	})
 }
 
 // RemoteDescription returns pendingRemoteDescription if it is not null and
@@ -1396,6 +1727,18 @@ func (pc *PeerConnection) RemoteDescription() *SessionDescription {
 	pc.mu.RLock()
 	defer pc.mu.RUnlock()
 
+	if pc.pendingRemoteDescription != nil {
+		return populateRemoteCandidates(pc.pendingRemoteDescription, pc.remoteCandidates)
+	}
+	return populateRemoteCandidates(pc.currentRemoteDescription, pc.remoteCandidates)
+}
+
+// remoteDescription is RemoteDescription as it was set, without the
+// candidates added since
+func (pc *PeerConnection) remoteDescription() *SessionDescription {
+	pc.mu.RLock()
+	defer pc.mu.RUnlock()
+
 	if pc.pendingRemoteDescription != nil {
 		return pc.pendingRemoteDescription
 	}
@@ -1403,12 +1746,17 @@ func (pc *PeerConnection) RemoteDescription() *SessionDescription {
 }
 
 // AddICECandidate accepts an ICE candidate string and adds it
-// to the existing set of candidates
+// to the existing set of candidates. A candidate given before the remote
+// description is added once SetRemoteDescription is called. A candidate
+// whose UsernameFragment isn't the one of the remote description, as it is
+// of an ICE generation before a restart, is rejected.
 func (pc *PeerConnection) AddICECandidate(candidate ICECandidateInit) error {
-	if pc.RemoteDescription() == nil {
-		return &rtcerr.InvalidStateError{Err: ErrNoRemoteDescription}
-	}
+	return pc.chain(func() error {
+		return pc.addICECandidate(candidate)
+	})
+}
 
+func (pc *PeerConnection) addICECandidate(candidate ICECandidateInit) error {
 	candidateValue := strings.TrimPrefix(candidate.Candidate, "candidate:")
 	c, err := ice.UnmarshalCandidate(candidateValue)
 	if err != nil {
@@ -1420,7 +1768,30 @@ func (pc *PeerConnection) AddICECandidate(candidate ICECandidateInit) error {
 		return err
 	}
 
-	return pc.iceTransport.AddRemoteCandidate(iceCandidate)
+	// Signaling can deliver candidates before the description they are for,
+	// they are added once a remote description is set
+	pc.mu.Lock()
+	if pc.pendingRemoteDescription == nil && pc.currentRemoteDescription == nil {
+		pc.bufferedRemoteCandidates = append(pc.bufferedRemoteCandidates, candidate)
+		pc.mu.Unlock()
+		return nil
+	}
+	pc.mu.Unlock()
+
+	// A candidate of another ICE generation, like one sent before an ICE
+	// restart, isn't added to the checks of this one
+	if ufrag := candidateUfrag(candidate); ufrag != "" && !haveICEUfrag(pc.remoteDescription().parsed, ufrag) {
+		return &rtcerr.OperationError{Err: fmt.Errorf("%w: %s", errPeerConnICECandidateUfragMismatch, ufrag)}
+	}
+
+	if err := pc.iceTransport.AddRemoteCandidate(iceCandidate); err != nil {
+		return err
+	}
+
+	pc.mu.Lock()
+	pc.remoteCandidates = append(pc.remoteCandidates, candidate)
+	pc.mu.Unlock()
+	return nil
 }
 
 // ICEConnectionState returns the ICE connection state of the
@@ -1464,7 +1835,7 @@ func (pc *PeerConnection) GetTransceivers() []*RTPTransceiver {
 	pc.mu.Lock()
 	defer pc.mu.Unlock()
 
-	return pc.rtpTransceivers
+	return append([]*RTPTransceiver{}, pc.rtpTransceivers...)
 }
 
 // AddTrack adds a Track to the PeerConnection
@@ -1503,6 +1874,30 @@ func (pc *PeerConnection) AddTrack(track *Track) (*RTPSender, error) {
 	return transceiver.Sender(), nil
 }
 
+// AddTrackContext adds a Track to the PeerConnection like AddTrack, and
+// removes it with RemoveTrack once ctx is done. This limits how long a
+// Track is sent, for example with a context.WithTimeout for a preview.
+func (pc *PeerConnection) AddTrackContext(ctx context.Context, track *Track) (*RTPSender, error) {
+	sender, err := pc.AddTrack(track)
+	if err != nil {
+		return nil, err
+	}
+
+	pc.goInternal(func() {
+		select {
+		case <-ctx.Done():
+		case <-sender.stopCalled:
+			return
+		}
+
+		if removeErr := pc.RemoveTrack(sender); removeErr != nil && !pc.isClosed.get() {
+			pc.log.Warnf("Failed to remove Track after context is done: %v", removeErr)
+		}
+	})
+
+	return sender, nil
+}
+
 // AddTransceiver Create a new RtpTransceiver and add it to the set of transceivers.
 // Deprecated: Use AddTrack, AddTransceiverFromKind or AddTransceiverFromTrack
 func (pc *PeerConnection) AddTransceiver(trackOrKind RTPCodecType, init ...RtpTransceiverInit) (*RTPTransceiver, error) {
@@ -1538,7 +1933,9 @@ func (pc *PeerConnection) RemoveTrack(sender *RTPSender) error {
 	return nil
 }
 
-// AddTransceiverFromKind Create a new RtpTransceiver(SendRecv or RecvOnly) and add it to the set of transceivers.
+// AddTransceiverFromKind Create a new RtpTransceiver and add it to the set of transceivers.
+// A sendrecv or sendonly RtpTransceiver sends a new Track of the first codec
+// of kind, see AddTransceiverFromTrack for the RtpTransceiverInit.
 func (pc *PeerConnection) AddTransceiverFromKind(kind RTPCodecType, init ...RtpTransceiverInit) (*RTPTransceiver, error) {
 	if pc.isClosed.get() {
 		return nil, &rtcerr.InvalidStateError{Err: ErrConnectionClosed}
@@ -1547,13 +1944,13 @@ func (pc *PeerConnection) AddTransceiverFromKind(kind RTPCodecType, init ...RtpT
 	direction := RTPTransceiverDirectionSendrecv
 	if len(init) > 1 {
 		return nil, errPeerConnAddTransceiverFromKindOnlyAcceptsOne
-	} else if len(init) == 1 {
+	} else if len(init) == 1 && init[0].Direction != RTPTransceiverDirection(Unknown) {
 		direction = init[0].Direction
 	}
 
 	switch direction {
-	case RTPTransceiverDirectionSendrecv:
-		codecs := pc.api.mediaEngine.GetCodecsByKind(kind)
+	case RTPTransceiverDirectionSendrecv, RTPTransceiverDirectionSendonly:
+		codecs := pc.getMediaEngine().GetCodecsByKind(kind)
 		if len(codecs) == 0 {
 			return nil, fmt.Errorf("%w: %s", errPeerConnCodecsNotFound, kind.String())
 		}
@@ -1565,7 +1962,7 @@ func (pc *PeerConnection) AddTransceiverFromKind(kind RTPCodecType, init ...RtpT
 
 		return pc.AddTransceiverFromTrack(track, init...)
 
-	case RTPTransceiverDirectionRecvonly:
+	case RTPTransceiverDirectionRecvonly, RTPTransceiverDirectionInactive:
 		receiver, err := pc.api.NewRTPReceiver(kind, pc.dtlsTransport)
 		if err != nil {
 			return nil, err
@@ -1574,9 +1971,12 @@ func (pc *PeerConnection) AddTransceiverFromKind(kind RTPCodecType, init ...RtpT
 		t := pc.newRTPTransceiver(
 			receiver,
 			nil,
-			RTPTransceiverDirectionRecvonly,
+			direction,
 			kind,
 		)
+		if len(init) == 1 {
+			t.setBandwidth(init[0].Bandwidth)
+		}
 
 		pc.onNegotiationNeeded()
 
@@ -1586,7 +1986,11 @@ func (pc *PeerConnection) AddTransceiverFromKind(kind RTPCodecType, init ...RtpT
 	}
 }
 
-// AddTransceiverFromTrack Create a new RtpTransceiver(SendRecv or SendOnly) and add it to the set of transceivers.
+// AddTransceiverFromTrack Create a new RtpTransceiver sending track and add it to the set of transceivers.
+// The RtpTransceiverInit sets the direction, sendrecv if unset, the IDs of
+// the streams track is signaled in and the parameters of the encoding sent.
+// A recvonly or inactive RtpTransceiver doesn't send track until its
+// direction is changed with SetDirection.
 func (pc *PeerConnection) AddTransceiverFromTrack(track *Track, init ...RtpTransceiverInit) (*RTPTransceiver, error) {
 	if pc.isClosed.get() {
 		return nil, &rtcerr.InvalidStateError{Err: ErrConnectionClosed}
@@ -1595,52 +1999,49 @@ func (pc *PeerConnection) AddTransceiverFromTrack(track *Track, init ...RtpTrans
 	direction := RTPTransceiverDirectionSendrecv
 	if len(init) > 1 {
 		return nil, errPeerConnAddTransceiverFromTrackOnlyAcceptsOne
-	} else if len(init) == 1 {
+	} else if len(init) == 1 && init[0].Direction != RTPTransceiverDirection(Unknown) {
 		direction = init[0].Direction
 	}
 
 	switch direction {
-	case RTPTransceiverDirectionSendrecv:
-		receiver, err := pc.api.NewRTPReceiver(track.Kind(), pc.dtlsTransport)
-		if err != nil {
-			return nil, err
-		}
+	case RTPTransceiverDirectionSendrecv, RTPTransceiverDirectionSendonly,
+		RTPTransceiverDirectionRecvonly, RTPTransceiverDirectionInactive:
+	default:
+		return nil, errPeerConnAddTransceiverFromTrackSupport
+	}
 
-		sender, err := pc.api.NewRTPSender(track, pc.dtlsTransport)
-		if err != nil {
+	var receiver *RTPReceiver
+	if direction != RTPTransceiverDirectionSendonly {
+		var err error
+		if receiver, err = pc.api.NewRTPReceiver(track.Kind(), pc.dtlsTransport); err != nil {
 			return nil, err
 		}
+	}
 
-		t := pc.newRTPTransceiver(
-			receiver,
-			sender,
-			RTPTransceiverDirectionSendrecv,
-			track.Kind(),
-		)
-
-		pc.onNegotiationNeeded()
-
-		return t, nil
-
-	case RTPTransceiverDirectionSendonly:
-		sender, err := pc.api.NewRTPSender(track, pc.dtlsTransport)
-		if err != nil {
+	sender, err := pc.api.NewRTPSender(track, pc.dtlsTransport)
+	if err != nil {
+		return nil, err
+	}
+	if len(init) == 1 {
+		if err = sender.setInit(init[0]); err != nil {
+			sender.Stop() // nolint:errcheck
 			return nil, err
 		}
+	}
 
-		t := pc.newRTPTransceiver(
-			nil,
-			sender,
-			RTPTransceiverDirectionSendonly,
-			track.Kind(),
-		)
+	t := pc.newRTPTransceiver(
+		receiver,
+		sender,
+		direction,
+		track.Kind(),
+	)
+	if len(init) == 1 {
+		t.setBandwidth(init[0].Bandwidth)
+	}
 
-		pc.onNegotiationNeeded()
+	pc.onNegotiationNeeded()
 
-		return t, nil
-	default:
-		return nil, errPeerConnAddTransceiverFromTrackOneTransceiver
-	}
+	return t, nil
 }
 
 // CreateDataChannel creates a new DataChannel object with the given label
@@ -1731,31 +2132,25 @@ func (pc *PeerConnection) SetIdentityProvider(provider string) error {
 // WriteRTCP sends a user provided RTCP packet to the connected peer
 // If no peer is connected the packet is discarded
 func (pc *PeerConnection) WriteRTCP(pkts []rtcp.Packet) error {
-	raw, err := rtcp.Marshal(pkts)
-	if err != nil {
-		return err
-	}
-
-	srtcpSession, err := pc.dtlsTransport.getSRTCPSession()
-	if err != nil {
-		return nil
-	}
-
-	writeStream, err := srtcpSession.OpenWriteStream()
-	if err != nil {
-		return fmt.Errorf("%w: %v", errPeerConnWriteRTCPOpenWriteStream, err)
-	}
-
-	if _, err := writeStream.Write(raw); err != nil {
-		return err
-	}
-	return nil
+	return pc.dtlsTransport.writeRTCP(pkts)
 }
 
-// Close ends the PeerConnection
+// Close ends the PeerConnection. It returns once the internal goroutines of
+// the PeerConnection have exited. Event handlers, the goroutines delivering
+// the messages of DataChannels to them, and the goroutines they started
+// aren't waited for, so handlers may call Close. Calls of Close during
+// another one wait for it to return.
 func (pc *PeerConnection) Close() error {
+	return pc.CloseWithOptions(CloseOptions{})
+}
+
+// CloseWithOptions ends the PeerConnection like Close, after sending what
+// options asks for, like the data still queued in the DataChannels, so
+// nothing sent right before closing is lost.
+func (pc *PeerConnection) CloseWithOptions(options CloseOptions) error { //nolint:gocognit
 	// https://www.w3.org/TR/webrtc/#dom-rtcpeerconnection-close (step #1)
 	if pc.isClosed.get() {
+		<-pc.done
 		return nil
 	}
 
@@ -1765,6 +2160,16 @@ func (pc *PeerConnection) Close() error {
 	// https://www.w3.org/TR/webrtc/#dom-rtcpeerconnection-close (step #3)
 	pc.signalingState.Set(SignalingStateClosed)
 
+	// Nothing may start internal goroutines once the transceivers are
+	// stopped, or Close would wait for streams that are never closed. The
+	// signaling calls in flight finish and the next ones fail, goInternal
+	// refuses new goroutines, and the running startRTP calls finish.
+	pc.chainOps.Done()
+	pc.goroutinesMu.Lock()
+	pc.goroutinesStopped = true
+	pc.goroutinesMu.Unlock()
+	pc.rtpStarts.Wait()
+
 	// Try closing everything and collect the errors
 	// Shutdown strategy:
 	// 1. All Conn close by closing their underlying Conn.
@@ -1772,15 +2177,36 @@ func (pc *PeerConnection) Close() error {
 	//    Conn if one of the endpoints is closed down. To
 	//    continue the chain the Mux has to be closed.
 	closeErrs := make([]error, 4)
+	transportClosed := func(transport ClosedTransport, started time.Time, err error) {
+		closeErrs = append(closeErrs, err)
+		if options.OnTransportClosed != nil {
+			options.OnTransportClosed(TransportClosedEvent{
+				Transport: transport,
+				Started:   started,
+				Duration:  time.Since(started),
+				Err:       err,
+			})
+		}
+	}
 
 	// https://www.w3.org/TR/webrtc/#dom-rtcpeerconnection-close (step #4)
+	started := time.Now()
+	var rtpErrs []error
+	if options.SendBye {
+		rtpErrs = append(rtpErrs, pc.sendBye())
+	}
 	for _, t := range pc.GetTransceivers() {
 		if !t.stopped {
-			closeErrs = append(closeErrs, t.Stop())
+			rtpErrs = append(rtpErrs, t.Stop())
 		}
 	}
+	transportClosed(ClosedTransportRTP, started, util.FlattenErrs(rtpErrs))
 
 	// https://www.w3.org/TR/webrtc/#dom-rtcpeerconnection-close (step #5)
+	started = time.Now()
+	if options.Linger > 0 {
+		pc.lingerDataChannels(options.Linger)
+	}
 	pc.sctpTransport.lock.Lock()
 	for _, d := range pc.sctpTransport.dataChannels {
 		d.setReadyState(DataChannelStateClosed)
@@ -1789,33 +2215,80 @@ func (pc *PeerConnection) Close() error {
 
 	// https://www.w3.org/TR/webrtc/#dom-rtcpeerconnection-close (step #6)
 	if pc.sctpTransport != nil {
-		closeErrs = append(closeErrs, pc.sctpTransport.Stop())
+		transportClosed(ClosedTransportSCTP, started, pc.sctpTransport.Stop())
 	}
 
 	// https://www.w3.org/TR/webrtc/#dom-rtcpeerconnection-close (step #7)
-	closeErrs = append(closeErrs, pc.dtlsTransport.Stop())
+	started = time.Now()
+	transportClosed(ClosedTransportDTLS, started, pc.dtlsTransport.Stop())
 
 	// https://www.w3.org/TR/webrtc/#dom-rtcpeerconnection-close (step #8, #9, #10)
 	if pc.iceTransport != nil {
-		closeErrs = append(closeErrs, pc.iceTransport.Stop())
+		started = time.Now()
+		transportClosed(ClosedTransportICE, started, pc.iceTransport.Stop())
 	}
 
 	// https://www.w3.org/TR/webrtc/#dom-rtcpeerconnection-close (step #11)
 	pc.updateConnectionState(pc.ICEConnectionState(), pc.dtlsTransport.State())
 
+	// The goroutines exit as the transports are closed
+	pc.goroutines.Wait()
+
+	pc.api.removePeerConnection(pc)
+	close(pc.done)
+
 	return util.FlattenErrs(closeErrs)
 }
 
-// NewTrack Creates a new Track
-func (pc *PeerConnection) NewTrack(payloadType uint8, ssrc uint32, id, label string) (*Track, error) {
-	codec, err := pc.api.mediaEngine.getCodec(payloadType)
-	if err != nil {
-		return nil, err
-	} else if codec.Payloader == nil {
-		return nil, errPeerConnCodecPayloaderNotSet
+// Done returns a channel that is closed when Close returns, once the
+// internal goroutines of the PeerConnection have exited
+func (pc *PeerConnection) Done() <-chan struct{} {
+	return pc.done
+}
+
+// getMediaEngine returns the MediaEngine of pc, with the payload types
+// negotiated with the remote
+func (pc *PeerConnection) getMediaEngine() *MediaEngine {
+	pc.mediaEngineMu.RLock()
+	defer pc.mediaEngineMu.RUnlock()
+	return pc.mediaEngine
+}
+
+// negotiatedPayloadType returns the payload type of the codec of track
+// negotiated by pc, or the one of track if pc doesn't have its codec
+func (pc *PeerConnection) negotiatedPayloadType(track *Track) uint8 {
+	if codec := track.Codec(); codec != nil {
+		for _, negotiated := range pc.getMediaEngine().codecs {
+			if negotiated.matches(codec) {
+				return negotiated.PayloadType
+			}
+		}
+	}
+	return track.PayloadType()
+}
+
+// goInternal runs f on a goroutine that Close waits for, it isn't run and
+// false is returned if the PeerConnection is closed
+func (pc *PeerConnection) goInternal(f func()) bool {
+	pc.goroutinesMu.Lock()
+	defer pc.goroutinesMu.Unlock()
+	if pc.goroutinesStopped {
+		return false
 	}
 
-	return NewTrack(payloadType, ssrc, id, label, codec)
+	pc.goroutines.Add(1)
+	atomic.AddInt32(&pc.goroutineCount, 1)
+	go pprof.Do(context.Background(), pc.pprofLabels(), func(context.Context) {
+		defer pc.goroutines.Done()
+		defer atomic.AddInt32(&pc.goroutineCount, -1)
+		f()
+	})
+	return true
+}
+
+// NewTrack Creates a new Track
+func (pc *PeerConnection) NewTrack(payloadType uint8, ssrc uint32, id, label string) (*Track, error) {
+	return pc.getMediaEngine().newTrack(payloadType, ssrc, id, label)
 }
 
 func (pc *PeerConnection) newRTPTransceiver(
@@ -1824,10 +2297,11 @@ func (pc *PeerConnection) newRTPTransceiver(
 	direction RTPTransceiverDirection,
 	kind RTPCodecType,
 ) *RTPTransceiver {
-	t := &RTPTransceiver{kind: kind}
+	t := &RTPTransceiver{kind: kind, onNegotiationNeeded: pc.onNegotiationNeeded}
 	t.setReceiver(receiver)
 	t.setSender(sender)
 	t.setDirection(direction)
+	t.updateSending()
 
 	pc.mu.Lock()
 	pc.rtpTransceivers = append(pc.rtpTransceivers, t)
@@ -1861,7 +2335,9 @@ func (pc *PeerConnection) PendingLocalDescription() *SessionDescription {
 // into the stable state plus any remote candidates that have been supplied
 // via AddICECandidate() since the offer or answer was created.
 func (pc *PeerConnection) CurrentRemoteDescription() *SessionDescription {
-	return pc.currentRemoteDescription
+	pc.mu.RLock()
+	defer pc.mu.RUnlock()
+	return populateRemoteCandidates(pc.currentRemoteDescription, pc.remoteCandidates)
 }
 
 // PendingRemoteDescription represents a remote description that is in the
@@ -1870,7 +2346,9 @@ func (pc *PeerConnection) CurrentRemoteDescription() *SessionDescription {
 // created. If the PeerConnection is in the stable state, the value is
 // null.
 func (pc *PeerConnection) PendingRemoteDescription() *SessionDescription {
-	return pc.pendingRemoteDescription
+	pc.mu.RLock()
+	defer pc.mu.RUnlock()
+	return populateRemoteCandidates(pc.pendingRemoteDescription, pc.remoteCandidates)
 }
 
 // SignalingState attribute returns the signaling state of the
@@ -1905,6 +2383,97 @@ func (pc *PeerConnection) ConnectionState() PeerConnectionState {
 	return pc.connectionState
 }
 
+// GetConnectionError returns why the PeerConnection failed, which is set
+// before the OnConnectionStateChange handler is called with
+// PeerConnectionStateFailed. The error is ErrICEConnectionFailed when ICE
+// found no working candidate pair, like behind a firewall, and wraps
+// ErrDTLSTransportFailed when the DTLS handshake failed, like on a timeout
+// or a certificate mismatch. It wraps ErrSCTPAssociationFailed when the SCTP
+// association of the DataChannels failed, which doesn't fail the
+// PeerConnection. It is nil if nothing failed since the last connection.
+func (pc *PeerConnection) GetConnectionError() error {
+	pc.mu.RLock()
+	connectionError := pc.connectionError
+	pc.mu.RUnlock()
+
+	if connectionError != nil {
+		return connectionError
+	}
+	if err := pc.sctpTransport.associationError(); err != nil {
+		return fmt.Errorf("%w: %v", ErrSCTPAssociationFailed, err)
+	}
+	return nil
+}
+
+// setConnectionError records why the PeerConnection failed
+func (pc *PeerConnection) setConnectionError(err error) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	pc.connectionError = err
+}
+
+// ConnectContext waits until the PeerConnection is connected, with SCTP
+// started too when data channels were negotiated. If ctx is done first, or
+// the connection fails, the PeerConnection is closed and the error returned,
+// the one of GetConnectionError for a failure.
+// This bounds the whole establishment, for example with a
+// context.WithTimeout, instead of timing the state changes.
+func (pc *PeerConnection) ConnectContext(ctx context.Context) error {
+	for {
+		pc.mu.RLock()
+		connectionState, stateChanged := pc.connectionState, pc.stateChanged
+		pc.mu.RUnlock()
+
+		switch connectionState {
+		case PeerConnectionStateConnected:
+			if !pc.awaitingSCTP() {
+				return nil
+			}
+		case PeerConnectionStateFailed:
+			connectErr := pc.GetConnectionError()
+			if connectErr == nil {
+				connectErr = errPeerConnConnectFailed
+			}
+			if err := pc.Close(); err != nil {
+				return util.FlattenErrs([]error{connectErr, err})
+			}
+			return connectErr
+		case PeerConnectionStateClosed:
+			return &rtcerr.InvalidStateError{Err: ErrConnectionClosed}
+		}
+
+		select {
+		case <-stateChanged:
+		case <-pc.done:
+		case <-ctx.Done():
+			if err := pc.Close(); err != nil {
+				return util.FlattenErrs([]error{ctx.Err(), err})
+			}
+			return ctx.Err()
+		}
+	}
+}
+
+// awaitingSCTP returns true if data channels were negotiated over SCTP and
+// it isn't connected yet
+func (pc *PeerConnection) awaitingSCTP() bool {
+	remoteDescription := pc.remoteDescription()
+	if remoteDescription == nil || !haveApplicationMediaSection(remoteDescription.parsed) {
+		return false
+	}
+	if pc.api.settingEngine.dtlsDataChannel && haveDTLSDataChannel(remoteDescription.parsed) {
+		return false
+	}
+	return pc.sctpTransport.State() != SCTPTransportStateConnected
+}
+
+// signalStateChanged wakes up the callers of ConnectContext, pc.mu must be
+// held
+func (pc *PeerConnection) signalStateChanged() {
+	close(pc.stateChanged)
+	pc.stateChanged = make(chan struct{})
+}
+
 // GetStats return data providing statistics about the overall connection
 func (pc *PeerConnection) GetStats() StatsReport {
 	var (
@@ -1941,6 +2510,15 @@ func (pc *PeerConnection) GetStats() StatsReport {
 	}
 	pc.sctpTransport.collectStats(statsCollector)
 
+	for _, t := range pc.rtpTransceivers {
+		if receiver := t.Receiver(); receiver != nil {
+			receiver.collectStats(statsCollector)
+		}
+		if sender := t.Sender(); sender != nil {
+			sender.collectStats(statsCollector)
+		}
+	}
+
 	stats := PeerConnectionStats{
 		Timestamp:             statsTimestampNow(),
 		Type:                  StatsTypePeerConnection,
@@ -1961,7 +2539,7 @@ func (pc *PeerConnection) GetStats() StatsReport {
 	}
 	pc.mu.Unlock()
 
-	pc.api.mediaEngine.collectStats(statsCollector)
+	pc.getMediaEngine().collectStats(statsCollector)
 
 	return statsCollector.Ready()
 }
@@ -1988,6 +2566,9 @@ func (pc *PeerConnection) startTransports(iceRole ICERole, dtlsRole DTLSRole, re
 		Role:         dtlsRole,
 		Fingerprints: []DTLSFingerprint{{Algorithm: fingerprintHash, Value: fingerprint}},
 	})
+	if err != nil && pc.dtlsTransport.State() == DTLSTransportStateFailed {
+		pc.setConnectionError(fmt.Errorf("%w: %v", ErrDTLSTransportFailed, err))
+	}
 	pc.updateConnectionState(pc.ICEConnectionState(), pc.dtlsTransport.State())
 	if err != nil {
 		pc.log.Warnf("Failed to start manager: %s", err)
@@ -1996,6 +2577,14 @@ func (pc *PeerConnection) startTransports(iceRole ICERole, dtlsRole DTLSRole, re
 }
 
 func (pc *PeerConnection) startRTP(isRenegotiation bool, remoteDesc *SessionDescription, currentTransceivers []*RTPTransceiver) {
+	pc.goroutinesMu.Lock()
+	if pc.goroutinesStopped {
+		pc.goroutinesMu.Unlock()
+		return
+	}
+	pc.rtpStarts.Add(1)
+	pc.goroutinesMu.Unlock()
+
 	trackDetails := trackDetailsFromSDP(pc.log, remoteDesc.parsed)
 	if isRenegotiation {
 		for _, t := range currentTransceivers {
@@ -2009,6 +2598,7 @@ func (pc *PeerConnection) startRTP(isRenegotiation bool, remoteDesc *SessionDesc
 			if details := trackDetailsForSSRC(trackDetails, ssrc); details != nil {
 				t.Receiver().Track().id = details.id
 				t.Receiver().Track().label = details.label
+				t.Receiver().Track().streamIDs = details.streamIDs
 				t.Receiver().Track().mu.Unlock()
 				continue
 			}
@@ -2029,10 +2619,24 @@ func (pc *PeerConnection) startRTP(isRenegotiation bool, remoteDesc *SessionDesc
 		}
 	}
 
+	remoteSSRCs := []uint32{}
+	for _, details := range trackDetails {
+		remoteSSRCs = append(remoteSSRCs, details.ssrc)
+	}
+	pc.resolveSSRCCollisions(remoteSSRCs, currentTransceivers)
+
 	pc.startRTPReceivers(trackDetails, currentTransceivers)
 	pc.startRTPSenders(currentTransceivers)
+
+	// Starting SCTP blocks until the association is established, or Close
+	// stops it
+	pc.rtpStarts.Done()
 	if haveApplicationMediaSection(remoteDesc.parsed) {
-		pc.startSCTP()
+		if pc.api.settingEngine.dtlsDataChannel && haveDTLSDataChannel(remoteDesc.parsed) {
+			pc.startDTLSDataChannel()
+		} else {
+			pc.startSCTP()
+		}
 	}
 
 	if !isRenegotiation {
@@ -2042,7 +2646,7 @@ func (pc *PeerConnection) startRTP(isRenegotiation bool, remoteDesc *SessionDesc
 
 // GetRegisteredRTPCodecs gets a list of registered RTPCodec from the underlying constructed MediaEngine
 func (pc *PeerConnection) GetRegisteredRTPCodecs(kind RTPCodecType) []*RTPCodec {
-	return pc.api.mediaEngine.GetCodecsByKind(kind)
+	return pc.getMediaEngine().GetCodecsByKind(kind)
 }
 
 // generateUnmatchedSDP generates an SDP that doesn't take remote state into account
@@ -2088,7 +2692,7 @@ func (pc *PeerConnection) generateUnmatchedSDP(transceivers []*RTPTransceiver, u
 			mediaSections = append(mediaSections, mediaSection{id: "audio", transceivers: audio})
 		}
 
-		if pc.sctpTransport.dataChannelsRequested != 0 {
+		if pc.sctpTransport.dataChannelsRequested != 0 || pc.api.settingEngine.dtlsDataChannel {
 			mediaSections = append(mediaSections, mediaSection{id: "data", data: true})
 		}
 	} else {
@@ -2099,7 +2703,7 @@ func (pc *PeerConnection) generateUnmatchedSDP(transceivers []*RTPTransceiver, u
 			mediaSections = append(mediaSections, mediaSection{id: t.Mid(), transceivers: []*RTPTransceiver{t}})
 		}
 
-		if pc.sctpTransport.dataChannelsRequested != 0 {
+		if pc.sctpTransport.dataChannelsRequested != 0 || pc.api.settingEngine.dtlsDataChannel {
 			mediaSections = append(mediaSections, mediaSection{id: strconv.Itoa(len(mediaSections)), data: true})
 		}
 	}
@@ -2109,7 +2713,16 @@ func (pc *PeerConnection) generateUnmatchedSDP(transceivers []*RTPTransceiver, u
 		return nil, err
 	}
 
-	return populateSDP(d, isPlanB, dtlsFingerprints, pc.api.settingEngine.sdpMediaLevelFingerprints, pc.api.settingEngine.candidates.ICELite, pc.api.mediaEngine, connectionRoleFromDtlsRole(defaultDtlsRoleOffer), candidates, iceParams, mediaSections, pc.ICEGatheringState(), pc.api.settingEngine.getSDPExtensions())
+	d, err = populateSDP(d, isPlanB, dtlsFingerprints, pc.api.settingEngine.sdpMediaLevelFingerprints, pc.api.settingEngine.candidates.ICELite, pc.getMediaEngine(), connectionRoleFromDtlsRole(defaultDtlsRoleOffer), candidates, iceParams, mediaSections, pc.ICEGatheringState(), pc.api.settingEngine.getSDPExtensions())
+	if err != nil {
+		return nil, err
+	}
+
+	if pc.api.settingEngine.dtlsDataChannel {
+		addDTLSDataChannelAttribute(d)
+	}
+
+	return d, nil
 }
 
 // generateMatchedSDP generates a SDP and takes the remote state into account
@@ -2133,11 +2746,29 @@ func (pc *PeerConnection) generateMatchedSDP(transceivers []*RTPTransceiver, use
 
 	var t *RTPTransceiver
 	localTransceivers := append([]*RTPTransceiver{}, transceivers...)
-	detectedPlanB := descriptionIsPlanB(pc.RemoteDescription())
+	detectedPlanB := descriptionIsPlanB(pc.remoteDescription())
 	mediaSections := []mediaSection{}
 	alreadyHaveApplicationMediaSection := false
 
-	for _, media := range pc.RemoteDescription().parsed.MediaDescriptions {
+	// All media sections share one transport, so an answer rejects the
+	// ones the offer doesn't bundle. Without BUNDLE, only the first one is
+	// answered with BundlePolicyMaxBundle.
+	bundled, haveBundle := bundledMids(pc.remoteDescription().parsed)
+	if !includeUnmatched && !haveBundle && len(pc.remoteDescription().parsed.MediaDescriptions) > 1 && pc.configuration.BundlePolicy != BundlePolicyMaxBundle {
+		pc.log.Warn("Remote offer doesn't use BUNDLE, all media sections are answered on one transport")
+	}
+	isRejected := func(i int, midValue string) bool {
+		switch {
+		case includeUnmatched:
+			return false
+		case haveBundle:
+			return !bundled[midValue]
+		default:
+			return i != 0 && pc.configuration.BundlePolicy == BundlePolicyMaxBundle
+		}
+	}
+
+	for i, media := range pc.remoteDescription().parsed.MediaDescriptions {
 		midValue := getMidValue(media)
 		if midValue == "" {
 			return nil, errPeerConnRemoteDescriptionWithoutMidValue
@@ -2190,11 +2821,25 @@ func (pc *PeerConnection) generateMatchedSDP(transceivers []*RTPTransceiver, use
 			if t == nil {
 				return nil, fmt.Errorf("%w: %q", errPeerConnTranscieverMidNil, midValue)
 			}
+			if isRejected(i, midValue) {
+				mediaSections = append(mediaSections, mediaSection{id: midValue, transceivers: []*RTPTransceiver{t}, rejected: true})
+				continue
+			}
 			if t.Sender() != nil {
 				t.Sender().setNegotiated()
 			}
+			// An answer only sends and receives what the offer allows
+			sectionDirection := RTPTransceiverDirection(Unknown)
+			if !includeUnmatched {
+				sectionDirection = answerDirection(t.Direction(), direction)
+			}
 			mediaTransceivers := []*RTPTransceiver{t}
-			mediaSections = append(mediaSections, mediaSection{id: midValue, transceivers: mediaTransceivers, ridMap: getRids(media)})
+			mediaSections = append(mediaSections, mediaSection{
+				id:           midValue,
+				transceivers: mediaTransceivers,
+				ridMap:       getRids(media),
+				direction:    sectionDirection,
+			})
 		}
 	}
 
@@ -2209,7 +2854,7 @@ func (pc *PeerConnection) generateMatchedSDP(transceivers []*RTPTransceiver, use
 			}
 		}
 
-		if pc.sctpTransport.dataChannelsRequested != 0 && !alreadyHaveApplicationMediaSection {
+		if (pc.sctpTransport.dataChannelsRequested != 0 || pc.api.settingEngine.dtlsDataChannel) && !alreadyHaveApplicationMediaSection {
 			if detectedPlanB {
 				mediaSections = append(mediaSections, mediaSection{id: "data", data: true})
 			} else {
@@ -2227,16 +2872,28 @@ func (pc *PeerConnection) generateMatchedSDP(transceivers []*RTPTransceiver, use
 		return nil, err
 	}
 
-	matchedSDPMap, err := matchedAnswerExt(pc.RemoteDescription().parsed, pc.api.settingEngine.getSDPExtensions())
+	matchedSDPMap, err := matchedAnswerExt(pc.remoteDescription().parsed, pc.api.settingEngine.getSDPExtensions())
+	if err != nil {
+		return nil, err
+	}
+
+	d, err = populateSDP(d, detectedPlanB, dtlsFingerprints, pc.api.settingEngine.sdpMediaLevelFingerprints, pc.api.settingEngine.candidates.ICELite, pc.getMediaEngine(), connectionRole, candidates, iceParams, mediaSections, pc.ICEGatheringState(), matchedSDPMap)
 	if err != nil {
 		return nil, err
 	}
 
-	return populateSDP(d, detectedPlanB, dtlsFingerprints, pc.api.settingEngine.sdpMediaLevelFingerprints, pc.api.settingEngine.candidates.ICELite, pc.api.mediaEngine, connectionRole, candidates, iceParams, mediaSections, pc.ICEGatheringState(), matchedSDPMap)
+	// Only answer with DTLSDataChannel if the remote offered it
+	if pc.api.settingEngine.dtlsDataChannel && (includeUnmatched || haveDTLSDataChannel(pc.remoteDescription().parsed)) {
+		addDTLSDataChannelAttribute(d)
+	}
+
+	return d, nil
 }
 
+// setGatherCompleteHandler adds a handler which is called once gathering is
+// complete, right away if it already is
 func (pc *PeerConnection) setGatherCompleteHandler(handler func()) {
-	pc.iceGatherer.onGatheringCompleteHandler.Store(handler)
+	pc.iceGatherer.onGatheringComplete(handler)
 }
 
 // SCTP returns the SCTPTransport for this PeerConnection
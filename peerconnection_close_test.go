@@ -6,6 +6,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/pion/dtls/v2"
+	"github.com/pion/randutil"
 	"github.com/pion/transport/test"
 	"github.com/stretchr/testify/assert"
 )
@@ -178,3 +180,77 @@ func TestPeerConnection_Close_DuringICE(t *testing.T) {
 		t.Error("pcOffer.Close() Timeout")
 	}
 }
+
+func TestPeerConnection_CloseWithOptions(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	pcOffer, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, randutil.NewMathRandomGenerator().Uint32(), "video", "pion")
+	assert.NoError(t, err)
+	_, err = pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+
+	// The BYE is sent once, pion/srtp drops about half of the SRTCP
+	// packets protected with AES-GCM as if they weren't encrypted
+	s := SettingEngine{}
+	s.SetSRTPProtectionProfiles(dtls.SRTP_AES128_CM_HMAC_SHA1_80)
+	m := MediaEngine{}
+	m.RegisterDefaultCodecs()
+	pcAnswer, err := NewAPI(WithMediaEngine(m), WithSettingEngine(s)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	const messageCount = 100
+	received := make(chan struct{}, messageCount)
+	pcAnswer.OnDataChannel(func(d *DataChannel) {
+		d.OnMessage(func(DataChannelMessage) {
+			received <- struct{}{}
+		})
+	})
+	onTrack := make(chan struct{})
+	onBye := make(chan struct{})
+	pcAnswer.OnTrack(func(track *Track, _ *RTPReceiver) {
+		track.OnEnded(func(reason TrackEndedReason) {
+			assert.Equal(t, TrackEndedReasonBye, reason)
+			close(onBye)
+		})
+		close(onTrack)
+	})
+
+	dc, err := pcOffer.CreateDataChannel("data", nil)
+	assert.NoError(t, err)
+	opened := make(chan struct{})
+	dc.OnOpen(func() {
+		close(opened)
+	})
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	sendVideoUntilDone(onTrack, t, []*Track{track})
+	<-opened
+
+	for i := 0; i < messageCount; i++ {
+		assert.NoError(t, dc.Send(make([]byte, 1000)))
+	}
+
+	closed := []ClosedTransport{}
+	assert.NoError(t, pcOffer.CloseWithOptions(CloseOptions{
+		Linger:  5 * time.Second,
+		SendBye: true,
+		OnTransportClosed: func(e TransportClosedEvent) {
+			assert.NoError(t, e.Err)
+			assert.False(t, e.Started.IsZero())
+			closed = append(closed, e.Transport)
+		},
+	}))
+	assert.Equal(t, []ClosedTransport{ClosedTransportRTP, ClosedTransportSCTP, ClosedTransportDTLS, ClosedTransportICE}, closed)
+
+	<-onBye
+	for i := 0; i < messageCount; i++ {
+		<-received
+	}
+	assert.NoError(t, pcAnswer.Close())
+}
@@ -9,12 +9,14 @@ import (
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/x509"
+	"errors"
 	"fmt"
 	"math/big"
 	"reflect"
 	"regexp"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -377,6 +379,84 @@ func TestPeerConnection_ShutdownNoDTLS(t *testing.T) {
 	assert.NoError(t, answerPC.Close())
 }
 
+func TestPeerConnection_Done(t *testing.T) {
+	lim := test.TimeOut(time.Second * 10)
+	defer lim.Stop()
+
+	pc, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	// An internal goroutine delays Close until it exits
+	release := make(chan struct{})
+	pc.goInternal(func() {
+		<-release
+	})
+
+	closed := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			closed <- pc.Close()
+		}()
+	}
+
+	select {
+	case <-pc.Done():
+		t.Fatal("Done before the internal goroutines exited")
+	case <-closed:
+		t.Fatal("Close returned before the internal goroutines exited")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	assert.NoError(t, <-closed)
+	assert.NoError(t, <-closed)
+	<-pc.Done()
+
+	// No internal goroutine starts once closed
+	pc.goInternal(func() {
+		t.Fatal("goroutine started after Close")
+	})
+}
+
+func TestPeerConnection_CloseInHandlers(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	for _, name := range []string{"OnDataChannel", "OnMessage"} {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			pcOffer, pcAnswer, err := newPair()
+			assert.NoError(t, err)
+
+			// Close doesn't wait for the handler that calls it
+			closed := make(chan error, 1)
+			pcAnswer.OnDataChannel(func(d *DataChannel) {
+				if name == "OnDataChannel" {
+					closed <- pcAnswer.Close()
+					return
+				}
+				d.OnMessage(func(DataChannelMessage) {
+					closed <- pcAnswer.Close()
+				})
+			})
+
+			d, err := pcOffer.CreateDataChannel("data", nil)
+			assert.NoError(t, err)
+			d.OnOpen(func() {
+				assert.NoError(t, d.SendText("close"))
+			})
+
+			assert.NoError(t, signalPair(pcOffer, pcAnswer))
+			assert.NoError(t, <-closed)
+			<-pcAnswer.Done()
+			assert.NoError(t, pcOffer.Close())
+		})
+	}
+}
+
 func TestPeerConnection_PropertyGetters(t *testing.T) {
 	pc := &PeerConnection{
 		currentLocalDescription:  &SessionDescription{},
@@ -789,6 +869,148 @@ func TestPeerConnectionTrickle(t *testing.T) {
 	assert.NoError(t, answerPC.Close())
 }
 
+func TestNegotiationNeededDebounced(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	pc, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	var fired uint32
+	pc.OnNegotiationNeeded(func() {
+		atomic.AddUint32(&fired, 1)
+	})
+
+	// A burst of changes fires negotiationneeded once the operations drain
+	senders := []*RTPSender{}
+	for i := 0; i < 10; i++ {
+		track, trackErr := pc.NewTrack(DefaultPayloadTypeVP8, uint32(i+1), "video", "pion")
+		assert.NoError(t, trackErr)
+
+		sender, addErr := pc.AddTrack(track)
+		assert.NoError(t, addErr)
+		senders = append(senders, sender)
+	}
+	for _, sender := range senders[:5] {
+		assert.NoError(t, pc.RemoveTrack(sender))
+	}
+	pc.ops.Done()
+	pc.ops.Done()
+	assert.Equal(t, uint32(1), atomic.LoadUint32(&fired))
+
+	assert.NoError(t, pc.Close())
+}
+
+func TestPeerConnection_AddICECandidateBeforeRemoteDescription(t *testing.T) {
+	report := test.CheckRoutines(t)
+	defer report()
+
+	offerPC, answerPC, err := newPair()
+	assert.NoError(t, err)
+
+	_, err = offerPC.CreateDataChannel("data", nil)
+	assert.NoError(t, err)
+	offer, err := offerPC.CreateOffer(nil)
+	assert.NoError(t, err)
+
+	// The candidate arrives before the offer, and is added with it
+	candidate := "candidate:1966762134 1 udp 2122260223 192.168.20.128 47298 typ host generation 0"
+	assert.NoError(t, answerPC.AddICECandidate(ICECandidateInit{Candidate: candidate}))
+	assert.Error(t, answerPC.AddICECandidate(ICECandidateInit{Candidate: "candidate:invalid"}))
+	assert.Nil(t, answerPC.RemoteDescription())
+
+	assert.NoError(t, answerPC.SetRemoteDescription(offer))
+	assert.Contains(t, answerPC.PendingRemoteDescription().SDP, "a="+candidate)
+
+	assert.NoError(t, offerPC.Close())
+	assert.NoError(t, answerPC.Close())
+}
+
+func TestPeerConnection_AddICECandidateUsernameFragment(t *testing.T) {
+	report := test.CheckRoutines(t)
+	defer report()
+
+	offerPC, answerPC, err := newPair()
+	assert.NoError(t, err)
+
+	_, err = offerPC.CreateDataChannel("data", nil)
+	assert.NoError(t, err)
+	offer, err := offerPC.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, answerPC.SetRemoteDescription(offer))
+
+	params, err := offerPC.iceGatherer.GetLocalParameters()
+	assert.NoError(t, err)
+	stale := "stale"
+
+	candidate := "candidate:1966762134 1 udp 2122260223 192.168.20.128 47298 typ host generation 0"
+	assert.NoError(t, answerPC.AddICECandidate(ICECandidateInit{Candidate: candidate, UsernameFragment: &params.UsernameFragment}))
+	assert.NoError(t, answerPC.AddICECandidate(ICECandidateInit{Candidate: candidate + " ufrag " + params.UsernameFragment}))
+
+	// A candidate of a previous ICE generation is rejected
+	err = answerPC.AddICECandidate(ICECandidateInit{Candidate: "candidate:1 1 udp 1 10.0.0.1 1 typ host", UsernameFragment: &stale})
+	assert.True(t, errors.Is(err, errPeerConnICECandidateUfragMismatch))
+	err = answerPC.AddICECandidate(ICECandidateInit{Candidate: "candidate:1 1 udp 1 10.0.0.1 1 typ host generation 0 ufrag stale"})
+	assert.True(t, errors.Is(err, errPeerConnICECandidateUfragMismatch))
+	assert.NotContains(t, answerPC.RemoteDescription().SDP, "10.0.0.1")
+
+	assert.NoError(t, offerPC.Close())
+	assert.NoError(t, answerPC.Close())
+}
+
+func TestPeerConnection_OperationsChain(t *testing.T) {
+	lim := test.TimeOut(time.Second * 10)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	offerPC, answerPC, err := newPair()
+	assert.NoError(t, err)
+
+	_, err = offerPC.CreateDataChannel("data", nil)
+	assert.NoError(t, err)
+	offer, err := offerPC.CreateOffer(nil)
+	assert.NoError(t, err)
+
+	// Hold the chain, the calls wait for it in the order they are made
+	held, release := make(chan struct{}), make(chan struct{})
+	answerPC.chainOps.Enqueue(func() {
+		close(held)
+		<-release
+	})
+	<-held
+
+	setRemoteErr := make(chan error)
+	go func() {
+		setRemoteErr <- answerPC.SetRemoteDescription(offer)
+	}()
+	for answerPC.chainOps.IsEmpty() {
+		time.Sleep(time.Millisecond)
+	}
+
+	// The candidate is added after the remote description is set
+	addCandidateErr := make(chan error)
+	go func() {
+		addCandidateErr <- answerPC.AddICECandidate(ICECandidateInit{Candidate: "candidate:1966762134 1 udp 2122260223 192.168.20.128 47298 typ host generation 0"})
+	}()
+	select {
+	case <-addCandidateErr:
+		assert.Fail(t, "AddICECandidate didn't wait for the operations chain")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	assert.NoError(t, <-setRemoteErr)
+	assert.NoError(t, <-addCandidateErr)
+
+	assert.NoError(t, offerPC.Close())
+	assert.NoError(t, answerPC.Close())
+}
+
 // Issue #1121, assert populateLocalCandidates doesn't mutate
 func TestPopulateLocalCandidates(t *testing.T) {
 	t.Run("PendingLocalDescription shouldn't add extra mutations", func(t *testing.T) {
@@ -829,6 +1051,39 @@ func TestPopulateLocalCandidates(t *testing.T) {
 	})
 }
 
+func TestPopulateRemoteCandidates(t *testing.T) {
+	offerPC, answerPC, err := newPair()
+	assert.NoError(t, err)
+
+	_, err = offerPC.CreateDataChannel("test-channel", nil)
+	assert.NoError(t, err)
+
+	offer, err := offerPC.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, answerPC.SetRemoteDescription(offer))
+	assert.NotContains(t, answerPC.PendingRemoteDescription().SDP, "a=candidate")
+
+	candidate := "candidate:1966762134 1 udp 2122260223 192.168.20.128 47298 typ host generation 0"
+	mid := "0"
+	assert.NoError(t, answerPC.AddICECandidate(ICECandidateInit{Candidate: candidate, SDPMid: &mid}))
+	assert.NoError(t, answerPC.AddICECandidate(ICECandidateInit{Candidate: candidate, SDPMid: &mid}))
+
+	pending := answerPC.PendingRemoteDescription()
+	assert.Equal(t, 1, strings.Count(pending.SDP, "a="+candidate))
+	assert.Equal(t, pending, answerPC.RemoteDescription())
+	assert.NotContains(t, answerPC.remoteDescription().SDP, "a=candidate")
+
+	// The candidates are kept once the offer is answered
+	answer, err := answerPC.CreateAnswer(nil)
+	assert.NoError(t, err)
+	assert.NoError(t, answerPC.SetLocalDescription(answer))
+	assert.Nil(t, answerPC.PendingRemoteDescription())
+	assert.Contains(t, answerPC.CurrentRemoteDescription().SDP, "a="+candidate)
+
+	assert.NoError(t, offerPC.Close())
+	assert.NoError(t, answerPC.Close())
+}
+
 // Assert that two agents that only generate mDNS candidates can connect
 func TestMulticastDNSCandidates(t *testing.T) {
 	lim := test.TimeOut(time.Second * 30)
@@ -1048,3 +1303,76 @@ func TestPeerConnection_MassiveTracks(t *testing.T) {
 	assert.NoError(t, offerPC.Close())
 	assert.NoError(t, answerPC.Close())
 }
+
+func TestPeerConnection_GetConnectionError(t *testing.T) {
+	pc, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	assert.NoError(t, pc.GetConnectionError())
+
+	// A failed SCTP association is reported without failing the connection
+	abortErr := errors.New("abort chunk received")
+	pc.sctpTransport.setAssociationError(abortErr)
+	err = pc.GetConnectionError()
+	assert.True(t, errors.Is(err, ErrSCTPAssociationFailed))
+	assert.Contains(t, err.Error(), abortErr.Error())
+
+	// The failure of the connection is reported first, until it connects
+	pc.setConnectionError(ErrICEConnectionFailed)
+	pc.updateConnectionState(ICEConnectionStateFailed, DTLSTransportStateNew)
+	assert.Equal(t, PeerConnectionStateFailed, pc.ConnectionState())
+	assert.Equal(t, ErrICEConnectionFailed, pc.GetConnectionError())
+
+	pc.sctpTransport.setAssociationError(nil)
+	pc.updateConnectionState(ICEConnectionStateConnected, DTLSTransportStateConnected)
+	assert.NoError(t, pc.GetConnectionError())
+
+	assert.NoError(t, pc.Close())
+}
+
+func TestPeerConnection_ConnectContext(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	t.Run("Connected", func(t *testing.T) {
+		pcOffer, pcAnswer, err := newPair()
+		assert.NoError(t, err)
+
+		_, err = pcOffer.CreateDataChannel("data", nil)
+		assert.NoError(t, err)
+		assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+		defer cancel()
+		assert.NoError(t, pcOffer.ConnectContext(ctx))
+		assert.NoError(t, pcAnswer.ConnectContext(ctx))
+
+		assert.Equal(t, PeerConnectionStateConnected, pcOffer.ConnectionState())
+		assert.Equal(t, SCTPTransportStateConnected, pcOffer.SCTP().State())
+		assert.Equal(t, SCTPTransportStateConnected, pcAnswer.SCTP().State())
+
+		closePairNow(t, pcOffer, pcAnswer)
+	})
+
+	t.Run("Canceled", func(t *testing.T) {
+		pc, err := NewPeerConnection(Configuration{})
+		assert.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+		defer cancel()
+		assert.Equal(t, context.DeadlineExceeded, pc.ConnectContext(ctx))
+
+		// The PeerConnection was closed
+		select {
+		case <-pc.Done():
+		default:
+			assert.Fail(t, "PeerConnection isn't closed after the context is done")
+		}
+		assert.Equal(t, PeerConnectionStateClosed, pc.ConnectionState())
+
+		var invalidStateErr *rtcerr.InvalidStateError
+		assert.True(t, errors.As(pc.ConnectContext(context.Background()), &invalidStateErr))
+	})
+}
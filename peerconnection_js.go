@@ -27,8 +27,8 @@ type PeerConnection struct {
 	onICECandidateHandler             *js.Func
 	onICEGatheringStateChangeHandler  *js.Func
 
-	// Used by GatheringCompletePromise
-	onGatherCompleteHandler func()
+	// Used by GatheringCompletePromise, each handler is called once
+	onGatherCompleteHandlers []func()
 
 	// A reference to the associated API state used by this connection
 	api *API
@@ -332,8 +332,11 @@ func (pc *PeerConnection) OnICECandidate(f func(candidate *ICECandidate)) {
 	}
 	onICECandidateHandler := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
 		candidate := valueToICECandidate(args[0].Get("candidate"))
-		if candidate == nil && pc.onGatherCompleteHandler != nil {
-			go pc.onGatherCompleteHandler()
+		if candidate == nil {
+			for _, handler := range pc.onGatherCompleteHandlers {
+				go handler()
+			}
+			pc.onGatherCompleteHandlers = nil
 		}
 
 		go f(candidate)
@@ -478,11 +481,13 @@ func (pc *PeerConnection) ConnectionState() PeerConnectionState {
 	return newPeerConnectionState(rawState)
 }
 
+// setGatherCompleteHandler adds a handler which is called once gathering is
+// complete
 func (pc *PeerConnection) setGatherCompleteHandler(handler func()) {
-	pc.onGatherCompleteHandler = handler
+	pc.onGatherCompleteHandlers = append(pc.onGatherCompleteHandlers, handler)
 
 	// If no onIceCandidate handler has been set provide an empty one
-	// otherwise our onGatherCompleteHandler will not be executed
+	// otherwise our onGatherCompleteHandlers will not be executed
 	if pc.onICECandidateHandler == nil {
 		pc.OnICECandidate(func(i *ICECandidate) {})
 	}
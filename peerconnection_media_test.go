@@ -5,6 +5,7 @@ package webrtc
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -26,6 +27,7 @@ import (
 var (
 	errIncomingTrackIDInvalid    = errors.New("incoming Track ID is invalid")
 	errIncomingTrackLabelInvalid = errors.New("incoming Track Label is invalid")
+	errIncomingTrackStreamIDs    = errors.New("incoming Track StreamIDs are invalid")
 	errNoTransceiverwithMid      = errors.New("no transceiver with mid")
 )
 
@@ -87,6 +89,11 @@ func TestPeerConnection_Media_Sample(t *testing.T) {
 			trackMetadataValid <- fmt.Errorf("%w: expected(%s) actual(%s)", errIncomingTrackLabelInvalid, expectedTrackLabel, track.Label())
 			return
 		}
+
+		if streamIDs := track.StreamIDs(); len(streamIDs) != 1 || streamIDs[0] != expectedTrackLabel {
+			trackMetadataValid <- fmt.Errorf("%w: expected(%s) actual(%v)", errIncomingTrackStreamIDs, expectedTrackLabel, streamIDs)
+			return
+		}
 		close(trackMetadataValid)
 
 		go func() {
@@ -802,6 +809,31 @@ func TestAddTransceiverAddTrack_Reuse(t *testing.T) {
 	assert.NoError(t, pc.Close())
 }
 
+func TestAddTrackContext(t *testing.T) {
+	lim := test.TimeOut(time.Second * 5)
+	defer lim.Stop()
+
+	pc, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	track, err := pc.NewTrack(DefaultPayloadTypeVP8, randutil.NewMathRandomGenerator().Uint32(), "foo", "bar")
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	sender, err := pc.AddTrackContext(ctx, track)
+	assert.NoError(t, err)
+	assert.Equal(t, sender, pc.GetTransceivers()[0].Sender())
+
+	// The Track is removed once the context is done
+	<-sender.stopCalled
+	assert.Equal(t, RTPTransceiverDirectionRecvonly, pc.GetTransceivers()[0].Direction())
+
+	_, err = pc.AddTrackContext(context.Background(), track)
+	assert.NoError(t, err)
+	assert.NoError(t, pc.Close())
+}
+
 func TestAddTransceiverAddTrack_NewRTPSender_Error(t *testing.T) {
 	mediaEngine := MediaEngine{}
 	mediaEngine.RegisterDefaultCodecs()
@@ -894,7 +926,7 @@ func TestAddTransceiverFromKind(t *testing.T) {
 	assert.NoError(t, pc.Close())
 }
 
-func TestAddTransceiverFromKindFailsSendOnly(t *testing.T) {
+func TestAddTransceiverFromKindSendOnly(t *testing.T) {
 	lim := test.TimeOut(time.Second * 30)
 	defer lim.Stop()
 
@@ -902,23 +934,28 @@ func TestAddTransceiverFromKindFailsSendOnly(t *testing.T) {
 	defer report()
 
 	pc, err := NewPeerConnection(Configuration{})
-	if err != nil {
-		t.Error(err.Error())
-	}
+	assert.NoError(t, err)
 
 	transceiver, err := pc.AddTransceiverFromKind(RTPCodecTypeVideo, RtpTransceiverInit{
 		Direction: RTPTransceiverDirectionSendonly,
 	})
+	assert.NoError(t, err)
+	assert.NotNil(t, transceiver.Sender())
+	assert.Nil(t, transceiver.Receiver())
 
-	if transceiver != nil {
-		t.Error("AddTransceiverFromKind shouldn't succeed with Direction RTPTransceiverDirectionSendonly")
-	}
+	offer, err := pc.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.True(t, offerMediaHasDirection(offer, RTPCodecTypeVideo, RTPTransceiverDirectionSendonly))
+
+	_, err = pc.AddTransceiverFromKind(RTPCodecTypeVideo, RtpTransceiverInit{
+		Direction: RTPTransceiverDirection(Unknown) + 100,
+	})
+	assert.Error(t, err)
 
-	assert.NotNil(t, err)
 	assert.NoError(t, pc.Close())
 }
 
-func TestAddTransceiverFromTrackFailsRecvOnly(t *testing.T) {
+func TestAddTransceiverFromTrackRecvOnly(t *testing.T) {
 	lim := test.TimeOut(time.Second * 30)
 	defer lim.Stop()
 
@@ -926,9 +963,7 @@ func TestAddTransceiverFromTrackFailsRecvOnly(t *testing.T) {
 	defer report()
 
 	pc, err := NewPeerConnection(Configuration{})
-	if err != nil {
-		t.Error(err.Error())
-	}
+	assert.NoError(t, err)
 
 	track, err := pc.NewTrack(
 		DefaultPayloadTypeH264,
@@ -936,19 +971,67 @@ func TestAddTransceiverFromTrackFailsRecvOnly(t *testing.T) {
 		"track-id",
 		"track-label",
 	)
-	if err != nil {
-		t.Error(err.Error())
-	}
+	assert.NoError(t, err)
 
+	// The Track is sent once the direction is changed
 	transceiver, err := pc.AddTransceiverFromTrack(track, RtpTransceiverInit{
 		Direction: RTPTransceiverDirectionRecvonly,
 	})
+	assert.NoError(t, err)
+	assert.Equal(t, track, transceiver.Sender().Track())
+	assert.NotNil(t, transceiver.Receiver())
+	assert.True(t, transceiver.Sender().paused.get())
 
-	if transceiver != nil {
-		t.Error("AddTransceiverFromTrack shouldn't succeed with Direction RTPTransceiverDirectionRecvonly")
-	}
+	offer, err := pc.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.True(t, offerMediaHasDirection(offer, RTPCodecTypeVideo, RTPTransceiverDirectionRecvonly))
+
+	assert.NoError(t, transceiver.SetDirection(RTPTransceiverDirectionSendrecv))
+	assert.False(t, transceiver.Sender().paused.get())
+
+	assert.NoError(t, pc.Close())
+}
+
+func TestAddTransceiverFromTrackInit(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	pc, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	track, err := pc.NewTrack(DefaultPayloadTypeVP8, 0xDEADBEEF, "track-id", "track-label")
+	assert.NoError(t, err)
+
+	// The Track is signaled in every stream, and sends the encoding given
+	transceiver, err := pc.AddTransceiverFromTrack(track, RtpTransceiverInit{
+		StreamIDs:     []string{"stream-a", "stream-b"},
+		SendEncodings: []RTPEncodingParameters{{Active: true, MaxBitrate: 500000}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, RTPTransceiverDirectionSendrecv, transceiver.Direction())
+	assert.Equal(t, uint64(500000), transceiver.Sender().MaxBitrate())
+	assert.False(t, transceiver.Sender().inactive.get())
+
+	offer, err := pc.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.Contains(t, offer.SDP, "a=msid:stream-a track-id\r\n")
+	assert.Contains(t, offer.SDP, "a=msid:stream-b track-id\r\n")
+	assert.NotContains(t, offer.SDP, "a=msid:track-label")
+
+	// Simulcast isn't sent, and the limits of the encoding are checked
+	_, err = pc.AddTransceiverFromTrack(track, RtpTransceiverInit{
+		SendEncodings: []RTPEncodingParameters{{RTPCodingParameters: RTPCodingParameters{RID: "a"}}, {RTPCodingParameters: RTPCodingParameters{RID: "b"}}},
+	})
+	assert.Error(t, err)
+	_, err = pc.AddTransceiverFromTrack(track, RtpTransceiverInit{
+		SendEncodings: []RTPEncodingParameters{{MaxFramerate: -1}},
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 1, len(pc.GetTransceivers()))
 
-	assert.NotNil(t, err)
 	assert.NoError(t, pc.Close())
 }
 
@@ -968,6 +1051,7 @@ a=fingerprint:sha-256 27:EF:25:BF:57:45:BC:1C:0D:36:42:FF:5E:93:71:D2:41:58:EA:4
 a=setup:actpass
 a=mid:0
 a=sendrecv
+a=rtcp-mux
 a=rtpmap:111 opus/48000/2
 a=fmtp:111 minptime=10;useinbandfec=1
 m=video 9 UDP/TLS/RTP/SAVPF 96
@@ -980,6 +1064,7 @@ a=fingerprint:sha-256 27:EF:25:BF:57:45:BC:1C:0D:36:42:FF:5E:93:71:D2:41:58:EA:4
 a=setup:actpass
 a=mid:1
 a=sendrecv
+a=rtcp-mux
 a=rtpmap:96 H264/90000
 a=fmtp:96 level-asymmetry-allowed=1;packetization-mode=1;profile-level-id=640c1f
 `
@@ -1030,6 +1115,77 @@ a=fmtp:96 level-asymmetry-allowed=1;packetization-mode=1;profile-level-id=640c1f
 	assert.NoError(t, pc.Close())
 }
 
+func TestBundlePolicyAnswer(t *testing.T) {
+	offerer, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	for _, kind := range []RTPCodecType{RTPCodecTypeAudio, RTPCodecTypeVideo} {
+		_, err = offerer.AddTransceiverFromKind(kind)
+		assert.NoError(t, err)
+	}
+	offer, err := offerer.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.Contains(t, offer.SDP, "a=group:BUNDLE 0 1\r\n")
+
+	for _, test := range []struct {
+		name   string
+		policy BundlePolicy
+		group  string
+		ports  []string
+		bundle string
+	}{
+		{"Offer bundles all", BundlePolicyMaxBundle, "a=group:BUNDLE 0 1\r\n", []string{"m=audio 9 ", "m=video 9 "}, "a=group:BUNDLE 0 1\r\n"},
+		{"Offer bundles one", BundlePolicyBalanced, "a=group:BUNDLE 1\r\n", []string{"m=audio 0 ", "m=video 9 "}, "a=group:BUNDLE 1\r\n"},
+		{"No BUNDLE max-bundle", BundlePolicyMaxBundle, "", []string{"m=audio 9 ", "m=video 0 "}, "a=group:BUNDLE 0\r\n"},
+		{"No BUNDLE balanced", BundlePolicyBalanced, "", []string{"m=audio 9 ", "m=video 9 "}, "a=group:BUNDLE 0 1\r\n"},
+	} {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			answerer, err := NewPeerConnection(Configuration{BundlePolicy: test.policy})
+			assert.NoError(t, err)
+
+			description := strings.Replace(offer.SDP, "a=group:BUNDLE 0 1\r\n", test.group, 1)
+			assert.NoError(t, answerer.SetRemoteDescription(SessionDescription{Type: SDPTypeOffer, SDP: description}))
+			answer, err := answerer.CreateAnswer(nil)
+			assert.NoError(t, err)
+
+			for _, port := range test.ports {
+				assert.Contains(t, answer.SDP, port)
+			}
+			assert.Contains(t, answer.SDP, test.bundle)
+			assert.NoError(t, answerer.Close())
+		})
+	}
+	assert.NoError(t, offerer.Close())
+}
+
+func TestRTCPMuxPolicy(t *testing.T) {
+	offerer, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	_, err = offerer.AddTransceiverFromKind(RTPCodecTypeVideo)
+	assert.NoError(t, err)
+	offer, err := offerer.CreateOffer(nil)
+	assert.NoError(t, err)
+	offer = SessionDescription{Type: SDPTypeOffer, SDP: strings.Replace(offer.SDP, "a=rtcp-mux\r\n", "", -1)}
+
+	answerer, err := NewPeerConnection(Configuration{RTCPMuxPolicy: RTCPMuxPolicyRequire})
+	assert.NoError(t, err)
+	err = answerer.SetRemoteDescription(offer)
+	assert.True(t, errors.Is(err, errPeerConnRTCPMuxRequired))
+	assert.NoError(t, answerer.Close())
+
+	// The default policy and RTCPMuxPolicyNegotiate accept the offer
+	for _, configuration := range []Configuration{{}, {RTCPMuxPolicy: RTCPMuxPolicyNegotiate}} {
+		answerer, err = NewPeerConnection(configuration)
+		assert.NoError(t, err)
+		assert.NoError(t, answerer.SetRemoteDescription(offer))
+		answer, answerErr := answerer.CreateAnswer(nil)
+		assert.NoError(t, answerErr)
+		assert.Contains(t, answer.SDP, "a=rtcp-mux\r\n")
+		assert.NoError(t, answerer.Close())
+	}
+	assert.NoError(t, offerer.Close())
+}
+
 func TestGetRegisteredRTPCodecs(t *testing.T) {
 	mediaEngine := MediaEngine{}
 	expectedCodec := NewRTPH264Codec(DefaultPayloadTypeH264, 90000)
@@ -1262,3 +1418,82 @@ func TestPeerConnection_Start_Right_Receiver(t *testing.T) {
 	assert.NoError(t, pcOffer.Close())
 	assert.NoError(t, pcAnswer.Close())
 }
+
+// Assert that the payload types of an offer are negotiated per
+// PeerConnection, without changing the MediaEngine they share
+func TestPeerConnection_NegotiatedPayloadTypes(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	offerEngine := MediaEngine{}
+	offerEngine.RegisterCodec(NewRTPVP8Codec(100, 90000))
+	offerEngine.RegisterCodec(NewRTPH264Codec(DefaultPayloadTypeVP8, 90000))
+	pcOffer, err := NewAPI(WithMediaEngine(offerEngine)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	answerEngine := MediaEngine{}
+	answerEngine.RegisterDefaultCodecs()
+	answerAPI := NewAPI(WithMediaEngine(answerEngine))
+	pcAnswer, err := answerAPI.NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	offerTrack, err := pcOffer.NewTrack(100, 5000, "video", "pion")
+	assert.NoError(t, err)
+	_, err = pcOffer.AddTrack(offerTrack)
+	assert.NoError(t, err)
+
+	offerTrackFired, answerTrackFired := make(chan struct{}), make(chan struct{})
+	pcAnswer.OnTrack(func(track *Track, _ *RTPReceiver) {
+		assert.Equal(t, uint8(100), track.PayloadType())
+		assert.Equal(t, VP8, track.Codec().Name)
+		close(offerTrackFired)
+	})
+	pcOffer.OnTrack(func(track *Track, _ *RTPReceiver) {
+		assert.Equal(t, uint8(100), track.PayloadType())
+		assert.Equal(t, VP8, track.Codec().Name)
+		close(answerTrackFired)
+	})
+
+	_, err = pcOffer.CreateDataChannel("initial_data_channel", nil)
+	assert.NoError(t, err)
+	offer, err := pcOffer.CreateOffer(nil)
+	assert.NoError(t, err)
+	offerGatheringComplete := GatheringCompletePromise(pcOffer)
+	assert.NoError(t, pcOffer.SetLocalDescription(offer))
+	<-offerGatheringComplete
+	assert.NoError(t, pcAnswer.SetRemoteDescription(*pcOffer.LocalDescription()))
+
+	// A Track created with the payload type of the MediaEngine is sent with
+	// the negotiated one
+	answerTrack, err := NewTrack(DefaultPayloadTypeVP8, 5001, "video", "pion", NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000))
+	assert.NoError(t, err)
+	_, err = pcAnswer.AddTrack(answerTrack)
+	assert.NoError(t, err)
+
+	answer, err := pcAnswer.CreateAnswer(nil)
+	assert.NoError(t, err)
+	assert.Contains(t, answer.SDP, "a=rtpmap:100 VP8/90000")
+	assert.NotContains(t, answer.SDP, "a=rtpmap:96 VP8/90000")
+	answerGatheringComplete := GatheringCompletePromise(pcAnswer)
+	assert.NoError(t, pcAnswer.SetLocalDescription(answer))
+	<-answerGatheringComplete
+	assert.NoError(t, pcOffer.SetRemoteDescription(*pcAnswer.LocalDescription()))
+
+	// The MediaEngine of the API is shared, and stays as it was
+	codec, err := answerAPI.getMediaEngine().getCodec(DefaultPayloadTypeVP8)
+	assert.NoError(t, err)
+	assert.Equal(t, VP8, codec.Name)
+
+	done := make(chan struct{})
+	go func() {
+		<-offerTrackFired
+		<-answerTrackFired
+		close(done)
+	}()
+	sendVideoUntilDone(done, t, []*Track{offerTrack, answerTrack})
+
+	closePairNow(t, pcOffer, pcAnswer)
+}
@@ -484,6 +484,62 @@ func TestPeerConnection_Renegotiation_RemoveTrack(t *testing.T) {
 	assert.NoError(t, pcAnswer.Close())
 }
 
+// TestPeerConnection_Renegotiation_Hold asserts that a call put on hold
+// with SetDirection stops sending on both sides, and resumes afterwards
+func TestPeerConnection_Renegotiation_Hold(t *testing.T) {
+	api := NewAPI()
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	api.mediaEngine.RegisterDefaultCodecs()
+	pcOffer, pcAnswer, err := api.newPair(Configuration{})
+	assert.NoError(t, err)
+
+	addTrack := func(pc *PeerConnection) *RTPSender {
+		track, trackErr := pc.NewTrack(DefaultPayloadTypeVP8, randutil.NewMathRandomGenerator().Uint32(), "video", "pion")
+		assert.NoError(t, trackErr)
+		sender, trackErr := pc.AddTrack(track)
+		assert.NoError(t, trackErr)
+		return sender
+	}
+	offerSender := addTrack(pcOffer)
+	answerSender := addTrack(pcAnswer)
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	transceiver := pcOffer.GetTransceivers()[0]
+	assert.Equal(t, RTPTransceiverDirectionSendrecv, transceiver.CurrentDirection())
+	assert.Equal(t, RTPTransceiverDirectionSendrecv, pcAnswer.GetTransceivers()[0].CurrentDirection())
+
+	assert.Equal(t, errRTPTransceiverSetDirectionUnknown, transceiver.SetDirection(RTPTransceiverDirection(Unknown)))
+
+	// Sending stops right away on hold, and on the remote once negotiated
+	assert.NoError(t, transceiver.SetDirection(RTPTransceiverDirectionInactive))
+	assert.True(t, offerSender.paused.get())
+	assert.False(t, answerSender.paused.get())
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	assert.Contains(t, pcAnswer.LocalDescription().SDP, "a=inactive")
+	assert.Equal(t, RTPTransceiverDirectionInactive, pcAnswer.GetTransceivers()[0].CurrentDirection())
+	assert.True(t, answerSender.paused.get())
+
+	// Both sides send again once resumed
+	assert.NoError(t, transceiver.SetDirection(RTPTransceiverDirectionSendrecv))
+	assert.True(t, offerSender.paused.get())
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	assert.False(t, offerSender.paused.get())
+	assert.False(t, answerSender.paused.get())
+
+	// Without a track the transceiver can only receive
+	assert.NoError(t, pcAnswer.RemoveTrack(answerSender))
+	assert.Equal(t, errRTPTransceiverSetDirectionNoSender, pcAnswer.GetTransceivers()[0].SetDirection(RTPTransceiverDirectionSendonly))
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
 func TestPeerConnection_RoleSwitch(t *testing.T) {
 	api := NewAPI()
 	lim := test.TimeOut(time.Second * 30)
@@ -959,6 +1015,43 @@ func TestNegotiationNeededStressOneSided(t *testing.T) {
 	assert.NoError(t, pcB.Close())
 }
 
+// TestPeerConnection_Renegotiation_Close asserts that Close returns while
+// renegotiations started by adding Tracks are still running
+func TestPeerConnection_Renegotiation_Close(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	for i := 0; i < 10; i++ {
+		pcA, pcB, err := newPair()
+		assert.NoError(t, err)
+
+		var wg sync.WaitGroup
+		pcA.OnNegotiationNeeded(func() {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				// Fails once the PeerConnections are closed
+				_ = signalPair(pcA, pcB)
+			}()
+		})
+
+		for j := 0; j < 20; j++ {
+			track, err := pcA.NewTrack(DefaultPayloadTypeVP8, randutil.NewMathRandomGenerator().Uint32(), "video", "pion")
+			assert.NoError(t, err)
+			_, err = pcA.AddTrack(track)
+			assert.NoError(t, err)
+			time.Sleep(time.Millisecond)
+		}
+
+		assert.NoError(t, pcA.Close())
+		assert.NoError(t, pcB.Close())
+		wg.Wait()
+	}
+}
+
 // TestPeerConnection_Renegotiation_DisableTrack asserts that if a remote track is set inactive
 // that locally it goes inactive as well
 func TestPeerConnection_Renegotiation_DisableTrack(t *testing.T) {
@@ -0,0 +1,117 @@
+// +build !js
+
+package webrtc
+
+import (
+	"runtime/pprof"
+	"sync"
+	"sync/atomic"
+)
+
+// PprofLabel is the pprof label of the goroutines of a PeerConnection, its
+// value is the ID of the PeerConnection. A goroutine profile can be filtered
+// with it to find what is left running by a PeerConnection.
+const PprofLabel = "peerconnection"
+
+// livePeerConnections are the PeerConnections of every API which Close
+// hasn't returned for yet
+var (
+	livePeerConnectionsMu sync.Mutex
+	livePeerConnections   = map[*PeerConnection]struct{}{}
+)
+
+// PeerConnectionResources are the resources held by a PeerConnection, to
+// diagnose the ones which aren't released
+type PeerConnectionResources struct {
+	// ID is the ID of the PeerConnection, the value of its PprofLabel
+	ID              string
+	ConnectionState PeerConnectionState
+	Closed          bool
+
+	// Goroutines are the internal goroutines of the PeerConnection which
+	// Close waits for. The goroutines of the transports aren't counted, they
+	// have the PprofLabel in goroutine profiles.
+	Goroutines int
+
+	// LocalCandidates are the gathered local ICE candidates, each of them
+	// keeps a socket open until the ICE agent is closed
+	LocalCandidates int
+
+	Transceivers   int
+	SendingTracks  int
+	ReceivedTracks int
+
+	// DataChannels are the DataChannels which aren't closed and
+	// BufferedAmount the bytes queued to be sent on them
+	DataChannels   int
+	BufferedAmount uint64
+}
+
+// LivePeerConnections returns the PeerConnections that were created and
+// which Close hasn't returned for yet. A PeerConnection which stays after
+// it is closed is stuck in Close.
+func LivePeerConnections() []*PeerConnection {
+	livePeerConnectionsMu.Lock()
+	defer livePeerConnectionsMu.Unlock()
+
+	peerConnections := make([]*PeerConnection, 0, len(livePeerConnections))
+	for pc := range livePeerConnections {
+		peerConnections = append(peerConnections, pc)
+	}
+	return peerConnections
+}
+
+// Resources returns the resources the PeerConnection holds now
+func (pc *PeerConnection) Resources() PeerConnectionResources {
+	resources := PeerConnectionResources{
+		ID:              pc.statsID,
+		ConnectionState: pc.ConnectionState(),
+		Closed:          pc.isClosed.get(),
+		Goroutines:      int(atomic.LoadInt32(&pc.goroutineCount)),
+		LocalCandidates: pc.iceGatherer.localCandidateCount(),
+	}
+
+	for _, t := range pc.GetTransceivers() {
+		resources.Transceivers++
+		if sender := t.Sender(); sender != nil && sender.Track() != nil {
+			resources.SendingTracks++
+		}
+		if receiver := t.Receiver(); receiver != nil {
+			for _, track := range receiver.Tracks() {
+				if track != nil {
+					resources.ReceivedTracks++
+				}
+			}
+		}
+	}
+
+	pc.sctpTransport.lock.RLock()
+	for _, d := range pc.sctpTransport.dataChannels {
+		if d.ReadyState() != DataChannelStateClosed {
+			resources.DataChannels++
+			resources.BufferedAmount += d.BufferedAmount()
+		}
+	}
+	pc.sctpTransport.lock.RUnlock()
+
+	return resources
+}
+
+// pprofLabels are the labels of the goroutines of the PeerConnection
+func (pc *PeerConnection) pprofLabels() pprof.LabelSet {
+	return pprof.Labels(PprofLabel, pc.statsID)
+}
+
+func addLivePeerConnection(pc *PeerConnection) {
+	livePeerConnectionsMu.Lock()
+	defer livePeerConnectionsMu.Unlock()
+
+	livePeerConnections[pc] = struct{}{}
+}
+
+func removeLivePeerConnection(pc *PeerConnection) {
+	livePeerConnectionsMu.Lock()
+	defer livePeerConnectionsMu.Unlock()
+
+	delete(livePeerConnections, pc)
+}
@@ -0,0 +1,56 @@
+// +build !js
+
+package webrtc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pion/transport/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPeerConnection_Resources(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	pcOffer, pcAnswer, err := newPair()
+	assert.NoError(t, err)
+	assert.Contains(t, LivePeerConnections(), pcOffer)
+	assert.Contains(t, LivePeerConnections(), pcAnswer)
+
+	track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, 1, "video", "pion")
+	assert.NoError(t, err)
+	_, err = pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	assert.NoError(t, pcOffer.ConnectContext(ctx))
+
+	resources := pcOffer.Resources()
+	assert.Equal(t, pcOffer.statsID, resources.ID)
+	assert.Equal(t, PeerConnectionStateConnected, resources.ConnectionState)
+	assert.False(t, resources.Closed)
+	assert.NotZero(t, resources.LocalCandidates)
+	assert.Equal(t, 1, resources.Transceivers)
+	assert.Equal(t, 1, resources.SendingTracks)
+	assert.Equal(t, 0, resources.ReceivedTracks)
+	assert.Equal(t, 1, resources.DataChannels)
+
+	closePairNow(t, pcOffer, pcAnswer)
+
+	// Nothing is left once Close returned
+	assert.NotContains(t, LivePeerConnections(), pcOffer)
+	assert.NotContains(t, LivePeerConnections(), pcAnswer)
+	resources = pcOffer.Resources()
+	assert.True(t, resources.Closed)
+	assert.Equal(t, 0, resources.Goroutines)
+	assert.Equal(t, 0, resources.LocalCandidates)
+	assert.Equal(t, 0, resources.DataChannels)
+}
@@ -0,0 +1,189 @@
+// Package colorspace implements the color-space RTP header extension of
+// libwebrtc. It carries the color space of a video frame, and optionally
+// its HDR metadata, so HDR video like BT.2020 PQ is decoded correctly by
+// browsers.
+//
+// The extension is negotiated by adding URI to the video extensions with
+// SettingEngine.AddSDPExtensions. With HDR metadata it is 28 bytes, which
+// requires the two byte header extension format of RFC 8285.
+package colorspace
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// URI of the color-space header extension
+const URI = "http://www.webrtc.org/experiments/rtp-hdrext/color-space"
+
+// Common values of the color space, as defined by ITU-T H.273
+const (
+	PrimariesBT709     = 1
+	PrimariesSMPTE170M = 6
+	PrimariesBT2020    = 9
+
+	TransferBT709     = 1
+	TransferSMPTE2084 = 16 // PQ
+	TransferHLG       = 18
+
+	MatrixBT709     = 1
+	MatrixSMPTE170M = 6
+	MatrixBT2020NCL = 9
+)
+
+// Range is the range of the color values
+type Range uint8
+
+// Ranges of the color values
+const (
+	RangeInvalid Range = iota
+	RangeLimited
+	RangeFull
+	RangeDerived
+)
+
+// ChromaSiting is the position of the chroma samples
+type ChromaSiting uint8
+
+// Positions of the chroma samples
+const (
+	ChromaSitingUnspecified ChromaSiting = iota
+	ChromaSitingCollocated
+	ChromaSitingHalf
+)
+
+const (
+	extensionSize            = 4
+	extensionSizeHDRMetadata = 28
+
+	chromaticityDenominator = 50000
+	luminanceMaxDenominator = 1
+	luminanceMinDenominator = 10000
+)
+
+var errExtensionSize = errors.New("color-space extension must be 4 or 28 bytes")
+
+// Extension is the payload of the color-space header extension
+type Extension struct {
+	Primaries              uint8
+	Transfer               uint8
+	Matrix                 uint8
+	Range                  Range
+	ChromaSitingHorizontal ChromaSiting
+	ChromaSitingVertical   ChromaSiting
+
+	// HDRMetadata is nil for video without HDR metadata
+	HDRMetadata *HDRMetadata
+}
+
+// Chromaticity is a point in the CIE 1931 color space
+type Chromaticity struct {
+	X, Y float64
+}
+
+// HDRMetadata is the mastering display color volume of SMPTE ST 2086 and the
+// content light levels of CTA-861.3
+type HDRMetadata struct {
+	PrimaryR   Chromaticity
+	PrimaryG   Chromaticity
+	PrimaryB   Chromaticity
+	WhitePoint Chromaticity
+
+	// LuminanceMax and LuminanceMin are in nits
+	LuminanceMax float64
+	LuminanceMin float64
+
+	// MaxContentLightLevel and MaxFrameAverageLightLevel are in nits
+	MaxContentLightLevel      uint16
+	MaxFrameAverageLightLevel uint16
+}
+
+// Marshal serializes the extension
+func (e Extension) Marshal() ([]byte, error) {
+	size := extensionSize
+	if e.HDRMetadata != nil {
+		size = extensionSizeHDRMetadata
+	}
+
+	buf := make([]byte, size)
+	buf[0] = e.Primaries
+	buf[1] = e.Transfer
+	buf[2] = e.Matrix
+	buf[3] = uint8(e.Range&0x03)<<4 | uint8(e.ChromaSitingHorizontal&0x03)<<2 | uint8(e.ChromaSitingVertical&0x03)
+	if e.HDRMetadata == nil {
+		return buf, nil
+	}
+
+	m := e.HDRMetadata
+	offset := extensionSize
+	for _, value := range []uint16{
+		fixedPoint(m.PrimaryR.X, chromaticityDenominator), fixedPoint(m.PrimaryR.Y, chromaticityDenominator),
+		fixedPoint(m.PrimaryG.X, chromaticityDenominator), fixedPoint(m.PrimaryG.Y, chromaticityDenominator),
+		fixedPoint(m.PrimaryB.X, chromaticityDenominator), fixedPoint(m.PrimaryB.Y, chromaticityDenominator),
+		fixedPoint(m.WhitePoint.X, chromaticityDenominator), fixedPoint(m.WhitePoint.Y, chromaticityDenominator),
+		fixedPoint(m.LuminanceMax, luminanceMaxDenominator),
+		fixedPoint(m.LuminanceMin, luminanceMinDenominator),
+		m.MaxContentLightLevel,
+		m.MaxFrameAverageLightLevel,
+	} {
+		binary.BigEndian.PutUint16(buf[offset:], value)
+		offset += 2
+	}
+	return buf, nil
+}
+
+// Unmarshal parses the extension, both with and without HDR metadata
+func (e *Extension) Unmarshal(rawData []byte) error {
+	if len(rawData) != extensionSize && len(rawData) != extensionSizeHDRMetadata {
+		return errExtensionSize
+	}
+
+	e.Primaries = rawData[0]
+	e.Transfer = rawData[1]
+	e.Matrix = rawData[2]
+	e.Range = Range(rawData[3] >> 4 & 0x03)
+	e.ChromaSitingHorizontal = ChromaSiting(rawData[3] >> 2 & 0x03)
+	e.ChromaSitingVertical = ChromaSiting(rawData[3] & 0x03)
+	e.HDRMetadata = nil
+	if len(rawData) == extensionSize {
+		return nil
+	}
+
+	values := make([]uint16, (extensionSizeHDRMetadata-extensionSize)/2)
+	for i := range values {
+		values[i] = binary.BigEndian.Uint16(rawData[extensionSize+2*i:])
+	}
+	chromaticity := func(i int) Chromaticity {
+		return Chromaticity{
+			X: float64(values[i]) / chromaticityDenominator,
+			Y: float64(values[i+1]) / chromaticityDenominator,
+		}
+	}
+
+	e.HDRMetadata = &HDRMetadata{
+		PrimaryR:                  chromaticity(0),
+		PrimaryG:                  chromaticity(2),
+		PrimaryB:                  chromaticity(4),
+		WhitePoint:                chromaticity(6),
+		LuminanceMax:              float64(values[8]) / luminanceMaxDenominator,
+		LuminanceMin:              float64(values[9]) / luminanceMinDenominator,
+		MaxContentLightLevel:      values[10],
+		MaxFrameAverageLightLevel: values[11],
+	}
+	return nil
+}
+
+// fixedPoint converts value to a multiple of 1/denominator, limited to the
+// range of the extension
+func fixedPoint(value float64, denominator float64) uint16 {
+	v := math.Round(value * denominator)
+	switch {
+	case v < 0:
+		return 0
+	case v > math.MaxUint16:
+		return math.MaxUint16
+	default:
+		return uint16(v)
+	}
+}
@@ -0,0 +1,68 @@
+package colorspace
+
+import (
+	"testing"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtension(t *testing.T) {
+	e := Extension{
+		Primaries:              PrimariesBT709,
+		Transfer:               TransferBT709,
+		Matrix:                 MatrixBT709,
+		Range:                  RangeLimited,
+		ChromaSitingHorizontal: ChromaSitingCollocated,
+		ChromaSitingVertical:   ChromaSitingHalf,
+	}
+	raw, err := e.Marshal()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x01, 0x01, 0x01, 0x16}, raw)
+
+	parsed := Extension{HDRMetadata: &HDRMetadata{}}
+	assert.NoError(t, parsed.Unmarshal(raw))
+	assert.Equal(t, e, parsed)
+
+	assert.Equal(t, errExtensionSize, parsed.Unmarshal(raw[1:]))
+}
+
+func TestExtensionHDRMetadata(t *testing.T) {
+	e := Extension{
+		Primaries: PrimariesBT2020,
+		Transfer:  TransferSMPTE2084,
+		Matrix:    MatrixBT2020NCL,
+		Range:     RangeFull,
+		HDRMetadata: &HDRMetadata{
+			PrimaryR:                  Chromaticity{0.708, 0.292},
+			PrimaryG:                  Chromaticity{0.17, 0.797},
+			PrimaryB:                  Chromaticity{0.131, 0.046},
+			WhitePoint:                Chromaticity{0.3127, 0.329},
+			LuminanceMax:              1000,
+			LuminanceMin:              0.005,
+			MaxContentLightLevel:      1000,
+			MaxFrameAverageLightLevel: 400,
+		},
+	}
+	raw, err := e.Marshal()
+	assert.NoError(t, err)
+	assert.Equal(t, 28, len(raw))
+	assert.Equal(t, []byte{0x09, 0x10, 0x09, 0x20, 0x8A, 0x48}, raw[:6])
+
+	// HDR metadata needs the two byte header extension format
+	packet := &rtp.Packet{Header: rtp.Header{Version: 2}}
+	assert.NoError(t, packet.SetExtension(3, raw))
+	marshaled, err := packet.Marshal()
+	assert.NoError(t, err)
+	assert.NoError(t, packet.Unmarshal(marshaled))
+
+	parsed := Extension{}
+	assert.NoError(t, parsed.Unmarshal(packet.GetExtension(3)))
+	assert.Equal(t, e, parsed)
+}
+
+func TestFixedPoint(t *testing.T) {
+	assert.Equal(t, uint16(0), fixedPoint(-1, 1))
+	assert.Equal(t, uint16(65535), fixedPoint(100000, 1))
+	assert.Equal(t, uint16(50), fixedPoint(0.005, 10000))
+}
@@ -0,0 +1,41 @@
+package interceptor
+
+import (
+	"testing"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+type appendInterceptor struct {
+	NoOp
+	value byte
+}
+
+func (a *appendInterceptor) BindLocalStream(_ *StreamInfo, writer RTPWriter) RTPWriter {
+	return RTPWriterFunc(func(header *rtp.Header, payload []byte) (int, error) {
+		return writer.Write(header, append(payload, a.value))
+	})
+}
+
+func TestRegistry_Build(t *testing.T) {
+	registry := Registry{}
+	_, isNoOp := registry.Build().(*NoOp)
+	assert.True(t, isNoOp)
+
+	registry.Add(&appendInterceptor{value: 1})
+	registry.Add(&appendInterceptor{value: 2})
+
+	var got []byte
+	writer := registry.Build().BindLocalStream(&StreamInfo{}, RTPWriterFunc(func(_ *rtp.Header, payload []byte) (int, error) {
+		got = payload
+		return len(payload), nil
+	}))
+
+	_, err := writer.Write(&rtp.Header{}, []byte{0})
+	assert.NoError(t, err)
+
+	// Interceptors added last wrap the writer last and so see the packet first
+	assert.Equal(t, []byte{0, 2, 1}, got)
+	assert.NoError(t, registry.Build().Close())
+}
@@ -0,0 +1,478 @@
+// Package impairment provides an Interceptor that simulates a lossy network by
+// dropping, duplicating, reordering and delaying RTP packets. It is meant for
+// validating retransmission and jitter buffer logic without external network
+// shaping tools, and should not be used in production.
+package impairment
+
+import (
+	"container/heap"
+	"errors"
+	"io"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3/pkg/interceptor"
+)
+
+var errInterceptorClosed = errors.New("impairment interceptor closed")
+
+const (
+	// defaultQueueSize is the number of received packets waiting for their
+	// delay to pass above which packets are dropped
+	defaultQueueSize = 1024
+
+	// minReadBufferSize is the smallest buffer packets are read with from the
+	// stream, whatever the size of the buffer of the first read
+	minReadBufferSize = 1500
+)
+
+// Direction selects which streams an Interceptor impairs
+type Direction int
+
+const (
+	// DirectionSendReceive impairs both outbound and inbound streams
+	DirectionSendReceive Direction = iota
+
+	// DirectionSend impairs only outbound streams
+	DirectionSend
+
+	// DirectionReceive impairs only inbound streams
+	DirectionReceive
+)
+
+// Interceptor drops, duplicates, reorders and delays RTP packets at configurable rates.
+type Interceptor struct {
+	interceptor.NoOp
+
+	direction     Direction
+	dropRate      float64
+	duplicateRate float64
+	reorderRate   float64
+	delay         time.Duration
+	jitter        time.Duration
+	queueSize     int
+
+	randMu sync.Mutex
+	rand   *rand.Rand
+
+	streamsMu     sync.Mutex
+	remoteStreams map[uint32]*remoteStream
+
+	wg        sync.WaitGroup
+	close     chan struct{}
+	closeOnce sync.Once
+}
+
+// An Option configures an Interceptor.
+type Option func(i *Interceptor)
+
+// WithDirection sets which streams are impaired. Defaults to DirectionSendReceive.
+func WithDirection(d Direction) Option {
+	return func(i *Interceptor) {
+		i.direction = d
+	}
+}
+
+// WithDropRate sets the probability (0.0 - 1.0) that a packet is discarded.
+func WithDropRate(rate float64) Option {
+	return func(i *Interceptor) {
+		i.dropRate = rate
+	}
+}
+
+// WithDuplicateRate sets the probability (0.0 - 1.0) that a packet is delivered twice.
+func WithDuplicateRate(rate float64) Option {
+	return func(i *Interceptor) {
+		i.duplicateRate = rate
+	}
+}
+
+// WithReorderRate sets the probability (0.0 - 1.0) that a packet is held back
+// and delivered after the packet that follows it.
+func WithReorderRate(rate float64) Option {
+	return func(i *Interceptor) {
+		i.reorderRate = rate
+	}
+}
+
+// WithDelay delays every packet by delay plus a uniformly distributed value in [0, jitter).
+// When jitter is larger than the packet interval packets will also arrive out of order.
+func WithDelay(delay, jitter time.Duration) Option {
+	return func(i *Interceptor) {
+		i.delay = delay
+		i.jitter = jitter
+	}
+}
+
+// WithQueueSize sets the number of received packets that can wait for their
+// delay to pass, packets received when it is reached are dropped. Defaults to 1024.
+func WithQueueSize(size int) Option {
+	return func(i *Interceptor) {
+		i.queueSize = size
+	}
+}
+
+// WithSeed sets the seed of the random source so runs can be reproduced.
+func WithSeed(seed int64) Option {
+	return func(i *Interceptor) {
+		i.rand = rand.New(rand.NewSource(seed)) // nolint:gosec
+	}
+}
+
+// New constructs a new impairment Interceptor
+func New(opts ...Option) *Interceptor {
+	i := &Interceptor{
+		queueSize:     defaultQueueSize,
+		remoteStreams: map[uint32]*remoteStream{},
+		close:         make(chan struct{}),
+	}
+	for _, o := range opts {
+		o(i)
+	}
+
+	if i.rand == nil {
+		i.rand = rand.New(rand.NewSource(time.Now().UnixNano())) // nolint:gosec
+	}
+
+	return i
+}
+
+// BindLocalStream impairs all packets written to the returned RTPWriter
+func (i *Interceptor) BindLocalStream(_ *interceptor.StreamInfo, writer interceptor.RTPWriter) interceptor.RTPWriter {
+	if i.direction == DirectionReceive {
+		return writer
+	}
+
+	s := &localStream{parent: i, next: writer}
+	return interceptor.RTPWriterFunc(s.write)
+}
+
+// BindRemoteStream impairs all packets read from the returned RTPReader
+func (i *Interceptor) BindRemoteStream(info *interceptor.StreamInfo, reader interceptor.RTPReader) interceptor.RTPReader {
+	if i.direction == DirectionSend {
+		return reader
+	}
+
+	s := &remoteStream{parent: i, next: reader, done: make(chan struct{})}
+
+	i.streamsMu.Lock()
+	i.remoteStreams[info.SSRC] = s
+	i.streamsMu.Unlock()
+
+	return interceptor.RTPReaderFunc(s.read)
+}
+
+// UnbindRemoteStream waits for the stream to stop being read. The stream is
+// closed before it is unbound, so the read fails.
+func (i *Interceptor) UnbindRemoteStream(info *interceptor.StreamInfo) {
+	i.streamsMu.Lock()
+	s, ok := i.remoteStreams[info.SSRC]
+	delete(i.remoteStreams, info.SSRC)
+	i.streamsMu.Unlock()
+
+	if ok {
+		s.wait()
+	}
+}
+
+// Close stops delivering delayed packets and waits for all pending deliveries
+// to return. The streams are read until their next read returns, they are
+// expected to be closed first.
+func (i *Interceptor) Close() error {
+	i.closeOnce.Do(func() {
+		close(i.close)
+	})
+	i.wg.Wait()
+
+	return nil
+}
+
+func (i *Interceptor) roll(rate float64) bool {
+	if rate <= 0 {
+		return false
+	}
+
+	i.randMu.Lock()
+	defer i.randMu.Unlock()
+	return i.rand.Float64() < rate
+}
+
+func (i *Interceptor) nextDelay() time.Duration {
+	if i.jitter <= 0 {
+		return i.delay
+	}
+
+	i.randMu.Lock()
+	defer i.randMu.Unlock()
+	return i.delay + time.Duration(i.rand.Int63n(int64(i.jitter)))
+}
+
+// wait blocks for d, it returns false if the Interceptor was closed while waiting
+func (i *Interceptor) wait(d time.Duration) bool {
+	if d <= 0 {
+		return true
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-i.close:
+		return false
+	}
+}
+
+type localStream struct {
+	parent *Interceptor
+	next   interceptor.RTPWriter
+
+	mu   sync.Mutex
+	held []*rtp.Packet
+}
+
+func (s *localStream) write(header *rtp.Header, payload []byte) (int, error) {
+	size := header.MarshalSize() + len(payload)
+	if s.parent.roll(s.parent.dropRate) {
+		return size, nil
+	}
+
+	pkt := copyPacket(header, payload)
+	out := []*rtp.Packet{pkt}
+	if s.parent.roll(s.parent.duplicateRate) {
+		out = append(out, pkt)
+	}
+
+	s.mu.Lock()
+	switch {
+	case s.held != nil:
+		out = append(out, s.held...)
+		s.held = nil
+	case s.parent.roll(s.parent.reorderRate):
+		s.held = out
+		out = nil
+	}
+	s.mu.Unlock()
+
+	for _, p := range out {
+		if err := s.send(p); err != nil {
+			return 0, err
+		}
+	}
+
+	return size, nil
+}
+
+func (s *localStream) send(p *rtp.Packet) error {
+	delay := s.parent.nextDelay()
+	if delay <= 0 {
+		_, err := s.next.Write(&p.Header, p.Payload)
+		return err
+	}
+
+	s.parent.wg.Add(1)
+	go func() {
+		defer s.parent.wg.Done()
+		if s.parent.wait(delay) {
+			_, _ = s.next.Write(&p.Header, p.Payload)
+		}
+	}()
+
+	return nil
+}
+
+// remoteStream reads the packets of the stream as they arrive, and releases
+// each once its delay has passed
+type remoteStream struct {
+	parent *Interceptor
+	next   interceptor.RTPReader
+
+	startOnce sync.Once
+	started   bool
+	released  chan struct{}
+	done      chan struct{}
+
+	mu       sync.Mutex
+	held     [][]byte
+	queue    releaseQueue
+	received int
+	err      error
+}
+
+func (s *remoteStream) read(b []byte) (int, error) {
+	s.startOnce.Do(func() {
+		s.released = make(chan struct{}, 1)
+
+		s.mu.Lock()
+		s.started = true
+		s.mu.Unlock()
+
+		s.parent.wg.Add(1)
+		go s.readLoop(len(b))
+	})
+
+	for {
+		s.mu.Lock()
+		var wait time.Duration
+		switch {
+		case len(s.queue) != 0:
+			if wait = time.Until(s.queue[0].releaseAt); wait <= 0 {
+				// The packet stays queued for a larger buffer
+				if len(b) < len(s.queue[0].data) {
+					s.mu.Unlock()
+					return 0, io.ErrShortBuffer
+				}
+				p := heap.Pop(&s.queue).(*queuedPacket)
+				s.mu.Unlock()
+				return copy(b, p.data), nil
+			}
+		case s.err != nil:
+			s.mu.Unlock()
+			return 0, s.err
+		}
+		s.mu.Unlock()
+
+		// Without a queued packet, wait until the next arrives
+		var timer *time.Timer
+		var timeout <-chan time.Time
+		if wait > 0 {
+			timer = time.NewTimer(wait)
+			timeout = timer.C
+		}
+
+		select {
+		case <-s.released:
+		case <-timeout:
+		case <-s.parent.close:
+			return 0, errInterceptorClosed
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+// readLoop impairs the packets read from the stream until it fails or the
+// Interceptor is closed, and queues them for release. The packet held for
+// reordering is discarded when the stream fails.
+func (s *remoteStream) readLoop(size int) {
+	defer s.parent.wg.Done()
+	defer close(s.done)
+
+	if size < minReadBufferSize {
+		size = minReadBufferSize
+	}
+	buf := make([]byte, size)
+	for {
+		n, err := s.next.Read(buf)
+		select {
+		case <-s.parent.close:
+			err = errInterceptorClosed
+		default:
+		}
+		if err != nil {
+			s.mu.Lock()
+			s.err = err
+			s.mu.Unlock()
+			s.signal()
+			return
+		}
+
+		if s.parent.roll(s.parent.dropRate) {
+			continue
+		}
+
+		pkt := append([]byte{}, buf[:n]...)
+		out := [][]byte{pkt}
+		if s.parent.roll(s.parent.duplicateRate) {
+			out = append(out, pkt)
+		}
+
+		s.mu.Lock()
+		switch {
+		case s.held != nil:
+			out = append(out, s.held...)
+			s.held = nil
+		case s.parent.roll(s.parent.reorderRate):
+			s.held = out
+			out = nil
+		}
+
+		now := time.Now()
+		for _, p := range out {
+			if len(s.queue) >= s.parent.queueSize {
+				continue
+			}
+			heap.Push(&s.queue, &queuedPacket{data: p, releaseAt: now.Add(s.parent.nextDelay()), index: s.received})
+			s.received++
+		}
+		s.mu.Unlock()
+		s.signal()
+	}
+}
+
+// wait blocks until the stream stops being read, if it was read
+func (s *remoteStream) wait() {
+	s.mu.Lock()
+	started := s.started
+	s.mu.Unlock()
+
+	if started {
+		<-s.done
+	}
+}
+
+// signal wakes up a read waiting for a packet
+func (s *remoteStream) signal() {
+	select {
+	case s.released <- struct{}{}:
+	default:
+	}
+}
+
+// queuedPacket is a packet waiting for its release time
+type queuedPacket struct {
+	data      []byte
+	releaseAt time.Time
+	index     int
+}
+
+// releaseQueue is a heap of packets ordered by release time, and then by
+// order of arrival
+type releaseQueue []*queuedPacket
+
+func (q releaseQueue) Len() int { return len(q) }
+
+func (q releaseQueue) Less(i, j int) bool {
+	if q[i].releaseAt.Equal(q[j].releaseAt) {
+		return q[i].index < q[j].index
+	}
+	return q[i].releaseAt.Before(q[j].releaseAt)
+}
+
+func (q releaseQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *releaseQueue) Push(x interface{}) {
+	*q = append(*q, x.(*queuedPacket))
+}
+
+func (q *releaseQueue) Pop() interface{} {
+	old := *q
+	p := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return p
+}
+
+func copyPacket(header *rtp.Header, payload []byte) *rtp.Packet {
+	p := &rtp.Packet{
+		Header:  *header,
+		Payload: append([]byte{}, payload...),
+	}
+	p.Header.CSRC = append([]uint32{}, header.CSRC...)
+	p.Header.Extensions = append([]rtp.Extension{}, header.Extensions...)
+
+	return p
+}
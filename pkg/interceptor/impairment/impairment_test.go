@@ -0,0 +1,245 @@
+package impairment
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3/pkg/interceptor"
+	"github.com/stretchr/testify/assert"
+)
+
+func bindRecorder(i *Interceptor) (interceptor.RTPWriter, chan uint16) {
+	written := make(chan uint16, 16)
+	writer := i.BindLocalStream(&interceptor.StreamInfo{SSRC: 1}, interceptor.RTPWriterFunc(func(header *rtp.Header, payload []byte) (int, error) {
+		written <- header.SequenceNumber
+		return len(payload), nil
+	}))
+
+	return writer, written
+}
+
+func writeSequence(t *testing.T, writer interceptor.RTPWriter, seqs ...uint16) {
+	for _, seq := range seqs {
+		_, err := writer.Write(&rtp.Header{SequenceNumber: seq}, []byte{0x00})
+		assert.NoError(t, err)
+	}
+}
+
+func drain(c chan uint16) (seqs []uint16) {
+	for {
+		select {
+		case seq := <-c:
+			seqs = append(seqs, seq)
+		default:
+			return
+		}
+	}
+}
+
+func TestImpairment_Send(t *testing.T) {
+	t.Run("Drop", func(t *testing.T) {
+		i := New(WithDropRate(1))
+		writer, written := bindRecorder(i)
+		writeSequence(t, writer, 1, 2, 3)
+		assert.Empty(t, drain(written))
+		assert.NoError(t, i.Close())
+	})
+
+	t.Run("Duplicate", func(t *testing.T) {
+		i := New(WithDuplicateRate(1))
+		writer, written := bindRecorder(i)
+		writeSequence(t, writer, 1, 2)
+		assert.Equal(t, []uint16{1, 1, 2, 2}, drain(written))
+		assert.NoError(t, i.Close())
+	})
+
+	t.Run("Reorder", func(t *testing.T) {
+		i := New(WithReorderRate(1))
+		writer, written := bindRecorder(i)
+		writeSequence(t, writer, 1, 2, 3, 4)
+		assert.Equal(t, []uint16{2, 1, 4, 3}, drain(written))
+		assert.NoError(t, i.Close())
+	})
+
+	t.Run("Duplicate and reorder", func(t *testing.T) {
+		i := New(WithDuplicateRate(1), WithReorderRate(1))
+		writer, written := bindRecorder(i)
+		writeSequence(t, writer, 1, 2, 3, 4)
+		assert.Equal(t, []uint16{2, 2, 1, 1, 4, 4, 3, 3}, drain(written))
+		assert.NoError(t, i.Close())
+	})
+
+	t.Run("Delay", func(t *testing.T) {
+		i := New(WithDelay(50*time.Millisecond, 0))
+		writer, written := bindRecorder(i)
+
+		start := time.Now()
+		writeSequence(t, writer, 1)
+		assert.Empty(t, drain(written))
+
+		assert.Equal(t, uint16(1), <-written)
+		assert.True(t, time.Since(start) >= 50*time.Millisecond)
+		assert.NoError(t, i.Close())
+	})
+
+	t.Run("Receive only", func(t *testing.T) {
+		i := New(WithDropRate(1), WithDirection(DirectionReceive))
+		writer, written := bindRecorder(i)
+		writeSequence(t, writer, 1, 2)
+		assert.Equal(t, []uint16{1, 2}, drain(written))
+		assert.NoError(t, i.Close())
+	})
+}
+
+func TestImpairment_Receive(t *testing.T) {
+	newReader := func(i *Interceptor, seqs ...uint16) interceptor.RTPReader {
+		return i.BindRemoteStream(&interceptor.StreamInfo{SSRC: 1}, interceptor.RTPReaderFunc(func(b []byte) (int, error) {
+			if len(seqs) == 0 {
+				return 0, io.EOF
+			}
+
+			raw, err := (&rtp.Packet{Header: rtp.Header{Version: 2, SequenceNumber: seqs[0]}}).Marshal()
+			if err != nil {
+				return 0, err
+			}
+			seqs = seqs[1:]
+			return copy(b, raw), nil
+		}))
+	}
+
+	readAll := func(t *testing.T, reader interceptor.RTPReader) (seqs []uint16) {
+		b := make([]byte, 1500)
+		for {
+			n, err := reader.Read(b)
+			if err == io.EOF {
+				return
+			}
+			assert.NoError(t, err)
+
+			p := &rtp.Packet{}
+			assert.NoError(t, p.Unmarshal(b[:n]))
+			seqs = append(seqs, p.SequenceNumber)
+		}
+	}
+
+	t.Run("Drop", func(t *testing.T) {
+		i := New(WithDropRate(1))
+		assert.Empty(t, readAll(t, newReader(i, 1, 2, 3)))
+	})
+
+	t.Run("Duplicate", func(t *testing.T) {
+		i := New(WithDuplicateRate(1))
+		assert.Equal(t, []uint16{1, 1, 2, 2}, readAll(t, newReader(i, 1, 2)))
+	})
+
+	t.Run("Reorder", func(t *testing.T) {
+		i := New(WithReorderRate(1))
+		assert.Equal(t, []uint16{2, 1, 4, 3}, readAll(t, newReader(i, 1, 2, 3, 4)))
+	})
+
+	t.Run("Duplicate and reorder", func(t *testing.T) {
+		i := New(WithDuplicateRate(1), WithReorderRate(1))
+		assert.Equal(t, []uint16{2, 2, 1, 1, 4, 4, 3, 3}, readAll(t, newReader(i, 1, 2, 3, 4)))
+	})
+
+	t.Run("Delay", func(t *testing.T) {
+		seqs := make([]uint16, 20)
+		for n := range seqs {
+			seqs[n] = uint16(n)
+		}
+
+		// Packets are delayed from their arrival, not one after the other
+		start := time.Now()
+		assert.Equal(t, seqs, readAll(t, newReader(New(WithDelay(50*time.Millisecond, 0)), seqs...)))
+		assert.True(t, time.Since(start) >= 50*time.Millisecond)
+		assert.True(t, time.Since(start) < 500*time.Millisecond)
+	})
+
+	t.Run("Close", func(t *testing.T) {
+		i := New(WithDelay(time.Hour, 0))
+		reader := newReader(i, 1)
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			assert.NoError(t, i.Close())
+		}()
+
+		_, err := reader.Read(make([]byte, 1500))
+		assert.Equal(t, errInterceptorClosed, err)
+	})
+
+	t.Run("Short buffer", func(t *testing.T) {
+		reader := newReader(New(), 1)
+
+		// The packet is kept for a read with a larger buffer
+		_, err := reader.Read(make([]byte, 1))
+		assert.Equal(t, io.ErrShortBuffer, err)
+		assert.Equal(t, []uint16{1}, readAll(t, reader))
+	})
+
+	t.Run("Queue size", func(t *testing.T) {
+		seqs := make([]uint16, 20)
+		for n := range seqs {
+			seqs[n] = uint16(n)
+		}
+
+		// Packets received while the queue is full are dropped
+		i := New(WithDelay(50*time.Millisecond, 0), WithQueueSize(5))
+		received := readAll(t, newReader(i, seqs...))
+		assert.True(t, len(received) >= 5 && len(received) < len(seqs))
+		assert.Equal(t, seqs[:5], received[:5])
+	})
+
+	t.Run("Unbind", func(t *testing.T) {
+		i := New()
+		info := &interceptor.StreamInfo{SSRC: 1}
+		readStarted := make(chan struct{})
+		closed := make(chan struct{})
+		reader := i.BindRemoteStream(info, interceptor.RTPReaderFunc(func(b []byte) (int, error) {
+			close(readStarted)
+			<-closed
+			return 0, io.EOF
+		}))
+
+		readDone := make(chan error)
+		go func() {
+			_, err := reader.Read(make([]byte, 1500))
+			readDone <- err
+		}()
+		<-readStarted
+
+		// The stream is read until it is closed
+		unbound := make(chan struct{})
+		go func() {
+			i.UnbindRemoteStream(info)
+			close(unbound)
+		}()
+		select {
+		case <-unbound:
+			t.Fatal("UnbindRemoteStream returned while the stream was read")
+		case <-time.After(20 * time.Millisecond):
+		}
+
+		close(closed)
+		<-unbound
+		assert.Equal(t, io.EOF, <-readDone)
+		assert.NoError(t, i.Close())
+	})
+
+	t.Run("Send only", func(t *testing.T) {
+		i := New(WithDropRate(1), WithDirection(DirectionSend))
+		assert.Equal(t, []uint16{1, 2}, readAll(t, newReader(i, 1, 2)))
+	})
+
+	t.Run("Seeded partial loss", func(t *testing.T) {
+		seqs := make([]uint16, 1000)
+		for n := range seqs {
+			seqs[n] = uint16(n)
+		}
+
+		received := len(readAll(t, newReader(New(WithDropRate(0.5), WithSeed(5)), seqs...)))
+		assert.Equal(t, received, len(readAll(t, newReader(New(WithDropRate(0.5), WithSeed(5)), seqs...))))
+		assert.True(t, received > 400 && received < 600)
+	})
+}
@@ -0,0 +1,66 @@
+// Package interceptor contains the Interceptor interface, which allows inspecting and
+// modifying the RTP packets that flow through an RTPSender or RTPReceiver.
+package interceptor
+
+import (
+	"io"
+
+	"github.com/pion/rtp"
+)
+
+// Interceptor can be used to add functionality to your PeerConnections by modifying
+// any incoming/outgoing RTP packets, or by dropping and injecting packets as needed.
+type Interceptor interface {
+	// BindLocalStream lets you modify any outgoing RTP packets. It is called once
+	// for every outbound stream. The returned method will be called once per RTP packet.
+	BindLocalStream(info *StreamInfo, writer RTPWriter) RTPWriter
+
+	// UnbindLocalStream is called when the Stream is removed. It can be used to clean up any data related to that track.
+	UnbindLocalStream(info *StreamInfo)
+
+	// BindRemoteStream lets you modify any incoming RTP packets. It is called once
+	// for every inbound stream. The returned method will be called once per RTP packet.
+	BindRemoteStream(info *StreamInfo, reader RTPReader) RTPReader
+
+	// UnbindRemoteStream is called when the Stream is removed. It can be used to clean up any data related to that track.
+	UnbindRemoteStream(info *StreamInfo)
+
+	io.Closer
+}
+
+// RTPWriter is used by Interceptor.BindLocalStream.
+type RTPWriter interface {
+	// Write a rtp packet
+	Write(header *rtp.Header, payload []byte) (int, error)
+}
+
+// RTPReader is used by Interceptor.BindRemoteStream.
+type RTPReader interface {
+	// Read a marshaled rtp packet into b
+	Read(b []byte) (int, error)
+}
+
+// RTPWriterFunc is an adapter for RTPWriter interface
+type RTPWriterFunc func(header *rtp.Header, payload []byte) (int, error)
+
+// RTPReaderFunc is an adapter for RTPReader interface
+type RTPReaderFunc func(b []byte) (int, error)
+
+// Write a rtp packet
+func (f RTPWriterFunc) Write(header *rtp.Header, payload []byte) (int, error) {
+	return f(header, payload)
+}
+
+// Read a marshaled rtp packet into b
+func (f RTPReaderFunc) Read(b []byte) (int, error) {
+	return f(b)
+}
+
+// StreamInfo is the Context passed when a StreamLocal or StreamRemote has been Binded or Unbinded
+type StreamInfo struct {
+	ID          string
+	SSRC        uint32
+	PayloadType uint8
+	MimeType    string
+	ClockRate   uint32
+}
@@ -0,0 +1,92 @@
+// Package aacpacketizer implements the MP4A-LATM RTP payload format of
+// RFC 6416 for AAC audio, with the configuration sent out of band
+package aacpacketizer
+
+// SamplesPerFrame is the number of samples per channel of an AAC frame,
+// which is what the RTP timestamp advances by
+const SamplesPerFrame = 1024
+
+// Payloader payloads AAC frames as audio mux elements of RFC 6416 with
+// cpresent=0, one frame per element. A frame larger than the MTU is
+// fragmented over several packets.
+type Payloader struct{}
+
+// Payload fragments an AAC frame, without its ADTS header, across one or
+// more byte arrays
+func (p *Payloader) Payload(mtu int, payload []byte) [][]byte {
+	if mtu <= 0 || len(payload) == 0 {
+		return nil
+	}
+
+	// The PayloadLengthInfo is 0xFF for each 255 bytes of the frame and
+	// the rest
+	element := make([]byte, 0, len(payload)/255+1+len(payload))
+	for length := len(payload); ; length -= 255 {
+		if length < 255 {
+			element = append(element, byte(length))
+			break
+		}
+		element = append(element, 0xFF)
+	}
+	element = append(element, payload...)
+
+	payloads := [][]byte{}
+	for len(element) > 0 {
+		size := mtu
+		if size > len(element) {
+			size = len(element)
+		}
+
+		payloads = append(payloads, element[:size])
+		element = element[size:]
+	}
+
+	return payloads
+}
+
+// StreamMuxConfig returns the StreamMuxConfig of RFC 6416 for a stream of
+// one AAC program with audioSpecificConfig, as sent in the config parameter
+// of the fmtp
+func StreamMuxConfig(audioSpecificConfig []byte) []byte {
+	w := &bitWriter{}
+	w.write(0, 1) // audioMuxVersion
+	w.write(1, 1) // allStreamsSameTimeFraming
+	w.write(0, 6) // numSubFrames
+	w.write(0, 4) // numProgram
+	w.write(0, 3) // numLayer
+	for _, b := range audioSpecificConfig {
+		w.write(uint32(b), 8)
+	}
+	w.write(0, 3)    // frameLengthType
+	w.write(0xFF, 8) // latmBufferFullness
+	w.write(0, 1)    // otherDataPresent
+	w.write(0, 1)    // crcCheckPresent
+	return w.bytes
+}
+
+// ObjectType returns the audio object type of audioSpecificConfig, like 2
+// for AAC LC
+func ObjectType(audioSpecificConfig []byte) uint8 {
+	if len(audioSpecificConfig) == 0 {
+		return 0
+	}
+	return audioSpecificConfig[0] >> 3
+}
+
+// bitWriter writes bits most significant first
+type bitWriter struct {
+	bytes []byte
+	bits  uint
+}
+
+func (w *bitWriter) write(v uint32, n uint) {
+	for i := n; i > 0; i-- {
+		if w.bits%8 == 0 {
+			w.bytes = append(w.bytes, 0)
+		}
+		if v&(1<<(i-1)) != 0 {
+			w.bytes[len(w.bytes)-1] |= 0x80 >> (w.bits % 8)
+		}
+		w.bits++
+	}
+}
@@ -0,0 +1,33 @@
+package aacpacketizer
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPayload(t *testing.T) {
+	p := &Payloader{}
+	assert.Equal(t, [][]byte{{0x03, 0x01, 0x02, 0x03}}, p.Payload(1200, []byte{0x01, 0x02, 0x03}))
+	assert.Equal(t, [][]byte{{0x03, 0x01}, {0x02, 0x03}}, p.Payload(2, []byte{0x01, 0x02, 0x03}))
+	assert.Nil(t, p.Payload(1200, nil))
+
+	// The length of frames of 255 bytes and more takes several bytes
+	frame := bytes.Repeat([]byte{0xAA}, 300)
+	payloads := p.Payload(1200, frame)
+	assert.Equal(t, 1, len(payloads))
+	assert.Equal(t, []byte{0xFF, 300 - 255}, payloads[0][:2])
+	assert.Equal(t, frame, payloads[0][2:])
+
+	payloads = p.Payload(1200, frame[:255])
+	assert.Equal(t, []byte{0xFF, 0x00}, payloads[0][:2])
+}
+
+func TestStreamMuxConfig(t *testing.T) {
+	// AAC LC at 44100 Hz in stereo, the example of RFC 6416
+	audioSpecificConfig := []byte{0x12, 0x10}
+	assert.Equal(t, []byte{0x40, 0x00, 0x24, 0x20, 0x3F, 0xC0}, StreamMuxConfig(audioSpecificConfig))
+	assert.Equal(t, uint8(2), ObjectType(audioSpecificConfig))
+	assert.Equal(t, uint8(0), ObjectType(nil))
+}
@@ -0,0 +1,253 @@
+// Package audiolevel implements active speaker detection from the audio
+// levels of RFC 6464, like the ones passed to RTPReceiver.OnAudioLevel
+package audiolevel
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// maxLevel is the level of silence, in -dBov
+	maxLevel = 127
+
+	// minActivity is the activity below which a speaker is silent, as the
+	// smoothed activity only tends to 0
+	minActivity = 1.0
+
+	defaultSmoothing      = 0.2
+	defaultSpeechLevel    = 60
+	defaultSwitchDelay    = 500 * time.Millisecond
+	defaultSilenceTimeout = time.Second
+)
+
+// Speaker is the state of a speaker of a Detector
+type Speaker struct {
+	ID string
+
+	// Activity is the smoothed loudness of the speech of the speaker, from 0
+	// for silence to 127 for 0 dBov
+	Activity float64
+
+	// LastLevel is the last level added, in -dBov
+	LastLevel uint8
+}
+
+type speaker struct {
+	Speaker
+	updated time.Time
+}
+
+// Detector detects the dominant speaker of a conference from the audio
+// levels of the Tracks of its participants. Levels of speech are smoothed
+// over time, and the dominant speaker only changes once another one has been
+// louder for the switch delay, so that short noises and interruptions are
+// ignored. Levels are added with AddLevel, for example from an RTPReceiver:
+//
+//	receiver.OnAudioLevel(func(level uint8, voice bool) {
+//	    detector.AddLevel(track.ID(), level, voice)
+//	})
+type Detector struct {
+	smoothing      float64
+	speechLevel    uint8
+	switchDelay    time.Duration
+	silenceTimeout time.Duration
+	requireVoice   bool
+
+	mu        sync.Mutex
+	speakers  map[string]*speaker
+	dominant  string
+	candidate string
+	since     time.Time
+	onChange  func(id string)
+}
+
+// Option configures a Detector
+type Option func(d *Detector)
+
+// WithSmoothing sets the weight, between 0 and 1, of a new level in the
+// activity of a speaker. Lower values smooth more. The default is 0.2.
+func WithSmoothing(smoothing float64) Option {
+	return func(d *Detector) {
+		if smoothing > 0 && smoothing <= 1 {
+			d.smoothing = smoothing
+		}
+	}
+}
+
+// WithSpeechLevel sets the level in -dBov above which a level is silence.
+// The default is 60, for -60 dBov.
+func WithSpeechLevel(level uint8) Option {
+	return func(d *Detector) {
+		d.speechLevel = level
+	}
+}
+
+// WithSwitchDelay sets how long another speaker must be the most active
+// before it becomes the dominant speaker. The default is 500ms.
+func WithSwitchDelay(delay time.Duration) Option {
+	return func(d *Detector) {
+		d.switchDelay = delay
+	}
+}
+
+// WithSilenceTimeout sets after how long without levels a speaker is
+// silent, as senders stop sending during silence. The default is 1s.
+func WithSilenceTimeout(timeout time.Duration) Option {
+	return func(d *Detector) {
+		d.silenceTimeout = timeout
+	}
+}
+
+// WithVoiceActivity makes levels without the voice activity flag silence,
+// for senders that set it
+func WithVoiceActivity() Option {
+	return func(d *Detector) {
+		d.requireVoice = true
+	}
+}
+
+// NewDetector creates a Detector
+func NewDetector(opts ...Option) *Detector {
+	d := &Detector{
+		smoothing:      defaultSmoothing,
+		speechLevel:    defaultSpeechLevel,
+		switchDelay:    defaultSwitchDelay,
+		silenceTimeout: defaultSilenceTimeout,
+		speakers:       map[string]*speaker{},
+	}
+	for _, o := range opts {
+		o(d)
+	}
+
+	return d
+}
+
+// OnDominantSpeakerChange sets an event handler which is called with the ID
+// of the new dominant speaker when it changes, or "" once no one speaks
+func (d *Detector) OnDominantSpeakerChange(f func(id string)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.onChange = f
+}
+
+// AddLevel adds the level in -dBov, from 0 for 0 dBov to 127 for silence, of
+// a packet of the speaker id, and its voice activity flag
+func (d *Detector) AddLevel(id string, level uint8, voice bool) {
+	d.addLevel(id, level, voice, time.Now())
+}
+
+// Remove removes the speaker id, when it leaves the conference
+func (d *Detector) Remove(id string) {
+	d.mu.Lock()
+	delete(d.speakers, id)
+	handler, changed := d.update(time.Now())
+	dominant := d.dominant
+	d.mu.Unlock()
+
+	if changed && handler != nil {
+		handler(dominant)
+	}
+}
+
+// DominantSpeaker returns the ID of the dominant speaker, or "" if no one
+// speaks
+func (d *Detector) DominantSpeaker() string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.dominant
+}
+
+// Speakers returns the speakers from the most to the least active, to
+// select the last N speakers of a conference
+func (d *Detector) Speakers() []Speaker {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	speakers := make([]Speaker, 0, len(d.speakers))
+	for _, s := range d.speakers {
+		speaker := s.Speaker
+		speaker.Activity = d.activity(s, now)
+		speakers = append(speakers, speaker)
+	}
+	sort.SliceStable(speakers, func(i, j int) bool {
+		if speakers[i].Activity != speakers[j].Activity {
+			return speakers[i].Activity > speakers[j].Activity
+		}
+		return speakers[i].ID < speakers[j].ID
+	})
+	return speakers
+}
+
+func (d *Detector) addLevel(id string, level uint8, voice bool, now time.Time) {
+	if level > maxLevel {
+		level = maxLevel
+	}
+
+	d.mu.Lock()
+	s, ok := d.speakers[id]
+	if !ok {
+		s = &speaker{Speaker: Speaker{ID: id}}
+		d.speakers[id] = s
+	}
+
+	loudness := 0.0
+	if level <= d.speechLevel && (voice || !d.requireVoice) {
+		loudness = float64(maxLevel - level)
+	}
+	activity := d.activity(s, now)
+	s.Activity = activity + d.smoothing*(loudness-activity)
+	s.LastLevel = level
+	s.updated = now
+
+	handler, changed := d.update(now)
+	dominant := d.dominant
+	d.mu.Unlock()
+
+	if changed && handler != nil {
+		handler(dominant)
+	}
+}
+
+// activity returns the activity of a speaker, 0 once it is silent or
+// hasn't sent levels for the silence timeout
+func (d *Detector) activity(s *speaker, now time.Time) float64 {
+	if s.Activity < minActivity || now.Sub(s.updated) >= d.silenceTimeout {
+		return 0
+	}
+	return s.Activity
+}
+
+// update changes the dominant speaker, the caller holds d.mu. It returns the
+// handler to call, and if the dominant speaker changed.
+func (d *Detector) update(now time.Time) (func(id string), bool) {
+	loudest, loudestActivity := "", 0.0
+	for id, s := range d.speakers {
+		activity := d.activity(s, now)
+		if activity > loudestActivity || (activity == loudestActivity && activity > 0 && id < loudest) {
+			loudest, loudestActivity = id, activity
+		}
+	}
+
+	dominant, ok := d.speakers[d.dominant]
+	switch {
+	case loudest == d.dominant:
+		d.candidate = ""
+		return nil, false
+	case d.dominant == "" || !ok || d.activity(dominant, now) == 0:
+		// No one was dominant, or the dominant speaker left or went silent,
+		// switch right away
+	case loudest != d.candidate:
+		d.candidate, d.since = loudest, now
+		if d.switchDelay > 0 {
+			return nil, false
+		}
+	case now.Sub(d.since) < d.switchDelay:
+		return nil, false
+	}
+
+	d.dominant, d.candidate = loudest, ""
+	return d.onChange, true
+}
@@ -0,0 +1,84 @@
+package audiolevel
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetector(t *testing.T) {
+	d := NewDetector(WithSwitchDelay(100*time.Millisecond), WithSilenceTimeout(time.Second))
+	changes := []string{}
+	d.OnDominantSpeakerChange(func(id string) {
+		changes = append(changes, id)
+	})
+
+	start := time.Now()
+	at := func(ms int) time.Time {
+		return start.Add(time.Duration(ms) * time.Millisecond)
+	}
+
+	// Silence doesn't make anyone dominant
+	d.addLevel("a", 127, false, at(0))
+	d.addLevel("b", 90, false, at(0))
+	assert.Equal(t, "", d.DominantSpeaker())
+
+	// The first speaker is dominant right away
+	d.addLevel("a", 20, true, at(20))
+	assert.Equal(t, "a", d.DominantSpeaker())
+
+	// A louder speaker is dominant once it's the loudest for the switch delay
+	for ms := 40; ms <= 200; ms += 20 {
+		d.addLevel("a", 40, true, at(ms))
+		d.addLevel("b", 10, true, at(ms))
+		if ms < 140 {
+			assert.Equal(t, "a", d.DominantSpeaker(), ms)
+		}
+	}
+	assert.Equal(t, "b", d.DominantSpeaker())
+
+	// A short noise of a doesn't make it dominant again
+	d.addLevel("a", 0, true, at(220))
+	d.addLevel("b", 10, true, at(220))
+	d.addLevel("a", 40, true, at(240))
+	d.addLevel("b", 10, true, at(240))
+	for ms := 260; ms <= 400; ms += 20 {
+		d.addLevel("a", 40, true, at(ms))
+		d.addLevel("b", 10, true, at(ms))
+	}
+	assert.Equal(t, "b", d.DominantSpeaker())
+
+	// Once b is silent, a is dominant again
+	for ms := 420; d.DominantSpeaker() == "b"; ms += 20 {
+		d.addLevel("b", 127, false, at(ms))
+		d.addLevel("a", 40, true, at(ms))
+	}
+	assert.Equal(t, "a", d.DominantSpeaker())
+
+	speakers := d.Speakers()
+	assert.Equal(t, 2, len(speakers))
+	assert.Equal(t, "a", speakers[0].ID)
+	assert.Equal(t, uint8(40), speakers[0].LastLevel)
+
+	// Once the dominant speaker leaves, no one speaks
+	d.Remove("b")
+	assert.Equal(t, "a", d.DominantSpeaker())
+	d.Remove("a")
+	assert.Equal(t, "", d.DominantSpeaker())
+	assert.Equal(t, []string{"a", "b", "a", ""}, changes)
+}
+
+func TestDetector_VoiceActivity(t *testing.T) {
+	start := time.Now()
+
+	d := NewDetector(WithVoiceActivity())
+	d.addLevel("a", 20, false, start)
+	assert.Equal(t, "", d.DominantSpeaker())
+	d.addLevel("a", 20, true, start)
+	assert.Equal(t, "a", d.DominantSpeaker())
+
+	// Speakers without levels for the silence timeout are silent
+	d.addLevel("b", 20, true, start.Add(2*time.Second))
+	assert.Equal(t, "b", d.DominantSpeaker())
+}
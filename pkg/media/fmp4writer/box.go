@@ -0,0 +1,82 @@
+package fmp4writer
+
+import (
+	"encoding/binary"
+)
+
+// unityMatrix is the identity transformation used by mvhd and tkhd
+var unityMatrix = []uint32{0x00010000, 0, 0, 0, 0x00010000, 0, 0, 0, 0x40000000} //nolint:gochecknoglobals
+
+// box builds an ISO BMFF box, see ISO/IEC 14496-12 Section 4.2
+func box(boxType string, children ...[]byte) []byte {
+	size := 8
+	for _, c := range children {
+		size += len(c)
+	}
+
+	out := make([]byte, 8, size)
+	binary.BigEndian.PutUint32(out, uint32(size))
+	copy(out[4:], boxType)
+	for _, c := range children {
+		out = append(out, c...)
+	}
+
+	return out
+}
+
+// fullBox builds a box with a version and flags header
+func fullBox(boxType string, version uint8, flags uint32, children ...[]byte) []byte {
+	header := u32(flags)
+	header[0] = version
+
+	return box(boxType, append([][]byte{header}, children...)...)
+}
+
+func u8(v uint8) []byte {
+	return []byte{v}
+}
+
+func u16(v uint16) []byte {
+	out := make([]byte, 2)
+	binary.BigEndian.PutUint16(out, v)
+	return out
+}
+
+func u32(v uint32) []byte {
+	out := make([]byte, 4)
+	binary.BigEndian.PutUint32(out, v)
+	return out
+}
+
+func u64(v uint64) []byte {
+	out := make([]byte, 8)
+	binary.BigEndian.PutUint64(out, v)
+	return out
+}
+
+func zeros(n int) []byte {
+	return make([]byte, n)
+}
+
+func matrix() []byte {
+	out := []byte{}
+	for _, v := range unityMatrix {
+		out = append(out, u32(v)...)
+	}
+	return out
+}
+
+// descriptor builds an MPEG-4 descriptor, see ISO/IEC 14496-1 Section 8.3.3
+func descriptor(tag uint8, children ...[]byte) []byte {
+	size := 0
+	for _, c := range children {
+		size += len(c)
+	}
+
+	// Sizes are encoded 7 bits at a time, always use the 4 byte form
+	out := []byte{tag, 0x80 | byte(size>>21)&0x7F, 0x80 | byte(size>>14)&0x7F, 0x80 | byte(size>>7)&0x7F, byte(size) & 0x7F}
+	for _, c := range children {
+		out = append(out, c...)
+	}
+	return out
+}
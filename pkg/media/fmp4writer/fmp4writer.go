@@ -0,0 +1,499 @@
+// Package fmp4writer implements a fragmented MP4 (CMAF) media container writer
+package fmp4writer
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"time"
+
+	"github.com/pion/webrtc/v3/pkg/media"
+	"github.com/pion/webrtc/v3/pkg/media/h264"
+)
+
+const (
+	trackTypeVideo = "vide"
+	trackTypeAudio = "soun"
+
+	videoTimescale = 90000
+
+	naluTypeIDR = 5
+	naluTypeSPS = 7
+	naluTypePPS = 8
+	naluTypeAUD = 9
+
+	sampleFlagsSync    = 0x02000000 // sample_depends_on = 2
+	sampleFlagsNonSync = 0x01010000 // sample_depends_on = 1, sample_is_non_sync_sample
+
+	defaultFragmentDuration = 2 * time.Second
+
+	opusPreSkip = 3840
+)
+
+var (
+	errFileNotOpened       = errors.New("file not opened")
+	errNoTracks            = errors.New("at least one track must be configured")
+	errMultipleAudioTracks = errors.New("only one audio track can be configured")
+	errNoVideoTrack        = errors.New("no video track configured")
+	errNoAudioTrack        = errors.New("no audio track configured")
+)
+
+type sample struct {
+	data     []byte
+	duration uint32
+	flags    uint32
+}
+
+type track struct {
+	id        uint32
+	trackType string
+	timescale uint32
+
+	// sampleEntry builds the stsd entry of the track
+	sampleEntry func() []byte
+
+	// decodeTime is the time of the first pending sample in timescale units
+	decodeTime uint64
+	pending    []sample
+}
+
+func (t *track) pendingDuration() uint64 {
+	d := uint64(0)
+	for _, s := range t.pending {
+		d += uint64(s.duration)
+	}
+	return d
+}
+
+// FMP4Writer is used to take H264 access units and AAC or Opus samples and
+// write them as a fragmented MP4. The output is CMAF compatible, the first
+// Write to the io.Writer is the initialization segment and every following
+// Write is exactly one fragment (moof and mdat), so it can be used as HLS or
+// DASH segments directly.
+type FMP4Writer struct {
+	ioWriter io.Writer
+
+	width, height    uint16
+	video, audio     *track
+	sps, pps         []byte
+	fragmentDuration time.Duration
+	partDuration     time.Duration
+	sequenceNumber   uint32
+	wroteInit        bool
+}
+
+// Option configures a FMP4Writer
+type Option func(w *FMP4Writer) error
+
+// WithH264Track adds an H264 video track with the given resolution
+func WithH264Track(width, height uint16) Option {
+	return func(w *FMP4Writer) error {
+		w.width = width
+		w.height = height
+		w.video = &track{trackType: trackTypeVideo, timescale: videoTimescale}
+		w.video.sampleEntry = w.avc1
+		return nil
+	}
+}
+
+// WithOpusTrack adds an Opus audio track, sample durations are in 48kHz units
+func WithOpusTrack(channels uint16) Option {
+	return func(w *FMP4Writer) error {
+		if w.audio != nil {
+			return errMultipleAudioTracks
+		}
+
+		w.audio = &track{trackType: trackTypeAudio, timescale: 48000}
+		w.audio.sampleEntry = func() []byte {
+			return box("Opus", audioSampleEntry(channels, 48000), box("dOps",
+				u8(0), // Version
+				u8(uint8(channels)),
+				u16(opusPreSkip),
+				u32(48000), // Input sample rate
+				u16(0),     // Output gain
+				u8(0),      // Channel mapping family
+			))
+		}
+		return nil
+	}
+}
+
+// WithAACTrack adds an AAC audio track, audioSpecificConfig is the
+// AudioSpecificConfig of ISO/IEC 14496-3 and sample durations are in
+// sampleRate units
+func WithAACTrack(sampleRate uint32, channels uint16, audioSpecificConfig []byte) Option {
+	return func(w *FMP4Writer) error {
+		if w.audio != nil {
+			return errMultipleAudioTracks
+		}
+
+		w.audio = &track{trackType: trackTypeAudio, timescale: sampleRate}
+		w.audio.sampleEntry = func() []byte {
+			return box("mp4a", audioSampleEntry(channels, sampleRate), fullBox("esds", 0, 0,
+				descriptor(0x03, // ES_Descriptor
+					u16(uint16(w.audio.id)),
+					u8(0),
+					descriptor(0x04, // DecoderConfigDescriptor
+						u8(0x40), // Audio ISO/IEC 14496-3
+						u8(0x15), // Audio stream
+						zeros(3), // Buffer size
+						u32(0),   // Max bitrate
+						u32(0),   // Average bitrate
+						descriptor(0x05, audioSpecificConfig),
+					),
+					descriptor(0x06, u8(0x02)), // SLConfigDescriptor
+				),
+			))
+		}
+		return nil
+	}
+}
+
+// WithFragmentDuration sets the duration of fragments when there is no video
+// track. With video a new fragment is started on every keyframe.
+func WithFragmentDuration(d time.Duration) Option {
+	return func(w *FMP4Writer) error {
+		w.fragmentDuration = d
+		return nil
+	}
+}
+
+// WithPartDuration also starts a fragment with a video track before a sample
+// that would make the fragment last longer than d, so fragments can be used
+// as the parts of low-latency HLS. Only fragments started by a keyframe begin
+// with a sync sample.
+func WithPartDuration(d time.Duration) Option {
+	return func(w *FMP4Writer) error {
+		w.partDuration = d
+		return nil
+	}
+}
+
+// New builds a new fragmented MP4 writer
+func New(fileName string, opts ...Option) (*FMP4Writer, error) {
+	f, err := os.Create(fileName)
+	if err != nil {
+		return nil, err
+	}
+	writer, err := NewWith(f, opts...)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return writer, nil
+}
+
+// NewWith initialize a new fragmented MP4 writer with an io.Writer output
+func NewWith(out io.Writer, opts ...Option) (*FMP4Writer, error) {
+	if out == nil {
+		return nil, errFileNotOpened
+	}
+
+	writer := &FMP4Writer{
+		ioWriter:         out,
+		fragmentDuration: defaultFragmentDuration,
+	}
+	for _, o := range opts {
+		if err := o(writer); err != nil {
+			return nil, err
+		}
+	}
+
+	if writer.video == nil && writer.audio == nil {
+		return nil, errNoTracks
+	}
+
+	trackID := uint32(1)
+	for _, t := range writer.tracks() {
+		t.id = trackID
+		trackID++
+	}
+
+	return writer, nil
+}
+
+func (w *FMP4Writer) tracks() []*track {
+	tracks := []*track{}
+	if w.video != nil {
+		tracks = append(tracks, w.video)
+	}
+	if w.audio != nil {
+		tracks = append(tracks, w.audio)
+	}
+	return tracks
+}
+
+// WriteVideoSample writes an H264 access unit in Annex B format. sample.Samples
+// is the duration of the access unit in 90kHz units. Access units are dropped
+// until a keyframe and the SPS and PPS have been seen.
+func (w *FMP4Writer) WriteVideoSample(s media.Sample) error {
+	if w.ioWriter == nil {
+		return errFileNotOpened
+	} else if w.video == nil {
+		return errNoVideoTrack
+	}
+
+	isKeyFrame := false
+	data := []byte{}
+	for _, nalu := range h264.SplitAnnexB(s.Data) {
+		if len(nalu) == 0 {
+			continue
+		}
+
+		// Parameter sets are stored in the sample entry
+		switch nalu[0] & 0x1F {
+		case naluTypeSPS:
+			if len(nalu) >= 4 {
+				w.sps = append([]byte{}, nalu...)
+			}
+			continue
+		case naluTypePPS:
+			w.pps = append([]byte{}, nalu...)
+			continue
+		case naluTypeAUD:
+			continue
+		case naluTypeIDR:
+			isKeyFrame = true
+		}
+
+		data = append(data, u32(uint32(len(nalu)))...)
+		data = append(data, nalu...)
+	}
+
+	if !w.wroteInit {
+		if !isKeyFrame || w.sps == nil || w.pps == nil {
+			return nil
+		}
+		if err := w.writeInit(); err != nil {
+			return err
+		}
+	}
+
+	if len(data) == 0 {
+		return nil
+	}
+
+	partDuration := (w.video.pendingDuration() + uint64(s.Samples)) * uint64(time.Second)
+	if isKeyFrame || (w.partDuration != 0 && partDuration > uint64(w.partDuration)*videoTimescale) {
+		if err := w.writeFragment(); err != nil {
+			return err
+		}
+	}
+
+	flags := uint32(sampleFlagsNonSync)
+	if isKeyFrame {
+		flags = sampleFlagsSync
+	}
+	w.video.pending = append(w.video.pending, sample{data: data, duration: s.Samples, flags: flags})
+	return nil
+}
+
+// WriteAudioSample writes a raw AAC frame or Opus packet. sample.Samples is
+// the duration in the timescale of the track. If there is a video track audio
+// is dropped until the first video keyframe.
+func (w *FMP4Writer) WriteAudioSample(s media.Sample) error {
+	if w.ioWriter == nil {
+		return errFileNotOpened
+	} else if w.audio == nil {
+		return errNoAudioTrack
+	}
+
+	if !w.wroteInit {
+		if w.video != nil {
+			return nil
+		}
+		if err := w.writeInit(); err != nil {
+			return err
+		}
+	}
+
+	if w.video == nil && w.audio.pendingDuration()*uint64(time.Second) >= uint64(w.fragmentDuration)*uint64(w.audio.timescale) {
+		if err := w.writeFragment(); err != nil {
+			return err
+		}
+	}
+
+	w.audio.pending = append(w.audio.pending, sample{data: append([]byte{}, s.Data...), duration: s.Samples, flags: sampleFlagsSync})
+	return nil
+}
+
+func (w *FMP4Writer) avc1() []byte {
+	return box("avc1",
+		zeros(6), u16(1), // Reserved, data reference index
+		zeros(16), // Pre-defined and reserved
+		u16(w.width), u16(w.height),
+		u32(0x00480000), u32(0x00480000), // 72 dpi
+		u32(0), u16(1), // Reserved, frame count
+		zeros(32),                // Compressor name
+		u16(0x0018), u16(0xFFFF), // Depth, pre-defined
+		box("avcC",
+			u8(1),      // Configuration version
+			w.sps[1:4], // Profile, compatibility and level
+			u8(0xFF),   // 4 byte NALU lengths
+			u8(0xE1), u16(uint16(len(w.sps))), w.sps,
+			u8(1), u16(uint16(len(w.pps))), w.pps,
+		),
+	)
+}
+
+func audioSampleEntry(channels uint16, sampleRate uint32) []byte {
+	return bytes.Join([][]byte{
+		zeros(6), u16(1), // Reserved, data reference index
+		zeros(8),               // Reserved
+		u16(channels), u16(16), // Channel count, sample size
+		zeros(4),              // Pre-defined and reserved
+		u32(sampleRate << 16), // Sample rate as 16.16 fixed point
+	}, nil)
+}
+
+func (w *FMP4Writer) writeInit() error {
+	traks := [][]byte{}
+	trexs := [][]byte{}
+	for _, t := range w.tracks() {
+		traks = append(traks, w.trak(t))
+		trexs = append(trexs, fullBox("trex", 0, 0,
+			u32(t.id), u32(1), // Track ID, sample description index
+			u32(0), u32(0), u32(0), // Default duration, size and flags
+		))
+	}
+
+	moov := [][]byte{
+		fullBox("mvhd", 0, 0,
+			u32(0), u32(0), // Creation and modification time
+			u32(1000), u32(0), // Timescale, duration
+			u32(0x00010000), u16(0x0100), zeros(10), // Rate, volume, reserved
+			matrix(),
+			zeros(24), // Pre-defined
+			u32(uint32(len(traks)+1)),
+		),
+	}
+	moov = append(moov, traks...)
+	moov = append(moov, box("mvex", trexs...))
+
+	init := append(box("ftyp",
+		[]byte("iso6"), u32(0),
+		[]byte("iso6"), []byte("cmfc"), []byte("mp41"),
+	), box("moov", moov...)...)
+
+	if _, err := w.ioWriter.Write(init); err != nil {
+		return err
+	}
+
+	w.wroteInit = true
+	return nil
+}
+
+func (w *FMP4Writer) trak(t *track) []byte {
+	volume, width, height := uint16(0x0100), uint32(0), uint32(0)
+	mediaHeader := fullBox("smhd", 0, 0, u16(0), u16(0))
+	handlerName := "SoundHandler"
+	if t.trackType == trackTypeVideo {
+		volume, width, height = 0, uint32(w.width)<<16, uint32(w.height)<<16
+		mediaHeader = fullBox("vmhd", 0, 1, zeros(8))
+		handlerName = "VideoHandler"
+	}
+
+	return box("trak",
+		fullBox("tkhd", 0, 3, // Track enabled and in movie
+			u32(0), u32(0), u32(t.id), u32(0), u32(0), // Creation, modification, track ID, reserved, duration
+			zeros(8), u16(0), u16(0), u16(volume), u16(0), // Reserved, layer, alternate group, volume, reserved
+			matrix(),
+			u32(width), u32(height),
+		),
+		box("mdia",
+			fullBox("mdhd", 0, 0,
+				u32(0), u32(0), u32(t.timescale), u32(0),
+				u16(0x55C4), u16(0), // Language "und"
+			),
+			fullBox("hdlr", 0, 0,
+				u32(0), []byte(t.trackType), zeros(12), []byte(handlerName), u8(0),
+			),
+			box("minf",
+				mediaHeader,
+				box("dinf", fullBox("dref", 0, 0, u32(1), fullBox("url ", 0, 1))),
+				box("stbl",
+					fullBox("stsd", 0, 0, u32(1), t.sampleEntry()),
+					fullBox("stts", 0, 0, u32(0)),
+					fullBox("stsc", 0, 0, u32(0)),
+					fullBox("stsz", 0, 0, u32(0), u32(0)),
+					fullBox("stco", 0, 0, u32(0)),
+				),
+			),
+		),
+	)
+}
+
+// writeFragment writes all pending samples as one moof and mdat
+func (w *FMP4Writer) writeFragment() error {
+	tracks := []*track{}
+	for _, t := range w.tracks() {
+		if len(t.pending) != 0 {
+			tracks = append(tracks, t)
+		}
+	}
+	if len(tracks) == 0 {
+		return nil
+	}
+
+	w.sequenceNumber++
+
+	// data_offset in trun is relative to the start of the moof, build it
+	// once to learn its size and then again with the correct offsets
+	moof := w.moof(tracks, 0)
+	moof = w.moof(tracks, uint32(len(moof))+8)
+
+	mdat := [][]byte{}
+	for _, t := range tracks {
+		for _, s := range t.pending {
+			mdat = append(mdat, s.data)
+		}
+		t.decodeTime += t.pendingDuration()
+		t.pending = nil
+	}
+
+	_, err := w.ioWriter.Write(append(moof, box("mdat", mdat...)...))
+	return err
+}
+
+func (w *FMP4Writer) moof(tracks []*track, dataOffset uint32) []byte {
+	trafs := [][]byte{fullBox("mfhd", 0, 0, u32(w.sequenceNumber))}
+	for _, t := range tracks {
+		samples := [][]byte{u32(uint32(len(t.pending))), u32(dataOffset)}
+		for _, s := range t.pending {
+			samples = append(samples, u32(s.duration), u32(uint32(len(s.data))), u32(s.flags))
+			dataOffset += uint32(len(s.data))
+		}
+
+		trafs = append(trafs, box("traf",
+			fullBox("tfhd", 0, 0x020000, u32(t.id)), // default-base-is-moof
+			fullBox("tfdt", 1, 0, u64(t.decodeTime)),
+			fullBox("trun", 0, 0x000701, samples...), // data offset, sample duration, size and flags present
+		))
+	}
+
+	return box("moof", trafs...)
+}
+
+// Close writes all pending samples and stops the recording
+func (w *FMP4Writer) Close() error {
+	if w.ioWriter == nil {
+		// Returns no error as it may be convenient to call
+		// Close() multiple times
+		return nil
+	}
+
+	defer func() {
+		w.ioWriter = nil
+	}()
+
+	if err := w.writeFragment(); err != nil {
+		return err
+	}
+
+	if closer, ok := w.ioWriter.(io.Closer); ok {
+		return closer.Close()
+	}
+
+	return nil
+}
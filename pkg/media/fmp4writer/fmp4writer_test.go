@@ -0,0 +1,243 @@
+package fmp4writer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/pion/webrtc/v3/pkg/media"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingWriter keeps every Write separately
+type recordingWriter struct {
+	writes [][]byte
+}
+
+func (r *recordingWriter) Write(b []byte) (int, error) {
+	r.writes = append(r.writes, append([]byte{}, b...))
+	return len(b), nil
+}
+
+type parsedBox struct {
+	boxType string
+	payload []byte
+}
+
+func parseBoxes(t *testing.T, b []byte) []parsedBox {
+	boxes := []parsedBox{}
+	for len(b) > 0 {
+		if !assert.True(t, len(b) >= 8) {
+			return nil
+		}
+		size := binary.BigEndian.Uint32(b)
+		if !assert.True(t, int(size) <= len(b) && size >= 8) {
+			return nil
+		}
+		boxes = append(boxes, parsedBox{string(b[4:8]), b[8:size]})
+		b = b[size:]
+	}
+	return boxes
+}
+
+func findBox(t *testing.T, b []byte, path ...string) []byte {
+	for _, boxType := range path {
+		found := false
+		for _, box := range parseBoxes(t, b) {
+			if box.boxType == boxType {
+				b = box.payload
+				found = true
+				break
+			}
+		}
+		if !assert.True(t, found, "box %s not found", boxType) {
+			return nil
+		}
+	}
+	return b
+}
+
+type trun struct {
+	trackID    uint32
+	decodeTime uint64
+	durations  []uint32
+	sizes      []uint32
+	flags      []uint32
+	dataOffset uint32
+}
+
+func parseFragment(t *testing.T, fragment []byte) (sequenceNumber uint32, runs []trun, mdat []byte) {
+	boxes := parseBoxes(t, fragment)
+	assert.Equal(t, 2, len(boxes))
+	assert.Equal(t, "moof", boxes[0].boxType)
+	assert.Equal(t, "mdat", boxes[1].boxType)
+
+	for _, b := range parseBoxes(t, boxes[0].payload) {
+		switch b.boxType {
+		case "mfhd":
+			sequenceNumber = binary.BigEndian.Uint32(b.payload[4:])
+		case "traf":
+			run := trun{
+				trackID:    binary.BigEndian.Uint32(findBox(t, b.payload, "tfhd")[4:]),
+				decodeTime: binary.BigEndian.Uint64(findBox(t, b.payload, "tfdt")[4:]),
+			}
+			payload := findBox(t, b.payload, "trun")
+			count := binary.BigEndian.Uint32(payload[4:])
+			run.dataOffset = binary.BigEndian.Uint32(payload[8:])
+			for i := uint32(0); i < count; i++ {
+				entry := payload[12+i*12:]
+				run.durations = append(run.durations, binary.BigEndian.Uint32(entry))
+				run.sizes = append(run.sizes, binary.BigEndian.Uint32(entry[4:]))
+				run.flags = append(run.flags, binary.BigEndian.Uint32(entry[8:]))
+			}
+			runs = append(runs, run)
+		}
+	}
+
+	return sequenceNumber, runs, boxes[1].payload
+}
+
+func TestFMP4Writer_H264AndOpus(t *testing.T) {
+	out := &recordingWriter{}
+	writer, err := NewWith(out, WithH264Track(640, 480), WithOpusTrack(2))
+	assert.NoError(t, err)
+
+	sps := []byte{0x67, 0x42, 0xC0, 0x1F, 0xAA}
+	pps := []byte{0x68, 0xCE, 0x3C, 0x80}
+	idr := []byte{0x65, 0x88, 0x84}
+	nonIDR := []byte{0x41, 0x9A, 0x02}
+	annexB := func(nalus ...[]byte) []byte {
+		out := []byte{}
+		for _, n := range nalus {
+			out = append(out, 0x00, 0x00, 0x00, 0x01)
+			out = append(out, n...)
+		}
+		return out
+	}
+
+	// Dropped, no keyframe yet
+	assert.NoError(t, writer.WriteAudioSample(media.Sample{Data: []byte{0xFF}, Samples: 960}))
+	assert.NoError(t, writer.WriteVideoSample(media.Sample{Data: annexB(nonIDR), Samples: 3000}))
+	assert.Empty(t, out.writes)
+
+	assert.NoError(t, writer.WriteVideoSample(media.Sample{Data: annexB([]byte{0x09, 0xF0}, sps, pps, idr), Samples: 3000}))
+	assert.NoError(t, writer.WriteAudioSample(media.Sample{Data: []byte{0x01, 0x02}, Samples: 960}))
+	assert.NoError(t, writer.WriteVideoSample(media.Sample{Data: annexB(nonIDR), Samples: 3000}))
+	assert.NoError(t, writer.WriteVideoSample(media.Sample{Data: annexB(idr), Samples: 3000}))
+	assert.NoError(t, writer.Close())
+	assert.NoError(t, writer.Close())
+
+	assert.Equal(t, 3, len(out.writes))
+
+	// Initialization segment
+	init := out.writes[0]
+	boxes := parseBoxes(t, init)
+	assert.Equal(t, "ftyp", boxes[0].boxType)
+	assert.Equal(t, "moov", boxes[1].boxType)
+	avcC := findBox(t, init, "moov", "trak", "mdia", "minf", "stbl", "stsd")
+	assert.True(t, bytes.Contains(avcC, append([]byte{0xE1, 0x00, byte(len(sps))}, sps...)))
+	assert.True(t, bytes.Contains(avcC, append([]byte{0x01, 0x00, byte(len(pps))}, pps...)))
+	assert.Equal(t, 2, bytes.Count(findBox(t, init, "moov", "mvex"), []byte("trex")))
+
+	// First fragment with a keyframe, an inter frame and audio
+	sequenceNumber, runs, mdat := parseFragment(t, out.writes[1])
+	assert.Equal(t, uint32(1), sequenceNumber)
+	assert.Equal(t, 2, len(runs))
+
+	lengthPrefixed := func(n []byte) []byte {
+		return append([]byte{0, 0, 0, byte(len(n))}, n...)
+	}
+	assert.Equal(t, trun{
+		trackID:    1,
+		decodeTime: 0,
+		durations:  []uint32{3000, 3000},
+		sizes:      []uint32{7, 7},
+		flags:      []uint32{sampleFlagsSync, sampleFlagsNonSync},
+		dataOffset: uint32(len(out.writes[1]) - len(mdat)),
+	}, runs[0])
+	assert.Equal(t, trun{
+		trackID:    2,
+		decodeTime: 0,
+		durations:  []uint32{960},
+		sizes:      []uint32{2},
+		flags:      []uint32{sampleFlagsSync},
+		dataOffset: uint32(len(out.writes[1]) - len(mdat) + 14),
+	}, runs[1])
+	assert.Equal(t, bytes.Join([][]byte{lengthPrefixed(idr), lengthPrefixed(nonIDR), {0x01, 0x02}}, nil), mdat)
+
+	// Second fragment is started by the keyframe and written on Close
+	sequenceNumber, runs, _ = parseFragment(t, out.writes[2])
+	assert.Equal(t, uint32(2), sequenceNumber)
+	assert.Equal(t, 1, len(runs))
+	assert.Equal(t, uint64(6000), runs[0].decodeTime)
+}
+
+func TestFMP4Writer_PartDuration(t *testing.T) {
+	out := &recordingWriter{}
+	writer, err := NewWith(out, WithH264Track(640, 480), WithPartDuration(100*time.Millisecond))
+	assert.NoError(t, err)
+
+	keyframe := []byte{0x00, 0x00, 0x00, 0x01, 0x67, 0x42, 0xC0, 0x1F, 0x00, 0x00, 0x00, 0x01, 0x68, 0xCE, 0x00, 0x00, 0x00, 0x01, 0x65, 0x88}
+	interframe := []byte{0x00, 0x00, 0x00, 0x01, 0x41, 0x9A}
+
+	// 30 fps, so parts of 3 frames and a keyframe that starts one
+	assert.NoError(t, writer.WriteVideoSample(media.Sample{Data: keyframe, Samples: 3000}))
+	for i := 0; i < 4; i++ {
+		assert.NoError(t, writer.WriteVideoSample(media.Sample{Data: interframe, Samples: 3000}))
+	}
+	assert.NoError(t, writer.WriteVideoSample(media.Sample{Data: keyframe, Samples: 3000}))
+	assert.NoError(t, writer.Close())
+
+	assert.Equal(t, 4, len(out.writes))
+	for i, flags := range [][]uint32{
+		{sampleFlagsSync, sampleFlagsNonSync, sampleFlagsNonSync},
+		{sampleFlagsNonSync, sampleFlagsNonSync},
+		{sampleFlagsSync},
+	} {
+		_, runs, _ := parseFragment(t, out.writes[i+1])
+		assert.Equal(t, flags, runs[0].flags)
+	}
+}
+
+func TestFMP4Writer_AAC(t *testing.T) {
+	out := &recordingWriter{}
+	audioSpecificConfig := []byte{0x12, 0x10}
+	writer, err := NewWith(out, WithAACTrack(44100, 2, audioSpecificConfig), WithFragmentDuration(time.Second))
+	assert.NoError(t, err)
+
+	// 1024 samples per frame, so 44 frames per fragment
+	for i := 0; i < 100; i++ {
+		assert.NoError(t, writer.WriteAudioSample(media.Sample{Data: []byte{byte(i)}, Samples: 1024}))
+	}
+	assert.NoError(t, writer.Close())
+
+	esds := findBox(t, out.writes[0], "moov", "trak", "mdia", "minf", "stbl", "stsd")
+	assert.True(t, bytes.Contains(esds, append([]byte{0x05, 0x80, 0x80, 0x80, 0x02}, audioSpecificConfig...)))
+	assert.Equal(t, uint32(44100), binary.BigEndian.Uint32(findBox(t, out.writes[0], "moov", "trak", "mdia", "mdhd")[12:]))
+
+	assert.Equal(t, 4, len(out.writes))
+	decodeTime := uint64(0)
+	for i, fragment := range out.writes[1:] {
+		sequenceNumber, runs, _ := parseFragment(t, fragment)
+		assert.Equal(t, uint32(i+1), sequenceNumber)
+		assert.Equal(t, decodeTime, runs[0].decodeTime)
+		decodeTime += uint64(1024 * len(runs[0].durations))
+	}
+	assert.Equal(t, uint64(100*1024), decodeTime)
+}
+
+func TestFMP4Writer_Options(t *testing.T) {
+	_, err := NewWith(nil, WithOpusTrack(2))
+	assert.Equal(t, errFileNotOpened, err)
+
+	_, err = NewWith(&bytes.Buffer{})
+	assert.Equal(t, errNoTracks, err)
+
+	_, err = NewWith(&bytes.Buffer{}, WithOpusTrack(2), WithAACTrack(48000, 2, nil))
+	assert.Equal(t, errMultipleAudioTracks, err)
+
+	writer, err := NewWith(&bytes.Buffer{}, WithOpusTrack(2))
+	assert.NoError(t, err)
+	assert.Equal(t, errNoVideoTrack, writer.WriteVideoSample(media.Sample{}))
+}
@@ -0,0 +1,57 @@
+// Package framepacketizer implements the RTP payload formats of audio codecs
+// with fixed size frames, like G.729 of RFC 3551 and iLBC of RFC 3952. The
+// frames are relayed as they are, without transcoding.
+package framepacketizer
+
+// Frame sizes in bytes
+const (
+	FrameSizeG729     = 10
+	FrameSizeILBC20ms = 38
+	FrameSizeILBC30ms = 50
+)
+
+// Payloader payloads audio frames of FrameSize bytes. Packets are split
+// between frames, a trailing partial frame like the 2 byte comfort noise
+// frame of G.729 Annex B is sent at the end of the last packet.
+type Payloader struct {
+	FrameSize int
+}
+
+// Payload fragments audio frames across one or more byte arrays
+func (p *Payloader) Payload(mtu int, payload []byte) [][]byte {
+	frameSize := p.FrameSize
+	if frameSize < 1 {
+		frameSize = 1
+	}
+
+	maxSize := mtu / frameSize * frameSize
+	if maxSize <= 0 || len(payload) == 0 {
+		return nil
+	}
+
+	payloads := [][]byte{}
+	for len(payload) > 0 {
+		size := maxSize
+		if size > len(payload) {
+			size = len(payload)
+		}
+
+		payloads = append(payloads, append([]byte{}, payload[:size]...))
+		payload = payload[size:]
+	}
+
+	return payloads
+}
+
+// Depacketizer returns the audio frames of RTP payloads
+type Depacketizer struct{}
+
+// Unmarshal parses the payload of an RTP packet of audio frames
+func (d *Depacketizer) Unmarshal(payload []byte) ([]byte, error) {
+	return append([]byte{}, payload...), nil
+}
+
+// IsPartitionHead checks if this is the head of a frame, which every packet is
+func (d *Depacketizer) IsPartitionHead(payload []byte) bool {
+	return true
+}
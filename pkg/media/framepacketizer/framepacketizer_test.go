@@ -0,0 +1,33 @@
+package framepacketizer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPayload(t *testing.T) {
+	p := &Payloader{FrameSize: FrameSizeG729}
+
+	// Three G.729 frames and a comfort noise frame
+	frames := make([]byte, 3*FrameSizeG729+2)
+	for i := range frames {
+		frames[i] = byte(i)
+	}
+
+	assert.Equal(t, [][]byte{frames[:20], frames[20:]}, p.Payload(25, frames))
+	assert.Equal(t, [][]byte{frames}, p.Payload(1200, frames))
+	assert.Nil(t, p.Payload(9, frames))
+	assert.Nil(t, p.Payload(1200, []byte{}))
+
+	// Any size without a frame size
+	assert.Equal(t, [][]byte{{0x01, 0x02}, {0x03}}, (&Payloader{}).Payload(2, []byte{0x01, 0x02, 0x03}))
+}
+
+func TestDepacketizer(t *testing.T) {
+	d := &Depacketizer{}
+	data, err := d.Unmarshal([]byte{0x01, 0x02})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x01, 0x02}, data)
+	assert.True(t, d.IsPartitionHead(data))
+}
@@ -0,0 +1,198 @@
+// Package h264 implements conversions between the H264 byte stream formats
+// and helpers to inspect NAL units
+package h264
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+// NAL unit types used by the helpers, see h264reader.NalUnitType for all of them
+const (
+	naluTypeBitmask = 0x1F
+
+	naluTypeIDR = 5
+	naluTypeSPS = 7
+	naluTypePPS = 8
+)
+
+var (
+	errInvalidLengthSize = errors.New("AVCC length size must be 1, 2 or 4")
+	errShortNALU         = errors.New("NAL unit is larger than the remaining data")
+	errEmptyNALU         = errors.New("NAL unit is empty")
+	errNALUTooLarge      = errors.New("NAL unit is too large for the length size")
+)
+
+func annexbNALUStartCode() []byte { return []byte{0x00, 0x00, 0x00, 0x01} }
+
+// SplitAnnexB splits an Annex B byte stream on its start codes and returns
+// the NAL units without them. Data that doesn't start with a start code is
+// returned as a single NAL unit.
+func SplitAnnexB(data []byte) [][]byte {
+	nalus := [][]byte{}
+	start := -1
+	for i := 0; i+2 < len(data); i++ {
+		if data[i] != 0 || data[i+1] != 0 || data[i+2] != 1 {
+			continue
+		}
+
+		if start != -1 {
+			// Trailing zeros are the leading zero of a 4 byte start code
+			nalus = append(nalus, bytes.TrimRight(data[start:i], "\x00"))
+		}
+		start = i + 3
+		i += 2
+	}
+
+	if start == -1 {
+		return [][]byte{data}
+	}
+	return append(nalus, data[start:])
+}
+
+// JoinAnnexB concatenates NAL units into an Annex B byte stream with 4 byte start codes
+func JoinAnnexB(nalus [][]byte) []byte {
+	out := []byte{}
+	for _, nalu := range nalus {
+		out = append(out, annexbNALUStartCode()...)
+		out = append(out, nalu...)
+	}
+	return out
+}
+
+// SplitAVCC splits length prefixed NAL units, as used in MP4 and by most
+// platform decoders. lengthSize is the size of the length prefix in bytes,
+// which is signalled in the AVCDecoderConfigurationRecord and usually 4.
+func SplitAVCC(data []byte, lengthSize int) ([][]byte, error) {
+	if lengthSize != 1 && lengthSize != 2 && lengthSize != 4 {
+		return nil, errInvalidLengthSize
+	}
+
+	nalus := [][]byte{}
+	for len(data) > 0 {
+		if len(data) < lengthSize {
+			return nil, errShortNALU
+		}
+
+		size := 0
+		for _, b := range data[:lengthSize] {
+			size = size<<8 | int(b)
+		}
+		data = data[lengthSize:]
+
+		if size > len(data) {
+			return nil, errShortNALU
+		}
+		nalus = append(nalus, data[:size])
+		data = data[size:]
+	}
+
+	return nalus, nil
+}
+
+// JoinAVCC prefixes each NAL unit with its length in lengthSize bytes
+func JoinAVCC(nalus [][]byte, lengthSize int) ([]byte, error) {
+	if lengthSize != 1 && lengthSize != 2 && lengthSize != 4 {
+		return nil, errInvalidLengthSize
+	}
+
+	out := []byte{}
+	for _, nalu := range nalus {
+		if uint64(len(nalu)) >= uint64(1)<<(8*uint(lengthSize)) {
+			return nil, errNALUTooLarge
+		}
+
+		for i := lengthSize - 1; i >= 0; i-- {
+			out = append(out, byte(len(nalu)>>(8*uint(i))))
+		}
+		out = append(out, nalu...)
+	}
+
+	return out, nil
+}
+
+// AnnexBToAVCC converts an Annex B byte stream to NAL units with 4 byte length prefixes
+func AnnexBToAVCC(data []byte) []byte {
+	// NAL units larger than 4GB are not expected, nil is returned for them
+	out, _ := JoinAVCC(SplitAnnexB(data), 4)
+	return out
+}
+
+// AVCCToAnnexB converts length prefixed NAL units to an Annex B byte stream
+func AVCCToAnnexB(data []byte, lengthSize int) ([]byte, error) {
+	nalus, err := SplitAVCC(data, lengthSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return JoinAnnexB(nalus), nil
+}
+
+// NALUType returns the type of a NAL unit, the values are listed in h264reader.NalUnitType
+func NALUType(nalu []byte) uint8 {
+	if len(nalu) == 0 {
+		return 0
+	}
+	return nalu[0] & naluTypeBitmask
+}
+
+// ParameterSets returns the SPS and PPS NAL units of an Annex B access unit
+func ParameterSets(data []byte) (sps, pps [][]byte) {
+	for _, nalu := range SplitAnnexB(data) {
+		switch NALUType(nalu) {
+		case naluTypeSPS:
+			sps = append(sps, nalu)
+		case naluTypePPS:
+			pps = append(pps, nalu)
+		}
+	}
+
+	return sps, pps
+}
+
+// IsIDR checks if an Annex B access unit contains a slice of an IDR picture,
+// so decoding can start at it
+func IsIDR(data []byte) bool {
+	for _, nalu := range SplitAnnexB(data) {
+		if NALUType(nalu) == naluTypeIDR {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SpropParameterSets encodes parameter sets for the sprop-parameter-sets
+// fmtp parameter of RFC 6184, so they can be signalled out of band
+func SpropParameterSets(sps, pps [][]byte) string {
+	encoded := []string{}
+	for _, nalu := range append(append([][]byte{}, sps...), pps...) {
+		encoded = append(encoded, base64.StdEncoding.EncodeToString(nalu))
+	}
+
+	return strings.Join(encoded, ",")
+}
+
+// ParseSpropParameterSets decodes the sprop-parameter-sets fmtp parameter
+// of RFC 6184 to the SPS and PPS it contains
+func ParseSpropParameterSets(sprop string) (sps, pps [][]byte, err error) {
+	for _, encoded := range strings.Split(sprop, ",") {
+		nalu, decodeErr := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if decodeErr != nil {
+			return nil, nil, decodeErr
+		} else if len(nalu) == 0 {
+			return nil, nil, errEmptyNALU
+		}
+
+		switch NALUType(nalu) {
+		case naluTypeSPS:
+			sps = append(sps, nalu)
+		case naluTypePPS:
+			pps = append(pps, nalu)
+		}
+	}
+
+	return sps, pps, nil
+}
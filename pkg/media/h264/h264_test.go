@@ -0,0 +1,71 @@
+package h264
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var ( //nolint:gochecknoglobals
+	sps = []byte{0x67, 0x42, 0xC0, 0x1F}
+	pps = []byte{0x68, 0xCE, 0x3C, 0x80}
+	idr = []byte{0x65, 0x88, 0x84}
+)
+
+func TestSplitAnnexB(t *testing.T) {
+	assert.Equal(t, [][]byte{{0x67, 0x01}, {0x68}, {0x65, 0x02}},
+		SplitAnnexB([]byte{0x00, 0x00, 0x00, 0x01, 0x67, 0x01, 0x00, 0x00, 0x01, 0x68, 0x00, 0x00, 0x00, 0x01, 0x65, 0x02}))
+	assert.Equal(t, [][]byte{{0x65, 0x02}}, SplitAnnexB([]byte{0x65, 0x02}))
+
+	annexB := JoinAnnexB([][]byte{sps, pps, idr})
+	assert.Equal(t, []byte{0x00, 0x00, 0x00, 0x01, 0x67}, annexB[:5])
+	assert.Equal(t, [][]byte{sps, pps, idr}, SplitAnnexB(annexB))
+}
+
+func TestAVCC(t *testing.T) {
+	annexB := JoinAnnexB([][]byte{sps, idr})
+	avcc := AnnexBToAVCC(annexB)
+	assert.Equal(t, append(append([]byte{0x00, 0x00, 0x00, 0x04}, sps...), append([]byte{0x00, 0x00, 0x00, 0x03}, idr...)...), avcc)
+
+	converted, err := AVCCToAnnexB(avcc, 4)
+	assert.NoError(t, err)
+	assert.Equal(t, annexB, converted)
+
+	short, err := JoinAVCC([][]byte{sps, idr}, 2)
+	assert.NoError(t, err)
+	nalus, err := SplitAVCC(short, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]byte{sps, idr}, nalus)
+
+	_, err = SplitAVCC(short, 3)
+	assert.Equal(t, errInvalidLengthSize, err)
+	_, err = SplitAVCC(short[:len(short)-1], 2)
+	assert.Equal(t, errShortNALU, err)
+	_, err = JoinAVCC([][]byte{make([]byte, 256)}, 1)
+	assert.Equal(t, errNALUTooLarge, err)
+}
+
+func TestParameterSets(t *testing.T) {
+	auWithParameterSets := JoinAnnexB([][]byte{{0x09, 0xF0}, sps, pps, idr})
+
+	foundSPS, foundPPS := ParameterSets(auWithParameterSets)
+	assert.Equal(t, [][]byte{sps}, foundSPS)
+	assert.Equal(t, [][]byte{pps}, foundPPS)
+	assert.True(t, IsIDR(auWithParameterSets))
+	assert.False(t, IsIDR(JoinAnnexB([][]byte{{0x41, 0x9A}})))
+	assert.Equal(t, uint8(7), NALUType(sps))
+	assert.Equal(t, uint8(0), NALUType(nil))
+
+	sprop := SpropParameterSets(foundSPS, foundPPS)
+	assert.Equal(t, "Z0LAHw==,aM48gA==", sprop)
+
+	parsedSPS, parsedPPS, err := ParseSpropParameterSets(sprop)
+	assert.NoError(t, err)
+	assert.Equal(t, foundSPS, parsedSPS)
+	assert.Equal(t, foundPPS, parsedPPS)
+
+	_, _, err = ParseSpropParameterSets("Z0LAHw==,")
+	assert.Equal(t, errEmptyNALU, err)
+	_, _, err = ParseSpropParameterSets("not base64!")
+	assert.Error(t, err)
+}
@@ -0,0 +1,352 @@
+// Package h264packetizer implements an H264 RTP payloader and depacketizer
+// supporting all packetization modes of RFC 6184
+package h264packetizer
+
+import (
+	"encoding/binary"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pion/webrtc/v3/pkg/media/h264"
+)
+
+// PacketizationMode is the packetization-mode parameter of RFC 6184 Section 8.1
+type PacketizationMode int
+
+const (
+	// PacketizationModeSingleNAL sends every NAL unit in its own packet
+	PacketizationModeSingleNAL PacketizationMode = 0
+
+	// PacketizationModeNonInterleaved allows STAP-A aggregation and FU-A fragmentation
+	PacketizationModeNonInterleaved PacketizationMode = 1
+
+	// PacketizationModeInterleaved sends NAL units with a decoding order
+	// number, using STAP-B aggregation and FU-B fragmentation
+	PacketizationModeInterleaved PacketizationMode = 2
+)
+
+const (
+	naluTypeBitmask   = 0x1F
+	naluRefIdcBitmask = 0x60
+	naluFBitmask      = 0x80
+
+	naluTypeAUD    = 9
+	naluTypeFiller = 12
+	naluTypeSTAPA  = 24
+	naluTypeSTAPB  = 25
+	naluTypeFUA    = 28
+	naluTypeFUB    = 29
+
+	fuStartBitmask = 0x80
+	fuEndBitmask   = 0x40
+
+	fuHeaderSize   = 2
+	donSize        = 2
+	naluLengthSize = 2
+)
+
+var (
+	errNilPacket         = errors.New("invalid nil packet")
+	errShortPacket       = errors.New("packet is not large enough")
+	errUnhandledNALUType = errors.New("NALU type is currently not handled")
+)
+
+// PacketizationModeFromFmtp returns the packetization-mode of an H264 fmtp line,
+// which defaults to PacketizationModeSingleNAL if it isn't present
+func PacketizationModeFromFmtp(fmtp string) PacketizationMode {
+	for _, param := range strings.Split(fmtp, ";") {
+		keyValue := strings.SplitN(strings.TrimSpace(param), "=", 2)
+		if len(keyValue) != 2 || !strings.EqualFold(keyValue[0], "packetization-mode") {
+			continue
+		}
+
+		if mode, err := strconv.Atoi(keyValue[1]); err == nil {
+			return PacketizationMode(mode)
+		}
+	}
+
+	return PacketizationModeSingleNAL
+}
+
+// Payloader payloads H264 access units for the configured packetization mode
+type Payloader struct {
+	mode               PacketizationMode
+	aggregate          bool
+	maxAggregationSize int
+
+	// don is the decoding order number of the next NAL unit in interleaved
+	// mode, a Payloader is shared by all Tracks of a codec
+	mu  sync.Mutex
+	don uint16
+}
+
+// Option configures a Payloader
+type Option func(p *Payloader)
+
+// WithPacketizationMode sets the packetization mode, the default is
+// PacketizationModeNonInterleaved
+func WithPacketizationMode(mode PacketizationMode) Option {
+	return func(p *Payloader) {
+		p.mode = mode
+	}
+}
+
+// WithAggregation enables or disables combining NAL units of an access unit
+// into STAP-A or STAP-B packets. It is enabled by default and has no effect
+// with PacketizationModeSingleNAL.
+func WithAggregation(enabled bool) Option {
+	return func(p *Payloader) {
+		p.aggregate = enabled
+	}
+}
+
+// WithMaxAggregationSize limits the size of STAP-A and STAP-B packets
+// below the MTU. Zero, the default, only limits by the MTU.
+func WithMaxAggregationSize(size int) Option {
+	return func(p *Payloader) {
+		p.maxAggregationSize = size
+	}
+}
+
+// NewPayloader creates a new Payloader
+func NewPayloader(opts ...Option) *Payloader {
+	p := &Payloader{
+		mode:      PacketizationModeNonInterleaved,
+		aggregate: true,
+	}
+	for _, o := range opts {
+		o(p)
+	}
+
+	return p
+}
+
+// Payload fragments and aggregates the NAL units of an Annex B access unit
+// into payloads no larger than mtu. Access unit delimiters and filler data
+// are dropped. In PacketizationModeSingleNAL NAL units that don't fit mtu
+// can't be fragmented and are sent as they are.
+func (p *Payloader) Payload(mtu int, payload []byte) [][]byte {
+	payloads := [][]byte{}
+
+	nalus := [][]byte{}
+	for _, nalu := range h264.SplitAnnexB(payload) {
+		if len(nalu) == 0 {
+			continue
+		}
+		if naluType := nalu[0] & naluTypeBitmask; naluType == naluTypeAUD || naluType == naluTypeFiller {
+			continue
+		}
+		nalus = append(nalus, nalu)
+	}
+
+	if p.mode == PacketizationModeSingleNAL {
+		for _, nalu := range nalus {
+			payloads = append(payloads, append([]byte{}, nalu...))
+		}
+		return payloads
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	aggregationSize := mtu
+	if p.maxAggregationSize > 0 && p.maxAggregationSize < mtu {
+		aggregationSize = p.maxAggregationSize
+	}
+
+	// Aggregated NAL units waiting to be sent, with the DON of the first one
+	pending := [][]byte{}
+	pendingSize := 0
+	pendingDON := uint16(0)
+	flush := func() {
+		switch {
+		case len(pending) == 0:
+			return
+		case len(pending) == 1 && p.mode == PacketizationModeNonInterleaved:
+			payloads = append(payloads, append([]byte{}, pending[0]...))
+		default:
+			payloads = append(payloads, p.aggregation(pending, pendingDON))
+		}
+		pending, pendingSize = nil, 0
+	}
+
+	for _, nalu := range nalus {
+		don := p.don
+		p.don++
+
+		// Size of the NAL unit as the first one of a STAP, and as a following one
+		firstSize := 1 + naluLengthSize + len(nalu)
+		if p.mode == PacketizationModeInterleaved {
+			firstSize += donSize
+		}
+		nextSize := naluLengthSize + len(nalu)
+
+		if len(pending) != 0 && (!p.aggregate || pendingSize+nextSize > aggregationSize) {
+			flush()
+		}
+
+		switch {
+		case len(pending) != 0:
+			pending = append(pending, nalu)
+			pendingSize += nextSize
+		case p.mode == PacketizationModeNonInterleaved && len(nalu) <= mtu,
+			p.mode == PacketizationModeInterleaved && firstSize <= mtu:
+			pending = append(pending, nalu)
+			pendingSize = firstSize
+			pendingDON = don
+		default:
+			payloads = append(payloads, p.fragment(mtu, nalu, don)...)
+		}
+	}
+	flush()
+
+	return payloads
+}
+
+// aggregation builds a STAP-A, or a STAP-B in interleaved mode
+func (p *Payloader) aggregation(nalus [][]byte, don uint16) []byte {
+	header := byte(naluTypeSTAPA)
+	for _, nalu := range nalus {
+		// The F bit is set if any is set, NRI is the maximum of all NAL units
+		header |= nalu[0] & naluFBitmask
+		if nalu[0]&naluRefIdcBitmask > header&naluRefIdcBitmask {
+			header = header&^naluRefIdcBitmask | nalu[0]&naluRefIdcBitmask
+		}
+	}
+
+	out := []byte{header}
+	if p.mode == PacketizationModeInterleaved {
+		out[0] = out[0]&^naluTypeBitmask | naluTypeSTAPB
+		out = append(out, byte(don>>8), byte(don))
+	}
+
+	for _, nalu := range nalus {
+		out = append(out, byte(len(nalu)>>8), byte(len(nalu)))
+		out = append(out, nalu...)
+	}
+
+	return out
+}
+
+// fragment splits nalu into FU-A packets. In interleaved mode the first
+// fragment is a FU-B carrying the DON.
+func (p *Payloader) fragment(mtu int, nalu []byte, don uint16) [][]byte {
+	payloads := [][]byte{}
+	indicator := nalu[0]&(naluFBitmask|naluRefIdcBitmask) | naluTypeFUA
+	naluType := nalu[0] & naluTypeBitmask
+
+	// The NAL unit header is carried in the FU indicator and header
+	data := nalu[1:]
+	for first := true; len(data) > 0; first = false {
+		headerSize := fuHeaderSize
+		isFUB := first && p.mode == PacketizationModeInterleaved
+		if isFUB {
+			headerSize += donSize
+		}
+
+		size := mtu - headerSize
+		if size <= 0 {
+			return nil
+		} else if size > len(data) {
+			size = len(data)
+		}
+
+		out := make([]byte, headerSize, headerSize+size)
+		out[0] = indicator
+		out[1] = naluType
+		if first {
+			out[1] |= fuStartBitmask
+		}
+		if size == len(data) {
+			out[1] |= fuEndBitmask
+		}
+		if isFUB {
+			out[0] = out[0]&^naluTypeBitmask | naluTypeFUB
+			binary.BigEndian.PutUint16(out[fuHeaderSize:], don)
+		}
+
+		payloads = append(payloads, append(out, data[:size]...))
+		data = data[size:]
+	}
+
+	return payloads
+}
+
+// Depacketizer converts H264 RTP payloads of any packetization mode to
+// Annex B. Fragments are returned as they are received and are joined by
+// concatenating them, like the SampleBuilder does. Decoding order numbers
+// are dropped, packets are expected to arrive in decoding order.
+type Depacketizer struct{}
+
+func annexbNALUStartCode() []byte { return []byte{0x00, 0x00, 0x00, 0x01} }
+
+// Unmarshal parses the payload of an RTP packet and returns its NAL units
+func (d *Depacketizer) Unmarshal(payload []byte) ([]byte, error) {
+	if payload == nil {
+		return nil, errNilPacket
+	} else if len(payload) < 2 {
+		return nil, errShortPacket
+	}
+
+	naluType := payload[0] & naluTypeBitmask
+	switch {
+	case naluType > 0 && naluType < naluTypeSTAPA:
+		return append(annexbNALUStartCode(), payload...), nil
+
+	case naluType == naluTypeSTAPA || naluType == naluTypeSTAPB:
+		offset := 1
+		if naluType == naluTypeSTAPB {
+			offset += donSize
+		}
+
+		result := []byte{}
+		for offset+naluLengthSize <= len(payload) {
+			naluSize := int(binary.BigEndian.Uint16(payload[offset:]))
+			offset += naluLengthSize
+			if offset+naluSize > len(payload) {
+				return nil, errShortPacket
+			}
+
+			result = append(result, annexbNALUStartCode()...)
+			result = append(result, payload[offset:offset+naluSize]...)
+			offset += naluSize
+		}
+		return result, nil
+
+	case naluType == naluTypeFUA || naluType == naluTypeFUB:
+		offset := fuHeaderSize
+		if naluType == naluTypeFUB {
+			offset += donSize
+		}
+		if len(payload) < offset {
+			return nil, errShortPacket
+		}
+
+		if payload[1]&fuStartBitmask == 0 {
+			return append([]byte{}, payload[offset:]...), nil
+		}
+
+		header := payload[0]&(naluFBitmask|naluRefIdcBitmask) | payload[1]&naluTypeBitmask
+		result := append(annexbNALUStartCode(), header)
+		return append(result, payload[offset:]...), nil
+	}
+
+	return nil, errUnhandledNALUType
+}
+
+// IsPartitionHead checks if this is the start of a NAL unit, either
+// a complete one, an aggregation or the first fragment
+func (d *Depacketizer) IsPartitionHead(payload []byte) bool {
+	if len(payload) < 2 {
+		return false
+	}
+
+	naluType := payload[0] & naluTypeBitmask
+	if naluType == naluTypeFUA || naluType == naluTypeFUB {
+		return payload[1]&fuStartBitmask != 0
+	}
+
+	return true
+}
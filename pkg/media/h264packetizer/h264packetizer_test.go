@@ -0,0 +1,114 @@
+package h264packetizer
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var ( //nolint:gochecknoglobals
+	sps = []byte{0x67, 0x42, 0xC0, 0x1F}
+	pps = []byte{0x68, 0xCE, 0x3C, 0x80}
+	aud = []byte{0x09, 0xF0}
+)
+
+func annexB(nalus ...[]byte) []byte {
+	out := []byte{}
+	for _, n := range nalus {
+		out = append(out, 0x00, 0x00, 0x00, 0x01)
+		out = append(out, n...)
+	}
+	return out
+}
+
+func idr(size int) []byte {
+	return append([]byte{0x65}, bytes.Repeat([]byte{0xAA}, size-1)...)
+}
+
+func depacketize(t *testing.T, payloads [][]byte) []byte {
+	d := &Depacketizer{}
+	out := []byte{}
+	for _, p := range payloads {
+		b, err := d.Unmarshal(p)
+		assert.NoError(t, err)
+		out = append(out, b...)
+	}
+	return out
+}
+
+func TestPayloader_SingleNAL(t *testing.T) {
+	p := NewPayloader(WithPacketizationMode(PacketizationModeSingleNAL))
+	payloads := p.Payload(10, annexB(aud, sps, pps, idr(20)))
+	assert.Equal(t, [][]byte{sps, pps, idr(20)}, payloads)
+}
+
+func TestPayloader_NonInterleaved(t *testing.T) {
+	p := NewPayloader()
+	payloads := p.Payload(100, annexB(aud, sps, pps, idr(150)))
+
+	// SPS and PPS are aggregated, the IDR is fragmented
+	assert.Equal(t, 3, len(payloads))
+	assert.Equal(t, append(append([]byte{0x78, 0x00, 0x04}, sps...), append([]byte{0x00, 0x04}, pps...)...), payloads[0])
+	assert.Equal(t, []byte{0x7C, 0x85}, payloads[1][:2])
+	assert.Equal(t, []byte{0x7C, 0x45}, payloads[2][:2])
+	assert.Equal(t, 100, len(payloads[1]))
+
+	assert.Equal(t, annexB(sps, pps, idr(150)), depacketize(t, payloads))
+
+	// A single NAL unit that fits isn't put into a STAP-A
+	assert.Equal(t, [][]byte{sps}, p.Payload(100, annexB(sps)))
+}
+
+func TestPayloader_AggregationControls(t *testing.T) {
+	p := NewPayloader(WithAggregation(false))
+	assert.Equal(t, [][]byte{sps, pps}, p.Payload(100, annexB(sps, pps)))
+
+	// SPS fits, adding PPS would exceed the limit
+	p = NewPayloader(WithMaxAggregationSize(10))
+	assert.Equal(t, [][]byte{sps, pps}, p.Payload(100, annexB(sps, pps)))
+
+	p = NewPayloader(WithMaxAggregationSize(13))
+	payloads := p.Payload(100, annexB(sps, pps))
+	assert.Equal(t, 1, len(payloads))
+	assert.Equal(t, 13, len(payloads[0]))
+}
+
+func TestPayloader_Interleaved(t *testing.T) {
+	p := NewPayloader(WithPacketizationMode(PacketizationModeInterleaved))
+	payloads := p.Payload(100, annexB(sps, pps, idr(150)))
+
+	// STAP-B with DON 0, then FU-B with DON 2 followed by FU-A
+	assert.Equal(t, 3, len(payloads))
+	assert.Equal(t, append(append([]byte{0x79, 0x00, 0x00, 0x00, 0x04}, sps...), append([]byte{0x00, 0x04}, pps...)...), payloads[0])
+	assert.Equal(t, []byte{0x7D, 0x85, 0x00, 0x02}, payloads[1][:4])
+	assert.Equal(t, []byte{0x7C, 0x45}, payloads[2][:2])
+	assert.Equal(t, annexB(sps, pps, idr(150)), depacketize(t, payloads))
+
+	// The DON continues across access units, single NAL units use a STAP-B
+	payloads = p.Payload(100, annexB(sps))
+	assert.Equal(t, [][]byte{append([]byte{0x79, 0x00, 0x03, 0x00, 0x04}, sps...)}, payloads)
+}
+
+func TestDepacketizer(t *testing.T) {
+	d := &Depacketizer{}
+
+	_, err := d.Unmarshal(nil)
+	assert.Equal(t, errNilPacket, err)
+	_, err = d.Unmarshal([]byte{0x78})
+	assert.Equal(t, errShortPacket, err)
+	_, err = d.Unmarshal([]byte{0x78, 0x00, 0x05, 0x67})
+	assert.Equal(t, errShortPacket, err)
+	_, err = d.Unmarshal([]byte{0x7E, 0x00})
+	assert.Equal(t, errUnhandledNALUType, err)
+
+	assert.True(t, d.IsPartitionHead([]byte{0x7D, 0x85, 0x00, 0x00}))
+	assert.False(t, d.IsPartitionHead([]byte{0x7C, 0x05}))
+	assert.True(t, d.IsPartitionHead(sps))
+}
+
+func TestPacketizationModeFromFmtp(t *testing.T) {
+	assert.Equal(t, PacketizationModeNonInterleaved, PacketizationModeFromFmtp("level-asymmetry-allowed=1;packetization-mode=1;profile-level-id=42001f"))
+	assert.Equal(t, PacketizationModeInterleaved, PacketizationModeFromFmtp("profile-level-id=42001f; packetization-mode=2"))
+	assert.Equal(t, PacketizationModeSingleNAL, PacketizationModeFromFmtp("profile-level-id=42001f"))
+}
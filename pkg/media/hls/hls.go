@@ -0,0 +1,544 @@
+// Package hls serves media as low-latency HLS (LL-HLS) with CMAF segments,
+// so a stream received over WebRTC can be watched by viewers without WebRTC
+package hls
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/rtp/codecs"
+	"github.com/pion/webrtc/v3/pkg/media"
+	"github.com/pion/webrtc/v3/pkg/media/fmp4writer"
+	"github.com/pion/webrtc/v3/pkg/media/samplebuilder"
+)
+
+const (
+	defaultPartDuration    = 200 * time.Millisecond
+	defaultSegmentDuration = 2 * time.Second
+	defaultSegmentCount    = 7
+
+	videoTimescale = 90000
+	opusTimescale  = 48000
+
+	// maxLate is how many packets the sample builders wait for reordered ones
+	maxLate = 50
+
+	// partsSegmentCount is how many complete segments list their parts in the
+	// playlist, besides the segment in progress
+	partsSegmentCount = 2
+
+	sampleIsNonSync = 0x00010000
+
+	contentTypePlaylist = "application/vnd.apple.mpegurl"
+	contentTypeMP4      = "video/mp4"
+)
+
+var (
+	errNoTracks     = errors.New("at least one track must be configured")
+	errNoVideoTrack = errors.New("no video track configured")
+	errNoAudioTrack = errors.New("no audio track configured")
+	errMuxerClosed  = errors.New("muxer closed")
+)
+
+// RTPReader is what a Muxer reads RTP packets from, like a remote webrtc.Track
+type RTPReader interface {
+	ReadRTP() (*rtp.Packet, error)
+}
+
+// part is a fragment of the fMP4 writer
+type part struct {
+	data        []byte
+	duration    time.Duration
+	independent bool
+}
+
+// segment is a sequence of parts that starts with an independent one
+type segment struct {
+	sequenceNumber uint64
+	parts          []*part
+	duration       time.Duration
+}
+
+// Muxer writes H264 and Opus samples as low-latency HLS, and serves the
+// playlist and the recent segments and parts as an http.Handler. A segment
+// starts with a keyframe once the previous one lasts the segment duration.
+type Muxer struct {
+	partDuration    time.Duration
+	segmentDuration time.Duration
+	segmentCount    int
+	writerOptions   []fmp4writer.Option
+	video, audio    bool
+	// timescales are those of the tracks by their ID
+	timescales map[uint32]uint32
+
+	// writeMu serializes the samples of the tracks to the writer
+	writeMu sync.Mutex
+	writer  *fmp4writer.FMP4Writer
+
+	mu       sync.Mutex
+	closed   bool
+	init     []byte
+	segments []*segment
+	current  *segment
+	// updated is closed and replaced when a part is added
+	updated chan struct{}
+}
+
+// Option configures a Muxer
+type Option func(m *Muxer) error
+
+// WithH264Track adds an H264 video track with the given resolution
+func WithH264Track(width, height uint16) Option {
+	return func(m *Muxer) error {
+		m.writerOptions = append(m.writerOptions, fmp4writer.WithH264Track(width, height))
+		m.video = true
+		return nil
+	}
+}
+
+// WithOpusTrack adds an Opus audio track
+func WithOpusTrack(channels uint16) Option {
+	return func(m *Muxer) error {
+		m.writerOptions = append(m.writerOptions, fmp4writer.WithOpusTrack(channels))
+		m.audio = true
+		return nil
+	}
+}
+
+// WithPartDuration sets the target duration of parts
+func WithPartDuration(d time.Duration) Option {
+	return func(m *Muxer) error {
+		m.partDuration = d
+		return nil
+	}
+}
+
+// WithSegmentDuration sets the minimum duration of segments
+func WithSegmentDuration(d time.Duration) Option {
+	return func(m *Muxer) error {
+		m.segmentDuration = d
+		return nil
+	}
+}
+
+// WithSegmentCount sets how many segments are listed in the playlist
+func WithSegmentCount(count int) Option {
+	return func(m *Muxer) error {
+		m.segmentCount = count
+		return nil
+	}
+}
+
+// NewMuxer creates a Muxer
+func NewMuxer(opts ...Option) (*Muxer, error) {
+	m := &Muxer{
+		partDuration:    defaultPartDuration,
+		segmentDuration: defaultSegmentDuration,
+		segmentCount:    defaultSegmentCount,
+		current:         &segment{},
+		updated:         make(chan struct{}),
+	}
+	for _, o := range opts {
+		if err := o(m); err != nil {
+			return nil, err
+		}
+	}
+	if !m.video && !m.audio {
+		return nil, errNoTracks
+	}
+
+	// The writer numbers the video track first
+	m.timescales = map[uint32]uint32{}
+	if m.video {
+		m.timescales[uint32(len(m.timescales)+1)] = videoTimescale
+	}
+	if m.audio {
+		m.timescales[uint32(len(m.timescales)+1)] = opusTimescale
+	}
+
+	// Without video every fragment is a part
+	writerOptions := append(m.writerOptions, fmp4writer.WithPartDuration(m.partDuration), fmp4writer.WithFragmentDuration(m.partDuration))
+	writer, err := fmp4writer.NewWith(fragmentWriterFunc(m.addFragment), writerOptions...)
+	if err != nil {
+		return nil, err
+	}
+	m.writer = writer
+	return m, nil
+}
+
+// WriteVideoSample writes an H264 access unit in Annex B format, with its
+// duration in 90kHz units
+func (m *Muxer) WriteVideoSample(s media.Sample) error {
+	m.writeMu.Lock()
+	defer m.writeMu.Unlock()
+	return m.writer.WriteVideoSample(s)
+}
+
+// WriteAudioSample writes an Opus packet, with its duration in 48kHz units
+func (m *Muxer) WriteAudioSample(s media.Sample) error {
+	m.writeMu.Lock()
+	defer m.writeMu.Unlock()
+	return m.writer.WriteAudioSample(s)
+}
+
+// ReadVideo writes the H264 RTP packets of r, until reading or writing fails
+func (m *Muxer) ReadVideo(r RTPReader) error {
+	if !m.video {
+		return errNoVideoTrack
+	}
+	return m.read(r, samplebuilder.New(maxLate, &codecs.H264Packet{}), m.WriteVideoSample)
+}
+
+// ReadAudio writes the Opus RTP packets of r, until reading or writing fails
+func (m *Muxer) ReadAudio(r RTPReader) error {
+	if !m.audio {
+		return errNoAudioTrack
+	}
+	return m.read(r, samplebuilder.New(maxLate, &codecs.OpusPacket{}), m.WriteAudioSample)
+}
+
+func (m *Muxer) read(r RTPReader, builder *samplebuilder.SampleBuilder, write func(media.Sample) error) error {
+	for {
+		packet, err := r.ReadRTP()
+		if err != nil {
+			return err
+		}
+
+		builder.Push(packet)
+		for sample := builder.Pop(); sample != nil; sample = builder.Pop() {
+			if writeErr := write(*sample); writeErr != nil {
+				return writeErr
+			}
+		}
+	}
+}
+
+// Close writes the pending samples as the last segment, and ends the playlist
+func (m *Muxer) Close() error {
+	m.writeMu.Lock()
+	err := m.writer.Close()
+	m.writeMu.Unlock()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.closed {
+		m.closed = true
+		if len(m.current.parts) != 0 {
+			m.completeSegment()
+		}
+		close(m.updated)
+	}
+	return err
+}
+
+// fragmentWriterFunc is an io.Writer of the fragments of a fMP4 writer
+type fragmentWriterFunc func([]byte) error
+
+func (f fragmentWriterFunc) Write(b []byte) (int, error) {
+	return len(b), f(b)
+}
+
+// addFragment adds a fragment of the writer as part, the first one is the
+// initialization segment
+func (m *Muxer) addFragment(b []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return errMuxerClosed
+	}
+	if m.init == nil {
+		m.init = append([]byte{}, b...)
+		return nil
+	}
+
+	p := &part{data: append([]byte{}, b...)}
+	p.duration, p.independent = m.parseFragment(b)
+
+	if p.independent && len(m.current.parts) != 0 && m.current.duration >= m.segmentDuration {
+		m.completeSegment()
+	}
+	m.current.parts = append(m.current.parts, p)
+	m.current.duration += p.duration
+
+	close(m.updated)
+	m.updated = make(chan struct{})
+	return nil
+}
+
+// completeSegment starts the next segment, it must be called with the lock
+// held
+func (m *Muxer) completeSegment() {
+	m.segments = append(m.segments, m.current)
+	if len(m.segments) > m.segmentCount {
+		m.segments = m.segments[len(m.segments)-m.segmentCount:]
+	}
+	m.current = &segment{sequenceNumber: m.current.sequenceNumber + 1}
+}
+
+// parseFragment returns the duration of a moof and mdat fragment, and if its
+// first video sample is a sync sample. Audio only fragments are independent.
+func (m *Muxer) parseFragment(b []byte) (duration time.Duration, independent bool) {
+	independent = true
+	moof := childBox(b, "moof")
+	for traf := moof; ; {
+		var payload []byte
+		if payload, traf = nextBox(traf, "traf"); payload == nil {
+			break
+		}
+
+		tfhd, trun := childBox(payload, "tfhd"), childBox(payload, "trun")
+		if len(tfhd) < 8 || len(trun) < 12 {
+			continue
+		}
+		timescale := m.timescales[binary.BigEndian.Uint32(tfhd[4:])]
+		if timescale == 0 {
+			continue
+		}
+
+		// Sample duration, size and flags of each sample follow the data offset
+		count := binary.BigEndian.Uint32(trun[4:])
+		samples := trun[12:]
+		total := uint64(0)
+		for i := uint32(0); i < count && len(samples) >= 12; i++ {
+			if i == 0 && timescale == videoTimescale {
+				independent = binary.BigEndian.Uint32(samples[8:])&sampleIsNonSync == 0
+			}
+			total += uint64(binary.BigEndian.Uint32(samples))
+			samples = samples[12:]
+		}
+
+		if d := time.Duration(total) * time.Second / time.Duration(timescale); d > duration {
+			duration = d
+		}
+	}
+	return duration, independent
+}
+
+// nextBox returns the payload of the first box of type boxType in b, and the
+// boxes after it
+func nextBox(b []byte, boxType string) (payload, rest []byte) {
+	for len(b) >= 8 {
+		size := binary.BigEndian.Uint32(b)
+		if size < 8 || int(size) > len(b) {
+			return nil, nil
+		}
+		if string(b[4:8]) == boxType {
+			return b[8:size], b[size:]
+		}
+		b = b[size:]
+	}
+	return nil, nil
+}
+
+func childBox(b []byte, boxType string) []byte {
+	payload, _ := nextBox(b, boxType)
+	return payload
+}
+
+// ServeHTTP serves the playlist "index.m3u8", the initialization segment
+// "init.mp4", segments "segment<N>.mp4" and parts "part<N>.<I>.mp4". The
+// playlist supports blocking reloads with _HLS_msn and _HLS_part.
+func (m *Muxer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := path.Base(r.URL.Path)
+	switch {
+	case name == "index.m3u8":
+		m.servePlaylist(w, r)
+	case name == "init.mp4":
+		m.mu.Lock()
+		init := m.init
+		m.mu.Unlock()
+		serveMP4(w, r, init)
+	case strings.HasPrefix(name, "segment") && strings.HasSuffix(name, ".mp4"):
+		sequenceNumber, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(name, "segment"), ".mp4"), 10, 64)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		serveMP4(w, r, m.segmentData(sequenceNumber))
+	case strings.HasPrefix(name, "part") && strings.HasSuffix(name, ".mp4"):
+		var sequenceNumber uint64
+		var index int
+		if _, err := fmt.Sscanf(name, "part%d.%d.mp4", &sequenceNumber, &index); err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		serveMP4(w, r, m.partData(sequenceNumber, index))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func serveMP4(w http.ResponseWriter, r *http.Request, data []byte) {
+	if data == nil {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", contentTypeMP4)
+	_, _ = w.Write(data)
+}
+
+// findSegment returns the segment with sequenceNumber, it must be called
+// with the lock held
+func (m *Muxer) findSegment(sequenceNumber uint64) *segment {
+	if sequenceNumber == m.current.sequenceNumber {
+		return m.current
+	}
+	for _, s := range m.segments {
+		if s.sequenceNumber == sequenceNumber {
+			return s
+		}
+	}
+	return nil
+}
+
+// segmentData returns a complete segment, the concatenation of its parts
+func (m *Muxer) segmentData(sequenceNumber uint64) []byte {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s := m.findSegment(sequenceNumber)
+	if s == nil || s == m.current {
+		return nil
+	}
+	data := []byte{}
+	for _, p := range s.parts {
+		data = append(data, p.data...)
+	}
+	return data
+}
+
+func (m *Muxer) partData(sequenceNumber uint64, index int) []byte {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s := m.findSegment(sequenceNumber)
+	if s == nil || index < 0 || index >= len(s.parts) {
+		return nil
+	}
+	return s.parts[index].data
+}
+
+// servePlaylist serves the playlist, a blocking reload waits until the
+// requested segment or part is added
+func (m *Muxer) servePlaylist(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if msn := query.Get("_HLS_msn"); msn != "" {
+		sequenceNumber, err := strconv.ParseUint(msn, 10, 64)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		index := -1
+		if p := query.Get("_HLS_part"); p != "" {
+			if index, err = strconv.Atoi(p); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		if !m.waitFor(r, sequenceNumber, index) {
+			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			return
+		}
+	}
+
+	m.mu.Lock()
+	playlist := m.playlist()
+	m.mu.Unlock()
+
+	w.Header().Set("Content-Type", contentTypePlaylist)
+	_, _ = w.Write([]byte(playlist))
+}
+
+// waitFor waits until the part index of segment sequenceNumber is added, or
+// the segment completes if index is -1. Segments more than 2 ahead aren't
+// waited for, as the spec requires.
+func (m *Muxer) waitFor(r *http.Request, sequenceNumber uint64, index int) bool {
+	timeout := time.NewTimer(3 * m.targetDuration())
+	defer timeout.Stop()
+
+	for {
+		m.mu.Lock()
+		current, updated, closed := m.current, m.updated, m.closed
+		ready := sequenceNumber < current.sequenceNumber ||
+			(sequenceNumber == current.sequenceNumber && index >= 0 && index < len(current.parts))
+		m.mu.Unlock()
+
+		switch {
+		case ready || closed:
+			return true
+		case sequenceNumber > current.sequenceNumber+2:
+			return false
+		}
+
+		select {
+		case <-updated:
+		case <-timeout.C:
+			return true
+		case <-r.Context().Done():
+			return true
+		}
+	}
+}
+
+// targetDuration is the segment duration rounded up to seconds
+func (m *Muxer) targetDuration() time.Duration {
+	return time.Duration(math.Ceil(m.segmentDuration.Seconds())) * time.Second
+}
+
+// playlist builds the media playlist, it must be called with the lock held
+func (m *Muxer) playlist() string {
+	targetDuration := m.targetDuration()
+	for _, s := range m.segments {
+		if d := time.Duration(math.Ceil(s.duration.Seconds())) * time.Second; d > targetDuration {
+			targetDuration = d
+		}
+	}
+
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "#EXTM3U\n#EXT-X-VERSION:9\n")
+	fmt.Fprintf(b, "#EXT-X-TARGETDURATION:%d\n", int(targetDuration.Seconds()))
+	fmt.Fprintf(b, "#EXT-X-SERVER-CONTROL:CAN-BLOCK-RELOAD=YES,PART-HOLD-BACK=%.3f\n", 3*m.partDuration.Seconds())
+	fmt.Fprintf(b, "#EXT-X-PART-INF:PART-TARGET=%.3f\n", m.partDuration.Seconds())
+
+	first := m.current.sequenceNumber
+	if len(m.segments) != 0 {
+		first = m.segments[0].sequenceNumber
+	}
+	fmt.Fprintf(b, "#EXT-X-MEDIA-SEQUENCE:%d\n", first)
+	if m.init != nil {
+		fmt.Fprintf(b, "#EXT-X-MAP:URI=\"init.mp4\"\n")
+	}
+
+	writeParts := func(s *segment) {
+		for i, p := range s.parts {
+			fmt.Fprintf(b, "#EXT-X-PART:DURATION=%.3f,URI=\"part%d.%d.mp4\"", p.duration.Seconds(), s.sequenceNumber, i)
+			if p.independent {
+				fmt.Fprintf(b, ",INDEPENDENT=YES")
+			}
+			fmt.Fprintf(b, "\n")
+		}
+	}
+	for i, s := range m.segments {
+		if i >= len(m.segments)-partsSegmentCount {
+			writeParts(s)
+		}
+		fmt.Fprintf(b, "#EXTINF:%.3f,\nsegment%d.mp4\n", s.duration.Seconds(), s.sequenceNumber)
+	}
+	writeParts(m.current)
+
+	if m.closed {
+		fmt.Fprintf(b, "#EXT-X-ENDLIST\n")
+	}
+	return b.String()
+}
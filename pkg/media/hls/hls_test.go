@@ -0,0 +1,145 @@
+package hls
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3/pkg/media"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	keyframe   = []byte{0x00, 0x00, 0x00, 0x01, 0x67, 0x42, 0xC0, 0x1F, 0x00, 0x00, 0x00, 0x01, 0x68, 0xCE, 0x00, 0x00, 0x00, 0x01, 0x65, 0x88}
+	interframe = []byte{0x00, 0x00, 0x00, 0x01, 0x41, 0x9A}
+)
+
+func get(m *Muxer, target string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+	return w
+}
+
+// writeVideo writes frames at 30 fps with a keyframe every second
+func writeVideo(t *testing.T, m *Muxer, from, to int) {
+	for i := from; i < to; i++ {
+		data := interframe
+		if i%30 == 0 {
+			data = keyframe
+		}
+		assert.NoError(t, m.WriteVideoSample(media.Sample{Data: data, Samples: 3000}))
+	}
+}
+
+func TestMuxer(t *testing.T) {
+	_, err := NewMuxer()
+	assert.Equal(t, errNoTracks, err)
+
+	m, err := NewMuxer(WithH264Track(640, 480), WithPartDuration(200*time.Millisecond), WithSegmentDuration(time.Second), WithSegmentCount(2))
+	assert.NoError(t, err)
+	assert.Equal(t, errNoAudioTrack, m.ReadAudio(nil))
+
+	// 3.6 seconds are 3 complete segments of 5 parts, and 2 parts of the next
+	writeVideo(t, m, 0, 108)
+
+	playlist := get(m, "/live/index.m3u8")
+	assert.Equal(t, contentTypePlaylist, playlist.Header().Get("Content-Type"))
+	assert.Equal(t, strings.Join([]string{
+		"#EXTM3U",
+		"#EXT-X-VERSION:9",
+		"#EXT-X-TARGETDURATION:1",
+		"#EXT-X-SERVER-CONTROL:CAN-BLOCK-RELOAD=YES,PART-HOLD-BACK=0.600",
+		"#EXT-X-PART-INF:PART-TARGET=0.200",
+		"#EXT-X-MEDIA-SEQUENCE:1",
+		`#EXT-X-MAP:URI="init.mp4"`,
+		`#EXT-X-PART:DURATION=0.200,URI="part1.0.mp4",INDEPENDENT=YES`,
+		`#EXT-X-PART:DURATION=0.200,URI="part1.1.mp4"`,
+		`#EXT-X-PART:DURATION=0.200,URI="part1.2.mp4"`,
+		`#EXT-X-PART:DURATION=0.200,URI="part1.3.mp4"`,
+		`#EXT-X-PART:DURATION=0.200,URI="part1.4.mp4"`,
+		"#EXTINF:1.000,",
+		"segment1.mp4",
+		`#EXT-X-PART:DURATION=0.200,URI="part2.0.mp4",INDEPENDENT=YES`,
+		`#EXT-X-PART:DURATION=0.200,URI="part2.1.mp4"`,
+		`#EXT-X-PART:DURATION=0.200,URI="part2.2.mp4"`,
+		`#EXT-X-PART:DURATION=0.200,URI="part2.3.mp4"`,
+		`#EXT-X-PART:DURATION=0.200,URI="part2.4.mp4"`,
+		"#EXTINF:1.000,",
+		"segment2.mp4",
+		`#EXT-X-PART:DURATION=0.200,URI="part3.0.mp4",INDEPENDENT=YES`,
+		`#EXT-X-PART:DURATION=0.200,URI="part3.1.mp4"`,
+		"",
+	}, "\n"), playlist.Body.String())
+
+	// A segment is the concatenation of its parts
+	assert.True(t, bytes.Contains(get(m, "/live/init.mp4").Body.Bytes(), []byte("avcC")))
+	segment := []byte{}
+	for i := 0; i < 5; i++ {
+		part := get(m, "/live/part2."+strconv.Itoa(i)+".mp4")
+		assert.Equal(t, http.StatusOK, part.Code)
+		segment = append(segment, part.Body.Bytes()...)
+	}
+	assert.Equal(t, segment, get(m, "/live/segment2.mp4").Body.Bytes())
+
+	for _, target := range []string{"/live/segment0.mp4", "/live/segment3.mp4", "/live/part3.2.mp4", "/live/other"} {
+		assert.Equal(t, http.StatusNotFound, get(m, target).Code, target)
+	}
+	assert.Equal(t, http.StatusBadRequest, get(m, "/live/index.m3u8?_HLS_msn=6").Code)
+
+	// A blocking reload waits for the part
+	done := make(chan string)
+	go func() {
+		done <- get(m, "/live/index.m3u8?_HLS_msn=3&_HLS_part=2").Body.String()
+	}()
+	select {
+	case <-done:
+		t.Fatal("blocking reload returned before the part was added")
+	case <-time.After(50 * time.Millisecond):
+	}
+	writeVideo(t, m, 108, 114)
+	assert.True(t, strings.Contains(<-done, `URI="part3.2.mp4"`))
+
+	assert.NoError(t, m.Close())
+	playlist = get(m, "/live/index.m3u8?_HLS_msn=10")
+	assert.True(t, strings.HasSuffix(playlist.Body.String(), "#EXTINF:0.800,\nsegment3.mp4\n#EXT-X-ENDLIST\n"))
+}
+
+type packetReader struct {
+	packets []*rtp.Packet
+}
+
+func (r *packetReader) ReadRTP() (*rtp.Packet, error) {
+	if len(r.packets) == 0 {
+		return nil, io.EOF
+	}
+	p := r.packets[0]
+	r.packets = r.packets[1:]
+	return p, nil
+}
+
+func TestMuxer_ReadAudio(t *testing.T) {
+	m, err := NewMuxer(WithOpusTrack(2), WithPartDuration(100*time.Millisecond))
+	assert.NoError(t, err)
+	assert.Equal(t, errNoVideoTrack, m.ReadVideo(nil))
+
+	// 20ms Opus packets, every part is independent
+	r := &packetReader{}
+	for i := 0; i < 30; i++ {
+		r.packets = append(r.packets, &rtp.Packet{
+			Header:  rtp.Header{Version: 2, SequenceNumber: uint16(i), Timestamp: uint32(i * 960)},
+			Payload: []byte{0xFC, byte(i)},
+		})
+	}
+	assert.Equal(t, io.EOF, m.ReadAudio(r))
+
+	playlist := get(m, "/index.m3u8").Body.String()
+	assert.Equal(t, 5, strings.Count(playlist, ",INDEPENDENT=YES"))
+	assert.True(t, strings.Contains(playlist, `#EXT-X-PART:DURATION=0.100,URI="part0.0.mp4",INDEPENDENT=YES`))
+	assert.NoError(t, m.Close())
+}
@@ -0,0 +1,83 @@
+// Package tstest builds MPEG transport streams for the tests of the packages
+// reading them
+package tstest
+
+import "bytes"
+
+// PIDs of the streams built by the tests
+const (
+	PIDPAT  = 0x0000
+	PIDPMT  = 0x1000
+	PIDH264 = 0x0100
+	PIDAAC  = 0x0101
+)
+
+const (
+	syncByte   = 0x47
+	packetSize = 188
+)
+
+// Packets splits payload in transport stream packets, the last one is
+// stuffed with an adaptation field
+func Packets(pid uint16, continuity *uint8, payload []byte) []byte {
+	out := []byte{}
+	for start := true; start || len(payload) > 0; start = false {
+		header := []byte{syncByte, byte(pid >> 8), byte(pid), 0x10 | *continuity&0x0F}
+		if start {
+			header[1] |= 0x40
+		}
+		*continuity++
+
+		n := len(payload)
+		if n >= packetSize-4 {
+			n = packetSize - 4
+			out = append(append(out, header...), payload[:n]...)
+		} else {
+			header[3] |= 0x20
+			stuffing := packetSize - 4 - n - 1
+			out = append(append(out, header...), byte(stuffing))
+			if stuffing > 0 {
+				out = append(out, 0x00)
+				out = append(out, bytes.Repeat([]byte{0xFF}, stuffing-1)...)
+			}
+			out = append(out, payload[:n]...)
+		}
+		payload = payload[n:]
+	}
+	return out
+}
+
+// Table returns a section with a pointer field, the CRC isn't computed
+func Table(tableID uint8, data []byte) []byte {
+	length := 5 + len(data) + 4
+	section := []byte{0x00, tableID, 0xB0 | byte(length>>8), byte(length), 0x00, 0x01, 0xC1, 0x00, 0x00}
+	return append(append(section, data...), 0x00, 0x00, 0x00, 0x00)
+}
+
+func encodeTimestamp(prefix uint8, t uint64) []byte {
+	return []byte{
+		prefix<<4 | byte(t>>29)&0x0E | 0x01,
+		byte(t >> 22),
+		byte(t>>14) | 0x01,
+		byte(t >> 7),
+		byte(t<<1) | 0x01,
+	}
+}
+
+// PES returns a video PES packet, with a DTS if it differs from the PTS. The
+// length of an unbounded packet is unknown.
+func PES(bounded bool, pts, dts uint64, data []byte) []byte {
+	header := []byte{0x80, 0x80, 0x05}
+	timestamps := encodeTimestamp(0x02, pts)
+	if dts != pts {
+		header = []byte{0x80, 0xC0, 0x0A}
+		timestamps = append(encodeTimestamp(0x03, pts), encodeTimestamp(0x01, dts)...)
+	}
+
+	length := 0
+	if bounded {
+		length = len(header) + len(timestamps) + len(data)
+	}
+	out := []byte{0x00, 0x00, 0x01, 0xE0, byte(length >> 8), byte(length)}
+	return append(append(append(out, header...), timestamps...), data...)
+}
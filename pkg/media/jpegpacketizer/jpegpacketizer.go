@@ -0,0 +1,349 @@
+// Package jpegpacketizer implements the RTP payload format for JPEG
+// compressed video of RFC 2435, as sent by MJPEG cameras
+package jpegpacketizer
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// JPEG markers
+const (
+	markerSOF0 = 0xC0
+	markerDHT  = 0xC4
+	markerSOI  = 0xD8
+	markerEOI  = 0xD9
+	markerSOS  = 0xDA
+	markerDQT  = 0xDB
+	markerDRI  = 0xDD
+)
+
+const (
+	mainHeaderSize         = 8
+	restartHeaderSize      = 4
+	quantizationHeaderSize = 4
+
+	// typeRestartMarkers is added to the type if the scan has restart markers
+	typeRestartMarkers = 64
+
+	// qDynamicTables is the Q value for tables sent in the quantization header
+	qDynamicTables = 255
+
+	maxDimension      = 2040
+	maxFragmentOffset = 1<<24 - 1
+)
+
+var (
+	errNotJPEG             = errors.New("data is not a JPEG image")
+	errShortJPEG           = errors.New("JPEG image is truncated")
+	errUnsupportedJPEG     = errors.New("only baseline YUV 4:2:0 and 4:2:2 JPEG images are supported")
+	errUnsupportedSize     = errors.New("JPEG image dimensions must be multiples of 8 up to 2040")
+	errMissingTables       = errors.New("JPEG image quantization tables are missing")
+	errShortPacket         = errors.New("packet is not large enough")
+	errUnsupportedType     = errors.New("RTP JPEG type is not supported")
+	errMissingDynamicTable = errors.New("RTP JPEG quantization tables are not in the packet")
+)
+
+// frame is a JPEG image as carried by RFC 2435
+type frame struct {
+	jpegType        byte
+	width, height   uint16
+	restartInterval uint16
+
+	// Luma and chroma quantization tables, bit 0 and 1 of precision are
+	// set if they have 16 bit values
+	tables    []byte
+	precision byte
+
+	scan []byte
+}
+
+// parseJPEG extracts the parameters and entropy coded data of a baseline JPEG image
+func parseJPEG(data []byte) (*frame, error) { // nolint:gocognit
+	if len(data) < 2 || data[0] != 0xFF || data[1] != markerSOI {
+		return nil, errNotJPEG
+	}
+
+	f := &frame{}
+	quantizationTables := map[byte][]byte{}
+	quantizationPrecision := map[byte]bool{}
+	var lumaTable, chromaTable byte
+	hasSOF := false
+
+	for i := 2; ; {
+		if i+4 > len(data) {
+			return nil, errShortJPEG
+		} else if data[i] != 0xFF {
+			return nil, errNotJPEG
+		} else if data[i+1] == 0xFF {
+			// Fill byte
+			i++
+			continue
+		}
+
+		marker := data[i+1]
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		if length < 2 || i+2+length > len(data) {
+			return nil, errShortJPEG
+		}
+		segment := data[i+4 : i+2+length]
+		i += 2 + length
+
+		switch {
+		case marker == markerDQT:
+			for len(segment) > 0 {
+				is16Bit := segment[0]>>4 != 0
+				size := 64
+				if is16Bit {
+					size = 128
+				}
+				if len(segment) < 1+size {
+					return nil, errShortJPEG
+				}
+
+				quantizationTables[segment[0]&0x0F] = segment[1 : 1+size]
+				quantizationPrecision[segment[0]&0x0F] = is16Bit
+				segment = segment[1+size:]
+			}
+
+		case marker == markerSOF0:
+			// Three components, the first is luma with 2x1 or 2x2 sampling
+			if len(segment) < 15 || segment[0] != 8 || segment[5] != 3 ||
+				segment[10] != 0x11 || segment[13] != 0x11 || segment[11] != segment[14] {
+				return nil, errUnsupportedJPEG
+			}
+
+			switch segment[7] {
+			case 0x21:
+				f.jpegType = 0
+			case 0x22:
+				f.jpegType = 1
+			default:
+				return nil, errUnsupportedJPEG
+			}
+
+			f.height = binary.BigEndian.Uint16(segment[1:])
+			f.width = binary.BigEndian.Uint16(segment[3:])
+			if f.width%8 != 0 || f.height%8 != 0 || f.width > maxDimension || f.height > maxDimension || f.width == 0 || f.height == 0 {
+				return nil, errUnsupportedSize
+			}
+
+			lumaTable, chromaTable = segment[8], segment[11]
+			hasSOF = true
+
+		case marker > markerSOF0 && marker <= 0xCF && marker != markerDHT && marker != 0xC8 && marker != 0xCC:
+			// Progressive, lossless and arithmetic coded frames
+			return nil, errUnsupportedJPEG
+
+		case marker == markerDRI:
+			if len(segment) < 2 {
+				return nil, errShortJPEG
+			}
+			f.restartInterval = binary.BigEndian.Uint16(segment)
+
+		case marker == markerSOS:
+			if !hasSOF {
+				return nil, errUnsupportedJPEG
+			}
+
+			luma, hasLuma := quantizationTables[lumaTable]
+			chroma, hasChroma := quantizationTables[chromaTable]
+			if !hasLuma || !hasChroma {
+				return nil, errMissingTables
+			}
+			f.tables = append(append([]byte{}, luma...), chroma...)
+			if quantizationPrecision[lumaTable] {
+				f.precision |= 1
+			}
+			if quantizationPrecision[chromaTable] {
+				f.precision |= 2
+			}
+
+			f.scan = data[i:]
+			if n := len(f.scan); n >= 2 && f.scan[n-2] == 0xFF && f.scan[n-1] == markerEOI {
+				f.scan = f.scan[:n-2]
+			}
+			return f, nil
+		}
+	}
+}
+
+// Payloader payloads JPEG images. Only baseline images with YUV 4:2:0
+// or 4:2:2 sampling and the standard Huffman tables can be sent, other
+// images are dropped. The quantization tables are sent with every image.
+type Payloader struct{}
+
+// Payload fragments a JPEG image across one or more byte arrays
+func (p *Payloader) Payload(mtu int, payload []byte) [][]byte {
+	f, err := parseJPEG(payload)
+	if err != nil || len(f.scan) == 0 || len(f.scan) > maxFragmentOffset {
+		return nil
+	}
+
+	jpegType := f.jpegType
+	if f.restartInterval != 0 {
+		jpegType += typeRestartMarkers
+	}
+
+	payloads := [][]byte{}
+	for offset := 0; offset < len(f.scan); {
+		header := []byte{
+			0, byte(offset >> 16), byte(offset >> 8), byte(offset),
+			jpegType, qDynamicTables, byte(f.width / 8), byte(f.height / 8),
+		}
+		if f.restartInterval != 0 {
+			// Restart markers aren't aligned to packets, F and L are set and the count is 0x3FFF
+			header = append(header, byte(f.restartInterval>>8), byte(f.restartInterval), 0xFF, 0xFF)
+		}
+		if offset == 0 {
+			header = append(header, 0, f.precision, byte(len(f.tables)>>8), byte(len(f.tables)))
+			header = append(header, f.tables...)
+		}
+
+		size := mtu - len(header)
+		if size <= 0 {
+			return nil
+		} else if size > len(f.scan)-offset {
+			size = len(f.scan) - offset
+		}
+
+		payloads = append(payloads, append(header, f.scan[offset:offset+size]...))
+		offset += size
+	}
+
+	return payloads
+}
+
+// Depacketizer converts RTP JPEG payloads to a JPEG image. The first
+// fragment of an image returns the JPEG headers followed by its data,
+// the following fragments return their data. The end of an image is
+// signalled by the RTP marker bit, so the EOI marker needs to be added
+// with AppendEOI once all fragments have been concatenated.
+type Depacketizer struct{}
+
+// Unmarshal parses the payload of an RTP JPEG packet
+func (d *Depacketizer) Unmarshal(payload []byte) ([]byte, error) {
+	if len(payload) < mainHeaderSize {
+		return nil, errShortPacket
+	}
+
+	offset := int(payload[1])<<16 | int(payload[2])<<8 | int(payload[3])
+	jpegType, q := payload[4], payload[5]
+	width, height := uint16(payload[6])*8, uint16(payload[7])*8
+	data := payload[mainHeaderSize:]
+
+	if jpegType&^typeRestartMarkers > 1 {
+		return nil, errUnsupportedType
+	}
+
+	restartInterval := uint16(0)
+	if jpegType >= typeRestartMarkers {
+		if len(data) < restartHeaderSize {
+			return nil, errShortPacket
+		}
+		restartInterval = binary.BigEndian.Uint16(data)
+		data = data[restartHeaderSize:]
+	}
+
+	if offset != 0 {
+		return append([]byte{}, data...), nil
+	}
+
+	tables, precision := []byte{}, byte(0)
+	if q >= 128 {
+		if len(data) < quantizationHeaderSize {
+			return nil, errShortPacket
+		}
+		precision = data[1]
+		length := int(binary.BigEndian.Uint16(data[2:]))
+		data = data[quantizationHeaderSize:]
+
+		// Tables may be sent once for all images, which isn't supported
+		if length == 0 {
+			return nil, errMissingDynamicTable
+		} else if len(data) < length {
+			return nil, errShortPacket
+		}
+		tables = data[:length]
+		data = data[length:]
+	} else {
+		tables = makeQuantizationTables(q)
+	}
+
+	lumaSize, chromaSize := 64, 64
+	if precision&1 != 0 {
+		lumaSize = 128
+	}
+	if precision&2 != 0 {
+		chromaSize = 128
+	}
+	if len(tables) < lumaSize+chromaSize {
+		return nil, errShortPacket
+	}
+
+	f := &frame{
+		jpegType:        jpegType &^ typeRestartMarkers,
+		width:           width,
+		height:          height,
+		restartInterval: restartInterval,
+		tables:          tables[:lumaSize+chromaSize],
+		precision:       precision,
+	}
+	return append(f.headers(), data...), nil
+}
+
+// IsPartitionHead checks if this is the first fragment of an image
+func (d *Depacketizer) IsPartitionHead(payload []byte) bool {
+	return len(payload) >= mainHeaderSize && payload[1] == 0 && payload[2] == 0 && payload[3] == 0
+}
+
+// AppendEOI terminates an image built from the Depacketizer with the EOI marker
+func AppendEOI(image []byte) []byte {
+	if n := len(image); n >= 2 && image[n-2] == 0xFF && image[n-1] == markerEOI {
+		return image
+	}
+	return append(image, 0xFF, markerEOI)
+}
+
+// headers builds the JPEG headers for f, as in RFC 2435 Appendix B
+func (f *frame) headers() []byte {
+	out := []byte{0xFF, markerSOI}
+	segment := func(marker byte, data ...byte) {
+		out = append(out, 0xFF, marker, byte((len(data)+2)>>8), byte(len(data)+2))
+		out = append(out, data...)
+	}
+
+	tables := f.tables
+	for id := byte(0); id < 2; id++ {
+		size := 64
+		if f.precision&(1<<id) != 0 {
+			size = 128
+		}
+		segment(markerDQT, append([]byte{byte(size/128)<<4 | id}, tables[:size]...)...)
+		tables = tables[size:]
+	}
+
+	if f.restartInterval != 0 {
+		segment(markerDRI, byte(f.restartInterval>>8), byte(f.restartInterval))
+	}
+
+	lumaSampling := byte(0x21)
+	if f.jpegType == 1 {
+		lumaSampling = 0x22
+	}
+	segment(markerSOF0,
+		8, byte(f.height>>8), byte(f.height), byte(f.width>>8), byte(f.width), 3,
+		0, lumaSampling, 0,
+		1, 0x11, 1,
+		2, 0x11, 1,
+	)
+
+	for _, table := range huffmanTables {
+		data := append([]byte{table.class<<4 | table.id}, table.codeLens[:]...)
+		segment(markerDHT, append(data, table.symbols...)...)
+	}
+
+	segment(markerSOS, 3, 0, 0x00, 1, 0x11, 2, 0x11, 0, 63, 0)
+
+	return out
+}
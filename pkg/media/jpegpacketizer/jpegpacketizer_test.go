@@ -0,0 +1,116 @@
+package jpegpacketizer
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func encodeJPEG(t *testing.T, width, height int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			img.Set(x, y, color.RGBA{R: uint8(x * 4), G: uint8(y * 4), B: 0x80, A: 0xFF})
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	assert.NoError(t, jpeg.Encode(buf, img, &jpeg.Options{Quality: 90}))
+	return buf.Bytes()
+}
+
+func TestPayloadRoundTrip(t *testing.T) {
+	original := encodeJPEG(t, 64, 48)
+
+	payloads := (&Payloader{}).Payload(200, original)
+	assert.True(t, len(payloads) > 1)
+
+	// 4:2:0 with dynamic tables, 64x48
+	assert.Equal(t, []byte{0, 0, 0, 0, 1, 255, 8, 6}, payloads[0][:mainHeaderSize])
+	assert.Equal(t, []byte{0, 0, 0, 128}, payloads[0][mainHeaderSize:mainHeaderSize+quantizationHeaderSize])
+
+	d := &Depacketizer{}
+	image := []byte{}
+	for i, p := range payloads {
+		assert.True(t, len(p) <= 200)
+		assert.Equal(t, i == 0, d.IsPartitionHead(p))
+
+		data, err := d.Unmarshal(p)
+		assert.NoError(t, err)
+		image = append(image, data...)
+	}
+	image = AppendEOI(image)
+
+	expected, err := jpeg.Decode(bytes.NewReader(original))
+	assert.NoError(t, err)
+	decoded, err := jpeg.Decode(bytes.NewReader(image))
+	assert.NoError(t, err)
+	assert.Equal(t, expected, decoded)
+
+	// The image can be sent again
+	assert.Equal(t, payloads, (&Payloader{}).Payload(200, image))
+}
+
+func TestDepacketizerStaticTables(t *testing.T) {
+	// Q of 50 scales the tables by 1, type 0 is 4:2:2
+	payload := []byte{0, 0, 0, 0, 0, 50, 2, 1, 0xAA}
+	data, err := (&Depacketizer{}).Unmarshal(payload)
+	assert.NoError(t, err)
+
+	f, err := parseJPEG(append(data, 0xFF, markerEOI))
+	assert.NoError(t, err)
+	assert.Equal(t, &frame{
+		jpegType: 0,
+		width:    16,
+		height:   8,
+		tables:   append(append([]byte{}, lumaQuantizer[:]...), chromaQuantizer[:]...),
+		scan:     []byte{0xAA},
+	}, f)
+}
+
+func TestRestartMarkers(t *testing.T) {
+	f := &frame{jpegType: 1, width: 16, height: 16, restartInterval: 4, tables: makeQuantizationTables(75), scan: []byte{0x01, 0x02}}
+	payloads := (&Payloader{}).Payload(1200, append(f.headers(), 0x01, 0x02, 0xFF, markerEOI))
+	assert.Equal(t, 1, len(payloads))
+	assert.Equal(t, byte(65), payloads[0][4])
+	assert.Equal(t, []byte{0x00, 0x04, 0xFF, 0xFF}, payloads[0][mainHeaderSize:mainHeaderSize+restartHeaderSize])
+
+	data, err := (&Depacketizer{}).Unmarshal(payloads[0])
+	assert.NoError(t, err)
+	parsed, err := parseJPEG(data)
+	assert.NoError(t, err)
+	assert.Equal(t, f, parsed)
+}
+
+func TestPayloaderUnsupported(t *testing.T) {
+	assert.Nil(t, (&Payloader{}).Payload(1200, []byte{0x00, 0x01}))
+	assert.Nil(t, (&Payloader{}).Payload(1200, encodeJPEG(t, 60, 48)))
+
+	_, err := parseJPEG(encodeJPEG(t, 60, 48))
+	assert.Equal(t, errUnsupportedSize, err)
+	_, err = parseJPEG([]byte{0xFF, markerSOI, 0xFF, 0xC2, 0x00, 0x02})
+	assert.Equal(t, errUnsupportedJPEG, err)
+	_, err = parseJPEG([]byte{0xFF, markerSOI, 0xFF, markerDQT, 0x00, 0x10})
+	assert.Equal(t, errShortJPEG, err)
+}
+
+func TestDepacketizerErrors(t *testing.T) {
+	d := &Depacketizer{}
+
+	_, err := d.Unmarshal([]byte{0, 0, 0, 0, 1, 255})
+	assert.Equal(t, errShortPacket, err)
+	_, err = d.Unmarshal([]byte{0, 0, 0, 0, 2, 50, 2, 1})
+	assert.Equal(t, errUnsupportedType, err)
+	_, err = d.Unmarshal([]byte{0, 0, 0, 0, 1, 255, 2, 1, 0, 0, 0, 0})
+	assert.Equal(t, errMissingDynamicTable, err)
+	_, err = d.Unmarshal([]byte{0, 0, 0, 0, 1, 255, 2, 1, 0, 0, 0, 128, 1})
+	assert.Equal(t, errShortPacket, err)
+
+	data, err := d.Unmarshal([]byte{0, 0, 0, 8, 65, 255, 2, 1, 0, 4, 0xFF, 0xFF, 0xAA})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0xAA}, data)
+}
@@ -5,12 +5,73 @@ import (
 	"time"
 
 	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3/pkg/colorspace"
+	"github.com/pion/webrtc/v3/pkg/videoorientation"
 )
 
 // A Sample contains encoded media and the number of samples in that media (see NSamples).
 type Sample struct {
 	Data    []byte
 	Samples uint32
+
+	// Duration is the length of the media, from which the number of samples
+	// is computed when Samples is 0. Writers carry the fraction of a sample
+	// it doesn't fill over to the next Sample, so timestamps don't drift
+	// when it isn't a whole number of samples, like 10ms at 22.05kHz.
+	Duration time.Duration
+
+	// PreviousDroppedPackets is the number of RTP packets that were lost or
+	// discarded between this Sample and the previous one. It is only set by
+	// readers of RTP, like the SampleBuilder.
+	PreviousDroppedPackets uint16
+
+	// PartialFrame is true if the Sample is a part of a frame continued by
+	// the next Sample, like a slice of a slice-based encoder. The Samples of
+	// a frame have the timestamp of the first one, and the marker bit is
+	// only set on the last packet of the last one. The Samples and Duration
+	// of the frame are taken from its first Sample.
+	PartialFrame bool
+
+	// Orientation and ColorSpace are the rotation and color space of a video
+	// frame, nil if unknown. Readers of RTP set them from the header
+	// extensions, and Track.WriteSample sends them.
+	Orientation *videoorientation.Extension
+	ColorSpace  *colorspace.Extension
+
+	// RTP is the metadata of the RTP packets the Sample was assembled from,
+	// nil if it isn't kept. The SampleBuilder sets it with WithRTPMetadata.
+	RTP *RTPMetadata
+}
+
+// RTPMetadata is the metadata of the RTP packets of a Sample, which is lost
+// when their payloads are depacketized
+type RTPMetadata struct {
+	SSRC        uint32
+	PayloadType uint8
+	Timestamp   uint32
+
+	// FirstSequenceNumber and LastSequenceNumber are the sequence numbers of
+	// the first and last packets of the Sample
+	FirstSequenceNumber uint16
+	LastSequenceNumber  uint16
+
+	// Marker is the marker bit of the last packet, which ends a video frame
+	Marker bool
+
+	// Headers are the headers of the packets in order, with their header
+	// extensions like the capture time
+	Headers []rtp.Header
+}
+
+// Extension returns the payload of the header extension with id of the
+// first packet which has it, nil if none has it
+func (m *RTPMetadata) Extension(id uint8) []byte {
+	for i := range m.Headers {
+		if payload := m.Headers[i].GetExtension(id); payload != nil {
+			return payload
+		}
+	}
+	return nil
 }
 
 // NSamples calculates the number of samples in media of length d with sampling frequency f.
@@ -20,6 +81,39 @@ func NSamples(d time.Duration, freq int) uint32 {
 	return uint32(time.Duration(freq) * d / time.Second)
 }
 
+// SampleCounter converts durations to numbers of samples at a clock rate.
+// Unlike NSamples, the fraction of a sample truncated from a duration is
+// carried over to the next one, so the sum of the samples doesn't drift from
+// the sum of the durations.
+type SampleCounter struct {
+	clockRate uint32
+
+	// remainder is the fraction of a sample carried over, in nanoseconds
+	// times the clock rate
+	remainder int64
+}
+
+// NewSampleCounter creates a SampleCounter for clockRate
+func NewSampleCounter(clockRate uint32) *SampleCounter {
+	return &SampleCounter{clockRate: clockRate}
+}
+
+// ClockRate returns the clock rate of the SampleCounter
+func (c *SampleCounter) ClockRate() uint32 {
+	return c.clockRate
+}
+
+// Samples returns the number of samples in media of length d
+func (c *SampleCounter) Samples(d time.Duration) uint32 {
+	if d <= 0 {
+		return 0
+	}
+
+	total := int64(d)*int64(c.clockRate) + c.remainder
+	c.remainder = total % int64(time.Second)
+	return uint32(total / int64(time.Second))
+}
+
 // Writer defines an interface to handle
 // the creation of media files
 type Writer interface {
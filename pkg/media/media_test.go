@@ -11,3 +11,17 @@ import (
 func TestNSamples(t *testing.T) {
 	assert.Equal(t, media.NSamples(20*time.Millisecond, 48000), uint32(48000*0.02))
 }
+
+func TestSampleCounter(t *testing.T) {
+	c := media.NewSampleCounter(22050)
+
+	// An hour of 10ms frames of 220.5 samples doesn't drift
+	total := uint32(0)
+	for i := 0; i < 100*3600; i++ {
+		samples := c.Samples(10 * time.Millisecond)
+		assert.True(t, samples == 220 || samples == 221)
+		total += samples
+	}
+	assert.Equal(t, uint32(22050*3600), total)
+	assert.Equal(t, uint32(0), c.Samples(-time.Second))
+}
@@ -0,0 +1,83 @@
+// Package pcmpacketizer implements the RTP payload formats for linear PCM
+// audio, L16 of RFC 3551 and L24 of RFC 3190
+package pcmpacketizer
+
+// Sample sizes in bytes of the linear PCM formats
+const (
+	SampleSizeL16 = 2
+	SampleSizeL24 = 3
+)
+
+// Payloader payloads interleaved big endian PCM samples. Packets are split
+// between sample frames, so every packet has a sample of every channel.
+// Trailing bytes that don't make up a sample frame are dropped.
+type Payloader struct {
+	SampleSize int
+	Channels   int
+}
+
+// Payload fragments PCM samples across one or more byte arrays
+func (p *Payloader) Payload(mtu int, payload []byte) [][]byte {
+	frameSize := p.frameSize()
+	maxSize := mtu / frameSize * frameSize
+	if maxSize <= 0 {
+		return nil
+	}
+
+	payloads := [][]byte{}
+	payload = payload[:len(payload)/frameSize*frameSize]
+	for len(payload) > 0 {
+		size := maxSize
+		if size > len(payload) {
+			size = len(payload)
+		}
+
+		payloads = append(payloads, append([]byte{}, payload[:size]...))
+		payload = payload[size:]
+	}
+
+	return payloads
+}
+
+// Samples returns the number of samples per channel in payload, which is
+// what the RTP timestamp advances by
+func (p *Payloader) Samples(payload []byte) uint32 {
+	return uint32(len(payload) / p.frameSize())
+}
+
+// frameSize returns the size in bytes of one sample of every channel
+func (p *Payloader) frameSize() int {
+	channels := p.Channels
+	if channels < 1 {
+		channels = 1
+	}
+	sampleSize := p.SampleSize
+	if sampleSize < 1 {
+		sampleSize = SampleSizeL16
+	}
+	return channels * sampleSize
+}
+
+// Depacketizer returns the PCM samples of RTP L16 and L24 payloads
+type Depacketizer struct{}
+
+// Unmarshal parses the payload of an RTP linear PCM packet
+func (d *Depacketizer) Unmarshal(payload []byte) ([]byte, error) {
+	return append([]byte{}, payload...), nil
+}
+
+// IsPartitionHead checks if this is the head of a sample, which every packet is
+func (d *Depacketizer) IsPartitionHead(payload []byte) bool {
+	return true
+}
+
+// FromLittleEndian converts little endian samples, as stored in WAV files,
+// to the big endian samples sent over RTP, in place. The conversion is its
+// own inverse.
+func FromLittleEndian(samples []byte, sampleSize int) {
+	for i := 0; i+sampleSize <= len(samples); i += sampleSize {
+		for a, b := i, i+sampleSize-1; a < b; a, b = a+1, b-1 {
+			samples[a], samples[b] = samples[b], samples[a]
+		}
+	}
+}
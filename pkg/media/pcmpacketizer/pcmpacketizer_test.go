@@ -0,0 +1,42 @@
+package pcmpacketizer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPayload(t *testing.T) {
+	p := &Payloader{SampleSize: SampleSizeL24, Channels: 2}
+	samples := []byte{
+		0x00, 0x01, 0x02, 0x10, 0x11, 0x12,
+		0x20, 0x21, 0x22, 0x30, 0x31, 0x32,
+		0x40,
+	}
+
+	// Packets are split between sample frames, the incomplete frame is dropped
+	assert.Equal(t, [][]byte{samples[:6], samples[6:12]}, p.Payload(11, samples))
+	assert.Equal(t, [][]byte{samples[:12]}, p.Payload(1200, samples))
+	assert.Nil(t, p.Payload(5, samples))
+	assert.Equal(t, uint32(2), p.Samples(samples))
+
+	// L16 mono by default
+	assert.Equal(t, [][]byte{{0x01, 0x02}, {0x03, 0x04}}, (&Payloader{}).Payload(3, []byte{0x01, 0x02, 0x03, 0x04}))
+}
+
+func TestFromLittleEndian(t *testing.T) {
+	samples := []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06}
+	FromLittleEndian(samples, SampleSizeL24)
+	assert.Equal(t, []byte{0x03, 0x02, 0x01, 0x06, 0x05, 0x04}, samples)
+
+	FromLittleEndian(samples, SampleSizeL16)
+	assert.Equal(t, []byte{0x02, 0x03, 0x06, 0x01, 0x04, 0x05}, samples)
+}
+
+func TestDepacketizer(t *testing.T) {
+	d := &Depacketizer{}
+	data, err := d.Unmarshal([]byte{0x01, 0x02})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x01, 0x02}, data)
+	assert.True(t, d.IsPartitionHead(data))
+}
@@ -0,0 +1,206 @@
+// Package playback implements pacing of media files, so they can be
+// sent in real time
+package playback
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/pion/webrtc/v3/pkg/media"
+)
+
+var (
+	errPlayerClosed       = errors.New("player closed")
+	errPlayerAlreadyStart = errors.New("Play has already been called")
+)
+
+// SampleWriter is where a Player writes Samples to, like a webrtc.Track
+type SampleWriter interface {
+	WriteSample(media.Sample) error
+}
+
+// Player reads Samples from a Source and writes them to a SampleWriter
+// at the rate they should be played back at
+type Player struct {
+	source Source
+	writer SampleWriter
+	loop   bool
+
+	mu      sync.Mutex
+	started bool
+	seekTo  *time.Duration
+	seekCh  chan struct{}
+	closed  chan struct{}
+}
+
+// Option configures a Player
+type Option func(p *Player)
+
+// WithLoop restarts playback at the start of the file when the end is reached
+func WithLoop() Option {
+	return func(p *Player) {
+		p.loop = true
+	}
+}
+
+// NewPlayer creates a Player for the given Source and SampleWriter
+func NewPlayer(source Source, writer SampleWriter, opts ...Option) *Player {
+	p := &Player{
+		source: source,
+		writer: writer,
+		seekCh: make(chan struct{}, 1),
+		closed: make(chan struct{}),
+	}
+	for _, o := range opts {
+		o(p)
+	}
+
+	return p
+}
+
+// Play writes Samples until the end of the Source is reached, or until
+// Close is called. It blocks, and can only be called once.
+func (p *Player) Play() error {
+	p.mu.Lock()
+	if p.started {
+		p.mu.Unlock()
+		return errPlayerAlreadyStart
+	}
+	p.started = true
+	p.mu.Unlock()
+
+	// start is the wall clock time at which the file position offset was played
+	start := time.Now()
+	offset := time.Duration(0)
+
+	// loopOffset is the duration of all previous iterations of the file
+	loopOffset := time.Duration(0)
+	var last, sample *Sample
+	var err error
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		if position, ok := p.takeSeek(); ok {
+			sample, err = p.seek(position)
+			if err == nil {
+				start, offset, loopOffset, last = time.Now(), sample.Timestamp, 0, nil
+			}
+		} else {
+			sample, err = p.source.NextSample()
+		}
+
+		switch {
+		case err == io.EOF && p.loop && last != nil:
+			loopOffset += last.Timestamp + last.Duration
+			last = nil
+			if err = p.source.Rewind(); err != nil {
+				return err
+			}
+			continue
+		case err == io.EOF:
+			return nil
+		case err != nil:
+			return err
+		}
+		last = sample
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(time.Until(start.Add(loopOffset + sample.Timestamp - offset)))
+
+		select {
+		case <-p.closed:
+			return nil
+		case <-p.seekCh:
+			continue
+		case <-timer.C:
+		}
+
+		if err = p.writer.WriteSample(sample.Sample); err != nil {
+			return err
+		}
+	}
+}
+
+// seek restarts the Source and returns the first Sample that is
+// still playing at position
+func (p *Player) seek(position time.Duration) (*Sample, error) {
+	if err := p.source.Rewind(); err != nil {
+		return nil, err
+	}
+
+	for {
+		sample, err := p.source.NextSample()
+		if err != nil {
+			return nil, err
+		}
+
+		if sample.Timestamp+sample.Duration > position {
+			return sample, nil
+		}
+	}
+}
+
+func (p *Player) takeSeek() (time.Duration, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.seekTo == nil {
+		return 0, false
+	}
+
+	position := *p.seekTo
+	p.seekTo = nil
+
+	// The seek is handled, drop the pending wakeup
+	select {
+	case <-p.seekCh:
+	default:
+	}
+
+	return position, true
+}
+
+// Seek continues playback at the first Sample that is still playing at
+// position. For video the remote decoder recovers at the next keyframe.
+// Seek can be called before or during Play.
+func (p *Player) Seek(position time.Duration) error {
+	select {
+	case <-p.closed:
+		return errPlayerClosed
+	default:
+	}
+
+	p.mu.Lock()
+	p.seekTo = &position
+	p.mu.Unlock()
+
+	select {
+	case p.seekCh <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// Close stops playback and makes Play return
+func (p *Player) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	select {
+	case <-p.closed:
+	default:
+		close(p.closed)
+	}
+
+	return nil
+}
@@ -0,0 +1,126 @@
+package playback
+
+import (
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pion/webrtc/v3/pkg/media"
+	"github.com/stretchr/testify/assert"
+)
+
+// sliceSource plays back samples of 20ms each
+type sliceSource struct {
+	data  []byte
+	index int
+}
+
+func (s *sliceSource) NextSample() (*Sample, error) {
+	if s.index == len(s.data) {
+		return nil, io.EOF
+	}
+
+	i := s.index
+	s.index++
+	return &Sample{
+		Sample:    media.Sample{Data: []byte{s.data[i]}, Samples: 960},
+		Timestamp: time.Duration(i) * 20 * time.Millisecond,
+		Duration:  20 * time.Millisecond,
+	}, nil
+}
+
+func (s *sliceSource) Rewind() error {
+	s.index = 0
+	return nil
+}
+
+type recordingWriter struct {
+	mu      sync.Mutex
+	start   time.Time
+	data    []byte
+	elapsed []time.Duration
+}
+
+func (r *recordingWriter) WriteSample(s media.Sample) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.data = append(r.data, s.Data...)
+	r.elapsed = append(r.elapsed, time.Since(r.start))
+	return nil
+}
+
+func (r *recordingWriter) written() []byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]byte{}, r.data...)
+}
+
+func TestPlayer_Pacing(t *testing.T) {
+	writer := &recordingWriter{start: time.Now()}
+	player := NewPlayer(&sliceSource{data: []byte{0, 1, 2, 3, 4}}, writer)
+
+	assert.NoError(t, player.Play())
+	assert.Equal(t, []byte{0, 1, 2, 3, 4}, writer.written())
+
+	for i, elapsed := range writer.elapsed {
+		assert.True(t, elapsed >= time.Duration(i)*20*time.Millisecond, "Sample %d written too early: %v", i, elapsed)
+	}
+
+	assert.Equal(t, errPlayerAlreadyStart, player.Play())
+}
+
+func TestPlayer_Loop(t *testing.T) {
+	writer := &recordingWriter{start: time.Now()}
+	player := NewPlayer(&sliceSource{data: []byte{0, 1}}, writer, WithLoop())
+
+	done := make(chan error)
+	go func() {
+		done <- player.Play()
+	}()
+
+	for len(writer.written()) < 5 {
+		time.Sleep(5 * time.Millisecond)
+	}
+	assert.NoError(t, player.Close())
+	assert.NoError(t, <-done)
+
+	assert.Equal(t, []byte{0, 1, 0, 1, 0}, writer.written()[:5])
+	assert.True(t, writer.elapsed[4] >= 80*time.Millisecond)
+}
+
+func TestPlayer_Seek(t *testing.T) {
+	writer := &recordingWriter{start: time.Now()}
+	player := NewPlayer(&sliceSource{data: []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}}, writer)
+
+	// The sample at 60ms is still playing at 70ms
+	assert.NoError(t, player.Seek(70*time.Millisecond))
+	assert.NoError(t, player.Play())
+
+	assert.Equal(t, []byte{3, 4, 5, 6, 7, 8, 9}, writer.written())
+	assert.True(t, writer.elapsed[0] < 20*time.Millisecond, "Playback after seek should start immediately")
+
+	assert.NoError(t, player.Close())
+	assert.Equal(t, errPlayerClosed, player.Seek(0))
+}
+
+func TestPlayer_SeekWhilePlaying(t *testing.T) {
+	writer := &recordingWriter{start: time.Now()}
+	player := NewPlayer(&sliceSource{data: []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}}, writer)
+
+	done := make(chan error)
+	go func() {
+		done <- player.Play()
+	}()
+
+	for len(writer.written()) < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	assert.NoError(t, player.Seek(0))
+	assert.NoError(t, <-done)
+
+	written := writer.written()
+	assert.Equal(t, []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, written[len(written)-10:])
+}
@@ -0,0 +1,194 @@
+package playback
+
+import (
+	"bytes"
+	"io"
+	"time"
+
+	"github.com/pion/webrtc/v3/pkg/media"
+	"github.com/pion/webrtc/v3/pkg/media/ivfreader"
+	"github.com/pion/webrtc/v3/pkg/media/oggreader"
+)
+
+const (
+	videoClockRate = 90000
+	opusClockRate  = 48000
+
+	opusTagsSignature = "OpusTags"
+)
+
+// Sample is a media.Sample and its position in the file
+type Sample struct {
+	media.Sample
+
+	// Timestamp is the time from the start of the file
+	Timestamp time.Duration
+
+	// Duration is the time until the next sample
+	Duration time.Duration
+}
+
+// Source reads Samples from a file
+type Source interface {
+	// NextSample returns the next Sample, or io.EOF at the end of the file
+	NextSample() (*Sample, error)
+
+	// Rewind restarts reading at the start of the file
+	Rewind() error
+}
+
+// IVFSource reads VP8 and VP9 frames from an IVF file
+type IVFSource struct {
+	stream io.ReadSeeker
+	reader *ivfreader.IVFReader
+	header *ivfreader.IVFFileHeader
+
+	// Frames are read one ahead to learn the duration of the current frame
+	next          []byte
+	nextTimestamp uint64
+	lastTicks     uint64
+}
+
+// NewIVFSource builds a Source for the IVF file in stream
+func NewIVFSource(stream io.ReadSeeker) (*IVFSource, error) {
+	s := &IVFSource{stream: stream}
+	if err := s.Rewind(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Rewind restarts reading at the start of the file
+func (s *IVFSource) Rewind() error {
+	if _, err := s.stream.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	reader, header, err := ivfreader.NewWith(s.stream)
+	if err != nil {
+		return err
+	}
+
+	s.reader, s.header, s.next, s.lastTicks = reader, header, nil, 1
+	return s.readNext()
+}
+
+func (s *IVFSource) readNext() error {
+	frame, frameHeader, err := s.reader.ParseNextFrame()
+	switch {
+	case err == io.EOF:
+		s.next = nil
+		return nil
+	case err != nil:
+		return err
+	}
+
+	s.next, s.nextTimestamp = frame, frameHeader.Timestamp
+	return nil
+}
+
+// toDuration converts IVF timestamp units to a time.Duration
+func (s *IVFSource) toDuration(timestamp uint64) time.Duration {
+	if s.header.TimebaseDenominator == 0 {
+		return 0
+	}
+
+	return time.Duration(timestamp) * time.Second * time.Duration(s.header.TimebaseNumerator) / time.Duration(s.header.TimebaseDenominator)
+}
+
+// toSamples converts IVF timestamp units to samples at the video clock rate
+func (s *IVFSource) toSamples(timestamp uint64) uint64 {
+	if s.header.TimebaseDenominator == 0 {
+		return 0
+	}
+
+	return timestamp * videoClockRate * uint64(s.header.TimebaseNumerator) / uint64(s.header.TimebaseDenominator)
+}
+
+// NextSample returns the next frame, or io.EOF at the end of the file
+func (s *IVFSource) NextSample() (*Sample, error) {
+	if s.next == nil {
+		return nil, io.EOF
+	}
+
+	frame, timestamp := s.next, s.nextTimestamp
+	if err := s.readNext(); err != nil {
+		return nil, err
+	}
+
+	// The last frame is assumed to be as long as the one before it
+	ticks := s.lastTicks
+	if s.next != nil && s.nextTimestamp > timestamp {
+		ticks = s.nextTimestamp - timestamp
+	}
+	s.lastTicks = ticks
+
+	// The samples are the difference of the converted timestamps, so the
+	// truncated fractions don't add up
+	samples := uint32(s.toSamples(timestamp+ticks) - s.toSamples(timestamp))
+
+	return &Sample{
+		Sample:    media.Sample{Data: frame, Samples: samples},
+		Timestamp: s.toDuration(timestamp),
+		Duration:  s.toDuration(ticks),
+	}, nil
+}
+
+// OggSource reads Opus packets from an Ogg file
+type OggSource struct {
+	stream      io.ReadSeeker
+	reader      *oggreader.OggReader
+	lastGranule uint64
+}
+
+// NewOggSource builds a Source for the Ogg file in stream
+func NewOggSource(stream io.ReadSeeker) (*OggSource, error) {
+	s := &OggSource{stream: stream}
+	if err := s.Rewind(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Rewind restarts reading at the start of the file
+func (s *OggSource) Rewind() error {
+	if _, err := s.stream.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	reader, _, err := oggreader.NewWith(s.stream)
+	if err != nil {
+		return err
+	}
+
+	s.reader, s.lastGranule = reader, 0
+	return nil
+}
+
+// NextSample returns the next page, or io.EOF at the end of the file
+func (s *OggSource) NextSample() (*Sample, error) {
+	for {
+		page, pageHeader, err := s.reader.ParseNextPage()
+		if err != nil {
+			return nil, err
+		}
+
+		// The comment header doesn't contain audio
+		if bytes.HasPrefix(page, []byte(opusTagsSignature)) {
+			continue
+		}
+
+		// The granule position is the end of the page in 48kHz units
+		samples := pageHeader.GranulePosition - s.lastGranule
+		timestamp := s.lastGranule
+		s.lastGranule = pageHeader.GranulePosition
+
+		return &Sample{
+			Sample:    media.Sample{Data: page, Samples: uint32(samples)},
+			Timestamp: time.Duration(timestamp) * time.Second / opusClockRate,
+			Duration:  time.Duration(samples) * time.Second / opusClockRate,
+		}, nil
+	}
+}
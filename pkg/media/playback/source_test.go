@@ -0,0 +1,104 @@
+package playback
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3/pkg/media/oggwriter"
+	"github.com/stretchr/testify/assert"
+)
+
+func buildIVF(timestamps []uint64) []byte {
+	header := make([]byte, 32)
+	copy(header[0:], "DKIF")
+	binary.LittleEndian.PutUint16(header[6:], 32)
+	copy(header[8:], "VP80")
+	binary.LittleEndian.PutUint32(header[16:], 30) // Timebase denominator
+	binary.LittleEndian.PutUint32(header[20:], 1)  // Timebase numerator
+	binary.LittleEndian.PutUint32(header[24:], uint32(len(timestamps)))
+
+	for i, timestamp := range timestamps {
+		frameHeader := make([]byte, 12)
+		binary.LittleEndian.PutUint32(frameHeader[0:], 1)
+		binary.LittleEndian.PutUint64(frameHeader[4:], timestamp)
+		header = append(header, frameHeader...)
+		header = append(header, byte(i))
+	}
+
+	return header
+}
+
+func TestIVFSource(t *testing.T) {
+	source, err := NewIVFSource(bytes.NewReader(buildIVF([]uint64{0, 1, 3})))
+	assert.NoError(t, err)
+
+	frameDuration := time.Second / 30
+	for i := 0; i < 2; i++ {
+		sample, err := source.NextSample()
+		assert.NoError(t, err)
+		assert.Equal(t, []byte{0}, sample.Data)
+		assert.Equal(t, time.Duration(0), sample.Timestamp)
+		assert.Equal(t, frameDuration, sample.Duration)
+		assert.Equal(t, uint32(3000), sample.Samples)
+
+		sample, err = source.NextSample()
+		assert.NoError(t, err)
+		assert.Equal(t, []byte{1}, sample.Data)
+		assert.Equal(t, frameDuration, sample.Timestamp)
+		assert.Equal(t, 2*frameDuration, sample.Duration)
+		assert.Equal(t, uint32(6000), sample.Samples)
+
+		// The last frame is as long as the previous one
+		sample, err = source.NextSample()
+		assert.NoError(t, err)
+		assert.Equal(t, []byte{2}, sample.Data)
+		assert.Equal(t, 2*frameDuration, sample.Duration)
+
+		_, err = source.NextSample()
+		assert.Equal(t, io.EOF, err)
+
+		assert.NoError(t, source.Rewind())
+	}
+}
+
+func TestOggSource(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	writer, err := oggwriter.NewWith(buffer, 48000, 2)
+	assert.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, writer.WriteRTP(&rtp.Packet{
+			Header:  rtp.Header{Timestamp: 961 + uint32(i)*960},
+			Payload: []byte{byte(i)},
+		}))
+	}
+
+	source, err := NewOggSource(bytes.NewReader(buffer.Bytes()))
+	assert.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		sample, err := source.NextSample()
+		assert.NoError(t, err)
+		assert.Equal(t, []byte{0}, sample.Data)
+		assert.Equal(t, time.Duration(0), sample.Timestamp)
+
+		sample, err = source.NextSample()
+		assert.NoError(t, err)
+		assert.Equal(t, []byte{1}, sample.Data)
+		assert.Equal(t, uint32(960), sample.Samples)
+		assert.Equal(t, 20*time.Millisecond, sample.Duration)
+		assert.Equal(t, time.Duration(961)*time.Second/48000, sample.Timestamp)
+
+		_, err = source.NextSample()
+		assert.NoError(t, err)
+
+		_, err = source.NextSample()
+		assert.Equal(t, io.EOF, err)
+
+		assert.NoError(t, source.Rewind())
+	}
+}
@@ -0,0 +1,369 @@
+// Package rawvideopacketizer implements the RTP payload format for
+// uncompressed video of RFC 4175. It needs a lot of bandwidth and is
+// meant for LANs, like in broadcast and pro-AV workflows.
+package rawvideopacketizer
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pion/rtp"
+)
+
+// Samplings of RFC 4175 Section 6.1 that are supported
+const (
+	SamplingRGB      = "RGB"
+	SamplingRGBA     = "RGBA"
+	SamplingBGR      = "BGR"
+	SamplingBGRA     = "BGRA"
+	SamplingYCbCr444 = "YCbCr-4:4:4"
+	SamplingYCbCr422 = "YCbCr-4:2:2"
+)
+
+const (
+	extendedSequenceNumberSize = 2
+	lineHeaderSize             = 6
+
+	continuationBitmask = 0x80
+	maxLineNumber       = 1<<15 - 1
+	maxOffset           = 1<<15 - 1
+	maxSegmentLength    = 1<<16 - 1
+)
+
+var (
+	errUnsupportedSampling = errors.New("sampling and depth are not supported")
+	errUnsupportedSize     = errors.New("width must be a multiple of the pixel group and up to 32768 pixels, height up to 32768 lines")
+	errInterlaced          = errors.New("interlaced video is not supported")
+	errInvalidParameter    = errors.New("invalid fmtp parameter")
+	errShortPacket         = errors.New("packet is not large enough")
+	errNilPacket           = errors.New("invalid nil packet")
+	errInvalidSegment      = errors.New("line segment is outside of the frame")
+)
+
+// Format describes the uncompressed frames of a stream, frames are
+// progressive with the lines stored from top to bottom
+type Format struct {
+	Sampling    string
+	Width       int
+	Height      int
+	Depth       int
+	Colorimetry string
+}
+
+// ParseFormat parses the format of an RFC 4175 fmtp line
+func ParseFormat(fmtp string) (Format, error) {
+	f := Format{}
+	for _, param := range strings.Split(fmtp, ";") {
+		keyValue := strings.SplitN(strings.TrimSpace(param), "=", 2)
+		if len(keyValue) != 2 {
+			if strings.EqualFold(keyValue[0], "interlace") {
+				return Format{}, errInterlaced
+			}
+			continue
+		}
+
+		key, value := strings.ToLower(keyValue[0]), strings.TrimSpace(keyValue[1])
+		var err error
+		switch key {
+		case "sampling":
+			f.Sampling = value
+		case "width":
+			f.Width, err = strconv.Atoi(value)
+		case "height":
+			f.Height, err = strconv.Atoi(value)
+		case "depth":
+			f.Depth, err = strconv.Atoi(value)
+		case "colorimetry":
+			f.Colorimetry = value
+		case "interlace":
+			return Format{}, errInterlaced
+		}
+		if err != nil {
+			return Format{}, fmt.Errorf("%w: %s", errInvalidParameter, key)
+		}
+	}
+
+	if _, _, err := f.pixelGroup(); err != nil {
+		return Format{}, err
+	}
+	return f, nil
+}
+
+// Fmtp returns the fmtp line for f. The parameters aren't separated by
+// spaces, as many SDP parsers don't expect spaces in fmtp lines.
+func (f Format) Fmtp() string {
+	fmtp := fmt.Sprintf("sampling=%s;width=%d;height=%d;depth=%d", f.Sampling, f.Width, f.Height, f.Depth)
+	if f.Colorimetry != "" {
+		fmtp += ";colorimetry=" + f.Colorimetry
+	}
+	return fmtp
+}
+
+// FrameSize returns the size of a frame in bytes, or 0 if f isn't supported
+func (f Format) FrameSize() int {
+	return f.Height * f.lineSize()
+}
+
+// lineSize returns the size of a line in bytes, or 0 if f isn't supported
+func (f Format) lineSize() int {
+	size, pixels, err := f.pixelGroup()
+	if err != nil {
+		return 0
+	}
+	return f.Width / pixels * size
+}
+
+// pixelGroup returns the size in bytes and the number of pixels of the
+// smallest group of pixels that ends on an octet boundary
+func (f Format) pixelGroup() (size, pixels int, err error) {
+	switch f.Sampling {
+	case SamplingRGB, SamplingBGR, SamplingYCbCr444:
+		switch f.Depth {
+		case 8:
+			size, pixels = 3, 1
+		case 10:
+			size, pixels = 15, 4
+		case 12:
+			size, pixels = 9, 2
+		case 16:
+			size, pixels = 6, 1
+		}
+	case SamplingRGBA, SamplingBGRA:
+		switch f.Depth {
+		case 8:
+			size, pixels = 4, 1
+		case 10:
+			size, pixels = 5, 1
+		case 12:
+			size, pixels = 6, 1
+		case 16:
+			size, pixels = 8, 1
+		}
+	case SamplingYCbCr422:
+		switch f.Depth {
+		case 8:
+			size, pixels = 4, 2
+		case 10:
+			size, pixels = 5, 2
+		case 12:
+			size, pixels = 6, 2
+		case 16:
+			size, pixels = 8, 2
+		}
+	}
+
+	if size == 0 {
+		return 0, 0, errUnsupportedSampling
+	} else if f.Width <= 0 || f.Height <= 0 || f.Width%pixels != 0 || f.Width > maxOffset+1 || f.Height > maxLineNumber+1 {
+		return 0, 0, errUnsupportedSize
+	}
+	return size, pixels, nil
+}
+
+// segment is a part of a line carried in a packet, offset and length are in bytes
+type segment struct {
+	line, offset, length int
+}
+
+// Payloader payloads uncompressed frames of Format. Frames that don't
+// have the size of a frame of Format are dropped.
+//
+// The RTP sequence numbers are assigned by the packetizer, so the extended
+// sequence number of the payload header is always 0.
+type Payloader struct {
+	Format Format
+}
+
+// Payload fragments a frame into line segments across one or more byte arrays
+func (p *Payloader) Payload(mtu int, payload []byte) [][]byte {
+	pixelGroupSize, pixelGroupPixels, err := p.Format.pixelGroup()
+	if err != nil || len(payload) != p.Format.FrameSize() ||
+		mtu < extendedSequenceNumberSize+lineHeaderSize+pixelGroupSize {
+		return nil
+	}
+
+	lineSize := p.Format.lineSize()
+	maxLength := maxSegmentLength / pixelGroupSize * pixelGroupSize
+
+	payloads := [][]byte{}
+	line, offset := 0, 0
+	for line < p.Format.Height {
+		// Fill the packet with as many whole pixel groups as fit
+		segments := []segment{}
+		size := extendedSequenceNumberSize
+		for line < p.Format.Height {
+			length := (mtu - size - lineHeaderSize) / pixelGroupSize * pixelGroupSize
+			if length <= 0 {
+				break
+			}
+			if length > lineSize-offset {
+				length = lineSize - offset
+			}
+			if length > maxLength {
+				length = maxLength
+			}
+
+			segments = append(segments, segment{line: line, offset: offset, length: length})
+			size += lineHeaderSize + length
+
+			if offset += length; offset == lineSize {
+				line++
+				offset = 0
+			}
+		}
+
+		out := make([]byte, extendedSequenceNumberSize, size)
+		for i, s := range segments {
+			pixelOffset := s.offset / pixelGroupSize * pixelGroupPixels
+			continuation := byte(0)
+			if i != len(segments)-1 {
+				continuation = continuationBitmask
+			}
+
+			out = append(out,
+				byte(s.length>>8), byte(s.length),
+				byte(s.line>>8), byte(s.line),
+				continuation|byte(pixelOffset>>8), byte(pixelOffset),
+			)
+		}
+		for _, s := range segments {
+			start := s.line*lineSize + s.offset
+			out = append(out, payload[start:start+s.length]...)
+		}
+		payloads = append(payloads, out)
+	}
+
+	return payloads
+}
+
+// lineHeader is a line segment as signalled in the payload header, offset is in pixels
+type lineHeader struct {
+	length, line, offset int
+}
+
+// parse returns the line headers of a payload and the data that follows them
+func parse(payload []byte) ([]lineHeader, []byte, error) {
+	if len(payload) < extendedSequenceNumberSize {
+		return nil, nil, errShortPacket
+	}
+
+	headers := []lineHeader{}
+	offset := extendedSequenceNumberSize
+	for {
+		if offset+lineHeaderSize > len(payload) {
+			return nil, nil, errShortPacket
+		}
+
+		h := payload[offset : offset+lineHeaderSize]
+		headers = append(headers, lineHeader{
+			length: int(h[0])<<8 | int(h[1]),
+			line:   int(h[2]&0x7F)<<8 | int(h[3]),
+			offset: int(h[4]&0x7F)<<8 | int(h[5]),
+		})
+		offset += lineHeaderSize
+
+		if h[4]&continuationBitmask == 0 {
+			break
+		}
+	}
+
+	data := payload[offset:]
+	size := 0
+	for _, h := range headers {
+		size += h.length
+	}
+	if size > len(data) {
+		return nil, nil, errShortPacket
+	}
+	return headers, data, nil
+}
+
+// Depacketizer returns the line segments of RTP raw video payloads. When
+// no packets are lost or reordered, the data of the packets of a frame
+// concatenate to the frame. Use a FrameBuilder to place each line segment
+// where it belongs in the frame.
+type Depacketizer struct{}
+
+// Unmarshal parses the payload of an RTP raw video packet
+func (d *Depacketizer) Unmarshal(payload []byte) ([]byte, error) {
+	headers, data, err := parse(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	size := 0
+	for _, h := range headers {
+		size += h.length
+	}
+	return append([]byte{}, data[:size]...), nil
+}
+
+// IsPartitionHead checks if the payload starts with the first line of a frame
+func (d *Depacketizer) IsPartitionHead(payload []byte) bool {
+	headers, _, err := parse(payload)
+	return err == nil && headers[0].line == 0 && headers[0].offset == 0
+}
+
+// FrameBuilder reassembles the frames of a stream from its RTP packets
+type FrameBuilder struct {
+	format Format
+
+	frame     []byte
+	received  int
+	timestamp uint32
+}
+
+// NewFrameBuilder creates a FrameBuilder for frames of format
+func NewFrameBuilder(format Format) (*FrameBuilder, error) {
+	if _, _, err := format.pixelGroup(); err != nil {
+		return nil, err
+	}
+	return &FrameBuilder{format: format}, nil
+}
+
+// Push adds an RTP packet to the frame with its timestamp. When the last
+// packet of a frame, which has the marker bit set, is pushed the frame is
+// returned. Frames with lost packets are dropped.
+func (b *FrameBuilder) Push(packet *rtp.Packet) ([]byte, error) {
+	if packet == nil {
+		return nil, errNilPacket
+	}
+
+	headers, data, err := parse(packet.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if b.frame == nil || packet.Timestamp != b.timestamp {
+		b.frame = make([]byte, b.format.FrameSize())
+		b.received = 0
+		b.timestamp = packet.Timestamp
+	}
+
+	pixelGroupSize, pixelGroupPixels, _ := b.format.pixelGroup()
+	lineSize := b.format.lineSize()
+	for _, h := range headers {
+		offset := h.offset / pixelGroupPixels * pixelGroupSize
+		if h.line >= b.format.Height || offset+h.length > lineSize {
+			return nil, errInvalidSegment
+		}
+
+		start := h.line*lineSize + offset
+		copy(b.frame[start:start+h.length], data[:h.length])
+		data = data[h.length:]
+		b.received += h.length
+	}
+
+	if !packet.Marker {
+		return nil, nil
+	}
+
+	frame := b.frame
+	b.frame = nil
+	if b.received != len(frame) {
+		return nil, nil
+	}
+	return frame, nil
+}
@@ -0,0 +1,111 @@
+package rawvideopacketizer
+
+import (
+	"testing"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFormat(t *testing.T) {
+	f, err := ParseFormat("sampling=YCbCr-4:2:2; width=1280; height=720; depth=10; colorimetry=BT709-2")
+	assert.NoError(t, err)
+	assert.Equal(t, Format{Sampling: SamplingYCbCr422, Width: 1280, Height: 720, Depth: 10, Colorimetry: "BT709-2"}, f)
+	assert.Equal(t, "sampling=YCbCr-4:2:2;width=1280;height=720;depth=10;colorimetry=BT709-2", f.Fmtp())
+	assert.Equal(t, 1280/2*5*720, f.FrameSize())
+
+	_, err = ParseFormat("sampling=YCbCr-4:2:0; width=1280; height=720; depth=8")
+	assert.Equal(t, errUnsupportedSampling, err)
+	_, err = ParseFormat("sampling=YCbCr-4:2:2; width=1279; height=720; depth=8")
+	assert.Equal(t, errUnsupportedSize, err)
+	_, err = ParseFormat("sampling=RGB; width=1280; height=720; depth=8; interlace")
+	assert.Equal(t, errInterlaced, err)
+	_, err = ParseFormat("sampling=RGB; width=wide; height=720; depth=8")
+	assert.Error(t, err)
+}
+
+func TestPayload(t *testing.T) {
+	format := Format{Sampling: SamplingYCbCr422, Width: 4, Height: 2, Depth: 8}
+	frame := []byte{
+		0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07,
+		0x10, 0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17,
+	}
+	p := &Payloader{Format: format}
+
+	// Both lines fit in one packet
+	assert.Equal(t, [][]byte{append([]byte{
+		0x00, 0x00,
+		0x00, 0x08, 0x00, 0x00, 0x80, 0x00,
+		0x00, 0x08, 0x00, 0x01, 0x00, 0x00,
+	}, frame...)}, p.Payload(1200, frame))
+
+	// Lines are split on pixel groups
+	assert.Equal(t, [][]byte{
+		{0x00, 0x00, 0x00, 0x04, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x02, 0x03},
+		{0x00, 0x00, 0x00, 0x04, 0x00, 0x00, 0x00, 0x02, 0x04, 0x05, 0x06, 0x07},
+		{0x00, 0x00, 0x00, 0x04, 0x00, 0x01, 0x00, 0x00, 0x10, 0x11, 0x12, 0x13},
+		{0x00, 0x00, 0x00, 0x04, 0x00, 0x01, 0x00, 0x02, 0x14, 0x15, 0x16, 0x17},
+	}, p.Payload(13, frame))
+
+	assert.Nil(t, p.Payload(11, frame))
+	assert.Nil(t, p.Payload(1200, frame[1:]))
+}
+
+func TestFrameBuilder(t *testing.T) {
+	format := Format{Sampling: SamplingRGB, Width: 16, Height: 16, Depth: 8}
+	frame := make([]byte, format.FrameSize())
+	for i := range frame {
+		frame[i] = byte(i)
+	}
+
+	payloads := (&Payloader{Format: format}).Payload(100, frame)
+	assert.True(t, len(payloads) > 2)
+
+	packets := []*rtp.Packet{}
+	for i, payload := range payloads {
+		packets = append(packets, &rtp.Packet{
+			Header:  rtp.Header{Timestamp: 3000, SequenceNumber: uint16(i), Marker: i == len(payloads)-1},
+			Payload: payload,
+		})
+	}
+
+	d := &Depacketizer{}
+	concatenated := []byte{}
+	for i, payload := range payloads {
+		assert.Equal(t, i == 0, d.IsPartitionHead(payload))
+		data, err := d.Unmarshal(payload)
+		assert.NoError(t, err)
+		concatenated = append(concatenated, data...)
+	}
+	assert.Equal(t, frame, concatenated)
+
+	b, err := NewFrameBuilder(format)
+	assert.NoError(t, err)
+
+	// Reordered packets are placed in the frame
+	for i := len(packets) - 2; i >= 0; i-- {
+		out, pushErr := b.Push(packets[i])
+		assert.NoError(t, pushErr)
+		assert.Nil(t, out)
+	}
+	out, err := b.Push(packets[len(packets)-1])
+	assert.NoError(t, err)
+	assert.Equal(t, frame, out)
+
+	// Frames with lost packets are dropped
+	for _, p := range packets[1:] {
+		out, err = b.Push(p)
+		assert.NoError(t, err)
+		assert.Nil(t, out)
+	}
+
+	_, err = b.Push(nil)
+	assert.Equal(t, errNilPacket, err)
+	_, err = b.Push(&rtp.Packet{Payload: []byte{0x00, 0x00, 0x00, 0x03, 0x00, 0x10, 0x00, 0x00, 0x01, 0x02, 0x03}})
+	assert.Equal(t, errInvalidSegment, err)
+	_, err = b.Push(&rtp.Packet{Payload: []byte{0x00, 0x00, 0x00, 0x03, 0x00, 0x00, 0x00, 0x00, 0x01}})
+	assert.Equal(t, errShortPacket, err)
+
+	_, err = NewFrameBuilder(Format{Sampling: SamplingRGB, Width: 16, Height: 16})
+	assert.Equal(t, errUnsupportedSampling, err)
+}
@@ -0,0 +1,314 @@
+// Package red implements redundant encoding of Opus audio as described in RFC 2198
+package red
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/pion/rtp"
+)
+
+const (
+	blockHeaderSize   = 4
+	maxBlockLength    = 1<<10 - 1
+	maxTimestampDelta = 1<<14 - 1
+	maxDTXFrameSize   = 2
+
+	followBitmask      = 0x80
+	payloadTypeBitmask = 0x7F
+)
+
+var (
+	errShortPacket = errors.New("packet is not large enough")
+	errNilPacket   = errors.New("invalid nil packet")
+)
+
+// block is an encoding that is carried in a RED packet
+type block struct {
+	payloadType    uint8
+	timestampDelta uint32
+	payload        []byte
+}
+
+// Payloader adds up to distance previous Opus payloads to every payload,
+// so the receiver can recover lost packets. A Payloader keeps the previous
+// payloads, use Clone to create one for each Track.
+type Payloader struct {
+	primaryPayloadType uint8
+	primary            rtp.Payloader
+	distance           int
+
+	mu       sync.Mutex
+	previous []block
+}
+
+// NewPayloader creates a Payloader for Opus with payload type primaryPayloadType,
+// which is payloaded by primary
+func NewPayloader(primaryPayloadType uint8, primary rtp.Payloader, distance int) *Payloader {
+	return &Payloader{
+		primaryPayloadType: primaryPayloadType,
+		primary:            primary,
+		distance:           distance,
+	}
+}
+
+// Clone returns a Payloader with the same configuration and no previous payloads
+func (p *Payloader) Clone() *Payloader {
+	return NewPayloader(p.primaryPayloadType, p.primary, p.distance)
+}
+
+// Payload wraps the payloads of the primary payloader into RED payloads
+func (p *Payloader) Payload(mtu int, payload []byte) [][]byte {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	payloads := [][]byte{}
+	for _, primary := range p.primary.Payload(mtu-1, payload) {
+		// Add as many previous payloads as fit, the oldest first
+		redundant := []block{}
+		size := 1 + len(primary)
+		timestampDelta := uint32(0)
+		for i := len(p.previous) - 1; i >= 0; i-- {
+			timestampDelta += p.previous[i].timestampDelta
+			if timestampDelta > maxTimestampDelta || len(p.previous[i].payload) > maxBlockLength ||
+				size+blockHeaderSize+len(p.previous[i].payload) > mtu {
+				break
+			}
+
+			size += blockHeaderSize + len(p.previous[i].payload)
+			redundant = append([]block{{
+				payloadType:    p.primaryPayloadType,
+				timestampDelta: timestampDelta,
+				payload:        p.previous[i].payload,
+			}}, redundant...)
+		}
+
+		payloads = append(payloads, marshal(p.primaryPayloadType, redundant, primary))
+
+		// Previous payloads store their own duration, which is the timestamp
+		// delta to the following payload. There may be a gap after discontinuous
+		// transmission (DTX) frames, which are only up to 2 bytes.
+		duration, ok := opusDuration(primary)
+		if !ok || len(primary) <= maxDTXFrameSize {
+			p.previous = nil
+			continue
+		}
+		p.previous = append(p.previous, block{timestampDelta: duration, payload: primary})
+		if len(p.previous) > p.distance {
+			p.previous = p.previous[len(p.previous)-p.distance:]
+		}
+	}
+
+	return payloads
+}
+
+// Encoder wraps RTP packets of the primary encoding into RED packets, with
+// up to distance previous payloads. Only the payloads of the packets with
+// the preceding sequence numbers are added, so a Decoder recovers the
+// sequence numbers of lost packets. An Encoder keeps the previous payloads,
+// create one for each stream.
+type Encoder struct {
+	payloadType uint8
+	distance    int
+
+	mu       sync.Mutex
+	previous []encoded
+}
+
+// encoded is a packet of the primary encoding an Encoder sent
+type encoded struct {
+	sequenceNumber uint16
+	timestamp      uint32
+	payload        []byte
+}
+
+// NewEncoder creates an Encoder for RED with payload type payloadType
+func NewEncoder(payloadType uint8, distance int) *Encoder {
+	return &Encoder{
+		payloadType: payloadType,
+		distance:    distance,
+	}
+}
+
+// Encode returns the header and the payload of the RED packet carrying a
+// packet of the primary encoding, the previous payloads are added as long
+// as the RED payload isn't larger than mtu. The header of the caller isn't
+// modified, and the payload is copied.
+func (e *Encoder) Encode(mtu int, header *rtp.Header, payload []byte) (*rtp.Header, []byte) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	// Previous payloads are only sent for consecutive packets
+	if len(e.previous) != 0 && e.previous[len(e.previous)-1].sequenceNumber+1 != header.SequenceNumber {
+		e.previous = nil
+	}
+
+	// Add as many previous payloads as fit, the oldest first
+	redundant := []block{}
+	size := 1 + len(payload)
+	for i := len(e.previous) - 1; i >= 0; i-- {
+		timestampDelta := header.Timestamp - e.previous[i].timestamp
+		if timestampDelta > maxTimestampDelta || len(e.previous[i].payload) > maxBlockLength ||
+			size+blockHeaderSize+len(e.previous[i].payload) > mtu {
+			break
+		}
+
+		size += blockHeaderSize + len(e.previous[i].payload)
+		redundant = append([]block{{
+			payloadType:    header.PayloadType,
+			timestampDelta: timestampDelta,
+			payload:        e.previous[i].payload,
+		}}, redundant...)
+	}
+
+	e.previous = append(e.previous, encoded{
+		sequenceNumber: header.SequenceNumber,
+		timestamp:      header.Timestamp,
+		payload:        append([]byte{}, payload...),
+	})
+	if len(e.previous) > e.distance {
+		e.previous = e.previous[len(e.previous)-e.distance:]
+	}
+
+	encodedHeader := *header
+	encodedHeader.PayloadType = e.payloadType
+	return &encodedHeader, marshal(header.PayloadType, redundant, payload)
+}
+
+// marshal returns a RED payload with the redundant blocks and the primary
+// payload
+func marshal(primaryPayloadType uint8, redundant []block, primary []byte) []byte {
+	size := 1 + len(primary)
+	for _, b := range redundant {
+		size += blockHeaderSize + len(b.payload)
+	}
+
+	out := make([]byte, 0, size)
+	for _, b := range redundant {
+		out = append(out,
+			followBitmask|b.payloadType,
+			byte(b.timestampDelta>>6),
+			byte(b.timestampDelta<<2)|byte(len(b.payload)>>8),
+			byte(len(b.payload)),
+		)
+	}
+	out = append(out, primaryPayloadType&payloadTypeBitmask)
+	for _, b := range redundant {
+		out = append(out, b.payload...)
+	}
+	return append(out, primary...)
+}
+
+// opusDuration returns the duration of an Opus packet in 48kHz samples, see RFC 6716 Section 3.1
+func opusDuration(payload []byte) (uint32, bool) {
+	if len(payload) < 1 {
+		return 0, false
+	}
+
+	config := payload[0] >> 3
+	var frameSize uint32
+	switch {
+	case config < 12: // SILK
+		frameSize = []uint32{480, 960, 1920, 2880}[config%4]
+	case config < 16: // Hybrid
+		frameSize = []uint32{480, 960}[config%2]
+	default: // CELT
+		frameSize = []uint32{120, 240, 480, 960}[config%4]
+	}
+
+	switch payload[0] & 0x03 {
+	case 0:
+		return frameSize, true
+	case 1, 2:
+		return 2 * frameSize, true
+	default:
+		if len(payload) < 2 {
+			return 0, false
+		}
+		return uint32(payload[1]&0x3F) * frameSize, true
+	}
+}
+
+// parse splits a RED payload into its blocks, the primary encoding is the last one
+func parse(payload []byte) ([]block, error) {
+	blocks := []block{}
+	lengths := []int{}
+
+	offset := 0
+	for {
+		if offset >= len(payload) {
+			return nil, errShortPacket
+		}
+
+		if payload[offset]&followBitmask == 0 {
+			blocks = append(blocks, block{payloadType: payload[offset] & payloadTypeBitmask})
+			offset++
+			break
+		}
+
+		if offset+blockHeaderSize > len(payload) {
+			return nil, errShortPacket
+		}
+		blocks = append(blocks, block{
+			payloadType:    payload[offset] & payloadTypeBitmask,
+			timestampDelta: uint32(payload[offset+1])<<6 | uint32(payload[offset+2])>>2,
+		})
+		lengths = append(lengths, int(payload[offset+2]&0x03)<<8|int(payload[offset+3]))
+		offset += blockHeaderSize
+	}
+
+	for i, length := range lengths {
+		if offset+length > len(payload) {
+			return nil, errShortPacket
+		}
+		blocks[i].payload = payload[offset : offset+length]
+		offset += length
+	}
+	blocks[len(blocks)-1].payload = payload[offset:]
+
+	return blocks, nil
+}
+
+// Decoder converts RED packets back to packets of the primary codec
+type Decoder struct {
+	hasLast bool
+	lastSeq uint16
+}
+
+// Decode returns the packets carried by a RED packet, the primary encoding
+// last. Redundant encodings of packets that have been decoded before are
+// dropped, so only lost packets are recovered. Recovered packets get the
+// sequence numbers they had, assuming each packet carried one encoding.
+func (d *Decoder) Decode(packet *rtp.Packet) ([]*rtp.Packet, error) {
+	if packet == nil {
+		return nil, errNilPacket
+	}
+
+	blocks, err := parse(packet.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	packets := []*rtp.Packet{}
+	for i, b := range blocks {
+		sequenceNumber := packet.SequenceNumber - uint16(len(blocks)-1-i)
+		if d.hasLast && int16(sequenceNumber-d.lastSeq) <= 0 {
+			continue
+		}
+
+		header := packet.Header
+		header.PayloadType = b.payloadType
+		header.SequenceNumber = sequenceNumber
+		header.Timestamp = packet.Timestamp - b.timestampDelta
+		if i != len(blocks)-1 {
+			header.Marker = false
+		}
+		packets = append(packets, &rtp.Packet{Header: header, Payload: b.payload})
+	}
+
+	if len(packets) != 0 {
+		d.hasLast = true
+		d.lastSeq = packet.SequenceNumber
+	}
+	return packets, nil
+}
@@ -0,0 +1,151 @@
+package red
+
+import (
+	"testing"
+
+	"github.com/pion/rtp"
+	"github.com/pion/rtp/codecs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPayloader(t *testing.T) {
+	p := NewPayloader(111, &codecs.OpusPayloader{}, 2)
+
+	// 20ms CELT frames
+	frames := [][]byte{{0xF8, 0x01}, {0xF8, 0x02, 0x02}, {0xF8, 0x03, 0x03}, {0xF8, 0x04, 0x04}}
+
+	assert.Equal(t, [][]byte{append([]byte{111}, frames[0]...)}, p.Payload(1200, frames[0]))
+
+	// The first frame is a DTX frame, which isn't sent again
+	assert.Equal(t, [][]byte{append([]byte{111}, frames[1]...)}, p.Payload(1200, frames[1]))
+
+	assert.Equal(t, [][]byte{{
+		0x80 | 111, 0x0F, 0x00, 0x03,
+		111,
+		0xF8, 0x02, 0x02,
+		0xF8, 0x03, 0x03,
+	}}, p.Payload(1200, frames[2]))
+
+	// Only distance previous frames are sent, the oldest first
+	assert.Equal(t, [][]byte{{
+		0x80 | 111, 0x1E, 0x00, 0x03,
+		0x80 | 111, 0x0F, 0x00, 0x03,
+		111,
+		0xF8, 0x02, 0x02,
+		0xF8, 0x03, 0x03,
+		0xF8, 0x04, 0x04,
+	}}, p.Payload(1200, frames[3]))
+
+	// Previous frames that don't fit are left out
+	assert.Equal(t, [][]byte{{
+		0x80 | 111, 0x0F, 0x00, 0x03,
+		111,
+		0xF8, 0x04, 0x04,
+		0xF8, 0x05, 0x05,
+	}}, p.Payload(12, []byte{0xF8, 0x05, 0x05}))
+
+	// A clone doesn't share the previous frames
+	assert.Equal(t, [][]byte{append([]byte{111}, frames[2]...)}, p.Clone().Payload(1200, frames[2]))
+}
+
+func TestEncoder(t *testing.T) {
+	e := NewEncoder(63, 2)
+	encode := func(mtu int, sequenceNumber uint16, payload []byte) (*rtp.Header, []byte) {
+		return e.Encode(mtu, &rtp.Header{PayloadType: 111, SequenceNumber: sequenceNumber, Timestamp: 960 * uint32(sequenceNumber)}, payload)
+	}
+
+	header, payload := encode(1200, 1, []byte{0xF8, 0x01})
+	assert.Equal(t, &rtp.Header{PayloadType: 63, SequenceNumber: 1, Timestamp: 960}, header)
+	assert.Equal(t, []byte{111, 0xF8, 0x01}, payload)
+
+	// Only distance previous payloads are sent, the oldest first
+	encode(1200, 2, []byte{0xF8, 0x02})
+	_, payload = encode(1200, 3, []byte{0xF8, 0x03})
+	assert.Equal(t, []byte{
+		0x80 | 111, 0x1E, 0x00, 0x02,
+		0x80 | 111, 0x0F, 0x00, 0x02,
+		111,
+		0xF8, 0x01,
+		0xF8, 0x02,
+		0xF8, 0x03,
+	}, payload)
+
+	// Previous payloads that don't fit are left out
+	_, payload = encode(9, 4, []byte{0xF8, 0x04})
+	assert.Equal(t, []byte{0x80 | 111, 0x0F, 0x00, 0x02, 111, 0xF8, 0x03, 0xF8, 0x04}, payload)
+
+	// Previous payloads aren't sent after a gap in the sequence numbers
+	_, payload = encode(1200, 6, []byte{0xF8, 0x06})
+	assert.Equal(t, []byte{111, 0xF8, 0x06}, payload)
+
+	// Decoding recovers the packets
+	d := &Decoder{}
+	_, payload = encode(1200, 7, []byte{0xF8, 0x07})
+	decoded, err := d.Decode(&rtp.Packet{Header: rtp.Header{PayloadType: 63, SequenceNumber: 7, Timestamp: 6720}, Payload: payload})
+	assert.NoError(t, err)
+	assert.Equal(t, []*rtp.Packet{
+		{Header: rtp.Header{PayloadType: 111, SequenceNumber: 6, Timestamp: 5760}, Payload: []byte{0xF8, 0x06}},
+		{Header: rtp.Header{PayloadType: 111, SequenceNumber: 7, Timestamp: 6720}, Payload: []byte{0xF8, 0x07}},
+	}, decoded)
+}
+
+func TestDecoder(t *testing.T) {
+	p := NewPayloader(111, &codecs.OpusPayloader{}, 2)
+	d := &Decoder{}
+
+	packets := []*rtp.Packet{}
+	for i := 0; i < 4; i++ {
+		packets = append(packets, &rtp.Packet{
+			Header:  rtp.Header{PayloadType: 63, SequenceNumber: uint16(100 + i), Timestamp: uint32(960 * i), Marker: i == 0},
+			Payload: p.Payload(1200, []byte{0xF8, byte(i), byte(i)})[0],
+		})
+	}
+
+	decoded, err := d.Decode(packets[0])
+	assert.NoError(t, err)
+	assert.Equal(t, []*rtp.Packet{
+		{Header: rtp.Header{PayloadType: 111, SequenceNumber: 100, Timestamp: 0, Marker: true}, Payload: []byte{0xF8, 0x00, 0x00}},
+	}, decoded)
+
+	// Packets 101 and 102 are lost, and recovered from 103
+	decoded, err = d.Decode(packets[3])
+	assert.NoError(t, err)
+	assert.Equal(t, []*rtp.Packet{
+		{Header: rtp.Header{PayloadType: 111, SequenceNumber: 101, Timestamp: 960}, Payload: []byte{0xF8, 0x01, 0x01}},
+		{Header: rtp.Header{PayloadType: 111, SequenceNumber: 102, Timestamp: 1920}, Payload: []byte{0xF8, 0x02, 0x02}},
+		{Header: rtp.Header{PayloadType: 111, SequenceNumber: 103, Timestamp: 2880}, Payload: []byte{0xF8, 0x03, 0x03}},
+	}, decoded)
+
+	// Late packets are dropped
+	decoded, err = d.Decode(packets[2])
+	assert.NoError(t, err)
+	assert.Empty(t, decoded)
+
+	_, err = d.Decode(nil)
+	assert.Equal(t, errNilPacket, err)
+	_, err = d.Decode(&rtp.Packet{Payload: []byte{0x80 | 111, 0x0F, 0x00}})
+	assert.Equal(t, errShortPacket, err)
+	_, err = d.Decode(&rtp.Packet{Payload: []byte{0x80 | 111, 0x0F, 0x00, 0x03, 111, 0xF8}})
+	assert.Equal(t, errShortPacket, err)
+}
+
+func TestOpusDuration(t *testing.T) {
+	for _, test := range []struct {
+		payload  []byte
+		duration uint32
+		ok       bool
+	}{
+		{[]byte{0x08}, 960, true},        // SILK 20ms
+		{[]byte{0x18}, 2880, true},       // SILK 60ms
+		{[]byte{0x68}, 960, true},        // Hybrid 20ms
+		{[]byte{0x80}, 120, true},        // CELT 2.5ms
+		{[]byte{0xF9}, 1920, true},       // CELT 20ms, 2 frames
+		{[]byte{0xFB, 0x03}, 2880, true}, // CELT 20ms, 3 frames
+		{[]byte{0xFB}, 0, false},
+		{[]byte{}, 0, false},
+	} {
+		duration, ok := opusDuration(test.payload)
+		assert.Equal(t, test.duration, duration)
+		assert.Equal(t, test.ok, ok)
+	}
+}
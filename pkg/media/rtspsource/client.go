@@ -0,0 +1,254 @@
+package rtspsource
+
+import (
+	"crypto/md5" // nolint:gosec
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	rtspVersion = "RTSP/1.0"
+	userAgent   = "pion-rtspsource"
+
+	// maxBodySize limits the size of session descriptions
+	maxBodySize = 64 * 1024
+)
+
+// request sends a request and returns the header and body of its response.
+// A request that needs authentication is sent again with credentials.
+func (s *Source) request(method, target string, headers map[string]string) (textproto.MIMEHeader, []byte, error) {
+	for retried := false; ; retried = true {
+		if err := s.writeRequest(method, target, headers); err != nil {
+			return nil, nil, err
+		}
+
+		status, header, body, err := s.readResponseSkippingInterleaved()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		switch {
+		case status == http.StatusUnauthorized && !retried && s.auth.challenge(header.Get("WWW-Authenticate")):
+			continue
+		case status != http.StatusOK:
+			return nil, nil, fmt.Errorf("%w: %s %d", errUnexpectedStatus, method, status)
+		}
+		return header, body, nil
+	}
+}
+
+// writeRequest sends a request without waiting for its response
+func (s *Source) writeRequest(method, target string, headers map[string]string) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	s.cseq++
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s %s\r\nCSeq: %d\r\nUser-Agent: %s\r\n", method, target, rtspVersion, s.cseq, userAgent)
+	if authorization := s.auth.authorization(method, target); authorization != "" {
+		fmt.Fprintf(&b, "Authorization: %s\r\n", authorization)
+	}
+
+	// Headers are sorted, so requests are the same for the same arguments
+	keys := make([]string, 0, len(headers))
+	for key := range headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(&b, "%s: %s\r\n", key, headers[key])
+	}
+	b.WriteString("\r\n")
+
+	_, err := io.WriteString(s.conn, b.String())
+	return err
+}
+
+// readResponseSkippingInterleaved reads the next response, interleaved
+// packets sent before it are dropped
+func (s *Source) readResponseSkippingInterleaved() (int, textproto.MIMEHeader, []byte, error) {
+	for {
+		marker, err := s.reader.Peek(4)
+		if err != nil {
+			return 0, nil, nil, err
+		}
+		if marker[0] != interleavedMarker {
+			break
+		}
+		if _, err = s.reader.Discard(4 + (int(marker[2])<<8 | int(marker[3]))); err != nil {
+			return 0, nil, nil, err
+		}
+	}
+
+	status, header, err := s.readResponse()
+	if err != nil {
+		return 0, nil, nil, err
+	}
+
+	length, _ := strconv.Atoi(header.Get("Content-Length"))
+	if length < 0 || length > maxBodySize {
+		return 0, nil, nil, errInvalidResponse
+	}
+	body := make([]byte, length)
+	if _, err = io.ReadFull(s.reader, body); err != nil {
+		return 0, nil, nil, err
+	}
+	return status, header, body, nil
+}
+
+// readResponse reads the status and header of a response. The body is
+// returned by readResponseSkippingInterleaved, or discarded if read here.
+func (s *Source) readResponse() (int, textproto.MIMEHeader, error) {
+	tp := textproto.NewReader(s.reader)
+	line, err := tp.ReadLine()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	parts := strings.SplitN(line, " ", 3)
+	if len(parts) < 2 || parts[0] != rtspVersion {
+		return 0, nil, errInvalidResponse
+	}
+	status, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, nil, errInvalidResponse
+	}
+
+	header, err := tp.ReadMIMEHeader()
+	if err != nil {
+		return 0, nil, err
+	}
+	return status, header, nil
+}
+
+// authenticator computes the Authorization header of requests with Basic or
+// Digest authentication (RFC 2617), once the server asked for it
+type authenticator struct {
+	user *url.Userinfo
+
+	basic bool
+	realm string
+	nonce string
+	qop   string
+	nc    int
+}
+
+// challenge handles a WWW-Authenticate header, it returns false if the
+// request can't be authenticated
+func (a *authenticator) challenge(header string) bool {
+	if a.user == nil {
+		return false
+	}
+
+	scheme := strings.SplitN(header, " ", 2)
+	switch {
+	case strings.EqualFold(scheme[0], "Basic"):
+		a.basic = true
+		return true
+	case !strings.EqualFold(scheme[0], "Digest") || len(scheme) < 2:
+		return false
+	}
+
+	params := authParams(scheme[1])
+	if algorithm, ok := params["algorithm"]; ok && !strings.EqualFold(algorithm, "MD5") {
+		return false
+	}
+	a.realm = params["realm"]
+	a.nonce = params["nonce"]
+	a.qop = ""
+	for _, qop := range strings.Split(params["qop"], ",") {
+		if strings.TrimSpace(qop) == "auth" {
+			a.qop = "auth"
+		}
+	}
+	a.nc = 0
+	return true
+}
+
+// authorization returns the Authorization header of a request, or "" before
+// a challenge
+func (a *authenticator) authorization(method, uri string) string {
+	if a.user == nil {
+		return ""
+	}
+	password, _ := a.user.Password()
+
+	switch {
+	case a.basic:
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(a.user.Username()+":"+password))
+	case a.nonce == "":
+		return ""
+	}
+
+	ha1 := md5Hex(a.user.Username() + ":" + a.realm + ":" + password)
+	ha2 := md5Hex(method + ":" + uri)
+	fields := fmt.Sprintf(`Digest username="%s", realm="%s", nonce="%s", uri="%s"`, a.user.Username(), a.realm, a.nonce, uri)
+	if a.qop == "" {
+		return fields + fmt.Sprintf(`, response="%s"`, md5Hex(ha1+":"+a.nonce+":"+ha2))
+	}
+
+	a.nc++
+	nc := fmt.Sprintf("%08x", a.nc)
+	cnonce := make([]byte, 8)
+	if _, err := rand.Read(cnonce); err != nil {
+		return ""
+	}
+	cnonceHex := hex.EncodeToString(cnonce)
+	response := md5Hex(ha1 + ":" + a.nonce + ":" + nc + ":" + cnonceHex + ":" + a.qop + ":" + ha2)
+	return fields + fmt.Sprintf(`, qop=%s, nc=%s, cnonce="%s", response="%s"`, a.qop, nc, cnonceHex, response)
+}
+
+// authParams parses the comma separated parameters of a challenge, values
+// may be quoted
+func authParams(s string) map[string]string {
+	params := map[string]string{}
+	for s = strings.TrimSpace(s); s != ""; s = strings.TrimSpace(s) {
+		eq := strings.IndexByte(s, '=')
+		if eq < 0 {
+			break
+		}
+		key := strings.ToLower(strings.TrimSpace(s[:eq]))
+		s = strings.TrimSpace(s[eq+1:])
+
+		var value string
+		if strings.HasPrefix(s, `"`) {
+			end := strings.IndexByte(s[1:], '"')
+			if end < 0 {
+				break
+			}
+			value, s = s[1:end+1], s[end+2:]
+		} else if comma := strings.IndexByte(s, ','); comma >= 0 {
+			value, s = s[:comma], s[comma:]
+		} else {
+			value, s = s, ""
+		}
+		params[key] = strings.TrimSpace(value)
+		s = strings.TrimPrefix(strings.TrimSpace(s), ",")
+	}
+	return params
+}
+
+func md5Hex(s string) string {
+	sum := md5.Sum([]byte(s)) // nolint:gosec
+	return hex.EncodeToString(sum[:])
+}
+
+// discardBody skips the body of a response read by readResponse
+func (s *Source) discardBody(header textproto.MIMEHeader) error {
+	length, _ := strconv.Atoi(header.Get("Content-Length"))
+	if length <= 0 {
+		return nil
+	}
+	_, err := io.CopyN(ioutil.Discard, s.reader, int64(length))
+	return err
+}
@@ -0,0 +1,408 @@
+// Package rtspsource pulls the H264 and PCMU streams of RTSP servers, like IP
+// cameras, and writes their RTP packets to tracks
+package rtspsource
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/sdp/v3"
+	"github.com/pion/webrtc/v3/pkg/media/h264"
+)
+
+const (
+	defaultPort        = "554"
+	dialTimeout        = 10 * time.Second
+	defaultSessionTime = 60 * time.Second
+
+	// interleavedMarker starts a RTP or RTCP packet on the connection
+	interleavedMarker = '$'
+
+	naluTypeIDR  = 5
+	naluTypeSPS  = 7
+	naluTypeSTAP = 24
+	naluTypeFUA  = 28
+)
+
+var (
+	errNoStreams         = errors.New("the server has no H264 or PCMU stream")
+	errUnexpectedStatus  = errors.New("unexpected RTSP status")
+	errInvalidResponse   = errors.New("invalid RTSP response")
+	errUnsupportedScheme = errors.New("URL scheme must be rtsp")
+)
+
+// Track is where a Source writes the RTP packets of a stream to, like a local
+// webrtc.Track. The SSRC and payload type of packets are those of the Track.
+type Track interface {
+	WriteRTP(*rtp.Packet) error
+	SSRC() uint32
+	PayloadType() uint8
+}
+
+// stream is a stream of the server played to a track
+type stream struct {
+	track   Track
+	control string
+
+	// sps and pps of the sprop-parameter-sets, sent before IDRs if the
+	// server doesn't send them
+	sps, pps             [][]byte
+	sawSPS               bool
+	sequenceNumberOffset uint16
+}
+
+// Source is a RTSP session, it plays the first H264 stream and the first
+// PCMU stream of the server to tracks. The session is interleaved on the
+// RTSP connection, so it works through NAT and firewalls.
+type Source struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	url    *url.URL
+	auth   *authenticator
+
+	writeMu sync.Mutex
+	cseq    int
+	session string
+	timeout time.Duration
+
+	// streams by their interleaved RTP channel
+	streams map[uint8]*stream
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+// Dial connects to the RTSP server of rawURL, and sets up its first H264
+// stream to be played to video and its first PCMU stream to audio. A nil
+// track skips its stream. Credentials of the URL are sent with Basic or
+// Digest authentication.
+func Dial(rawURL string, video, audio Track) (*Source, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	} else if u.Scheme != "rtsp" {
+		return nil, errUnsupportedScheme
+	}
+
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), defaultPort)
+	}
+	conn, err := net.DialTimeout("tcp", host, dialTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Source{
+		conn:    conn,
+		reader:  bufio.NewReader(conn),
+		url:     u,
+		auth:    &authenticator{user: u.User},
+		timeout: defaultSessionTime,
+		streams: map[uint8]*stream{},
+		closed:  make(chan struct{}),
+	}
+	u.User = nil
+
+	if err = s.setup(video, audio); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// setup describes the session, and sets up the streams of the tracks
+func (s *Source) setup(video, audio Track) error {
+	if _, _, err := s.request("OPTIONS", s.url.String(), nil); err != nil {
+		return err
+	}
+
+	header, body, err := s.request("DESCRIBE", s.url.String(), map[string]string{"Accept": "application/sdp"})
+	if err != nil {
+		return err
+	}
+
+	base := s.url
+	if contentBase := header.Get("Content-Base"); contentBase != "" {
+		if base, err = url.Parse(contentBase); err != nil {
+			return err
+		}
+	}
+
+	desc := sdp.SessionDescription{}
+	if err = desc.Unmarshal(body); err != nil {
+		return err
+	}
+
+	for _, m := range desc.MediaDescriptions {
+		var track Track
+		var codec string
+		switch {
+		case m.MediaName.Media == "video" && video != nil:
+			track, codec, video = video, "H264", nil
+		case m.MediaName.Media == "audio" && audio != nil:
+			track, codec, audio = audio, "PCMU", nil
+		default:
+			continue
+		}
+
+		st, ok := newStream(m, track, codec, base)
+		if !ok {
+			// The track is kept for a later stream of the codec
+			if codec == "H264" {
+				video = track
+			} else {
+				audio = track
+			}
+			continue
+		}
+
+		channel := uint8(2 * len(s.streams))
+		transport := fmt.Sprintf("RTP/AVP/TCP;unicast;interleaved=%d-%d", channel, channel+1)
+		headers := map[string]string{"Transport": transport}
+		if s.session != "" {
+			headers["Session"] = s.session
+		}
+		if header, _, err = s.request("SETUP", st.control, headers); err != nil {
+			return err
+		}
+		s.setSession(header.Get("Session"))
+		s.streams[channel] = st
+	}
+
+	if len(s.streams) == 0 {
+		return errNoStreams
+	}
+	return nil
+}
+
+// newStream creates the stream of a media description, if it has the codec
+func newStream(m *sdp.MediaDescription, track Track, codec string, base *url.URL) (*stream, bool) {
+	st := &stream{track: track, control: base.String()}
+	if control, ok := m.Attribute("control"); ok && control != "*" {
+		ref, err := url.Parse(control)
+		if err != nil {
+			return nil, false
+		}
+		// Relative controls are below the base, even if it has no trailing slash
+		if !ref.IsAbs() && !strings.HasSuffix(base.Path, "/") {
+			withSlash := *base
+			withSlash.Path += "/"
+			base = &withSlash
+		}
+		st.control = base.ResolveReference(ref).String()
+	}
+
+	for _, format := range m.MediaName.Formats {
+		// PCMU has a static payload type
+		if codec == "PCMU" && format == "0" {
+			return st, true
+		}
+
+		for _, a := range m.Attributes {
+			if a.Key != "rtpmap" || !strings.HasPrefix(a.Value, format+" ") {
+				continue
+			}
+			if !strings.EqualFold(strings.SplitN(strings.TrimPrefix(a.Value, format+" "), "/", 2)[0], codec) {
+				continue
+			}
+
+			if codec == "H264" {
+				st.sps, st.pps = spropParameterSets(m, format)
+			}
+			return st, true
+		}
+	}
+	return nil, false
+}
+
+// spropParameterSets returns the parameter sets of the fmtp of format
+func spropParameterSets(m *sdp.MediaDescription, format string) (sps, pps [][]byte) {
+	for _, a := range m.Attributes {
+		if a.Key != "fmtp" || !strings.HasPrefix(a.Value, format+" ") {
+			continue
+		}
+		for _, param := range strings.Split(strings.TrimPrefix(a.Value, format+" "), ";") {
+			if kv := strings.SplitN(strings.TrimSpace(param), "=", 2); len(kv) == 2 && kv[0] == "sprop-parameter-sets" {
+				// Invalid parameter sets are ignored, the server may send them in band
+				sps, pps, _ = h264.ParseSpropParameterSets(kv[1])
+			}
+		}
+	}
+	return sps, pps
+}
+
+// setSession keeps the session ID and the timeout of a Session header
+func (s *Source) setSession(header string) {
+	parts := strings.Split(header, ";")
+	if parts[0] == "" {
+		return
+	}
+	s.session = strings.TrimSpace(parts[0])
+
+	for _, part := range parts[1:] {
+		if kv := strings.SplitN(strings.TrimSpace(part), "=", 2); len(kv) == 2 && kv[0] == "timeout" {
+			if seconds, err := strconv.Atoi(kv[1]); err == nil && seconds > 0 {
+				s.timeout = time.Duration(seconds) * time.Second
+			}
+		}
+	}
+}
+
+// Play starts playing the streams, and writes their packets to the tracks
+// until the Source is closed or the connection fails
+func (s *Source) Play() error {
+	if _, _, err := s.request("PLAY", s.url.String(), map[string]string{"Session": s.session, "Range": "npt=0.000-"}); err != nil {
+		return err
+	}
+
+	// The session is kept alive, responses are skipped by the read loop
+	go func() {
+		ticker := time.NewTicker(s.timeout / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := s.writeRequest("GET_PARAMETER", s.url.String(), map[string]string{"Session": s.session}); err != nil {
+					return
+				}
+			case <-s.closed:
+				return
+			}
+		}
+	}()
+
+	for {
+		channel, data, err := s.readInterleaved()
+		if err != nil {
+			select {
+			case <-s.closed:
+				return nil
+			default:
+				return err
+			}
+		}
+
+		st, ok := s.streams[channel]
+		if !ok {
+			continue
+		}
+		packet := &rtp.Packet{}
+		if packet.Unmarshal(data) != nil {
+			continue
+		}
+		if err = st.write(packet); err != nil && !errors.Is(err, io.ErrClosedPipe) {
+			return err
+		}
+	}
+}
+
+// Close tears down the session and closes the connection
+func (s *Source) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		close(s.closed)
+		_ = s.writeRequest("TEARDOWN", s.url.String(), map[string]string{"Session": s.session})
+		err = s.conn.Close()
+	})
+	return err
+}
+
+// readInterleaved returns the next interleaved packet, RTSP responses are
+// skipped
+func (s *Source) readInterleaved() (uint8, []byte, error) {
+	for {
+		marker, err := s.reader.Peek(1)
+		if err != nil {
+			return 0, nil, err
+		}
+		if marker[0] != interleavedMarker {
+			_, header, responseErr := s.readResponse()
+			if responseErr != nil {
+				return 0, nil, responseErr
+			}
+			if err = s.discardBody(header); err != nil {
+				return 0, nil, err
+			}
+			continue
+		}
+
+		header := make([]byte, 4)
+		if _, err = io.ReadFull(s.reader, header); err != nil {
+			return 0, nil, err
+		}
+		data := make([]byte, binary.BigEndian.Uint16(header[2:]))
+		if _, err = io.ReadFull(s.reader, data); err != nil {
+			return 0, nil, err
+		}
+		return header[1], data, nil
+	}
+}
+
+// write writes a packet to the track, with the parameter sets before IDRs
+// that lack them
+func (st *stream) write(packet *rtp.Packet) error {
+	if st.sps != nil && len(packet.Payload) != 0 {
+		switch naluType := startedNALUType(packet.Payload); {
+		case naluType == naluTypeSPS:
+			st.sawSPS = true
+		case naluType == naluTypeIDR && !st.sawSPS:
+			parameterSets := &rtp.Packet{Header: packet.Header, Payload: stapA(append(append([][]byte{}, st.sps...), st.pps...))}
+			parameterSets.Marker = false
+			if err := st.writeRewritten(parameterSets); err != nil {
+				return err
+			}
+			st.sequenceNumberOffset++
+		case naluType == naluTypeIDR:
+			st.sawSPS = false
+		}
+	}
+
+	return st.writeRewritten(packet)
+}
+
+func (st *stream) writeRewritten(packet *rtp.Packet) error {
+	packet.SSRC = st.track.SSRC()
+	packet.PayloadType = st.track.PayloadType()
+	packet.SequenceNumber += st.sequenceNumberOffset
+	return st.track.WriteRTP(packet)
+}
+
+// startedNALUType returns the type of the NAL unit started by a H264 payload,
+// a STAP-A returns its first NAL unit. Other fragments return 0.
+func startedNALUType(payload []byte) uint8 {
+	switch naluType := payload[0] & 0x1F; naluType {
+	case naluTypeSTAP:
+		if len(payload) < 4 {
+			return 0
+		}
+		return payload[3] & 0x1F
+	case naluTypeFUA:
+		if len(payload) < 2 || payload[1]&0x80 == 0 {
+			return 0
+		}
+		return payload[1] & 0x1F
+	default:
+		return naluType
+	}
+}
+
+// stapA aggregates NAL units in a STAP-A payload
+func stapA(nalus [][]byte) []byte {
+	payload := []byte{0x78} // NRI 3, type 24
+	for _, nalu := range nalus {
+		payload = append(payload, byte(len(nalu)>>8), byte(len(nalu)))
+		payload = append(payload, nalu...)
+	}
+	return payload
+}
@@ -0,0 +1,170 @@
+package rtspsource
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+type track struct {
+	ssrc        uint32
+	payloadType uint8
+	packets     chan *rtp.Packet
+}
+
+func newTrack(ssrc uint32, payloadType uint8) *track {
+	return &track{ssrc: ssrc, payloadType: payloadType, packets: make(chan *rtp.Packet, 16)}
+}
+
+func (t *track) WriteRTP(p *rtp.Packet) error {
+	t.packets <- p
+	return nil
+}
+
+func (t *track) SSRC() uint32       { return t.ssrc }
+func (t *track) PayloadType() uint8 { return t.payloadType }
+
+const describe = "v=0\r\n" +
+	"o=- 0 0 IN IP4 127.0.0.1\r\n" +
+	"s=camera\r\n" +
+	"t=0 0\r\n" +
+	"m=video 0 RTP/AVP 96\r\n" +
+	"a=rtpmap:96 H264/90000\r\n" +
+	"a=fmtp:96 packetization-mode=1;sprop-parameter-sets=Z0IAKeKQFAe2AtwEBAaQeJEV,aM48gA==\r\n" +
+	"a=control:trackID=1\r\n" +
+	"m=audio 0 RTP/AVP 0\r\n" +
+	"a=control:trackID=2\r\n"
+
+// serve answers the requests of a client, the DESCRIBE needs Digest
+// authentication, and sends packets after the PLAY
+func serve(t *testing.T, conn net.Conn, packets map[uint8][]*rtp.Packet) []string {
+	reader := textproto.NewReader(bufio.NewReader(conn))
+	requests := []string{}
+	authenticated := false
+	for {
+		line, err := reader.ReadLine()
+		if err != nil {
+			return requests
+		}
+		header, err := reader.ReadMIMEHeader()
+		assert.NoError(t, err)
+		requests = append(requests, line+" "+header.Get("Transport"))
+
+		response := "RTSP/1.0 200 OK\r\nCSeq: " + header.Get("CSeq") + "\r\n"
+		switch method := strings.Fields(line)[0]; {
+		case method == "DESCRIBE" && !authenticated:
+			response = "RTSP/1.0 401 Unauthorized\r\nCSeq: " + header.Get("CSeq") + "\r\n" +
+				`WWW-Authenticate: Digest realm="camera", nonce="abc", qop="auth"` + "\r\n"
+			authenticated = true
+		case method == "DESCRIBE":
+			assert.True(t, strings.HasPrefix(header.Get("Authorization"), `Digest username="user", realm="camera", nonce="abc", uri="rtsp://`))
+			response += fmt.Sprintf("Content-Base: rtsp://%s/stream\r\nContent-Length: %d\r\n\r\n%s", conn.LocalAddr(), len(describe), describe)
+		case method == "SETUP":
+			response += "Session: 1234;timeout=30\r\n"
+		case method == "PLAY":
+			assert.Equal(t, "1234", header.Get("Session"))
+		}
+		if !strings.Contains(response, "\r\n\r\n") {
+			response += "\r\n"
+		}
+		_, err = conn.Write([]byte(response))
+		assert.NoError(t, err)
+
+		if strings.HasPrefix(line, "PLAY") {
+			for channel := uint8(0); channel <= 2; channel += 2 {
+				for _, p := range packets[channel] {
+					data, marshalErr := p.Marshal()
+					assert.NoError(t, marshalErr)
+					_, err = conn.Write(append([]byte{'$', channel, byte(len(data) >> 8), byte(len(data))}, data...))
+					assert.NoError(t, err)
+				}
+			}
+		}
+	}
+}
+
+func TestSource(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close() // nolint:errcheck
+
+	video := []*rtp.Packet{
+		{Header: rtp.Header{Version: 2, PayloadType: 96, SequenceNumber: 100, Timestamp: 9000, SSRC: 1}, Payload: []byte{0x7C, 0x85, 0xAA}},
+		{Header: rtp.Header{Version: 2, PayloadType: 96, SequenceNumber: 101, Timestamp: 9000, SSRC: 1, Marker: true}, Payload: []byte{0x7C, 0x45, 0xBB}},
+		{Header: rtp.Header{Version: 2, PayloadType: 96, SequenceNumber: 102, Timestamp: 12000, SSRC: 1, Marker: true}, Payload: []byte{0x41, 0xCC}},
+	}
+	audio := []*rtp.Packet{
+		{Header: rtp.Header{Version: 2, PayloadType: 0, SequenceNumber: 7, Timestamp: 160, SSRC: 2}, Payload: []byte{0xFF}},
+	}
+
+	requests := make(chan []string)
+	go func() {
+		conn, acceptErr := listener.Accept()
+		assert.NoError(t, acceptErr)
+		requests <- serve(t, conn, map[uint8][]*rtp.Packet{0: video, 2: audio})
+	}()
+
+	videoTrack, audioTrack := newTrack(0xAAAA, 102), newTrack(0xBBBB, 0)
+	source, err := Dial("rtsp://user:pass@"+listener.Addr().String()+"/stream", videoTrack, audioTrack)
+	assert.NoError(t, err)
+
+	played := make(chan error)
+	go func() {
+		played <- source.Play()
+	}()
+
+	// The parameter sets are sent before the IDR, with its timestamp
+	sps, pps := []byte{0x67, 0x42, 0x00, 0x29, 0xE2, 0x90, 0x14, 0x07, 0xB6, 0x02, 0xDC, 0x04, 0x04, 0x06, 0x90, 0x78, 0x91, 0x15}, []byte{0x68, 0xCE, 0x3C, 0x80}
+	parameterSets := <-videoTrack.packets
+	assert.Equal(t, stapA([][]byte{sps, pps}), parameterSets.Payload)
+	assert.Equal(t, uint16(100), parameterSets.SequenceNumber)
+	assert.Equal(t, uint32(9000), parameterSets.Timestamp)
+	assert.False(t, parameterSets.Marker)
+
+	for _, expected := range video {
+		p := <-videoTrack.packets
+		assert.Equal(t, expected.SequenceNumber+1, p.SequenceNumber)
+		assert.Equal(t, expected.Timestamp, p.Timestamp)
+		assert.Equal(t, uint32(0xAAAA), p.SSRC)
+		assert.Equal(t, uint8(102), p.PayloadType)
+		assert.Equal(t, expected.Payload, p.Payload)
+	}
+
+	p := <-audioTrack.packets
+	assert.Equal(t, uint16(7), p.SequenceNumber)
+	assert.Equal(t, uint32(0xBBBB), p.SSRC)
+
+	assert.NoError(t, source.Close())
+	select {
+	case err = <-played:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Play didn't return after Close")
+	}
+
+	host := listener.Addr().String()
+	assert.Equal(t, []string{
+		"OPTIONS rtsp://" + host + "/stream RTSP/1.0 ",
+		"DESCRIBE rtsp://" + host + "/stream RTSP/1.0 ",
+		"DESCRIBE rtsp://" + host + "/stream RTSP/1.0 ",
+		"SETUP rtsp://" + host + "/stream/trackID=1 RTSP/1.0 RTP/AVP/TCP;unicast;interleaved=0-1",
+		"SETUP rtsp://" + host + "/stream/trackID=2 RTSP/1.0 RTP/AVP/TCP;unicast;interleaved=2-3",
+		"PLAY rtsp://" + host + "/stream RTSP/1.0 ",
+		"TEARDOWN rtsp://" + host + "/stream RTSP/1.0 ",
+	}, <-requests)
+}
+
+func TestAuthParams(t *testing.T) {
+	assert.Equal(t, map[string]string{
+		"realm":     "a, b",
+		"nonce":     "123",
+		"algorithm": "MD5",
+	}, authParams(`realm="a, b", nonce=123 ,algorithm=MD5`))
+}
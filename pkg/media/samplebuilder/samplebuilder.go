@@ -2,8 +2,12 @@
 package samplebuilder
 
 import (
+	"time"
+
 	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3/pkg/colorspace"
 	"github.com/pion/webrtc/v3/pkg/media"
+	"github.com/pion/webrtc/v3/pkg/videoorientation"
 )
 
 // SampleBuilder buffers packets until media frames are complete.
@@ -11,21 +15,41 @@ type SampleBuilder struct {
 	maxLate uint16 // how many packets to wait until we get a valid Sample
 	buffer  [65536]*rtp.Packet
 
+	// How many RTP timestamp units to wait for missing packets, 0 if unlimited.
+	// Frames with missing packets are discarded after maxLateTimestamp and
+	// released after partialTimeout.
+	maxLateTimestamp uint32
+	partialTimeout   uint32
+
 	// Interface that allows us to take RTP packets to samples
 	depacketizer rtp.Depacketizer
 
-	// Last seqnum that has been added to buffer
-	lastPush uint16
+	// Newest seqnum and timestamp that has been added to buffer
+	hasPushed         bool
+	lastPush          uint16
+	lastPushTimestamp uint32
 
 	// Last seqnum that has been successfully popped
 	// isContiguous is false when we start or when we have a gap
 	// that is older then maxLate
 	isContiguous     bool
+	hasPopped        bool
 	lastPopSeq       uint16
 	lastPopTimestamp uint32
 
 	// Interface that checks whether the packet is the first fragment of the frame or not
 	partitionHeadChecker rtp.PartitionHeadChecker
+
+	// IDs of the video orientation and color space header extensions, 0 if
+	// they aren't read. Senders only send them on some frames, the last ones
+	// read apply to the following Samples.
+	orientationID uint8
+	colorSpaceID  uint8
+	orientation   *videoorientation.Extension
+	colorSpace    *colorspace.Extension
+
+	// rtpMetadata is true if Samples keep the metadata of their packets
+	rtpMetadata bool
 }
 
 // New constructs a new SampleBuilder.
@@ -43,50 +67,81 @@ func New(maxLate uint16, depacketizer rtp.Depacketizer, opts ...Option) *SampleB
 }
 
 // Push adds an RTP Packet to s's buffer.
+// Packets may be pushed out of order, packets that are older than
+// maxLate or than the last popped Sample are ignored.
 //
 // Push does not copy the input. If you wish to reuse
 // this memory make sure to copy before calling Push
 func (s *SampleBuilder) Push(p *rtp.Packet) {
+	if s.hasPushed {
+		diff := int16(p.SequenceNumber - s.lastPush)
+		if diff < 0 && uint16(-diff) > s.maxLate {
+			return
+		}
+		if s.hasPopped && int16(p.SequenceNumber-s.lastPopSeq) <= 0 {
+			return
+		}
+	}
+
 	s.buffer[p.SequenceNumber] = p
 
+	if !s.hasPushed {
+		s.hasPushed = true
+		s.lastPush = p.SequenceNumber
+		s.lastPushTimestamp = p.Timestamp
+		return
+	}
+
 	// Remove outdated references if SequenceNumber is increased.
 	if int16(p.SequenceNumber-s.lastPush) > 0 {
 		for i := s.lastPush; i != p.SequenceNumber+1; i++ {
 			s.buffer[i-s.maxLate] = nil
 		}
+		s.lastPush = p.SequenceNumber
+		s.lastPushTimestamp = p.Timestamp
 	}
+}
 
-	s.lastPush = p.SequenceNumber
+// frameEnd returns the seqnum following the last received packet of the
+// frame starting at first. The frame is complete if the first packet of
+// the next frame is at that seqnum.
+func (s *SampleBuilder) frameEnd(first uint16) (end uint16, complete bool) {
+	for end = first; s.buffer[end] != nil; end++ {
+		if s.buffer[end].Timestamp != s.buffer[first].Timestamp {
+			return end, true
+		}
+	}
+	return end, false
 }
 
-// We have a valid collection of RTP Packets
-// walk forwards building a sample if everything looks good clear and update buffer+values
-func (s *SampleBuilder) buildSample(firstBuffer uint16) (*media.Sample, uint32) {
-	data := []byte{}
+// isFrameHead checks if we can start building Samples at i after a gap
+func (s *SampleBuilder) isFrameHead(i uint16) bool {
+	if s.partitionHeadChecker != nil {
+		return s.partitionHeadChecker.IsPartitionHead(s.buffer[i].Payload)
+	}
 
-	for i := firstBuffer; s.buffer[i] != nil; i++ {
-		if s.buffer[i].Timestamp != s.buffer[firstBuffer].Timestamp {
-			lastTimeStamp := s.lastPopTimestamp
-			if !s.isContiguous {
-				if s.buffer[firstBuffer-1] != nil {
-					lastTimeStamp = s.buffer[firstBuffer-1].Timestamp
-				} else {
-					// If PartitionHeadChecker detects that the first packet is a head,
-					// the duration of the packet is not guessable
-					lastTimeStamp = s.buffer[firstBuffer].Timestamp
-				}
-			}
+	// We can't assert that the first RTP packet we encounter is valid, unless the previous
+	// packet has a different timestamp and so it doesn't span multiple RTP packets
+	return s.buffer[i-1] != nil && s.buffer[i-1].Timestamp != s.buffer[i].Timestamp
+}
 
-			samples := s.buffer[i-1].Timestamp - lastTimeStamp
-			s.lastPopSeq = i - 1
-			s.isContiguous = true
-			s.lastPopTimestamp = s.buffer[i-1].Timestamp
-			for j := firstBuffer; j < i; j++ {
-				s.buffer[j] = nil
-			}
-			return &media.Sample{Data: data, Samples: samples}, s.lastPopTimestamp
-		}
+// isLate checks if packets with timestamp have been waited for longer than limit
+func (s *SampleBuilder) isLate(timestamp, limit uint32) bool {
+	return limit != 0 && int32(s.lastPushTimestamp-timestamp) > int32(limit)
+}
+
+// discard drops the packets from first until end
+func (s *SampleBuilder) discard(first, end uint16) {
+	for i := first; i != end; i++ {
+		s.buffer[i] = nil
+	}
+}
 
+// We have a valid collection of RTP Packets from firstBuffer until end
+// build a sample from them, clear and update buffer+values
+func (s *SampleBuilder) buildSample(firstBuffer, end uint16) (*media.Sample, uint32) {
+	data := []byte{}
+	for i := firstBuffer; i != end; i++ {
 		p, err := s.depacketizer.Unmarshal(s.buffer[i].Payload)
 		if err != nil {
 			return nil, 0
@@ -94,7 +149,91 @@ func (s *SampleBuilder) buildSample(firstBuffer uint16) (*media.Sample, uint32)
 
 		data = append(data, p...)
 	}
-	return nil, 0
+
+	lastTimeStamp := s.lastPopTimestamp
+	if !s.isContiguous {
+		if s.buffer[firstBuffer-1] != nil {
+			lastTimeStamp = s.buffer[firstBuffer-1].Timestamp
+		} else {
+			// If PartitionHeadChecker detects that the first packet is a head,
+			// the duration of the packet is not guessable
+			lastTimeStamp = s.buffer[firstBuffer].Timestamp
+		}
+	}
+
+	s.readExtensions(firstBuffer, end)
+	sample := &media.Sample{
+		Data:        data,
+		Samples:     s.buffer[end-1].Timestamp - lastTimeStamp,
+		Orientation: s.orientation,
+		ColorSpace:  s.colorSpace,
+	}
+	if s.hasPopped {
+		sample.PreviousDroppedPackets = firstBuffer - s.lastPopSeq - 1
+	}
+	if s.rtpMetadata {
+		sample.RTP = s.buildMetadata(firstBuffer, end)
+	}
+
+	s.lastPopSeq = end - 1
+	s.isContiguous = true
+	s.hasPopped = true
+	s.lastPopTimestamp = s.buffer[end-1].Timestamp
+	s.discard(firstBuffer, end)
+
+	return sample, s.lastPopTimestamp
+}
+
+// readExtensions updates the video orientation and color space from the
+// packets from first until end
+func (s *SampleBuilder) readExtensions(first, end uint16) {
+	if s.orientationID == 0 && s.colorSpaceID == 0 {
+		return
+	}
+
+	for i := first; i != end; i++ {
+		if s.buffer[i] == nil {
+			continue
+		}
+
+		if payload := s.buffer[i].GetExtension(s.orientationID); s.orientationID != 0 && payload != nil {
+			orientation := &videoorientation.Extension{}
+			if orientation.Unmarshal(payload) == nil {
+				s.orientation = orientation
+			}
+		}
+		if payload := s.buffer[i].GetExtension(s.colorSpaceID); s.colorSpaceID != 0 && payload != nil {
+			colorSpace := &colorspace.Extension{}
+			if colorSpace.Unmarshal(payload) == nil {
+				s.colorSpace = colorSpace
+			}
+		}
+	}
+}
+
+// buildMetadata returns the metadata of the packets from first until end
+func (s *SampleBuilder) buildMetadata(first, end uint16) *media.RTPMetadata {
+	last := s.buffer[end-1]
+	metadata := &media.RTPMetadata{
+		SSRC:                last.SSRC,
+		PayloadType:         last.PayloadType,
+		Timestamp:           last.Timestamp,
+		FirstSequenceNumber: first,
+		LastSequenceNumber:  end - 1,
+		Marker:              last.Marker,
+		Headers:             make([]rtp.Header, 0, end-first),
+	}
+
+	// Pushed packets aren't copied, their headers are copied by marshaling
+	// them so the packets can be reused
+	for i := first; i != end; i++ {
+		header := rtp.Header{}
+		if raw, err := s.buffer[i].Header.Marshal(); err != nil || header.Unmarshal(raw) != nil {
+			header = s.buffer[i].Header
+		}
+		metadata.Headers = append(metadata.Headers, header)
+	}
+	return metadata
 }
 
 // Distance between two seqnums
@@ -117,45 +256,68 @@ func (s *SampleBuilder) Pop() *media.Sample {
 // PopWithTimestamp scans s's buffer for a valid sample and its RTP timestamp.
 // It returns nil, 0 when no valid samples have been found.
 func (s *SampleBuilder) PopWithTimestamp() (*media.Sample, uint32) {
-	var i uint16
-	if !s.isContiguous {
-		i = s.lastPush - s.maxLate
-	} else {
-		if seqnumDistance(s.lastPopSeq, s.lastPush) > s.maxLate {
-			i = s.lastPush - s.maxLate
+	if !s.hasPushed {
+		return nil, 0
+	}
+
+	if s.isContiguous && seqnumDistance(s.lastPopSeq, s.lastPush) > s.maxLate {
+		s.isContiguous = false
+	}
+
+	if s.isContiguous {
+		next := s.lastPopSeq + 1
+		if s.buffer[next] == nil {
+			// The start of the next frame is missing, wait for it
+			if !s.isLate(s.lastPopTimestamp, s.maxLateTimestamp) && !s.isLate(s.lastPopTimestamp, s.partialTimeout) {
+				return nil, 0
+			}
 			s.isContiguous = false
-		} else {
-			i = s.lastPopSeq + 1
+		} else if sample, timestamp, ok := s.popFrame(next); ok || s.buffer[next] != nil {
+			return sample, timestamp
 		}
 	}
 
-	for ; i != s.lastPush; i++ {
-		curr := s.buffer[i]
-		if curr == nil {
-			continue // we haven't hit a buffer yet, keep moving
-		}
+	i := s.lastPush - s.maxLate
+	if s.hasPopped && seqnumDistance(s.lastPopSeq, s.lastPush) <= s.maxLate {
+		i = s.lastPopSeq + 1
+	}
 
-		if !s.isContiguous {
-			if s.partitionHeadChecker == nil {
-				if s.buffer[i-1] == nil {
-					continue // We have never popped a buffer, so we can't assert that the first RTP packet we encounter is valid
-				} else if s.buffer[i-1].Timestamp == curr.Timestamp {
-					continue // We have the same timestamps, so it is data that spans multiple RTP packets
-				}
-			} else {
-				if !s.partitionHeadChecker.IsPartitionHead(curr.Payload) {
-					continue
-				}
-				// We can start using this frame as it is a head of frame partition
-			}
+	for ; i != s.lastPush; i++ {
+		if s.buffer[i] == nil || !s.isFrameHead(i) {
+			continue
 		}
 
 		// Initial validity checks have passed, walk forward
-		return s.buildSample(i)
+		// and continue scanning if the frame has been discarded
+		if sample, timestamp, ok := s.popFrame(i); ok || s.buffer[i] != nil {
+			return sample, timestamp
+		}
 	}
 	return nil, 0
 }
 
+// popFrame pops the frame starting at first if it is complete, or if it
+// is missing packets for longer than partialTimeout. If it is missing packets
+// for longer than maxLateTimestamp it is discarded, and ok is false.
+func (s *SampleBuilder) popFrame(first uint16) (sample *media.Sample, timestamp uint32, ok bool) {
+	end, complete := s.frameEnd(first)
+	switch {
+	case complete:
+		sample, timestamp = s.buildSample(first, end)
+		return sample, timestamp, sample != nil
+	case s.isLate(s.buffer[first].Timestamp, s.partialTimeout):
+		sample, timestamp = s.buildSample(first, end)
+		// Packets following the gap can't be assumed to start a frame
+		s.isContiguous = false
+		return sample, timestamp, sample != nil
+	case s.isLate(s.buffer[first].Timestamp, s.maxLateTimestamp):
+		s.discard(first, end)
+		s.isContiguous = false
+	}
+
+	return nil, 0, false
+}
+
 // An Option configures a SampleBuilder.
 type Option func(o *SampleBuilder)
 
@@ -166,3 +328,50 @@ func WithPartitionHeadChecker(checker rtp.PartitionHeadChecker) Option {
 		o.partitionHeadChecker = checker
 	}
 }
+
+// WithMaxTimeDelay limits how long the SampleBuilder waits for missing
+// packets, in addition to maxLate. Frames that are still missing packets
+// when packets maxLateDuration newer have been pushed are discarded.
+// The delay is measured in RTP timestamps, sampleRate is the clock rate of the codec.
+func WithMaxTimeDelay(maxLateDuration time.Duration, sampleRate uint32) Option {
+	return func(o *SampleBuilder) {
+		o.maxLateTimestamp = media.NSamples(maxLateDuration, int(sampleRate))
+	}
+}
+
+// WithPartialTimeout releases frames that are still missing packets when
+// packets timeout newer have been pushed, instead of discarding them.
+// The received packets of the frame are depacketized as they are, frames
+// whose first packet is missing are always discarded.
+// The timeout is measured in RTP timestamps, sampleRate is the clock rate of the codec.
+func WithPartialTimeout(timeout time.Duration, sampleRate uint32) Option {
+	return func(o *SampleBuilder) {
+		o.partialTimeout = media.NSamples(timeout, int(sampleRate))
+	}
+}
+
+// WithVideoOrientation sets the Orientation of Samples from the video
+// orientation header extension negotiated with id, see
+// RTPReceiver.HeaderExtensionID and package videoorientation.
+func WithVideoOrientation(id uint8) Option {
+	return func(o *SampleBuilder) {
+		o.orientationID = id
+	}
+}
+
+// WithColorSpace sets the ColorSpace of Samples from the color space header
+// extension negotiated with id, see RTPReceiver.HeaderExtensionID and
+// package colorspace.
+func WithColorSpace(id uint8) Option {
+	return func(o *SampleBuilder) {
+		o.colorSpaceID = id
+	}
+}
+
+// WithRTPMetadata sets the RTP of Samples to the metadata of their packets,
+// like their sequence numbers and header extensions
+func WithRTPMetadata() Option {
+	return func(o *SampleBuilder) {
+		o.rtpMetadata = true
+	}
+}
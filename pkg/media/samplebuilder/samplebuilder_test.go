@@ -3,9 +3,12 @@ package samplebuilder
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3/pkg/colorspace"
 	"github.com/pion/webrtc/v3/pkg/media"
+	"github.com/pion/webrtc/v3/pkg/videoorientation"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -214,7 +217,7 @@ func TestSampleBuilderMaxLate(t *testing.T) {
 	s.Push(&rtp.Packet{Header: rtp.Header{SequenceNumber: 5000, Timestamp: 500}, Payload: []byte{0x02}})
 	s.Push(&rtp.Packet{Header: rtp.Header{SequenceNumber: 5001, Timestamp: 501}, Payload: []byte{0x02}})
 	s.Push(&rtp.Packet{Header: rtp.Header{SequenceNumber: 5002, Timestamp: 502}, Payload: []byte{0x02}})
-	assert.Equal(s.Pop(), &media.Sample{Data: []byte{0x02}, Samples: 1}, "Failed to build samples after large gap")
+	assert.Equal(s.Pop(), &media.Sample{Data: []byte{0x02}, Samples: 1, PreviousDroppedPackets: 4999}, "Failed to build samples after large gap")
 }
 
 func TestSeqnumDistance(t *testing.T) {
@@ -271,3 +274,152 @@ func TestSampleBuilderCleanReference(t *testing.T) {
 		})
 	}
 }
+
+func TestSampleBuilderOutOfOrder(t *testing.T) {
+	assert := assert.New(t)
+	s := New(10, &fakeDepacketizer{})
+
+	s.Push(&rtp.Packet{Header: rtp.Header{SequenceNumber: 0, Timestamp: 1}, Payload: []byte{0x01}})
+	s.Push(&rtp.Packet{Header: rtp.Header{SequenceNumber: 1, Timestamp: 2}, Payload: []byte{0x02}})
+	s.Push(&rtp.Packet{Header: rtp.Header{SequenceNumber: 3, Timestamp: 2}, Payload: []byte{0x04}})
+	s.Push(&rtp.Packet{Header: rtp.Header{SequenceNumber: 4, Timestamp: 3}, Payload: []byte{0x05}})
+	assert.Nil(s.Pop(), "Sample must wait for the missing packet")
+
+	s.Push(&rtp.Packet{Header: rtp.Header{SequenceNumber: 2, Timestamp: 2}, Payload: []byte{0x03}})
+	assert.Equal(&media.Sample{Data: []byte{0x02, 0x03, 0x04}, Samples: 1}, s.Pop())
+	assert.Nil(s.Pop())
+
+	// Packets of popped Samples are ignored
+	s.Push(&rtp.Packet{Header: rtp.Header{SequenceNumber: 2, Timestamp: 2}, Payload: []byte{0x03}})
+	s.Push(&rtp.Packet{Header: rtp.Header{SequenceNumber: 5, Timestamp: 4}, Payload: []byte{0x06}})
+	assert.Equal(&media.Sample{Data: []byte{0x05}, Samples: 1}, s.Pop())
+}
+
+func TestSampleBuilderPreviousDroppedPackets(t *testing.T) {
+	assert := assert.New(t)
+	s := New(5, &fakeDepacketizer{})
+
+	s.Push(&rtp.Packet{Header: rtp.Header{SequenceNumber: 0, Timestamp: 1}, Payload: []byte{0x01}})
+	s.Push(&rtp.Packet{Header: rtp.Header{SequenceNumber: 1, Timestamp: 2}, Payload: []byte{0x02}})
+	s.Push(&rtp.Packet{Header: rtp.Header{SequenceNumber: 2, Timestamp: 3}, Payload: []byte{0x03}})
+	assert.Equal(&media.Sample{Data: []byte{0x02}, Samples: 1}, s.Pop())
+
+	// Packets 3 and 4 are lost, 5 can't be assumed to be the start of a frame
+	for i := uint16(5); i < 10; i++ {
+		s.Push(&rtp.Packet{Header: rtp.Header{SequenceNumber: i, Timestamp: uint32(i)}, Payload: []byte{byte(i)}})
+	}
+	assert.Equal(&media.Sample{Data: []byte{0x06}, Samples: 1, PreviousDroppedPackets: 4}, s.Pop())
+	assert.Equal(&media.Sample{Data: []byte{0x07}, Samples: 1}, s.Pop())
+}
+
+func TestSampleBuilderMaxTimeDelay(t *testing.T) {
+	assert := assert.New(t)
+	s := New(50, &fakeDepacketizer{}, WithMaxTimeDelay(time.Second, 90000))
+
+	s.Push(&rtp.Packet{Header: rtp.Header{SequenceNumber: 0, Timestamp: 0}, Payload: []byte{0x01}})
+	s.Push(&rtp.Packet{Header: rtp.Header{SequenceNumber: 1, Timestamp: 3000}, Payload: []byte{0x02}})
+	s.Push(&rtp.Packet{Header: rtp.Header{SequenceNumber: 2, Timestamp: 6000}, Payload: []byte{0x03}})
+	assert.Equal(&media.Sample{Data: []byte{0x02}, Samples: 3000}, s.Pop())
+
+	// Frame 6000 is missing its last packet, and is discarded once packets a second newer arrive
+	s.Push(&rtp.Packet{Header: rtp.Header{SequenceNumber: 4, Timestamp: 9000}, Payload: []byte{0x05}})
+	s.Push(&rtp.Packet{Header: rtp.Header{SequenceNumber: 5, Timestamp: 12000}, Payload: []byte{0x06}})
+	assert.Nil(s.Pop())
+
+	s.Push(&rtp.Packet{Header: rtp.Header{SequenceNumber: 6, Timestamp: 96001}, Payload: []byte{0x07}})
+	assert.Equal(&media.Sample{Data: []byte{0x06}, Samples: 3000, PreviousDroppedPackets: 3}, s.Pop())
+	assert.Nil(s.Pop())
+}
+
+func TestSampleBuilderPartialTimeout(t *testing.T) {
+	assert := assert.New(t)
+	s := New(50, &fakeDepacketizer{}, WithPartialTimeout(100*time.Millisecond, 90000))
+
+	s.Push(&rtp.Packet{Header: rtp.Header{SequenceNumber: 0, Timestamp: 0}, Payload: []byte{0x01}})
+	s.Push(&rtp.Packet{Header: rtp.Header{SequenceNumber: 1, Timestamp: 3000}, Payload: []byte{0x02}})
+	s.Push(&rtp.Packet{Header: rtp.Header{SequenceNumber: 2, Timestamp: 6000}, Payload: []byte{0x03}})
+	assert.Equal(&media.Sample{Data: []byte{0x02}, Samples: 3000}, s.Pop())
+
+	// Frame 6000 is missing packet 4, it is released with the packets received so far
+	s.Push(&rtp.Packet{Header: rtp.Header{SequenceNumber: 3, Timestamp: 6000}, Payload: []byte{0x04}})
+	s.Push(&rtp.Packet{Header: rtp.Header{SequenceNumber: 5, Timestamp: 9000}, Payload: []byte{0x06}})
+	s.Push(&rtp.Packet{Header: rtp.Header{SequenceNumber: 6, Timestamp: 12000}, Payload: []byte{0x07}})
+	assert.Nil(s.Pop())
+
+	s.Push(&rtp.Packet{Header: rtp.Header{SequenceNumber: 7, Timestamp: 15001}, Payload: []byte{0x08}})
+	assert.Equal(&media.Sample{Data: []byte{0x03, 0x04}, Samples: 3000}, s.Pop())
+
+	// Frame 9000 follows the gap, it can't be assumed to start with packet 5
+	assert.Equal(&media.Sample{Data: []byte{0x07}, Samples: 3000, PreviousDroppedPackets: 2}, s.Pop())
+	assert.Nil(s.Pop())
+}
+
+func TestSampleBuilderExtensions(t *testing.T) {
+	assert := assert.New(t)
+	s := New(5, &fakeDepacketizer{}, WithVideoOrientation(1), WithColorSpace(2))
+
+	orientation := videoorientation.Extension{Rotation: videoorientation.Rotation90}
+	colorSpace := colorspace.Extension{Primaries: colorspace.PrimariesBT709, Range: colorspace.RangeLimited}
+	rawOrientation, err := orientation.Marshal()
+	assert.NoError(err)
+	rawColorSpace, err := colorSpace.Marshal()
+	assert.NoError(err)
+
+	// The extensions are sent with the last packet of the first frame, and
+	// apply to the following frames until they change
+	last := &rtp.Packet{Header: rtp.Header{SequenceNumber: 2, Timestamp: 1}, Payload: []byte{0x02}}
+	assert.NoError(last.SetExtension(1, rawOrientation))
+	assert.NoError(last.SetExtension(2, rawColorSpace))
+	s.Push(&rtp.Packet{Header: rtp.Header{SequenceNumber: 0, Timestamp: 0}, Payload: []byte{0x00}})
+	s.Push(&rtp.Packet{Header: rtp.Header{SequenceNumber: 1, Timestamp: 1}, Payload: []byte{0x01}})
+	s.Push(last)
+	s.Push(&rtp.Packet{Header: rtp.Header{SequenceNumber: 3, Timestamp: 2}, Payload: []byte{0x03}})
+	s.Push(&rtp.Packet{Header: rtp.Header{SequenceNumber: 4, Timestamp: 3}, Payload: []byte{0x04}})
+
+	sample := s.Pop()
+	assert.Equal([]byte{0x01, 0x02}, sample.Data)
+	assert.Equal(&orientation, sample.Orientation)
+	assert.Equal(&colorSpace, sample.ColorSpace)
+
+	sample = s.Pop()
+	assert.Equal([]byte{0x03}, sample.Data)
+	assert.Equal(&orientation, sample.Orientation)
+}
+
+func TestSampleBuilderRTPMetadata(t *testing.T) {
+	assert := assert.New(t)
+	s := New(5, &fakeDepacketizer{}, WithRTPMetadata())
+
+	first := &rtp.Packet{Header: rtp.Header{Version: 2, SSRC: 5000, PayloadType: 96, SequenceNumber: 11, Timestamp: 1}, Payload: []byte{0x01}}
+	assert.NoError(first.SetExtension(3, []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08}))
+	s.Push(&rtp.Packet{Header: rtp.Header{Version: 2, SSRC: 5000, PayloadType: 96, SequenceNumber: 10, Timestamp: 0, Marker: true}, Payload: []byte{0x00}})
+	s.Push(first)
+	s.Push(&rtp.Packet{Header: rtp.Header{Version: 2, SSRC: 5000, PayloadType: 96, SequenceNumber: 12, Timestamp: 1, Marker: true}, Payload: []byte{0x02}})
+	s.Push(&rtp.Packet{Header: rtp.Header{Version: 2, SSRC: 5000, PayloadType: 96, SequenceNumber: 13, Timestamp: 2}, Payload: []byte{0x03}})
+
+	sample := s.Pop()
+	assert.Equal([]byte{0x01, 0x02}, sample.Data)
+	metadata := sample.RTP
+	assert.NotNil(metadata)
+	assert.Equal(uint32(5000), metadata.SSRC)
+	assert.Equal(uint8(96), metadata.PayloadType)
+	assert.Equal(uint32(1), metadata.Timestamp)
+	assert.Equal(uint16(11), metadata.FirstSequenceNumber)
+	assert.Equal(uint16(12), metadata.LastSequenceNumber)
+	assert.True(metadata.Marker)
+	assert.Equal(2, len(metadata.Headers))
+
+	// The headers are copied, the packets can be reused
+	first.GetExtension(3)[0] = 0xFF
+	assert.Equal([]byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08}, metadata.Extension(3))
+	assert.Nil(metadata.Extension(4))
+
+	// Without the option the metadata isn't kept
+	s = New(5, &fakeDepacketizer{})
+	s.Push(&rtp.Packet{Header: rtp.Header{SequenceNumber: 0, Timestamp: 0}, Payload: []byte{0x00}})
+	s.Push(&rtp.Packet{Header: rtp.Header{SequenceNumber: 1, Timestamp: 1}, Payload: []byte{0x01}})
+	s.Push(&rtp.Packet{Header: rtp.Header{SequenceNumber: 2, Timestamp: 2}, Payload: []byte{0x02}})
+	sample = s.Pop()
+	assert.Equal([]byte{0x01}, sample.Data)
+	assert.Nil(sample.RTP)
+}
@@ -0,0 +1,312 @@
+// Package tsreader implements a MPEG transport stream (ISO/IEC 13818-1)
+// demuxer, for streams of contribution encoders sent over SRT or UDP
+package tsreader
+
+import (
+	"errors"
+	"io"
+	"sort"
+)
+
+const (
+	packetSize = 188
+	syncByte   = 0x47
+
+	// bufferSize leaves room for a whole message of message oriented
+	// connections, like SRT and UDP, in each Read
+	bufferSize = 64 * 1024
+
+	pidPAT = 0x0000
+
+	tableIDPAT = 0x00
+	tableIDPMT = 0x02
+)
+
+// StreamType is the type of an elementary stream in the program map table
+type StreamType uint8
+
+// Stream types of audio and video codecs
+const (
+	StreamTypeMPEG1Audio StreamType = 0x03
+	StreamTypeMPEG2Audio StreamType = 0x04
+	StreamTypeAAC        StreamType = 0x0F
+	StreamTypeH264       StreamType = 0x1B
+	StreamTypeH265       StreamType = 0x24
+)
+
+var errNilStream = errors.New("stream is nil")
+
+// Frame is the payload of a PES packet of an elementary stream, like a H264
+// access unit in Annex B format or AAC frames with ADTS headers
+type Frame struct {
+	PID        uint16
+	StreamType StreamType
+
+	// PTS and DTS are in units of 90 kHz, DTS is PTS if the stream has no DTS
+	PTS uint64
+	DTS uint64
+
+	Data []byte
+}
+
+// elementaryStream is a PID listed in a program map table
+type elementaryStream struct {
+	streamType StreamType
+
+	hasContinuity bool
+	continuity    uint8
+
+	// pes holds the PES packet being received, nil if its start was lost
+	pes []byte
+}
+
+// TSReader reads the frames of the elementary streams of a transport stream.
+// Tables must fit in a single transport stream packet.
+type TSReader struct {
+	stream io.Reader
+	buffer []byte
+	start  int
+	end    int
+	eof    bool
+
+	pmtPIDs map[uint16]bool
+	streams map[uint16]*elementaryStream
+	frames  []*Frame
+}
+
+// NewReader creates a TSReader that reads from in. Reads are given at least
+// 64 KiB, so a reader of SRT or UDP messages may return a whole one.
+func NewReader(in io.Reader) (*TSReader, error) {
+	if in == nil {
+		return nil, errNilStream
+	}
+
+	return &TSReader{
+		stream:  in,
+		buffer:  make([]byte, bufferSize),
+		pmtPIDs: map[uint16]bool{},
+		streams: map[uint16]*elementaryStream{},
+	}, nil
+}
+
+// ReadFrame returns the next frame of any elementary stream. Frames of PES
+// packets with unknown length are returned when the next packet starts, or
+// at the end of the stream. PES packets with lost parts are dropped.
+func (r *TSReader) ReadFrame() (*Frame, error) {
+	for len(r.frames) == 0 {
+		if r.eof {
+			return nil, io.EOF
+		}
+
+		packet, err := r.readPacket()
+		switch {
+		case errors.Is(err, io.EOF):
+			r.eof = true
+			r.flush()
+		case err != nil:
+			return nil, err
+		default:
+			r.handlePacket(packet)
+		}
+	}
+
+	frame := r.frames[0]
+	r.frames = r.frames[1:]
+	return frame, nil
+}
+
+// readPacket returns the next transport stream packet, bytes before a sync
+// byte are skipped
+func (r *TSReader) readPacket() ([]byte, error) {
+	for {
+		for r.start < r.end && r.buffer[r.start] != syncByte {
+			r.start++
+		}
+		if r.end-r.start >= packetSize {
+			packet := r.buffer[r.start : r.start+packetSize]
+			r.start += packetSize
+			return packet, nil
+		}
+
+		r.end = copy(r.buffer, r.buffer[r.start:r.end])
+		r.start = 0
+		n, err := r.stream.Read(r.buffer[r.end:])
+		r.end += n
+		if err != nil && (n == 0 || !errors.Is(err, io.EOF)) {
+			return nil, err
+		}
+	}
+}
+
+func (r *TSReader) handlePacket(packet []byte) {
+	payloadUnitStart := packet[1]&0x40 != 0
+	pid := uint16(packet[1]&0x1F)<<8 | uint16(packet[2])
+	adaptationFieldControl := packet[3] >> 4 & 0x03
+	continuity := packet[3] & 0x0F
+
+	// The payload follows the adaptation field
+	payload := packet[4:]
+	switch adaptationFieldControl {
+	case 0x01:
+	case 0x03:
+		if int(payload[0]) >= len(payload) {
+			return
+		}
+		payload = payload[1+payload[0]:]
+	default:
+		return
+	}
+
+	switch s, ok := r.streams[pid]; {
+	case pid == pidPAT && payloadUnitStart:
+		r.handlePAT(section(payload))
+	case r.pmtPIDs[pid] && payloadUnitStart:
+		r.handlePMT(section(payload))
+	case ok:
+		r.handlePES(s, pid, payloadUnitStart, continuity, payload)
+	}
+}
+
+// section returns the table section of a payload that starts with a pointer field
+func section(payload []byte) []byte {
+	if int(payload[0]) >= len(payload) {
+		return nil
+	}
+	return payload[1+payload[0]:]
+}
+
+// tableData returns the data of a table section after the syntax header,
+// without the CRC
+func tableData(section []byte, tableID uint8) []byte {
+	const headerSize, crcSize = 8, 4
+	if len(section) < headerSize+crcSize || section[0] != tableID {
+		return nil
+	}
+
+	end := 3 + (int(section[1]&0x0F)<<8 | int(section[2]))
+	if end > len(section) || end < headerSize+crcSize {
+		return nil
+	}
+	return section[headerSize : end-crcSize]
+}
+
+func (r *TSReader) handlePAT(section []byte) {
+	data := tableData(section, tableIDPAT)
+	for ; len(data) >= 4; data = data[4:] {
+		programNumber := uint16(data[0])<<8 | uint16(data[1])
+		// Program 0 is the network information table
+		if programNumber != 0 {
+			r.pmtPIDs[uint16(data[2]&0x1F)<<8|uint16(data[3])] = true
+		}
+	}
+}
+
+func (r *TSReader) handlePMT(section []byte) {
+	data := tableData(section, tableIDPMT)
+	if len(data) < 4 {
+		return
+	}
+
+	programInfoLength := int(data[2]&0x0F)<<8 | int(data[3])
+	if 4+programInfoLength > len(data) {
+		return
+	}
+	data = data[4+programInfoLength:]
+
+	for len(data) >= 5 {
+		streamType := StreamType(data[0])
+		pid := uint16(data[1]&0x1F)<<8 | uint16(data[2])
+		esInfoLength := int(data[3]&0x0F)<<8 | int(data[4])
+		if 5+esInfoLength > len(data) {
+			return
+		}
+		data = data[5+esInfoLength:]
+
+		if s, ok := r.streams[pid]; !ok || s.streamType != streamType {
+			r.streams[pid] = &elementaryStream{streamType: streamType}
+		}
+	}
+}
+
+func (r *TSReader) handlePES(s *elementaryStream, pid uint16, payloadUnitStart bool, continuity uint8, payload []byte) {
+	// A PES packet with a lost transport stream packet is dropped
+	if s.hasContinuity && continuity != (s.continuity+1)&0x0F {
+		s.pes = nil
+	}
+	s.hasContinuity = true
+	s.continuity = continuity
+
+	if payloadUnitStart {
+		r.emit(s, pid)
+		s.pes = make([]byte, 0, len(payload))
+	}
+	if s.pes == nil {
+		return
+	}
+	s.pes = append(s.pes, payload...)
+
+	// PES packets with a length are complete without waiting for the next one
+	if len(s.pes) >= 6 {
+		if length := int(s.pes[4])<<8 | int(s.pes[5]); length != 0 && len(s.pes) >= 6+length {
+			s.pes = s.pes[:6+length]
+			r.emit(s, pid)
+		}
+	}
+}
+
+// flush emits the PES packets that end with the stream, in the order of
+// their PIDs
+func (r *TSReader) flush() {
+	pids := make([]int, 0, len(r.streams))
+	for pid := range r.streams {
+		pids = append(pids, int(pid))
+	}
+	sort.Ints(pids)
+
+	for _, pid := range pids {
+		r.emit(r.streams[uint16(pid)], uint16(pid))
+	}
+}
+
+// emit queues the frame of the PES packet of s, if it is valid
+func (r *TSReader) emit(s *elementaryStream, pid uint16) {
+	pes := s.pes
+	s.pes = nil
+
+	const headerSize = 9
+	if len(pes) < headerSize || pes[0] != 0x00 || pes[1] != 0x00 || pes[2] != 0x01 {
+		return
+	}
+	if length := int(pes[4])<<8 | int(pes[5]); length != 0 && len(pes) < 6+length {
+		return
+	}
+
+	headerDataLength := int(pes[8])
+	if headerSize+headerDataLength > len(pes) {
+		return
+	}
+	frame := &Frame{
+		PID:        pid,
+		StreamType: s.streamType,
+		Data:       pes[headerSize+headerDataLength:],
+	}
+
+	switch ptsDTSFlags := pes[7] >> 6; {
+	case ptsDTSFlags == 0x02 && headerDataLength >= 5:
+		frame.PTS = timestamp(pes[9:])
+		frame.DTS = frame.PTS
+	case ptsDTSFlags == 0x03 && headerDataLength >= 10:
+		frame.PTS = timestamp(pes[9:])
+		frame.DTS = timestamp(pes[14:])
+	}
+	r.frames = append(r.frames, frame)
+}
+
+// timestamp parses the 33 bits of a PTS or DTS
+func timestamp(b []byte) uint64 {
+	return uint64(b[0]>>1&0x07)<<30 |
+		uint64(b[1])<<22 |
+		uint64(b[2]>>1)<<15 |
+		uint64(b[3])<<7 |
+		uint64(b[4]>>1)
+}
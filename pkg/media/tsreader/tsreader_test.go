@@ -0,0 +1,55 @@
+package tsreader
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/pion/webrtc/v3/pkg/media/internal/tstest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTSReader(t *testing.T) {
+	var patContinuity, pmtContinuity, h264Continuity, aacContinuity uint8
+	stream := []byte{0x00, 0x01} // Garbage before the first sync byte
+	stream = append(stream, tstest.Packets(tstest.PIDPAT, &patContinuity, tstest.Table(tableIDPAT, []byte{0x00, 0x01, 0xE0 | tstest.PIDPMT>>8, tstest.PIDPMT & 0xFF}))...)
+	stream = append(stream, tstest.Packets(tstest.PIDPMT, &pmtContinuity, tstest.Table(tableIDPMT, []byte{
+		0xE0 | tstest.PIDH264>>8, tstest.PIDH264 & 0xFF, 0xF0, 0x00,
+		byte(StreamTypeH264), 0xE0 | tstest.PIDH264>>8, tstest.PIDH264 & 0xFF, 0xF0, 0x00,
+		byte(StreamTypeAAC), 0xE0 | tstest.PIDAAC>>8, tstest.PIDAAC & 0xFF, 0xF0, 0x00,
+	}))...)
+
+	accessUnit := append([]byte{0x00, 0x00, 0x00, 0x01, 0x65}, bytes.Repeat([]byte{0xAB}, 500)...)
+	stream = append(stream, tstest.Packets(tstest.PIDH264, &h264Continuity, tstest.PES(false, 93000, 90000, accessUnit))...)
+	stream = append(stream, tstest.Packets(tstest.PIDAAC, &aacContinuity, tstest.PES(true, 1<<32+5, 1<<32+5, []byte{0xFF, 0xF1, 0x01}))...)
+
+	// A PES packet with a lost packet is dropped
+	lost := tstest.Packets(tstest.PIDH264, &h264Continuity, tstest.PES(false, 96000, 93000, accessUnit))
+	stream = append(stream, lost[:packetSize]...)
+	stream = append(stream, lost[2*packetSize:]...)
+
+	stream = append(stream, tstest.Packets(tstest.PIDH264, &h264Continuity, tstest.PES(false, 99000, 96000, []byte{0x00, 0x00, 0x01, 0x41}))...)
+
+	_, err := NewReader(nil)
+	assert.Equal(t, errNilStream, err)
+
+	reader, err := NewReader(bytes.NewReader(stream))
+	assert.NoError(t, err)
+
+	// The AAC frame is bounded, and complete before the H264 one
+	frame, err := reader.ReadFrame()
+	assert.NoError(t, err)
+	assert.Equal(t, &Frame{PID: tstest.PIDAAC, StreamType: StreamTypeAAC, PTS: 1<<32 + 5, DTS: 1<<32 + 5, Data: []byte{0xFF, 0xF1, 0x01}}, frame)
+
+	frame, err = reader.ReadFrame()
+	assert.NoError(t, err)
+	assert.Equal(t, &Frame{PID: tstest.PIDH264, StreamType: StreamTypeH264, PTS: 93000, DTS: 90000, Data: accessUnit}, frame)
+
+	// The last frame ends with the stream
+	frame, err = reader.ReadFrame()
+	assert.NoError(t, err)
+	assert.Equal(t, &Frame{PID: tstest.PIDH264, StreamType: StreamTypeH264, PTS: 99000, DTS: 96000, Data: []byte{0x00, 0x00, 0x01, 0x41}}, frame)
+
+	_, err = reader.ReadFrame()
+	assert.Equal(t, io.EOF, err)
+}
@@ -0,0 +1,225 @@
+// Package tssource writes the H264 and AAC streams of an MPEG transport
+// stream, like one of a contribution encoder sent over SRT, to tracks
+package tssource
+
+import (
+	"errors"
+	"io"
+
+	"github.com/pion/webrtc/v3/pkg/media"
+	"github.com/pion/webrtc/v3/pkg/media/aacpacketizer"
+	"github.com/pion/webrtc/v3/pkg/media/tsreader"
+)
+
+const (
+	// probeFrames is the number of frames NewSource reads at most to find
+	// the H264 and AAC streams
+	probeFrames = 256
+
+	adtsHeaderSize = 7
+	adtsCRCSize    = 2
+
+	// timestampMask wraps the 33 bits of a PTS or DTS
+	timestampMask = 1<<33 - 1
+)
+
+var errNoStreams = errors.New("the stream has no H264 or AAC stream")
+
+// sampleRates are the sample rates of the sampling frequency indexes of ADTS
+var sampleRates = []uint32{ //nolint:gochecknoglobals
+	96000, 88200, 64000, 48000, 44100, 32000, 24000, 22050, 16000, 12000, 11025, 8000, 7350,
+}
+
+// SampleWriter is where a Source writes Samples to, like a local
+// webrtc.Track
+type SampleWriter interface {
+	WriteSample(media.Sample) error
+}
+
+// AudioConfig describes the AAC stream of a Source, the audio track is
+// created with it by webrtc.NewRTPAACCodec
+type AudioConfig struct {
+	AudioSpecificConfig []byte
+	SampleRate          uint32
+	Channels            uint16
+}
+
+// Source writes the first H264 stream and the first AAC stream of a
+// transport stream to tracks. The stream is played as fast as it is read,
+// so it must be received in real time, like from an encoder.
+type Source struct {
+	reader *tsreader.TSReader
+
+	// probed are the frames read by NewSource, which Play writes first
+	probed []*tsreader.Frame
+
+	hasVideo, hasAudio bool
+	videoPID, audioPID uint16
+	audio              *AudioConfig
+}
+
+// NewSource reads in until it found an H264 and an AAC stream, so the
+// tracks can be created with their codecs. It gives up on the missing one
+// after a few hundred frames.
+func NewSource(in io.Reader) (*Source, error) {
+	reader, err := tsreader.NewReader(in)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Source{reader: reader}
+	for len(s.probed) < probeFrames && !(s.hasVideo && s.hasAudio) {
+		var frame *tsreader.Frame
+		frame, err = reader.ReadFrame()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		s.probed = append(s.probed, frame)
+
+		switch {
+		case frame.StreamType == tsreader.StreamTypeH264 && !s.hasVideo:
+			s.hasVideo, s.videoPID = true, frame.PID
+		case frame.StreamType == tsreader.StreamTypeAAC && !s.hasAudio:
+			if config, ok := parseAudioConfig(frame.Data); ok {
+				s.hasAudio, s.audioPID, s.audio = true, frame.PID, config
+			}
+		}
+	}
+
+	if !s.hasVideo && !s.hasAudio {
+		return nil, errNoStreams
+	}
+	return s, nil
+}
+
+// HasVideo returns true if the stream has an H264 stream
+func (s *Source) HasVideo() bool {
+	return s.hasVideo
+}
+
+// Audio returns the configuration of the AAC stream, nil if the stream has
+// none
+func (s *Source) Audio() *AudioConfig {
+	return s.audio
+}
+
+// Play writes the H264 access units to video, and the AAC frames without
+// their ADTS headers to audio, until the stream ends. A nil track skips its
+// stream. The clock rate of the audio track must be the sample rate.
+func (s *Source) Play(video, audio SampleWriter) error {
+	// An access unit is written once the next one gives its duration
+	var pending *tsreader.Frame
+	writeVideo := func(frame *tsreader.Frame) error {
+		if pending == nil {
+			pending = frame
+			return nil
+		}
+
+		sample := media.Sample{Data: pending.Data, Samples: uint32((frame.DTS - pending.DTS) & timestampMask)}
+		pending = frame
+		return ignoreClosed(video.WriteSample(sample))
+	}
+
+	for {
+		frame, err := s.nextFrame()
+		if errors.Is(err, io.EOF) {
+			if pending == nil {
+				return nil
+			}
+			return ignoreClosed(video.WriteSample(media.Sample{Data: pending.Data}))
+		} else if err != nil {
+			return err
+		}
+
+		switch {
+		case s.hasVideo && frame.PID == s.videoPID && video != nil:
+			err = writeVideo(frame)
+		case s.hasAudio && frame.PID == s.audioPID && audio != nil:
+			err = writeAudio(audio, frame.Data)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// nextFrame returns the probed frames, then reads the next ones
+func (s *Source) nextFrame() (*tsreader.Frame, error) {
+	if len(s.probed) != 0 {
+		frame := s.probed[0]
+		s.probed = s.probed[1:]
+		return frame, nil
+	}
+	return s.reader.ReadFrame()
+}
+
+// writeAudio writes each ADTS frame of a PES packet, a frame with an
+// invalid header drops the rest of the packet
+func writeAudio(audio SampleWriter, data []byte) error {
+	for len(data) != 0 {
+		headerSize, frameLength, ok := parseADTS(data)
+		if !ok {
+			return nil
+		}
+
+		sample := media.Sample{Data: data[headerSize:frameLength], Samples: aacpacketizer.SamplesPerFrame}
+		if err := ignoreClosed(audio.WriteSample(sample)); err != nil {
+			return err
+		}
+		data = data[frameLength:]
+	}
+	return nil
+}
+
+// parseADTS returns the size of the ADTS header at the start of data, and
+// the length of its frame with the header, or false if it is invalid
+func parseADTS(data []byte) (headerSize, frameLength int, ok bool) {
+	if len(data) < adtsHeaderSize || data[0] != 0xFF || data[1]&0xF0 != 0xF0 {
+		return 0, 0, false
+	}
+
+	headerSize = adtsHeaderSize
+	if data[1]&0x01 == 0 {
+		headerSize += adtsCRCSize
+	}
+	frameLength = int(data[3]&0x03)<<11 | int(data[4])<<3 | int(data[5]>>5)
+	if frameLength < headerSize || frameLength > len(data) {
+		return 0, 0, false
+	}
+	return headerSize, frameLength, true
+}
+
+// parseAudioConfig returns the AudioConfig of the ADTS header at the start
+// of data, or false if it is invalid
+func parseAudioConfig(data []byte) (*AudioConfig, bool) {
+	if _, _, ok := parseADTS(data); !ok {
+		return nil, false
+	}
+
+	objectType := data[2]>>6 + 1
+	frequencyIndex := data[2] >> 2 & 0x0F
+	channelConfiguration := data[2]&0x01<<2 | data[3]>>6
+	if int(frequencyIndex) >= len(sampleRates) {
+		return nil, false
+	}
+
+	return &AudioConfig{
+		AudioSpecificConfig: []byte{
+			objectType<<3 | frequencyIndex>>1,
+			frequencyIndex&0x01<<7 | channelConfiguration<<3,
+		},
+		SampleRate: sampleRates[frequencyIndex],
+		Channels:   uint16(channelConfiguration),
+	}, true
+}
+
+// ignoreClosed ignores the error of writing to a track without any
+// PeerConnection sending it
+func ignoreClosed(err error) error {
+	if errors.Is(err, io.ErrClosedPipe) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,78 @@
+package tssource
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/pion/webrtc/v3/pkg/media"
+	"github.com/pion/webrtc/v3/pkg/media/aacpacketizer"
+	"github.com/pion/webrtc/v3/pkg/media/internal/tstest"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	streamTypeH264 = 0x1B
+	streamTypeAAC  = 0x0F
+)
+
+type sampleWriter struct {
+	samples []media.Sample
+	err     error
+}
+
+func (w *sampleWriter) WriteSample(s media.Sample) error {
+	w.samples = append(w.samples, s)
+	return w.err
+}
+
+// adts returns an AAC LC frame of 48 kHz stereo with an ADTS header
+func adts(frame []byte) []byte {
+	length := 7 + len(frame)
+	return append([]byte{
+		0xFF, 0xF1, 0x4C, 0x80 | byte(length>>11), byte(length >> 3), byte(length<<5) | 0x1F, 0xFC,
+	}, frame...)
+}
+
+func TestSource(t *testing.T) {
+	var patContinuity, pmtContinuity, h264Continuity, aacContinuity uint8
+	stream := tstest.Packets(tstest.PIDPAT, &patContinuity, tstest.Table(0x00, []byte{0x00, 0x01, 0xE0 | tstest.PIDPMT>>8, tstest.PIDPMT & 0xFF}))
+	stream = append(stream, tstest.Packets(tstest.PIDPMT, &pmtContinuity, tstest.Table(0x02, []byte{
+		0xE0 | tstest.PIDH264>>8, tstest.PIDH264 & 0xFF, 0xF0, 0x00,
+		streamTypeH264, 0xE0 | tstest.PIDH264>>8, tstest.PIDH264 & 0xFF, 0xF0, 0x00,
+		streamTypeAAC, 0xE0 | tstest.PIDAAC>>8, tstest.PIDAAC & 0xFF, 0xF0, 0x00,
+	}))...)
+
+	keyframe := []byte{0x00, 0x00, 0x00, 0x01, 0x09, 0xF0, 0x00, 0x00, 0x00, 0x01, 0x65, 0xAB}
+	stream = append(stream, tstest.Packets(tstest.PIDH264, &h264Continuity, tstest.PES(false, 90000, 90000, keyframe))...)
+	stream = append(stream, tstest.Packets(tstest.PIDAAC, &aacContinuity, tstest.PES(false, 90000, 90000, append(adts([]byte{0x01, 0x02}), adts([]byte{0x03})...)))...)
+	stream = append(stream, tstest.Packets(tstest.PIDH264, &h264Continuity, tstest.PES(false, 93000, 93000, []byte{0x00, 0x00, 0x01, 0x41, 0x01}))...)
+	stream = append(stream, tstest.Packets(tstest.PIDH264, &h264Continuity, tstest.PES(false, 96000, 96000, []byte{0x00, 0x00, 0x01, 0x41, 0x02}))...)
+
+	source, err := NewSource(bytes.NewReader(stream))
+	assert.NoError(t, err)
+	assert.True(t, source.HasVideo())
+	assert.Equal(t, &AudioConfig{AudioSpecificConfig: []byte{0x11, 0x90}, SampleRate: 48000, Channels: 2}, source.Audio())
+
+	video, audio := &sampleWriter{}, &sampleWriter{err: io.ErrClosedPipe}
+	assert.NoError(t, source.Play(video, audio))
+
+	// Each access unit lasts until the next one, the last one is written
+	// at the end of the stream
+	assert.Equal(t, []media.Sample{
+		{Data: keyframe, Samples: 3000},
+		{Data: []byte{0x00, 0x00, 0x01, 0x41, 0x01}, Samples: 3000},
+		{Data: []byte{0x00, 0x00, 0x01, 0x41, 0x02}},
+	}, video.samples)
+
+	// The ADTS headers are removed from the frames
+	assert.Equal(t, []media.Sample{
+		{Data: []byte{0x01, 0x02}, Samples: aacpacketizer.SamplesPerFrame},
+		{Data: []byte{0x03}, Samples: aacpacketizer.SamplesPerFrame},
+	}, audio.samples)
+}
+
+func TestSource_NoStreams(t *testing.T) {
+	_, err := NewSource(bytes.NewReader(nil))
+	assert.Equal(t, errNoStreams, err)
+}
@@ -0,0 +1,115 @@
+package webmwriter
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// EBML and Matroska element IDs used by the writer, the marker bits
+// are part of the ID
+const (
+	idEBML               = 0x1A45DFA3
+	idEBMLVersion        = 0x4286
+	idEBMLReadVersion    = 0x42F7
+	idEBMLMaxIDLength    = 0x42F2
+	idEBMLMaxSizeLength  = 0x42F3
+	idDocType            = 0x4282
+	idDocTypeVersion     = 0x4287
+	idDocTypeReadVersion = 0x4285
+
+	idSegment       = 0x18538067
+	idInfo          = 0x1549A966
+	idTimecodeScale = 0x2AD7B1
+	idMuxingApp     = 0x4D80
+	idWritingApp    = 0x5741
+
+	idTracks            = 0x1654AE6B
+	idTrackEntry        = 0xAE
+	idTrackNumber       = 0xD7
+	idTrackUID          = 0x73C5
+	idTrackType         = 0x83
+	idCodecID           = 0x86
+	idCodecPrivate      = 0x63A2
+	idVideo             = 0xE0
+	idPixelWidth        = 0xB0
+	idPixelHeight       = 0xBA
+	idAudio             = 0xE1
+	idSamplingFrequency = 0xB5
+	idChannels          = 0x9F
+
+	idCluster     = 0x1F43B675
+	idTimecode    = 0xE7
+	idSimpleBlock = 0xA3
+)
+
+// unknownSize is the reserved all ones size, used for elements
+// whose size isn't known when they are started
+var unknownSize = []byte{0x01, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF} //nolint:gochecknoglobals
+
+func ebmlID(id uint32) []byte {
+	switch {
+	case id > 0xFFFFFF:
+		return []byte{byte(id >> 24), byte(id >> 16), byte(id >> 8), byte(id)}
+	case id > 0xFFFF:
+		return []byte{byte(id >> 16), byte(id >> 8), byte(id)}
+	case id > 0xFF:
+		return []byte{byte(id >> 8), byte(id)}
+	default:
+		return []byte{byte(id)}
+	}
+}
+
+// ebmlSize encodes size as a variable length integer using the
+// smallest possible length. The all ones value is reserved.
+func ebmlSize(size uint64) []byte {
+	length := 1
+	for length < 8 && size >= (uint64(1)<<(7*uint(length)))-1 {
+		length++
+	}
+
+	out := make([]byte, length)
+	for i := length - 1; i >= 0; i-- {
+		out[i] = byte(size)
+		size >>= 8
+	}
+	out[0] |= 0x80 >> uint(length-1)
+
+	return out
+}
+
+func ebmlElement(id uint32, data []byte) []byte {
+	out := append(ebmlID(id), ebmlSize(uint64(len(data)))...)
+	return append(out, data...)
+}
+
+func ebmlMaster(id uint32, children ...[]byte) []byte {
+	data := []byte{}
+	for _, c := range children {
+		data = append(data, c...)
+	}
+
+	return ebmlElement(id, data)
+}
+
+func ebmlUint(id uint32, v uint64) []byte {
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint64(data, v)
+
+	i := 0
+	for i < 7 && data[i] == 0 {
+		i++
+	}
+
+	return ebmlElement(id, data[i:])
+}
+
+func ebmlFloat(id uint32, v float64) []byte {
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint64(data, math.Float64bits(v))
+
+	return ebmlElement(id, data)
+}
+
+func ebmlString(id uint32, s string) []byte {
+	return ebmlElement(id, []byte(s))
+}
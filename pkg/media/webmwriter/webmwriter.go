@@ -0,0 +1,432 @@
+// Package webmwriter implements WebM media container writer
+package webmwriter
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"os"
+
+	"github.com/pion/randutil"
+	"github.com/pion/rtp"
+	"github.com/pion/rtp/codecs"
+	"github.com/pion/webrtc/v3/pkg/media"
+	"github.com/pion/webrtc/v3/pkg/media/samplebuilder"
+)
+
+// Codec IDs of the supported codecs
+const (
+	CodecVP8  = "V_VP8"
+	CodecVP9  = "V_VP9"
+	CodecOpus = "A_OPUS"
+)
+
+const (
+	trackTypeVideo = 1
+	trackTypeAudio = 2
+
+	videoClockRate = 90000
+	opusClockRate  = 48000
+
+	// Timecodes are written in milliseconds
+	timecodeScale = 1000000
+
+	videoMaxLate = 256
+	audioMaxLate = 32
+)
+
+var (
+	errFileNotOpened    = errors.New("file not opened")
+	errInvalidNilPacket = errors.New("invalid nil packet")
+	errNoTracks         = errors.New("at least one track must be configured")
+	errUnsupportedCodec = errors.New("unsupported codec")
+	errNoVideoTrack     = errors.New("no video track configured")
+	errNoAudioTrack     = errors.New("no audio track configured")
+)
+
+type track struct {
+	number    uint64
+	clockRate uint32
+	builder   *samplebuilder.SampleBuilder
+
+	// elapsed is the time of the next sample in clockRate units
+	elapsed       uint64
+	lastTimestamp uint32
+	hasTimestamp  bool
+}
+
+// timecode returns the time of the next sample in milliseconds
+func (t *track) timecode() int64 {
+	return int64(t.elapsed * 1000 / uint64(t.clockRate))
+}
+
+// WebMWriter is used to take samples or RTP packets of a VP8/VP9 video
+// track and an Opus audio track and write them to a WebM file on disk
+type WebMWriter struct {
+	ioWriter io.Writer
+
+	videoCodec               string
+	videoWidth, videoHeight  uint64
+	audioSampleRate          uint32
+	audioChannels            uint16
+	video, audio             *track
+	seenKeyFrame             bool
+	cluster                  []byte
+	clusterTimecode          int64
+	clusterHasVideoKeyFrames bool
+}
+
+// Option configures a WebMWriter
+type Option func(w *WebMWriter) error
+
+// WithVideoTrack adds a video track with the given codec and resolution,
+// codec must be either CodecVP8 or CodecVP9
+func WithVideoTrack(codec string, width, height uint64) Option {
+	return func(w *WebMWriter) error {
+		if codec != CodecVP8 && codec != CodecVP9 {
+			return errUnsupportedCodec
+		}
+
+		w.videoCodec = codec
+		w.videoWidth = width
+		w.videoHeight = height
+		w.video = &track{clockRate: videoClockRate}
+
+		return nil
+	}
+}
+
+// WithOpusTrack adds an Opus audio track
+func WithOpusTrack(sampleRate uint32, channels uint16) Option {
+	return func(w *WebMWriter) error {
+		w.audioSampleRate = sampleRate
+		w.audioChannels = channels
+		w.audio = &track{clockRate: opusClockRate}
+
+		return nil
+	}
+}
+
+// New builds a new WebM writer
+func New(fileName string, opts ...Option) (*WebMWriter, error) {
+	f, err := os.Create(fileName)
+	if err != nil {
+		return nil, err
+	}
+	writer, err := NewWith(f, opts...)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return writer, nil
+}
+
+// NewWith initialize a new WebM writer with an io.Writer output
+func NewWith(out io.Writer, opts ...Option) (*WebMWriter, error) {
+	if out == nil {
+		return nil, errFileNotOpened
+	}
+
+	writer := &WebMWriter{
+		ioWriter: out,
+	}
+	for _, o := range opts {
+		if err := o(writer); err != nil {
+			return nil, err
+		}
+	}
+
+	if writer.video == nil && writer.audio == nil {
+		return nil, errNoTracks
+	}
+
+	trackNumber := uint64(1)
+	if writer.video != nil {
+		writer.video.number = trackNumber
+		trackNumber++
+
+		var depacketizer rtp.Depacketizer = &codecs.VP8Packet{}
+		var checker rtp.PartitionHeadChecker = &codecs.VP8PartitionHeadChecker{}
+		if writer.videoCodec == CodecVP9 {
+			depacketizer = &codecs.VP9Packet{}
+			checker = &codecs.VP9PartitionHeadChecker{}
+		}
+		writer.video.builder = samplebuilder.New(videoMaxLate, depacketizer, samplebuilder.WithPartitionHeadChecker(checker))
+	}
+	if writer.audio != nil {
+		writer.audio.number = trackNumber
+		writer.audio.builder = samplebuilder.New(audioMaxLate, &codecs.OpusPacket{}, samplebuilder.WithPartitionHeadChecker(&codecs.OpusPartitionHeadChecker{}))
+	}
+
+	if err := writer.writeHeader(); err != nil {
+		return nil, err
+	}
+
+	return writer, nil
+}
+
+func (w *WebMWriter) writeHeader() error {
+	header := ebmlMaster(idEBML,
+		ebmlUint(idEBMLVersion, 1),
+		ebmlUint(idEBMLReadVersion, 1),
+		ebmlUint(idEBMLMaxIDLength, 4),
+		ebmlUint(idEBMLMaxSizeLength, 8),
+		ebmlString(idDocType, "webm"),
+		ebmlUint(idDocTypeVersion, 4),
+		ebmlUint(idDocTypeReadVersion, 2),
+	)
+
+	// The Segment size isn't known until the recording is stopped, and
+	// may not be updatable if the output isn't seekable
+	header = append(header, ebmlID(idSegment)...)
+	header = append(header, unknownSize...)
+
+	header = append(header, ebmlMaster(idInfo,
+		ebmlUint(idTimecodeScale, timecodeScale),
+		ebmlString(idMuxingApp, "pion"),
+		ebmlString(idWritingApp, "pion"),
+	)...)
+
+	rng := randutil.NewMathRandomGenerator()
+	tracks := [][]byte{}
+	if w.video != nil {
+		tracks = append(tracks, ebmlMaster(idTrackEntry,
+			ebmlUint(idTrackNumber, w.video.number),
+			ebmlUint(idTrackUID, uint64(rng.Uint32())),
+			ebmlUint(idTrackType, trackTypeVideo),
+			ebmlString(idCodecID, w.videoCodec),
+			ebmlMaster(idVideo,
+				ebmlUint(idPixelWidth, w.videoWidth),
+				ebmlUint(idPixelHeight, w.videoHeight),
+			),
+		))
+	}
+	if w.audio != nil {
+		tracks = append(tracks, ebmlMaster(idTrackEntry,
+			ebmlUint(idTrackNumber, w.audio.number),
+			ebmlUint(idTrackUID, uint64(rng.Uint32())),
+			ebmlUint(idTrackType, trackTypeAudio),
+			ebmlString(idCodecID, CodecOpus),
+			ebmlElement(idCodecPrivate, opusHead(w.audioSampleRate, w.audioChannels)),
+			ebmlMaster(idAudio,
+				ebmlFloat(idSamplingFrequency, float64(w.audioSampleRate)),
+				ebmlUint(idChannels, uint64(w.audioChannels)),
+			),
+		))
+	}
+	header = append(header, ebmlMaster(idTracks, tracks...)...)
+
+	_, err := w.ioWriter.Write(header)
+	return err
+}
+
+// opusHead builds the identification header, see RFC 7845 Section 5.1
+func opusHead(sampleRate uint32, channels uint16) []byte {
+	head := make([]byte, 19)
+	copy(head[0:], "OpusHead")
+	head[8] = 1                                          // Version
+	head[9] = uint8(channels)                            // Channel count
+	binary.LittleEndian.PutUint16(head[10:], 0)          // Pre-skip
+	binary.LittleEndian.PutUint32(head[12:], sampleRate) // Input sample rate
+	binary.LittleEndian.PutUint16(head[16:], 0)          // Output gain
+	head[18] = 0                                         // Channel map 0 = one stream: mono or stereo
+
+	return head
+}
+
+// WriteVideoSample writes a complete VP8 or VP9 frame. sample.Samples is
+// the duration of the frame in 90kHz units, and is used to compute the
+// timestamp of the next frame.
+func (w *WebMWriter) WriteVideoSample(sample media.Sample) error {
+	if w.video == nil {
+		return errNoVideoTrack
+	}
+
+	err := w.writeVideoFrame(sample.Data)
+	w.video.elapsed += uint64(sample.Samples)
+	return err
+}
+
+// WriteAudioSample writes a complete Opus packet. sample.Samples is
+// the duration of the packet in 48kHz units, and is used to compute the
+// timestamp of the next packet.
+func (w *WebMWriter) WriteAudioSample(sample media.Sample) error {
+	if w.audio == nil {
+		return errNoAudioTrack
+	}
+
+	err := w.writeBlock(w.audio, true, sample.Data)
+	w.audio.elapsed += uint64(sample.Samples)
+	return err
+}
+
+// WriteVideoRTP adds a VP8 or VP9 RTP packet, frames are written once
+// they have been fully received. The first packet is at time zero.
+func (w *WebMWriter) WriteVideoRTP(packet *rtp.Packet) error {
+	if w.video == nil {
+		return errNoVideoTrack
+	} else if packet == nil {
+		return errInvalidNilPacket
+	}
+
+	w.video.builder.Push(packet)
+	for {
+		sample, timestamp := w.video.builder.PopWithTimestamp()
+		if sample == nil {
+			return nil
+		}
+
+		w.video.updateTimestamp(timestamp)
+		if err := w.writeVideoFrame(sample.Data); err != nil {
+			return err
+		}
+	}
+}
+
+// WriteAudioRTP adds an Opus RTP packet. The first packet is at time zero.
+func (w *WebMWriter) WriteAudioRTP(packet *rtp.Packet) error {
+	if w.audio == nil {
+		return errNoAudioTrack
+	} else if packet == nil {
+		return errInvalidNilPacket
+	}
+
+	w.audio.builder.Push(packet)
+	for {
+		sample, timestamp := w.audio.builder.PopWithTimestamp()
+		if sample == nil {
+			return nil
+		}
+
+		w.audio.updateTimestamp(timestamp)
+		if err := w.writeBlock(w.audio, true, sample.Data); err != nil {
+			return err
+		}
+	}
+}
+
+// updateTimestamp sets the time of the next sample from its RTP timestamp
+func (t *track) updateTimestamp(timestamp uint32) {
+	if t.hasTimestamp {
+		t.elapsed += uint64(timestamp - t.lastTimestamp)
+	}
+	t.hasTimestamp = true
+	t.lastTimestamp = timestamp
+}
+
+func (w *WebMWriter) writeVideoFrame(frame []byte) error {
+	if len(frame) == 0 {
+		return nil
+	}
+
+	isKeyFrame := false
+	if w.videoCodec == CodecVP9 {
+		isKeyFrame = isVP9KeyFrame(frame)
+	} else {
+		isKeyFrame = frame[0]&0x01 == 0
+	}
+
+	// Playback can only start at a keyframe
+	if !w.seenKeyFrame && !isKeyFrame {
+		return nil
+	}
+	w.seenKeyFrame = true
+
+	return w.writeBlock(w.video, isKeyFrame, frame)
+}
+
+// isVP9KeyFrame parses the start of the uncompressed header,
+// see VP9 Bitstream Specification Section 6.2
+func isVP9KeyFrame(frame []byte) bool {
+	b := frame[0]
+	if b>>6 != 0x2 { // frame_marker
+		return false
+	}
+
+	profile := (b>>5)&0x1 | ((b>>4)&0x1)<<1
+	bit := uint(3)
+	if profile == 3 {
+		bit-- // reserved_zero
+	}
+
+	if (b>>bit)&0x1 == 1 { // show_existing_frame
+		return false
+	}
+
+	return (b>>(bit-1))&0x1 == 0 // frame_type
+}
+
+func (w *WebMWriter) writeBlock(t *track, isKeyFrame bool, data []byte) error {
+	if w.ioWriter == nil {
+		return errFileNotOpened
+	}
+
+	timecode := t.timecode()
+
+	// Start a new cluster on every video keyframe so players can seek to it,
+	// or if the block timecode can't be stored relative to the cluster
+	startCluster := w.cluster == nil ||
+		(t == w.video && isKeyFrame && w.clusterHasVideoKeyFrames) ||
+		timecode-w.clusterTimecode > math.MaxInt16 ||
+		timecode-w.clusterTimecode < math.MinInt16
+	if startCluster {
+		if err := w.flushCluster(); err != nil {
+			return err
+		}
+		w.cluster = ebmlUint(idTimecode, uint64(timecode))
+		w.clusterTimecode = timecode
+		w.clusterHasVideoKeyFrames = false
+	}
+
+	if t == w.video && isKeyFrame {
+		w.clusterHasVideoKeyFrames = true
+	}
+
+	flags := byte(0)
+	if isKeyFrame {
+		flags |= 0x80
+	}
+
+	block := append(ebmlSize(t.number), 0, 0, flags)
+	binary.BigEndian.PutUint16(block[len(block)-3:], uint16(int16(timecode-w.clusterTimecode)))
+	block = append(block, data...)
+
+	w.cluster = append(w.cluster, ebmlElement(idSimpleBlock, block)...)
+	return nil
+}
+
+func (w *WebMWriter) flushCluster() error {
+	if w.cluster == nil {
+		return nil
+	}
+
+	cluster := ebmlElement(idCluster, w.cluster)
+	w.cluster = nil
+
+	_, err := w.ioWriter.Write(cluster)
+	return err
+}
+
+// Close stops the recording
+func (w *WebMWriter) Close() error {
+	if w.ioWriter == nil {
+		// Returns no error as it may be convenient to call
+		// Close() multiple times
+		return nil
+	}
+
+	defer func() {
+		w.ioWriter = nil
+	}()
+
+	if err := w.flushCluster(); err != nil {
+		return err
+	}
+
+	if closer, ok := w.ioWriter.(io.Closer); ok {
+		return closer.Close()
+	}
+
+	return nil
+}
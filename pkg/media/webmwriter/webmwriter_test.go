@@ -0,0 +1,199 @@
+package webmwriter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3/pkg/media"
+	"github.com/stretchr/testify/assert"
+)
+
+type simpleBlock struct {
+	track      uint64
+	timecode   int64
+	isKeyFrame bool
+	data       []byte
+}
+
+func readVint(b []byte, keepMarker bool) (uint64, int) {
+	length := 1
+	for length <= 8 && b[0]&(0x80>>uint(length-1)) == 0 {
+		length++
+	}
+
+	v := uint64(b[0])
+	if !keepMarker {
+		v &= uint64(0xFF >> uint(length))
+	}
+	for i := 1; i < length; i++ {
+		v = v<<8 | uint64(b[i])
+	}
+	return v, length
+}
+
+// parseClusters walks the file and returns all SimpleBlocks with absolute timecodes
+func parseClusters(t *testing.T, b []byte) (tracks int, blocks []simpleBlock) {
+	id, n := readVint(b, true)
+	assert.Equal(t, uint64(idEBML), id)
+	size, m := readVint(b[n:], false)
+	assert.True(t, bytes.Contains(b[n+m:n+m+int(size)], []byte("webm")))
+	b = b[n+m+int(size):]
+
+	id, n = readVint(b, true)
+	assert.Equal(t, uint64(idSegment), id)
+	assert.Equal(t, unknownSize, b[n:n+8])
+	b = b[n+8:]
+
+	for len(b) > 0 {
+		id, n = readVint(b, true)
+		size, m = readVint(b[n:], false)
+		data := b[n+m : n+m+int(size)]
+		b = b[n+m+int(size):]
+
+		switch id {
+		case idTracks:
+			for len(data) > 0 {
+				_, n = readVint(data, true)
+				size, m = readVint(data[n:], false)
+				data = data[n+m+int(size):]
+				tracks++
+			}
+		case idCluster:
+			clusterTimecode := int64(-1)
+			for len(data) > 0 {
+				id, n = readVint(data, true)
+				size, m = readVint(data[n:], false)
+				child := data[n+m : n+m+int(size)]
+				data = data[n+m+int(size):]
+
+				switch id {
+				case idTimecode:
+					v := uint64(0)
+					for _, c := range child {
+						v = v<<8 | uint64(c)
+					}
+					clusterTimecode = int64(v)
+				case idSimpleBlock:
+					assert.NotEqual(t, int64(-1), clusterTimecode, "Timecode must precede blocks")
+					track, k := readVint(child, false)
+					blocks = append(blocks, simpleBlock{
+						track:      track,
+						timecode:   clusterTimecode + int64(int16(binary.BigEndian.Uint16(child[k:]))),
+						isKeyFrame: child[k+2]&0x80 != 0,
+						data:       child[k+3:],
+					})
+				}
+			}
+		}
+	}
+
+	return tracks, blocks
+}
+
+func TestWebMWriter_Samples(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	writer, err := NewWith(buffer, WithVideoTrack(CodecVP8, 640, 480), WithOpusTrack(48000, 2))
+	assert.NoError(t, err)
+
+	interFrame := []byte{0x01, 0xAA}
+	keyFrame := []byte{0x00, 0xBB}
+
+	// Inter frames before the first keyframe are dropped
+	assert.NoError(t, writer.WriteVideoSample(media.Sample{Data: interFrame, Samples: 3000}))
+	assert.NoError(t, writer.WriteVideoSample(media.Sample{Data: keyFrame, Samples: 3000}))
+	assert.NoError(t, writer.WriteAudioSample(media.Sample{Data: []byte{0x01}, Samples: 960}))
+	assert.NoError(t, writer.WriteVideoSample(media.Sample{Data: interFrame, Samples: 3000}))
+	assert.NoError(t, writer.WriteAudioSample(media.Sample{Data: []byte{0x02}, Samples: 960}))
+	assert.NoError(t, writer.WriteVideoSample(media.Sample{Data: keyFrame, Samples: 3000}))
+	assert.NoError(t, writer.Close())
+	assert.NoError(t, writer.Close())
+
+	tracks, blocks := parseClusters(t, buffer.Bytes())
+	assert.Equal(t, 2, tracks)
+	assert.Equal(t, []simpleBlock{
+		{track: 1, timecode: 33, isKeyFrame: true, data: keyFrame},
+		{track: 2, timecode: 0, isKeyFrame: true, data: []byte{0x01}},
+		{track: 1, timecode: 66, isKeyFrame: false, data: interFrame},
+		{track: 2, timecode: 20, isKeyFrame: true, data: []byte{0x02}},
+		{track: 1, timecode: 100, isKeyFrame: true, data: keyFrame},
+	}, blocks)
+
+	assert.Equal(t, 2, bytes.Count(buffer.Bytes(), ebmlID(idCluster)), "Each keyframe should start a cluster")
+}
+
+func TestWebMWriter_LongRecording(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	writer, err := NewWith(buffer, WithOpusTrack(48000, 2))
+	assert.NoError(t, err)
+
+	// 100 seconds of audio doesn't fit into a single cluster
+	for i := 0; i < 5000; i++ {
+		assert.NoError(t, writer.WriteAudioSample(media.Sample{Data: []byte{0x00}, Samples: 960}))
+	}
+	assert.NoError(t, writer.Close())
+
+	_, blocks := parseClusters(t, buffer.Bytes())
+	assert.Equal(t, 5000, len(blocks))
+	for i, b := range blocks {
+		assert.Equal(t, int64(i*20), b.timecode)
+	}
+}
+
+func TestWebMWriter_RTP(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	writer, err := NewWith(buffer, WithVideoTrack(CodecVP8, 640, 480))
+	assert.NoError(t, err)
+
+	// VP8 payload descriptor with the start of partition bit set, followed by the frame
+	packets := []*rtp.Packet{
+		{Header: rtp.Header{SequenceNumber: 10, Timestamp: 1000, Marker: true}, Payload: []byte{0x10, 0x00, 0x01, 0x02}},
+		{Header: rtp.Header{SequenceNumber: 11, Timestamp: 4000}, Payload: []byte{0x10, 0x01, 0x02, 0x03}},
+		{Header: rtp.Header{SequenceNumber: 12, Timestamp: 4000, Marker: true}, Payload: []byte{0x00, 0x04, 0x05, 0x06}},
+		{Header: rtp.Header{SequenceNumber: 13, Timestamp: 7000, Marker: true}, Payload: []byte{0x10, 0x00, 0x07, 0x08}},
+	}
+	for _, p := range packets {
+		assert.NoError(t, writer.WriteVideoRTP(p))
+	}
+	assert.Equal(t, errInvalidNilPacket, writer.WriteVideoRTP(nil))
+	assert.Equal(t, errNoAudioTrack, writer.WriteAudioRTP(packets[0]))
+	assert.NoError(t, writer.Close())
+
+	_, blocks := parseClusters(t, buffer.Bytes())
+	assert.Equal(t, []simpleBlock{
+		{track: 1, timecode: 0, isKeyFrame: true, data: []byte{0x00, 0x01, 0x02}},
+		{track: 1, timecode: 33, isKeyFrame: false, data: []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06}},
+	}, blocks)
+}
+
+func TestWebMWriter_VP9KeyFrame(t *testing.T) {
+	assert.True(t, isVP9KeyFrame([]byte{0x82}))  // profile 0, keyframe
+	assert.False(t, isVP9KeyFrame([]byte{0x86})) // profile 0, inter frame
+	assert.False(t, isVP9KeyFrame([]byte{0x88})) // profile 0, show existing frame
+	assert.True(t, isVP9KeyFrame([]byte{0xB0}))  // profile 3, keyframe
+	assert.False(t, isVP9KeyFrame([]byte{0xB2})) // profile 3, inter frame
+	assert.False(t, isVP9KeyFrame([]byte{0x00})) // invalid frame marker
+}
+
+func TestWebMWriter_Options(t *testing.T) {
+	_, err := NewWith(nil, WithOpusTrack(48000, 2))
+	assert.Equal(t, errFileNotOpened, err)
+
+	_, err = NewWith(&bytes.Buffer{})
+	assert.Equal(t, errNoTracks, err)
+
+	_, err = NewWith(&bytes.Buffer{}, WithVideoTrack("V_MPEG4/ISO/AVC", 640, 480))
+	assert.Equal(t, errUnsupportedCodec, err)
+
+	writer, err := NewWith(&bytes.Buffer{}, WithOpusTrack(48000, 2))
+	assert.NoError(t, err)
+	assert.Equal(t, errNoVideoTrack, writer.WriteVideoSample(media.Sample{}))
+}
+
+func TestEBMLSize(t *testing.T) {
+	assert.Equal(t, []byte{0x81}, ebmlSize(1))
+	assert.Equal(t, []byte{0x40, 0x7F}, ebmlSize(127))
+	assert.Equal(t, []byte{0x7F, 0xFE}, ebmlSize(16382))
+	assert.Equal(t, []byte{0x20, 0x3F, 0xFF}, ebmlSize(16383))
+}
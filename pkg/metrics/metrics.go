@@ -0,0 +1,352 @@
+// +build !js
+
+// Package metrics exports the stats of PeerConnections in the Prometheus
+// text exposition format, so media servers can be scraped by Prometheus
+// without glue code. Bitrates are derived from the byte counters with rate().
+//
+// The module doesn't depend on the Prometheus client library, so Exporter
+// isn't a prometheus.Collector that can be registered with a Registry. It is
+// an http.Handler of its own, to be scraped on a path next to the one of the
+// Registry, like /metrics/webrtc.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pion/webrtc/v3"
+)
+
+// ContentType is the Content-Type of the Prometheus text exposition format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// iceTransportStatsID is the ID of the TransportStats of the ICE transport,
+// those of the SCTP transport count the same bytes again
+const iceTransportStatsID = "iceTransport"
+
+// states are the states PeerConnections are counted by
+var states = []webrtc.PeerConnectionState{
+	webrtc.PeerConnectionStateNew,
+	webrtc.PeerConnectionStateConnecting,
+	webrtc.PeerConnectionStateConnected,
+	webrtc.PeerConnectionStateDisconnected,
+	webrtc.PeerConnectionStateFailed,
+	webrtc.PeerConnectionStateClosed,
+}
+
+// Exporter is the http.Handler of the metrics of the PeerConnections added
+// to it. They are aggregated over all the PeerConnections, the counters
+// keep those of the removed ones. Gauges of times are averaged:
+//
+//	webrtc_peer_connections{state}
+//	webrtc_transport_bytes_sent_total
+//	webrtc_transport_bytes_received_total
+//	webrtc_candidate_pair_round_trip_time_seconds
+//	webrtc_ice_candidates{side,candidate_type}
+//	webrtc_inbound_rtp_packets_received_total{kind}
+//	webrtc_inbound_rtp_bytes_received_total{kind}
+//	webrtc_inbound_rtp_packets_lost{kind}
+//	webrtc_inbound_rtp_jitter_seconds{kind}
+//	webrtc_remote_outbound_rtp_round_trip_time_seconds{kind}
+//
+// WithPeerConnectionLabels exports them for each PeerConnection instead.
+type Exporter struct {
+	peerConnectionLabels bool
+
+	mu sync.Mutex
+
+	// peerConnections are the counters of each PeerConnection at the last
+	// scrape, which are added to removed once it is removed
+	peerConnections map[*webrtc.PeerConnection]counters
+	removed         counters
+}
+
+// counters are the values of the series of counters, by series
+type counters map[string]float64
+
+// Option configures an Exporter
+type Option func(e *Exporter)
+
+// WithPeerConnectionLabels exports the metrics of each PeerConnection, with
+// the ID of its stats as the peer_connection label, and those of RTP streams
+// with their ssrc label. The number of series grows with the number of
+// PeerConnections, and they end when the PeerConnection is removed.
+func WithPeerConnectionLabels() Option {
+	return func(e *Exporter) {
+		e.peerConnectionLabels = true
+	}
+}
+
+// NewExporter creates an Exporter without PeerConnections
+func NewExporter(opts ...Option) *Exporter {
+	e := &Exporter{
+		peerConnections: map[*webrtc.PeerConnection]counters{},
+		removed:         counters{},
+	}
+	for _, o := range opts {
+		o(e)
+	}
+
+	return e
+}
+
+// Add exports the metrics of pc. It is removed once it is closed, after its
+// last metrics were exported.
+func (e *Exporter) Add(pc *webrtc.PeerConnection) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, ok := e.peerConnections[pc]; !ok {
+		e.peerConnections[pc] = counters{}
+	}
+}
+
+// Remove stops exporting the metrics of pc
+func (e *Exporter) Remove(pc *webrtc.PeerConnection) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.remove(pc)
+}
+
+// remove keeps the last counters of pc when they are aggregated, so they
+// don't decrease
+func (e *Exporter) remove(pc *webrtc.PeerConnection) {
+	last, ok := e.peerConnections[pc]
+	if !ok {
+		return
+	}
+	delete(e.peerConnections, pc)
+
+	if !e.peerConnectionLabels {
+		for series, value := range last {
+			e.removed[series] += value
+		}
+	}
+}
+
+// ServeHTTP implements http.Handler
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", ContentType)
+	_ = e.WriteMetrics(w)
+}
+
+// family is a metric and its series
+type family struct {
+	name, help, kind string
+
+	// average averages the values added to a series, instead of summing them
+	average bool
+
+	series map[string]*value
+}
+
+// value is the sum of the values added to a series, and their number
+type value struct {
+	sum   float64
+	count int
+}
+
+// add adds v to the series of labels, and returns the series
+func (f *family) add(v float64, labels ...string) string {
+	pairs := make([]string, 0, len(labels)/2)
+	for i := 0; i+1 < len(labels); i += 2 {
+		pairs = append(pairs, labels[i]+`="`+labelValueReplacer.Replace(labels[i+1])+`"`)
+	}
+	series := f.name
+	if len(pairs) != 0 {
+		series += "{" + strings.Join(pairs, ",") + "}"
+	}
+	f.addSeries(series, v)
+	return series
+}
+
+// addSeries adds v to series
+func (f *family) addSeries(series string, v float64) {
+	if f.series == nil {
+		f.series = map[string]*value{}
+	}
+	if _, ok := f.series[series]; !ok {
+		f.series[series] = &value{}
+	}
+	f.series[series].sum += v
+	f.series[series].count++
+}
+
+// samples returns the samples of the series, sorted
+func (f *family) samples() []string {
+	samples := make([]string, 0, len(f.series))
+	for series, v := range f.series {
+		sum := v.sum
+		if f.average {
+			sum /= float64(v.count)
+		}
+		samples = append(samples, series+" "+strconv.FormatFloat(sum, 'g', -1, 64))
+	}
+	sort.Strings(samples)
+	return samples
+}
+
+// labelValueReplacer escapes label values as the text format does
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// WriteMetrics writes the metrics of the PeerConnections to w in the
+// Prometheus text exposition format
+func (e *Exporter) WriteMetrics(w io.Writer) error {
+	peerConnections := e.collect()
+
+	connections := &family{name: "webrtc_peer_connections", help: "PeerConnections by connection state.", kind: "gauge"}
+	bytesSent := &family{name: "webrtc_transport_bytes_sent_total", help: "Bytes sent on the ICE transport.", kind: "counter"}
+	bytesReceived := &family{name: "webrtc_transport_bytes_received_total", help: "Bytes received on the ICE transport.", kind: "counter"}
+	pairRTT := &family{name: "webrtc_candidate_pair_round_trip_time_seconds", help: "Round trip time of the nominated ICE candidate pair.", kind: "gauge", average: true}
+	candidates := &family{name: "webrtc_ice_candidates", help: "ICE candidates by side and candidate type.", kind: "gauge"}
+	packetsReceived := &family{name: "webrtc_inbound_rtp_packets_received_total", help: "RTP packets received.", kind: "counter"}
+	rtpBytesReceived := &family{name: "webrtc_inbound_rtp_bytes_received_total", help: "RTP payload and padding bytes received.", kind: "counter"}
+	packetsLost := &family{name: "webrtc_inbound_rtp_packets_lost", help: "RTP packets lost.", kind: "gauge"}
+	jitter := &family{name: "webrtc_inbound_rtp_jitter_seconds", help: "Interarrival jitter of RTP packets.", kind: "gauge", average: true}
+	remoteRTT := &family{name: "webrtc_remote_outbound_rtp_round_trip_time_seconds", help: "Round trip time measured with RTCP extended reports.", kind: "gauge", average: true}
+	families := []*family{connections, bytesSent, bytesReceived, pairRTT, candidates, packetsReceived, rtpBytesReceived, packetsLost, jitter, remoteRTT}
+
+	counts := map[webrtc.PeerConnectionState]int{}
+	for i := range peerConnections {
+		pc := &peerConnections[i]
+		counts[pc.state]++
+
+		// The labels of the PeerConnection and of its RTP streams
+		pcLabels := []string{}
+		if e.peerConnectionLabels {
+			pcLabels = []string{"peer_connection", pc.id}
+		}
+		streamLabels := func(ssrc uint32, kind string) []string {
+			if e.peerConnectionLabels {
+				return append(pcLabels, "ssrc", strconv.FormatUint(uint64(ssrc), 10), "kind", kind)
+			}
+			return []string{"kind", kind}
+		}
+		addCounter := func(f *family, v float64, labels ...string) {
+			pc.counters[f.add(v, labels...)] += v
+		}
+
+		candidateCounts := map[[2]string]int{}
+		for _, s := range pc.stats {
+			switch stats := s.(type) {
+			case webrtc.TransportStats:
+				if stats.ID != iceTransportStatsID {
+					continue
+				}
+				addCounter(bytesSent, float64(stats.BytesSent), pcLabels...)
+				addCounter(bytesReceived, float64(stats.BytesReceived), pcLabels...)
+			case webrtc.ICECandidatePairStats:
+				if stats.Nominated {
+					pairRTT.add(stats.CurrentRoundTripTime, pcLabels...)
+				}
+			case webrtc.ICECandidateStats:
+				side := "local"
+				if stats.Type == webrtc.StatsTypeRemoteCandidate {
+					side = "remote"
+				}
+				candidateCounts[[2]string{side, stats.CandidateType.String()}]++
+			case webrtc.InboundRTPStreamStats:
+				labels := streamLabels(stats.SSRC, stats.Kind)
+				addCounter(packetsReceived, float64(stats.PacketsReceived), labels...)
+				addCounter(rtpBytesReceived, float64(stats.BytesReceived), labels...)
+				packetsLost.add(float64(stats.PacketsLost), labels...)
+				jitter.add(stats.Jitter, labels...)
+			case webrtc.RemoteOutboundRTPStreamStats:
+				remoteRTT.add(stats.RoundTripTime, streamLabels(stats.SSRC, stats.Kind)...)
+			}
+		}
+
+		for key, count := range candidateCounts {
+			candidates.add(float64(count), append(pcLabels, "side", key[0], "candidate_type", key[1])...)
+		}
+	}
+	for _, state := range states {
+		connections.add(float64(counts[state]), "state", state.String())
+	}
+
+	// The counters of the removed PeerConnections are added to their series
+	byName := make(map[string]*family, len(families))
+	for _, f := range families {
+		byName[f.name] = f
+	}
+	for series, v := range e.update(peerConnections) {
+		name := series
+		if i := strings.IndexByte(series, '{'); i != -1 {
+			name = series[:i]
+		}
+		if f, ok := byName[name]; ok {
+			f.addSeries(series, v)
+		}
+	}
+
+	for _, f := range families {
+		if len(f.series) == 0 {
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s\n", f.name, f.help, f.name, f.kind, strings.Join(f.samples(), "\n")); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// update keeps the counters of the scraped PeerConnections and removes the
+// closed ones. It returns the counters of the PeerConnections removed
+// before, the closed ones are still exported by this scrape.
+func (e *Exporter) update(peerConnections []peerConnectionStats) counters {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	removed := make(counters, len(e.removed))
+	for series, v := range e.removed {
+		removed[series] = v
+	}
+
+	for _, pc := range peerConnections {
+		if _, ok := e.peerConnections[pc.pc]; !ok {
+			continue
+		}
+		e.peerConnections[pc.pc] = pc.counters
+		if pc.state == webrtc.PeerConnectionStateClosed {
+			e.remove(pc.pc)
+		}
+	}
+
+	return removed
+}
+
+// peerConnectionStats are the stats of a PeerConnection at a scrape
+type peerConnectionStats struct {
+	pc    *webrtc.PeerConnection
+	id    string
+	state webrtc.PeerConnectionState
+	stats webrtc.StatsReport
+
+	// counters are the series of counters of the PeerConnection
+	counters counters
+}
+
+// collect returns the stats of the PeerConnections
+func (e *Exporter) collect() []peerConnectionStats {
+	e.mu.Lock()
+	peerConnections := make([]*webrtc.PeerConnection, 0, len(e.peerConnections))
+	for pc := range e.peerConnections {
+		peerConnections = append(peerConnections, pc)
+	}
+	e.mu.Unlock()
+
+	collected := make([]peerConnectionStats, 0, len(peerConnections))
+	for _, pc := range peerConnections {
+		state := pc.ConnectionState()
+		report := pc.GetStats()
+		pcStats, _ := report.GetConnectionStats(pc)
+		collected = append(collected, peerConnectionStats{pc: pc, id: pcStats.ID, state: state, stats: report, counters: counters{}})
+	}
+
+	return collected
+}
@@ -0,0 +1,153 @@
+// +build !js
+
+package metrics
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pion/transport/test"
+	"github.com/pion/webrtc/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func connectedPair(t *testing.T) (*webrtc.PeerConnection, *webrtc.PeerConnection) {
+	pcOffer, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+	assert.NoError(t, err)
+	pcAnswer, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+	assert.NoError(t, err)
+
+	_, err = pcOffer.CreateDataChannel("data", nil)
+	assert.NoError(t, err)
+	offer, err := pcOffer.CreateOffer(nil)
+	assert.NoError(t, err)
+	gatheringComplete := webrtc.GatheringCompletePromise(pcOffer)
+	assert.NoError(t, pcOffer.SetLocalDescription(offer))
+	<-gatheringComplete
+
+	assert.NoError(t, pcAnswer.SetRemoteDescription(*pcOffer.LocalDescription()))
+	answer, err := pcAnswer.CreateAnswer(nil)
+	assert.NoError(t, err)
+	gatheringComplete = webrtc.GatheringCompletePromise(pcAnswer)
+	assert.NoError(t, pcAnswer.SetLocalDescription(answer))
+	<-gatheringComplete
+	assert.NoError(t, pcOffer.SetRemoteDescription(*pcAnswer.LocalDescription()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	assert.NoError(t, pcOffer.ConnectContext(ctx))
+	assert.NoError(t, pcAnswer.ConnectContext(ctx))
+
+	return pcOffer, pcAnswer
+}
+
+func scrape(t *testing.T, e *Exporter) string {
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, ContentType, w.Header().Get("Content-Type"))
+
+	body, err := ioutil.ReadAll(w.Body)
+	assert.NoError(t, err)
+	return string(body)
+}
+
+// sampleValue returns the value of the sample of series in metrics
+func sampleValue(t *testing.T, metrics, series string) float64 {
+	for _, line := range strings.Split(metrics, "\n") {
+		if strings.HasPrefix(line, series+" ") {
+			v, err := strconv.ParseFloat(strings.TrimPrefix(line, series+" "), 64)
+			assert.NoError(t, err)
+			return v
+		}
+	}
+	assert.Fail(t, "no sample of "+series)
+	return 0
+}
+
+func TestExporter(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	pcOffer, pcAnswer := connectedPair(t)
+	e := NewExporter()
+	e.Add(pcOffer)
+	e.Add(pcAnswer)
+
+	stats, ok := pcOffer.GetStats().GetConnectionStats(pcOffer)
+	assert.True(t, ok)
+
+	// The metrics are aggregated over the PeerConnections
+	metrics := scrape(t, e)
+	assert.Contains(t, metrics, "# TYPE webrtc_peer_connections gauge\n")
+	assert.Contains(t, metrics, `webrtc_peer_connections{state="connected"} 2`+"\n")
+	assert.Contains(t, metrics, `webrtc_peer_connections{state="failed"} 0`+"\n")
+	assert.Contains(t, metrics, "# TYPE webrtc_transport_bytes_sent_total counter\n")
+	assert.Contains(t, metrics, `webrtc_ice_candidates{side="local",candidate_type="host"} `)
+	assert.Contains(t, metrics, "webrtc_candidate_pair_round_trip_time_seconds ")
+	assert.False(t, strings.Contains(metrics, stats.ID))
+	bytesSent := sampleValue(t, metrics, "webrtc_transport_bytes_sent_total")
+	assert.NotZero(t, bytesSent)
+
+	// Closed PeerConnections are exported once more
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+	metrics = scrape(t, e)
+	assert.Contains(t, metrics, `webrtc_peer_connections{state="closed"} 2`+"\n")
+	closedBytesSent := sampleValue(t, metrics, "webrtc_transport_bytes_sent_total")
+	assert.GreaterOrEqual(t, closedBytesSent, bytesSent)
+
+	// The counters keep those of the removed PeerConnections
+	metrics = scrape(t, e)
+	assert.Contains(t, metrics, `webrtc_peer_connections{state="closed"} 0`+"\n")
+	assert.Equal(t, closedBytesSent, sampleValue(t, metrics, "webrtc_transport_bytes_sent_total"))
+	assert.False(t, strings.Contains(metrics, "webrtc_ice_candidates"))
+}
+
+func TestExporter_PeerConnectionLabels(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	pcOffer, pcAnswer := connectedPair(t)
+	e := NewExporter(WithPeerConnectionLabels())
+	e.Add(pcOffer)
+	e.Add(pcAnswer)
+
+	stats, ok := pcOffer.GetStats().GetConnectionStats(pcOffer)
+	assert.True(t, ok)
+
+	metrics := scrape(t, e)
+	assert.Contains(t, metrics, `webrtc_peer_connections{state="connected"} 2`+"\n")
+	assert.Contains(t, metrics, `webrtc_transport_bytes_sent_total{peer_connection="`+stats.ID+`"} `)
+	assert.Contains(t, metrics, `webrtc_ice_candidates{peer_connection="`+stats.ID+`",side="local",candidate_type="host"} `)
+	assert.Contains(t, metrics, `webrtc_candidate_pair_round_trip_time_seconds{peer_connection="`+stats.ID+`"} `)
+
+	// The series of removed PeerConnections end
+	e.Remove(pcOffer)
+	assert.False(t, strings.Contains(scrape(t, e), stats.ID))
+
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
+
+func TestFamily_add(t *testing.T) {
+	f := &family{name: "webrtc_test"}
+	assert.Equal(t, `webrtc_test{label="a\"b\\c\nd"}`, f.add(1.5, "label", "a\"b\\c\nd"))
+	f.add(1, "label", "e")
+	f.add(2, "label", "e")
+	f.add(3)
+	assert.Equal(t, []string{`webrtc_test 3`, `webrtc_test{label="a\"b\\c\nd"} 1.5`, `webrtc_test{label="e"} 3`}, f.samples())
+
+	f.average = true
+	assert.Equal(t, `webrtc_test{label="e"} 1.5`, f.samples()[2])
+}
@@ -0,0 +1,447 @@
+// Package rtcpxr implements the RTCP Extended Reports (XR) of RFC 3611. They
+// carry the receiver reference time and DLRR blocks, which give receivers
+// that don't send RTP a round trip time, and the statistics summary and VoIP
+// metrics blocks, which report the quality of a stream beyond a receiver
+// report.
+//
+// Extended reports are parsed from the RTCP read from an RTPSender or an
+// RTPReceiver, which has them as an rtcp.RawPacket.
+package rtcpxr
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/pion/rtcp"
+)
+
+// TypeExtendedReport is the RTCP packet type of extended reports
+const TypeExtendedReport rtcp.PacketType = 207
+
+// BlockType is the type of a report block of an extended report
+type BlockType uint8
+
+// Types of the report blocks of RFC 3611
+const (
+	BlockTypeReceiverReferenceTime BlockType = 4
+	BlockTypeDLRR                  BlockType = 5
+	BlockTypeStatisticsSummary     BlockType = 6
+	BlockTypeVoIPMetrics           BlockType = 7
+)
+
+// TTLOrHopLimit tells if a statistics summary has TTL or hop limit values
+type TTLOrHopLimit uint8
+
+// TTLOrHopLimit values of a statistics summary
+const (
+	TTLOrHopLimitNone TTLOrHopLimit = iota
+	TTLOrHopLimitIPv4
+	TTLOrHopLimitIPv6
+)
+
+// Unavailable is the value of the VoIP metrics that aren't measured
+const Unavailable = 127
+
+const (
+	headerLength      = 8
+	blockHeaderLength = 4
+
+	receiverReferenceTimeLength = 8
+	dlrrReportLength            = 12
+	statisticsSummaryLength     = 36
+	voipMetricsLength           = 32
+
+	versionShift = 6
+	version      = 2
+)
+
+var (
+	errPacketTooShort    = errors.New("rtcpxr: packet too short")
+	errWrongType         = errors.New("rtcpxr: wrong packet type")
+	errBadVersion        = errors.New("rtcpxr: invalid packet version")
+	errBadLength         = errors.New("rtcpxr: invalid length")
+	errBlockTooShort     = errors.New("rtcpxr: report block too short")
+	errBlockTooLong      = errors.New("rtcpxr: report block too long")
+	errInvalidBlockWords = errors.New("rtcpxr: report block contents must be multiples of 4 bytes")
+)
+
+// ReportBlock is a report block of an extended report
+type ReportBlock interface {
+	// BlockType returns the type of the block
+	BlockType() BlockType
+
+	// marshal returns the type specific byte and the contents of the block
+	marshal() (typeSpecific uint8, contents []byte)
+
+	// unmarshal parses the type specific byte and the contents of the block
+	unmarshal(typeSpecific uint8, contents []byte) error
+}
+
+// ExtendedReport is an RTCP extended report packet
+type ExtendedReport struct {
+	// SenderSSRC is the SSRC of the originator of the report
+	SenderSSRC uint32
+
+	Reports []ReportBlock
+}
+
+var _ rtcp.Packet = (*ExtendedReport)(nil) // assert is a rtcp.Packet
+
+// Marshal serializes the report
+func (x *ExtendedReport) Marshal() ([]byte, error) {
+	buf := make([]byte, headerLength)
+	for _, report := range x.Reports {
+		typeSpecific, contents := report.marshal()
+		if len(contents)%4 != 0 {
+			return nil, errInvalidBlockWords
+		} else if len(contents)/4 > 0xFFFF {
+			return nil, errBlockTooLong
+		}
+
+		block := make([]byte, blockHeaderLength, blockHeaderLength+len(contents))
+		block[0] = uint8(report.BlockType())
+		block[1] = typeSpecific
+		binary.BigEndian.PutUint16(block[2:], uint16(len(contents)/4))
+		buf = append(buf, append(block, contents...)...)
+	}
+	if len(buf)/4-1 > 0xFFFF {
+		return nil, errBadLength
+	}
+
+	buf[0] = version << versionShift
+	buf[1] = uint8(TypeExtendedReport)
+	binary.BigEndian.PutUint16(buf[2:], uint16(len(buf)/4-1))
+	binary.BigEndian.PutUint32(buf[4:], x.SenderSSRC)
+	return buf, nil
+}
+
+// Unmarshal parses a report. Blocks of unknown types are kept as
+// UnknownReportBlock.
+func (x *ExtendedReport) Unmarshal(rawPacket []byte) error {
+	if len(rawPacket) < headerLength {
+		return errPacketTooShort
+	} else if rawPacket[0]>>versionShift != version {
+		return errBadVersion
+	} else if rtcp.PacketType(rawPacket[1]) != TypeExtendedReport {
+		return errWrongType
+	}
+
+	length := (int(binary.BigEndian.Uint16(rawPacket[2:])) + 1) * 4
+	if length < headerLength || length > len(rawPacket) {
+		return errBadLength
+	}
+
+	x.SenderSSRC = binary.BigEndian.Uint32(rawPacket[4:])
+	x.Reports = nil
+	for b := rawPacket[headerLength:length]; len(b) > 0; {
+		if len(b) < blockHeaderLength {
+			return errBlockTooShort
+		}
+		blockLength := blockHeaderLength + int(binary.BigEndian.Uint16(b[2:]))*4
+		if blockLength > len(b) {
+			return errBlockTooShort
+		}
+
+		var report ReportBlock
+		switch BlockType(b[0]) {
+		case BlockTypeReceiverReferenceTime:
+			report = &ReceiverReferenceTimeReportBlock{}
+		case BlockTypeDLRR:
+			report = &DLRRReportBlock{}
+		case BlockTypeStatisticsSummary:
+			report = &StatisticsSummaryReportBlock{}
+		case BlockTypeVoIPMetrics:
+			report = &VoIPMetricsReportBlock{}
+		default:
+			report = &UnknownReportBlock{Type: BlockType(b[0])}
+		}
+		if err := report.unmarshal(b[1], b[blockHeaderLength:blockLength]); err != nil {
+			return err
+		}
+
+		x.Reports = append(x.Reports, report)
+		b = b[blockLength:]
+	}
+	return nil
+}
+
+// DestinationSSRC returns the SSRCs the report is about
+func (x *ExtendedReport) DestinationSSRC() []uint32 {
+	ssrcs := []uint32{}
+	for _, report := range x.Reports {
+		switch report := report.(type) {
+		case *DLRRReportBlock:
+			for _, dlrr := range report.Reports {
+				ssrcs = append(ssrcs, dlrr.SSRC)
+			}
+		case *StatisticsSummaryReportBlock:
+			ssrcs = append(ssrcs, report.SSRC)
+		case *VoIPMetricsReportBlock:
+			ssrcs = append(ssrcs, report.SSRC)
+		}
+	}
+	return ssrcs
+}
+
+// Unmarshal parses the extended reports in the compound RTCP packet in
+// rawData, other packets are skipped.
+func Unmarshal(rawData []byte) ([]*ExtendedReport, error) {
+	reports := []*ExtendedReport{}
+	for len(rawData) >= 4 {
+		length := (int(binary.BigEndian.Uint16(rawData[2:])) + 1) * 4
+		if length > len(rawData) {
+			return nil, errPacketTooShort
+		}
+
+		if rtcp.PacketType(rawData[1]) == TypeExtendedReport {
+			report := &ExtendedReport{}
+			if err := report.Unmarshal(rawData[:length]); err != nil {
+				return nil, err
+			}
+			reports = append(reports, report)
+		}
+		rawData = rawData[length:]
+	}
+	return reports, nil
+}
+
+// ReceiverReferenceTimeReportBlock carries the NTP time a receiver sent the
+// report at, section 4.4 of RFC 3611
+type ReceiverReferenceTimeReportBlock struct {
+	NTPTimestamp uint64
+}
+
+// BlockType returns BlockTypeReceiverReferenceTime
+func (b *ReceiverReferenceTimeReportBlock) BlockType() BlockType {
+	return BlockTypeReceiverReferenceTime
+}
+
+func (b *ReceiverReferenceTimeReportBlock) marshal() (uint8, []byte) {
+	contents := make([]byte, receiverReferenceTimeLength)
+	binary.BigEndian.PutUint64(contents, b.NTPTimestamp)
+	return 0, contents
+}
+
+func (b *ReceiverReferenceTimeReportBlock) unmarshal(_ uint8, contents []byte) error {
+	if len(contents) < receiverReferenceTimeLength {
+		return errBlockTooShort
+	}
+	b.NTPTimestamp = binary.BigEndian.Uint64(contents)
+	return nil
+}
+
+// DLRRReport answers the receiver reference time of a receiver
+type DLRRReport struct {
+	// SSRC is the SSRC of the receiver
+	SSRC uint32
+
+	// LastRR is the middle 32 bits of the NTP timestamp of the last
+	// receiver reference time block of the receiver
+	LastRR uint32
+
+	// DLRR is the delay since it was received, in 1/65536 seconds
+	DLRR uint32
+}
+
+// DLRRReportBlock carries the delay since the last receiver reference time
+// block of receivers, section 4.5 of RFC 3611
+type DLRRReportBlock struct {
+	Reports []DLRRReport
+}
+
+// BlockType returns BlockTypeDLRR
+func (b *DLRRReportBlock) BlockType() BlockType {
+	return BlockTypeDLRR
+}
+
+func (b *DLRRReportBlock) marshal() (uint8, []byte) {
+	contents := make([]byte, dlrrReportLength*len(b.Reports))
+	for i, report := range b.Reports {
+		binary.BigEndian.PutUint32(contents[i*dlrrReportLength:], report.SSRC)
+		binary.BigEndian.PutUint32(contents[i*dlrrReportLength+4:], report.LastRR)
+		binary.BigEndian.PutUint32(contents[i*dlrrReportLength+8:], report.DLRR)
+	}
+	return 0, contents
+}
+
+func (b *DLRRReportBlock) unmarshal(_ uint8, contents []byte) error {
+	if len(contents)%dlrrReportLength != 0 {
+		return errBlockTooShort
+	}
+
+	b.Reports = nil
+	for ; len(contents) > 0; contents = contents[dlrrReportLength:] {
+		b.Reports = append(b.Reports, DLRRReport{
+			SSRC:   binary.BigEndian.Uint32(contents),
+			LastRR: binary.BigEndian.Uint32(contents[4:]),
+			DLRR:   binary.BigEndian.Uint32(contents[8:]),
+		})
+	}
+	return nil
+}
+
+// StatisticsSummaryReportBlock summarizes the packets received from SSRC
+// with sequence numbers from BeginSeq up to EndSeq excluded, section 4.6 of
+// RFC 3611. The loss, duplicate, jitter and TTL values are only set if
+// their report flag or TTLOrHopLimit is.
+type StatisticsSummaryReportBlock struct {
+	LossReports      bool
+	DuplicateReports bool
+	JitterReports    bool
+	TTLOrHopLimit    TTLOrHopLimit
+
+	SSRC             uint32
+	BeginSeq, EndSeq uint16
+
+	LostPackets uint32
+	DupPackets  uint32
+
+	// Jitter values are in timestamp units
+	MinJitter, MaxJitter, MeanJitter, DevJitter uint32
+
+	MinTTLOrHL, MaxTTLOrHL, MeanTTLOrHL, DevTTLOrHL uint8
+}
+
+// BlockType returns BlockTypeStatisticsSummary
+func (b *StatisticsSummaryReportBlock) BlockType() BlockType {
+	return BlockTypeStatisticsSummary
+}
+
+func (b *StatisticsSummaryReportBlock) marshal() (uint8, []byte) {
+	typeSpecific := uint8(b.TTLOrHopLimit&0x03) << 3
+	for i, flag := range []bool{b.LossReports, b.DuplicateReports, b.JitterReports} {
+		if flag {
+			typeSpecific |= 0x80 >> uint(i)
+		}
+	}
+
+	contents := make([]byte, statisticsSummaryLength)
+	binary.BigEndian.PutUint32(contents, b.SSRC)
+	binary.BigEndian.PutUint16(contents[4:], b.BeginSeq)
+	binary.BigEndian.PutUint16(contents[6:], b.EndSeq)
+	for i, value := range []uint32{b.LostPackets, b.DupPackets, b.MinJitter, b.MaxJitter, b.MeanJitter, b.DevJitter} {
+		binary.BigEndian.PutUint32(contents[8+4*i:], value)
+	}
+	copy(contents[32:], []byte{b.MinTTLOrHL, b.MaxTTLOrHL, b.MeanTTLOrHL, b.DevTTLOrHL})
+	return typeSpecific, contents
+}
+
+func (b *StatisticsSummaryReportBlock) unmarshal(typeSpecific uint8, contents []byte) error {
+	if len(contents) < statisticsSummaryLength {
+		return errBlockTooShort
+	}
+
+	b.LossReports = typeSpecific&0x80 != 0
+	b.DuplicateReports = typeSpecific&0x40 != 0
+	b.JitterReports = typeSpecific&0x20 != 0
+	b.TTLOrHopLimit = TTLOrHopLimit(typeSpecific>>3) & 0x03
+
+	b.SSRC = binary.BigEndian.Uint32(contents)
+	b.BeginSeq = binary.BigEndian.Uint16(contents[4:])
+	b.EndSeq = binary.BigEndian.Uint16(contents[6:])
+	for i, value := range []*uint32{&b.LostPackets, &b.DupPackets, &b.MinJitter, &b.MaxJitter, &b.MeanJitter, &b.DevJitter} {
+		*value = binary.BigEndian.Uint32(contents[8+4*i:])
+	}
+	b.MinTTLOrHL, b.MaxTTLOrHL, b.MeanTTLOrHL, b.DevTTLOrHL = contents[32], contents[33], contents[34], contents[35]
+	return nil
+}
+
+// VoIPMetricsReportBlock reports the call quality of the voice received
+// from SSRC, section 4.7 of RFC 3611. Rates and densities are fractions in
+// 1/256, durations and delays are in milliseconds, and metrics that aren't
+// measured are Unavailable, or 0 for the rates and delays.
+type VoIPMetricsReportBlock struct {
+	SSRC uint32
+
+	LossRate     uint8
+	DiscardRate  uint8
+	BurstDensity uint8
+	GapDensity   uint8
+
+	BurstDuration  uint16
+	GapDuration    uint16
+	RoundTripDelay uint16
+	EndSystemDelay uint16
+
+	// SignalLevel and NoiseLevel are in dBm
+	SignalLevel int8
+	NoiseLevel  int8
+
+	RERL       uint8
+	Gmin       uint8
+	RFactor    uint8
+	ExtRFactor uint8
+	MOSLQ      uint8
+	MOSCQ      uint8
+	RXConfig   uint8
+
+	JBNominal uint16
+	JBMaximum uint16
+	JBAbsMax  uint16
+}
+
+// BlockType returns BlockTypeVoIPMetrics
+func (b *VoIPMetricsReportBlock) BlockType() BlockType {
+	return BlockTypeVoIPMetrics
+}
+
+func (b *VoIPMetricsReportBlock) marshal() (uint8, []byte) {
+	contents := make([]byte, voipMetricsLength)
+	binary.BigEndian.PutUint32(contents, b.SSRC)
+	copy(contents[4:], []byte{b.LossRate, b.DiscardRate, b.BurstDensity, b.GapDensity})
+	for i, value := range []uint16{b.BurstDuration, b.GapDuration, b.RoundTripDelay, b.EndSystemDelay} {
+		binary.BigEndian.PutUint16(contents[8+2*i:], value)
+	}
+	copy(contents[16:], []byte{
+		uint8(b.SignalLevel), uint8(b.NoiseLevel), b.RERL, b.Gmin,
+		b.RFactor, b.ExtRFactor, b.MOSLQ, b.MOSCQ,
+		b.RXConfig, 0,
+	})
+	for i, value := range []uint16{b.JBNominal, b.JBMaximum, b.JBAbsMax} {
+		binary.BigEndian.PutUint16(contents[26+2*i:], value)
+	}
+	return 0, contents
+}
+
+func (b *VoIPMetricsReportBlock) unmarshal(_ uint8, contents []byte) error {
+	if len(contents) < voipMetricsLength {
+		return errBlockTooShort
+	}
+
+	b.SSRC = binary.BigEndian.Uint32(contents)
+	b.LossRate, b.DiscardRate, b.BurstDensity, b.GapDensity = contents[4], contents[5], contents[6], contents[7]
+	for i, value := range []*uint16{&b.BurstDuration, &b.GapDuration, &b.RoundTripDelay, &b.EndSystemDelay} {
+		*value = binary.BigEndian.Uint16(contents[8+2*i:])
+	}
+	b.SignalLevel, b.NoiseLevel = int8(contents[16]), int8(contents[17])
+	b.RERL, b.Gmin = contents[18], contents[19]
+	b.RFactor, b.ExtRFactor, b.MOSLQ, b.MOSCQ = contents[20], contents[21], contents[22], contents[23]
+	b.RXConfig = contents[24]
+	for i, value := range []*uint16{&b.JBNominal, &b.JBMaximum, &b.JBAbsMax} {
+		*value = binary.BigEndian.Uint16(contents[26+2*i:])
+	}
+	return nil
+}
+
+// UnknownReportBlock is a report block of a type this package doesn't
+// parse
+type UnknownReportBlock struct {
+	Type         BlockType
+	TypeSpecific uint8
+	Contents     []byte
+}
+
+// BlockType returns the type of the block
+func (b *UnknownReportBlock) BlockType() BlockType {
+	return b.Type
+}
+
+func (b *UnknownReportBlock) marshal() (uint8, []byte) {
+	return b.TypeSpecific, b.Contents
+}
+
+func (b *UnknownReportBlock) unmarshal(typeSpecific uint8, contents []byte) error {
+	b.TypeSpecific = typeSpecific
+	b.Contents = append([]byte{}, contents...)
+	return nil
+}
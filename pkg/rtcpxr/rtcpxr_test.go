@@ -0,0 +1,69 @@
+package rtcpxr
+
+import (
+	"testing"
+
+	"github.com/pion/rtcp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtendedReport(t *testing.T) {
+	report := &ExtendedReport{
+		SenderSSRC: 0x01020304,
+		Reports: []ReportBlock{
+			&ReceiverReferenceTimeReportBlock{NTPTimestamp: 0x0102030405060708},
+			&DLRRReportBlock{Reports: []DLRRReport{{SSRC: 0x0A, LastRR: 0x0B, DLRR: 0x0C}}},
+			&StatisticsSummaryReportBlock{
+				LossReports:   true,
+				JitterReports: true,
+				TTLOrHopLimit: TTLOrHopLimitIPv4,
+				SSRC:          0x0D,
+				BeginSeq:      1,
+				EndSeq:        101,
+				LostPackets:   3,
+				MeanJitter:    90,
+				MaxTTLOrHL:    64,
+			},
+			&VoIPMetricsReportBlock{
+				SSRC:           0x0E,
+				LossRate:       12,
+				RoundTripDelay: 150,
+				SignalLevel:    -30,
+				NoiseLevel:     Unavailable,
+				RFactor:        Unavailable,
+				JBAbsMax:       200,
+			},
+			&UnknownReportBlock{Type: 42, TypeSpecific: 1, Contents: []byte{1, 2, 3, 4}},
+		},
+	}
+
+	raw, err := report.Marshal()
+	assert.NoError(t, err)
+	assert.Equal(t, 8+12+16+40+36+8, len(raw))
+	assert.Equal(t, []byte{0x80, 207, 0x00, byte(len(raw)/4 - 1), 0x01, 0x02, 0x03, 0x04}, raw[:8])
+	assert.Equal(t, []byte{0x06, 0xA8, 0x00, 0x09}, raw[36:40])
+	assert.ElementsMatch(t, []uint32{0x0A, 0x0D, 0x0E}, report.DestinationSSRC())
+
+	parsed := &ExtendedReport{}
+	assert.NoError(t, parsed.Unmarshal(raw))
+	assert.Equal(t, report, parsed)
+
+	// Extended reports are found in compound packets
+	compound, err := rtcp.Marshal([]rtcp.Packet{&rtcp.ReceiverReport{SSRC: 1}, report})
+	assert.NoError(t, err)
+	reports, err := Unmarshal(compound)
+	assert.NoError(t, err)
+	assert.Equal(t, []*ExtendedReport{report}, reports)
+
+	for _, invalid := range [][]byte{
+		raw[:4],
+		raw[:len(raw)-4],
+		{0x80, 200, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00},
+		{0x80, 207, 0x00, 0x02, 0x00, 0x00, 0x00, 0x00, 0x04, 0x00, 0x00, 0x02},
+	} {
+		assert.Error(t, (&ExtendedReport{}).Unmarshal(invalid))
+	}
+
+	_, err = (&ExtendedReport{Reports: []ReportBlock{&UnknownReportBlock{Contents: []byte{1}}}}).Marshal()
+	assert.Error(t, err)
+}
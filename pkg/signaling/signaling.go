@@ -0,0 +1,70 @@
+// +build !js
+
+// Package signaling implements webrtc.Signaler over a WebSocket. WebSocket
+// sends the offers, answers and ICE candidates of a PeerConnection as JSON,
+// it negotiates PeerConnections with webrtc.Negotiate. WebSocketListener
+// accepts a WebSocket for each remote peer, for a webrtc.Listener.
+package signaling
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/pion/webrtc/v3"
+	"golang.org/x/net/websocket"
+)
+
+var errListenerClosed = errors.New("the WebSocketListener is closed")
+
+// WebSocketListener is a webrtc.SignalerListener that accepts the WebSockets
+// of its Handler
+type WebSocketListener struct {
+	accepted chan *WebSocket
+	done     chan struct{}
+
+	closeOnce sync.Once
+}
+
+// NewWebSocketListener creates a WebSocketListener, its Handler must be
+// served for WebSockets to be accepted
+func NewWebSocketListener() *WebSocketListener {
+	return &WebSocketListener{
+		accepted: make(chan *WebSocket),
+		done:     make(chan struct{}),
+	}
+}
+
+// Handler returns the websocket.Handler of the WebSocketListener. It passes
+// each connection to Accept as a WebSocket, and returns once it is closed.
+func (l *WebSocketListener) Handler() websocket.Handler {
+	return func(conn *websocket.Conn) {
+		w := NewWebSocket(conn)
+		select {
+		case l.accepted <- w:
+		case <-l.done:
+			_ = w.Close()
+			return
+		}
+
+		<-w.closed
+	}
+}
+
+// Accept waits for the next WebSocket of the Handler
+func (l *WebSocketListener) Accept() (webrtc.Signaler, error) {
+	select {
+	case w := <-l.accepted:
+		return w, nil
+	case <-l.done:
+		return nil, errListenerClosed
+	}
+}
+
+// Close stops accepting WebSockets, those of the Handler are then closed.
+// The WebSockets already accepted are left open.
+func (l *WebSocketListener) Close() error {
+	l.closeOnce.Do(func() {
+		close(l.done)
+	})
+	return nil
+}
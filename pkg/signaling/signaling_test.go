@@ -0,0 +1,101 @@
+// +build !js
+
+package signaling
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pion/transport/test"
+	"github.com/pion/webrtc/v3"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/websocket"
+)
+
+func TestWebSocket(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	// The answerer accepts the WebSocket
+	answererDone := make(chan struct{})
+	received := make(chan string, 1)
+	server := httptest.NewServer(websocket.Handler(func(conn *websocket.Conn) {
+		defer close(answererDone)
+
+		pc, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+		assert.NoError(t, err)
+		pc.OnDataChannel(func(d *webrtc.DataChannel) {
+			d.OnMessage(func(msg webrtc.DataChannelMessage) {
+				received <- string(msg.Data)
+			})
+		})
+
+		signaler := NewWebSocket(conn)
+		assert.NoError(t, webrtc.Negotiate(context.Background(), pc, signaler, false))
+		assert.Equal(t, "hello", <-received)
+
+		assert.NoError(t, signaler.Close())
+		assert.NoError(t, pc.Close())
+	}))
+	defer server.Close()
+
+	conn, err := websocket.Dial("ws"+strings.TrimPrefix(server.URL, "http"), "", server.URL)
+	assert.NoError(t, err)
+
+	pc, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+	assert.NoError(t, err)
+	d, err := pc.CreateDataChannel("data", nil)
+	assert.NoError(t, err)
+	d.OnOpen(func() {
+		assert.NoError(t, d.SendText("hello"))
+	})
+
+	signaler := NewWebSocket(conn)
+	assert.NoError(t, webrtc.Negotiate(context.Background(), pc, signaler, true))
+	<-answererDone
+
+	assert.NoError(t, signaler.Close())
+	assert.NoError(t, pc.Close())
+}
+
+func TestWebSocketListener(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	signalers := NewWebSocketListener()
+	listener := webrtc.NewListener(signalers, webrtc.ListenerOptions{})
+	server := httptest.NewServer(signalers.Handler())
+	defer server.Close()
+
+	conn, err := websocket.Dial("ws"+strings.TrimPrefix(server.URL, "http"), "", server.URL)
+	assert.NoError(t, err)
+
+	pcOffer, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+	assert.NoError(t, err)
+	_, err = pcOffer.CreateDataChannel("data", nil)
+	assert.NoError(t, err)
+
+	signaler := NewWebSocket(conn)
+	assert.NoError(t, webrtc.Negotiate(context.Background(), pcOffer, signaler, true))
+
+	pcAnswer, err := listener.Accept()
+	assert.NoError(t, err)
+	assert.Equal(t, webrtc.PeerConnectionStateConnected, pcAnswer.ConnectionState())
+
+	assert.NoError(t, listener.Close())
+	_, err = signalers.Accept()
+	assert.Equal(t, errListenerClosed, err)
+
+	assert.NoError(t, signaler.Close())
+	assert.NoError(t, pcOffer.Close())
+	assert.NoError(t, pcAnswer.Close())
+}
@@ -0,0 +1,93 @@
+// +build !js
+
+package signaling
+
+import (
+	"sync"
+
+	"github.com/pion/webrtc/v3"
+	"golang.org/x/net/websocket"
+)
+
+// Types of the messages of a WebSocket
+const (
+	messageTypeOffer     = "offer"
+	messageTypeAnswer    = "answer"
+	messageTypeCandidate = "candidate"
+)
+
+// message is sent as JSON, like
+//   {"type": "offer", "description": {"type": "offer", "sdp": "v=0..."}}
+//   {"type": "candidate", "candidate": {"candidate": "candidate:..."}}
+type message struct {
+	Type        string                     `json:"type"`
+	Description *webrtc.SessionDescription `json:"description,omitempty"`
+	Candidate   *webrtc.ICECandidateInit   `json:"candidate,omitempty"`
+}
+
+// WebSocket is a webrtc.Signaler that sends each message as a JSON object
+// in a WebSocket text frame. Messages of unknown types are ignored.
+type WebSocket struct {
+	conn *websocket.Conn
+
+	mu sync.Mutex
+
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// NewWebSocket creates a WebSocket Signaler over conn, either dialed with
+// websocket.Dial or accepted by a websocket.Handler
+func NewWebSocket(conn *websocket.Conn) *WebSocket {
+	return &WebSocket{conn: conn, closed: make(chan struct{})}
+}
+
+// SendOffer sends an offer
+func (w *WebSocket) SendOffer(offer webrtc.SessionDescription) error {
+	return w.send(message{Type: messageTypeOffer, Description: &offer})
+}
+
+// SendAnswer sends an answer
+func (w *WebSocket) SendAnswer(answer webrtc.SessionDescription) error {
+	return w.send(message{Type: messageTypeAnswer, Description: &answer})
+}
+
+// SendCandidate sends an ICE candidate
+func (w *WebSocket) SendCandidate(candidate webrtc.ICECandidateInit) error {
+	return w.send(message{Type: messageTypeCandidate, Candidate: &candidate})
+}
+
+// Receive calls handlers with the received messages until the connection is
+// closed, and returns the error that ended it
+func (w *WebSocket) Receive(handlers webrtc.SignalerHandlers) error {
+	for {
+		msg := message{}
+		if err := websocket.JSON.Receive(w.conn, &msg); err != nil {
+			return err
+		}
+
+		switch {
+		case msg.Type == messageTypeOffer && msg.Description != nil && handlers.OnOffer != nil:
+			handlers.OnOffer(*msg.Description)
+		case msg.Type == messageTypeAnswer && msg.Description != nil && handlers.OnAnswer != nil:
+			handlers.OnAnswer(*msg.Description)
+		case msg.Type == messageTypeCandidate && msg.Candidate != nil && handlers.OnCandidate != nil:
+			handlers.OnCandidate(*msg.Candidate)
+		}
+	}
+}
+
+// Close closes the connection
+func (w *WebSocket) Close() error {
+	err := w.conn.Close()
+	w.closeOnce.Do(func() {
+		close(w.closed)
+	})
+	return err
+}
+
+func (w *WebSocket) send(msg message) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return websocket.JSON.Send(w.conn, msg)
+}
@@ -0,0 +1,78 @@
+// Package videoorientation implements the Coordination of Video Orientation
+// RTP header extension of 3GPP TS 26.114. It carries the rotation of a video
+// frame, so video captured by a rotated phone is displayed upright instead
+// of sideways.
+//
+// The extension is negotiated by adding URI to the video extensions with
+// SettingEngine.AddSDPExtensions.
+package videoorientation
+
+import (
+	"errors"
+)
+
+// URI of the video orientation header extension
+const URI = "urn:3gpp:video-orientation"
+
+// Rotation is the counter-clockwise rotation of a frame the receiver has to
+// undo by rotating it clockwise before displaying it
+type Rotation uint8
+
+// Rotations of a frame
+const (
+	Rotation0 Rotation = iota
+	Rotation90
+	Rotation180
+	Rotation270
+)
+
+const (
+	extensionSize = 1
+
+	cameraBit = 0x08
+	flipBit   = 0x04
+)
+
+var errExtensionSize = errors.New("video-orientation extension must be 1 byte")
+
+// Degrees returns the rotation in degrees
+func (r Rotation) Degrees() int {
+	return int(r&0x03) * 90
+}
+
+// Extension is the payload of the video orientation header extension
+type Extension struct {
+	// BackFacing is true if the frame was captured by a back facing camera
+	BackFacing bool
+
+	// Flip is true if the frame is mirrored horizontally, it is applied
+	// before the rotation
+	Flip bool
+
+	Rotation Rotation
+}
+
+// Marshal serializes the extension
+func (e Extension) Marshal() ([]byte, error) {
+	b := uint8(e.Rotation & 0x03)
+	if e.BackFacing {
+		b |= cameraBit
+	}
+	if e.Flip {
+		b |= flipBit
+	}
+	return []byte{b}, nil
+}
+
+// Unmarshal parses the extension. The payload may be padded by senders
+// using the two byte header format.
+func (e *Extension) Unmarshal(rawData []byte) error {
+	if len(rawData) < extensionSize {
+		return errExtensionSize
+	}
+
+	e.BackFacing = rawData[0]&cameraBit != 0
+	e.Flip = rawData[0]&flipBit != 0
+	e.Rotation = Rotation(rawData[0] & 0x03)
+	return nil
+}
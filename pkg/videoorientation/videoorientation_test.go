@@ -0,0 +1,24 @@
+package videoorientation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtension(t *testing.T) {
+	e := Extension{BackFacing: true, Rotation: Rotation270}
+	raw, err := e.Marshal()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x0B}, raw)
+
+	parsed := Extension{Flip: true}
+	assert.NoError(t, parsed.Unmarshal(raw))
+	assert.Equal(t, e, parsed)
+	assert.Equal(t, 270, parsed.Rotation.Degrees())
+
+	assert.NoError(t, parsed.Unmarshal([]byte{0x05, 0x00}))
+	assert.Equal(t, Extension{Flip: true, Rotation: Rotation90}, parsed)
+
+	assert.Equal(t, errExtensionSize, parsed.Unmarshal(nil))
+}
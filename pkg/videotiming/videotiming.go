@@ -0,0 +1,133 @@
+// Package videotiming implements the video-timing RTP header extension of
+// libwebrtc. It carries when a frame was encoded, packetized and sent, so
+// the latency of a sender can be broken down by its receiver.
+//
+// The extension is negotiated by adding URI to the video extensions with
+// SettingEngine.AddSDPExtensions.
+package videotiming
+
+import (
+	"encoding/binary"
+	"errors"
+	"time"
+)
+
+// URI of the video-timing header extension
+const URI = "http://www.webrtc.org/experiments/rtp-hdrext/video-timing"
+
+// Flags of the extension, which tell why the timing of a frame was sent
+const (
+	FlagTriggeredByTimer = 0x01
+	FlagTriggeredBySize  = 0x02
+)
+
+const (
+	// extensionSize is the size of the extension, the legacy version has no flags
+	extensionSize       = 13
+	extensionSizeLegacy = 12
+
+	maxDelta = 0xFFFF
+)
+
+var errExtensionSize = errors.New("video-timing extension must be 12 or 13 bytes")
+
+// Extension is the payload of the video-timing header extension. The deltas
+// are in milliseconds since the frame was captured. The network timestamps
+// are set by middleboxes like SFUs, they are 0 otherwise.
+type Extension struct {
+	Flags uint8
+
+	EncodeStartDelta         uint16
+	EncodeFinishDelta        uint16
+	PacketizationFinishDelta uint16
+	PacerExitDelta           uint16
+	NetworkTimestampDelta    uint16
+	Network2TimestampDelta   uint16
+}
+
+// Timing holds the times a frame passed the stages of a sender
+type Timing struct {
+	Capture             time.Time
+	EncodeStart         time.Time
+	EncodeFinish        time.Time
+	PacketizationFinish time.Time
+	PacerExit           time.Time
+}
+
+// NewExtension creates the extension of a frame sent with timing
+func NewExtension(timing Timing, flags uint8) Extension {
+	return Extension{
+		Flags:                    flags,
+		EncodeStartDelta:         delta(timing.Capture, timing.EncodeStart),
+		EncodeFinishDelta:        delta(timing.Capture, timing.EncodeFinish),
+		PacketizationFinishDelta: delta(timing.Capture, timing.PacketizationFinish),
+		PacerExitDelta:           delta(timing.Capture, timing.PacerExit),
+	}
+}
+
+// Timing returns the times of the extension for a frame captured at capture
+func (e Extension) Timing(capture time.Time) Timing {
+	at := func(delta uint16) time.Time {
+		return capture.Add(time.Duration(delta) * time.Millisecond)
+	}
+
+	return Timing{
+		Capture:             capture,
+		EncodeStart:         at(e.EncodeStartDelta),
+		EncodeFinish:        at(e.EncodeFinishDelta),
+		PacketizationFinish: at(e.PacketizationFinishDelta),
+		PacerExit:           at(e.PacerExitDelta),
+	}
+}
+
+// Marshal serializes the extension
+func (e Extension) Marshal() ([]byte, error) {
+	buf := make([]byte, extensionSize)
+	buf[0] = e.Flags
+	for i, delta := range []uint16{
+		e.EncodeStartDelta,
+		e.EncodeFinishDelta,
+		e.PacketizationFinishDelta,
+		e.PacerExitDelta,
+		e.NetworkTimestampDelta,
+		e.Network2TimestampDelta,
+	} {
+		binary.BigEndian.PutUint16(buf[1+2*i:], delta)
+	}
+	return buf, nil
+}
+
+// Unmarshal parses the extension, both with and without flags
+func (e *Extension) Unmarshal(rawData []byte) error {
+	switch len(rawData) {
+	case extensionSize:
+		e.Flags = rawData[0]
+		rawData = rawData[1:]
+	case extensionSizeLegacy:
+		e.Flags = 0
+	default:
+		return errExtensionSize
+	}
+
+	e.EncodeStartDelta = binary.BigEndian.Uint16(rawData[0:])
+	e.EncodeFinishDelta = binary.BigEndian.Uint16(rawData[2:])
+	e.PacketizationFinishDelta = binary.BigEndian.Uint16(rawData[4:])
+	e.PacerExitDelta = binary.BigEndian.Uint16(rawData[6:])
+	e.NetworkTimestampDelta = binary.BigEndian.Uint16(rawData[8:])
+	e.Network2TimestampDelta = binary.BigEndian.Uint16(rawData[10:])
+	return nil
+}
+
+// delta returns the milliseconds from capture to t, limited to the range of
+// the extension. Stages that didn't happen, with a zero t, are 0.
+func delta(capture, t time.Time) uint16 {
+	if t.IsZero() || t.Before(capture) {
+		return 0
+	}
+
+	ms := t.Sub(capture) / time.Millisecond
+	if ms > maxDelta {
+		return maxDelta
+	}
+	return uint16(ms)
+}
@@ -0,0 +1,57 @@
+package videotiming
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtension(t *testing.T) {
+	capture := time.Unix(1000, 0)
+	e := NewExtension(Timing{
+		Capture:             capture,
+		EncodeStart:         capture.Add(2 * time.Millisecond),
+		EncodeFinish:        capture.Add(15 * time.Millisecond),
+		PacketizationFinish: capture.Add(16 * time.Millisecond),
+		PacerExit:           capture.Add(2 * time.Minute),
+	}, FlagTriggeredByTimer)
+	assert.Equal(t, Extension{
+		Flags:                    FlagTriggeredByTimer,
+		EncodeStartDelta:         2,
+		EncodeFinishDelta:        15,
+		PacketizationFinishDelta: 16,
+		PacerExitDelta:           maxDelta,
+	}, e)
+
+	raw, err := e.Marshal()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x01, 0x00, 0x02, 0x00, 0x0F, 0x00, 0x10, 0xFF, 0xFF, 0x00, 0x00, 0x00, 0x00}, raw)
+
+	// The extension is carried in RTP packets
+	packet := &rtp.Packet{Header: rtp.Header{Version: 2}}
+	assert.NoError(t, packet.SetExtension(4, raw))
+	marshaled, err := packet.Marshal()
+	assert.NoError(t, err)
+	assert.NoError(t, packet.Unmarshal(marshaled))
+
+	parsed := Extension{}
+	assert.NoError(t, parsed.Unmarshal(packet.GetExtension(4)))
+	assert.Equal(t, e, parsed)
+	assert.Equal(t, capture.Add(15*time.Millisecond), parsed.Timing(capture).EncodeFinish)
+
+	// Legacy extensions have no flags
+	assert.NoError(t, parsed.Unmarshal(raw[1:]))
+	assert.Equal(t, uint8(0), parsed.Flags)
+	assert.Equal(t, uint16(16), parsed.PacketizationFinishDelta)
+
+	assert.Equal(t, errExtensionSize, parsed.Unmarshal(raw[2:]))
+}
+
+func TestDelta(t *testing.T) {
+	capture := time.Unix(1000, 0)
+	assert.Equal(t, uint16(0), delta(capture, time.Time{}))
+	assert.Equal(t, uint16(0), delta(capture, capture.Add(-time.Second)))
+	assert.Equal(t, uint16(1500), delta(capture, capture.Add(1500*time.Millisecond)))
+}
@@ -0,0 +1,250 @@
+// +build !js
+
+package whip
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/pion/webrtc/v3"
+)
+
+// Client publishes to a WHIP endpoint, or subscribes to a WHEP endpoint
+type Client struct {
+	// Endpoint is the URL of the WHIP or WHEP endpoint
+	Endpoint string
+
+	// Token authenticates the client as a bearer token, unless it is empty
+	Token string
+
+	// HTTPClient sends the requests, it is http.DefaultClient if nil
+	HTTPClient *http.Client
+}
+
+// Session is a session created by a Client
+type Session struct {
+	// URL of the session on the server
+	URL string
+
+	client *Client
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu sync.Mutex
+	// fragmentHeader has the ICE credentials of the trickled candidates
+	fragmentHeader string
+	candidates     []string
+	trickling      bool
+}
+
+// Publish sends tracks with a new PeerConnection to a WHIP endpoint. The
+// PeerConnection is negotiated once, the tracks must be added by Publish.
+// The offer is sent once all ICE candidates are gathered.
+func (c *Client) Publish(ctx context.Context, pc *webrtc.PeerConnection, tracks ...*webrtc.Track) (*Session, error) {
+	for _, track := range tracks {
+		if _, err := pc.AddTransceiverFromTrack(track, webrtc.RtpTransceiverInit{Direction: webrtc.RTPTransceiverDirectionSendonly}); err != nil {
+			return nil, err
+		}
+	}
+
+	offer, err := pc.CreateOffer(nil)
+	if err != nil {
+		return nil, err
+	}
+	sdp, err := setLocalDescription(ctx, pc, offer)
+	if err != nil {
+		return nil, err
+	}
+
+	session := c.newSession()
+	if err = session.negotiate(ctx, pc, sdp); err != nil {
+		session.cancel()
+		return nil, err
+	}
+	return session, nil
+}
+
+// Subscribe receives tracks of kinds with a new PeerConnection from a WHEP
+// endpoint, the tracks are received with the OnTrack handler of pc. The
+// offer is sent right away, and the ICE candidates of pc are trickled to the
+// session with PATCH requests.
+//
+// Subscribe sets the OnICECandidate handler of pc.
+func (c *Client) Subscribe(ctx context.Context, pc *webrtc.PeerConnection, kinds ...webrtc.RTPCodecType) (*Session, error) {
+	for _, kind := range kinds {
+		if _, err := pc.AddTransceiverFromKind(kind, webrtc.RtpTransceiverInit{Direction: webrtc.RTPTransceiverDirectionRecvonly}); err != nil {
+			return nil, err
+		}
+	}
+
+	offer, err := pc.CreateOffer(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	session := c.newSession()
+	session.fragmentHeader = sdpFragmentHeader(offer.SDP)
+	pc.OnICECandidate(session.addCandidate)
+
+	if err = pc.SetLocalDescription(offer); err == nil {
+		err = session.negotiate(ctx, pc, offer.SDP)
+	}
+	if err != nil {
+		session.cancel()
+		return nil, err
+	}
+
+	// The candidates gathered until now are sent to the session
+	session.mu.Lock()
+	if len(session.candidates) != 0 && !session.trickling {
+		session.trickle()
+	}
+	session.mu.Unlock()
+	return session, nil
+}
+
+// Close deletes the session on the server. The PeerConnection isn't closed.
+func (s *Session) Close(ctx context.Context) error {
+	s.cancel()
+
+	resp, err := s.client.do(ctx, http.MethodDelete, s.URL, "", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() // nolint:errcheck
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("%w: %s", errUnexpectedStatus, resp.Status)
+	}
+	return nil
+}
+
+func (c *Client) newSession() *Session {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Session{client: c, ctx: ctx, cancel: cancel}
+}
+
+// negotiate POSTs the offer to the endpoint, and sets the answer of the
+// session as remote description of pc
+func (s *Session) negotiate(ctx context.Context, pc *webrtc.PeerConnection, offer string) error {
+	resp, err := s.client.do(ctx, http.MethodPost, s.client.Endpoint, contentTypeSDP, strings.NewReader(offer))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() // nolint:errcheck
+
+	if resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("%w: %s", errUnexpectedStatus, resp.Status)
+	}
+	location, err := resp.Location()
+	if err != nil {
+		return errNoLocation
+	}
+	answer, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxSDPSize))
+	if err != nil {
+		return err
+	}
+
+	if err = pc.SetRemoteDescription(webrtc.SessionDescription{Type: webrtc.SDPTypeAnswer, SDP: string(answer)}); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.URL = location.String()
+	s.mu.Unlock()
+	return nil
+}
+
+// addCandidate queues a local ICE candidate to be trickled, nil ends the
+// candidates
+func (s *Session) addCandidate(candidate *webrtc.ICECandidate) {
+	line := "a=end-of-candidates"
+	if candidate != nil {
+		line = "a=" + candidate.ToJSON().Candidate
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.candidates = append(s.candidates, line)
+	if s.URL != "" && !s.trickling {
+		s.trickle()
+	}
+}
+
+// trickle sends the queued candidates until there are none left, it must be
+// called with the lock held
+func (s *Session) trickle() {
+	s.trickling = true
+	go func() {
+		for {
+			s.mu.Lock()
+			candidates := s.candidates
+			s.candidates = nil
+			if len(candidates) == 0 {
+				s.trickling = false
+				s.mu.Unlock()
+				return
+			}
+			url := s.URL
+			body := s.fragmentHeader + strings.Join(candidates, "\r\n") + "\r\n"
+			s.mu.Unlock()
+
+			// Lost candidates only remove candidate pairs, ICE goes on without them
+			_ = s.patch(url, body)
+		}
+	}()
+}
+
+// patch sends a fragment of trickled candidates to the session
+func (s *Session) patch(url, fragment string) error {
+	resp, err := s.client.do(s.ctx, http.MethodPatch, url, contentTypeTrickleICE, strings.NewReader(fragment))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() // nolint:errcheck
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("%w: %s", errUnexpectedStatus, resp.Status)
+	}
+	return nil
+}
+
+func (c *Client) do(ctx context.Context, method, url, contentType string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return httpClient.Do(req)
+}
+
+// sdpFragmentHeader returns the lines of a trickle-ice-sdpfrag with the ICE
+// credentials and the first media section of sdp, the candidates follow them
+func sdpFragmentHeader(sdp string) string {
+	header := map[string]string{}
+	for _, line := range strings.Split(sdp, "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		for _, prefix := range []string{"a=ice-ufrag:", "a=ice-pwd:", "m=", "a=mid:"} {
+			if _, ok := header[prefix]; !ok && strings.HasPrefix(line, prefix) {
+				header[prefix] = line + "\r\n"
+			}
+		}
+	}
+	return header["a=ice-ufrag:"] + header["a=ice-pwd:"] + header["m="] + header["a=mid:"]
+}
@@ -0,0 +1,219 @@
+// +build !js
+
+package whip
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/pion/webrtc/v3"
+)
+
+const sessionIDLength = 16
+
+// Handler is the http.Handler of a WHIP or WHEP endpoint. Offers are POSTed
+// to the endpoint. The URL in the Location header of an answer is the session,
+// ICE candidates are trickled to it with PATCH and it is deleted with DELETE.
+// The Handler must serve both the endpoint and the paths below it, like the
+// http.ServeMux patterns "/whip" and "/whip/".
+type Handler struct {
+	newPeerConnection func(*http.Request) (*webrtc.PeerConnection, error)
+
+	mu       sync.Mutex
+	sessions map[string]*webrtc.PeerConnection
+}
+
+// NewHandler creates a Handler. newPeerConnection creates the PeerConnection
+// of an offer, with the OnTrack handler set for WHIP, or the tracks added for
+// WHEP. It authenticates the client with the request, and rejects it with
+// ErrUnauthorized.
+//
+// The Handler sets the OnConnectionStateChange handler of the PeerConnections
+// to delete the sessions that fail or are closed.
+func NewHandler(newPeerConnection func(*http.Request) (*webrtc.PeerConnection, error)) *Handler {
+	return &Handler{
+		newPeerConnection: newPeerConnection,
+		sessions:          map[string]*webrtc.PeerConnection{},
+	}
+}
+
+// ServeHTTP implements http.Handler
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		h.servePost(w, r)
+	case http.MethodPatch:
+		h.servePatch(w, r)
+	case http.MethodDelete:
+		h.serveDelete(w, r)
+	case http.MethodOptions:
+		w.Header().Set("Accept-Post", contentTypeSDP)
+		w.Header().Set("Accept-Patch", contentTypeTrickleICE)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Allow", "POST, PATCH, DELETE, OPTIONS")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+}
+
+// Close closes the PeerConnections of all sessions
+func (h *Handler) Close() error {
+	h.mu.Lock()
+	sessions := h.sessions
+	h.sessions = map[string]*webrtc.PeerConnection{}
+	h.mu.Unlock()
+
+	var closeErr error
+	for _, pc := range sessions {
+		if err := pc.Close(); err != nil {
+			closeErr = err
+		}
+	}
+	return closeErr
+}
+
+func (h *Handler) servePost(w http.ResponseWriter, r *http.Request) {
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mediaType != contentTypeSDP {
+		http.Error(w, http.StatusText(http.StatusUnsupportedMediaType), http.StatusUnsupportedMediaType)
+		return
+	}
+
+	offer, err := ioutil.ReadAll(io.LimitReader(r.Body, maxSDPSize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	id, err := newSessionID()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	pc, err := h.newPeerConnection(r)
+	switch {
+	case errors.Is(err, ErrUnauthorized):
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	pc.OnConnectionStateChange(func(state webrtc.PeerConnectionState) {
+		if state == webrtc.PeerConnectionStateFailed || state == webrtc.PeerConnectionStateClosed {
+			h.deleteSession(id, pc)
+		}
+	})
+	h.mu.Lock()
+	h.sessions[id] = pc
+	h.mu.Unlock()
+
+	answer, err := answer(r.Context(), pc, string(offer))
+	if err != nil {
+		h.deleteSession(id, pc)
+		if closeErr := pc.Close(); closeErr != nil {
+			err = closeErr
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentTypeSDP)
+	w.Header().Set("Location", path.Join(r.URL.Path, id))
+	w.WriteHeader(http.StatusCreated)
+	_, _ = w.Write([]byte(answer))
+}
+
+// servePatch adds the candidates of a trickle-ice-sdpfrag to a session
+func (h *Handler) servePatch(w http.ResponseWriter, r *http.Request) {
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mediaType != contentTypeTrickleICE {
+		http.Error(w, http.StatusText(http.StatusUnsupportedMediaType), http.StatusUnsupportedMediaType)
+		return
+	}
+
+	h.mu.Lock()
+	pc, ok := h.sessions[path.Base(r.URL.Path)]
+	h.mu.Unlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	fragment, err := ioutil.ReadAll(io.LimitReader(r.Body, maxSDPSize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	for _, line := range strings.Split(string(fragment), "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if !strings.HasPrefix(line, "a=candidate:") {
+			continue
+		}
+		if err = pc.AddICECandidate(webrtc.ICECandidateInit{Candidate: strings.TrimPrefix(line, "a=")}); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) serveDelete(w http.ResponseWriter, r *http.Request) {
+	id := path.Base(r.URL.Path)
+
+	h.mu.Lock()
+	pc, ok := h.sessions[id]
+	delete(h.sessions, id)
+	h.mu.Unlock()
+
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	if err := pc.Close(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// deleteSession deletes the session id, if it is still of pc
+func (h *Handler) deleteSession(id string, pc *webrtc.PeerConnection) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.sessions[id] == pc {
+		delete(h.sessions, id)
+	}
+}
+
+func answer(ctx context.Context, pc *webrtc.PeerConnection, offer string) (string, error) {
+	if err := pc.SetRemoteDescription(webrtc.SessionDescription{Type: webrtc.SDPTypeOffer, SDP: offer}); err != nil {
+		return "", err
+	}
+
+	answer, err := pc.CreateAnswer(nil)
+	if err != nil {
+		return "", err
+	}
+	return setLocalDescription(ctx, pc, answer)
+}
+
+func newSessionID() (string, error) {
+	id := make([]byte, sessionIDLength)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
@@ -0,0 +1,51 @@
+// +build !js
+
+// Package whip implements the WebRTC-HTTP Ingestion Protocol (WHIP), which
+// publishes media to a server with a single HTTP offer/answer exchange, and
+// its counterpart for playback, the WebRTC-HTTP Egress Protocol (WHEP).
+// Encoders like OBS publish with WHIP.
+//
+// Answers are sent once all ICE candidates of the server are gathered. The
+// candidates of a client may be trickled with PATCH requests, ICE restarts
+// aren't supported.
+package whip
+
+import (
+	"context"
+	"errors"
+
+	"github.com/pion/webrtc/v3"
+)
+
+const (
+	contentTypeSDP        = "application/sdp"
+	contentTypeTrickleICE = "application/trickle-ice-sdpfrag"
+
+	// maxSDPSize limits the size of the offers and answers that are read
+	maxSDPSize = 1 << 20
+)
+
+var (
+	// ErrUnauthorized rejects a publisher, it is returned by the
+	// PeerConnection factory of a Handler
+	ErrUnauthorized = errors.New("unauthorized")
+
+	errUnexpectedStatus = errors.New("unexpected HTTP status")
+	errNoLocation       = errors.New("response has no Location of the session")
+)
+
+// setLocalDescription sets the local description of pc, and returns its SDP
+// once all ICE candidates are gathered
+func setLocalDescription(ctx context.Context, pc *webrtc.PeerConnection, desc webrtc.SessionDescription) (string, error) {
+	gatherComplete := webrtc.GatheringCompletePromise(pc)
+	if err := pc.SetLocalDescription(desc); err != nil {
+		return "", err
+	}
+
+	select {
+	case <-gatherComplete:
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+	return pc.LocalDescription().SDP, nil
+}
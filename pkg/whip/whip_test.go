@@ -0,0 +1,213 @@
+// +build !js
+
+package whip
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pion/transport/test"
+	"github.com/pion/webrtc/v3"
+	"github.com/pion/webrtc/v3/pkg/media"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWHIP(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	received := make(chan *webrtc.Track, 1)
+	handler := NewHandler(func(r *http.Request) (*webrtc.PeerConnection, error) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			return nil, ErrUnauthorized
+		}
+
+		pc, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+		if err != nil {
+			return nil, err
+		}
+		pc.OnTrack(func(track *webrtc.Track, _ *webrtc.RTPReceiver) {
+			received <- track
+		})
+		return pc, nil
+	})
+	mux := http.NewServeMux()
+	mux.Handle("/whip", handler)
+	mux.Handle("/whip/", handler)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	track, err := webrtc.NewTrack(webrtc.DefaultPayloadTypeVP8, 1234, "video", "pion", webrtc.NewRTPVP8Codec(webrtc.DefaultPayloadTypeVP8, 90000))
+	assert.NoError(t, err)
+
+	// Publishers without the token are rejected
+	pc, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+	assert.NoError(t, err)
+	client := &Client{Endpoint: server.URL + "/whip"}
+	_, err = client.Publish(context.Background(), pc, track)
+	assert.True(t, strings.Contains(err.Error(), "401"))
+	assert.NoError(t, pc.Close())
+
+	pc, err = webrtc.NewPeerConnection(webrtc.Configuration{})
+	assert.NoError(t, err)
+	client.Token = "token"
+	session, err := client.Publish(context.Background(), pc, track)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(session.URL, server.URL+"/whip/"))
+
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(20 * time.Millisecond):
+				assert.NoError(t, track.WriteSample(media.Sample{Data: []byte{0x00}, Samples: 1}))
+			}
+		}
+	}()
+	assert.Equal(t, uint32(1234), (<-received).SSRC())
+	close(done)
+
+	assert.NoError(t, session.Close(context.Background()))
+	assert.Error(t, session.Close(context.Background()))
+	assert.NoError(t, pc.Close())
+	assert.NoError(t, handler.Close())
+}
+
+func TestWHEP(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	track, err := webrtc.NewTrack(webrtc.DefaultPayloadTypeVP8, 1234, "video", "pion", webrtc.NewRTPVP8Codec(webrtc.DefaultPayloadTypeVP8, 90000))
+	assert.NoError(t, err)
+
+	handler := NewHandler(func(*http.Request) (*webrtc.PeerConnection, error) {
+		pc, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+		if err != nil {
+			return nil, err
+		}
+		if _, err = pc.AddTrack(track); err != nil {
+			return nil, err
+		}
+		return pc, nil
+	})
+
+	// Record the responses to trickled candidates
+	var patchesMu sync.Mutex
+	patches := []int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch {
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, r)
+		w.WriteHeader(recorder.Code)
+
+		patchesMu.Lock()
+		patches = append(patches, recorder.Code)
+		patchesMu.Unlock()
+	}))
+	defer server.Close()
+
+	pc, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+	assert.NoError(t, err)
+	received := make(chan *webrtc.Track, 1)
+	pc.OnTrack(func(track *webrtc.Track, _ *webrtc.RTPReceiver) {
+		received <- track
+	})
+	connected := make(chan struct{})
+	pc.OnConnectionStateChange(func(state webrtc.PeerConnectionState) {
+		if state == webrtc.PeerConnectionStateConnected {
+			close(connected)
+		}
+	})
+
+	client := &Client{Endpoint: server.URL + "/whep"}
+	session, err := client.Subscribe(context.Background(), pc, webrtc.RTPCodecTypeVideo)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(session.URL, server.URL+"/whep/"))
+	<-connected
+
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(20 * time.Millisecond):
+				assert.NoError(t, track.WriteSample(media.Sample{Data: []byte{0x00}, Samples: 1}))
+			}
+		}
+	}()
+	assert.Equal(t, uint32(1234), (<-received).SSRC())
+	close(done)
+
+	patchesMu.Lock()
+	assert.NotEmpty(t, patches)
+	for _, status := range patches {
+		assert.Equal(t, http.StatusNoContent, status)
+	}
+	patchesMu.Unlock()
+
+	assert.NoError(t, session.Close(context.Background()))
+	assert.NoError(t, pc.Close())
+	assert.NoError(t, handler.Close())
+}
+
+func TestSDPFragmentHeader(t *testing.T) {
+	sdp := "v=0\r\nm=video 9 UDP/TLS/RTP/SAVPF 96\r\na=ice-ufrag:ufrag\r\na=ice-pwd:pwd\r\na=mid:0\r\n" +
+		"m=audio 9 UDP/TLS/RTP/SAVPF 111\r\na=ice-ufrag:ufrag\r\na=ice-pwd:pwd\r\na=mid:1\r\n"
+	assert.Equal(t, "a=ice-ufrag:ufrag\r\na=ice-pwd:pwd\r\nm=video 9 UDP/TLS/RTP/SAVPF 96\r\na=mid:0\r\n", sdpFragmentHeader(sdp))
+}
+
+func TestHandler_Requests(t *testing.T) {
+	handler := NewHandler(func(*http.Request) (*webrtc.PeerConnection, error) {
+		return webrtc.NewPeerConnection(webrtc.Configuration{})
+	})
+
+	for _, c := range []struct {
+		method, contentType string
+		status              int
+	}{
+		{http.MethodPost, "text/plain", http.StatusUnsupportedMediaType},
+		{http.MethodPost, contentTypeSDP, http.StatusBadRequest},
+		{http.MethodOptions, "", http.StatusNoContent},
+		{http.MethodGet, "", http.StatusMethodNotAllowed},
+	} {
+		r := httptest.NewRequest(c.method, "/whip", strings.NewReader("invalid"))
+		r.Header.Set("Content-Type", c.contentType)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		assert.Equal(t, c.status, w.Code, c.method+" "+c.contentType)
+	}
+
+	for _, c := range []struct {
+		method, contentType string
+		status              int
+	}{
+		{http.MethodPatch, contentTypeSDP, http.StatusUnsupportedMediaType},
+		{http.MethodPatch, contentTypeTrickleICE, http.StatusNotFound},
+		{http.MethodDelete, "", http.StatusNotFound},
+	} {
+		r := httptest.NewRequest(c.method, "/whip/unknown", nil)
+		r.Header.Set("Content-Type", c.contentType)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		assert.Equal(t, c.status, w.Code, c.method+" "+c.contentType)
+	}
+	assert.NoError(t, handler.Close())
+}
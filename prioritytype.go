@@ -0,0 +1,58 @@
+package webrtc
+
+// PriorityType is the priority of an encoding relative to the others of the
+// PeerConnection, see RTPEncodingParameters.Priority.
+// https://www.w3.org/TR/webrtc-priority/#rtc-priority-type
+type PriorityType int
+
+const (
+	// PriorityTypeVeryLow gets half the bitrate of PriorityTypeLow.
+	PriorityTypeVeryLow PriorityType = iota + 1
+
+	// PriorityTypeLow is the default priority.
+	PriorityTypeLow
+
+	// PriorityTypeMedium gets twice the bitrate of PriorityTypeLow.
+	PriorityTypeMedium
+
+	// PriorityTypeHigh gets four times the bitrate of PriorityTypeLow.
+	PriorityTypeHigh
+)
+
+// This is done this way because of a linter.
+const (
+	priorityTypeVeryLowStr = "very-low"
+	priorityTypeLowStr     = "low"
+	priorityTypeMediumStr  = "medium"
+	priorityTypeHighStr    = "high"
+)
+
+func (p PriorityType) String() string {
+	switch p {
+	case PriorityTypeVeryLow:
+		return priorityTypeVeryLowStr
+	case PriorityTypeLow:
+		return priorityTypeLowStr
+	case PriorityTypeMedium:
+		return priorityTypeMediumStr
+	case PriorityTypeHigh:
+		return priorityTypeHighStr
+	default:
+		return ErrUnknownType.Error()
+	}
+}
+
+// weight returns the share of the bitrate of the priority relative to the
+// others, an unset priority is PriorityTypeLow
+func (p PriorityType) weight() uint64 {
+	switch p {
+	case PriorityTypeVeryLow:
+		return 1
+	case PriorityTypeMedium:
+		return 4
+	case PriorityTypeHigh:
+		return 8
+	default:
+		return 2
+	}
+}
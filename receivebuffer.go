@@ -0,0 +1,181 @@
+// +build !js
+
+package webrtc
+
+import (
+	"io"
+	"sync"
+
+	"github.com/pion/webrtc/v3/pkg/interceptor"
+)
+
+// ReceiveBufferPolicy tells which packets an RTPReceiver drops when the
+// buffer set with WithReceiveBuffer is full
+type ReceiveBufferPolicy int
+
+const (
+	// ReceiveBufferDropOldest drops the oldest buffered packets to make room
+	// for a new one, so a slow reader skips to the most recent media.
+	ReceiveBufferDropOldest ReceiveBufferPolicy = iota + 1
+
+	// ReceiveBufferDropNewest drops the new packets until the reader made
+	// room for them.
+	ReceiveBufferDropNewest
+)
+
+// This is done this way because of a linter.
+const (
+	receiveBufferDropOldestStr = "drop-oldest"
+	receiveBufferDropNewestStr = "drop-newest"
+)
+
+func (p ReceiveBufferPolicy) String() string {
+	switch p {
+	case ReceiveBufferDropOldest:
+		return receiveBufferDropOldestStr
+	case ReceiveBufferDropNewest:
+		return receiveBufferDropNewestStr
+	default:
+		return ErrUnknownType.Error()
+	}
+}
+
+// WithReceiveBuffer reads the RTP of each Track of the RTPReceiver as it
+// arrives into a buffer of at most maxBytes, which ReadRTP reads from. When
+// the reader doesn't keep up, packets are dropped as policy tells and counted
+// in the PacketsDiscarded of the inbound RTP stats, instead of piling up.
+// The Interceptors see every packet as it arrives.
+func WithReceiveBuffer(maxBytes int, policy ReceiveBufferPolicy) RTPReceiverOption {
+	return func(r *RTPReceiver) {
+		r.receiveBufferSize = maxBytes
+		r.receiveBufferPolicy = policy
+	}
+}
+
+// receiveBuffer reads the packets of a reader into a bounded queue with
+// fill, which runs on its own goroutine until the reader returns an error.
+// The packets are read into buffers of pool, which are put back once the
+// packets are read or dropped.
+type receiveBuffer struct {
+	mu        sync.Mutex
+	packets   []*pooledPacket
+	size      int
+	discarded uint32
+	err       error
+
+	maxSize int
+	policy  ReceiveBufferPolicy
+	pool    *sync.Pool
+
+	// notify is signaled when a packet or the error is added
+	notify chan struct{}
+}
+
+func newReceiveBuffer(maxSize int, policy ReceiveBufferPolicy, pool *sync.Pool) *receiveBuffer {
+	return &receiveBuffer{
+		maxSize: maxSize,
+		policy:  policy,
+		pool:    pool,
+		notify:  make(chan struct{}, 1),
+	}
+}
+
+func (b *receiveBuffer) fill(reader interceptor.RTPReader) {
+	for {
+		pooled := b.pool.Get().(*pooledPacket)
+		n, err := reader.Read(pooled.buffer)
+		if err != nil {
+			b.pool.Put(pooled)
+			b.mu.Lock()
+			b.err = err
+			b.mu.Unlock()
+			b.signal()
+			return
+		}
+
+		pooled.raw = pooled.buffer[:n]
+		b.push(pooled)
+	}
+}
+
+func (b *receiveBuffer) push(packet *pooledPacket) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(packet.raw) <= b.maxSize && b.policy != ReceiveBufferDropNewest {
+		for len(b.packets) > 0 && b.size+len(packet.raw) > b.maxSize {
+			b.drop()
+		}
+	}
+	if b.size+len(packet.raw) > b.maxSize {
+		b.discarded++
+		b.pool.Put(packet)
+		return
+	}
+
+	b.packets = append(b.packets, packet)
+	b.size += len(packet.raw)
+	b.signal()
+}
+
+// drop discards the oldest buffered packet
+func (b *receiveBuffer) drop() {
+	b.pool.Put(b.pop())
+	b.discarded++
+}
+
+// pop removes the oldest buffered packet
+func (b *receiveBuffer) pop() *pooledPacket {
+	packet := b.packets[0]
+	b.packets[0] = nil
+	b.packets = b.packets[1:]
+	b.size -= len(packet.raw)
+	return packet
+}
+
+func (b *receiveBuffer) signal() {
+	select {
+	case b.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Read reads the oldest buffered packet, it blocks until there is one. The
+// error of the reader is returned once the buffered packets were read.
+func (b *receiveBuffer) Read(p []byte) (int, error) {
+	for {
+		b.mu.Lock()
+		if len(b.packets) > 0 {
+			if len(p) < len(b.packets[0].raw) {
+				b.mu.Unlock()
+				return 0, io.ErrShortBuffer
+			}
+
+			packet := b.pop()
+			if len(b.packets) > 0 {
+				b.signal()
+			}
+			b.mu.Unlock()
+
+			n := copy(p, packet.raw)
+			b.pool.Put(packet)
+			return n, nil
+		} else if b.err != nil {
+			err := b.err
+			b.signal()
+			b.mu.Unlock()
+			return 0, err
+		}
+		b.mu.Unlock()
+
+		<-b.notify
+	}
+}
+
+// discardedPackets returns the number of dropped packets
+func (b *receiveBuffer) discardedPackets() uint32 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.discarded
+}
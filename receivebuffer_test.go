@@ -0,0 +1,157 @@
+// +build !js
+
+package webrtc
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/pion/transport/test"
+	"github.com/pion/webrtc/v3/pkg/media"
+	"github.com/stretchr/testify/assert"
+)
+
+// chanReader returns the packets sent on it, and io.EOF once it is closed
+type chanReader chan []byte
+
+func (c chanReader) Read(b []byte) (int, error) {
+	packet, ok := <-c
+	if !ok {
+		return 0, io.EOF
+	}
+	return copy(b, packet), nil
+}
+
+func TestReceiveBufferPolicy_String(t *testing.T) {
+	testCases := []struct {
+		policy         ReceiveBufferPolicy
+		expectedString string
+	}{
+		{ReceiveBufferPolicy(0), ErrUnknownType.Error()},
+		{ReceiveBufferDropOldest, "drop-oldest"},
+		{ReceiveBufferDropNewest, "drop-newest"},
+	}
+
+	for i, testCase := range testCases {
+		assert.Equal(t,
+			testCase.expectedString,
+			testCase.policy.String(),
+			"testCase: %d %v", i, testCase,
+		)
+	}
+}
+
+func TestReceiveBuffer(t *testing.T) {
+	for _, testCase := range []struct {
+		policy   ReceiveBufferPolicy
+		expected []byte
+	}{
+		{ReceiveBufferDropOldest, []byte{3, 4}},
+		{ReceiveBufferDropNewest, []byte{0, 1}},
+	} {
+		t.Run(testCase.policy.String(), func(t *testing.T) {
+			reader := make(chanReader)
+			b := newReceiveBuffer(20, testCase.policy, &NewAPI().rtpPacketPool)
+			go b.fill(reader)
+
+			// Only two packets of 10 bytes fit, the sends return once the
+			// previous packet was buffered
+			for i := byte(0); i < 5; i++ {
+				reader <- append([]byte{i}, make([]byte, 9)...)
+			}
+			close(reader)
+
+			// The buffered packets are read before the error of the reader
+			buf := make([]byte, receiveMTU)
+			for _, first := range testCase.expected {
+				n, err := b.Read(buf)
+				assert.NoError(t, err)
+				assert.Equal(t, 10, n)
+				assert.Equal(t, first, buf[0])
+			}
+			_, err := b.Read(buf)
+			assert.Equal(t, io.EOF, err)
+			assert.Equal(t, uint32(3), b.discardedPackets())
+		})
+	}
+}
+
+func TestReceiveBuffer_Oversized(t *testing.T) {
+	reader := make(chanReader)
+	b := newReceiveBuffer(5, ReceiveBufferDropOldest, &NewAPI().rtpPacketPool)
+	go b.fill(reader)
+
+	reader <- []byte{1, 2, 3}
+	reader <- make([]byte, 10)
+	close(reader)
+
+	// A packet larger than the buffer is dropped without the others
+	buf := make([]byte, receiveMTU)
+	n, err := b.Read(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{1, 2, 3}, buf[:n])
+	_, err = b.Read(buf)
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, uint32(1), b.discardedPackets())
+}
+
+func TestPeerConnection_ReceiveBuffer(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	m := MediaEngine{}
+	m.RegisterDefaultCodecs()
+	s := SettingEngine{}
+	s.SetRTPReceiverOptions(WithReceiveBuffer(100, ReceiveBufferDropNewest))
+	api := NewAPI(WithMediaEngine(m), WithSettingEngine(s))
+
+	pcOffer, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	pcAnswer, err := api.NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, 5000, "video", "pion")
+	assert.NoError(t, err)
+	_, err = pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+
+	// The Track is read once and then left alone
+	read := make(chan *Track, 1)
+	pcAnswer.OnTrack(func(track *Track, r *RTPReceiver) {
+		if _, readErr := track.ReadRTP(); readErr == nil {
+			read <- track
+		}
+	})
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	var remote *Track
+	for remote == nil {
+		select {
+		case remote = <-read:
+		case <-time.After(20 * time.Millisecond):
+			assert.NoError(t, track.WriteSample(media.Sample{Data: []byte{0x00}, Samples: 1}))
+		}
+	}
+
+	// The packets which don't fit are discarded
+	for {
+		assert.NoError(t, track.WriteSample(media.Sample{Data: []byte{0x00}, Samples: 1}))
+		time.Sleep(5 * time.Millisecond)
+
+		stats, ok := pcAnswer.GetStats().GetInboundRTPStreamStats(remote)
+		assert.True(t, ok)
+		if stats.PacketsDiscarded != 0 {
+			break
+		}
+	}
+
+	// The buffered packets can still be read
+	_, err = remote.ReadRTP()
+	assert.NoError(t, err)
+
+	closePairNow(t, pcOffer, pcAnswer)
+}
@@ -0,0 +1,218 @@
+// +build !js
+
+package webrtc
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3/internal/util"
+)
+
+const (
+	// maxDropout and maxMisorder are the largest jumps forward and backward
+	// of sequence numbers that aren't taken for a restart of the sender, as
+	// in RFC 3550 appendix A.1
+	maxDropout  = 3000
+	maxMisorder = 100
+)
+
+// receiveStats are the statistics of the packets read from a remote stream.
+// Sequence numbers and timestamps are extended to 64 bits, so loss and
+// jitter stay right when they wrap around on long streams.
+type receiveStats struct {
+	sequence  util.SequenceUnwrapper
+	timestamp util.TimestampUnwrapper
+
+	// base is the lowest extended sequence number since the sender started
+	// or restarted, with resyncReceived packets received since then
+	base           int64
+	resyncReceived uint64
+	lostBefore     int64
+
+	// badSequence is the sequence number expected after a large jump, which
+	// is only followed if the next packet has it
+	hasBadSequence bool
+	badSequence    uint16
+
+	received     uint64
+	bytes        uint64
+	lastReceived time.Time
+
+	// jitter is the interarrival jitter in timestamp units, computed from
+	// the arrival times since start
+	jitter     float64
+	start      time.Time
+	hasTransit bool
+	transit    int64
+
+	// reportedExpected and reportedReceived are the packets expected and
+	// received when the last report was sent
+	reportedExpected int64
+	reportedReceived uint64
+}
+
+// update adds the packet with header and size, received at now, to the
+// statistics. The jitter is only computed with a clockRate.
+func (s *receiveStats) update(header *rtp.Header, size int, now time.Time, clockRate uint32) {
+	if s.received != 0 {
+		delta := header.SequenceNumber - uint16(s.sequence.Highest())
+		if delta >= maxDropout && delta <= math.MaxUint16-maxMisorder {
+			if !s.hasBadSequence || header.SequenceNumber != s.badSequence {
+				s.hasBadSequence = true
+				s.badSequence = header.SequenceNumber + 1
+				return
+			}
+
+			// Two sequential packets after a large jump, the sender restarted
+			s.lostBefore = s.packetsLost()
+			s.sequence = util.SequenceUnwrapper{}
+			s.timestamp = util.TimestampUnwrapper{}
+			s.resyncReceived = 0
+			s.hasTransit = false
+		}
+	}
+	s.hasBadSequence = false
+
+	if sequence := s.sequence.Unwrap(header.SequenceNumber); s.resyncReceived == 0 || sequence < s.base {
+		s.base = sequence
+	}
+	s.resyncReceived++
+	s.received++
+	s.bytes += uint64(size)
+	s.lastReceived = now
+
+	if clockRate == 0 {
+		return
+	}
+	if s.start.IsZero() {
+		s.start = now
+	}
+
+	// The arrival time is converted in two steps, as nanoseconds times the
+	// clock rate overflow after a day
+	elapsed := now.Sub(s.start)
+	arrival := int64(elapsed/time.Second)*int64(clockRate) + int64(elapsed%time.Second)*int64(clockRate)/int64(time.Second)
+	transit := arrival - s.timestamp.Unwrap(header.Timestamp)
+	if s.hasTransit {
+		d := float64(transit - s.transit)
+		s.jitter += (math.Abs(d) - s.jitter) / 16
+	}
+	s.hasTransit, s.transit = true, transit
+}
+
+// packetsLost returns the packets expected but not received, which is
+// negative if packets are duplicated
+func (s *receiveStats) packetsLost() int64 {
+	if s.resyncReceived == 0 {
+		return s.lostBefore
+	}
+
+	expected := s.sequence.Highest() - s.base + 1
+	return s.lostBefore + expected - int64(s.resyncReceived)
+}
+
+// fractionLost returns the fraction of the packets lost since the previous
+// call, in 1/256 as in receiver reports
+func (s *receiveStats) fractionLost() uint8 {
+	expected := int64(s.received) + s.packetsLost()
+	expectedInterval := expected - s.reportedExpected
+	lostInterval := expectedInterval - int64(s.received-s.reportedReceived)
+	s.reportedExpected, s.reportedReceived = expected, s.received
+
+	if expectedInterval <= 0 || lostInterval <= 0 {
+		return 0
+	}
+	if fraction := lostInterval * 256 / expectedInterval; fraction < math.MaxUint8 {
+		return uint8(fraction)
+	}
+	return math.MaxUint8
+}
+
+// inboundRTPStreamStatsID returns the ID of the stats of the remote stream
+// with ssrc
+func inboundRTPStreamStatsID(ssrc uint32) string {
+	return fmt.Sprintf("InboundRTPStream-%d", ssrc)
+}
+
+// updateStats adds the packet with header and size, read from t, to its
+// statistics
+func (r *RTPReceiver) updateStats(t *trackStreams, header *rtp.Header, size int) {
+	clockRate := uint32(0)
+	if codec := t.track.Codec(); codec != nil {
+		clockRate = codec.ClockRate
+	}
+
+	r.statsMu.Lock()
+	defer r.statsMu.Unlock()
+	t.stats.update(header, size, time.Now(), clockRate)
+}
+
+func (r *RTPReceiver) collectStats(collector *statsReportCollector) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	r.statsMu.Lock()
+	defer r.statsMu.Unlock()
+
+	for i := range r.tracks {
+		t := &r.tracks[i]
+		ssrc := t.track.SSRC()
+		if ssrc == 0 || t.stats.received == 0 {
+			continue
+		}
+
+		collector.Collecting()
+
+		stats := InboundRTPStreamStats{
+			Timestamp:                   statsTimestampFrom(time.Now()),
+			Type:                        StatsTypeInboundRTP,
+			ID:                          inboundRTPStreamStatsID(ssrc),
+			SSRC:                        ssrc,
+			Kind:                        r.kind.String(),
+			PacketsReceived:             uint32(t.stats.received),
+			BytesReceived:               t.stats.bytes,
+			LastPacketReceivedTimestamp: statsTimestampFrom(t.stats.lastReceived),
+		}
+
+		if t.receiveBuffer != nil {
+			stats.PacketsDiscarded = t.receiveBuffer.discardedPackets()
+		}
+
+		lost := t.stats.packetsLost()
+		switch {
+		case lost > math.MaxInt32:
+			stats.PacketsLost = math.MaxInt32
+		case lost < math.MinInt32:
+			stats.PacketsLost = math.MinInt32
+		default:
+			stats.PacketsLost = int32(lost)
+		}
+
+		if codec := t.track.Codec(); codec != nil {
+			stats.CodecID = codec.statsID
+			if codec.ClockRate != 0 {
+				stats.Jitter = t.stats.jitter / float64(codec.ClockRate)
+			}
+		}
+
+		if roundTripTime, ok := r.RoundTripTime(); ok {
+			collector.Collecting()
+			remoteStats := RemoteOutboundRTPStreamStats{
+				Timestamp:     stats.Timestamp,
+				Type:          StatsTypeRemoteOutboundRTP,
+				ID:            remoteOutboundRTPStreamStatsID(ssrc),
+				SSRC:          ssrc,
+				Kind:          stats.Kind,
+				CodecID:       stats.CodecID,
+				LocalID:       stats.ID,
+				RoundTripTime: roundTripTime.Seconds(),
+			}
+			stats.RemoteID = remoteStats.ID
+			collector.Collect(remoteStats.ID, remoteStats)
+		}
+
+		collector.Collect(stats.ID, stats)
+	}
+}
@@ -0,0 +1,108 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3/pkg/media"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReceiveStats_Rollover(t *testing.T) {
+	s := &receiveStats{}
+	now := time.Unix(1600000000, 0)
+	seq, timestamp := uint16(65500), uint32(0xFFFFFFFF-90000)
+
+	// Packets sent every 20ms with a constant delay cross both rollovers
+	for i := 0; i < 200; i++ {
+		if i%50 != 10 {
+			s.update(&rtp.Header{SequenceNumber: seq, Timestamp: timestamp}, 100, now, 90000)
+		}
+		seq++
+		timestamp += 1800
+		now = now.Add(20 * time.Millisecond)
+	}
+
+	assert.Equal(t, uint64(196), s.received)
+	assert.Equal(t, uint64(19600), s.bytes)
+	assert.Equal(t, int64(4), s.packetsLost())
+	assert.InDelta(t, 0, s.jitter, 0.001)
+}
+
+func TestReceiveStats_Reordered(t *testing.T) {
+	s := &receiveStats{}
+	now := time.Now()
+	for _, seq := range []uint16{65534, 0, 65535, 1, 1} {
+		s.update(&rtp.Header{SequenceNumber: seq}, 10, now, 0)
+	}
+
+	// The duplicate makes the loss negative
+	assert.Equal(t, uint64(5), s.received)
+	assert.Equal(t, int64(-1), s.packetsLost())
+}
+
+func TestReceiveStats_Restart(t *testing.T) {
+	s := &receiveStats{}
+	now := time.Now()
+	for _, seq := range []uint16{100, 101, 103} {
+		s.update(&rtp.Header{SequenceNumber: seq}, 10, now, 0)
+	}
+	assert.Equal(t, int64(1), s.packetsLost())
+
+	// A single packet far away is dropped, two sequential ones restart
+	s.update(&rtp.Header{SequenceNumber: 40000}, 10, now, 0)
+	assert.Equal(t, uint64(3), s.received)
+	s.update(&rtp.Header{SequenceNumber: 40001}, 10, now, 0)
+	s.update(&rtp.Header{SequenceNumber: 40003}, 10, now, 0)
+	assert.Equal(t, uint64(5), s.received)
+	assert.Equal(t, int64(2), s.packetsLost())
+}
+
+func TestPeerConnection_GetStats_InboundRTP(t *testing.T) {
+	pcOffer, pcAnswer, err := newPair()
+	assert.NoError(t, err)
+
+	track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, 5000, "video", "pion")
+	assert.NoError(t, err)
+	_, err = pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+
+	read := make(chan *Track)
+	pcAnswer.OnTrack(func(track *Track, r *RTPReceiver) {
+		for i := 0; i < 5; i++ {
+			if _, readErr := track.ReadRTP(); readErr != nil {
+				return
+			}
+		}
+		read <- track
+	})
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-time.After(20 * time.Millisecond):
+				assert.NoError(t, track.WriteSample(media.Sample{Data: []byte{0x00}, Samples: 1}))
+			case <-done:
+				return
+			}
+		}
+	}()
+	remote := <-read
+	close(done)
+
+	stats, ok := pcAnswer.GetStats().GetInboundRTPStreamStats(remote)
+	assert.True(t, ok)
+	assert.Equal(t, StatsTypeInboundRTP, stats.Type)
+	assert.Equal(t, uint32(5000), stats.SSRC)
+	assert.Equal(t, "video", stats.Kind)
+	assert.Equal(t, uint32(5), stats.PacketsReceived)
+	assert.Equal(t, int32(0), stats.PacketsLost)
+
+	closePairNow(t, pcOffer, pcAnswer)
+}
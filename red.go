@@ -0,0 +1,167 @@
+// +build !js
+
+package webrtc
+
+import (
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pion/rtp"
+	"github.com/pion/sdp/v3"
+	"github.com/pion/webrtc/v3/pkg/media/red"
+)
+
+// redSender wraps the Opus packets of an RTPSender into RED packets of RFC
+// 2198 when RED is negotiated for their payload type
+type redSender struct {
+	mu sync.Mutex
+
+	// encoder is nil if RED isn't negotiated
+	encoder         *red.Encoder
+	opusPayloadType uint8
+}
+
+func (s *redSender) setPayloadTypes(opusPayloadType, redPayloadType uint8, distance int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.opusPayloadType = opusPayloadType
+	s.encoder = red.NewEncoder(redPayloadType, distance)
+}
+
+// wrap returns the header and the payload of the RED packet carrying an
+// Opus packet, other packets like telephone-events are returned as they
+// are. The header of the caller isn't modified.
+func (s *redSender) wrap(header *rtp.Header, payload []byte) (*rtp.Header, []byte) {
+	s.mu.Lock()
+	encoder, opusPayloadType := s.encoder, s.opusPayloadType
+	s.mu.Unlock()
+
+	if encoder == nil || header.PayloadType != opusPayloadType {
+		return header, payload
+	}
+	return encoder.Encode(rtpOutboundMTU-header.MarshalSize(), header, payload)
+}
+
+// negotiatedRed returns the payload type and the distance of the RED codec
+// carrying the Opus payload type opusPayloadType, if it is registered and in
+// the remote description
+func (pc *PeerConnection) negotiatedRed(opusPayloadType uint8) (payloadType uint8, distance int, ok bool) {
+	remoteDescription := pc.remoteDescription()
+	if remoteDescription == nil || remoteDescription.parsed == nil {
+		return 0, 0, false
+	}
+
+	for _, codec := range pc.getMediaEngine().GetCodecsByName(RED) {
+		primary, redDistance, isRed := parseRedFmtp(codec.SDPFmtpLine)
+		if isRed && primary == opusPayloadType && hasRemoteCodec(remoteDescription.parsed, codec) {
+			return codec.PayloadType, redDistance, true
+		}
+	}
+	return 0, 0, false
+}
+
+// hasRemoteCodec returns true if an audio section of sd has the payload type
+// of codec, with its name
+func hasRemoteCodec(sd *sdp.SessionDescription, codec *RTPCodec) bool {
+	for _, md := range sd.MediaDescriptions {
+		if md.MediaName.Media != mediaNameAudio {
+			continue
+		}
+
+		section := &sdp.SessionDescription{MediaDescriptions: []*sdp.MediaDescription{md}}
+		for _, format := range md.MediaName.Formats {
+			if format != strconv.Itoa(int(codec.PayloadType)) {
+				continue
+			}
+			if remote, err := section.GetCodecForPayloadType(codec.PayloadType); err == nil && strings.EqualFold(remote.Name, codec.Name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// getRemoteCodec returns the codec of a remote Track receiving payloadType,
+// and the payload type of the RED packets carrying it, 0 if RED isn't
+// registered for it. A Track receiving RED has the codec of the primary Opus
+// encoding, as Read decodes the RED packets.
+func (m *MediaEngine) getRemoteCodec(payloadType uint8) (codec *RTPCodec, redPayloadType uint8, err error) {
+	codec, err = m.getCodec(payloadType)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if strings.EqualFold(codec.Name, RED) {
+		if opusPayloadType, _, ok := parseRedFmtp(codec.SDPFmtpLine); ok {
+			if opus, opusErr := m.getCodec(opusPayloadType); opusErr == nil {
+				return opus, payloadType, nil
+			}
+		}
+		return codec, 0, nil
+	}
+
+	for _, redCodec := range m.GetCodecsByName(RED) {
+		if opusPayloadType, _, ok := parseRedFmtp(redCodec.SDPFmtpLine); ok && opusPayloadType == payloadType {
+			return codec, redCodec.PayloadType, nil
+		}
+	}
+	return codec, 0, nil
+}
+
+// readRED returns the next Opus packet decoded from the RED packets read by
+// Track.Read, or false if none is queued
+func (t *Track) readRED(b []byte) (n int, ok bool, err error) {
+	t.mu.Lock()
+	if len(t.redQueue) == 0 {
+		t.mu.Unlock()
+		return 0, false, nil
+	}
+	packet := t.redQueue[0]
+	t.redQueue = t.redQueue[1:]
+	t.mu.Unlock()
+
+	if len(b) < len(packet) {
+		return 0, true, io.ErrShortBuffer
+	}
+	return copy(b, packet), true, nil
+}
+
+// decodeRED replaces the RED packet in b[:n] with the first Opus packet it
+// carries, and queues the others for the next Reads. Other packets are left
+// as they are. It returns false if the RED packet carries no new packet, or
+// is invalid, so it must be dropped.
+func (t *Track) decodeRED(b []byte, n int) (int, bool, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.redPayloadType == 0 || n < 2 || b[1]&0x7F != t.redPayloadType {
+		return n, true, nil
+	}
+
+	packet := &rtp.Packet{}
+	if packet.Unmarshal(b[:n]) != nil {
+		return 0, false, nil
+	}
+	decoded, decodeErr := t.redDecoder.Decode(packet)
+	if decodeErr != nil || len(decoded) == 0 {
+		return 0, false, nil
+	}
+
+	raw := make([][]byte, 0, len(decoded))
+	for _, p := range decoded {
+		p.Padding = false
+		marshaled, err := p.Marshal()
+		if err != nil {
+			return 0, false, err
+		}
+		raw = append(raw, marshaled)
+	}
+
+	t.redQueue = append(t.redQueue, raw[1:]...)
+	if len(b) < len(raw[0]) {
+		return 0, true, io.ErrShortBuffer
+	}
+	return copy(b, raw[0]), true, nil
+}
@@ -0,0 +1,200 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/transport/test"
+	"github.com/pion/webrtc/v3/pkg/media"
+	"github.com/pion/webrtc/v3/pkg/media/red"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedSender(t *testing.T) {
+	sender, track, written := newDTMFSender(t)
+	sender.red.setPayloadTypes(DefaultPayloadTypeOpus, 63, 1)
+
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, track.WriteRTP(&rtp.Packet{
+			Header:  rtp.Header{Version: 2, PayloadType: DefaultPayloadTypeOpus, SequenceNumber: uint16(10 + i), Timestamp: uint32(960 * i), SSRC: 1234},
+			Payload: []byte{0xF8, byte(i)},
+		}))
+	}
+
+	// The previous payload is sent with the next one
+	assert.Equal(t, []*rtp.Packet{
+		{Header: rtp.Header{Version: 2, PayloadType: 63, SequenceNumber: 10, SSRC: 1234}, Payload: []byte{DefaultPayloadTypeOpus, 0xF8, 0x00}},
+		{
+			Header:  rtp.Header{Version: 2, PayloadType: 63, SequenceNumber: 11, Timestamp: 960, SSRC: 1234},
+			Payload: []byte{0x80 | DefaultPayloadTypeOpus, 0x0F, 0x00, 0x02, DefaultPayloadTypeOpus, 0xF8, 0x00, 0xF8, 0x01},
+		},
+	}, written())
+
+	// Telephone-events aren't wrapped
+	assert.NoError(t, sender.InsertDTMF("1", 40*time.Millisecond, 0))
+	assert.NoError(t, track.WriteRTP(&rtp.Packet{
+		Header:  rtp.Header{Version: 2, PayloadType: DefaultPayloadTypeOpus, SequenceNumber: 12, Timestamp: 1920, SSRC: 1234},
+		Payload: []byte{0xF8, 0x02},
+	}))
+	assert.Equal(t, uint8(110), written()[2].PayloadType)
+}
+
+func TestTrack_decodeRED(t *testing.T) {
+	track := &Track{redPayloadType: 63}
+	encoder := red.NewEncoder(63, 2)
+	encode := func(sequenceNumber uint16) []byte {
+		header, payload := encoder.Encode(1200, &rtp.Header{
+			Version:        2,
+			PayloadType:    DefaultPayloadTypeOpus,
+			SequenceNumber: sequenceNumber,
+			Timestamp:      960 * uint32(sequenceNumber),
+		}, []byte{0xF8, byte(sequenceNumber)})
+		raw, err := (&rtp.Packet{Header: *header, Payload: payload}).Marshal()
+		assert.NoError(t, err)
+		return raw
+	}
+	decode := func(raw []byte) (*rtp.Packet, bool) {
+		b := make([]byte, receiveMTU)
+		n, ok, err := track.decodeRED(b, copy(b, raw))
+		assert.NoError(t, err)
+		if !ok {
+			return nil, false
+		}
+
+		packet := &rtp.Packet{}
+		assert.NoError(t, packet.Unmarshal(b[:n]))
+		return packet, true
+	}
+	queued := func() *rtp.Packet {
+		b := make([]byte, receiveMTU)
+		n, ok, err := track.readRED(b)
+		assert.NoError(t, err)
+		if !ok {
+			return nil
+		}
+
+		packet := &rtp.Packet{}
+		assert.NoError(t, packet.Unmarshal(b[:n]))
+		return packet
+	}
+
+	packet, ok := decode(encode(1))
+	assert.True(t, ok)
+	assert.Equal(t, uint8(DefaultPayloadTypeOpus), packet.PayloadType)
+	assert.Equal(t, []byte{0xF8, 0x01}, packet.Payload)
+	assert.Nil(t, queued())
+
+	// Packet 2 is lost, it is read before packet 3
+	late := encode(2)
+	packet, ok = decode(encode(3))
+	assert.True(t, ok)
+	assert.Equal(t, uint16(2), packet.SequenceNumber)
+	assert.Equal(t, []byte{0xF8, 0x02}, packet.Payload)
+	packet = queued()
+	assert.Equal(t, uint16(3), packet.SequenceNumber)
+	assert.Equal(t, []byte{0xF8, 0x03}, packet.Payload)
+	assert.Nil(t, queued())
+
+	// Late packets are dropped
+	_, ok = decode(late)
+	assert.False(t, ok)
+
+	// Opus packets are read as they are
+	raw, err := (&rtp.Packet{Header: rtp.Header{Version: 2, PayloadType: DefaultPayloadTypeOpus}, Payload: []byte{0xF8}}).Marshal()
+	assert.NoError(t, err)
+	packet, ok = decode(raw)
+	assert.True(t, ok)
+	assert.Equal(t, []byte{0xF8}, packet.Payload)
+}
+
+func TestPeerConnection_Red(t *testing.T) {
+	lim := test.TimeOut(time.Second * 30)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	newAPI := func(withRed bool) *API {
+		m := MediaEngine{}
+		m.RegisterDefaultCodecs()
+		if withRed {
+			m.RegisterCodec(NewRTPRedCodec(63, 48000, DefaultPayloadTypeOpus, 1))
+		}
+		return NewAPI(WithMediaEngine(m))
+	}
+
+	pcOffer, err := newAPI(true).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	pcAnswer, err := newAPI(true).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	track, err := pcOffer.NewTrack(DefaultPayloadTypeOpus, 5000, "audio", "pion")
+	assert.NoError(t, err)
+	_, err = pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+
+	// The remote Track is an Opus Track reading the packets carried by RED
+	read := make(chan []*rtp.Packet)
+	pcAnswer.OnTrack(func(track *Track, r *RTPReceiver) {
+		assert.Equal(t, Opus, track.Codec().Name)
+		assert.Equal(t, uint8(DefaultPayloadTypeOpus), track.PayloadType())
+
+		packets := []*rtp.Packet{}
+		for len(packets) < 3 {
+			packet, readErr := track.ReadRTP()
+			if readErr != nil {
+				return
+			}
+			packets = append(packets, packet)
+		}
+		read <- packets
+	})
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	redPayloadType, distance, ok := pcOffer.negotiatedRed(DefaultPayloadTypeOpus)
+	assert.True(t, ok)
+	assert.Equal(t, uint8(63), redPayloadType)
+	assert.Equal(t, 1, distance)
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; ; i++ {
+			select {
+			case <-time.After(20 * time.Millisecond):
+				assert.NoError(t, track.WriteSample(media.Sample{Data: []byte{0xF8, byte(i)}, Samples: 960}))
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	packets := <-read
+	for i, packet := range packets {
+		assert.Equal(t, uint8(DefaultPayloadTypeOpus), packet.PayloadType)
+		assert.Equal(t, 2, len(packet.Payload))
+		assert.Equal(t, uint8(0xF8), packet.Payload[0])
+		if i != 0 {
+			assert.Equal(t, packets[i-1].SequenceNumber+1, packet.SequenceNumber)
+			assert.Equal(t, packets[i-1].Payload[1]+1, packet.Payload[1])
+		}
+	}
+
+	close(done)
+	closePairNow(t, pcOffer, pcAnswer)
+
+	// RED is only sent if both sides have it
+	pcOffer, err = newAPI(true).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	pcAnswer, err = newAPI(false).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	_, err = pcOffer.AddTransceiverFromKind(RTPCodecTypeAudio)
+	assert.NoError(t, err)
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	_, _, ok = pcOffer.negotiatedRed(DefaultPayloadTypeOpus)
+	assert.False(t, ok)
+	closePairNow(t, pcOffer, pcAnswer)
+}
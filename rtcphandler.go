@@ -0,0 +1,104 @@
+// +build !js
+
+package webrtc
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/pion/rtcp"
+)
+
+// rtcpHandler calls the OnRTCP handler of an RTPSender or an RTPReceiver
+// with the RTCP received for it
+type rtcpHandler struct {
+	mu      sync.Mutex
+	handler func([]rtcp.Packet)
+}
+
+func (h *rtcpHandler) set(f func([]rtcp.Packet)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.handler = f
+}
+
+// handle calls the handler with the packets of b, RTCP that can't be
+// unmarshaled is dropped
+func (h *rtcpHandler) handle(b []byte) {
+	h.mu.Lock()
+	handler := h.handler
+	h.mu.Unlock()
+	if handler == nil {
+		return
+	}
+
+	pkts, err := rtcp.Unmarshal(b)
+	if err != nil {
+		return
+	}
+	handler(pkts)
+}
+
+// OnRTCP sets an event handler which is called with the RTCP packets
+// received for the RTPSender as they arrive, like the feedback of the
+// receiver about its SSRC. The packets are still returned by Read and
+// ReadRTCP.
+func (r *RTPSender) OnRTCP(f func([]rtcp.Packet)) {
+	r.rtcpHandler.set(f)
+}
+
+// WriteRTCP sends RTCP packets about the stream of the RTPSender, like
+// sender reports or source descriptions. Every packet has to be for the SSRC
+// of the RTPSender, so it is routed to the media section of the remote
+// receiver.
+func (r *RTPSender) WriteRTCP(pkts []rtcp.Packet) error {
+	ssrc := r.SSRC()
+	for _, pkt := range pkts {
+		if !containsSSRC(pkt.DestinationSSRC(), ssrc) {
+			return fmt.Errorf("%w: %d", errRTPSenderRTCPSSRCMismatch, ssrc)
+		}
+	}
+	return r.Transport().writeRTCP(pkts)
+}
+
+// OnRTCP sets an event handler which is called with the RTCP packets
+// received for the Tracks of the RTPReceiver as they arrive, like the
+// reports of the remote sender. Unlike Read, it is called for every Track
+// of a simulcast RTPReceiver. The packets are still returned by Read,
+// ReadSimulcast and their RTCP variants.
+func (r *RTPReceiver) OnRTCP(f func([]rtcp.Packet)) {
+	r.rtcpHandler.set(f)
+}
+
+// WriteRTCP sends RTCP packets about the Tracks of the RTPReceiver, like
+// receiver reports or keyframe requests. Every packet has to be for the
+// SSRC of a Track of the RTPReceiver, so it is routed to the media section
+// of the remote sender.
+func (r *RTPReceiver) WriteRTCP(pkts []rtcp.Packet) error {
+	ssrcs := []uint32{}
+	for _, track := range r.Tracks() {
+		if ssrc := track.SSRC(); ssrc != 0 {
+			ssrcs = append(ssrcs, ssrc)
+		}
+	}
+
+	for _, pkt := range pkts {
+		found := false
+		for _, ssrc := range pkt.DestinationSSRC() {
+			found = found || containsSSRC(ssrcs, ssrc)
+		}
+		if !found {
+			return errRTPReceiverRTCPSSRCMismatch
+		}
+	}
+	return r.Transport().writeRTCP(pkts)
+}
+
+func containsSSRC(ssrcs []uint32, ssrc uint32) bool {
+	for _, s := range ssrcs {
+		if s == ssrc {
+			return true
+		}
+	}
+	return false
+}
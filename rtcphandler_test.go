@@ -0,0 +1,96 @@
+// +build !js
+
+package webrtc
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/transport/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRTPSender_OnRTCP(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	pcOffer, pcAnswer, err := newPair()
+	assert.NoError(t, err)
+
+	track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, 5000, "video", "pion")
+	assert.NoError(t, err)
+	sender, err := pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+
+	pliReceived := make(chan struct{})
+	sender.OnRTCP(func(pkts []rtcp.Packet) {
+		for _, pkt := range pkts {
+			if pli, ok := pkt.(*rtcp.PictureLossIndication); ok && pli.MediaSSRC == 5000 {
+				select {
+				case <-pliReceived:
+				default:
+					close(pliReceived)
+				}
+			}
+		}
+	})
+
+	onTrack := make(chan *RTPReceiver, 1)
+	pcAnswer.OnTrack(func(_ *Track, receiver *RTPReceiver) {
+		onTrack <- receiver
+	})
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	go sendVideoUntilDone(pliReceived, t, []*Track{track})
+	receiver := <-onTrack
+
+	sdesReceived := make(chan struct{})
+	receiver.OnRTCP(func(pkts []rtcp.Packet) {
+		for _, pkt := range pkts {
+			if sdes, ok := pkt.(*rtcp.SourceDescription); ok && sdes.Chunks[0].Source == 5000 {
+				select {
+				case <-sdesReceived:
+				default:
+					close(sdesReceived)
+				}
+			}
+		}
+	})
+
+	// RTCP for other SSRCs isn't routed to the remote media section
+	assert.True(t, errors.Is(sender.WriteRTCP([]rtcp.Packet{&rtcp.PictureLossIndication{MediaSSRC: 5001}}), errRTPSenderRTCPSSRCMismatch))
+	assert.True(t, errors.Is(receiver.WriteRTCP([]rtcp.Packet{&rtcp.PictureLossIndication{MediaSSRC: 5001}}), errRTPReceiverRTCPSSRCMismatch))
+
+	sdes := &rtcp.SourceDescription{Chunks: []rtcp.SourceDescriptionChunk{{
+		Source: 5000,
+		Items:  []rtcp.SourceDescriptionItem{{Type: rtcp.SDESCNAME, Text: "pion"}},
+	}}}
+	for _, done := range []chan struct{}{pliReceived, sdesReceived} {
+		func() {
+			ticker := time.NewTicker(20 * time.Millisecond)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-done:
+					return
+				case <-ticker.C:
+					assert.NoError(t, receiver.WriteRTCP([]rtcp.Packet{&rtcp.PictureLossIndication{MediaSSRC: 5000}}))
+					assert.NoError(t, sender.WriteRTCP([]rtcp.Packet{sdes}))
+				}
+			}
+		}()
+	}
+
+	// The RTCP handled is still read
+	_, err = sender.ReadRTCP()
+	assert.NoError(t, err)
+	_, err = receiver.ReadRTCP()
+	assert.NoError(t, err)
+
+	closePairNow(t, pcOffer, pcAnswer)
+}
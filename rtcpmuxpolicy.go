@@ -2,18 +2,27 @@ package webrtc
 
 // RTCPMuxPolicy affects what ICE candidates are gathered to support
 // non-multiplexed RTCP.
+//
+// Non-multiplexed RTCP isn't supported: only RTP candidates are gathered,
+// and RTCP is always sent on them, which a=rtcp-mux in the answer asks the
+// remote endpoint to do too. A remote description that doesn't multiplex
+// RTCP is accepted, unless RTCPMuxPolicyRequire is set explicitly in the
+// Configuration. It is the default policy, but only rejects these
+// descriptions when it is asked for.
 type RTCPMuxPolicy int
 
 const (
 	// RTCPMuxPolicyNegotiate indicates to gather ICE candidates for both
 	// RTP and RTCP candidates. If the remote-endpoint is capable of
 	// multiplexing RTCP, multiplex RTCP on the RTP candidates. If it is not,
-	// use both the RTP and RTCP candidates separately.
+	// RTCP is still sent on the RTP candidates, RTCP candidates aren't
+	// gathered.
 	RTCPMuxPolicyNegotiate RTCPMuxPolicy = iota + 1
 
 	// RTCPMuxPolicyRequire indicates to gather ICE candidates only for
 	// RTP and multiplex RTCP on the RTP candidates. If the remote endpoint is
-	// not capable of rtcp-mux, session negotiation will fail.
+	// not capable of rtcp-mux and the policy is set explicitly, session
+	// negotiation will fail.
 	RTCPMuxPolicyRequire
 )
 
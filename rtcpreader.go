@@ -0,0 +1,102 @@
+// +build !js
+
+package webrtc
+
+import (
+	"io"
+	"sync"
+)
+
+// rtcpReadQueueSize is the number of RTCP packets an rtcpReader queues for
+// Read, the oldest are dropped when the application doesn't read them
+const rtcpReadQueueSize = 64
+
+// rtcpReader reads the RTCP of an RTPSender or an RTPReceiver as it
+// arrives, so it is handled even when the application doesn't read it. The
+// packets are then queued for Read.
+type rtcpReader struct {
+	mu    sync.Mutex
+	queue [][]byte
+	err   error
+
+	queued chan struct{}
+	done   chan struct{}
+}
+
+func newRTCPReader() *rtcpReader {
+	return &rtcpReader{
+		queued: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+}
+
+// run reads stream and calls handle with each packet until stream fails, as
+// the RTPSender or RTPReceiver is stopped
+func (q *rtcpReader) run(stream io.Reader, handle func([]byte)) {
+	b := make([]byte, receiveMTU)
+	for {
+		n, err := stream.Read(b)
+		if err != nil {
+			q.mu.Lock()
+			q.err = err
+			q.mu.Unlock()
+			close(q.done)
+			return
+		}
+
+		pkt := append([]byte{}, b[:n]...)
+		handle(pkt)
+
+		q.mu.Lock()
+		if len(q.queue) == rtcpReadQueueSize {
+			q.queue = q.queue[1:]
+		}
+		q.queue = append(q.queue, pkt)
+		q.mu.Unlock()
+
+		select {
+		case q.queued <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// read returns the oldest packet queued, it blocks until one is, or returns
+// the error of the stream once it failed and the queue is empty
+func (q *rtcpReader) read(b []byte) (int, error) {
+	for {
+		q.mu.Lock()
+		if len(q.queue) != 0 {
+			// The packet stays queued for a read with a larger buffer
+			if len(b) < len(q.queue[0]) {
+				q.mu.Unlock()
+				return 0, io.ErrShortBuffer
+			}
+
+			pkt := q.queue[0]
+			q.queue = q.queue[1:]
+			remaining := len(q.queue)
+			q.mu.Unlock()
+
+			// Let other readers take the remaining packets
+			if remaining != 0 {
+				select {
+				case q.queued <- struct{}{}:
+				default:
+				}
+			}
+			return copy(b, pkt), nil
+		}
+		err := q.err
+		q.mu.Unlock()
+
+		if err != nil {
+			return 0, err
+		}
+
+		select {
+		case <-q.queued:
+		case <-q.done:
+		}
+	}
+}
@@ -0,0 +1,47 @@
+// +build !js
+
+package webrtc
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRTCPReader(t *testing.T) {
+	stream := make(chanReader)
+	reader := newRTCPReader()
+
+	handled := make(chan []byte, rtcpReadQueueSize+1)
+	done := make(chan struct{})
+	go func() {
+		reader.run(stream, func(b []byte) {
+			handled <- b
+		})
+		close(done)
+	}()
+
+	// Every packet is handled, the oldest aren't queued anymore when they
+	// aren't read
+	for i := 0; i < rtcpReadQueueSize+1; i++ {
+		stream <- []byte{byte(i), 0x00}
+	}
+	close(stream)
+	<-done
+	assert.Len(t, handled, rtcpReadQueueSize+1)
+
+	b := make([]byte, receiveMTU)
+	_, err := reader.read(b[:1])
+	assert.Equal(t, io.ErrShortBuffer, err)
+
+	// The packet isn't lost when the buffer is too small
+	for i := 1; i < rtcpReadQueueSize+1; i++ {
+		n, err := reader.read(b)
+		assert.NoError(t, err)
+		assert.Equal(t, []byte{byte(i), 0x00}, b[:n])
+	}
+
+	_, err = reader.read(b)
+	assert.Equal(t, io.EOF, err)
+}
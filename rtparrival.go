@@ -0,0 +1,51 @@
+// +build !js
+
+package webrtc
+
+import (
+	"encoding/binary"
+	"net"
+)
+
+// rtpArrivalConn is the connection the SRTP session reads, it tells the
+// DTLSTransport the SSRC of each RTP packet as it arrives, before the
+// application reads it. The SSRC isn't encrypted.
+type rtpArrivalConn struct {
+	net.Conn
+	transport *DTLSTransport
+}
+
+func (c *rtpArrivalConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if err == nil && n >= 12 {
+		c.transport.rtpArrived(binary.BigEndian.Uint32(b[8:]))
+	}
+	return n, err
+}
+
+// onRTPArrival sets f to be called when RTP with ssrc arrives, or removes
+// the handler of ssrc if f is nil. f is called on the goroutine reading the
+// RTP of every SSRC, so it must not block.
+func (t *DTLSTransport) onRTPArrival(ssrc uint32, f func()) {
+	t.rtpArrivalMu.Lock()
+	defer t.rtpArrivalMu.Unlock()
+
+	if f == nil {
+		delete(t.rtpArrival, ssrc)
+		return
+	}
+	if t.rtpArrival == nil {
+		t.rtpArrival = map[uint32]func(){}
+	}
+	t.rtpArrival[ssrc] = f
+}
+
+func (t *DTLSTransport) rtpArrived(ssrc uint32) {
+	t.rtpArrivalMu.RLock()
+	f := t.rtpArrival[ssrc]
+	t.rtpArrivalMu.RUnlock()
+
+	if f != nil {
+		f()
+	}
+}
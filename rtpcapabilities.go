@@ -0,0 +1,89 @@
+// +build !js
+
+package webrtc
+
+import (
+	"github.com/pion/sdp/v3"
+)
+
+// GetRTPSenderCapabilities returns the codecs and header extensions of kind
+// a PeerConnection created with NewPeerConnection can send, like
+// RTCRtpSender.getCapabilities. It allows a capability exchange before
+// creating PeerConnections.
+func GetRTPSenderCapabilities(kind RTPCodecType) RTPCapabilities {
+	return defaultCapabilitiesAPI().GetRTPSenderCapabilities(kind)
+}
+
+// GetRTPReceiverCapabilities returns the codecs and header extensions of kind
+// a PeerConnection created with NewPeerConnection can receive, like
+// RTCRtpReceiver.getCapabilities.
+func GetRTPReceiverCapabilities(kind RTPCodecType) RTPCapabilities {
+	return defaultCapabilitiesAPI().GetRTPReceiverCapabilities(kind)
+}
+
+// defaultCapabilitiesAPI returns an API configured like the one of
+// NewPeerConnection
+func defaultCapabilitiesAPI() *API {
+	m := MediaEngine{}
+	m.RegisterDefaultCodecs()
+	return NewAPI(WithMediaEngine(m))
+}
+
+// GetRTPSenderCapabilities returns the codecs and header extensions of kind
+// the PeerConnections of the API can send. The codecs are the ones registered
+// in its MediaEngine, and the header extensions the ones added with
+// SettingEngine.AddSDPExtensions that aren't recvonly.
+func (api *API) GetRTPSenderCapabilities(kind RTPCodecType) RTPCapabilities {
+	return api.getCapabilities(kind, sdp.DirectionRecvOnly)
+}
+
+// GetRTPReceiverCapabilities returns the codecs and header extensions of kind
+// the PeerConnections of the API can receive. The codecs are the ones
+// registered in its MediaEngine, and the header extensions the ones added
+// with SettingEngine.AddSDPExtensions that aren't sendonly.
+func (api *API) GetRTPReceiverCapabilities(kind RTPCodecType) RTPCapabilities {
+	return api.getCapabilities(kind, sdp.DirectionSendOnly)
+}
+
+// getCapabilities returns the capabilities of kind, without the header
+// extensions negotiated with the excluded direction
+func (api *API) getCapabilities(kind RTPCodecType, excluded sdp.Direction) RTPCapabilities {
+	capabilities := RTPCapabilities{
+		Codecs:           []RTPCodecCapability{},
+		HeaderExtensions: []RTPHeaderExtensionCapability{},
+	}
+
+	// A MediaEngine populated from SDP can have the same codec with
+	// different payload types
+	for _, codec := range api.getMediaEngine().GetCodecsByKind(kind) {
+		duplicate := false
+		for _, c := range capabilities.Codecs {
+			if c.MimeType == codec.MimeType && c.ClockRate == codec.ClockRate && c.Channels == codec.Channels && c.SDPFmtpLine == codec.SDPFmtpLine {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			capability := codec.RTPCodecCapability
+			capability.RTCPFeedback = append([]RTCPFeedback{}, codec.RTCPFeedback...)
+			capabilities.Codecs = append(capabilities.Codecs, capability)
+		}
+	}
+
+	extensions := api.settingEngine.getSDPExtensions()
+	seen := map[string]bool{}
+	for _, section := range []SDPSectionType{SDPSectionGlobal, SDPSectionType(kind.String())} {
+		for _, extMap := range extensions[section] {
+			if extMap.URI == nil || extMap.Direction == excluded || extMap.Direction == sdp.DirectionInactive {
+				continue
+			}
+
+			uri := extMap.URI.String()
+			if !seen[uri] {
+				seen[uri] = true
+				capabilities.HeaderExtensions = append(capabilities.HeaderExtensions, RTPHeaderExtensionCapability{URI: uri})
+			}
+		}
+	}
+	return capabilities
+}
@@ -0,0 +1,57 @@
+// +build !js
+
+package webrtc
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/pion/sdp/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetRTPCapabilities(t *testing.T) {
+	audio := GetRTPSenderCapabilities(RTPCodecTypeAudio)
+	assert.Equal(t, 4, len(audio.Codecs))
+	assert.Equal(t, "audio/opus", audio.Codecs[0].MimeType)
+	assert.Equal(t, uint32(48000), audio.Codecs[0].ClockRate)
+	assert.Empty(t, audio.HeaderExtensions)
+
+	video := GetRTPReceiverCapabilities(RTPCodecTypeVideo)
+	assert.Equal(t, 3, len(video.Codecs))
+	assert.Equal(t, "video/VP8", video.Codecs[0].MimeType)
+}
+
+func TestAPI_GetRTPCapabilities(t *testing.T) {
+	m := MediaEngine{}
+	m.RegisterCodec(NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000))
+	m.RegisterCodec(NewRTPVP8Codec(120, 90000))
+	m.RegisterCodec(NewRTPOpusCodec(DefaultPayloadTypeOpus, 48000))
+
+	extension := func(uri string, direction sdp.Direction) sdp.ExtMap {
+		parsed, err := url.Parse(uri)
+		assert.NoError(t, err)
+		return sdp.ExtMap{URI: parsed, Direction: direction}
+	}
+	s := SettingEngine{}
+	s.AddSDPExtensions(SDPSectionGlobal, []sdp.ExtMap{extension(sdp.SDESMidURI, 0)})
+	s.AddSDPExtensions(SDPSectionVideo, []sdp.ExtMap{
+		extension(sdp.ABSSendTimeURI, sdp.DirectionSendOnly),
+		extension(PlayoutDelayURI, sdp.DirectionRecvOnly),
+		extension(sdp.SDESMidURI, 0),
+	})
+	api := NewAPI(WithMediaEngine(m), WithSettingEngine(s))
+
+	// Codecs registered with several payload types are listed once
+	sender := api.GetRTPSenderCapabilities(RTPCodecTypeVideo)
+	assert.Equal(t, 1, len(sender.Codecs))
+	assert.Equal(t, "video/VP8", sender.Codecs[0].MimeType)
+	assert.Equal(t, []RTPHeaderExtensionCapability{{URI: sdp.SDESMidURI}, {URI: sdp.ABSSendTimeURI}}, sender.HeaderExtensions)
+
+	receiver := api.GetRTPReceiverCapabilities(RTPCodecTypeVideo)
+	assert.Equal(t, []RTPHeaderExtensionCapability{{URI: sdp.SDESMidURI}, {URI: PlayoutDelayURI}}, receiver.HeaderExtensions)
+
+	audio := api.GetRTPReceiverCapabilities(RTPCodecTypeAudio)
+	assert.Equal(t, 1, len(audio.Codecs))
+	assert.Equal(t, []RTPHeaderExtensionCapability{{URI: sdp.SDESMidURI}}, audio.HeaderExtensions)
+}
@@ -5,4 +5,25 @@ package webrtc
 // http://draft.ortc.org/#dom-rtcrtpencodingparameters
 type RTPEncodingParameters struct {
 	RTPCodingParameters
+
+	// Active tells if RTP is sent, see RTPSender.SetParameters
+	Active bool `json:"active"`
+
+	// MaxBitrate is the maximum bitrate in bits per second, 0 if unlimited
+	MaxBitrate uint64 `json:"maxBitrate"`
+
+	// MinBitrate is the bitrate in bits per second the encoding is given
+	// before the others get more, see PeerConnection.SetTargetBitrate
+	MinBitrate uint64 `json:"minBitrate"`
+
+	// Priority is the share of the target bitrate of the PeerConnection
+	// the encoding gets relative to the others, PriorityTypeLow if unset
+	Priority PriorityType `json:"priority"`
+
+	// MaxFramerate is the maximum frames per second, 0 if unlimited
+	MaxFramerate float64 `json:"maxFramerate"`
+
+	// ScaleResolutionDownBy is the factor the resolution of the video is
+	// scaled down by, 0 if not scaled
+	ScaleResolutionDownBy float64 `json:"scaleResolutionDownBy"`
 }
@@ -3,12 +3,17 @@
 package webrtc
 
 import (
+	"encoding/binary"
 	"fmt"
 	"io"
 	"sync"
+	"time"
 
+	"github.com/pion/logging"
 	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
 	"github.com/pion/srtp"
+	"github.com/pion/webrtc/v3/pkg/interceptor"
 )
 
 // trackStreams maintains a mapping of RTP/RTCP streams to a specific track
@@ -17,6 +22,62 @@ type trackStreams struct {
 	track          *Track
 	rtpReadStream  *srtp.ReadStreamSRTP
 	rtcpReadStream *srtp.ReadStreamSRTCP
+
+	// rtcpReader reads rtcpReadStream, Read returns the packets it queued
+	rtcpReader *rtcpReader
+
+	streamInfo     interceptor.StreamInfo
+	rtpInterceptor interceptor.RTPReader
+
+	// receiveBuffer is the buffer set with WithReceiveBuffer, rtpInterceptor
+	// reads from it then
+	receiveBuffer *receiveBuffer
+
+	// Guarded by the pliMu of the RTPReceiver
+	hasRead            bool
+	hasSequenceNumber  bool
+	lastSequenceNumber uint16
+	lost               int
+	lossWindowStart    time.Time
+
+	// Guarded by the statsMu of the RTPReceiver
+	stats receiveStats
+
+	// Guarded by the activityMu of the RTPReceiver
+	ended      bool
+	endedTimer *time.Timer
+	muteTimer  *time.Timer
+}
+
+const defaultPLIInterval = 500 * time.Millisecond
+
+// RTPReceiverOption configures an RTPReceiver
+type RTPReceiverOption func(r *RTPReceiver)
+
+// WithPLIInterval sets the minimum interval between Picture Loss Indications,
+// so many readers attaching at once don't flood the sender. The default is 500ms.
+func WithPLIInterval(interval time.Duration) RTPReceiverOption {
+	return func(r *RTPReceiver) {
+		r.pliInterval = interval
+	}
+}
+
+// WithPLIOnRead sends a Picture Loss Indication when the first packet read from
+// a Track of the RTPReceiver isn't the start of a keyframe, so the reader doesn't
+// wait for the next keyframe.
+func WithPLIOnRead() RTPReceiverOption {
+	return func(r *RTPReceiver) {
+		r.pliOnRead = true
+	}
+}
+
+// WithPLIOnLoss sends a Picture Loss Indication when at least lost packets of a
+// Track are lost within window, as decoding likely failed.
+func WithPLIOnLoss(lost int, window time.Duration) RTPReceiverOption {
+	return func(r *RTPReceiver) {
+		r.pliLost = lost
+		r.pliLossWindow = window
+	}
 }
 
 // RTPReceiver allows an application to inspect the receipt of a Track
@@ -29,24 +90,69 @@ type RTPReceiver struct {
 	closed, received chan interface{}
 	mu               sync.RWMutex
 
+	pliInterval   time.Duration
+	pliOnRead     bool
+	pliLost       int
+	pliLossWindow time.Duration
+	pliMu         sync.Mutex
+	lastPLI       time.Time
+
+	// firSequenceNumber is the sequence number of the last FIR, guarded by
+	// pliMu
+	firSequenceNumber uint8
+
+	statsMu sync.Mutex
+
+	receiveBufferSize   int
+	receiveBufferPolicy ReceiveBufferPolicy
+
+	endedTimeout time.Duration
+	muteTimeout  time.Duration
+	activityMu   sync.Mutex
+
+	dtmf            dtmfReceiver
+	audioLevel      audioLevelReceiver
+	absTime         absTimeReceiver
+	trackExtensions trackExtensionReceiver
+	mute            muteReceiver
+	extendedReport  extendedReportReceiver
+	rtcpHandler     rtcpHandler
+
+	// headerExtensions are the IDs of the negotiated header extensions by
+	// their URI
+	headerExtensions map[string]uint8
+
 	// A reference to the associated api object
 	api *API
+
+	log logging.LeveledLogger
 }
 
-// NewRTPReceiver constructs a new RTPReceiver
-func (api *API) NewRTPReceiver(kind RTPCodecType, transport *DTLSTransport) (*RTPReceiver, error) {
+// NewRTPReceiver constructs a new RTPReceiver. The options of the SettingEngine
+// are applied before options.
+func (api *API) NewRTPReceiver(kind RTPCodecType, transport *DTLSTransport, options ...RTPReceiverOption) (*RTPReceiver, error) {
 	if transport == nil {
 		return nil, errRTPReceiverDTLSTransportNil
 	}
 
-	return &RTPReceiver{
-		kind:      kind,
-		transport: transport,
-		api:       api,
-		closed:    make(chan interface{}),
-		received:  make(chan interface{}),
-		tracks:    []trackStreams{},
-	}, nil
+	r := &RTPReceiver{
+		kind:        kind,
+		transport:   transport,
+		api:         api,
+		closed:      make(chan interface{}),
+		received:    make(chan interface{}),
+		tracks:      []trackStreams{},
+		pliInterval: defaultPLIInterval,
+		log:         api.settingEngine.LoggerFactory.NewLogger("RTPReceiver"),
+	}
+	for _, option := range api.settingEngine.rtpReceiverOptions {
+		option(r)
+	}
+	for _, option := range options {
+		option(r)
+	}
+
+	return r, nil
 }
 
 // Transport returns the currently-configured *DTLSTransport or nil
@@ -88,6 +194,9 @@ func (r *RTPReceiver) Receive(parameters RTPReceiveParameters) error {
 	select {
 	case <-r.received:
 		return errRTPReceiverReceiveAlreadyCalled
+	case <-r.closed:
+		// A renegotiation racing Stop must not open streams Stop won't close
+		return errRTPReceiverStopped
 	default:
 	}
 	defer close(r.received)
@@ -107,7 +216,12 @@ func (r *RTPReceiver) Receive(parameters RTPReceiveParameters) error {
 			return err
 		}
 
+		t.streamInfo = createStreamInfo("", parameters.Encodings[0].SSRC, 0, nil)
+		r.bindRTPReader(&t)
+		r.startRTCPReader(&t)
+
 		r.tracks = append(r.tracks, t)
+		r.watchArrival(&r.tracks[len(r.tracks)-1])
 	} else {
 		for _, encoding := range parameters.Encodings {
 			r.tracks = append(r.tracks, trackStreams{
@@ -120,6 +234,7 @@ func (r *RTPReceiver) Receive(parameters RTPReceiveParameters) error {
 		}
 	}
 
+	r.startExtendedReports()
 	return nil
 }
 
@@ -127,7 +242,10 @@ func (r *RTPReceiver) Receive(parameters RTPReceiveParameters) error {
 func (r *RTPReceiver) Read(b []byte) (n int, err error) {
 	select {
 	case <-r.received:
-		return r.tracks[0].rtcpReadStream.Read(b)
+		if len(r.tracks) == 0 || r.tracks[0].rtcpReader == nil {
+			return 0, errRTPReceiverForSSRCTrackStreamNotFound
+		}
+		return r.tracks[0].rtcpReader.read(b)
 	case <-r.closed:
 		return 0, io.ErrClosedPipe
 	}
@@ -139,7 +257,7 @@ func (r *RTPReceiver) ReadSimulcast(b []byte, rid string) (n int, err error) {
 	case <-r.received:
 		for _, t := range r.tracks {
 			if t.track != nil && t.track.rid == rid {
-				return t.rtcpReadStream.Read(b)
+				return t.rtcpReader.read(b)
 			}
 		}
 		return 0, fmt.Errorf("%w: %s", errRTPReceiverForRIDTrackStreamNotFound, rid)
@@ -193,15 +311,17 @@ func (r *RTPReceiver) Stop() error {
 	select {
 	case <-r.received:
 		for i := range r.tracks {
+			ended := r.stopEnded(&r.tracks[i])
 			if r.tracks[i].rtcpReadStream != nil {
 				if err := r.tracks[i].rtcpReadStream.Close(); err != nil {
 					return err
 				}
 			}
-			if r.tracks[i].rtpReadStream != nil {
+			if r.tracks[i].rtpReadStream != nil && !ended {
 				if err := r.tracks[i].rtpReadStream.Close(); err != nil {
 					return err
 				}
+				r.api.interceptor.UnbindRemoteStream(&r.tracks[i].streamInfo)
 			}
 		}
 	default:
@@ -220,14 +340,165 @@ func (r *RTPReceiver) streamsForTrack(t *Track) *trackStreams {
 	return nil
 }
 
-// readRTP should only be called by a track, this only exists so we can keep state in one place
-func (r *RTPReceiver) readRTP(b []byte, reader *Track) (n int, err error) {
+// readRTP should only be called by a track, this only exists so we can keep state in one place.
+// If packet isn't nil, the packet read is unmarshaled into it, and parsed is
+// true if that succeeded, so the reader doesn't unmarshal it again.
+func (r *RTPReceiver) readRTP(b []byte, reader *Track, packet *rtp.Packet) (n int, parsed bool, err error) {
 	<-r.received
 	if t := r.streamsForTrack(reader); t != nil {
-		return t.rtpReadStream.Read(b)
+		n, err = t.rtpInterceptor.Read(b)
+		if err != nil {
+			return n, false, err
+		}
+
+		// Without handlers the statistics only need the fixed header
+		if packet == nil && !r.handlesPackets(t) {
+			if n >= rtpFixedHeaderLength {
+				r.updateStats(t, &rtp.Header{
+					SequenceNumber: binary.BigEndian.Uint16(b[2:]),
+					Timestamp:      binary.BigEndian.Uint32(b[4:]),
+				}, n)
+			}
+			return n, false, nil
+		}
+
+		// The packet is unmarshaled once for every handler, its payload
+		// isn't copied
+		parsed = packet != nil
+		if !parsed {
+			packet = &rtp.Packet{}
+		}
+		if packet.Unmarshal(b[:n]) != nil {
+			return n, false, nil
+		}
+		r.updateStats(t, &packet.Header, n)
+		r.handleDTMF(packet)
+		r.handleAudioLevel(packet)
+		r.handleAbsTime(packet)
+		r.handleTrackExtensions(packet, reader)
+		// The packet was read, a failed request doesn't fail the read
+		if r.firstRead(t, packet) || r.lossDetected(t, packet) {
+			if err = r.RequestKeyframe(); err != nil {
+				r.log.Warnf("Failed to request a keyframe: %s", err)
+			}
+		}
+		return n, parsed, nil
 	}
 
-	return 0, fmt.Errorf("%w: %d", errRTPReceiverWithSSRCTrackStreamNotFound, reader.SSRC())
+	return 0, false, fmt.Errorf("%w: %d", errRTPReceiverWithSSRCTrackStreamNotFound, reader.SSRC())
+}
+
+// handlesPackets returns whether a handler or a keyframe request needs the
+// packets read from t, so they must be unmarshaled
+func (r *RTPReceiver) handlesPackets(t *trackStreams) bool {
+	r.pliMu.Lock()
+	firstRead := r.pliOnRead && !t.hasRead
+	r.pliMu.Unlock()
+	if firstRead || r.pliLost > 0 {
+		return true
+	}
+
+	r.dtmf.mu.Lock()
+	dtmf := r.dtmf.handler != nil
+	r.dtmf.mu.Unlock()
+
+	r.audioLevel.mu.Lock()
+	audioLevel := r.audioLevel.id != 0 && r.audioLevel.handler != nil
+	r.audioLevel.mu.Unlock()
+
+	r.absTime.mu.Lock()
+	absTime := (r.absTime.sendTimeID != 0 && r.absTime.sendTimeHandler != nil) ||
+		(r.absTime.captureTimeID != 0 && r.absTime.captureTimeHandler != nil)
+	r.absTime.mu.Unlock()
+
+	r.trackExtensions.mu.Lock()
+	trackExtensions := r.trackExtensions.playoutDelayID != 0 || r.trackExtensions.videoTimingID != 0 ||
+		r.trackExtensions.orientationID != 0 || r.trackExtensions.colorSpaceID != 0
+	r.trackExtensions.mu.Unlock()
+
+	return dtmf || audioLevel || absTime || trackExtensions
+}
+
+// RequestKeyframe sends a Picture Loss Indication for every Track of a video
+// RTPReceiver, which asks the sender for a keyframe. SFUs call it when they
+// start forwarding to a new subscriber. A Full Intra Request is sent instead
+// for codecs that only negotiated ccm fir feedback. Requests within the
+// interval set by WithPLIInterval of the previous one are dropped.
+func (r *RTPReceiver) RequestKeyframe() error {
+	if r.kind != RTPCodecTypeVideo {
+		return nil
+	}
+
+	r.pliMu.Lock()
+	if !r.lastPLI.IsZero() && time.Since(r.lastPLI) < r.pliInterval {
+		r.pliMu.Unlock()
+		return nil
+	}
+	r.lastPLI = time.Now()
+	r.pliMu.Unlock()
+
+	pkts := r.keyframeRequests()
+	if len(pkts) == 0 {
+		return nil
+	}
+
+	return r.transport.writeRTCP(pkts)
+}
+
+// firstRead checks if packet is the first one read from t, and a keyframe
+// should be requested as it doesn't start one
+func (r *RTPReceiver) firstRead(t *trackStreams, packet *rtp.Packet) bool {
+	r.pliMu.Lock()
+	hasRead := t.hasRead
+	t.hasRead = true
+	r.pliMu.Unlock()
+
+	if !r.pliOnRead || hasRead {
+		return false
+	}
+
+	// The codec isn't known yet when the PeerConnection peeks the first packet
+	codec := t.track.Codec()
+	return codec == nil || !IsKeyframe(packet.Payload, codec.MimeType)
+}
+
+// lossDetected counts the packets lost before packet, and checks if enough
+// packets have been lost within the loss window to request a keyframe
+func (r *RTPReceiver) lossDetected(t *trackStreams, packet *rtp.Packet) bool {
+	if r.pliLost <= 0 {
+		return false
+	}
+
+	r.pliMu.Lock()
+	defer r.pliMu.Unlock()
+
+	if !t.hasSequenceNumber {
+		t.hasSequenceNumber = true
+		t.lastSequenceNumber = packet.SequenceNumber
+		return false
+	}
+
+	// Duplicate and reordered packets aren't counted
+	diff := packet.SequenceNumber - t.lastSequenceNumber
+	if diff == 0 || diff >= 1<<15 {
+		return false
+	}
+	t.lastSequenceNumber = packet.SequenceNumber
+	if diff == 1 {
+		return false
+	}
+
+	if now := time.Now(); now.Sub(t.lossWindowStart) > r.pliLossWindow {
+		t.lossWindowStart = now
+		t.lost = 0
+	}
+	t.lost += int(diff - 1)
+	if t.lost < r.pliLost {
+		return false
+	}
+
+	t.lost = 0
+	return true
 }
 
 // receiveForRid is the sibling of Receive expect for RIDs instead of SSRCs
@@ -236,6 +507,12 @@ func (r *RTPReceiver) receiveForRid(rid string, codec *RTPCodec, ssrc uint32) (*
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
+	select {
+	case <-r.closed:
+		return nil, errRTPReceiverStopped
+	default:
+	}
+
 	for i := range r.tracks {
 		if r.tracks[i].track.RID() == rid {
 			r.tracks[i].track.mu.Lock()
@@ -250,6 +527,11 @@ func (r *RTPReceiver) receiveForRid(rid string, codec *RTPCodec, ssrc uint32) (*
 				return nil, err
 			}
 
+			r.tracks[i].streamInfo = createStreamInfo(r.tracks[i].track.ID(), ssrc, codec.PayloadType, codec)
+			r.bindRTPReader(&r.tracks[i])
+			r.startRTCPReader(&r.tracks[i])
+			r.watchArrival(&r.tracks[i])
+
 			return r.tracks[i].track, nil
 		}
 	}
@@ -257,6 +539,37 @@ func (r *RTPReceiver) receiveForRid(rid string, codec *RTPCodec, ssrc uint32) (*
 	return nil, fmt.Errorf("%w: %d", errRTPReceiverForSSRCTrackStreamNotFound, ssrc)
 }
 
+// bindRTPReader binds the RTP stream of t to the Interceptors, behind the
+// buffer set with WithReceiveBuffer if any
+func (r *RTPReceiver) bindRTPReader(t *trackStreams) {
+	t.rtpInterceptor = r.api.interceptor.BindRemoteStream(&t.streamInfo, t.rtpReadStream)
+	if r.receiveBufferSize > 0 {
+		buffer, reader := newReceiveBuffer(r.receiveBufferSize, r.receiveBufferPolicy, &r.api.rtpPacketPool), t.rtpInterceptor
+		r.transport.goStreamReader(func() {
+			buffer.fill(reader)
+		})
+		t.receiveBuffer = buffer
+		t.rtpInterceptor = buffer
+	}
+}
+
+// startRTCPReader reads the RTCP of t as it arrives, and handles it
+func (r *RTPReceiver) startRTCPReader(t *trackStreams) {
+	reader, stream := newRTCPReader(), t.rtcpReadStream
+	t.rtcpReader = reader
+	r.transport.goStreamReader(func() {
+		reader.run(stream, r.handleRTCP)
+	})
+}
+
+// handleRTCP handles the RTCP read for a Track of the RTPReceiver
+func (r *RTPReceiver) handleRTCP(b []byte) {
+	r.handleMute(b)
+	r.handleBye(b)
+	r.handleExtendedReport(b)
+	r.rtcpHandler.handle(b)
+}
+
 func (r *RTPReceiver) streamsForSSRC(ssrc uint32) (*srtp.ReadStreamSRTP, *srtp.ReadStreamSRTCP, error) {
 	srtpSession, err := r.transport.getSRTPSession()
 	if err != nil {
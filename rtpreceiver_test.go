@@ -0,0 +1,82 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRTPReceiver_LossDetected(t *testing.T) {
+	r := &RTPReceiver{}
+	WithPLIOnLoss(3, time.Minute)(r)
+	streams := &trackStreams{}
+
+	packet := func(sequenceNumber uint16) *rtp.Packet {
+		return &rtp.Packet{Header: rtp.Header{Version: 2, SequenceNumber: sequenceNumber}}
+	}
+
+	assert.False(t, r.lossDetected(streams, packet(65534)))
+	assert.False(t, r.lossDetected(streams, packet(65535)))
+
+	// Two packets lost across the wrap around
+	assert.False(t, r.lossDetected(streams, packet(2)))
+
+	// Duplicate and reordered packets aren't lost
+	assert.False(t, r.lossDetected(streams, packet(2)))
+	assert.False(t, r.lossDetected(streams, packet(1)))
+
+	// The third lost packet requests a keyframe
+	assert.True(t, r.lossDetected(streams, packet(4)))
+	assert.False(t, r.lossDetected(streams, packet(6)))
+
+	// Loss isn't detected without WithPLIOnLoss
+	assert.False(t, (&RTPReceiver{}).lossDetected(&trackStreams{}, packet(10)))
+}
+
+func TestRTPReceiver_FirstRead(t *testing.T) {
+	r := &RTPReceiver{}
+	WithPLIOnRead()(r)
+
+	packet := func(payload ...byte) *rtp.Packet {
+		return &rtp.Packet{Header: rtp.Header{Version: 2}, Payload: payload}
+	}
+
+	// Only the first packet of a Track that isn't a keyframe requests one
+	streams := &trackStreams{track: &Track{codec: NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000)}}
+	assert.True(t, r.firstRead(streams, packet(0x10, 0x01)))
+	assert.False(t, r.firstRead(streams, packet(0x10, 0x01)))
+
+	streams = &trackStreams{track: &Track{codec: NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000)}}
+	assert.False(t, r.firstRead(streams, packet(0x10, 0x00)))
+
+	// The codec isn't known when the PeerConnection peeks
+	assert.True(t, r.firstRead(&trackStreams{track: &Track{}}, packet(0x10, 0x00)))
+
+	assert.False(t, (&RTPReceiver{}).firstRead(&trackStreams{track: &Track{}}, packet(0x10, 0x01)))
+}
+
+func TestRTPReceiver_HandlesPackets(t *testing.T) {
+	// Packets are only unmarshaled for the handlers and keyframe requests
+	r := &RTPReceiver{}
+	streams := &trackStreams{track: &Track{}}
+	assert.False(t, r.handlesPackets(streams))
+
+	r.OnAudioLevel(func(uint8, bool) {})
+	assert.False(t, r.handlesPackets(streams))
+	r.audioLevel.setExtensionID(1)
+	assert.True(t, r.handlesPackets(streams))
+
+	r = &RTPReceiver{}
+	WithPLIOnRead()(r)
+	assert.True(t, r.handlesPackets(streams))
+	r.firstRead(streams, &rtp.Packet{})
+	assert.False(t, r.handlesPackets(streams))
+
+	r = &RTPReceiver{}
+	WithPLIOnLoss(3, time.Minute)(r)
+	assert.True(t, r.handlesPackets(streams))
+}
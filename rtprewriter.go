@@ -0,0 +1,73 @@
+// +build !js
+
+package webrtc
+
+import (
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3/internal/util"
+)
+
+// rtpRewriter rewrites the sequence numbers and timestamps of packets of
+// many sources written to one Track, so they continue when the source
+// changes
+type rtpRewriter struct {
+	hasWritten         bool
+	lastSequenceNumber uint16
+	lastTimestamp      uint32
+	lastMarker         bool
+	lastWrite          time.Time
+}
+
+// rewriteSource is the state of a source of an rtpRewriter
+type rewriteSource struct {
+	sequence             util.SequenceUnwrapper
+	firstSequenceNumber  int64
+	sequenceNumberOffset uint16
+	timestampOffset      uint32
+}
+
+// activate makes src continue the sequence numbers and timestamps of the
+// previous source from header, its first packet
+func (w *rtpRewriter) activate(src *rewriteSource, header *rtp.Header, clockRate uint32) {
+	src.sequence = util.SequenceUnwrapper{}
+	src.firstSequenceNumber = src.sequence.Unwrap(header.SequenceNumber)
+	src.sequenceNumberOffset = 0
+	src.timestampOffset = 0
+	if !w.hasWritten {
+		return
+	}
+
+	// The timestamp advances by the time since the last packet of the previous source
+	elapsed := uint32(time.Since(w.lastWrite).Seconds() * float64(clockRate))
+	if elapsed == 0 {
+		elapsed = 1
+	}
+
+	src.sequenceNumberOffset = w.lastSequenceNumber + 1 - header.SequenceNumber
+	src.timestampOffset = w.lastTimestamp + elapsed - header.Timestamp
+}
+
+// rewrite rewrites header, a packet of the active source src, and returns
+// false if the packet was reordered before the first packet of src, so it
+// can't be written
+func (w *rtpRewriter) rewrite(src *rewriteSource, header *rtp.Header) bool {
+	sequenceNumber := src.sequence.Unwrap(header.SequenceNumber)
+	if sequenceNumber < src.firstSequenceNumber {
+		return false
+	}
+
+	header.SequenceNumber += src.sequenceNumberOffset
+	header.Timestamp += src.timestampOffset
+
+	// The next source continues from the highest packet, not from one reordered
+	if sequenceNumber == src.sequence.Highest() {
+		w.hasWritten = true
+		w.lastSequenceNumber = header.SequenceNumber
+		w.lastTimestamp = header.Timestamp
+		w.lastMarker = header.Marker
+		w.lastWrite = time.Now()
+	}
+	return true
+}
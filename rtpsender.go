@@ -5,10 +5,13 @@ package webrtc
 import (
 	"io"
 	"sync"
+	"time"
 
 	"github.com/pion/rtcp"
 	"github.com/pion/rtp"
 	"github.com/pion/srtp"
+	"github.com/pion/webrtc/v3/pkg/interceptor"
+	"github.com/pion/webrtc/v3/pkg/rtcerr"
 )
 
 // RTPSender allows an application to control how a given Track is encoded and transmitted to a remote peer
@@ -16,6 +19,12 @@ type RTPSender struct {
 	track          *Track
 	rtcpReadStream *srtp.ReadStreamSRTCP
 
+	// rtcpReader reads rtcpReadStream, Read returns the packets it queued
+	rtcpReader *rtcpReader
+
+	streamInfo interceptor.StreamInfo
+	rtpWriter  interceptor.RTPWriter
+
 	transport *DTLSTransport
 
 	// nolint:godox
@@ -27,6 +36,38 @@ type RTPSender struct {
 	// A reference to the associated api object
 	api *API
 
+	// paused drops RTP while the RTPTransceiver doesn't send
+	paused atomicBool
+
+	// inactive drops RTP while the encoding isn't active
+	inactive   atomicBool
+	parameters RTPSendParameters
+
+	// streamIDs are the IDs of the streams the Track is signaled in, the
+	// label of the Track if empty
+	streamIDs []string
+
+	// remoteBandwidth is the bandwidth of the media section of the remote
+	// description, 0 if unlimited
+	remoteBandwidth uint64
+
+	dtmf            dtmfSequencer
+	dtmfSender      *DTMFSender
+	audioLevel      audioLevelSender
+	absTime         absTimeSender
+	trackExtensions trackExtensionSender
+	mute            muteSender
+	keyframeRequest keyframeRequestHandler
+	contentHint     contentHintSender
+	red             redSender
+	targetBitrate   targetBitrateNotifier
+	extendedReport  extendedReportSender
+	rtcpHandler     rtcpHandler
+
+	// collisionSSRC is the SSRC sent with after a collision, 0 if there was
+	// none. It is accessed atomically.
+	collisionSSRC uint32
+
 	mu                     sync.RWMutex
 	sendCalled, stopCalled chan interface{}
 }
@@ -50,6 +91,7 @@ func (api *API) NewRTPSender(track *Track, transport *DTLSTransport) (*RTPSender
 		track:      track,
 		transport:  transport,
 		api:        api,
+		parameters: RTPSendParameters{Encodings: RTPEncodingParameters{Active: true}},
 		sendCalled: make(chan interface{}),
 		stopCalled: make(chan interface{}),
 	}, nil
@@ -89,6 +131,7 @@ func (r *RTPSender) setTrack(track *Track) {
 }
 
 // Send Attempts to set the parameters controlling the sending of media.
+// Only the RTPCodingParameters are used, see SetParameters for the others.
 func (r *RTPSender) Send(parameters RTPSendParameters) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -97,6 +140,13 @@ func (r *RTPSender) Send(parameters RTPSendParameters) error {
 		return errRTPSenderSendAlreadyCalled
 	}
 
+	// A renegotiation racing Stop must not open a stream Stop won't close
+	select {
+	case <-r.stopCalled:
+		return errRTPSenderStopped
+	default:
+	}
+
 	srtcpSession, err := r.transport.getSRTCPSession()
 	if err != nil {
 		return err
@@ -107,6 +157,11 @@ func (r *RTPSender) Send(parameters RTPSendParameters) error {
 		return err
 	}
 
+	r.parameters.Encodings.RTPCodingParameters = parameters.Encodings.RTPCodingParameters
+	r.streamInfo = createStreamInfo(r.track.ID(), parameters.Encodings.SSRC, parameters.Encodings.PayloadType, r.track.Codec())
+	r.rtpWriter = r.api.interceptor.BindLocalStream(&r.streamInfo, interceptor.RTPWriterFunc(r.writeRTP))
+	r.startRTCPReader()
+
 	r.track.mu.Lock()
 	r.track.activeSenders = append(r.track.activeSenders, r)
 	r.track.mu.Unlock()
@@ -115,6 +170,106 @@ func (r *RTPSender) Send(parameters RTPSendParameters) error {
 	return nil
 }
 
+// GetParameters returns the parameters of the encoding sent by the RTPSender
+func (r *RTPSender) GetParameters() RTPSendParameters {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.parameters
+}
+
+// SetParameters changes the parameters of the encoding sent by the
+// RTPSender. The RTPCodingParameters returned by GetParameters can't be
+// modified. An encoding that isn't Active is not sent. As Pion WebRTC
+// doesn't encode media itself, MaxBitrate, MaxFramerate and
+// ScaleResolutionDownBy are limits for the encoder writing to the Track,
+// which reads them with GetParameters.
+func (r *RTPSender) SetParameters(parameters RTPSendParameters) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	encodings := parameters.Encodings
+	if encodings.RTPCodingParameters != r.parameters.Encodings.RTPCodingParameters {
+		return &rtcerr.InvalidModificationError{Err: errRTPSenderModifyingCoding}
+	} else if err := validateEncoding(encodings); err != nil {
+		return err
+	}
+
+	r.parameters = parameters
+	r.inactive.set(!encodings.Active)
+	return nil
+}
+
+// setInit sets the stream IDs and the encoding sent from the
+// RTPTransceiverInit of the RTPTransceiver of the RTPSender. The
+// RTPCodingParameters of the encoding are set when it is negotiated.
+func (r *RTPSender) setInit(init RTPTransceiverInit) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.streamIDs = append([]string{}, init.StreamIDs...)
+
+	switch len(init.SendEncodings) {
+	case 0:
+		return nil
+	case 1:
+	default:
+		return &rtcerr.NotSupportedError{Err: errRTPSenderSendEncodingsSimulcast}
+	}
+
+	encodings := init.SendEncodings[0]
+	if err := validateEncoding(encodings); err != nil {
+		return err
+	}
+	encodings.RTPCodingParameters = r.parameters.Encodings.RTPCodingParameters
+	r.parameters.Encodings = encodings
+	r.inactive.set(!encodings.Active)
+	return nil
+}
+
+// validateEncoding returns an error if the limits of encodings are out of range
+func validateEncoding(encodings RTPEncodingParameters) error {
+	if encodings.MaxFramerate < 0 || encodings.ScaleResolutionDownBy != 0 && encodings.ScaleResolutionDownBy < 1 ||
+		encodings.MaxBitrate != 0 && encodings.MinBitrate > encodings.MaxBitrate {
+		return &rtcerr.RangeError{Err: errRTPSenderEncodingInvalid}
+	}
+	return nil
+}
+
+// getStreamIDs returns the IDs of the streams the Track of the RTPSender is
+// signaled in, at least one
+func (r *RTPSender) getStreamIDs() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if len(r.streamIDs) != 0 {
+		return r.streamIDs
+	} else if r.track == nil {
+		return []string{""}
+	}
+	return []string{r.track.Label()}
+}
+
+// MaxBitrate returns the bitrate in bits per second the encoder writing to
+// the Track should not exceed, 0 if unlimited. It is the lowest of the
+// MaxBitrate of the parameters and of the bandwidth the remote peer signaled
+// with b=AS or b=TIAS lines.
+func (r *RTPSender) MaxBitrate() uint64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	maxBitrate := r.parameters.Encodings.MaxBitrate
+	if r.remoteBandwidth != 0 && (maxBitrate == 0 || r.remoteBandwidth < maxBitrate) {
+		maxBitrate = r.remoteBandwidth
+	}
+	return maxBitrate
+}
+
+func (r *RTPSender) setRemoteBandwidth(bandwidth uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.remoteBandwidth = bandwidth
+}
+
 // Stop irreversibly stops the RTPSender
 func (r *RTPSender) Stop() error {
 	r.mu.Lock()
@@ -140,6 +295,7 @@ func (r *RTPSender) Stop() error {
 	close(r.stopCalled)
 
 	if r.hasSent() {
+		r.api.interceptor.UnbindLocalStream(&r.streamInfo)
 		return r.rtcpReadStream.Close()
 	}
 
@@ -150,12 +306,43 @@ func (r *RTPSender) Stop() error {
 func (r *RTPSender) Read(b []byte) (n int, err error) {
 	select {
 	case <-r.sendCalled:
-		return r.rtcpReadStream.Read(b)
+		for {
+			r.mu.RLock()
+			rtcpReader := r.rtcpReader
+			r.mu.RUnlock()
+			n, err = rtcpReader.read(b)
+
+			// The reader is replaced when the SSRC changes after a collision,
+			// the next packets are read from the new one
+			r.mu.RLock()
+			replaced := r.rtcpReader != rtcpReader
+			r.mu.RUnlock()
+			if err == nil || !replaced {
+				return n, err
+			}
+		}
 	case <-r.stopCalled:
 		return 0, io.ErrClosedPipe
 	}
 }
 
+// startRTCPReader reads the RTCP of the RTPSender as it arrives, and
+// handles it
+func (r *RTPSender) startRTCPReader() {
+	reader, stream := newRTCPReader(), r.rtcpReadStream
+	r.rtcpReader = reader
+	r.transport.goStreamReader(func() {
+		reader.run(stream, r.handleRTCP)
+	})
+}
+
+// handleRTCP handles the RTCP read for the RTPSender
+func (r *RTPSender) handleRTCP(b []byte) {
+	r.handleKeyframeRequest(b)
+	r.handleExtendedReport(b)
+	r.rtcpHandler.handle(b)
+}
+
 // ReadRTCP is a convenience method that wraps Read and unmarshals for you
 func (r *RTPSender) ReadRTCP() ([]rtcp.Packet, error) {
 	b := make([]byte, receiveMTU)
@@ -178,18 +365,54 @@ func (r *RTPSender) SendRTP(header *rtp.Header, payload []byte) (int, error) {
 	case <-r.stopCalled:
 		return 0, errRTPSenderStopped
 	case <-r.sendCalled:
-		srtpSession, err := r.transport.getSRTPSession()
-		if err != nil {
-			return 0, err
+		if r.paused.get() || r.inactive.get() || r.mute.muted.get() {
+			return 0, nil
 		}
+		return r.write(r.contentHint.stamp(r.audioLevel.stamp(r.dtmf.rewrite(header))), payload)
+	}
+}
 
-		writeStream, err := srtpSession.OpenWriteStream()
-		if err != nil {
-			return 0, err
-		}
+// write writes a packet to the interceptor chain, with the SSRC, the
+// payload type and the extensions of the RTPSender
+func (r *RTPSender) write(header *rtp.Header, payload []byte) (int, error) {
+	r.mu.RLock()
+	rtpWriter, track, payloadType := r.rtpWriter, r.track, r.parameters.Encodings.PayloadType
+	r.mu.RUnlock()
+
+	header, payload = r.red.wrap(rewritePayloadType(r.rewriteSSRC(header), track, payloadType), payload)
+	header = r.trackExtensions.stamp(header, track)
+	return rtpWriter.Write(r.absTime.stamp(header, time.Now()), payload)
+}
 
-		return writeStream.WriteRTP(header, payload)
+// rewritePayloadType returns header with payloadType, the payload type
+// negotiated for the codec of track, if it has the one track was created
+// with. A Track can be sent by PeerConnections that negotiated other payload
+// types for its codec. A payloadType of 0 is unset, as only PCMU, whose
+// static payload type can't be negotiated, has it. The header of the caller
+// isn't modified.
+func rewritePayloadType(header *rtp.Header, track *Track, payloadType uint8) *rtp.Header {
+	if track == nil || payloadType == 0 || header.PayloadType == payloadType || header.PayloadType != track.PayloadType() {
+		return header
 	}
+
+	rewritten := *header
+	rewritten.PayloadType = payloadType
+	return &rewritten
+}
+
+// writeRTP is the last step of the interceptor chain, it hands the packet to SRTP
+func (r *RTPSender) writeRTP(header *rtp.Header, payload []byte) (int, error) {
+	srtpSession, err := r.transport.getSRTPSession()
+	if err != nil {
+		return 0, err
+	}
+
+	writeStream, err := srtpSession.OpenWriteStream()
+	if err != nil {
+		return 0, err
+	}
+
+	return writeStream.WriteRTP(header, payload)
 }
 
 // hasSent tells if data has been ever sent for this instance
@@ -0,0 +1,76 @@
+// +build !js
+
+package webrtc
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/pion/randutil"
+	"github.com/pion/webrtc/v3/pkg/rtcerr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRTPSender_SetParameters(t *testing.T) {
+	pc, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	track, err := pc.NewTrack(DefaultPayloadTypeVP8, randutil.NewMathRandomGenerator().Uint32(), "video", "pion")
+	assert.NoError(t, err)
+	sender, err := pc.AddTrack(track)
+	assert.NoError(t, err)
+
+	parameters := sender.GetParameters()
+	assert.True(t, parameters.Encodings.Active)
+
+	// Limits are kept for the encoder, an inactive encoding isn't sent
+	parameters.Encodings.Active = false
+	parameters.Encodings.MaxBitrate = 500000
+	parameters.Encodings.MaxFramerate = 15
+	parameters.Encodings.ScaleResolutionDownBy = 2
+	assert.NoError(t, sender.SetParameters(parameters))
+	assert.Equal(t, parameters, sender.GetParameters())
+	assert.True(t, sender.inactive.get())
+
+	parameters.Encodings.Active = true
+	assert.NoError(t, sender.SetParameters(parameters))
+	assert.False(t, sender.inactive.get())
+
+	var modificationErr *rtcerr.InvalidModificationError
+	modified := parameters
+	modified.Encodings.SSRC++
+	assert.True(t, errors.As(sender.SetParameters(modified), &modificationErr))
+
+	var rangeErr *rtcerr.RangeError
+	invalid := parameters
+	invalid.Encodings.ScaleResolutionDownBy = 0.5
+	assert.True(t, errors.As(sender.SetParameters(invalid), &rangeErr))
+	invalid = parameters
+	invalid.Encodings.MaxFramerate = -1
+	assert.True(t, errors.As(sender.SetParameters(invalid), &rangeErr))
+	invalid = parameters
+	invalid.Encodings.MinBitrate = 600000
+	assert.True(t, errors.As(sender.SetParameters(invalid), &rangeErr))
+	assert.Equal(t, parameters, sender.GetParameters())
+
+	assert.NoError(t, pc.Close())
+}
+
+func TestRTPSender_SendAfterStop(t *testing.T) {
+	pc, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	track, err := pc.NewTrack(DefaultPayloadTypeVP8, randutil.NewMathRandomGenerator().Uint32(), "video", "pion")
+	assert.NoError(t, err)
+	sender, err := pc.AddTrack(track)
+	assert.NoError(t, err)
+
+	// A renegotiation finishing after Stop doesn't start the RTPSender
+	assert.NoError(t, sender.Stop())
+	assert.Equal(t, errRTPSenderStopped, sender.Send(RTPSendParameters{
+		Encodings: RTPEncodingParameters{RTPCodingParameters: RTPCodingParameters{SSRC: sender.SSRC()}},
+	}))
+	assert.False(t, sender.hasSent())
+
+	assert.NoError(t, pc.Close())
+}
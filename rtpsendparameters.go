@@ -3,4 +3,9 @@ package webrtc
 // RTPSendParameters contains the RTP stack settings used by receivers
 type RTPSendParameters struct {
 	Encodings RTPEncodingParameters
+
+	// DegradationPreference is what the encoder gives up first when the
+	// bitrate is constrained, unset to derive it from the ContentHint of the
+	// RTPSender, see RTPSender.DegradationPreference
+	DegradationPreference DegradationPreference
 }
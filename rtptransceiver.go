@@ -17,8 +17,18 @@ type RTPTransceiver struct {
 	receiver  atomic.Value // *RTPReceiver
 	direction atomic.Value // RTPTransceiverDirection
 
+	// currentDirection is the direction negotiated by the last answer
+	currentDirection atomic.Value // RTPTransceiverDirection
+
+	// bandwidth is the bandwidth of the RTPTransceiverInit, and
+	// remoteBandwidth the one of the media section of the remote description
+	bandwidth       atomic.Value // uint64
+	remoteBandwidth atomic.Value // uint64
+
 	stopped bool
 	kind    RTPCodecType
+
+	onNegotiationNeeded func()
 }
 
 // Sender returns the RTPTransceiver's RTPSender if it has one
@@ -37,6 +47,9 @@ func (t *RTPTransceiver) SetSender(s *RTPSender, track *Track) error {
 }
 
 func (t *RTPTransceiver) setSender(s *RTPSender) {
+	if s != nil {
+		s.setRemoteBandwidth(t.getRemoteBandwidth())
+	}
 	t.sender.Store(s)
 }
 
@@ -76,6 +89,60 @@ func (t *RTPTransceiver) Direction() RTPTransceiverDirection {
 	return t.direction.Load().(RTPTransceiverDirection)
 }
 
+// SetDirection changes the direction of the RTPTransceiver, which takes
+// effect with the next offer or answer. Sending RTP pauses right away if
+// the direction doesn't send. This is used to put a call on hold, with
+// RTPTransceiverDirectionSendonly or RTPTransceiverDirectionInactive, and
+// to resume it later.
+func (t *RTPTransceiver) SetDirection(d RTPTransceiverDirection) error {
+	switch d {
+	case RTPTransceiverDirectionSendrecv, RTPTransceiverDirectionSendonly:
+		if t.Sender() == nil {
+			return errRTPTransceiverSetDirectionNoSender
+		}
+	case RTPTransceiverDirectionRecvonly, RTPTransceiverDirectionInactive:
+	default:
+		return errRTPTransceiverSetDirectionUnknown
+	}
+
+	if d == t.Direction() {
+		return nil
+	}
+	t.setDirection(d)
+	t.updateSending()
+
+	if t.onNegotiationNeeded != nil {
+		t.onNegotiationNeeded()
+	}
+	return nil
+}
+
+// CurrentDirection returns the direction negotiated by the last answer,
+// or zero before it has been negotiated
+func (t *RTPTransceiver) CurrentDirection() RTPTransceiverDirection {
+	if v := t.currentDirection.Load(); v != nil {
+		return v.(RTPTransceiverDirection)
+	}
+	return RTPTransceiverDirection(Unknown)
+}
+
+func (t *RTPTransceiver) setCurrentDirection(d RTPTransceiverDirection) {
+	t.currentDirection.Store(d)
+	t.updateSending()
+}
+
+// updateSending pauses the RTPSender while either the direction or the
+// negotiated direction doesn't send
+func (t *RTPTransceiver) updateSending() {
+	sender := t.Sender()
+	if sender == nil {
+		return
+	}
+
+	current := t.CurrentDirection()
+	sender.paused.set(!t.Direction().hasSend() || (current != RTPTransceiverDirection(Unknown) && !current.hasSend()))
+}
+
 // Stop irreversibly stops the RTPTransceiver
 func (t *RTPTransceiver) Stop() error {
 	if t.Sender() != nil {
@@ -97,6 +164,33 @@ func (t *RTPTransceiver) setReceiver(r *RTPReceiver) {
 	t.receiver.Store(r)
 }
 
+func (t *RTPTransceiver) getBandwidth() uint64 {
+	if v := t.bandwidth.Load(); v != nil {
+		return v.(uint64)
+	}
+	return 0
+}
+
+func (t *RTPTransceiver) setBandwidth(bandwidth uint64) {
+	t.bandwidth.Store(bandwidth)
+}
+
+func (t *RTPTransceiver) getRemoteBandwidth() uint64 {
+	if v := t.remoteBandwidth.Load(); v != nil {
+		return v.(uint64)
+	}
+	return 0
+}
+
+// setRemoteBandwidth sets the bandwidth of the remote description, which
+// limits the bitrate of the RTPSender
+func (t *RTPTransceiver) setRemoteBandwidth(bandwidth uint64) {
+	t.remoteBandwidth.Store(bandwidth)
+	if sender := t.Sender(); sender != nil {
+		sender.setRemoteBandwidth(bandwidth)
+	}
+}
+
 func (t *RTPTransceiver) setDirection(d RTPTransceiverDirection) {
 	t.direction.Store(d)
 }
@@ -116,9 +210,12 @@ func (t *RTPTransceiver) setSendingTrack(track *Track) error {
 		t.setDirection(RTPTransceiverDirectionRecvonly)
 	case track == nil && t.Direction() == RTPTransceiverDirectionSendonly:
 		t.setDirection(RTPTransceiverDirectionInactive)
+	case track == nil && !t.Direction().hasSend():
+		// The direction was already changed with SetDirection
 	default:
 		return errRTPTransceiverSetSendingInvalidState
 	}
+	t.updateSending()
 	return nil
 }
 
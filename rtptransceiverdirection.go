@@ -72,3 +72,28 @@ func (t RTPTransceiverDirection) Revers() RTPTransceiverDirection {
 		return t
 	}
 }
+
+func (t RTPTransceiverDirection) hasSend() bool {
+	return t == RTPTransceiverDirectionSendrecv || t == RTPTransceiverDirectionSendonly
+}
+
+func (t RTPTransceiverDirection) hasRecv() bool {
+	return t == RTPTransceiverDirectionSendrecv || t == RTPTransceiverDirectionRecvonly
+}
+
+// answerDirection is the direction of an answer to an offer of remote,
+// which only sends and receives if local does as well
+func answerDirection(local, remote RTPTransceiverDirection) RTPTransceiverDirection {
+	send := local.hasSend() && remote.hasRecv()
+	recv := local.hasRecv() && remote.hasSend()
+	switch {
+	case send && recv:
+		return RTPTransceiverDirectionSendrecv
+	case send:
+		return RTPTransceiverDirectionSendonly
+	case recv:
+		return RTPTransceiverDirectionRecvonly
+	default:
+		return RTPTransceiverDirectionInactive
+	}
+}
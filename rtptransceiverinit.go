@@ -2,9 +2,21 @@ package webrtc
 
 // RTPTransceiverInit dictionary is used when calling the WebRTC function addTransceiver() to provide configuration options for the new transceiver.
 type RTPTransceiverInit struct {
-	Direction     RTPTransceiverDirection
+	// Direction is the direction of the RTPTransceiver, sendrecv if unset
+	Direction RTPTransceiverDirection
+
+	// SendEncodings are the parameters of the encoding sent, as the
+	// RTPSender sends a single encoding it has at most one. Like with
+	// RTPSender.SetParameters, the encoding isn't sent unless it is Active.
 	SendEncodings []RTPEncodingParameters
-	// Streams       []*Track
+
+	// StreamIDs are the IDs of the streams the Track sent is signaled in with
+	// a=msid lines, the label of the Track if empty
+	StreamIDs []string
+
+	// Bandwidth is the bitrate in bits per second the remote peer should
+	// send at most, signaled with a b=AS line. 0 if unlimited.
+	Bandwidth uint64
 }
 
 type RtpTransceiverInit = RTPTransceiverInit //nolint: stylecheck,golint
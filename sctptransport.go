@@ -42,15 +42,24 @@ type SCTPTransport struct {
 	onErrorHandler func(error)
 
 	association                *sctp.Association
+	associationErr             error
 	onDataChannelHandler       func(*DataChannel)
 	onDataChannelOpenedHandler func(*DataChannel)
 
+	// associationClosed is closed once association is, so the goroutines
+	// of the association exit
+	associationClosed chan struct{}
+
 	// DataChannels
 	dataChannels          []*DataChannel
 	dataChannelsOpened    uint32
 	dataChannelsRequested uint32
 	dataChannelsAccepted  uint32
 
+	// goInternal runs the goroutines of the association, the PeerConnection
+	// sets it so Close waits for them
+	goInternal func(func()) bool
+
 	api *API
 	log logging.LeveledLogger
 }
@@ -105,6 +114,7 @@ func (r *SCTPTransport) Start(remoteCaps SCTPCapabilities) error {
 		LoggerFactory: r.api.settingEngine.LoggerFactory,
 	})
 	if err != nil {
+		r.setAssociationError(err)
 		return err
 	}
 
@@ -112,9 +122,14 @@ func (r *SCTPTransport) Start(remoteCaps SCTPCapabilities) error {
 	defer r.lock.Unlock()
 
 	r.association = sctpAssociation
+	r.associationClosed = make(chan struct{})
+	r.associationErr = nil
 	r.state = SCTPTransportStateConnected
 
-	go r.acceptDataChannels(sctpAssociation)
+	closed := r.associationClosed
+	r.goAssociation(func() {
+		r.acceptDataChannels(sctpAssociation, closed)
+	})
 
 	return nil
 }
@@ -126,6 +141,10 @@ func (r *SCTPTransport) Stop() error {
 	if r.association == nil {
 		return nil
 	}
+
+	close(r.associationClosed)
+	r.associationClosed = nil
+
 	err := r.association.Close()
 	if err != nil {
 		return err
@@ -146,7 +165,28 @@ func (r *SCTPTransport) ensureDTLS() error {
 	return nil
 }
 
-func (r *SCTPTransport) acceptDataChannels(a *sctp.Association) {
+// goAssociation runs f, which returns once the association is closed, on a
+// goroutine
+func (r *SCTPTransport) goAssociation(f func()) {
+	if r.goInternal == nil || !r.goInternal(f) {
+		// Without a PeerConnection, or once it is closed, which closes the
+		// association so f returns
+		go f()
+	}
+}
+
+// getAssociationClosed returns the channel closed once the association is,
+// nil without an association
+func (r *SCTPTransport) getAssociationClosed() <-chan struct{} {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.associationClosed
+}
+
+// acceptDataChannels accepts the DataChannels opened by the remote until the
+// association is closed. It doesn't wait for the OnDataChannel handler once
+// closed is, as the handler may be closing the PeerConnection.
+func (r *SCTPTransport) acceptDataChannels(a *sctp.Association, closed <-chan struct{}) {
 	for {
 		dc, err := datachannel.Accept(a, &datachannel.Config{
 			LoggerFactory: r.api.settingEngine.LoggerFactory,
@@ -154,6 +194,7 @@ func (r *SCTPTransport) acceptDataChannels(a *sctp.Association) {
 		if err != nil {
 			if err != io.EOF {
 				r.log.Errorf("Failed to accept data channel: %v", err)
+				r.setAssociationError(err)
 				r.onError(err)
 			}
 			return
@@ -202,8 +243,12 @@ func (r *SCTPTransport) acceptDataChannels(a *sctp.Association) {
 			return
 		}
 
-		<-r.onDataChannel(rtcDC)
-		rtcDC.handleOpen(dc)
+		select {
+		case <-r.onDataChannel(rtcDC):
+		case <-closed:
+			return
+		}
+		rtcDC.handleOpen(dc, r)
 
 		r.lock.Lock()
 		r.dataChannelsOpened++
@@ -216,6 +261,20 @@ func (r *SCTPTransport) acceptDataChannels(a *sctp.Association) {
 	}
 }
 
+// setAssociationError records why the association failed
+func (r *SCTPTransport) setAssociationError(err error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.associationErr = err
+}
+
+// associationError returns why the association failed, nil if it didn't
+func (r *SCTPTransport) associationError() error {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.associationErr
+}
+
 // OnError sets an event handler which is invoked when
 // the SCTP connection error occurs.
 func (r *SCTPTransport) OnError(f func(err error)) {
@@ -16,12 +16,13 @@ import (
 // trackDetails represents any media source that can be represented in a SDP
 // This isn't keyed by SSRC because it also needs to support rid based sources
 type trackDetails struct {
-	mid   string
-	kind  RTPCodecType
-	label string
-	id    string
-	ssrc  uint32
-	rids  []string
+	mid       string
+	kind      RTPCodecType
+	label     string
+	id        string
+	streamIDs []string
+	ssrc      uint32
+	rids      []string
 }
 
 func trackDetailsForSSRC(trackDetails []trackDetails, ssrc uint32) *trackDetails {
@@ -62,6 +63,7 @@ func trackDetailsFromSDP(log logging.LeveledLogger, s *sdp.SessionDescription) [
 		// Plan B can have multiple tracks in a signle media section
 		trackLabel := ""
 		trackID := ""
+		streamIDs := []string{}
 
 		// If media section is recvonly or inactive skip
 		if _, ok := media.Attribute(sdp.AttrKeyRecvOnly); ok {
@@ -108,11 +110,17 @@ func trackDetailsFromSDP(log logging.LeveledLogger, s *sdp.SessionDescription) [
 			// Handle `a=msid:<stream_id> <track_label>` for Unified plan. The first value is the same as MediaStream.id
 			// in the browser and can be used to figure out which tracks belong to the same stream. The browser should
 			// figure this out automatically when an ontrack event is emitted on RTCPeerConnection.
+			// A track in many streams has an `a=msid` for each of them, and a stream_id of `-` for none (RFC 8830).
 			case sdp.AttrKeyMsid:
 				split := strings.Split(attr.Value, " ")
 				if len(split) == 2 {
-					trackLabel = split[0]
+					if trackLabel == "" {
+						trackLabel = split[0]
+					}
 					trackID = split[1]
+					if split[0] != "-" {
+						streamIDs = append(streamIDs, split[0])
+					}
 				}
 
 			case sdp.AttrKeySSRC:
@@ -130,6 +138,7 @@ func trackDetailsFromSDP(log logging.LeveledLogger, s *sdp.SessionDescription) [
 				if len(split) == 3 && strings.HasPrefix(split[1], "msid:") {
 					trackLabel = split[1][len("msid:"):]
 					trackID = split[2]
+					streamIDs = []string{trackLabel}
 				}
 
 				isNewTrack := true
@@ -145,6 +154,7 @@ func trackDetailsFromSDP(log logging.LeveledLogger, s *sdp.SessionDescription) [
 				trackDetails.kind = codecType
 				trackDetails.label = trackLabel
 				trackDetails.id = trackID
+				trackDetails.streamIDs = streamIDs
 				trackDetails.ssrc = uint32(ssrc)
 
 				if isNewTrack {
@@ -155,11 +165,12 @@ func trackDetailsFromSDP(log logging.LeveledLogger, s *sdp.SessionDescription) [
 
 		if rids := getRids(media); len(rids) != 0 && trackID != "" && trackLabel != "" {
 			newTrack := trackDetails{
-				mid:   midValue,
-				kind:  codecType,
-				label: trackLabel,
-				id:    trackID,
-				rids:  []string{},
+				mid:       midValue,
+				kind:      codecType,
+				label:     trackLabel,
+				id:        trackID,
+				streamIDs: streamIDs,
+				rids:      []string{},
 			}
 			for rid := range rids {
 				newTrack.rids = append(newTrack.rids, rid)
@@ -266,7 +277,11 @@ func populateLocalCandidates(sessionDescription *SessionDescription, i *ICEGathe
 		return sessionDescription
 	}
 
-	parsed := sessionDescription.parsed
+	// The candidates are added to a copy, the description set isn't modified
+	parsed := &sdp.SessionDescription{}
+	if err = parsed.Unmarshal([]byte(sessionDescription.SDP)); err != nil {
+		return sessionDescription
+	}
 	if len(parsed.MediaDescriptions) > 0 {
 		m := parsed.MediaDescriptions[0]
 		if err = addCandidatesToMediaDescriptions(candidates, m, iceGatheringState); err != nil {
@@ -285,6 +300,65 @@ func populateLocalCandidates(sessionDescription *SessionDescription, i *ICEGathe
 	}
 }
 
+// populateRemoteCandidates returns sessionDescription with the candidates
+// given to AddICECandidate added to the media sections they are for
+func populateRemoteCandidates(sessionDescription *SessionDescription, candidates []ICECandidateInit) *SessionDescription {
+	if sessionDescription == nil || len(candidates) == 0 {
+		return sessionDescription
+	}
+
+	// The candidates are added to a copy, the description set isn't modified
+	parsed := &sdp.SessionDescription{}
+	if err := parsed.Unmarshal([]byte(sessionDescription.SDP)); err != nil || len(parsed.MediaDescriptions) == 0 {
+		return sessionDescription
+	}
+
+	for _, c := range candidates {
+		m := candidateMediaDescription(parsed, c)
+		value := strings.TrimPrefix(c.Candidate, "candidate:")
+		if !hasAttributeValue(m, "candidate", value) {
+			m.WithValueAttribute("candidate", value)
+		}
+	}
+
+	sdp, err := parsed.Marshal()
+	if err != nil {
+		return sessionDescription
+	}
+
+	return &SessionDescription{
+		SDP:  string(sdp),
+		Type: sessionDescription.Type,
+	}
+}
+
+// candidateMediaDescription returns the media section of parsed a candidate
+// is for, by its SDPMid and else its SDPMLineIndex. It defaults to the first
+// one, as the media sections are bundled.
+func candidateMediaDescription(parsed *sdp.SessionDescription, c ICECandidateInit) *sdp.MediaDescription {
+	if c.SDPMid != nil {
+		for _, m := range parsed.MediaDescriptions {
+			if getMidValue(m) == *c.SDPMid {
+				return m
+			}
+		}
+	}
+	if c.SDPMLineIndex != nil && int(*c.SDPMLineIndex) < len(parsed.MediaDescriptions) {
+		return parsed.MediaDescriptions[*c.SDPMLineIndex]
+	}
+	return parsed.MediaDescriptions[0]
+}
+
+// hasAttributeValue returns if m has an attribute key with value
+func hasAttributeValue(m *sdp.MediaDescription, key, value string) bool {
+	for _, a := range m.Attributes {
+		if a.Key == key && a.Value == value {
+			return true
+		}
+	}
+	return false
+}
+
 func addTransceiverSDP(d *sdp.SessionDescription, isPlanB, shouldAddCandidates bool, dtlsFingerprints []DTLSFingerprint, mediaEngine *MediaEngine, midValue string, iceParams ICEParameters, candidates []ICECandidate, dtlsRole sdp.ConnectionRole, iceGatheringState ICEGatheringState, extMaps map[SDPSectionType][]sdp.ExtMap, mediaSection mediaSection) (bool, error) {
 	transceivers := mediaSection.transceivers
 	if len(transceivers) < 1 {
@@ -292,6 +366,18 @@ func addTransceiverSDP(d *sdp.SessionDescription, isPlanB, shouldAddCandidates b
 	}
 	// Use the first transceiver to generate the section attributes
 	t := transceivers[0]
+	if mediaSection.rejected {
+		d.WithMedia(&sdp.MediaDescription{
+			MediaName: sdp.MediaName{
+				Media:   t.kind.String(),
+				Port:    sdp.RangedPort{Value: 0},
+				Protos:  []string{"UDP", "TLS", "RTP", "SAVPF"},
+				Formats: []string{"0"},
+			},
+			Attributes: []sdp.Attribute{{Key: sdp.AttrKeyMID, Value: midValue}},
+		})
+		return false, nil
+	}
 	media := sdp.NewJSEPMediaDescription(t.kind.String(), []string{}).
 		WithValueAttribute(sdp.AttrKeyConnectionSetup, dtlsRole.String()).
 		WithValueAttribute(sdp.AttrKeyMID, midValue).
@@ -320,6 +406,8 @@ func addTransceiverSDP(d *sdp.SessionDescription, isPlanB, shouldAddCandidates b
 		return false, nil
 	}
 
+	addBandwidth(media, t.getBandwidth())
+
 	// Add extmaps
 	if maps, ok := extMaps[SDPSectionType(t.kind.String())]; ok {
 		for _, m := range maps {
@@ -341,15 +429,22 @@ func addTransceiverSDP(d *sdp.SessionDescription, isPlanB, shouldAddCandidates b
 	for _, mt := range transceivers {
 		if mt.Sender() != nil && mt.Sender().Track() != nil {
 			track := mt.Sender().Track()
-			media = media.WithMediaSource(track.SSRC(), track.Label() /* cname */, track.Label() /* streamLabel */, track.ID())
+			streamIDs := mt.Sender().getStreamIDs()
+			media = media.WithMediaSource(mt.Sender().SSRC(), track.Label() /* cname */, streamIDs[0] /* streamLabel */, track.ID())
 			if !isPlanB {
-				media = media.WithPropertyAttribute("msid:" + track.Label() + " " + track.ID())
+				for _, streamID := range streamIDs {
+					media = media.WithPropertyAttribute("msid:" + streamID + " " + track.ID())
+				}
 				break
 			}
 		}
 	}
 
-	media = media.WithPropertyAttribute(t.Direction().String())
+	direction := t.Direction()
+	if mediaSection.direction != RTPTransceiverDirection(Unknown) {
+		direction = mediaSection.direction
+	}
+	media = media.WithPropertyAttribute(direction.String())
 
 	for _, fingerprint := range dtlsFingerprints {
 		media = media.WithFingerprint(fingerprint.Algorithm, strings.ToUpper(fingerprint.Value))
@@ -371,6 +466,13 @@ type mediaSection struct {
 	transceivers []*RTPTransceiver
 	data         bool
 	ridMap       map[string]string
+
+	// direction of an answer, instead of the direction of the transceiver
+	direction RTPTransceiverDirection
+
+	// rejected is true if the media section of an offer isn't answered, as
+	// it isn't bundled with the others
+	rejected bool
 }
 
 // populateSDP serializes a PeerConnections state into an SDP
@@ -422,7 +524,7 @@ func populateSDP(d *sdp.SessionDescription, isPlanB bool, dtlsFingerprints []DTL
 
 	if isICELite {
 		// RFC 5245 S15.3
-		d = d.WithValueAttribute(sdp.AttrKeyICELite, sdp.AttrKeyICELite)
+		d = d.WithPropertyAttribute(sdp.AttrKeyICELite)
 	}
 
 	// Add global exts
@@ -497,6 +599,104 @@ func extractFingerprint(desc *sdp.SessionDescription) (string, string, error) {
 	return parts[1], parts[0], nil
 }
 
+// mungeSDP calls munger with d, and fails if it changed the ICE credentials
+// or DTLS fingerprints of d
+func mungeSDP(munger func(SDPType, *sdp.SessionDescription) error, sdpType SDPType, d *sdp.SessionDescription) error {
+	if munger == nil {
+		return nil
+	}
+
+	credentials := func() [3]string {
+		ufrag, pwd, _, _ := extractICEDetails(d)
+		fingerprint, hash, _ := extractFingerprint(d)
+		return [3]string{ufrag, pwd, hash + " " + fingerprint}
+	}
+	before := credentials()
+	if err := munger(sdpType, d); err != nil {
+		return err
+	}
+	if credentials() != before {
+		return errSDPMungerChangedCredentials
+	}
+	return nil
+}
+
+// bundledMids returns the mids of the first BUNDLE group of desc, false if
+// it has none
+func bundledMids(desc *sdp.SessionDescription) (map[string]bool, bool) {
+	for _, attr := range desc.Attributes {
+		if attr.Key != sdp.AttrKeyGroup {
+			continue
+		}
+		fields := strings.Fields(attr.Value)
+		if len(fields) == 0 || fields[0] != "BUNDLE" {
+			continue
+		}
+
+		mids := map[string]bool{}
+		for _, mid := range fields[1:] {
+			mids[mid] = true
+		}
+		return mids, true
+	}
+	return nil, false
+}
+
+// rtcpMuxMissing returns whether a media section of desc that isn't
+// rejected doesn't multiplex RTCP
+func rtcpMuxMissing(desc *sdp.SessionDescription) bool {
+	for _, media := range desc.MediaDescriptions {
+		if media.MediaName.Media == mediaSectionApplication || media.MediaName.Port.Value == 0 {
+			continue
+		}
+		if _, ok := media.Attribute(sdp.AttrKeyRTCPMux); !ok {
+			return true
+		}
+	}
+	return false
+}
+
+// isICELite returns whether desc is of an ICE lite agent. Older versions
+// of Pion WebRTC wrote a=ice-lite:ice-lite instead of a=ice-lite.
+func isICELite(desc *sdp.SessionDescription) bool {
+	_, isLite := desc.Attribute(sdp.AttrKeyICELite)
+	return isLite
+}
+
+// haveICEUfrag returns if ufrag is an ice-ufrag of desc, of the session or
+// of one of its media sections
+func haveICEUfrag(desc *sdp.SessionDescription, ufrag string) bool {
+	if desc == nil {
+		return false
+	}
+	if value, ok := desc.Attribute("ice-ufrag"); ok && value == ufrag {
+		return true
+	}
+	for _, m := range desc.MediaDescriptions {
+		if value, ok := m.Attribute("ice-ufrag"); ok && value == ufrag {
+			return true
+		}
+	}
+	return false
+}
+
+// candidateUfrag returns the ICE ufrag of a candidate, its UsernameFragment
+// or else the ufrag extension of the candidate attribute browsers add. It
+// is empty if the candidate has none.
+func candidateUfrag(candidate ICECandidateInit) string {
+	if candidate.UsernameFragment != nil {
+		return *candidate.UsernameFragment
+	}
+
+	fields := strings.Fields(candidate.Candidate)
+	for i := 8; i+1 < len(fields); i += 2 {
+		if fields[i] == "ufrag" {
+			return fields[i+1]
+		}
+	}
+	return ""
+}
+
 func extractICEDetails(desc *sdp.SessionDescription) (string, string, []ICECandidate, error) {
 	candidates := []ICECandidate{}
 	remotePwds := []string{}
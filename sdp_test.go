@@ -222,6 +222,7 @@ func TestTrackDetailsFromSDP(t *testing.T) {
 			assert.Equal(t, RTPCodecTypeAudio, track.kind)
 			assert.Equal(t, uint32(2000), track.ssrc)
 			assert.Equal(t, "audio_trk_label", track.label)
+			assert.Equal(t, []string{"audio_trk_label"}, track.streamIDs)
 		}
 		if track := trackDetailsForSSRC(tracks, 3000); track == nil {
 			assert.Fail(t, "missing video track with ssrc:3000")
@@ -240,6 +241,48 @@ func TestTrackDetailsFromSDP(t *testing.T) {
 			assert.Equal(t, uint32(5000), track.ssrc)
 			assert.Equal(t, "video_trk_id", track.id)
 			assert.Equal(t, "video_stream_id", track.label)
+			assert.Equal(t, []string{"video_stream_id"}, track.streamIDs)
+		}
+	})
+
+	t.Run("Track in many or no streams", func(t *testing.T) {
+		s := &sdp.SessionDescription{
+			MediaDescriptions: []*sdp.MediaDescription{
+				{
+					MediaName: sdp.MediaName{
+						Media: "audio",
+					},
+					Attributes: []sdp.Attribute{
+						{Key: "mid", Value: "0"},
+						{Key: "sendrecv"},
+						{Key: "msid", Value: "participant audio_trk_id"},
+						{Key: "msid", Value: "room audio_trk_id"},
+						{Key: "ssrc", Value: "1000"},
+					},
+				},
+				{
+					MediaName: sdp.MediaName{
+						Media: "video",
+					},
+					Attributes: []sdp.Attribute{
+						{Key: "mid", Value: "1"},
+						{Key: "sendrecv"},
+						{Key: "msid", Value: "- video_trk_id"},
+						{Key: "ssrc", Value: "2000"},
+					},
+				},
+			},
+		}
+
+		tracks := trackDetailsFromSDP(nil, s)
+		assert.Equal(t, 2, len(tracks))
+		if track := trackDetailsForSSRC(tracks, 1000); assert.NotNil(t, track) {
+			assert.Equal(t, "participant", track.label)
+			assert.Equal(t, []string{"participant", "room"}, track.streamIDs)
+		}
+		if track := trackDetailsForSSRC(tracks, 2000); assert.NotNil(t, track) {
+			assert.Equal(t, "video_trk_id", track.id)
+			assert.Equal(t, []string{}, track.streamIDs)
 		}
 	})
 
@@ -462,6 +505,24 @@ func TestPopulateSDP(t *testing.T) {
 	})
 }
 
+func TestICELite(t *testing.T) {
+	tr := &RTPTransceiver{kind: RTPCodecTypeVideo}
+	tr.setDirection(RTPTransceiverDirectionSendrecv)
+	m := MediaEngine{}
+	m.RegisterDefaultCodecs()
+
+	d, err := populateSDP(&sdp.SessionDescription{}, false, []DTLSFingerprint{}, false, true, &m, connectionRoleFromDtlsRole(defaultDtlsRoleOffer), []ICECandidate{}, ICEParameters{}, []mediaSection{{id: "video", transceivers: []*RTPTransceiver{tr}}}, ICEGatheringStateComplete, nil)
+	assert.NoError(t, err)
+	raw, err := d.Marshal()
+	assert.NoError(t, err)
+	assert.Contains(t, string(raw), "a=ice-lite\r\n")
+	assert.True(t, isICELite(d))
+
+	// The attribute written by older versions is still recognized
+	assert.True(t, isICELite(&sdp.SessionDescription{Attributes: []sdp.Attribute{{Key: sdp.AttrKeyICELite, Value: sdp.AttrKeyICELite}}}))
+	assert.False(t, isICELite(&sdp.SessionDescription{}))
+}
+
 func TestMatchedAnswerExt(t *testing.T) {
 	s := &sdp.SessionDescription{
 		MediaDescriptions: []*sdp.MediaDescription{
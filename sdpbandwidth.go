@@ -0,0 +1,65 @@
+// +build !js
+
+package webrtc
+
+import (
+	"github.com/pion/sdp/v3"
+)
+
+const (
+	// sdpBandwidthAS is the bandwidth in kilobits per second of RFC 4566
+	sdpBandwidthAS = "AS"
+	// sdpBandwidthTIAS is the bandwidth in bits per second without the
+	// transport overhead of RFC 3890
+	sdpBandwidthTIAS = "TIAS"
+)
+
+// addBandwidth adds the b=AS line of bandwidth in bits per second to media,
+// nothing if it is 0. b=TIAS isn't added, as descriptions with it fail to
+// parse with pion/sdp.
+func addBandwidth(media *sdp.MediaDescription, bandwidth uint64) {
+	if bandwidth == 0 {
+		return
+	}
+
+	media.Bandwidth = append(media.Bandwidth, sdp.Bandwidth{Type: sdpBandwidthAS, Bandwidth: (bandwidth + 999) / 1000})
+}
+
+// getBandwidth returns the bandwidth in bits per second of the b= lines, 0
+// if there are none. b=TIAS is preferred as it is more precise.
+func getBandwidth(bandwidths []sdp.Bandwidth) uint64 {
+	var as uint64
+	for _, b := range bandwidths {
+		switch {
+		case b.Experimental:
+		case b.Type == sdpBandwidthTIAS:
+			return b.Bandwidth
+		case b.Type == sdpBandwidthAS:
+			as = b.Bandwidth * 1000
+		}
+	}
+	return as
+}
+
+// setRemoteBandwidths sets the bandwidth of the media sections of the
+// remote description d on the transceivers with their mid. The bandwidth of
+// the session applies to the media sections without one.
+func setRemoteBandwidths(d *sdp.SessionDescription, transceivers []*RTPTransceiver) {
+	sessionBandwidth := getBandwidth(d.Bandwidth)
+	for _, media := range d.MediaDescriptions {
+		midValue := getMidValue(media)
+		if midValue == "" {
+			continue
+		}
+
+		bandwidth := getBandwidth(media.Bandwidth)
+		if bandwidth == 0 {
+			bandwidth = sessionBandwidth
+		}
+		for _, t := range transceivers {
+			if t.Mid() == midValue {
+				t.setRemoteBandwidth(bandwidth)
+			}
+		}
+	}
+}
@@ -0,0 +1,62 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/pion/randutil"
+	"github.com/pion/sdp/v3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetBandwidth(t *testing.T) {
+	assert.Equal(t, uint64(0), getBandwidth(nil))
+	assert.Equal(t, uint64(64000), getBandwidth([]sdp.Bandwidth{{Type: "AS", Bandwidth: 64}}))
+	assert.Equal(t, uint64(0), getBandwidth([]sdp.Bandwidth{{Experimental: true, Type: "TIAS", Bandwidth: 1000}}))
+	assert.Equal(t, uint64(62500), getBandwidth([]sdp.Bandwidth{
+		{Type: "AS", Bandwidth: 64},
+		{Type: "TIAS", Bandwidth: 62500},
+	}))
+}
+
+func TestSDPBandwidth(t *testing.T) {
+	offerer, answerer, err := newPair()
+	assert.NoError(t, err)
+
+	_, err = offerer.AddTransceiverFromKind(RTPCodecTypeVideo, RtpTransceiverInit{
+		Direction: RTPTransceiverDirectionRecvonly,
+		Bandwidth: 499500,
+	})
+	assert.NoError(t, err)
+
+	track, err := answerer.NewTrack(DefaultPayloadTypeVP8, randutil.NewMathRandomGenerator().Uint32(), "video", "pion")
+	assert.NoError(t, err)
+	sender, err := answerer.AddTrack(track)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), sender.MaxBitrate())
+
+	offer, err := offerer.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.Contains(t, offer.SDP, "b=AS:500\r\n")
+	assert.NoError(t, offerer.SetLocalDescription(offer))
+	assert.NoError(t, answerer.SetRemoteDescription(offer))
+
+	// The bandwidth of the remote peer limits the bitrate of the sender,
+	// b=AS is rounded up to kilobits per second
+	assert.Equal(t, uint64(500000), sender.MaxBitrate())
+	parameters := sender.GetParameters()
+	parameters.Encodings.MaxBitrate = 300000
+	assert.NoError(t, sender.SetParameters(parameters))
+	assert.Equal(t, uint64(300000), sender.MaxBitrate())
+	parameters.Encodings.MaxBitrate = 800000
+	assert.NoError(t, sender.SetParameters(parameters))
+	assert.Equal(t, uint64(500000), sender.MaxBitrate())
+
+	answer, err := answerer.CreateAnswer(nil)
+	assert.NoError(t, err)
+	assert.NotContains(t, answer.SDP, "b=AS")
+
+	assert.NoError(t, offerer.Close())
+	assert.NoError(t, answerer.Close())
+}
@@ -3,8 +3,10 @@
 package webrtc
 
 import (
+	"net/url"
 	"time"
 
+	"github.com/pion/dtls/v2"
 	"github.com/pion/ice/v2"
 	"github.com/pion/logging"
 	"github.com/pion/sdp/v3"
@@ -34,6 +36,7 @@ type SettingEngine struct {
 	}
 	candidates struct {
 		ICELite                bool
+		HostOnly               bool
 		ICENetworkTypes        []NetworkType
 		InterfaceFilter        func(string) bool
 		NAT1To1IPs             []string
@@ -49,15 +52,24 @@ type SettingEngine struct {
 		SRTCP *uint
 	}
 	sdpMediaLevelFingerprints                 bool
+	dtlsDataChannel                           bool
 	sdpExtensions                             map[SDPSectionType][]sdp.ExtMap
 	answeringDTLSRole                         DTLSRole
 	disableCertificateFingerprintVerification bool
 	disableSRTPReplayProtection               bool
 	disableSRTCPReplayProtection              bool
+	srtpProtectionProfiles                    []dtls.SRTPProtectionProfile
 	vnet                                      *vnet.Net
 	LoggerFactory                             logging.LoggerFactory
 	iceTCPMux                                 ice.TCPMux
 	iceProxyDialer                            proxy.Dialer
+	iceResolver                               ICEResolver
+	rtpReceiverOptions                        []RTPReceiverOption
+	trackHandlerMode                          TrackHandlerMode
+	trackHandlerPanicHandler                  func(error)
+	disableSSRCCollisionResolution            bool
+	sdpMunger                                 func(SDPType, *sdp.SessionDescription) error
+	remoteBrowser                             RemoteBrowser
 }
 
 // DetachDataChannels enables detaching data channels. When enabled
@@ -67,6 +79,14 @@ func (e *SettingEngine) DetachDataChannels() {
 	e.detach.DataChannels = true
 }
 
+// SetDTLSDataChannel enables sending data channel messages directly as DTLS
+// application data instead of over SCTP. This is only used if the remote
+// peer signals support for it as well, see DTLSDataChannel for details.
+// Data is exchanged via PeerConnection.OnDTLSDataChannel
+func (e *SettingEngine) SetDTLSDataChannel(enabled bool) {
+	e.dtlsDataChannel = enabled
+}
+
 // SetICETimeouts sets the behavior around ICE Timeouts
 // * disconnectedTimeout is the duration without network activity before a Agent is considered disconnected. Default is 5 Seconds
 // * failedTimeout is the duration without network activity before a Agent is considered failed after disconnected. Default is 25 Seconds
@@ -110,13 +130,28 @@ func (e *SettingEngine) SetEphemeralUDPPortRange(portMin, portMax uint16) error
 	return nil
 }
 
-// SetLite configures whether or not the ice agent should be a lite agent
+// SetLite configures whether or not the ice agent should be a lite agent.
+// A lite agent only gathers host candidates, so it suits servers with a
+// public IP, doesn't start connectivity checks but answers those of the
+// remote agent, and signals a=ice-lite in its descriptions. The remote
+// agent must be a full agent, which takes the controlling role.
 func (e *SettingEngine) SetLite(lite bool) {
 	e.candidates.ICELite = lite
 }
 
+// SetHostOnly configures the ICE agent to not contact any STUN or TURN server,
+// for air-gapped networks or networks where external traffic isn't allowed.
+// Only host candidates, and server reflexive candidates configured with
+// SetNAT1To1IPs, are gathered. Configuring ICE servers in this mode is an
+// error, and gathering fails if there is no network interface to gather from.
+func (e *SettingEngine) SetHostOnly(hostOnly bool) {
+	e.candidates.HostOnly = hostOnly
+}
+
 // SetNetworkTypes configures what types of candidate networks are supported
-// during local and server reflexive gathering.
+// during local and server reflexive gathering. For example, only
+// NetworkTypeUDP4 doesn't gather IPv6 and TCP candidates, on networks where
+// they can't connect and only slow down the connection checks.
 func (e *SettingEngine) SetNetworkTypes(candidateTypes []NetworkType) {
 	e.candidates.ICENetworkTypes = candidateTypes
 }
@@ -183,6 +218,19 @@ func (e *SettingEngine) SetVNet(vnet *vnet.Net) {
 	e.vnet = vnet
 }
 
+// SetTrackHandlerMode sets on which goroutine the OnTrack handlers of
+// PeerConnections are called, see TrackHandlerMode.
+func (e *SettingEngine) SetTrackHandlerMode(mode TrackHandlerMode) {
+	e.trackHandlerMode = mode
+}
+
+// SetTrackHandlerPanicHandler recovers the panics of OnTrack handlers, and
+// passes them as errors to f, so a failing handler doesn't take down the
+// process. A nil f lets panics crash the process, this is the default.
+func (e *SettingEngine) SetTrackHandlerPanicHandler(f func(error)) {
+	e.trackHandlerPanicHandler = f
+}
+
 // SetICEMulticastDNSMode controls if pion/ice queries and generates mDNS ICE Candidates
 func (e *SettingEngine) SetICEMulticastDNSMode(multicastDNSMode ice.MulticastDNSMode) {
 	e.candidates.MulticastDNSMode = multicastDNSMode
@@ -236,6 +284,20 @@ func (e *SettingEngine) DisableSRTCPReplayProtection(isDisabled bool) {
 	e.disableSRTCPReplayProtection = isDisabled
 }
 
+// SetSRTPProtectionProfiles sets the SRTP protection profiles offered in the
+// DTLS handshake, in order of preference. By default
+// SRTP_AEAD_AES_128_GCM is preferred over SRTP_AES128_CM_HMAC_SHA1_80.
+func (e *SettingEngine) SetSRTPProtectionProfiles(profiles ...dtls.SRTPProtectionProfile) {
+	e.srtpProtectionProfiles = profiles
+}
+
+// DisableSSRCCollisionResolution disables changing the SSRC of an RTPSender
+// which collides with the SSRC of a remote stream. Collisions are still
+// reported to PeerConnection.OnSSRCCollision.
+func (e *SettingEngine) DisableSSRCCollisionResolution(isDisabled bool) {
+	e.disableSSRCCollisionResolution = isDisabled
+}
+
 // SetSDPMediaLevelFingerprints configures the logic for DTLS Fingerprint insertion
 // If true, fingerprints will be inserted in the sdp at the fingerprint
 // level, instead of the session level. This helps with compatibility with
@@ -244,6 +306,22 @@ func (e *SettingEngine) SetSDPMediaLevelFingerprints(sdpMediaLevelFingerprints b
 	e.sdpMediaLevelFingerprints = sdpMediaLevelFingerprints
 }
 
+// SetSDPMunger sets a function which can change the offers and answers
+// created by a PeerConnection before they are returned, like to add a b=AS
+// line or vendor attributes. Returning an error fails CreateOffer or
+// CreateAnswer, as does changing the ICE credentials or DTLS fingerprints.
+func (e *SettingEngine) SetSDPMunger(munger func(sdpType SDPType, d *sdp.SessionDescription) error) {
+	e.sdpMunger = munger
+}
+
+// SetRemoteBrowser works around the quirks of the browser of the remote
+// peer in the offers and answers created by a PeerConnection, see
+// RemoteBrowserFromUserAgent to get it from a User-Agent. The workarounds
+// are applied before the SDP munger.
+func (e *SettingEngine) SetRemoteBrowser(browser RemoteBrowser) {
+	e.remoteBrowser = browser
+}
+
 // SetICETCPMux enables ICE-TCP when set to a non-nil value. Make sure that
 // NetworkTypeTCP4 or NetworkTypeTCP6 is enabled as well.
 func (e *SettingEngine) SetICETCPMux(tcpMux ice.TCPMux) {
@@ -316,3 +394,38 @@ func (e *SettingEngine) getSDPExtensions() map[SDPSectionType][]sdp.ExtMap {
 func (e *SettingEngine) SetICEProxyDialer(d proxy.Dialer) {
 	e.iceProxyDialer = d
 }
+
+// SetICEProxy sets the proxy TURN over TCP connects through. proxyURL is
+// http or https for an HTTP proxy, which is sent a CONNECT request, or
+// socks5 for a SOCKS5 proxy. The user and password of proxyURL are used to
+// authenticate with the proxy.
+func (e *SettingEngine) SetICEProxy(proxyURL *url.URL) error {
+	dialer, err := newICEProxyDialer(proxyURL)
+	if err != nil {
+		return err
+	}
+
+	e.iceProxyDialer = dialer
+	return nil
+}
+
+// SetICEResolver sets the resolver of the host names of STUN and TURN
+// servers and of .local mDNS candidates, in place of the system resolver and
+// mDNS queries, like for split-horizon DNS or DNS over HTTPS. A
+// *net.Resolver with a custom Dial can be used.
+func (e *SettingEngine) SetICEResolver(resolver ICEResolver) {
+	e.iceResolver = resolver
+}
+
+// SetICEProxyFromEnvironment makes TURN over TCP connect through the proxy
+// of the HTTPS_PROXY and NO_PROXY environment variables, or their lowercase
+// versions, like HTTP clients. They are read once.
+func (e *SettingEngine) SetICEProxyFromEnvironment() {
+	e.iceProxyDialer = newEnvironmentProxyDialer()
+}
+
+// SetRTPReceiverOptions sets the options of the RTPReceivers created by a
+// PeerConnection, like WithPLIOnRead to request keyframes automatically.
+func (e *SettingEngine) SetRTPReceiverOptions(options ...RTPReceiverOption) {
+	e.rtpReceiverOptions = options
+}
@@ -3,10 +3,12 @@
 package webrtc
 
 import (
+	"errors"
 	"net"
 	"testing"
 	"time"
 
+	"github.com/pion/sdp/v3"
 	"github.com/pion/transport/test"
 	"github.com/stretchr/testify/assert"
 )
@@ -139,3 +141,108 @@ func TestSettingEngine_SetICETCP(t *testing.T) {
 
 	assert.Equal(t, tcpMux, settingEngine.iceTCPMux)
 }
+
+func TestSettingEngine_TrackHandlerMode(t *testing.T) {
+	report := test.CheckRoutines(t)
+	defer report()
+
+	panics := make(chan error, 2)
+	settingEngine := SettingEngine{}
+	settingEngine.SetTrackHandlerMode(TrackHandlerModeDispatcher)
+	settingEngine.SetTrackHandlerPanicHandler(func(err error) {
+		panics <- err
+	})
+
+	pc, err := NewAPI(WithSettingEngine(settingEngine)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	// Handlers run one after the other, a panic doesn't stop the next one
+	handled := make(chan string, 2)
+	running := &atomicBool{}
+	pc.OnTrack(func(track *Track, _ *RTPReceiver) {
+		assert.False(t, running.get())
+		running.set(true)
+		defer running.set(false)
+
+		time.Sleep(10 * time.Millisecond)
+		handled <- track.ID()
+		if track.ID() == "first" {
+			panic("handler failed")
+		}
+	})
+	pc.onTrack(&Track{id: "first"}, nil)
+	pc.onTrack(&Track{id: "second"}, nil)
+
+	assert.Equal(t, "first", <-handled)
+	assert.Equal(t, "second", <-handled)
+	assert.True(t, errors.Is(<-panics, errTrackHandlerPanic))
+	pc.trackHandlerOps.Done()
+	assert.Equal(t, "dispatcher", TrackHandlerModeDispatcher.String())
+
+	assert.NoError(t, pc.Close())
+}
+
+func TestSettingEngine_SetSDPMunger(t *testing.T) {
+	report := test.CheckRoutines(t)
+	defer report()
+
+	var munge func(SDPType, *sdp.SessionDescription) error
+	settingEngine := SettingEngine{}
+	settingEngine.SetSDPMunger(func(sdpType SDPType, d *sdp.SessionDescription) error {
+		return munge(sdpType, d)
+	})
+	m := MediaEngine{}
+	m.RegisterDefaultCodecs()
+	api := NewAPI(WithSettingEngine(settingEngine), WithMediaEngine(m))
+
+	offerer, err := api.NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	answerer, err := api.NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	_, err = offerer.AddTransceiverFromKind(RTPCodecTypeVideo)
+	assert.NoError(t, err)
+
+	munge = func(sdpType SDPType, d *sdp.SessionDescription) error {
+		for _, m := range d.MediaDescriptions {
+			m.Bandwidth = append(m.Bandwidth, sdp.Bandwidth{Type: "AS", Bandwidth: 500})
+		}
+		return nil
+	}
+	offer, err := offerer.CreateOffer(nil)
+	assert.NoError(t, err)
+	assert.Contains(t, offer.SDP, "b=AS:500")
+	assert.NoError(t, offerer.SetLocalDescription(offer))
+	assert.NoError(t, answerer.SetRemoteDescription(offer))
+
+	munge = func(sdpType SDPType, d *sdp.SessionDescription) error {
+		assert.Equal(t, SDPTypeAnswer, sdpType)
+		d.WithValueAttribute("x-vendor", "1")
+		return nil
+	}
+	answer, err := answerer.CreateAnswer(nil)
+	assert.NoError(t, err)
+	assert.Contains(t, answer.SDP, "a=x-vendor:1")
+
+	// Errors and changed ICE credentials fail creating the description
+	errMunger := errors.New("munger failed")
+	munge = func(SDPType, *sdp.SessionDescription) error {
+		return errMunger
+	}
+	_, err = answerer.CreateAnswer(nil)
+	assert.Equal(t, errMunger, err)
+	munge = func(_ SDPType, d *sdp.SessionDescription) error {
+		for _, m := range d.MediaDescriptions {
+			for i := range m.Attributes {
+				if m.Attributes[i].Key == "ice-ufrag" {
+					m.Attributes[i].Value = "munged"
+				}
+			}
+		}
+		return nil
+	}
+	_, err = answerer.CreateAnswer(nil)
+	assert.Equal(t, errSDPMungerChangedCredentials, err)
+
+	assert.NoError(t, offerer.Close())
+	assert.NoError(t, answerer.Close())
+}
@@ -0,0 +1,133 @@
+// +build !js
+
+package webrtc
+
+import (
+	"context"
+	"sync"
+)
+
+// SignalerHandlers are called with the messages received by a Signaler
+type SignalerHandlers struct {
+	OnOffer     func(SessionDescription)
+	OnAnswer    func(SessionDescription)
+	OnCandidate func(ICECandidateInit)
+}
+
+// Signaler exchanges the session descriptions and ICE candidates of a
+// PeerConnection with one remote peer, over the signaling of an application
+// like a WebSocket. PeerConnections are negotiated over it with Negotiate,
+// or accepted with a Listener. The signaling package implements it.
+type Signaler interface {
+	SendOffer(SessionDescription) error
+	SendAnswer(SessionDescription) error
+	SendCandidate(ICECandidateInit) error
+
+	// Receive calls handlers with the received messages until the Signaler
+	// is closed, and returns the error that ended it
+	Receive(handlers SignalerHandlers) error
+
+	Close() error
+}
+
+// Negotiate negotiates pc with the remote peer over signaler, one of the
+// peers is the offerer. It returns once the descriptions are exchanged, the
+// ICE candidates are trickled until signaler is closed.
+//
+// Negotiate sets the OnICECandidate handler of pc, and receives from signaler.
+func Negotiate(ctx context.Context, pc *PeerConnection, signaler Signaler, offerer bool) error {
+	var (
+		mu                sync.Mutex
+		hasRemote         bool
+		pendingCandidates []ICECandidateInit
+	)
+
+	descriptions := make(chan SessionDescription, 1)
+	onDescription := func(desc SessionDescription) {
+		select {
+		case descriptions <- desc:
+		default:
+		}
+	}
+
+	// Remote candidates are added once the remote description is set
+	receiveErr := make(chan error, 1)
+	go func() {
+		receiveErr <- signaler.Receive(SignalerHandlers{
+			OnOffer:  onDescription,
+			OnAnswer: onDescription,
+			OnCandidate: func(candidate ICECandidateInit) {
+				mu.Lock()
+				if !hasRemote {
+					pendingCandidates = append(pendingCandidates, candidate)
+					mu.Unlock()
+					return
+				}
+				mu.Unlock()
+				_ = pc.AddICECandidate(candidate)
+			},
+		})
+	}()
+
+	pc.OnICECandidate(func(candidate *ICECandidate) {
+		if candidate != nil {
+			_ = signaler.SendCandidate(candidate.ToJSON())
+		}
+	})
+
+	receiveDescription := func(sdpType SDPType) error {
+		var desc SessionDescription
+		select {
+		case desc = <-descriptions:
+		case err := <-receiveErr:
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if desc.Type != sdpType {
+			return errSignalerUnexpectedDescription
+		}
+		if err := pc.SetRemoteDescription(desc); err != nil {
+			return err
+		}
+
+		mu.Lock()
+		hasRemote = true
+		candidates := pendingCandidates
+		pendingCandidates = nil
+		mu.Unlock()
+
+		for _, candidate := range candidates {
+			if err := pc.AddICECandidate(candidate); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if offerer {
+		offer, err := pc.CreateOffer(nil)
+		if err != nil {
+			return err
+		}
+		if err = pc.SetLocalDescription(offer); err != nil {
+			return err
+		}
+		if err = signaler.SendOffer(offer); err != nil {
+			return err
+		}
+		return receiveDescription(SDPTypeAnswer)
+	}
+
+	if err := receiveDescription(SDPTypeOffer); err != nil {
+		return err
+	}
+	answer, err := pc.CreateAnswer(nil)
+	if err != nil {
+		return err
+	}
+	if err = pc.SetLocalDescription(answer); err != nil {
+		return err
+	}
+	return signaler.SendAnswer(answer)
+}
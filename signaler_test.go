@@ -0,0 +1,134 @@
+// +build !js
+
+package webrtc
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pion/transport/test"
+	"github.com/stretchr/testify/assert"
+)
+
+// chanSignaler is a Signaler that delivers its messages to the chanSignaler
+// it was paired with. Closing either closes both, like a net.Pipe.
+type chanSignaler struct {
+	messages chan func(SignalerHandlers)
+	remote   *chanSignaler
+
+	closed    chan struct{}
+	closeOnce *sync.Once
+}
+
+func newChanSignalerPair() (*chanSignaler, *chanSignaler) {
+	closed, closeOnce := make(chan struct{}), &sync.Once{}
+	a := &chanSignaler{messages: make(chan func(SignalerHandlers), 64), closed: closed, closeOnce: closeOnce}
+	b := &chanSignaler{messages: make(chan func(SignalerHandlers), 64), closed: closed, closeOnce: closeOnce}
+	a.remote, b.remote = b, a
+	return a, b
+}
+
+func (s *chanSignaler) send(message func(SignalerHandlers)) error {
+	select {
+	case s.remote.messages <- message:
+		return nil
+	case <-s.closed:
+		return io.ErrClosedPipe
+	}
+}
+
+func (s *chanSignaler) SendOffer(offer SessionDescription) error {
+	return s.send(func(handlers SignalerHandlers) { handlers.OnOffer(offer) })
+}
+
+func (s *chanSignaler) SendAnswer(answer SessionDescription) error {
+	return s.send(func(handlers SignalerHandlers) { handlers.OnAnswer(answer) })
+}
+
+func (s *chanSignaler) SendCandidate(candidate ICECandidateInit) error {
+	return s.send(func(handlers SignalerHandlers) { handlers.OnCandidate(candidate) })
+}
+
+func (s *chanSignaler) Receive(handlers SignalerHandlers) error {
+	for {
+		select {
+		case message := <-s.messages:
+			message(handlers)
+		case <-s.closed:
+			return io.EOF
+		}
+	}
+}
+
+func (s *chanSignaler) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.closed)
+	})
+	return nil
+}
+
+func TestNegotiate(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	signalerOffer, signalerAnswer := newChanSignalerPair()
+
+	pcOffer, pcAnswer, err := newPair()
+	assert.NoError(t, err)
+
+	received := make(chan string, 1)
+	pcAnswer.OnDataChannel(func(d *DataChannel) {
+		d.OnMessage(func(msg DataChannelMessage) {
+			received <- string(msg.Data)
+		})
+	})
+	d, err := pcOffer.CreateDataChannel("data", nil)
+	assert.NoError(t, err)
+	d.OnOpen(func() {
+		assert.NoError(t, d.SendText("hello"))
+	})
+
+	answered := make(chan error, 1)
+	go func() {
+		answered <- Negotiate(context.Background(), pcAnswer, signalerAnswer, false)
+	}()
+	assert.NoError(t, Negotiate(context.Background(), pcOffer, signalerOffer, true))
+	assert.NoError(t, <-answered)
+	assert.Equal(t, "hello", <-received)
+
+	assert.NoError(t, signalerOffer.Close())
+	assert.NoError(t, signalerAnswer.Close())
+	closePairNow(t, pcOffer, pcAnswer)
+}
+
+func TestNegotiate_UnexpectedDescription(t *testing.T) {
+	report := test.CheckRoutines(t)
+	defer report()
+
+	signalerA, signalerB := newChanSignalerPair()
+
+	pcA, pcB, err := newPair()
+	assert.NoError(t, err)
+	_, err = pcA.CreateDataChannel("data", nil)
+	assert.NoError(t, err)
+	_, err = pcB.CreateDataChannel("data", nil)
+	assert.NoError(t, err)
+
+	// Both peers offer
+	offered := make(chan error, 1)
+	go func() {
+		offered <- Negotiate(context.Background(), pcB, signalerB, true)
+	}()
+	assert.Equal(t, errSignalerUnexpectedDescription, Negotiate(context.Background(), pcA, signalerA, true))
+	assert.Equal(t, errSignalerUnexpectedDescription, <-offered)
+
+	assert.NoError(t, signalerA.Close())
+	assert.NoError(t, signalerB.Close())
+	closePairNow(t, pcA, pcB)
+}
@@ -0,0 +1,134 @@
+// +build !js
+
+package webrtc
+
+import (
+	"sync/atomic"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3/internal/util"
+	"github.com/pion/webrtc/v3/pkg/interceptor"
+)
+
+// SSRC returns the SSRC the RTPSender sends with. It is the SSRC of its
+// Track, unless it collided with the SSRC of a remote stream.
+func (r *RTPSender) SSRC() uint32 {
+	if ssrc := atomic.LoadUint32(&r.collisionSSRC); ssrc != 0 {
+		return ssrc
+	}
+	return r.Track().SSRC()
+}
+
+// rewriteSSRC returns the header with the SSRC chosen after a collision, or
+// header if there was none. The header of the caller isn't modified.
+func (r *RTPSender) rewriteSSRC(header *rtp.Header) *rtp.Header {
+	ssrc := atomic.LoadUint32(&r.collisionSSRC)
+	if ssrc == 0 || header.SSRC == ssrc {
+		return header
+	}
+
+	rewritten := *header
+	rewritten.SSRC = ssrc
+	return &rewritten
+}
+
+// changeSSRC makes the RTPSender send with a new random SSRC, as per RFC 3550
+// section 8.2 its SSRC collided with the one of a remote stream. If sending
+// already started, the streams and the RTCP reader are rebound to the new
+// SSRC and a BYE is sent for the old one. It returns the old and new SSRCs.
+func (r *RTPSender) changeSSRC() (uint32, uint32, error) {
+	oldSSRC := r.SSRC()
+	newSSRC := oldSSRC
+	for newSSRC == oldSSRC || newSSRC == 0 {
+		newSSRC = util.RandUint32()
+	}
+
+	r.mu.Lock()
+	atomic.StoreUint32(&r.collisionSSRC, newSSRC)
+	if !r.hasSent() {
+		r.mu.Unlock()
+		return oldSSRC, newSSRC, nil
+	}
+
+	srtcpSession, err := r.transport.getSRTCPSession()
+	if err != nil {
+		r.mu.Unlock()
+		return oldSSRC, newSSRC, err
+	}
+	oldStream := r.rtcpReadStream
+	if r.rtcpReadStream, err = srtcpSession.OpenReadStream(newSSRC); err != nil {
+		r.rtcpReadStream = oldStream
+		r.mu.Unlock()
+		return oldSSRC, newSSRC, err
+	}
+
+	r.api.interceptor.UnbindLocalStream(&r.streamInfo)
+	r.parameters.Encodings.SSRC = newSSRC
+	r.streamInfo = createStreamInfo(r.track.ID(), newSSRC, r.parameters.Encodings.PayloadType, r.track.Codec())
+	r.rtpWriter = r.api.interceptor.BindLocalStream(&r.streamInfo, interceptor.RTPWriterFunc(r.writeRTP))
+	r.startRTCPReader()
+	transport := r.transport
+	r.mu.Unlock()
+
+	// The reader of the old stream exits once it is closed. Collisions are
+	// resolved before the RTPReceiver of the remote stream starts, which
+	// opens the stream of the old SSRC again.
+	closeErr := oldStream.Close()
+	return oldSSRC, newSSRC, util.FlattenErrs([]error{closeErr, transport.writeRTCP([]rtcp.Packet{&rtcp.Goodbye{Sources: []uint32{oldSSRC}}})})
+}
+
+// OnSSRCCollision sets an event handler which is called when the SSRC of an
+// RTPSender is the same as the one of a remote stream. Unless
+// SettingEngine.DisableSSRCCollisionResolution was called, the RTPSender
+// then sends with newSSRC, and a negotiation is needed to signal it.
+// Otherwise newSSRC is 0.
+func (pc *PeerConnection) OnSSRCCollision(f func(sender *RTPSender, oldSSRC, newSSRC uint32)) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	pc.onSSRCCollisionHandler = f
+}
+
+// resolveSSRCCollisions changes the SSRC of the RTPSenders that collide with
+// the remote SSRCs
+func (pc *PeerConnection) resolveSSRCCollisions(remoteSSRCs []uint32, transceivers []*RTPTransceiver) {
+	resolved := false
+	for _, t := range transceivers {
+		sender := t.Sender()
+		if sender == nil || sender.Track() == nil {
+			continue
+		}
+
+		ssrc := sender.SSRC()
+		for _, remoteSSRC := range remoteSSRCs {
+			if remoteSSRC != ssrc {
+				continue
+			}
+
+			newSSRC := uint32(0)
+			if pc.api.settingEngine.disableSSRCCollisionResolution {
+				pc.log.Warnf("SSRC %d of a local track collides with a remote one", ssrc)
+			} else {
+				var err error
+				if ssrc, newSSRC, err = sender.changeSSRC(); err != nil {
+					pc.log.Warnf("Failed to rebind colliding SSRC %d to %d: %s", ssrc, newSSRC, err)
+				} else {
+					pc.log.Infof("SSRC %d of a local track collides with a remote one, sending with %d", ssrc, newSSRC)
+				}
+				resolved = true
+			}
+
+			pc.mu.RLock()
+			handler := pc.onSSRCCollisionHandler
+			pc.mu.RUnlock()
+			if handler != nil {
+				go handler(sender, ssrc, newSSRC)
+			}
+			break
+		}
+	}
+
+	if resolved {
+		pc.onNegotiationNeeded()
+	}
+}
@@ -0,0 +1,137 @@
+// +build !js
+
+package webrtc
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+	"github.com/pion/transport/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRTPSender_rewriteSSRC(t *testing.T) {
+	r := &RTPSender{}
+	header := &rtp.Header{Version: 2, SSRC: 1234}
+	assert.Equal(t, header, r.rewriteSSRC(header))
+
+	r.collisionSSRC = 5678
+	assert.Equal(t, uint32(5678), r.rewriteSSRC(header).SSRC)
+	assert.Equal(t, uint32(1234), header.SSRC)
+}
+
+func TestPeerConnection_SSRCCollision(t *testing.T) {
+	type collision struct {
+		oldSSRC, newSSRC uint32
+	}
+
+	testCollision := func(disable bool) (*RTPSender, collision) {
+		s := SettingEngine{}
+		s.DisableSSRCCollisionResolution(disable)
+		m := MediaEngine{}
+		m.RegisterDefaultCodecs()
+		api := NewAPI(WithMediaEngine(m), WithSettingEngine(s))
+
+		pcOffer, pcAnswer, err := api.newPair(Configuration{})
+		assert.NoError(t, err)
+
+		// Both sides send with the same SSRC
+		offerTrack, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, 1234, "video", "pion")
+		assert.NoError(t, err)
+		sender, err := pcOffer.AddTrack(offerTrack)
+		assert.NoError(t, err)
+		answerTrack, err := pcAnswer.NewTrack(DefaultPayloadTypeVP8, 1234, "video", "pion")
+		assert.NoError(t, err)
+		_, err = pcAnswer.AddTrack(answerTrack)
+		assert.NoError(t, err)
+
+		collisions := make(chan collision, 1)
+		pcOffer.OnSSRCCollision(func(s *RTPSender, oldSSRC, newSSRC uint32) {
+			assert.Equal(t, sender, s)
+			collisions <- collision{oldSSRC, newSSRC}
+		})
+
+		assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+		var c collision
+		select {
+		case c = <-collisions:
+		case <-time.After(5 * time.Second):
+			t.Fatal("SSRC collision not detected")
+		}
+
+		// A negotiation signals the new SSRC
+		offer, err := pcOffer.CreateOffer(nil)
+		assert.NoError(t, err)
+		assert.Equal(t, !disable, strings.Contains(offer.SDP, "a=ssrc:"+strconv.FormatUint(uint64(sender.SSRC()), 10)+" ") && sender.SSRC() != 1234)
+
+		closePairNow(t, pcOffer, pcAnswer)
+		return sender, c
+	}
+
+	sender, c := testCollision(false)
+	assert.Equal(t, uint32(1234), c.oldSSRC)
+	assert.NotEqual(t, uint32(0), c.newSSRC)
+	assert.Equal(t, c.newSSRC, sender.SSRC())
+	assert.Equal(t, c.newSSRC, sender.GetParameters().Encodings.SSRC)
+	assert.Equal(t, uint32(1234), sender.Track().SSRC())
+
+	sender, c = testCollision(true)
+	assert.Equal(t, collision{1234, 0}, c)
+	assert.Equal(t, uint32(1234), sender.SSRC())
+}
+
+func TestRTPSender_changeSSRC(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	pcOffer, pcAnswer, err := newPair()
+	assert.NoError(t, err)
+
+	track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, 5000, "video", "pion")
+	assert.NoError(t, err)
+	sender, err := pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+
+	requested := make(chan struct{}, 1)
+	sender.OnKeyframeRequest(func() {
+		select {
+		case requested <- struct{}{}:
+		default:
+		}
+	})
+
+	onTrack := make(chan struct{})
+	pcAnswer.OnTrack(func(*Track, *RTPReceiver) {
+		close(onTrack)
+	})
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	sendVideoUntilDone(onTrack, t, []*Track{track})
+
+	// Keyframe requests for the new SSRC are handled, the reader of the
+	// old one has exited
+	_, newSSRC, err := sender.changeSSRC()
+	assert.NoError(t, err)
+
+	// The PLI is resent in case it's lost
+	ticker := time.NewTicker(20 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		assert.NoError(t, pcAnswer.WriteRTCP([]rtcp.Packet{&rtcp.PictureLossIndication{MediaSSRC: newSSRC}}))
+
+		select {
+		case <-requested:
+			closePairNow(t, pcOffer, pcAnswer)
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -666,6 +666,11 @@ type RemoteOutboundRTPStreamStats struct {
 	// Sender Report (SR) packet, which reflects the remote endpoint's clock.
 	// That clock may not be synchronized with the local clock.
 	RemoteTimestamp StatsTimestamp `json:"remoteTimestamp"`
+
+	// RoundTripTime is the estimated round trip time for this SSRC based on the
+	// RTCP Extended Report (XR) DLRR blocks, see WithExtendedReports, and
+	// measured in seconds.
+	RoundTripTime float64 `json:"roundTripTime"`
 }
 
 // RTPContributingSourceStats contains statistics for a contributing source (CSRC) that contributed
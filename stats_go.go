@@ -91,3 +91,48 @@ func (r StatsReport) GetCodecStats(c *RTPCodec) (CodecStats, bool) {
 	}
 	return codecStats, true
 }
+
+// GetInboundRTPStreamStats is a helper method to return the associated stats for a given remote Track
+func (r StatsReport) GetInboundRTPStreamStats(t *Track) (InboundRTPStreamStats, bool) {
+	statsID := inboundRTPStreamStatsID(t.SSRC())
+	stats, ok := r[statsID]
+	if !ok {
+		return InboundRTPStreamStats{}, false
+	}
+
+	inboundStats, ok := stats.(InboundRTPStreamStats)
+	if !ok {
+		return InboundRTPStreamStats{}, false
+	}
+	return inboundStats, true
+}
+
+// GetRemoteInboundRTPStreamStats is a helper method to return the associated stats for a given RTPSender
+func (r StatsReport) GetRemoteInboundRTPStreamStats(s *RTPSender) (RemoteInboundRTPStreamStats, bool) {
+	statsID := remoteInboundRTPStreamStatsID(s.SSRC())
+	stats, ok := r[statsID]
+	if !ok {
+		return RemoteInboundRTPStreamStats{}, false
+	}
+
+	remoteInboundStats, ok := stats.(RemoteInboundRTPStreamStats)
+	if !ok {
+		return RemoteInboundRTPStreamStats{}, false
+	}
+	return remoteInboundStats, true
+}
+
+// GetRemoteOutboundRTPStreamStats is a helper method to return the associated stats for a given remote Track
+func (r StatsReport) GetRemoteOutboundRTPStreamStats(t *Track) (RemoteOutboundRTPStreamStats, bool) {
+	statsID := remoteOutboundRTPStreamStatsID(t.SSRC())
+	stats, ok := r[statsID]
+	if !ok {
+		return RemoteOutboundRTPStreamStats{}, false
+	}
+
+	remoteOutboundStats, ok := stats.(RemoteOutboundRTPStreamStats)
+	if !ok {
+		return RemoteOutboundRTPStreamStats{}, false
+	}
+	return remoteOutboundStats, true
+}
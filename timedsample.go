@@ -0,0 +1,185 @@
+// +build !js
+
+package webrtc
+
+import (
+	"sort"
+	"time"
+
+	"github.com/pion/webrtc/v3/pkg/media"
+)
+
+// TimedSampleOrder tells how Track.WriteTimedSample handles Samples that
+// aren't written in decode order, see Track.SetTimedSampleOrder.
+type TimedSampleOrder int
+
+const (
+	// TimedSampleOrderKeep writes the Samples in the order they are given.
+	// This is the default.
+	TimedSampleOrderKeep TimedSampleOrder = iota + 1
+
+	// TimedSampleOrderDrop drops the Samples whose decode timestamp isn't
+	// after the one of the last Sample written.
+	TimedSampleOrderDrop
+
+	// TimedSampleOrderReorder holds back Samples up to the reorder window,
+	// and writes them by decode timestamp. Samples arriving after a Sample
+	// with a later decode timestamp was written are dropped.
+	TimedSampleOrderReorder
+)
+
+// This is done this way because of a linter.
+const (
+	timedSampleOrderKeepStr    = "keep"
+	timedSampleOrderDropStr    = "drop"
+	timedSampleOrderReorderStr = "reorder"
+)
+
+func (o TimedSampleOrder) String() string {
+	switch o {
+	case TimedSampleOrderKeep:
+		return timedSampleOrderKeepStr
+	case TimedSampleOrderDrop:
+		return timedSampleOrderDropStr
+	case TimedSampleOrderReorder:
+		return timedSampleOrderReorderStr
+	default:
+		return ErrUnknownType.Error()
+	}
+}
+
+// timedSample is a Sample written with WriteTimedSample
+type timedSample struct {
+	sample   media.Sample
+	pts, dts time.Duration
+}
+
+// timedSamples orders the Samples written with WriteTimedSample, guarded by
+// the mu of the Track
+type timedSamples struct {
+	order  TimedSampleOrder
+	window int
+
+	// base is the RTP timestamp of a presentation timestamp of 0
+	hasBase bool
+	base    uint32
+
+	// lastDTS is the decode timestamp of the last Sample written
+	hasLastDTS bool
+	lastDTS    time.Duration
+
+	pending []timedSample
+}
+
+// SetTimedSampleOrder sets how WriteTimedSample handles Samples that aren't
+// written in decode order, like from a demuxer interleaving streams. window
+// is the number of Samples held back with TimedSampleOrderReorder.
+func (t *Track) SetTimedSampleOrder(order TimedSampleOrder, window int) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.receiver != nil {
+		return errTrackLocalTrackWrite
+	}
+	t.timedSamples.order = order
+	t.timedSamples.window = window
+	return nil
+}
+
+// WriteTimedSample packetizes and writes s to the Track with the RTP
+// timestamp of its presentation timestamp pts, like WriteSample. Samples are
+// written in decode order, given by dts, so video with B-frames is sent with
+// timestamps going back and forth as the decoder of the receiver expects.
+// The RTP timestamp of a pts of 0 is taken from the TimestampGenerator of
+// the Track when the first Sample is written.
+func (t *Track) WriteTimedSample(s media.Sample, pts, dts time.Duration) error {
+	for _, sample := range t.orderTimedSample(timedSample{sample: s, pts: pts, dts: dts}) {
+		if err := t.writeTimedSample(sample); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FlushTimedSamples writes the Samples held back by
+// TimedSampleOrderReorder, like at the end of a stream.
+func (t *Track) FlushTimedSamples() error {
+	t.mu.Lock()
+	pending := t.timedSamples.pending
+	t.timedSamples.pending = nil
+	if len(pending) != 0 {
+		t.timedSamples.hasLastDTS = true
+		t.timedSamples.lastDTS = pending[len(pending)-1].dts
+	}
+	t.mu.Unlock()
+
+	for _, sample := range pending {
+		if err := t.writeTimedSample(sample); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// orderTimedSample returns the Samples to write after sample was given
+func (t *Track) orderTimedSample(sample timedSample) []timedSample {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s := &t.timedSamples
+	late := s.hasLastDTS && sample.dts <= s.lastDTS
+	switch s.order {
+	case TimedSampleOrderDrop:
+		if late {
+			return nil
+		}
+	case TimedSampleOrderReorder:
+		if late {
+			return nil
+		}
+		i := sort.Search(len(s.pending), func(i int) bool {
+			return s.pending[i].dts > sample.dts
+		})
+		s.pending = append(s.pending, timedSample{})
+		copy(s.pending[i+1:], s.pending[i:])
+		s.pending[i] = sample
+		if len(s.pending) <= s.window {
+			return nil
+		}
+		sample, s.pending = s.pending[0], s.pending[1:]
+	default:
+	}
+
+	s.hasLastDTS = true
+	s.lastDTS = sample.dts
+	return []timedSample{sample}
+}
+
+// writeTimedSample writes sample with the RTP timestamp of its pts
+func (t *Track) writeTimedSample(sample timedSample) error {
+	timestamps := t.TimestampGenerator()
+	if timestamps == nil {
+		return errTrackLocalTrackWrite
+	}
+
+	t.mu.Lock()
+	if !t.timedSamples.hasBase {
+		t.timedSamples.hasBase = true
+		t.timedSamples.base = timestamps.NextTimestamp(0)
+	}
+	timestamp := t.timedSamples.base
+	clockRate := uint32(0)
+	if t.codec != nil {
+		clockRate = t.codec.ClockRate
+	}
+	t.mu.Unlock()
+
+	// The timestamp is converted in two steps, as nanoseconds times the clock
+	// rate overflow after a day. It is rounded, as frame durations like 1/30s
+	// aren't whole nanoseconds.
+	pts := sample.pts
+	timestamp += uint32(int64(pts/time.Second)*int64(clockRate) + (int64(pts%time.Second)*int64(clockRate)+int64(time.Second/2))/int64(time.Second))
+	if err := t.setSampleExtensions(sample.sample); err != nil {
+		return err
+	}
+	return t.writeSample(sample.sample, timestamp, sample.sample.Samples)
+}
@@ -0,0 +1,104 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/transport/test"
+	"github.com/pion/webrtc/v3/pkg/media"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrack_orderTimedSample(t *testing.T) {
+	order := func(track *Track, dtss ...time.Duration) []time.Duration {
+		written := []time.Duration{}
+		for _, dts := range dtss {
+			for _, sample := range track.orderTimedSample(timedSample{dts: dts}) {
+				written = append(written, sample.dts)
+			}
+		}
+		return written
+	}
+
+	track := &Track{}
+	assert.Equal(t, []time.Duration{1, 3, 2}, order(track, 1, 3, 2))
+
+	assert.NoError(t, track.SetTimedSampleOrder(TimedSampleOrderDrop, 0))
+	assert.Equal(t, []time.Duration{5, 6}, order(track, 5, 4, 5, 6))
+
+	// Samples are held back by the window, late ones are dropped
+	track = &Track{}
+	assert.NoError(t, track.SetTimedSampleOrder(TimedSampleOrderReorder, 2))
+	assert.Equal(t, []time.Duration{1, 2, 3}, order(track, 3, 1, 2, 5, 4))
+	assert.Equal(t, []time.Duration{4}, order(track, 2, 7))
+	assert.Equal(t, 2, len(track.timedSamples.pending))
+
+	remote := &Track{receiver: &RTPReceiver{}}
+	assert.Equal(t, errTrackLocalTrackWrite, remote.SetTimedSampleOrder(TimedSampleOrderKeep, 0))
+	assert.Equal(t, "reorder", TimedSampleOrderReorder.String())
+}
+
+func TestTrack_WriteTimedSample(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	pcOffer, pcAnswer, err := newPair()
+	assert.NoError(t, err)
+
+	track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, 5000, "video", "pion")
+	assert.NoError(t, err)
+	_, err = pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+	assert.NoError(t, track.SetTimestampGenerator(NewTimestampGenerator(0)))
+
+	received := make(chan []*rtp.Packet)
+	pcAnswer.OnTrack(func(track *Track, r *RTPReceiver) {
+		packets := []*rtp.Packet{}
+		for len(packets) < 8 {
+			packet, readErr := track.ReadRTP()
+			if readErr != nil {
+				return
+			}
+			packets = append(packets, packet)
+		}
+		received <- packets
+	})
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	// Frames are presented in the order 0 1 2 3, and decoded in the order
+	// 0 3 1 2 as 1 and 2 are B-frames referencing 3
+	done := make(chan struct{})
+	go func() {
+		frame := time.Second / 30
+		for gop := time.Duration(0); ; gop += 4 {
+			for i, presented := range []time.Duration{0, 3, 1, 2} {
+				select {
+				case <-time.After(20 * time.Millisecond):
+				case <-done:
+					return
+				}
+				assert.NoError(t, track.WriteTimedSample(media.Sample{Data: []byte{0x00}}, (gop+presented)*frame, (gop+time.Duration(i))*frame))
+			}
+		}
+	}()
+	packets := <-received
+	close(done)
+
+	wentBack := false
+	for i := 1; i < len(packets); i++ {
+		assert.Equal(t, uint32(0), packets[i].Timestamp%3000)
+		delta := int64(packets[i].Timestamp) - int64(packets[i-1].Timestamp)
+		assert.Contains(t, []int64{3 * 3000, -2 * 3000, 3000, 2 * 3000}, delta)
+		wentBack = wentBack || delta < 0
+	}
+	assert.True(t, wentBack)
+
+	closePairNow(t, pcOffer, pcAnswer)
+}
@@ -5,10 +5,15 @@ package webrtc
 import (
 	"io"
 	"sync"
+	"time"
 
 	"github.com/pion/rtp"
 	"github.com/pion/webrtc/v3/internal/util"
+	"github.com/pion/webrtc/v3/pkg/colorspace"
 	"github.com/pion/webrtc/v3/pkg/media"
+	"github.com/pion/webrtc/v3/pkg/media/red"
+	"github.com/pion/webrtc/v3/pkg/videoorientation"
+	"github.com/pion/webrtc/v3/pkg/videotiming"
 )
 
 const (
@@ -28,13 +33,43 @@ type Track struct {
 	ssrc        uint32
 	codec       *RTPCodec
 	rid         string
+	streamIDs   []string
 
 	packetizer rtp.Packetizer
+	sequencer  rtp.Sequencer
+	timestamps TimestampGenerator
+
+	// partialFrame is true if the last Sample written is continued by the
+	// next one, which has partialFrameTimestamp
+	partialFrame          bool
+	partialFrameTimestamp uint32
+
+	timedSamples timedSamples
 
 	receiver         *RTPReceiver
 	activeSenders    []*RTPSender
 	totalSenderCount int // count of all senders (accounts for senders that have not been started yet)
 	peeked           []byte
+
+	sampleCounter *media.SampleCounter
+
+	playoutDelay  playoutDelay
+	orientation   *videoorientation.Extension
+	colorSpace    *colorspace.Extension
+	onVideoTiming func(timing videotiming.Extension, timestamp uint32)
+
+	onEnded     func(reason TrackEndedReason)
+	endedReason TrackEndedReason
+
+	onMute, onUnmute func()
+	inactive         bool
+
+	// redPayloadType is the payload type of the RED packets a remote Track
+	// decodes into packets of its Opus codec, 0 if RED isn't negotiated.
+	// redQueue are the decoded packets Read returns next.
+	redPayloadType uint8
+	redDecoder     red.Decoder
+	redQueue       [][]byte
 }
 
 // ID gets the ID of the track
@@ -82,6 +117,19 @@ func (t *Track) SSRC() uint32 {
 	return t.ssrc
 }
 
+// StreamIDs gets the IDs of the MediaStreams the track belongs to, which
+// group the tracks of a remote participant. For a remote track these are
+// the stream IDs of its a=msid lines, for a local track it is the Label.
+func (t *Track) StreamIDs() []string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if t.receiver == nil {
+		return []string{t.label}
+	}
+	return append([]string{}, t.streamIDs...)
+}
+
 // Msid gets the Msid of the track
 func (t *Track) Msid() string {
 	return t.Label() + " " + t.ID()
@@ -101,14 +149,42 @@ func (t *Track) Packetizer() rtp.Packetizer {
 	return t.packetizer
 }
 
-// Read reads data from the track. If this is a local track this will error
+// Read reads data from the track. If this is a local track this will error.
+// When RED is negotiated for its Opus codec, the RED packets are decoded, and
+// the Opus packets they carry are read instead, including the recovered ones.
 func (t *Track) Read(b []byte) (n int, err error) {
+	n, _, err = t.readPacket(b, nil)
+	return n, err
+}
+
+// readPacket is like Read. If packet isn't nil, parsed is true if the packet
+// read was unmarshaled into it on the way.
+func (t *Track) readPacket(b []byte, packet *rtp.Packet) (n int, parsed bool, err error) {
+	for {
+		var ok bool
+		if n, ok, err = t.readRED(b); ok {
+			return n, false, err
+		}
+
+		if n, parsed, err = t.read(b, packet); err != nil {
+			return n, false, err
+		}
+		if n, ok, err = t.decodeRED(b, n); ok || err != nil {
+			// A decoded RED packet is replaced by an Opus packet, which has
+			// another payload type
+			return n, parsed && n > 1 && packet.PayloadType == b[1]&0x7F, err
+		}
+	}
+}
+
+// read reads the next packet of the track, see readPacket
+func (t *Track) read(b []byte, packet *rtp.Packet) (n int, parsed bool, err error) {
 	t.mu.RLock()
 	r := t.receiver
 
 	if t.totalSenderCount != 0 || r == nil {
 		t.mu.RUnlock()
-		return 0, errTrackLocalTrackRead
+		return 0, false, errTrackLocalTrackRead
 	}
 	peeked := t.peeked != nil
 	t.mu.RUnlock()
@@ -126,7 +202,7 @@ func (t *Track) Read(b []byte) (n int, err error) {
 		}
 	}
 
-	return r.readRTP(b, t)
+	return r.readRTP(b, t, packet)
 }
 
 // peek is like Read, but it doesn't discard the packet read
@@ -162,6 +238,59 @@ func (t *Track) ReadRTP() (*rtp.Packet, error) {
 	return r, nil
 }
 
+// pooledPacket is a buffer for reading RTP packets and the packet read into
+// it, raw is the part of the buffer a receiveBuffer holds
+type pooledPacket struct {
+	buffer []byte
+	raw    []byte
+	packet rtp.Packet
+}
+
+// reset clears the packet before it is unmarshaled, as Unmarshal appends to
+// the extensions of the previous packet
+func (p *pooledPacket) reset() {
+	extensions := p.packet.Extensions[:0]
+	p.packet = rtp.Packet{}
+	p.packet.Extensions = extensions
+}
+
+// ReadRTPPooled is like ReadRTP, but the packet and the buffer it is read
+// into are reused across reads to avoid allocations. Once the packet has
+// been handled, release must be called and the packet, including its
+// payload, must not be used anymore.
+func (t *Track) ReadRTPPooled() (packet *rtp.Packet, release func(), err error) {
+	t.mu.RLock()
+	r := t.receiver
+	t.mu.RUnlock()
+	if r == nil {
+		return nil, nil, errTrackLocalTrackRead
+	}
+
+	pooled := r.api.rtpPacketPool.Get().(*pooledPacket)
+	release = func() {
+		r.api.rtpPacketPool.Put(pooled)
+	}
+
+	// The packet is only unmarshaled here if the RTPReceiver didn't
+	pooled.reset()
+	n, parsed, err := t.readPacket(pooled.buffer, &pooled.packet)
+	if err != nil {
+		release()
+		return nil, nil, err
+	}
+	if !parsed {
+		pooled.reset()
+		if err = pooled.packet.Unmarshal(pooled.buffer[:n]); err != nil {
+			release()
+			return nil, nil, err
+		}
+	}
+	if len(pooled.packet.Extensions) == 0 {
+		pooled.packet.Extensions = nil
+	}
+	return &pooled.packet, release, nil
+}
+
 // Write writes data to the track. If this is a remote track this will error
 func (t *Track) Write(b []byte) (n int, err error) {
 	packet := &rtp.Packet{}
@@ -178,10 +307,46 @@ func (t *Track) Write(b []byte) (n int, err error) {
 	return len(b), nil
 }
 
-// WriteSample packetizes and writes to the track
+// WriteSample packetizes and writes to the track. If the Samples of s is 0,
+// they are computed from its Duration and the clock rate of the codec. Its
+// Orientation and ColorSpace are sent with it and the following Samples.
+// The marker bit is set on the last packet of each Sample, unless it is a
+// PartialFrame.
 func (t *Track) WriteSample(s media.Sample) error {
-	packets := t.packetizer.Packetize(s.Data, s.Samples)
+	if err := t.setSampleExtensions(s); err != nil {
+		return err
+	}
+
+	timestamp, samples, err := t.frameTimestamp(s)
+	if err != nil {
+		return err
+	}
+	return t.writeSample(s, timestamp, samples)
+}
+
+// setSampleExtensions sets the Orientation and ColorSpace of s, if any
+func (t *Track) setSampleExtensions(s media.Sample) error {
+	if s.Orientation != nil {
+		if err := t.SetVideoOrientation(*s.Orientation); err != nil {
+			return err
+		}
+	}
+	if s.ColorSpace != nil {
+		if err := t.SetColorSpace(*s.ColorSpace); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeSample packetizes and writes s with timestamp
+func (t *Track) writeSample(s media.Sample, timestamp, samples uint32) error {
+	packets := t.packetizer.Packetize(s.Data, samples)
 	for _, p := range packets {
+		p.Timestamp = timestamp
+		if s.PartialFrame {
+			p.Marker = false
+		}
 		err := t.WriteRTP(p)
 		if err != nil {
 			return err
@@ -191,6 +356,52 @@ func (t *Track) WriteSample(s media.Sample) error {
 	return nil
 }
 
+// frameTimestamp returns the timestamp and samples of s. A Sample following a
+// PartialFrame continues its frame, with the same timestamp. The timestamp is
+// taken and recorded at once, so concurrent writes of a frame share it.
+func (t *Track) frameTimestamp(s media.Sample) (uint32, uint32, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	continued := t.partialFrame
+	t.partialFrame = s.PartialFrame
+	if continued {
+		return t.partialFrameTimestamp, 0, nil
+	}
+
+	if t.timestamps == nil {
+		return 0, 0, errTrackLocalTrackWrite
+	}
+
+	samples := s.Samples
+	if samples == 0 && s.Duration > 0 {
+		samples = t.countSamples(s.Duration)
+	}
+	t.partialFrameTimestamp = t.timestamps.NextTimestamp(samples)
+	return t.partialFrameTimestamp, samples, nil
+}
+
+// durationSamples returns the number of samples in media of length d,
+// carrying the fractions of samples over between calls
+func (t *Track) durationSamples(d time.Duration) uint32 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.countSamples(d)
+}
+
+// countSamples is durationSamples, t.mu must be held
+func (t *Track) countSamples(d time.Duration) uint32 {
+	clockRate := uint32(0)
+	if t.codec != nil {
+		clockRate = t.codec.ClockRate
+	}
+	if t.sampleCounter == nil || t.sampleCounter.ClockRate() != clockRate {
+		t.sampleCounter = media.NewSampleCounter(clockRate)
+	}
+	return t.sampleCounter.Samples(d)
+}
+
 // WriteRTP writes RTP packets to the track
 func (t *Track) WriteRTP(p *rtp.Packet) error {
 	t.mu.RLock()
@@ -218,28 +429,40 @@ func (t *Track) WriteRTP(p *rtp.Packet) error {
 
 // NewTrack initializes a new *Track
 func NewTrack(payloadType uint8, ssrc uint32, id, label string, codec *RTPCodec) (*Track, error) {
+	// RED keeps the previous packets of a Track
+	payloader := codec.Payloader
+	if redPayloader, ok := payloader.(*red.Payloader); ok {
+		payloader = redPayloader.Clone()
+	}
+
+	return newTrack(payloadType, ssrc, id, label, codec, payloader)
+}
+
+// newTrack initializes a new *Track packetizing with payloader
+func newTrack(payloadType uint8, ssrc uint32, id, label string, codec *RTPCodec, payloader rtp.Payloader) (*Track, error) {
 	if ssrc == 0 {
 		return nil, errTrackSSRCNewTrackZero
 	}
 
-	packetizer := rtp.NewPacketizer(
-		rtpOutboundMTU,
-		payloadType,
-		ssrc,
-		codec.Payloader,
-		rtp.NewRandomSequencer(),
-		codec.ClockRate,
-	)
-
-	return &Track{
+	t := &Track{
 		id:          id,
 		payloadType: payloadType,
 		kind:        codec.Type,
 		label:       label,
 		ssrc:        ssrc,
 		codec:       codec,
-		packetizer:  packetizer,
-	}, nil
+		sequencer:   rtp.NewRandomSequencer(),
+		timestamps:  NewTimestampGenerator(util.RandUint32()),
+	}
+	t.packetizer = rtp.NewPacketizer(
+		rtpOutboundMTU,
+		payloadType,
+		ssrc,
+		payloader,
+		&trackSequencer{track: t},
+		codec.ClockRate,
+	)
+	return t, nil
 }
 
 // determinePayloadType blocks and reads a single packet to determine the PayloadType for this Track
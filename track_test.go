@@ -3,9 +3,14 @@
 package webrtc
 
 import (
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/pion/randutil"
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3/pkg/interceptor"
+	"github.com/pion/webrtc/v3/pkg/media"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -128,3 +133,230 @@ func TestTrackReadWhenNotAdded(t *testing.T) {
 	_, err = track.Read([]byte{})
 	assert.Error(t, err)
 }
+
+func TestNewTrackRed(t *testing.T) {
+	codec := NewRTPRedCodec(63, 48000, DefaultPayloadTypeOpus, 1)
+	frame := []byte{0xF8, 0x01, 0x02}
+
+	first, err := NewTrack(63, 1, "first", "first", codec)
+	assert.NoError(t, err)
+	second, err := NewTrack(63, 2, "second", "second", codec)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 1, len(first.packetizer.Packetize(frame, 960)))
+	packets := first.packetizer.Packetize(frame, 960)
+	assert.Equal(t, uint8(63), packets[0].PayloadType)
+	assert.Equal(t, 4+1+2*len(frame), len(packets[0].Payload))
+
+	// Every Track has its own previous packets
+	packets = second.packetizer.Packetize(frame, 960)
+	assert.Equal(t, append([]byte{DefaultPayloadTypeOpus}, frame...), packets[0].Payload)
+}
+
+func TestTrackReadRTPPooled(t *testing.T) {
+	api := NewAPI()
+	receiver := &RTPReceiver{api: api}
+
+	packet := &rtp.Packet{Header: rtp.Header{Version: 2, SequenceNumber: 5000, Timestamp: 3000, SSRC: 1234}, Payload: []byte{0x01, 0x02}}
+	assert.NoError(t, packet.SetExtension(1, []byte{0xAA}))
+	raw, err := packet.Marshal()
+	assert.NoError(t, err)
+
+	track := &Track{receiver: receiver, peeked: raw}
+	pooled, release, err := track.ReadRTPPooled()
+	assert.NoError(t, err)
+	assert.Equal(t, packet.Header.SequenceNumber, pooled.SequenceNumber)
+	assert.Equal(t, []byte{0xAA}, pooled.GetExtension(1))
+	assert.Equal(t, packet.Payload, pooled.Payload)
+	release()
+
+	// Reused packets don't keep the extensions of the previous packet
+	packet = &rtp.Packet{Header: rtp.Header{Version: 2, SequenceNumber: 5001, Timestamp: 3000, SSRC: 1234}, Payload: []byte{0x03}}
+	raw, err = packet.Marshal()
+	assert.NoError(t, err)
+
+	track.peeked = raw
+	pooled, release, err = track.ReadRTPPooled()
+	assert.NoError(t, err)
+	assert.Equal(t, packet.SequenceNumber, pooled.SequenceNumber)
+	assert.False(t, pooled.Extension)
+	assert.Empty(t, pooled.Extensions)
+	assert.Equal(t, packet.Payload, pooled.Payload)
+	release()
+
+	// The packet unmarshaled by the RTPReceiver is returned
+	packet = &rtp.Packet{Header: rtp.Header{Version: 2, SequenceNumber: 5002, Timestamp: 3000, SSRC: 1234}, Payload: []byte{0x04}}
+	assert.NoError(t, packet.SetExtension(1, []byte{0xBB}))
+	raw, err = packet.Marshal()
+	assert.NoError(t, err)
+
+	receiver.received = make(chan interface{})
+	close(receiver.received)
+	receiver.tracks = []trackStreams{{
+		track: track,
+		rtpInterceptor: interceptor.RTPReaderFunc(func(b []byte) (int, error) {
+			return copy(b, raw), nil
+		}),
+	}}
+	pooled, release, err = track.ReadRTPPooled()
+	assert.NoError(t, err)
+	assert.Equal(t, packet.SequenceNumber, pooled.SequenceNumber)
+	assert.Equal(t, []byte{0xBB}, pooled.GetExtension(1))
+	assert.Equal(t, packet.Payload, pooled.Payload)
+	assert.Equal(t, uint64(1), receiver.tracks[0].stats.received)
+	release()
+
+	_, _, err = (&Track{}).ReadRTPPooled()
+	assert.Equal(t, errTrackLocalTrackRead, err)
+}
+
+// BenchmarkTrackWriteRTP measures the fan-out of WriteRTP to the RTPSenders of a Track.
+// The senders discard the packets after the interceptors, so SRTP isn't included.
+func BenchmarkTrackWriteRTP(b *testing.B) {
+	for _, senderCount := range []int{1, 100, 10000} {
+		senderCount := senderCount
+		b.Run(fmt.Sprintf("senders=%d", senderCount), func(b *testing.B) {
+			track, err := NewTrack(DefaultPayloadTypeVP8, 1234, "video", "pion", NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000))
+			assert.NoError(b, err)
+
+			api := NewAPI()
+			discard := interceptor.RTPWriterFunc(func(header *rtp.Header, payload []byte) (int, error) {
+				return header.MarshalSize() + len(payload), nil
+			})
+			for i := 0; i < senderCount; i++ {
+				sender := &RTPSender{
+					track:      track,
+					api:        api,
+					sendCalled: make(chan interface{}),
+					stopCalled: make(chan interface{}),
+				}
+				sender.streamInfo = createStreamInfo(track.ID(), track.SSRC(), track.PayloadType(), track.Codec())
+				sender.rtpWriter = api.interceptor.BindLocalStream(&sender.streamInfo, discard)
+				close(sender.sendCalled)
+
+				track.activeSenders = append(track.activeSenders, sender)
+				track.totalSenderCount++
+			}
+
+			packet := &rtp.Packet{
+				Header:  rtp.Header{Version: 2, SSRC: 1234, PayloadType: DefaultPayloadTypeVP8},
+				Payload: make([]byte, rtpOutboundMTU),
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				packet.SequenceNumber++
+				if err := track.WriteRTP(packet); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestTrackDurationSamples(t *testing.T) {
+	track := &Track{codec: NewRTPOpusCodec(DefaultPayloadTypeOpus, 22050)}
+
+	// 10ms at 22050Hz is 220.5 samples, the carried fractions make up for
+	// the truncated half samples
+	total := uint32(0)
+	for i := 0; i < 100; i++ {
+		total += track.durationSamples(10 * time.Millisecond)
+	}
+	assert.Equal(t, uint32(22050), total)
+}
+
+func TestTrackFrameTimestamp(t *testing.T) {
+	track := &Track{timestamps: NewTimestampGenerator(1000)}
+
+	// Concurrent slices of a frame all get the timestamp of its first slice
+	type frameTimestamp struct{ timestamp, samples uint32 }
+	results := make(chan frameTimestamp, 10)
+	for i := 0; i < 10; i++ {
+		go func() {
+			timestamp, samples, err := track.frameTimestamp(media.Sample{Samples: 3000, PartialFrame: true})
+			assert.NoError(t, err)
+			results <- frameTimestamp{timestamp, samples}
+		}()
+	}
+
+	frames := 0
+	for i := 0; i < 10; i++ {
+		result := <-results
+		assert.Equal(t, uint32(1000), result.timestamp)
+		if result.samples != 0 {
+			frames++
+		}
+	}
+	assert.Equal(t, 1, frames)
+
+	timestamp, samples, err := track.frameTimestamp(media.Sample{Samples: 3000})
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(1000), timestamp)
+	assert.Equal(t, uint32(0), samples)
+
+	timestamp, _, err = track.frameTimestamp(media.Sample{Samples: 3000})
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(4000), timestamp)
+}
+
+func TestTrackWriteSamplePartialFrame(t *testing.T) {
+	pcOffer, pcAnswer, err := newPair()
+	assert.NoError(t, err)
+
+	track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, 5000, "video", "pion")
+	assert.NoError(t, err)
+	_, err = pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+	assert.NoError(t, track.SetTimestampGenerator(NewTimestampGenerator(0)))
+
+	received := make(chan *rtp.Packet, 16)
+	pcAnswer.OnTrack(func(track *Track, r *RTPReceiver) {
+		for {
+			packet, readErr := track.ReadRTP()
+			if readErr != nil {
+				return
+			}
+			select {
+			case received <- packet:
+			default:
+			}
+		}
+	})
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	// Frames of three slices are written until one is received in full
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-time.After(20 * time.Millisecond):
+				assert.NoError(t, track.WriteSample(media.Sample{Data: []byte{0x01}, Samples: 3000, PartialFrame: true}))
+				assert.NoError(t, track.WriteSample(media.Sample{Data: []byte{0x02}, Samples: 3000, PartialFrame: true}))
+				assert.NoError(t, track.WriteSample(media.Sample{Data: []byte{0x03}, Samples: 3000}))
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	slices := []*rtp.Packet{}
+	for len(slices) < 3 {
+		packet := <-received
+		if packet.Payload[len(packet.Payload)-1] == 0x01 {
+			slices = slices[:0]
+		}
+		slices = append(slices, packet)
+	}
+	close(done)
+
+	assert.False(t, slices[0].Marker)
+	assert.False(t, slices[1].Marker)
+	assert.True(t, slices[2].Marker)
+	assert.Equal(t, slices[0].Timestamp, slices[2].Timestamp)
+	assert.Equal(t, uint32(0), slices[0].Timestamp%3000)
+
+	closePairNow(t, pcOffer, pcAnswer)
+}
@@ -0,0 +1,203 @@
+// +build !js
+
+package webrtc
+
+import (
+	"encoding/binary"
+	"time"
+
+	"github.com/pion/rtcp"
+)
+
+// TrackEndedReason tells why a remote Track ended, see Track.OnEnded.
+type TrackEndedReason int
+
+const (
+	// TrackEndedReasonBye indicates the remote sent an RTCP BYE for the
+	// SSRC of the Track, like when the participant left.
+	TrackEndedReasonBye TrackEndedReason = iota + 1
+
+	// TrackEndedReasonTimeout indicates no RTP arrived for the Track for
+	// the timeout set with WithEndedTimeout.
+	TrackEndedReasonTimeout
+)
+
+// This is done this way because of a linter.
+const (
+	trackEndedReasonByeStr     = "bye"
+	trackEndedReasonTimeoutStr = "timeout"
+)
+
+func (r TrackEndedReason) String() string {
+	switch r {
+	case TrackEndedReasonBye:
+		return trackEndedReasonByeStr
+	case TrackEndedReasonTimeout:
+		return trackEndedReasonTimeoutStr
+	default:
+		return ErrUnknownType.Error()
+	}
+}
+
+// WithEndedTimeout ends the Tracks of the RTPReceiver once no RTP arrived
+// for them for timeout, after the first packet arrived, see Track.OnEnded.
+// The RTP doesn't have to be read. By default Tracks only end with an RTCP
+// BYE.
+func WithEndedTimeout(timeout time.Duration) RTPReceiverOption {
+	return func(r *RTPReceiver) {
+		r.endedTimeout = timeout
+	}
+}
+
+// OnEnded sets an event handler which is called when a remote Track ends, as
+// the remote sent an RTCP BYE for it, or no RTP arrived for it for the
+// timeout set with WithEndedTimeout. Reading the Track then returns io.EOF
+// instead of blocking. BYE is learned from RTCP as it arrives.
+func (t *Track) OnEnded(f func(reason TrackEndedReason)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onEnded = f
+}
+
+// Ended returns why the remote Track ended, false if it didn't
+func (t *Track) Ended() (TrackEndedReason, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.endedReason, t.endedReason != 0
+}
+
+// watchArrival restarts the timeouts of t, and unmutes it, whenever its RTP
+// arrives
+func (r *RTPReceiver) watchArrival(t *trackStreams) {
+	if ssrc := t.track.SSRC(); ssrc != 0 {
+		r.transport.onRTPArrival(ssrc, func() {
+			r.trackActivity(t)
+		})
+	}
+}
+
+// trackActivity restarts the timeouts of t after its RTP arrived, and
+// unmutes it. It is called for every packet before it is read, so the
+// handlers of the changes are called on their own goroutine.
+func (r *RTPReceiver) trackActivity(t *trackStreams) {
+	muted := r.mute.muted.get()
+	if r.endedTimeout <= 0 && r.muteTimeout <= 0 && !muted {
+		return
+	}
+
+	r.activityMu.Lock()
+	if t.ended {
+		r.activityMu.Unlock()
+		return
+	}
+	t.endedTimer = resetTimer(t.endedTimer, r.endedTimeout, func() {
+		r.endTrack(t, TrackEndedReasonTimeout)
+	})
+	t.muteTimer = resetTimer(t.muteTimer, r.muteTimeout, func() {
+		r.muteTrack(t)
+	})
+	handler := t.track.setInactive(false)
+	r.activityMu.Unlock()
+
+	if handler == nil && !muted {
+		return
+	}
+	go func() {
+		if muted {
+			r.setMuted(false)
+		}
+		if handler != nil {
+			handler()
+		}
+	}()
+}
+
+// resetTimer restarts timer, or starts a timer calling f if it is nil, it
+// does nothing if timeout isn't positive
+func resetTimer(timer *time.Timer, timeout time.Duration, f func()) *time.Timer {
+	switch {
+	case timeout <= 0:
+		return timer
+	case timer == nil:
+		return time.AfterFunc(timeout, f)
+	default:
+		timer.Reset(timeout)
+		return timer
+	}
+}
+
+// stopEnded marks t as ended as the RTPReceiver stops, it returns whether t
+// had already ended
+func (r *RTPReceiver) stopEnded(t *trackStreams) bool {
+	r.activityMu.Lock()
+	defer r.activityMu.Unlock()
+
+	if !t.ended && r.transport != nil && t.track != nil {
+		r.transport.onRTPArrival(t.track.SSRC(), nil)
+	}
+
+	ended := t.ended
+	t.ended = true
+	for _, timer := range []*time.Timer{t.endedTimer, t.muteTimer} {
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+	return ended
+}
+
+// endTrack ends t, its RTP stream is closed so its readers return io.EOF
+func (r *RTPReceiver) endTrack(t *trackStreams, reason TrackEndedReason) {
+	if r.stopEnded(t) {
+		return
+	}
+
+	if t.rtpReadStream != nil {
+		if err := t.rtpReadStream.Close(); err == nil {
+			r.api.interceptor.UnbindRemoteStream(&t.streamInfo)
+		}
+	}
+
+	t.track.mu.Lock()
+	t.track.endedReason = reason
+	handler := t.track.onEnded
+	t.track.mu.Unlock()
+
+	if handler != nil {
+		handler(reason)
+	}
+}
+
+// handleBye ends the Tracks of the SSRCs of the RTCP BYE packets read by the
+// RTPReceiver
+func (r *RTPReceiver) handleBye(b []byte) {
+	for len(b) >= 4 {
+		length := (int(binary.BigEndian.Uint16(b[2:])) + 1) * 4
+		if length > len(b) {
+			return
+		}
+		if rtcp.PacketType(b[1]) == rtcp.TypeGoodbye {
+			count := int(b[0] & 0x1F)
+			for i := 0; i < count && 8+i*4 <= length; i++ {
+				r.endTrackWithSSRC(binary.BigEndian.Uint32(b[4+i*4:]))
+			}
+		}
+		b = b[length:]
+	}
+}
+
+// endTrackWithSSRC ends the Track with ssrc after a BYE
+func (r *RTPReceiver) endTrackWithSSRC(ssrc uint32) {
+	r.mu.RLock()
+	var t *trackStreams
+	for i := range r.tracks {
+		if r.tracks[i].track != nil && r.tracks[i].track.SSRC() == ssrc {
+			t = &r.tracks[i]
+		}
+	}
+	r.mu.RUnlock()
+
+	if t != nil {
+		r.endTrack(t, TrackEndedReasonBye)
+	}
+}
@@ -0,0 +1,158 @@
+// +build !js
+
+package webrtc
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/transport/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRTPReceiver_handleBye(t *testing.T) {
+	track := &Track{ssrc: 5000}
+	r := &RTPReceiver{tracks: []trackStreams{{track: track}}}
+	track.receiver = r
+
+	reasons := []TrackEndedReason{}
+	track.OnEnded(func(reason TrackEndedReason) {
+		reasons = append(reasons, reason)
+	})
+
+	b, err := rtcp.Marshal([]rtcp.Packet{
+		&rtcp.ReceiverReport{SSRC: 1},
+		&rtcp.Goodbye{Sources: []uint32{4000}},
+	})
+	assert.NoError(t, err)
+	r.handleBye(b)
+	_, ended := track.Ended()
+	assert.False(t, ended)
+
+	b, err = rtcp.Marshal([]rtcp.Packet{
+		&rtcp.ReceiverReport{SSRC: 1},
+		&rtcp.Goodbye{Sources: []uint32{4000, 5000}},
+	})
+	assert.NoError(t, err)
+	r.handleBye(b)
+	r.handleBye(b)
+	reason, ended := track.Ended()
+	assert.True(t, ended)
+	assert.Equal(t, TrackEndedReasonBye, reason)
+	assert.Equal(t, []TrackEndedReason{TrackEndedReasonBye}, reasons)
+	assert.Equal(t, "bye", reason.String())
+}
+
+func TestTrack_OnEndedTimeout(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	s := SettingEngine{}
+	s.SetRTPReceiverOptions(WithEndedTimeout(200 * time.Millisecond))
+	m := MediaEngine{}
+	m.RegisterDefaultCodecs()
+	pcAnswer, err := NewAPI(WithMediaEngine(m), WithSettingEngine(s)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	pcOffer, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, 5000, "video", "pion")
+	assert.NoError(t, err)
+	_, err = pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+
+	onTrack := make(chan struct{})
+	onEnded := make(chan TrackEndedReason, 1)
+	readErr := make(chan error, 1)
+	pcAnswer.OnTrack(func(track *Track, _ *RTPReceiver) {
+		track.OnEnded(func(reason TrackEndedReason) {
+			onEnded <- reason
+		})
+		close(onTrack)
+		for {
+			if _, err := track.ReadRTP(); err != nil {
+				readErr <- err
+				return
+			}
+		}
+	})
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	sendVideoUntilDone(onTrack, t, []*Track{track})
+
+	// The reader blocked by the silence is woken up
+	assert.Equal(t, TrackEndedReasonTimeout, <-onEnded)
+	assert.Equal(t, io.EOF, <-readErr)
+
+	closePairNow(t, pcOffer, pcAnswer)
+}
+
+func TestTrack_OnEndedUnread(t *testing.T) {
+	lim := test.TimeOut(time.Second * 20)
+	defer lim.Stop()
+
+	report := test.CheckRoutines(t)
+	defer report()
+
+	s := SettingEngine{}
+	s.SetRTPReceiverOptions(WithEndedTimeout(200 * time.Millisecond))
+	m := MediaEngine{}
+	m.RegisterDefaultCodecs()
+	pcAnswer, err := NewAPI(WithMediaEngine(m), WithSettingEngine(s)).NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+	pcOffer, err := NewPeerConnection(Configuration{})
+	assert.NoError(t, err)
+
+	track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, 5000, "video", "pion")
+	assert.NoError(t, err)
+	sender, err := pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+
+	// Neither RTP nor RTCP is read by the application
+	onTrack := make(chan struct{})
+	onEnded := make(chan TrackEndedReason, 1)
+	pcAnswer.OnTrack(func(track *Track, _ *RTPReceiver) {
+		track.OnEnded(func(reason TrackEndedReason) {
+			onEnded <- reason
+		})
+		close(onTrack)
+	})
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+	sendVideoUntilDone(onTrack, t, []*Track{track})
+
+	// RTP arriving keeps the Track from timing out
+	sending := make(chan struct{})
+	time.AfterFunc(600*time.Millisecond, func() {
+		close(sending)
+	})
+	sendVideoUntilDone(sending, t, []*Track{track})
+	select {
+	case reason := <-onEnded:
+		t.Fatalf("Track ended while RTP arrived: %s", reason)
+	default:
+	}
+
+	// A BYE ends the Track, it's resent in case it's lost
+	ticker := time.NewTicker(20 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		assert.NoError(t, sender.WriteRTCP([]rtcp.Packet{
+			&rtcp.SenderReport{SSRC: track.SSRC()},
+			&rtcp.Goodbye{Sources: []uint32{track.SSRC()}},
+		}))
+
+		select {
+		case reason := <-onEnded:
+			assert.Equal(t, TrackEndedReasonBye, reason)
+			closePairNow(t, pcOffer, pcAnswer)
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,273 @@
+// +build !js
+
+package webrtc
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3/pkg/colorspace"
+	"github.com/pion/webrtc/v3/pkg/videoorientation"
+	"github.com/pion/webrtc/v3/pkg/videotiming"
+)
+
+// PlayoutDelayURI is the URI of the playout delay header extension. Once it
+// is added with SettingEngine.AddSDPExtensions and negotiated, the playout
+// delay set with Track.SetPlayoutDelay is requested from the receivers, and
+// the one of remote Tracks is returned by Track.PlayoutDelay. The timings of
+// remote Tracks are passed to Track.OnVideoTiming once videotiming.URI is
+// negotiated the same way, and videoorientation.URI and colorspace.URI send
+// and receive the orientation and color space of Tracks.
+const PlayoutDelayURI = "http://www.webrtc.org/experiments/rtp-hdrext/playout-delay"
+
+const (
+	// playoutDelayGranularity is the unit of the playout delays, which have
+	// 12 bits
+	playoutDelayGranularity = 10 * time.Millisecond
+	playoutDelayMax         = 0xFFF * playoutDelayGranularity
+	playoutDelayLength      = 3
+)
+
+// playoutDelay is the playout delay of a Track, requested by the sender
+type playoutDelay struct {
+	set      bool
+	min, max time.Duration
+}
+
+// SetPlayoutDelay requests the receivers of the Track to play it out after
+// a delay between min and max, up to 40.95s with a granularity of 10ms. A min
+// and max of 0 ask to play frames as soon as possible, as for cloud gaming.
+func (t *Track) SetPlayoutDelay(min, max time.Duration) error {
+	if min < 0 || max < min || max > playoutDelayMax {
+		return errTrackPlayoutDelayInvalid
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.receiver != nil {
+		return errTrackExtensionRemoteTrack
+	}
+	t.playoutDelay = playoutDelay{set: true, min: min, max: max}
+	return nil
+}
+
+// PlayoutDelay returns the playout delay set with SetPlayoutDelay, or for a
+// remote Track the last one requested by the remote peer. ok is false if
+// there is none.
+func (t *Track) PlayoutDelay() (min, max time.Duration, ok bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.playoutDelay.min, t.playoutDelay.max, t.playoutDelay.set
+}
+
+// OnVideoTiming sets an event handler which is called with the video-timing
+// extension of each packet read from a remote Track that has one, and its RTP
+// timestamp. It is called while the Track is read.
+func (t *Track) OnVideoTiming(f func(timing videotiming.Extension, timestamp uint32)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onVideoTiming = f
+}
+
+// SetVideoOrientation sets the orientation of the frames of the Track, which
+// is sent with the last packet of each frame. WriteSample sets it from the
+// Orientation of Samples.
+func (t *Track) SetVideoOrientation(orientation videoorientation.Extension) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.receiver != nil {
+		return errTrackExtensionRemoteTrack
+	}
+	t.orientation = &orientation
+	return nil
+}
+
+// VideoOrientation returns the orientation set with SetVideoOrientation, or
+// for a remote Track the last one sent by the remote peer. ok is false if
+// there is none.
+func (t *Track) VideoOrientation() (orientation videoorientation.Extension, ok bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.orientation == nil {
+		return orientation, false
+	}
+	return *t.orientation, true
+}
+
+// SetColorSpace sets the color space of the frames of the Track, which is
+// sent with the last packet of each frame. WriteSample sets it from the
+// ColorSpace of Samples.
+func (t *Track) SetColorSpace(colorSpace colorspace.Extension) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.receiver != nil {
+		return errTrackExtensionRemoteTrack
+	}
+	t.colorSpace = &colorSpace
+	return nil
+}
+
+// ColorSpace returns the color space set with SetColorSpace, or for a remote
+// Track the last one sent by the remote peer. ok is false if there is none.
+func (t *Track) ColorSpace() (colorSpace colorspace.Extension, ok bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.colorSpace == nil {
+		return colorSpace, false
+	}
+	return *t.colorSpace, true
+}
+
+// trackExtensionSender stamps the playout delay, orientation and color space
+// of the Track on the packets of an RTPSender
+type trackExtensionSender struct {
+	mu             sync.Mutex
+	playoutDelayID uint8
+	orientationID  uint8
+	colorSpaceID   uint8
+}
+
+func (e *trackExtensionSender) setExtensionIDs(playoutDelayID, orientationID, colorSpaceID uint8) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.playoutDelayID, e.orientationID, e.colorSpaceID = playoutDelayID, orientationID, colorSpaceID
+}
+
+// stamp returns the header with the playout delay of track, and its
+// orientation and color space on the last packet of a frame, or header if it
+// has none. The header of the caller isn't modified.
+func (e *trackExtensionSender) stamp(header *rtp.Header, track *Track) *rtp.Header {
+	e.mu.Lock()
+	playoutDelayID, orientationID, colorSpaceID := e.playoutDelayID, e.orientationID, e.colorSpaceID
+	e.mu.Unlock()
+	if (playoutDelayID == 0 && orientationID == 0 && colorSpaceID == 0) || track == nil {
+		return header
+	}
+
+	track.mu.RLock()
+	delay, orientation, colorSpace := track.playoutDelay, track.orientation, track.colorSpace
+	track.mu.RUnlock()
+
+	extensions := map[uint8][]byte{}
+	if colorSpaceID != 0 && colorSpace != nil && header.Marker {
+		if payload, err := colorSpace.Marshal(); err == nil {
+			extensions[colorSpaceID] = payload
+		}
+	}
+	if orientationID != 0 && orientation != nil && header.Marker {
+		if payload, err := orientation.Marshal(); err == nil {
+			extensions[orientationID] = payload
+		}
+	}
+	if playoutDelayID != 0 && delay.set {
+		delays := uint32(delay.min/playoutDelayGranularity)<<12 | uint32(delay.max/playoutDelayGranularity)
+		extensions[playoutDelayID] = []byte{byte(delays >> 16), byte(delays >> 8), byte(delays)}
+	}
+	if len(extensions) == 0 {
+		return header
+	}
+
+	stamped := *header
+	stamped.Extensions = append([]rtp.Extension{}, header.Extensions...)
+	// The color space is set first, with HDR metadata it needs the two byte
+	// header format which is only chosen by the first extension
+	for _, id := range []uint8{colorSpaceID, orientationID, playoutDelayID} {
+		if payload, ok := extensions[id]; ok {
+			if err := stamped.SetExtension(id, payload); err != nil {
+				return header
+			}
+		}
+	}
+	return &stamped
+}
+
+// trackExtensionReceiver passes the playout delay, video timing, orientation
+// and color space of the packets read by an RTPReceiver to their Track
+type trackExtensionReceiver struct {
+	mu             sync.Mutex
+	playoutDelayID uint8
+	videoTimingID  uint8
+	orientationID  uint8
+	colorSpaceID   uint8
+}
+
+func (e *trackExtensionReceiver) setExtensionIDs(playoutDelayID, videoTimingID, orientationID, colorSpaceID uint8) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.playoutDelayID, e.videoTimingID = playoutDelayID, videoTimingID
+	e.orientationID, e.colorSpaceID = orientationID, colorSpaceID
+}
+
+// handleTrackExtensions updates the playout delay, orientation and color
+// space of track, and calls its OnVideoTiming handler, if packet, which was
+// read from it, has the extensions
+func (r *RTPReceiver) handleTrackExtensions(packet *rtp.Packet, track *Track) {
+	r.trackExtensions.mu.Lock()
+	playoutDelayID, videoTimingID := r.trackExtensions.playoutDelayID, r.trackExtensions.videoTimingID
+	orientationID, colorSpaceID := r.trackExtensions.orientationID, r.trackExtensions.colorSpaceID
+	r.trackExtensions.mu.Unlock()
+	if playoutDelayID == 0 && videoTimingID == 0 && orientationID == 0 && colorSpaceID == 0 {
+		return
+	}
+
+	if playoutDelayID != 0 {
+		if payload := packet.GetExtension(playoutDelayID); len(payload) >= playoutDelayLength {
+			delays := uint32(payload[0])<<16 | uint32(payload[1])<<8 | uint32(payload[2])
+			track.mu.Lock()
+			track.playoutDelay = playoutDelay{
+				set: true,
+				min: time.Duration(delays>>12) * playoutDelayGranularity,
+				max: time.Duration(delays&0xFFF) * playoutDelayGranularity,
+			}
+			track.mu.Unlock()
+		}
+	}
+
+	if orientationID != 0 {
+		orientation := &videoorientation.Extension{}
+		if payload := packet.GetExtension(orientationID); payload != nil && orientation.Unmarshal(payload) == nil {
+			track.mu.Lock()
+			track.orientation = orientation
+			track.mu.Unlock()
+		}
+	}
+
+	if colorSpaceID != 0 {
+		colorSpace := &colorspace.Extension{}
+		if payload := packet.GetExtension(colorSpaceID); payload != nil && colorSpace.Unmarshal(payload) == nil {
+			track.mu.Lock()
+			track.colorSpace = colorSpace
+			track.mu.Unlock()
+		}
+	}
+
+	if videoTimingID != 0 {
+		track.mu.RLock()
+		handler := track.onVideoTiming
+		track.mu.RUnlock()
+		if handler == nil {
+			return
+		}
+
+		timing := videotiming.Extension{}
+		if payload := packet.GetExtension(videoTimingID); payload != nil && timing.Unmarshal(payload) == nil {
+			handler(timing, packet.Timestamp)
+		}
+	}
+}
+
+// HeaderExtensionID returns the ID of the header extension uri negotiated for
+// the RTPReceiver, or 0 if it isn't. It is needed to read extensions from
+// packets, like with the options of the SampleBuilder.
+func (r *RTPReceiver) HeaderExtensionID(uri string) uint8 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.headerExtensions[uri]
+}
+
+func (r *RTPReceiver) setHeaderExtensions(extensions map[string]uint8) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.headerExtensions = extensions
+}
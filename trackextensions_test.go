@@ -0,0 +1,117 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3/pkg/colorspace"
+	"github.com/pion/webrtc/v3/pkg/media"
+	"github.com/pion/webrtc/v3/pkg/videoorientation"
+	"github.com/pion/webrtc/v3/pkg/videotiming"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrack_SetPlayoutDelay(t *testing.T) {
+	track := &Track{}
+	_, _, ok := track.PlayoutDelay()
+	assert.False(t, ok)
+
+	assert.Equal(t, errTrackPlayoutDelayInvalid, track.SetPlayoutDelay(-time.Millisecond, 0))
+	assert.Equal(t, errTrackPlayoutDelayInvalid, track.SetPlayoutDelay(time.Second, 0))
+	assert.Equal(t, errTrackPlayoutDelayInvalid, track.SetPlayoutDelay(0, time.Minute))
+	assert.NoError(t, track.SetPlayoutDelay(0, 0))
+
+	min, max, ok := track.PlayoutDelay()
+	assert.True(t, ok)
+	assert.Equal(t, time.Duration(0), min)
+	assert.Equal(t, time.Duration(0), max)
+
+	remote := &Track{receiver: &RTPReceiver{}}
+	assert.Equal(t, errTrackExtensionRemoteTrack, remote.SetPlayoutDelay(0, 0))
+}
+
+func TestTrackExtensions(t *testing.T) {
+	local := &Track{}
+	assert.NoError(t, local.SetPlayoutDelay(100*time.Millisecond, 2*time.Second))
+
+	// Nothing is stamped until the extension is negotiated
+	p := &trackExtensionSender{}
+	header := &rtp.Header{Version: 2, Timestamp: 1234}
+	assert.Equal(t, header, p.stamp(header, local))
+	p.setExtensionIDs(4, 0, 0)
+	stamped := p.stamp(header, local)
+	assert.False(t, header.Extension)
+	assert.Equal(t, []byte{0x00, 0xA0, 0xC8}, stamped.GetExtension(4))
+
+	timing := []byte{0x01, 0x00, 0x01, 0x00, 0x02, 0x00, 0x03, 0x00, 0x04, 0x00, 0x00, 0x00, 0x00}
+	assert.NoError(t, stamped.SetExtension(5, timing))
+	packet := &rtp.Packet{Header: *stamped}
+
+	r := &RTPReceiver{}
+	remote := &Track{receiver: r}
+	timings := []videotiming.Extension{}
+	remote.OnVideoTiming(func(timing videotiming.Extension, timestamp uint32) {
+		assert.Equal(t, uint32(1234), timestamp)
+		timings = append(timings, timing)
+	})
+
+	r.handleTrackExtensions(packet, remote)
+	_, _, ok := remote.PlayoutDelay()
+	assert.False(t, ok)
+
+	r.trackExtensions.setExtensionIDs(4, 5, 0, 0)
+	r.handleTrackExtensions(packet, remote)
+	min, max, ok := remote.PlayoutDelay()
+	assert.True(t, ok)
+	assert.Equal(t, 100*time.Millisecond, min)
+	assert.Equal(t, 2*time.Second, max)
+	assert.Equal(t, []videotiming.Extension{{
+		Flags:                    videotiming.FlagTriggeredByTimer,
+		EncodeStartDelta:         1,
+		EncodeFinishDelta:        2,
+		PacketizationFinishDelta: 3,
+		PacerExitDelta:           4,
+	}}, timings)
+}
+
+func TestTrackExtensions_OrientationColorSpace(t *testing.T) {
+	local := &Track{}
+	orientation := videoorientation.Extension{BackFacing: true, Rotation: videoorientation.Rotation270}
+	assert.NoError(t, local.SetVideoOrientation(orientation))
+	colorSpace := colorspace.Extension{
+		Primaries:   colorspace.PrimariesBT2020,
+		Transfer:    colorspace.TransferSMPTE2084,
+		Matrix:      colorspace.MatrixBT2020NCL,
+		HDRMetadata: &colorspace.HDRMetadata{LuminanceMax: 1000},
+	}
+	assert.NoError(t, local.SetColorSpace(colorSpace))
+
+	// They are only sent with the last packet of a frame, the color space
+	// with HDR metadata in the two byte header format
+	p := &trackExtensionSender{}
+	p.setExtensionIDs(0, 3, 20)
+	header := &rtp.Header{Version: 2, Timestamp: 1234}
+	assert.Equal(t, header, p.stamp(header, local))
+	header.Marker = true
+	stamped := p.stamp(header, local)
+	assert.Equal(t, []byte{0x0B}, stamped.GetExtension(3))
+	assert.Equal(t, 28, len(stamped.GetExtension(20)))
+	packet := &rtp.Packet{Header: *stamped}
+
+	r := &RTPReceiver{}
+	remote := &Track{receiver: r}
+	assert.Equal(t, errTrackExtensionRemoteTrack, remote.SetVideoOrientation(orientation))
+	assert.Equal(t, errTrackExtensionRemoteTrack, remote.WriteSample(media.Sample{Orientation: &orientation}))
+	r.trackExtensions.setExtensionIDs(0, 0, 3, 20)
+	r.handleTrackExtensions(packet, remote)
+
+	received, ok := remote.VideoOrientation()
+	assert.True(t, ok)
+	assert.Equal(t, orientation, received)
+	receivedColorSpace, ok := remote.ColorSpace()
+	assert.True(t, ok)
+	assert.Equal(t, colorSpace, receivedColorSpace)
+}
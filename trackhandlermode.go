@@ -0,0 +1,55 @@
+// +build !js
+
+package webrtc
+
+import (
+	"fmt"
+)
+
+// TrackHandlerMode decides on which goroutine the OnTrack handler of a
+// PeerConnection is called.
+type TrackHandlerMode int
+
+const (
+	// TrackHandlerModeGoroutine calls the handler of each track on a
+	// dedicated goroutine, so handlers may read their track until it ends.
+	// This is the default.
+	TrackHandlerModeGoroutine TrackHandlerMode = iota
+
+	// TrackHandlerModeDispatcher calls the handlers of all the tracks of a
+	// PeerConnection one after the other, in the order the tracks arrived, on
+	// a goroutine shared by the PeerConnection. Handlers should return
+	// quickly, and read their track on a goroutine of their own.
+	TrackHandlerModeDispatcher
+)
+
+// This is done this way because of a linter.
+const (
+	trackHandlerModeGoroutineStr  = "goroutine"
+	trackHandlerModeDispatcherStr = "dispatcher"
+)
+
+func (t TrackHandlerMode) String() string {
+	switch t {
+	case TrackHandlerModeGoroutine:
+		return trackHandlerModeGoroutineStr
+	case TrackHandlerModeDispatcher:
+		return trackHandlerModeDispatcherStr
+	default:
+		return ErrUnknownType.Error()
+	}
+}
+
+// callTrackHandler calls handler, a panic of it is recovered and passed to
+// onPanic if it is set
+func callTrackHandler(handler func(*Track, *RTPReceiver), t *Track, r *RTPReceiver, onPanic func(error)) {
+	if onPanic != nil {
+		defer func() {
+			if e := recover(); e != nil {
+				onPanic(fmt.Errorf("%w: %v", errTrackHandlerPanic, e))
+			}
+		}()
+	}
+
+	handler(t, r)
+}
@@ -0,0 +1,73 @@
+// +build !js
+
+package webrtc
+
+import (
+	"time"
+)
+
+// WithMuteTimeout mutes the Tracks of the RTPReceiver once no RTP arrived
+// for them for timeout, and unmutes them when RTP arrives again, like the
+// muted state of a MediaStreamTrack, see Track.OnMute. The timeout only
+// starts after the first packet arrived.
+func WithMuteTimeout(timeout time.Duration) RTPReceiverOption {
+	return func(r *RTPReceiver) {
+		r.muteTimeout = timeout
+	}
+}
+
+// OnMute sets an event handler which is called when a remote Track is muted
+// as no RTP arrived for it for the timeout set with WithMuteTimeout. Unlike
+// RTPReceiver.OnMute, it doesn't need the remote to signal it, so it also
+// fires when the remote stops sending, like when its camera is disabled.
+func (t *Track) OnMute(f func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onMute = f
+}
+
+// OnUnmute sets an event handler which is called when RTP arrives for a
+// remote Track again after it was muted, see OnMute
+func (t *Track) OnUnmute(f func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onUnmute = f
+}
+
+// Muted tells if a remote Track is muted, see OnMute
+func (t *Track) Muted() bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.inactive
+}
+
+// setInactive sets the muted state of the Track, it returns the handler of
+// the change, or nil if the state didn't change
+func (t *Track) setInactive(inactive bool) func() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.inactive == inactive {
+		return nil
+	}
+	t.inactive = inactive
+	if inactive {
+		return t.onMute
+	}
+	return t.onUnmute
+}
+
+// muteTrack mutes t after its mute timeout
+func (r *RTPReceiver) muteTrack(t *trackStreams) {
+	r.activityMu.Lock()
+	if t.ended {
+		r.activityMu.Unlock()
+		return
+	}
+	handler := t.track.setInactive(true)
+	r.activityMu.Unlock()
+
+	if handler != nil {
+		handler()
+	}
+}
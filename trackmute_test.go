@@ -0,0 +1,44 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrack_OnMute(t *testing.T) {
+	r := &RTPReceiver{}
+	WithMuteTimeout(20 * time.Millisecond)(r)
+	track := &Track{receiver: r}
+	r.tracks = []trackStreams{{track: track}}
+
+	events := make(chan bool, 2)
+	track.OnMute(func() {
+		events <- true
+	})
+	track.OnUnmute(func() {
+		events <- false
+	})
+
+	// The timeout starts once RTP is read
+	r.trackActivity(&r.tracks[0])
+	assert.False(t, track.Muted())
+	assert.True(t, <-events)
+	assert.True(t, track.Muted())
+
+	r.trackActivity(&r.tracks[0])
+	assert.False(t, <-events)
+	assert.False(t, track.Muted())
+	assert.True(t, <-events)
+
+	// A stopped RTPReceiver doesn't mute its Tracks anymore
+	r.trackActivity(&r.tracks[0])
+	assert.False(t, <-events)
+	assert.False(t, r.stopEnded(&r.tracks[0]))
+	time.Sleep(50 * time.Millisecond)
+	assert.False(t, track.Muted())
+	assert.Empty(t, events)
+}
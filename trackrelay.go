@@ -0,0 +1,167 @@
+// +build !js
+
+package webrtc
+
+import (
+	"sync"
+
+	"github.com/pion/rtp"
+)
+
+// TrackRelay forwards the RTP packets of a remote Track to a local Track,
+// as done by a selective forwarding unit. The sequence numbers and
+// timestamps are rewritten so they continue when the source changes, and
+// the SSRC and payload type are those of the local Track. Header extensions
+// are removed, as their IDs are negotiated for each PeerConnection.
+//
+// All sources must use the codec of the local Track. A video source becomes
+// active with its first keyframe, until then the previous source is forwarded.
+type TrackRelay struct {
+	local *Track
+
+	mu      sync.Mutex
+	closed  bool
+	source  *relaySource
+	pending *relaySource
+	readers map[*Track]*relaySource
+
+	rewriter rtpRewriter
+}
+
+// relaySource is a remote Track read by a TrackRelay
+type relaySource struct {
+	track *Track
+
+	rewriteSource
+}
+
+// NewTrackRelay creates a TrackRelay that writes to local
+func NewTrackRelay(local *Track) (*TrackRelay, error) {
+	local.mu.RLock()
+	isRemote := local.receiver != nil
+	local.mu.RUnlock()
+	if isRemote {
+		return nil, errTrackRelayRemoteTrack
+	}
+
+	return &TrackRelay{
+		local:   local,
+		readers: map[*Track]*relaySource{},
+	}, nil
+}
+
+// SetSource switches the TrackRelay to remote. From now on remote is read by
+// the TrackRelay, and must not be read elsewhere. A keyframe is requested
+// for a video source, which is switched to once the keyframe is received.
+func (r *TrackRelay) SetSource(remote *Track) error {
+	remote.mu.RLock()
+	receiver := remote.receiver
+	remote.mu.RUnlock()
+	if receiver == nil {
+		return errTrackRelayLocalSource
+	}
+
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return errTrackRelayClosed
+	}
+
+	s, isReading := r.readers[remote]
+	if !isReading {
+		s = &relaySource{track: remote}
+		r.readers[remote] = s
+		go r.forward(s)
+	}
+	if s == r.source {
+		r.pending = nil
+		r.mu.Unlock()
+		return nil
+	}
+	r.pending = s
+	r.mu.Unlock()
+
+	return receiver.RequestKeyframe()
+}
+
+// Source returns the remote Track that is forwarded, or nil before the first
+// source becomes active
+func (r *TrackRelay) Source() *Track {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.source == nil {
+		return nil
+	}
+	return r.source.track
+}
+
+// Close stops forwarding. Sources are no longer read once their next packet
+// has been received.
+func (r *TrackRelay) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.closed = true
+	r.source = nil
+	r.pending = nil
+	return nil
+}
+
+// forward reads the packets of s until it is neither the source nor pending
+func (r *TrackRelay) forward(s *relaySource) {
+	for {
+		packet, release, err := s.track.ReadRTPPooled()
+		if err != nil {
+			r.mu.Lock()
+			delete(r.readers, s.track)
+			if r.source == s {
+				r.source = nil
+			}
+			if r.pending == s {
+				r.pending = nil
+			}
+			r.mu.Unlock()
+			return
+		}
+
+		isRead := r.relay(s, packet)
+		release()
+		if !isRead {
+			return
+		}
+	}
+}
+
+// relay writes a packet of s to the local Track, and returns false once s is
+// no longer read. Errors writing to the local Track, like when it has no
+// senders yet, drop the packet.
+func (r *TrackRelay) relay(s *relaySource, packet *rtp.Packet) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch {
+	case s == r.pending:
+		if s.track.Kind() == RTPCodecTypeVideo && !IsKeyframe(packet.Payload, r.local.Codec().MimeType) {
+			return true
+		}
+		r.source, r.pending = s, nil
+		r.rewriter.activate(&s.rewriteSource, &packet.Header, r.local.Codec().ClockRate)
+	case s != r.source:
+		delete(r.readers, s.track)
+		return false
+	}
+
+	// Packets reordered before the first packet can't be forwarded
+	if !r.rewriter.rewrite(&s.rewriteSource, &packet.Header) {
+		return true
+	}
+	packet.SSRC = r.local.SSRC()
+	packet.PayloadType = r.local.PayloadType()
+	packet.Extension = false
+	packet.ExtensionProfile = 0
+	packet.Extensions = nil
+
+	_ = r.local.WriteRTP(packet)
+	return true
+}
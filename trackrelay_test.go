@@ -0,0 +1,122 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/pion/rtp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrackRelay(t *testing.T) {
+	local, err := NewTrack(DefaultPayloadTypeVP8, 1234, "video", "pion", NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000))
+	assert.NoError(t, err)
+
+	remote := func(ssrc uint32) *Track {
+		return &Track{kind: RTPCodecTypeVideo, ssrc: ssrc, payloadType: 100, receiver: &RTPReceiver{}}
+	}
+	packet := func(sequenceNumber uint16, timestamp uint32, payload ...byte) *rtp.Packet {
+		p := &rtp.Packet{
+			Header: rtp.Header{
+				Version:        2,
+				SSRC:           5678,
+				PayloadType:    100,
+				SequenceNumber: sequenceNumber,
+				Timestamp:      timestamp,
+			},
+			Payload: payload,
+		}
+		assert.NoError(t, p.SetExtension(1, []byte{0x01}))
+		return p
+	}
+	keyframe := []byte{0x10, 0x00}
+	interframe := []byte{0x10, 0x01}
+
+	_, err = NewTrackRelay(remote(1))
+	assert.Equal(t, errTrackRelayRemoteTrack, err)
+
+	relay, err := NewTrackRelay(local)
+	assert.NoError(t, err)
+	assert.Equal(t, errTrackRelayLocalSource, relay.SetSource(local))
+
+	a := &relaySource{track: remote(1)}
+	b := &relaySource{track: remote(2)}
+	relay.pending = a
+
+	// The first source starts with a keyframe
+	p := packet(100, 9000, interframe...)
+	assert.True(t, relay.relay(a, p))
+	assert.Equal(t, uint32(5678), p.SSRC)
+	assert.Nil(t, relay.Source())
+
+	p = packet(101, 9000, keyframe...)
+	assert.True(t, relay.relay(a, p))
+	assert.Equal(t, rtp.Header{Version: 2, SSRC: 1234, PayloadType: DefaultPayloadTypeVP8, SequenceNumber: 101, Timestamp: 9000}, p.Header)
+	assert.Equal(t, a.track, relay.Source())
+
+	// The previous source is forwarded until the next one has a keyframe
+	relay.pending = b
+	p = packet(5000, 100, interframe...)
+	assert.True(t, relay.relay(b, p))
+	assert.Equal(t, uint32(5678), p.SSRC)
+
+	p = packet(102, 12000, interframe...)
+	assert.True(t, relay.relay(a, p))
+	assert.Equal(t, uint16(102), p.SequenceNumber)
+	assert.Equal(t, uint32(12000), p.Timestamp)
+
+	// Sequence numbers and timestamps continue
+	p = packet(5001, 100, keyframe...)
+	assert.True(t, relay.relay(b, p))
+	assert.Equal(t, uint16(103), p.SequenceNumber)
+	assert.True(t, p.Timestamp > 12000)
+	assert.Equal(t, b.track, relay.Source())
+	timestamp := p.Timestamp
+
+	p = packet(5003, 3100, interframe...)
+	assert.True(t, relay.relay(b, p))
+	assert.Equal(t, uint16(105), p.SequenceNumber)
+	assert.Equal(t, timestamp+3000, p.Timestamp)
+
+	// Packets from before the switch are dropped
+	p = packet(5000, 100, interframe...)
+	assert.True(t, relay.relay(b, p))
+	assert.Equal(t, uint32(5678), p.SSRC)
+
+	// The previous source isn't read anymore
+	relay.readers[a.track] = a
+	assert.False(t, relay.relay(a, packet(103, 15000, interframe...)))
+	assert.NotContains(t, relay.readers, a.track)
+
+	assert.NoError(t, relay.Close())
+	assert.False(t, relay.relay(b, packet(5004, 6100, interframe...)))
+	assert.Equal(t, errTrackRelayClosed, relay.SetSource(remote(3)))
+}
+
+func TestTrackRelay_SequenceNumberWrap(t *testing.T) {
+	local, err := NewTrack(DefaultPayloadTypeOpus, 1234, "audio", "pion", NewRTPOpusCodec(DefaultPayloadTypeOpus, 48000))
+	assert.NoError(t, err)
+
+	relay, err := NewTrackRelay(local)
+	assert.NoError(t, err)
+
+	a := &relaySource{track: &Track{kind: RTPCodecTypeAudio, ssrc: 1, payloadType: 100, receiver: &RTPReceiver{}}}
+	relay.pending = a
+
+	// Every packet is forwarded after the sequence numbers wrap around, even more than once
+	count := 1<<17 + 10
+	for i := 0; i < count; i++ {
+		p := &rtp.Packet{Header: rtp.Header{Version: 2, SSRC: 5678, SequenceNumber: uint16(60000 + i), Timestamp: uint32(i * 960)}}
+		assert.True(t, relay.relay(a, p))
+		if p.SSRC != 1234 || p.SequenceNumber != uint16(60000+i) {
+			t.Fatalf("packet %d wasn't forwarded", i)
+		}
+	}
+
+	// A reordered packet is forwarded, and the next source continues from the highest
+	p := &rtp.Packet{Header: rtp.Header{Version: 2, SSRC: 5678, SequenceNumber: uint16(60000 + count - 5)}}
+	assert.True(t, relay.relay(a, p))
+	assert.Equal(t, uint32(1234), p.SSRC)
+	assert.Equal(t, uint16(60000+count-1), relay.rewriter.lastSequenceNumber)
+}
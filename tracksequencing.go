@@ -0,0 +1,103 @@
+// +build !js
+
+package webrtc
+
+import (
+	"sync"
+
+	"github.com/pion/rtp"
+)
+
+// TimestampGenerator generates the RTP timestamps of the Samples written to a
+// Track, like an rtp.Sequencer does for its sequence numbers
+type TimestampGenerator interface {
+	// NextTimestamp returns the timestamp of the next Sample, which lasts
+	// samples
+	NextTimestamp(samples uint32) uint32
+}
+
+// TimestampGeneratorFunc is a function used as a TimestampGenerator, like to
+// convert the presentation timestamps of an encoder
+type TimestampGeneratorFunc func(samples uint32) uint32
+
+// NextTimestamp calls f
+func (f TimestampGeneratorFunc) NextTimestamp(samples uint32) uint32 {
+	return f(samples)
+}
+
+// timestampGenerator advances the timestamps by the samples of each Sample
+type timestampGenerator struct {
+	mu   sync.Mutex
+	next uint32
+}
+
+// NewTimestampGenerator returns a TimestampGenerator starting at timestamp,
+// which advances by the samples of each Sample
+func NewTimestampGenerator(timestamp uint32) TimestampGenerator {
+	return &timestampGenerator{next: timestamp}
+}
+
+func (g *timestampGenerator) NextTimestamp(samples uint32) uint32 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	timestamp := g.next
+	g.next += samples
+	return timestamp
+}
+
+// trackSequencer is the rtp.Sequencer of the packetizer of a Track, which
+// uses the one set with SetSequencer
+type trackSequencer struct {
+	track *Track
+}
+
+func (s *trackSequencer) NextSequenceNumber() uint16 {
+	return s.track.Sequencer().NextSequenceNumber()
+}
+
+func (s *trackSequencer) RollOverCount() uint64 {
+	return s.track.Sequencer().RollOverCount()
+}
+
+// SetSequencer sets the rtp.Sequencer of the sequence numbers of the Samples
+// written to the Track, instead of one starting at a random sequence number.
+// Passing the Sequencer of another Track continues its numbering, like when
+// a Track is handed over.
+func (t *Track) SetSequencer(sequencer rtp.Sequencer) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.receiver != nil {
+		return errTrackLocalTrackWrite
+	}
+	t.sequencer = sequencer
+	return nil
+}
+
+// Sequencer returns the rtp.Sequencer of the Track
+func (t *Track) Sequencer() rtp.Sequencer {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.sequencer
+}
+
+// SetTimestampGenerator sets the TimestampGenerator of the timestamps of the
+// Samples written to the Track, instead of one starting at a random
+// timestamp. Passing the TimestampGenerator of another Track continues its
+// timeline, like when a Track is handed over.
+func (t *Track) SetTimestampGenerator(generator TimestampGenerator) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.receiver != nil {
+		return errTrackLocalTrackWrite
+	}
+	t.timestamps = generator
+	return nil
+}
+
+// TimestampGenerator returns the TimestampGenerator of the Track
+func (t *Track) TimestampGenerator() TimestampGenerator {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.timestamps
+}
@@ -0,0 +1,72 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3/pkg/media"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimestampGenerator(t *testing.T) {
+	g := NewTimestampGenerator(0xFFFFFF00)
+	assert.Equal(t, uint32(0xFFFFFF00), g.NextTimestamp(0x100))
+	assert.Equal(t, uint32(0), g.NextTimestamp(3000))
+	assert.Equal(t, uint32(3000), g.NextTimestamp(3000))
+}
+
+func TestTrack_SetSequencer(t *testing.T) {
+	remote := &Track{receiver: &RTPReceiver{}}
+	assert.Equal(t, errTrackLocalTrackWrite, remote.SetSequencer(rtp.NewRandomSequencer()))
+	assert.Equal(t, errTrackLocalTrackWrite, remote.SetTimestampGenerator(NewTimestampGenerator(0)))
+	assert.Equal(t, errTrackLocalTrackWrite, remote.WriteSample(media.Sample{Data: []byte{0x00}}))
+
+	pcOffer, pcAnswer, err := newPair()
+	assert.NoError(t, err)
+
+	track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, 5000, "video", "pion")
+	assert.NoError(t, err)
+	_, err = pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+
+	// Each Sample is a single packet, so the sequence numbers and timestamps
+	// advance together
+	assert.NoError(t, track.SetSequencer(rtp.NewFixedSequencer(1000)))
+	frame := uint32(0)
+	assert.NoError(t, track.SetTimestampGenerator(TimestampGeneratorFunc(func(samples uint32) uint32 {
+		frame++
+		return 90000 + frame*3000
+	})))
+
+	received := make(chan *rtp.Packet)
+	pcAnswer.OnTrack(func(track *Track, r *RTPReceiver) {
+		packet, readErr := track.ReadRTP()
+		if readErr == nil {
+			received <- packet
+		}
+	})
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-time.After(20 * time.Millisecond):
+				assert.NoError(t, track.WriteSample(media.Sample{Data: []byte{0x00}, Samples: 1}))
+			case <-done:
+				return
+			}
+		}
+	}()
+	packet := <-received
+	close(done)
+
+	assert.True(t, packet.SequenceNumber >= 1000)
+	assert.Equal(t, 90000+3000*uint32(packet.SequenceNumber-999), packet.Timestamp)
+
+	closePairNow(t, pcOffer, pcAnswer)
+}
@@ -0,0 +1,143 @@
+// +build !js
+
+package webrtc
+
+import (
+	"sync"
+
+	"github.com/pion/rtp"
+)
+
+// TrackSwitcher writes one of many sources of RTP packets to a local Track,
+// like a program feed and a slate. The sequence numbers and timestamps are
+// rewritten so they continue when the source is switched, and the SSRC and
+// payload type are those of the local Track.
+//
+// All sources must use the codec of the local Track. A video source becomes
+// active with a keyframe that starts after the last frame of the previous
+// source is complete, so a decoder never sees a partial frame. Until then
+// the previous source is written.
+type TrackSwitcher struct {
+	local *Track
+
+	mu                sync.Mutex
+	active            *SwitcherSource
+	pending           *SwitcherSource
+	onKeyframeRequest func(*SwitcherSource)
+
+	rewriter rtpRewriter
+}
+
+// SwitcherSource is a source of RTP packets of a TrackSwitcher
+type SwitcherSource struct {
+	switcher *TrackSwitcher
+
+	rewriteSource
+}
+
+// NewTrackSwitcher creates a TrackSwitcher that writes to local
+func NewTrackSwitcher(local *Track) (*TrackSwitcher, error) {
+	local.mu.RLock()
+	isRemote := local.receiver != nil
+	local.mu.RUnlock()
+	if isRemote {
+		return nil, errTrackSwitcherRemoteTrack
+	}
+
+	return &TrackSwitcher{local: local}, nil
+}
+
+// NewSource creates a source of the TrackSwitcher
+func (s *TrackSwitcher) NewSource() *SwitcherSource {
+	return &SwitcherSource{switcher: s}
+}
+
+// OnKeyframeRequest sets an event handler which is called when a video
+// source needs a keyframe to become active. It is called again if a
+// keyframe arrives before the previous source completes its frame.
+func (s *TrackSwitcher) OnKeyframeRequest(f func(*SwitcherSource)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onKeyframeRequest = f
+}
+
+// Switch makes src active with its next packet, or for video with its next
+// keyframe
+func (s *TrackSwitcher) Switch(src *SwitcherSource) error {
+	if src.switcher != s {
+		return errTrackSwitcherSourceUnknown
+	}
+
+	s.mu.Lock()
+	if src == s.active {
+		s.pending = nil
+		s.mu.Unlock()
+		return nil
+	}
+	s.pending = src
+	s.mu.Unlock()
+
+	if s.local.Kind() == RTPCodecTypeVideo {
+		s.requestKeyframe(src)
+	}
+	return nil
+}
+
+// Active returns the source that is written, or nil before the first source
+// becomes active
+func (s *TrackSwitcher) Active() *SwitcherSource {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.active
+}
+
+func (s *TrackSwitcher) requestKeyframe(src *SwitcherSource) {
+	s.mu.Lock()
+	handler := s.onKeyframeRequest
+	s.mu.Unlock()
+
+	if handler != nil {
+		handler(src)
+	}
+}
+
+// WriteRTP writes a packet of the source to the local Track if the source
+// is active, otherwise the packet is dropped. The packet isn't modified.
+func (src *SwitcherSource) WriteRTP(p *rtp.Packet) error {
+	s := src.switcher
+	header := p.Header
+
+	s.mu.Lock()
+	switch {
+	case src == s.pending:
+		if s.local.Kind() == RTPCodecTypeVideo {
+			if !IsKeyframe(p.Payload, s.local.Codec().MimeType) {
+				s.mu.Unlock()
+				return nil
+			}
+			// The frame of the previous source is incomplete, wait for the next keyframe
+			if s.rewriter.hasWritten && !s.rewriter.lastMarker {
+				s.mu.Unlock()
+				s.requestKeyframe(src)
+				return nil
+			}
+		}
+		s.active, s.pending = src, nil
+		s.rewriter.activate(&src.rewriteSource, &header, s.local.Codec().ClockRate)
+	case src != s.active:
+		s.mu.Unlock()
+		return nil
+	}
+
+	// Packets reordered before the first packet can't be written
+	if !s.rewriter.rewrite(&src.rewriteSource, &header) {
+		s.mu.Unlock()
+		return nil
+	}
+	header.SSRC = s.local.SSRC()
+	header.PayloadType = s.local.PayloadType()
+
+	// Written under the lock, so packets of sources being switched aren't reordered
+	defer s.mu.Unlock()
+	return s.local.WriteRTP(&rtp.Packet{Header: header, Payload: p.Payload})
+}
@@ -0,0 +1,132 @@
+// +build !js
+
+package webrtc
+
+import (
+	"io"
+	"testing"
+
+	"github.com/pion/rtp"
+	"github.com/pion/webrtc/v3/pkg/interceptor"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrackSwitcher(t *testing.T) {
+	local, err := NewTrack(DefaultPayloadTypeVP8, 1234, "video", "pion", NewRTPVP8Codec(DefaultPayloadTypeVP8, 90000))
+	assert.NoError(t, err)
+
+	// Capture the packets written to the local Track
+	written := []rtp.Header{}
+	api := NewAPI()
+	sender := &RTPSender{
+		track:      local,
+		api:        api,
+		sendCalled: make(chan interface{}),
+		stopCalled: make(chan interface{}),
+	}
+	sender.streamInfo = createStreamInfo(local.ID(), local.SSRC(), local.PayloadType(), local.Codec())
+	sender.rtpWriter = api.interceptor.BindLocalStream(&sender.streamInfo, interceptor.RTPWriterFunc(func(header *rtp.Header, payload []byte) (int, error) {
+		written = append(written, *header)
+		return header.MarshalSize() + len(payload), nil
+	}))
+	close(sender.sendCalled)
+	local.activeSenders = append(local.activeSenders, sender)
+	local.totalSenderCount++
+
+	packet := func(sequenceNumber uint16, timestamp uint32, marker bool, payload ...byte) *rtp.Packet {
+		return &rtp.Packet{
+			Header: rtp.Header{
+				Version:        2,
+				SSRC:           5678,
+				PayloadType:    100,
+				SequenceNumber: sequenceNumber,
+				Timestamp:      timestamp,
+				Marker:         marker,
+			},
+			Payload: payload,
+		}
+	}
+	keyframe := []byte{0x10, 0x00}
+	interframe := []byte{0x10, 0x01}
+
+	_, err = NewTrackSwitcher(&Track{receiver: &RTPReceiver{}})
+	assert.Equal(t, errTrackSwitcherRemoteTrack, err)
+
+	switcher, err := NewTrackSwitcher(local)
+	assert.NoError(t, err)
+	other, err := NewTrackSwitcher(local)
+	assert.NoError(t, err)
+	assert.Equal(t, errTrackSwitcherSourceUnknown, switcher.Switch(other.NewSource()))
+
+	keyframeRequests := []*SwitcherSource{}
+	switcher.OnKeyframeRequest(func(src *SwitcherSource) {
+		keyframeRequests = append(keyframeRequests, src)
+	})
+
+	program := switcher.NewSource()
+	slate := switcher.NewSource()
+	assert.NoError(t, switcher.Switch(program))
+	assert.Equal(t, []*SwitcherSource{program}, keyframeRequests)
+
+	// The first source starts with a keyframe
+	assert.NoError(t, program.WriteRTP(packet(100, 9000, true, interframe...)))
+	assert.Empty(t, written)
+	assert.Nil(t, switcher.Active())
+
+	p := packet(101, 9000, false, keyframe...)
+	assert.NoError(t, program.WriteRTP(p))
+	assert.Equal(t, uint32(5678), p.SSRC)
+	assert.Equal(t, []rtp.Header{{Version: 2, SSRC: 1234, PayloadType: DefaultPayloadTypeVP8, SequenceNumber: 101, Timestamp: 9000}}, written)
+	assert.Equal(t, program, switcher.Active())
+
+	// A keyframe in the middle of a frame of the previous source is skipped
+	assert.NoError(t, switcher.Switch(slate))
+	assert.NoError(t, slate.WriteRTP(packet(5000, 100, false, keyframe...)))
+	assert.Equal(t, []*SwitcherSource{program, slate, slate}, keyframeRequests)
+	assert.Len(t, written, 1)
+
+	assert.NoError(t, program.WriteRTP(packet(102, 9000, true, interframe...)))
+	assert.Len(t, written, 2)
+	assert.Equal(t, program, switcher.Active())
+
+	// Sequence numbers and timestamps continue
+	assert.NoError(t, slate.WriteRTP(packet(5010, 100, false, keyframe...)))
+	assert.Len(t, written, 3)
+	assert.Equal(t, uint16(103), written[2].SequenceNumber)
+	assert.True(t, written[2].Timestamp > 9000)
+	assert.Equal(t, slate, switcher.Active())
+	timestamp := written[2].Timestamp
+
+	assert.NoError(t, slate.WriteRTP(packet(5011, 3100, true, interframe...)))
+	assert.Equal(t, uint16(104), written[3].SequenceNumber)
+	assert.Equal(t, timestamp+3000, written[3].Timestamp)
+
+	// Inactive sources and packets before the first are dropped
+	assert.NoError(t, program.WriteRTP(packet(103, 12000, true, keyframe...)))
+	assert.NoError(t, slate.WriteRTP(packet(5009, 100, false, interframe...)))
+	assert.Len(t, written, 4)
+
+	// Switching to the active source cancels a switch
+	assert.NoError(t, switcher.Switch(program))
+	assert.NoError(t, switcher.Switch(slate))
+	assert.NoError(t, program.WriteRTP(packet(104, 15000, false, keyframe...)))
+	assert.Len(t, written, 4)
+	assert.Equal(t, slate, switcher.Active())
+}
+
+func TestTrackSwitcher_SequenceNumberWrap(t *testing.T) {
+	local, err := NewTrack(DefaultPayloadTypeOpus, 1234, "audio", "pion", NewRTPOpusCodec(DefaultPayloadTypeOpus, 48000))
+	assert.NoError(t, err)
+
+	switcher, err := NewTrackSwitcher(local)
+	assert.NoError(t, err)
+	src := switcher.NewSource()
+	assert.NoError(t, switcher.Switch(src))
+
+	// The local Track has no senders, so every packet written fails to send
+	count := 1<<17 + 10
+	for i := 0; i < count; i++ {
+		assert.Equal(t, io.ErrClosedPipe, src.WriteRTP(&rtp.Packet{Header: rtp.Header{Version: 2, SequenceNumber: uint16(60000 + i)}}))
+	}
+	assert.Equal(t, uint16(60000+count-1), switcher.rewriter.lastSequenceNumber)
+}
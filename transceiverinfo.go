@@ -0,0 +1,148 @@
+// +build !js
+
+package webrtc
+
+// TrackInfo is a snapshot of the state of a Track
+type TrackInfo struct {
+	ID          string
+	Label       string
+	RID         string
+	Kind        RTPCodecType
+	SSRC        uint32
+	PayloadType uint8
+
+	// Codec is the name of the codec of the Track, "" if it has none
+	Codec string
+}
+
+// RTPSenderInfo is a snapshot of the state of an RTPSender
+type RTPSenderInfo struct {
+	// Sending tells if Send was called, Stopped if Stop was
+	Sending bool
+	Stopped bool
+
+	// Paused tells if RTP is dropped as the RTPTransceiver doesn't send,
+	// Muted if it is as SetMuted muted the RTPSender
+	Paused bool
+	Muted  bool
+
+	Parameters RTPSendParameters
+
+	// Track is the Track sent, nil if there is none
+	Track *TrackInfo
+}
+
+// RTPReceiverInfo is a snapshot of the state of an RTPReceiver
+type RTPReceiverInfo struct {
+	// Receiving tells if Receive was called, Stopped if Stop was
+	Receiving bool
+	Stopped   bool
+
+	// Muted tells if the remote RTPSender is muted, see OnMute
+	Muted bool
+
+	// Tracks are the Tracks received, one per RID with Simulcast
+	Tracks []TrackInfo
+}
+
+// RTPTransceiverInfo is a snapshot of the state of an RTPTransceiver, its
+// RTPSender and its RTPReceiver
+type RTPTransceiverInfo struct {
+	Mid              string
+	Kind             RTPCodecType
+	Direction        RTPTransceiverDirection
+	CurrentDirection RTPTransceiverDirection
+
+	// Sender is nil if the RTPTransceiver has no RTPSender, Receiver if it
+	// has no RTPReceiver
+	Sender   *RTPSenderInfo
+	Receiver *RTPReceiverInfo
+}
+
+// GetTransceiverInfo returns a snapshot of the state of the RTPTransceivers
+// of the PeerConnection. Unlike the RTPTransceivers, the snapshot isn't
+// modified afterwards, so it can be kept and used from any goroutine, for
+// example to serve the state on a debug endpoint.
+func (pc *PeerConnection) GetTransceiverInfo() []RTPTransceiverInfo {
+	transceivers := pc.GetTransceivers()
+
+	infos := make([]RTPTransceiverInfo, 0, len(transceivers))
+	for _, t := range transceivers {
+		infos = append(infos, t.info())
+	}
+	return infos
+}
+
+func (t *RTPTransceiver) info() RTPTransceiverInfo {
+	info := RTPTransceiverInfo{
+		Mid:              t.Mid(),
+		Kind:             t.Kind(),
+		Direction:        t.Direction(),
+		CurrentDirection: t.CurrentDirection(),
+	}
+	if sender := t.Sender(); sender != nil {
+		senderInfo := sender.info()
+		info.Sender = &senderInfo
+	}
+	if receiver := t.Receiver(); receiver != nil {
+		receiverInfo := receiver.info()
+		info.Receiver = &receiverInfo
+	}
+	return info
+}
+
+func (r *RTPSender) info() RTPSenderInfo {
+	info := RTPSenderInfo{
+		Sending:    r.hasSent(),
+		Paused:     r.paused.get(),
+		Muted:      r.Muted(),
+		Parameters: r.GetParameters(),
+	}
+	select {
+	case <-r.stopCalled:
+		info.Stopped = true
+	default:
+	}
+
+	if track := r.Track(); track != nil {
+		trackInfo := track.info()
+		info.Track = &trackInfo
+	}
+	return info
+}
+
+func (r *RTPReceiver) info() RTPReceiverInfo {
+	info := RTPReceiverInfo{
+		Receiving: r.haveReceived(),
+		Muted:     r.Muted(),
+		Tracks:    []TrackInfo{},
+	}
+	select {
+	case <-r.closed:
+		info.Stopped = true
+	default:
+	}
+
+	for _, track := range r.Tracks() {
+		info.Tracks = append(info.Tracks, track.info())
+	}
+	return info
+}
+
+func (t *Track) info() TrackInfo {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	info := TrackInfo{
+		ID:          t.id,
+		Label:       t.label,
+		RID:         t.rid,
+		Kind:        t.kind,
+		SSRC:        t.ssrc,
+		PayloadType: t.payloadType,
+	}
+	if t.codec != nil {
+		info.Codec = t.codec.Name
+	}
+	return info
+}
@@ -0,0 +1,62 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPeerConnection_GetTransceiverInfo(t *testing.T) {
+	pcOffer, pcAnswer, err := newPair()
+	assert.NoError(t, err)
+	assert.Equal(t, []RTPTransceiverInfo{}, pcOffer.GetTransceiverInfo())
+
+	track, err := pcOffer.NewTrack(DefaultPayloadTypeVP8, 1234, "video", "pion")
+	assert.NoError(t, err)
+	_, err = pcOffer.AddTrack(track)
+	assert.NoError(t, err)
+	_, err = pcOffer.AddTransceiverFromKind(RTPCodecTypeAudio, RtpTransceiverInit{Direction: RTPTransceiverDirectionRecvonly})
+	assert.NoError(t, err)
+
+	infos := pcOffer.GetTransceiverInfo()
+	assert.Equal(t, 2, len(infos))
+	assert.Equal(t, RTPTransceiverInfo{
+		Kind:             RTPCodecTypeVideo,
+		Direction:        RTPTransceiverDirectionSendrecv,
+		CurrentDirection: RTPTransceiverDirection(Unknown),
+		Sender: &RTPSenderInfo{
+			Parameters: RTPSendParameters{Encodings: RTPEncodingParameters{Active: true}},
+			Track: &TrackInfo{
+				ID:          "video",
+				Label:       "pion",
+				Kind:        RTPCodecTypeVideo,
+				SSRC:        1234,
+				PayloadType: DefaultPayloadTypeVP8,
+				Codec:       VP8,
+			},
+		},
+		Receiver: &RTPReceiverInfo{Tracks: []TrackInfo{}},
+	}, infos[0])
+	assert.Equal(t, RTPTransceiverDirectionRecvonly, infos[1].Direction)
+	assert.Nil(t, infos[1].Sender)
+
+	assert.NoError(t, signalPair(pcOffer, pcAnswer))
+
+	// The snapshot taken before isn't modified
+	assert.Equal(t, "", infos[0].Mid)
+	assert.False(t, infos[0].Sender.Sending)
+
+	infos = pcOffer.GetTransceiverInfo()
+	assert.Equal(t, "0", infos[0].Mid)
+	assert.Equal(t, RTPTransceiverDirectionSendonly, infos[0].CurrentDirection)
+	assert.Equal(t, "1", infos[1].Mid)
+	assert.Equal(t, RTPTransceiverDirectionInactive, infos[1].CurrentDirection)
+
+	assert.NoError(t, pcOffer.Close())
+	infos = pcOffer.GetTransceiverInfo()
+	assert.True(t, infos[0].Sender.Stopped)
+	assert.True(t, infos[0].Receiver.Stopped)
+	assert.NoError(t, pcAnswer.Close())
+}
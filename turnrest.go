@@ -0,0 +1,57 @@
+// +build !js
+
+package webrtc
+
+import (
+	"crypto/hmac"
+	"crypto/sha1" // nolint:gosec
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NewTURNRESTICEServer returns an ICEServer with the ephemeral credentials of
+// the TURN REST API, as supported by coturn with use-auth-secret. The
+// username is the unix time the credentials expire at, ttl from now, and
+// user separated by a colon, and the password the base64 HMAC-SHA1 of the
+// username keyed with the secret shared with the TURN server. The TURN
+// server refuses the credentials once they expired, so an ICEServer is
+// created for each PeerConnection.
+func NewTURNRESTICEServer(urls []string, secret, user string, ttl time.Duration) ICEServer {
+	username, password := turnRESTCredentials(secret, user, time.Now().Add(ttl))
+	return ICEServer{
+		URLs:           urls,
+		Username:       username,
+		Credential:     password,
+		CredentialType: ICECredentialTypePassword,
+	}
+}
+
+// TURNRESTCredentialsExpiry returns when the TURN REST API credentials of
+// server expire, false if it doesn't have such credentials
+func TURNRESTCredentialsExpiry(server ICEServer) (time.Time, bool) {
+	expiry := server.Username
+	if i := strings.IndexByte(expiry, ':'); i != -1 {
+		expiry = expiry[:i]
+	}
+
+	seconds, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil || seconds <= 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(seconds, 0), true
+}
+
+// turnRESTCredentials returns the username and password of user for the
+// TURN REST API, which expire at expires
+func turnRESTCredentials(secret, user string, expires time.Time) (string, string) {
+	username := strconv.FormatInt(expires.Unix(), 10)
+	if user != "" {
+		username += ":" + user
+	}
+
+	mac := hmac.New(sha1.New, []byte(secret))
+	mac.Write([]byte(username)) // nolint:errcheck
+	return username, base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
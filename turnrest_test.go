@@ -0,0 +1,29 @@
+// +build !js
+
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTURNRESTCredentials(t *testing.T) {
+	// The password is the base64 HMAC-SHA1 of the username
+	username, password := turnRESTCredentials("north", "alice", time.Unix(1600000000, 0))
+	assert.Equal(t, "1600000000:alice", username)
+	assert.Equal(t, "gq98pTOhhHaAu0we9aV79kOVVv0=", password)
+
+	username, _ = turnRESTCredentials("north", "", time.Unix(1600000000, 0))
+	assert.Equal(t, "1600000000", username)
+
+	server := NewTURNRESTICEServer([]string{"turn:turn.example.com:3478"}, "north", "alice", time.Hour)
+	assert.NoError(t, server.validate())
+	expiry, ok := TURNRESTCredentialsExpiry(server)
+	assert.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), expiry, 2*time.Second)
+
+	_, ok = TURNRESTCredentialsExpiry(ICEServer{Username: "alice"})
+	assert.False(t, ok)
+}